@@ -0,0 +1,49 @@
+package core
+
+import (
+	"errors"
+	"net/netip"
+	"time"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/api"
+)
+
+// BanService provides methods to manage the banned IP addresses.
+type BanService struct {
+	banAPI api.BanAPI
+}
+
+func NewBanService(banAPI api.BanAPI) *BanService {
+	return &BanService{
+		banAPI: banAPI,
+	}
+}
+
+// ListBans returns all bans that have not expired.
+func (s *BanService) ListBans() []api.BanInfo {
+	return s.banAPI.GetBans()
+}
+
+// AddBan bans an IP address for the given duration, or for the configured ban duration if the duration is 0.
+func (s *BanService) AddBan(ip []byte, duration time.Duration, reason string) error {
+	ipAddr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return errors.New("invalid IP address format")
+	}
+	if duration == 0 {
+		duration = common.BanDuration()
+	}
+
+	return s.banAPI.Ban(ipAddr, duration, reason)
+}
+
+// RemoveBan lifts the ban of an IP address.
+func (s *BanService) RemoveBan(ip []byte) error {
+	ipAddr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return errors.New("invalid IP address format")
+	}
+
+	return s.banAPI.Unban(ipAddr)
+}
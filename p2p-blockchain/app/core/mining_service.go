@@ -1,20 +1,30 @@
 package core
 
 import (
+	"fmt"
 	minerApi "s3b/vsp-blockchain/p2p-blockchain/miner/api"
 
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 )
 
+// AddressDecoder decodes V$Addresses.
+// It is implemented by keys.KeyEncodingsImpl.
+type AddressDecoder interface {
+	VSAddressToPubKeyHash(address string) ([common.PublicKeyHashSize]byte, error)
+}
+
 // MiningService provides methods to control the mining process.
 type MiningService struct {
-	minerAPI minerApi.MinerAPI
+	minerAPI       minerApi.MinerAPI
+	addressDecoder AddressDecoder
 }
 
 // NewMiningService creates a new MiningService with the given miner API.
-func NewMiningService(minerAPI minerApi.MinerAPI) *MiningService {
+func NewMiningService(minerAPI minerApi.MinerAPI, addressDecoder AddressDecoder) *MiningService {
 	return &MiningService{
-		minerAPI: minerAPI,
+		minerAPI:       minerAPI,
+		addressDecoder: addressDecoder,
 	}
 }
 
@@ -41,3 +51,47 @@ func (s *MiningService) DisableMining() error {
 	s.minerAPI.DisableMining()
 	return nil
 }
+
+// ConfigurePayout sets the V$Addresses receiving the rewards of mined blocks and the message embedded in their coinbase.
+// If no addresses are given, the current addresses are kept. If no tag is given (nil), the current tag is kept.
+// Nothing is changed if an address or the tag is invalid.
+func (s *MiningService) ConfigurePayout(addresses []common.PayoutAddress, coinbaseTag *string) error {
+	if coinbaseTag != nil {
+		if err := transaction.ValidateCoinbaseTag([]byte(*coinbaseTag)); err != nil {
+			return err
+		}
+	}
+
+	if len(addresses) > 0 {
+		payouts, err := DecodePayoutAddresses(s.addressDecoder, addresses)
+		if err != nil {
+			return err
+		}
+		if err := s.minerAPI.SetPayouts(payouts); err != nil {
+			return err
+		}
+	}
+
+	if coinbaseTag == nil {
+		return nil
+	}
+	return s.minerAPI.SetCoinbaseTag([]byte(*coinbaseTag))
+}
+
+// GetMiningStatus returns the current state of the miner and the blocks it mined.
+func (s *MiningService) GetMiningStatus() minerApi.MiningStatus {
+	return s.minerAPI.GetMiningStatus()
+}
+
+// DecodePayoutAddresses decodes the V$Addresses of the payouts.
+func DecodePayoutAddresses(decoder AddressDecoder, addresses []common.PayoutAddress) ([]transaction.CoinbasePayout, error) {
+	payouts := make([]transaction.CoinbasePayout, 0, len(addresses))
+	for _, address := range addresses {
+		pubKeyHash, err := decoder.VSAddressToPubKeyHash(address.VSAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payout address %s: %w", address.VSAddress, err)
+		}
+		payouts = append(payouts, transaction.CoinbasePayout{PubKeyHash: pubKeyHash, Percent: address.Percent})
+	}
+	return payouts, nil
+}
@@ -180,7 +180,8 @@ func formatTransaction(sb *strings.Builder, tx *transaction.Transaction, isCoinb
 		// Format output side
 		if row < len(tx.Outputs) {
 			output := &tx.Outputs[row]
-			pubKeyHashStr := hex.EncodeToString(output.PubKeyHash[:])
+			addressHash := output.AddressHash()
+			pubKeyHashStr := hex.EncodeToString(addressHash[:])
 			bytesWrote, err := fmt.Fprintf(sb, "%s(%d)", shortenHash(pubKeyHashStr), output.Value)
 			if err != nil {
 				logger.Warnf("[visualization] Wrote %d bytes. Failed to format transaction input: %v", bytesWrote, err)
@@ -1,7 +1,9 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -9,8 +11,11 @@ import (
 	"net/netip"
 	"s3b/vsp-blockchain/p2p-blockchain/app/infrastructure/adapters"
 	blockcahin_api "s3b/vsp-blockchain/p2p-blockchain/blockchain/api"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	minerApi "s3b/vsp-blockchain/p2p-blockchain/miner/api"
 	"s3b/vsp-blockchain/p2p-blockchain/wallet/api"
+	"time"
 
 	"s3b/vsp-blockchain/p2p-blockchain/app/core"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
@@ -18,6 +23,7 @@ import (
 
 	"bjoernblessin.de/go-utils/util/logger"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -32,13 +38,17 @@ type Server struct {
 	queryRegistryService *core.QueryRegistryService
 	discoveryService     *core.DiscoveryService
 	disconnectService    *core.DisconnectService
+	banService           *core.BanService
 	keysApi              api.KeyGeneratorApi
 	transactionAPI       api.TransactionCreationAPI
 	kontoAPI             api.KontoAPI
 	historyAPI           api.HistoryAPI
 	visualizationHandler *adapters.VisualizationHandlerAdapter
 	miningService        *core.MiningService
+	blockTemplateAPI     minerApi.BlockTemplateAPI
 	blockStore           blockcahin_api.BlockStoreAPI
+	// tlsConfig secures the connections of local systems, nil for plaintext connections, see nodecert.AppServerConfig.
+	tlsConfig *tls.Config
 }
 
 // NewServer creates a new external API server.
@@ -53,8 +63,11 @@ func NewServer(
 	historyAPI api.HistoryAPI,
 	visualizationHandler *adapters.VisualizationHandlerAdapter,
 	miningService *core.MiningService,
+	blockTemplateAPI minerApi.BlockTemplateAPI,
 	disconnectService *core.DisconnectService,
+	banService *core.BanService,
 	blockStore blockcahin_api.BlockStoreAPI,
+	tlsConfig *tls.Config,
 ) *Server {
 	return &Server{
 		connService:          connService,
@@ -62,13 +75,16 @@ func NewServer(
 		queryRegistryService: queryRegistryService,
 		discoveryService:     discoveryService,
 		disconnectService:    disconnectService,
+		banService:           banService,
 		keysApi:              keysApi,
 		transactionAPI:       transactionAPI,
 		kontoAPI:             kontoAPI,
 		historyAPI:           historyAPI,
 		visualizationHandler: visualizationHandler,
 		miningService:        miningService,
+		blockTemplateAPI:     blockTemplateAPI,
 		blockStore:           blockStore,
+		tlsConfig:            tlsConfig,
 	}
 }
 
@@ -81,7 +97,11 @@ func (s *Server) Start(port uint16) error {
 	}
 	s.listener = listener
 
-	s.grpcServer = grpc.NewServer()
+	var options []grpc.ServerOption
+	if s.tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	s.grpcServer = grpc.NewServer(options...)
 	pb.RegisterAppServiceServer(s.grpcServer, s)
 
 	go func() {
@@ -154,6 +174,55 @@ func (s *Server) Disconnect(_ context.Context, req *pb.DisconnectRequest) (*pb.D
 	}, nil
 }
 
+// ListBans handles the ListBans RPC call from external local systems.
+func (s *Server) ListBans(_ context.Context, _ *emptypb.Empty) (*pb.ListBansResponse, error) {
+	bans := s.banService.ListBans()
+
+	response := &pb.ListBansResponse{Bans: make([]*pb.Ban, 0, len(bans))}
+	for _, ban := range bans {
+		response.Bans = append(response.Bans, &pb.Ban{
+			IpAddress: ban.Addr.AsSlice(),
+			Reason:    ban.Reason,
+			CreatedAt: ban.CreatedAt,
+			ExpiresAt: ban.ExpiresAt,
+		})
+	}
+	return response, nil
+}
+
+// AddBan handles the AddBan RPC call from external local systems.
+func (s *Server) AddBan(_ context.Context, req *pb.AddBanRequest) (*pb.AddBanResponse, error) {
+	if req.DurationSeconds < 0 {
+		return &pb.AddBanResponse{
+			Success:      false,
+			ErrorMessage: "duration must not be negative",
+		}, nil
+	}
+
+	err := s.banService.AddBan(req.IpAddress, time.Duration(req.DurationSeconds)*time.Second, req.Reason)
+	if err != nil {
+		return &pb.AddBanResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("failed to ban: %v", err),
+		}, nil
+	}
+
+	return &pb.AddBanResponse{Success: true}, nil
+}
+
+// RemoveBan handles the RemoveBan RPC call from external local systems.
+func (s *Server) RemoveBan(_ context.Context, req *pb.RemoveBanRequest) (*pb.RemoveBanResponse, error) {
+	err := s.banService.RemoveBan(req.IpAddress)
+	if err != nil {
+		return &pb.RemoveBanResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("failed to remove ban: %v", err),
+		}, nil
+	}
+
+	return &pb.RemoveBanResponse{Success: true}, nil
+}
+
 // ListeningEndpoint returns the server's listening endpoint as netip.AddrPort.
 // If the server is not started, it returns an error.
 func (s *Server) ListeningEndpoint() (netip.AddrPort, error) {
@@ -249,33 +318,193 @@ func (s *Server) CreateTransaction(_ context.Context, req *pb.CreateTransactionR
 		}, nil
 	}
 
-	result := s.transactionAPI.CreateTransaction(req.RecipientVsAddress, req.Amount, req.SenderPrivateKeyWif)
+	result := s.transactionAPI.CreateTransaction(req.RecipientVsAddress, req.Amount, req.SenderPrivateKeyWif, req.Replaceable)
 
-	// Map error code
-	var pbErrorCode pb.TransactionErrorCode
-	switch result.ErrorCode {
+	return &pb.CreateTransactionResponse{
+		Success:       result.Success,
+		ErrorCode:     toPbTransactionErrorCode(result.ErrorCode),
+		ErrorMessage:  result.ErrorMessage,
+		TransactionId: result.TransactionID,
+	}, nil
+}
+
+func toPbTransactionErrorCode(errorCode transaction.TransactionErrorCode) pb.TransactionErrorCode {
+	switch errorCode {
 	case transaction.ErrorCodeNone:
-		pbErrorCode = pb.TransactionErrorCode_NONE
+		return pb.TransactionErrorCode_NONE
 	case transaction.ErrorCodeInvalidPrivateKey:
-		pbErrorCode = pb.TransactionErrorCode_INVALID_PRIVATE_KEY
+		return pb.TransactionErrorCode_INVALID_PRIVATE_KEY
 	case transaction.ErrorCodeInsufficientFunds:
-		pbErrorCode = pb.TransactionErrorCode_INSUFFICIENT_FUNDS
+		return pb.TransactionErrorCode_INSUFFICIENT_FUNDS
 	case transaction.ErrorCodeValidationFailed:
-		pbErrorCode = pb.TransactionErrorCode_VALIDATION_FAILED
+		return pb.TransactionErrorCode_VALIDATION_FAILED
 	case transaction.ErrorCodeBroadcastFailed:
-		pbErrorCode = pb.TransactionErrorCode_BROADCAST_FAILED
+		return pb.TransactionErrorCode_BROADCAST_FAILED
 	default:
-		pbErrorCode = pb.TransactionErrorCode_VALIDATION_FAILED
+		return pb.TransactionErrorCode_VALIDATION_FAILED
+	}
+}
+
+// CreateMultisigAddress creates an M-of-N multisig address from the public keys of the co-signers.
+func (s *Server) CreateMultisigAddress(_ context.Context, req *pb.CreateMultisigAddressRequest) (*pb.CreateMultisigAddressResponse, error) {
+	publicKeys := make([][common.PublicKeySize]byte, 0, len(req.PublicKeys))
+	for _, publicKey := range req.PublicKeys {
+		if len(publicKey) != common.PublicKeySize {
+			return &pb.CreateMultisigAddressResponse{
+				Success:      false,
+				ErrorMessage: fmt.Sprintf("public keys must be %d bytes long", common.PublicKeySize),
+			}, nil
+		}
+		publicKeys = append(publicKeys, [common.PublicKeySize]byte(publicKey))
 	}
 
+	multisigAddress, err := s.keysApi.GetMultisigAddress(int(req.RequiredSignatures), publicKeys)
+	if err != nil {
+		return &pb.CreateMultisigAddressResponse{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return &pb.CreateMultisigAddressResponse{
+		Success:         true,
+		MultisigAddress: multisigAddress.Address,
+		RedeemScript:    multisigAddress.RedeemScript,
+	}, nil
+}
+
+// CreateMultisigTransaction creates a transaction spending from a multisig address and signs it with the key of one co-signer.
+func (s *Server) CreateMultisigTransaction(_ context.Context, req *pb.CreateMultisigTransactionRequest) (*pb.MultisigTransactionResponse, error) {
+	if s.transactionAPI == nil {
+		return &pb.MultisigTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "wallet subsystem is not enabled",
+		}, nil
+	}
+
+	if req.RedeemScript == "" || req.RecipientVsAddress == "" {
+		return &pb.MultisigTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "redeem script and recipient address are required",
+		}, nil
+	}
+	if req.Amount == 0 {
+		return &pb.MultisigTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "amount must be greater than 0",
+		}, nil
+	}
+
+	result := s.transactionAPI.CreateMultisigTransaction(req.RedeemScript, req.RecipientVsAddress, req.Amount, req.SignerPrivateKeyWif)
+	return toPbMultisigTransactionResponse(result), nil
+}
+
+// CoSignTransaction adds the signature of another co-signer to a partially signed multisig transaction.
+func (s *Server) CoSignTransaction(_ context.Context, req *pb.CoSignTransactionRequest) (*pb.MultisigTransactionResponse, error) {
+	if s.transactionAPI == nil {
+		return &pb.MultisigTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "wallet subsystem is not enabled",
+		}, nil
+	}
+
+	if req.SerializedTransaction == "" {
+		return &pb.MultisigTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "serialized transaction is required",
+		}, nil
+	}
+
+	result := s.transactionAPI.CoSignTransaction(req.SerializedTransaction, req.SignerPrivateKeyWif)
+	return toPbMultisigTransactionResponse(result), nil
+}
+
+// CreateDataTransaction creates and broadcasts a transaction that anchors the data of the request in the blockchain.
+func (s *Server) CreateDataTransaction(_ context.Context, req *pb.CreateDataTransactionRequest) (*pb.CreateTransactionResponse, error) {
+	if s.transactionAPI == nil {
+		return &pb.CreateTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "wallet subsystem is not enabled",
+		}, nil
+	}
+
+	if len(req.Data) == 0 {
+		return &pb.CreateTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "data is required",
+		}, nil
+	}
+	if req.SenderPrivateKeyWif == "" {
+		return &pb.CreateTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_INVALID_PRIVATE_KEY,
+			ErrorMessage: "sender private key is required",
+		}, nil
+	}
+
+	result := s.transactionAPI.CreateDataTransaction(req.Data, req.SenderPrivateKeyWif)
+
 	return &pb.CreateTransactionResponse{
 		Success:       result.Success,
-		ErrorCode:     pbErrorCode,
+		ErrorCode:     toPbTransactionErrorCode(result.ErrorCode),
 		ErrorMessage:  result.ErrorMessage,
 		TransactionId: result.TransactionID,
 	}, nil
 }
 
+// BumpFee replaces a pending transaction with one paying the higher fee of the request.
+func (s *Server) BumpFee(_ context.Context, req *pb.BumpFeeRequest) (*pb.CreateTransactionResponse, error) {
+	if s.transactionAPI == nil {
+		return &pb.CreateTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "wallet subsystem is not enabled",
+		}, nil
+	}
+
+	if req.TransactionId == "" {
+		return &pb.CreateTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_VALIDATION_FAILED,
+			ErrorMessage: "transaction ID is required",
+		}, nil
+	}
+	if req.SenderPrivateKeyWif == "" {
+		return &pb.CreateTransactionResponse{
+			Success:      false,
+			ErrorCode:    pb.TransactionErrorCode_INVALID_PRIVATE_KEY,
+			ErrorMessage: "sender private key is required",
+		}, nil
+	}
+
+	result := s.transactionAPI.BumpFee(req.TransactionId, req.Fee, req.SenderPrivateKeyWif)
+
+	return &pb.CreateTransactionResponse{
+		Success:       result.Success,
+		ErrorCode:     toPbTransactionErrorCode(result.ErrorCode),
+		ErrorMessage:  result.ErrorMessage,
+		TransactionId: result.TransactionID,
+	}, nil
+}
+
+func toPbMultisigTransactionResponse(result transaction.MultisigTransactionResult) *pb.MultisigTransactionResponse {
+	return &pb.MultisigTransactionResponse{
+		Success:               result.Success,
+		ErrorCode:             toPbTransactionErrorCode(result.ErrorCode),
+		ErrorMessage:          result.ErrorMessage,
+		TransactionId:         result.TransactionID,
+		Complete:              result.Complete,
+		SerializedTransaction: result.SerializedTransaction,
+	}
+}
+
 func (s *Server) GenerateKeyset(context.Context, *emptypb.Empty) (*pb.GenerateKeysetResponse, error) {
 	keyset := s.keysApi.GenerateKeyset()
 
@@ -360,7 +589,8 @@ func (s *Server) GetAssets(_ context.Context, req *pb.GetAssetsRequest) (*pb.Get
 	pbAssets := make([]*pb.Asset, 0, len(result.Assets))
 	for _, asset := range result.Assets {
 		pbAssets = append(pbAssets, &pb.Asset{
-			Value: asset.Value,
+			Value:    asset.Value,
+			Immature: asset.Immature,
 		})
 	}
 
@@ -419,6 +649,42 @@ func (s *Server) GetHistory(_ context.Context, req *pb.GetHistoryRequest) (*pb.G
 	}, nil
 }
 
+// GetHistoryByData returns all main chain transactions with a data output containing the data of the request.
+func (s *Server) GetHistoryByData(_ context.Context, req *pb.GetHistoryByDataRequest) (*pb.GetHistoryResponse, error) {
+	if s.historyAPI == nil {
+		return &pb.GetHistoryResponse{
+			Success:      false,
+			ErrorMessage: "wallet subsystem is not enabled",
+		}, nil
+	}
+
+	if len(req.Data) == 0 {
+		return &pb.GetHistoryResponse{
+			Success:      false,
+			ErrorMessage: "data is required",
+		}, nil
+	}
+
+	result := s.historyAPI.GetHistoryByData(req.Data)
+
+	if !result.Success {
+		return &pb.GetHistoryResponse{
+			Success:      false,
+			ErrorMessage: result.ErrorMessage,
+		}, nil
+	}
+
+	txStrings := make([]string, 0, len(result.Transactions))
+	for _, tx := range result.Transactions {
+		txStrings = append(txStrings, fmt.Sprintf("TxID: %s, Block: %d", tx.TransactionID, tx.BlockHeight))
+	}
+
+	return &pb.GetHistoryResponse{
+		Success:      true,
+		Transactions: txStrings,
+	}, nil
+}
+
 func (s *Server) GetBlockchainVisualization(_ context.Context, req *pb.GetBlockchainVisualizationRequest) (*pb.GetBlockchainVisualizationResponse, error) {
 	return s.visualizationHandler.GetBlockchainVisualization(req), nil
 }
@@ -476,6 +742,166 @@ func (s *Server) StopMining(_ context.Context, _ *emptypb.Empty) (*pb.StopMining
 	}, nil
 }
 
+func (s *Server) SetMinerPayout(_ context.Context, req *pb.SetMinerPayoutRequest) (*pb.SetMinerPayoutResponse, error) {
+	if s.miningService == nil {
+		return &pb.SetMinerPayoutResponse{
+			Success:      false,
+			ErrorMessage: "mining subsystem is not enabled",
+		}, nil
+	}
+
+	addresses := make([]common.PayoutAddress, 0, len(req.GetPayouts()))
+	for _, payout := range req.GetPayouts() {
+		addresses = append(addresses, common.PayoutAddress{VSAddress: payout.GetVsAddress(), Percent: payout.GetPercent()})
+	}
+
+	err := s.miningService.ConfigurePayout(addresses, req.CoinbaseTag)
+	if err != nil {
+		return &pb.SetMinerPayoutResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("failed to configure miner payout: %v", err),
+		}, nil
+	}
+
+	return &pb.SetMinerPayoutResponse{
+		Success:      true,
+		ErrorMessage: "",
+	}, nil
+}
+
+func (s *Server) GetMiningStatus(_ context.Context, _ *emptypb.Empty) (*pb.GetMiningStatusResponse, error) {
+	if s.miningService == nil {
+		return &pb.GetMiningStatusResponse{
+			Success:      false,
+			ErrorMessage: "mining subsystem is not enabled",
+		}, nil
+	}
+
+	status := s.miningService.GetMiningStatus()
+
+	minedBlocks := make([]*pb.MinedBlock, 0, len(status.MinedBlocks))
+	for _, mined := range status.MinedBlocks {
+		minedBlocks = append(minedBlocks, &pb.MinedBlock{
+			Hash:   mined.Hash[:],
+			Height: mined.Height,
+			Reward: mined.Reward,
+			Status: toPbMinedBlockStatus(mined.Status),
+		})
+	}
+
+	response := &pb.GetMiningStatusResponse{
+		Success:          true,
+		Enabled:          status.Enabled,
+		Mining:           status.Mining,
+		CandidateHeight:  status.CandidateHeight,
+		TransactionCount: uint32(status.TransactionCount),
+		HashesTried:      status.HashesTried,
+		HashRate:         status.HashRate,
+		MinedBlocks:      minedBlocks,
+		TotalRewards:     status.TotalRewards,
+	}
+	if status.Mining {
+		response.PreviousBlockHash = status.PreviousBlockHash[:]
+	}
+	return response, nil
+}
+
+func toPbMinedBlockStatus(status minerApi.MinedBlockStatus) pb.MinedBlockStatus {
+	switch status {
+	case minerApi.MinedBlockMainChain:
+		return pb.MinedBlockStatus_MINED_BLOCK_STATUS_MAIN_CHAIN
+	case minerApi.MinedBlockSideChain:
+		return pb.MinedBlockStatus_MINED_BLOCK_STATUS_SIDE_CHAIN
+	case minerApi.MinedBlockOrphan:
+		return pb.MinedBlockStatus_MINED_BLOCK_STATUS_ORPHAN
+	default:
+		return pb.MinedBlockStatus_MINED_BLOCK_STATUS_UNKNOWN
+	}
+}
+
+func (s *Server) GetBlockTemplate(ctx context.Context, req *pb.GetBlockTemplateRequest) (*pb.GetBlockTemplateResponse, error) {
+	if s.blockTemplateAPI == nil {
+		return &pb.GetBlockTemplateResponse{
+			Success:      false,
+			ErrorMessage: "full blockchain is not enabled",
+		}, nil
+	}
+
+	template, err := s.blockTemplateAPI.GetBlockTemplate(ctx, req.GetLongPollId())
+	if err != nil {
+		return &pb.GetBlockTemplateResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("failed to create block template: %v", err),
+		}, nil
+	}
+
+	transactions := make([]*pb.BlockTemplateTransaction, 0, len(template.Transactions))
+	for _, tx := range template.Transactions {
+		transactions = append(transactions, &pb.BlockTemplateTransaction{Data: tx.Transaction.Serialize(), Fee: tx.Fee})
+	}
+	merkleBranch := make([][]byte, 0, len(template.MerkleBranch))
+	for _, hash := range template.MerkleBranch {
+		merkleBranch = append(merkleBranch, hash[:])
+	}
+
+	return &pb.GetBlockTemplateResponse{
+		Success:           true,
+		TemplateId:        template.ID,
+		PreviousBlockHash: template.PreviousBlockHash[:],
+		Height:            template.Height,
+		DifficultyTarget:  uint32(template.DifficultyTarget),
+		MinTimestamp:      template.MinTimestamp,
+		CurrentTimestamp:  template.CurrentTimestamp,
+		CoinbaseValue:     template.CoinbaseValue,
+		MaxCoinbaseSize:   uint32(template.MaxCoinbaseSize),
+		Transactions:      transactions,
+		MerkleBranch:      merkleBranch,
+		LongPollId:        template.LongPollID,
+	}, nil
+}
+
+func (s *Server) SubmitBlock(_ context.Context, req *pb.SubmitBlockRequest) (*pb.SubmitBlockResponse, error) {
+	if s.blockTemplateAPI == nil {
+		return &pb.SubmitBlockResponse{
+			Success:      false,
+			ErrorMessage: "full blockchain is not enabled",
+		}, nil
+	}
+
+	header, err := block.DeserializeBlockHeader(req.GetHeader())
+	if err != nil {
+		return &pb.SubmitBlockResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("invalid block header: %v", err),
+		}, nil
+	}
+
+	coinbaseReader := bytes.NewReader(req.GetCoinbase())
+	coinbase, err := transaction.DeserializeTransaction(coinbaseReader)
+	if err == nil && coinbaseReader.Len() != 0 {
+		err = errors.New("trailing data")
+	}
+	if err != nil {
+		return &pb.SubmitBlockResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("invalid coinbase transaction: %v", err),
+		}, nil
+	}
+
+	blockHash, err := s.blockTemplateAPI.SubmitBlock(req.GetTemplateId(), header, coinbase)
+	if err != nil {
+		return &pb.SubmitBlockResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("failed to submit block: %v", err),
+		}, nil
+	}
+
+	return &pb.SubmitBlockResponse{
+		Success:   true,
+		BlockHash: blockHash[:],
+	}, nil
+}
+
 func (s *Server) GetConfirmationStatus(_ context.Context, request *pb.GetConfirmationStatusRequest) (*pb.GetConfirmationStatusResponse, error) {
 	txIDString := request.GetTransactionId()
 
@@ -2,6 +2,7 @@ package api
 
 import (
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 	"strconv"
 )
@@ -16,8 +17,37 @@ func NewMempoolAPI(mempool *core.Mempool) *MempoolAPI {
 	}
 }
 
-func (api *MempoolAPI) AddTransaction(transaction transaction.Transaction) bool {
-	return api.mempool.AddTransaction(transaction)
+// AddLocalTransaction adds a transaction created by the wallet to the mempool.
+// The transaction is rebroadcast until it is mined, see GetLocalTransactionHashes.
+func (api *MempoolAPI) AddLocalTransaction(transaction transaction.Transaction) bool {
+	return api.mempool.AddLocalTransaction(transaction)
+}
+
+// GetLocalTransactionHashes returns the hashes of the pending transactions created by the wallet.
+func (api *MempoolAPI) GetLocalTransactionHashes() []common.Hash {
+	return api.mempool.GetLocalTransactionHashes()
+}
+
+// ApplyToUtxos removes the UTXOs spent by unconfirmed transactions and adds the unconfirmed outputs paying to the address,
+// so the wallet can spend change of transactions that are not mined yet.
+func (api *MempoolAPI) ApplyToUtxos(utxos []transaction.UTXO, addressHash transaction.PubKeyHash) []transaction.UTXO {
+	return api.mempool.ApplyToUtxos(utxos, addressHash)
+}
+
+// ApplyToUtxosReplacing is ApplyToUtxos as if the given pending transaction and its descendants were not in the mempool,
+// so the wallet can spend the UTXOs of the transaction again in a replacement with a higher fee.
+func (api *MempoolAPI) ApplyToUtxosReplacing(utxos []transaction.UTXO, addressHash transaction.PubKeyHash, replacedId transaction.TransactionID) []transaction.UTXO {
+	return api.mempool.ApplyToUtxosReplacing(utxos, addressHash, replacedId)
+}
+
+// GetTransaction returns the pending transaction with the given ID and true, or false if it is not in the mempool.
+func (api *MempoolAPI) GetTransaction(txId transaction.TransactionID) (transaction.Transaction, bool) {
+	return api.mempool.GetTransaction(txId)
+}
+
+// GetTransactionsForMining returns the pending transactions to include in new blocks, parents before their children.
+func (api *MempoolAPI) GetTransactionsForMining() []transaction.Transaction {
+	return api.mempool.GetTransactionsForMining()
 }
 
 func (api *MempoolAPI) GetTransactionValues() string {
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"errors"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/validation"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 
@@ -29,6 +31,7 @@ func (b *Blockchain) Block(receivedBlock block.Block, peerID common.PeerId) {
 		logger.Warnf("[block_handler] "+invalidBlockMessageFormat, peerID, err)
 		blockHash := receivedBlock.Hash()
 		b.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectMalformed, "block", blockHash[:])
+		b.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorMalformedBlock, "malformed block")
 		return
 	}
 
@@ -36,11 +39,17 @@ func (b *Blockchain) Block(receivedBlock block.Block, peerID common.PeerId) {
 		logger.Warnf("[block_handler] "+invalidBlockMessageFormat, peerID, err)
 		blockHash := receivedBlock.Hash()
 		b.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "block", blockHash[:])
+		// A timestamp too far in the future depends on the local clock, an honest peer may relay such a block
+		if !errors.Is(err, validation.ErrTimestampTooFarInFuture) {
+			b.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorInvalidBlockHeader, "block with invalid header")
+		}
 		return
 	}
 
 	b.NotifyStopMining()
 	defer b.NotifyStartMining()
+	previousTip := b.blockStore.GetMainChainTip()
+	previousTipHash := previousTip.Hash()
 	// 2. Add block to store
 	addedBlocks := b.blockStore.AddBlock(receivedBlock)
 
@@ -53,22 +62,31 @@ func (b *Blockchain) Block(receivedBlock block.Block, peerID common.PeerId) {
 	}
 
 	// 4. Full validation BEFORE applying to UTXO set
-	if ok, _ := b.blockValidator.FullValidation(receivedBlock); !ok {
-		// An invalid block will be "removed" in the block store in form of not beeing available for retreval
-		if peerID != "" {
+	if ok, err := b.blockValidator.FullValidation(receivedBlock); !ok {
+		// A block of a side chain may fail only because the UTXO set of its previous block is not reachable.
+		// The peer is not to blame for that, the block is fully validated by the chain reorganization once it is connected.
+		if validation.IsLookupError(err) {
+			logger.Debugf("[block_handler] Block %v can't be fully validated yet: %v", &receivedBlock.Header, err)
+		} else {
 			blockHash := receivedBlock.Hash()
-			b.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "block", blockHash[:])
+			// An invalid block will be "removed" in the block store in form of never becoming part of the main chain
+			if err := b.blockStore.MarkBlockInvalid(blockHash); err != nil {
+				logger.Errorf("[block_handler] %v", err)
+			}
+			if peerID != "" {
+				b.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "block", blockHash[:])
+				b.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorInvalidBlock, "invalid block")
+			}
+			logger.Warnf("[block_handler] Block %v is invalid after full validation: %v", &receivedBlock.Header, err)
+			return
 		}
-		logger.Warnf("[block_handler] Block %v is invalid after full validation", &receivedBlock.Header)
-		return
+	} else {
+		logger.Debugf("[block_handler] Block %v passed full validation", &receivedBlock.Header)
 	}
 
-	logger.Debugf("[block_handler] Block %v passed full validation", &receivedBlock.Header)
-
 	// 5. Check if chain reorganization is needed
 	tip := b.blockStore.GetMainChainTip()
-	tipHash := tip.Hash()
-	reorganized, err := b.chainReorganization.CheckAndReorganize(tipHash)
+	reorganized, err := b.chainReorganization.CheckAndReorganize(tip.Hash())
 	if err != nil {
 		logger.Warnf("[block_handler] Chain reorganization failed: %v", err)
 		return
@@ -77,6 +95,11 @@ func (b *Blockchain) Block(receivedBlock block.Block, peerID common.PeerId) {
 	if reorganized {
 		logger.Debugf("[block_handler] Chain reorganization performed")
 	}
+	// Blocks failing full validation during the reorganization are marked invalid, which may change the tip again
+	tip = b.blockStore.GetMainChainTip()
+	if tipHash := tip.Hash(); tipHash != previousTipHash {
+		b.NotifyTipChanged(tipHash)
+	}
 
 	// 6. Broadcast new blocks
 	b.blockchainMsgSender.BroadcastAddedBlocks(addedBlocks, peerID)
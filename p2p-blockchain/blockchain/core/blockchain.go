@@ -22,6 +22,7 @@ type Blockchain struct {
 	blockchainMsgSender    api.BlockchainAPI
 	fullInventoryMsgSender api.FullInventoryInformationMsgSenderAPI
 	errorMsgSender         api.ErrorMsgSenderAPI
+	misbehaviorReporter    api.MisbehaviorReporterAPI
 
 	transactionValidator validation.TransactionValidatorAPI
 	blockValidator       validation.BlockValidationAPI
@@ -29,7 +30,8 @@ type Blockchain struct {
 	blockStore          blockchain.BlockStoreAPI
 	chainReorganization ChainReorganizationAPI
 
-	observers mapset.Set[observer.BlockchainObserverAPI]
+	observers    mapset.Set[observer.BlockchainObserverAPI]
+	tipObservers mapset.Set[observer.ChainTipObserverAPI]
 
 	peerRetriever peerRetriever
 }
@@ -38,6 +40,7 @@ func NewBlockchain(
 	blockchainMsgSender api.BlockchainAPI,
 	fullInventoryMsgSender api.FullInventoryInformationMsgSenderAPI,
 	errorMsgSender api.ErrorMsgSenderAPI,
+	misbehaviorReporter api.MisbehaviorReporterAPI,
 	blockValidator validation.BlockValidationAPI,
 	blockStore blockchain.BlockStoreAPI,
 	peerRetriever peerRetriever,
@@ -45,21 +48,21 @@ func NewBlockchain(
 	utxoService utxo.UtxoStoreAPI,
 	mempool *Mempool,
 ) *Blockchain {
-	genesis := blockchain.GenesisBlock()
-	genesisHash := genesis.Hash()
 	return &Blockchain{
 		mempool:                mempool,
 		blockchainMsgSender:    blockchainMsgSender,
 		fullInventoryMsgSender: fullInventoryMsgSender,
 		errorMsgSender:         errorMsgSender,
+		misbehaviorReporter:    misbehaviorReporter,
 
 		transactionValidator: transactionValidator,
 		blockValidator:       blockValidator,
 
 		blockStore:          blockStore,
-		chainReorganization: NewChainReorganization(blockStore, utxoService, mempool, genesisHash),
+		chainReorganization: NewChainReorganization(blockStore, utxoService, mempool, blockValidator),
 
-		observers: mapset.NewSet[observer.BlockchainObserverAPI](),
+		observers:    mapset.NewSet[observer.BlockchainObserverAPI](),
+		tipObservers: mapset.NewSet[observer.ChainTipObserverAPI](),
 
 		peerRetriever: peerRetriever,
 	}
@@ -90,6 +93,29 @@ func (b *Blockchain) NotifyStopMining() {
 	}
 }
 
+func (b *Blockchain) AttachTipObserver(o observer.ChainTipObserverAPI) {
+	b.tipObservers.Add(o)
+}
+
+func (b *Blockchain) DetachTipObserver(o observer.ChainTipObserverAPI) {
+	b.tipObservers.Remove(o)
+}
+
+func (b *Blockchain) NotifyTipChanged(tip common.Hash) {
+	for o := range b.tipObservers.Iter() {
+		o.TipChanged(tip)
+	}
+}
+
+// ConnectStoredChain moves the UTXO set to the current main chain tip of the block store.
+// Needed at startup when the block store was restored from disk, as the UTXO set may lag behind (e.g. after a crash).
+// Does nothing if both are in sync. Must be called before any new blocks are handled.
+func (b *Blockchain) ConnectStoredChain() error {
+	tip := b.blockStore.GetMainChainTip()
+	_, err := b.chainReorganization.CheckAndReorganize(tip.Hash())
+	return err
+}
+
 // CheckPeerIsConnected checks if the peer with the given ID exists and is in the connected state.
 // Should be used at the beginning of message handlers to validate the peer.
 func (b *Blockchain) CheckPeerIsConnected(peerID common.PeerId) bool {
@@ -2,7 +2,10 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/utxo"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/validation"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/blockchain"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/inv"
@@ -97,6 +100,17 @@ func (m *mockErrorMsgSender) SendReject(peerId common.PeerId, errorType int32, r
 	m.lastData = data
 }
 
+type mockMisbehaviorReporter struct {
+	scores map[common.PeerId]int
+}
+
+func (m *mockMisbehaviorReporter) ReportMisbehavior(peerID common.PeerId, score int, _ string) {
+	if m.scores == nil {
+		m.scores = make(map[common.PeerId]int)
+	}
+	m.scores[peerID] += score
+}
+
 // mockBlockValidator is a mock for validation.BlockValidationAPI
 type mockBlockValidator struct {
 	sanityCheckResult    bool
@@ -145,6 +159,12 @@ type mockBlockStore struct {
 
 	// Configurable return for GetBlockByHash (to simulate block not found)
 	getBlockByHashErr error
+
+	// If set, an added block becomes the main chain tip
+	addBlockBecomesTip bool
+
+	// Hashes of the blocks marked invalid
+	markedInvalid []common.Hash
 }
 
 func (m *mockBlockStore) GetBlockByHash(_ common.Hash) (block.Block, error) {
@@ -156,6 +176,9 @@ func (m *mockBlockStore) GetBlockByHash(_ common.Hash) (block.Block, error) {
 
 func (m *mockBlockStore) AddBlock(b block.Block) []common.Hash {
 	m.addedBlocks = append(m.addedBlocks, b.Hash())
+	if m.addBlockBecomesTip {
+		m.mainChainTip = b
+	}
 	if m.addBlockReturnValue != nil {
 		return m.addBlockReturnValue
 	}
@@ -175,6 +198,10 @@ func (m *mockBlockStore) GetMainChainHeight() uint64 {
 	return m.currentHeight
 }
 
+func (m *mockBlockStore) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+
 func (m *mockBlockStore) GetMainChainTip() block.Block {
 	m.getMainChainTipCalled = true
 	return m.mainChainTip
@@ -202,6 +229,11 @@ func (m *mockBlockStore) IsBlockInvalid(_ block.Block) (bool, error) {
 	return false, nil
 }
 
+func (m *mockBlockStore) MarkBlockInvalid(hash common.Hash) error {
+	m.markedInvalid = append(m.markedInvalid, hash)
+	return nil
+}
+
 // mockChainReorganization is a mock for ChainReorganization
 type mockChainReorganization struct {
 	checkAndReorganizeResult bool
@@ -240,6 +272,14 @@ func (m *mockBlockStore) IsTransactionAccepted(txID transaction.TransactionID) (
 	return false, nil
 }
 
+func (m *mockBlockStore) GetNextDifficultyTarget(_ common.Hash) (uint8, error) {
+	return 0, nil
+}
+
+func (m *mockBlockStore) GetMedianTimePast(_ common.Hash) (int64, error) {
+	return 0, nil
+}
+
 type mockUtxoStoreAPI struct{}
 
 func (a mockUtxoStoreAPI) ValidateTransactionsOfBlock(blockToValidate block.Block) bool {
@@ -254,9 +294,19 @@ func (mockUtxoStoreAPI) InitializeGenesisPool(_ block.Block) error {
 func (mockUtxoStoreAPI) AddNewBlock(_ block.Block) error {
 	return nil
 }
+func (mockUtxoStoreAPI) DisconnectBlock(_ block.Block) error {
+	return nil
+}
+func (mockUtxoStoreAPI) GetChainTip() common.Hash {
+	genesis := blockchain.GenesisBlock()
+	return genesis.Hash()
+}
 func (mockUtxoStoreAPI) GetUtxoFromBlock(_ transaction.TransactionID, _ uint32, _ common.Hash) (transaction.Output, error) {
 	return transaction.Output{}, nil
 }
+func (mockUtxoStoreAPI) GetUtxoEntryFromBlock(_ transaction.TransactionID, _ uint32, _ common.Hash) (transaction.UTXO, error) {
+	return transaction.UTXO{}, nil
+}
 func (mockUtxoStoreAPI) ValidateBlock(_ block.Block) bool {
 	return true
 }
@@ -266,6 +316,12 @@ func (mockUtxoStoreAPI) ValidateTransactionFromBlock(_ transaction.Transaction,
 func (mockUtxoStoreAPI) GetUtxosByPubKeyHashFromBlock(_ transaction.PubKeyHash, _ common.Hash) ([]transaction.UTXO, error) {
 	return []transaction.UTXO{}, nil
 }
+func (mockUtxoStoreAPI) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+func (mockUtxoStoreAPI) GetAncestorMedianTimePast(_ common.Hash, _ uint64) (int64, error) {
+	return 0, nil
+}
 
 func TestBlockchain_Inv_InvokesRequestDataByCallingSendGetData(t *testing.T) {
 	// Arrange: create blockchain with mocked sender
@@ -275,7 +331,7 @@ func TestBlockchain_Inv_InvokesRequestDataByCallingSendGetData(t *testing.T) {
 
 	peerID := common.PeerId("peer-1")
 	peerRetriever.AddPeer(peerID, &common.Peer{State: common.StateConnected})
-	bc := NewBlockchain(sender, nil, errorMsgSender, &mockBlockValidator{
+	bc := NewBlockchain(sender, nil, errorMsgSender, &mockMisbehaviorReporter{}, &mockBlockValidator{
 		sanityCheckResult:    true,
 		validateHeaderResult: true,
 		fullValidationResult: true,
@@ -333,6 +389,7 @@ func TestBlockchain_Block_SanityCheckFailure(t *testing.T) {
 	}
 	reorg := &mockChainReorganization{}
 	errorMsgSender := &mockErrorMsgSender{}
+	misbehaviorReporter := &mockMisbehaviorReporter{}
 	peerRetriever := newMockPeerRetriever()
 	peerID := common.PeerId("peer-1")
 	peerRetriever.AddPeer(peerID, &common.Peer{State: common.StateConnected})
@@ -344,6 +401,7 @@ func TestBlockchain_Block_SanityCheckFailure(t *testing.T) {
 		chainReorganization: reorg,
 		peerRetriever:       peerRetriever,
 		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: misbehaviorReporter,
 	}
 
 	testBlock := createTestBlock(common.Hash{}, 123)
@@ -360,6 +418,7 @@ func TestBlockchain_Block_SanityCheckFailure(t *testing.T) {
 	assert.False(t, store.isOrphanCalled, "IsOrphanBlock should not be called when SanityCheck fails")
 	assert.False(t, reorg.checkAndReorganizeCalled, "CheckAndReorganize should not be called when SanityCheck fails")
 	assert.False(t, sender.broadcastAddedBlocksCalled, "BroadcastAddedBlocks should not be called when SanityCheck fails")
+	assert.Equal(t, common.MisbehaviorMalformedBlock, misbehaviorReporter.scores[peerID], "the peer should be scored for the SanityCheck failure")
 }
 
 // TestBlockchain_Block_ValidateHeaderFailure verifies that when ValidateHeader fails,
@@ -368,6 +427,7 @@ func TestBlockchain_Block_ValidateHeaderFailure(t *testing.T) {
 	// Arrange
 	sender := &mockBlockchainSender{}
 	errorMsgSender := &mockErrorMsgSender{}
+	misbehaviorReporter := &mockMisbehaviorReporter{}
 	validator := &mockBlockValidator{
 		sanityCheckResult:    true,
 		validateHeaderResult: false,
@@ -389,6 +449,7 @@ func TestBlockchain_Block_ValidateHeaderFailure(t *testing.T) {
 		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
 		peerRetriever:       peerRetriever,
 		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: misbehaviorReporter,
 	}
 
 	testBlock := createTestBlock(common.Hash{}, 123)
@@ -405,6 +466,79 @@ func TestBlockchain_Block_ValidateHeaderFailure(t *testing.T) {
 	assert.False(t, store.isOrphanCalled, "IsOrphanBlock should not be called when ValidateHeader fails")
 	assert.False(t, reorg.checkAndReorganizeCalled, "CheckAndReorganize should not be called when ValidateHeader fails")
 	assert.False(t, sender.broadcastAddedBlocksCalled, "BroadcastAddedBlocks should not be called when ValidateHeader fails")
+	assert.Equal(t, common.MisbehaviorInvalidBlockHeader, misbehaviorReporter.scores[peerID], "the peer should be scored for the ValidateHeader failure")
+}
+
+// TestBlockchain_Block_TimestampTooFarInFutureIsNotScored verifies that a block rejected only because of
+// the local clock is not scored, an honest peer may relay it.
+func TestBlockchain_Block_TimestampTooFarInFutureIsNotScored(t *testing.T) {
+	// Arrange
+	errorMsgSender := &mockErrorMsgSender{}
+	misbehaviorReporter := &mockMisbehaviorReporter{}
+	validator := &mockBlockValidator{
+		sanityCheckResult:    true,
+		validateHeaderResult: false,
+		validateHeaderErr:    validation.ErrTimestampTooFarInFuture,
+	}
+	store := &mockBlockStore{
+		getBlockByHashErr: errors.New("block not found"),
+	}
+	peerRetriever := newMockPeerRetriever()
+	peerID := common.PeerId("peer-2")
+	peerRetriever.AddPeer(peerID, &common.Peer{State: common.StateConnected})
+
+	bc := &Blockchain{
+		blockchainMsgSender: &mockBlockchainSender{},
+		blockValidator:      validator,
+		blockStore:          store,
+		chainReorganization: &mockChainReorganization{},
+		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
+		peerRetriever:       peerRetriever,
+		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: misbehaviorReporter,
+	}
+
+	// Act
+	bc.Block(createTestBlock(common.Hash{}, 123), peerID)
+
+	// Assert
+	assert.True(t, errorMsgSender.sendRejectCalled, "the block should still be rejected")
+	assert.False(t, store.isOrphanCalled, "the block should not be processed further")
+	assert.Zero(t, misbehaviorReporter.scores[peerID], "a timestamp too far in the future must not be scored")
+}
+
+// TestBlockchain_Headers_TimestampTooFarInFutureIsNotScored verifies that headers rejected only because of
+// the local clock are not scored.
+func TestBlockchain_Headers_TimestampTooFarInFutureIsNotScored(t *testing.T) {
+	// Arrange
+	sender := &mockBlockchainSender{}
+	errorMsgSender := &mockErrorMsgSender{}
+	misbehaviorReporter := &mockMisbehaviorReporter{}
+	validator := &mockBlockValidator{
+		validateHeaderResult: false,
+		validateHeaderErr:    validation.ErrTimestampTooFarInFuture,
+	}
+	peerRetriever := newMockPeerRetriever()
+	peerID := common.PeerId("peer-2")
+	peerRetriever.AddPeer(peerID, &common.Peer{State: common.StateConnected})
+
+	bc := &Blockchain{
+		blockchainMsgSender: sender,
+		blockValidator:      validator,
+		blockStore:          &mockBlockStore{},
+		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
+		peerRetriever:       peerRetriever,
+		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: misbehaviorReporter,
+	}
+	header := createTestBlock(common.Hash{}, 123).Header
+
+	// Act
+	bc.Headers([]*block.BlockHeader{&header}, peerID)
+
+	// Assert
+	assert.True(t, errorMsgSender.sendRejectCalled, "the header should still be rejected")
+	assert.Zero(t, misbehaviorReporter.scores[peerID], "a timestamp too far in the future must not be scored")
 }
 
 // TestBlockchain_Block_IsOrphanRequestsMissingHeaders verifies that when a block
@@ -447,6 +581,7 @@ func TestBlockchain_Block_IsOrphanRequestsMissingHeaders(t *testing.T) {
 		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
 		peerRetriever:       peerRetriever,
 		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: &mockMisbehaviorReporter{},
 	}
 
 	// Create a block with a non-zero parent hash (simulating an orphan)
@@ -499,6 +634,7 @@ func TestBlockchain_Block_FullValidationFailure(t *testing.T) {
 	peerRetriever := newMockPeerRetriever()
 	peerID := common.PeerId("peer-invalid")
 	peerRetriever.AddPeer(peerID, &common.Peer{State: common.StateConnected})
+	misbehaviorReporter := &mockMisbehaviorReporter{}
 
 	bc := &Blockchain{
 		blockchainMsgSender: sender,
@@ -509,6 +645,7 @@ func TestBlockchain_Block_FullValidationFailure(t *testing.T) {
 		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
 		peerRetriever:       peerRetriever,
 		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: misbehaviorReporter,
 	}
 
 	testBlock := createTestBlock(common.Hash{}, 123)
@@ -525,6 +662,54 @@ func TestBlockchain_Block_FullValidationFailure(t *testing.T) {
 	// Assert: No reorganization or broadcasting for invalid blocks
 	assert.False(t, reorg.checkAndReorganizeCalled, "CheckAndReorganize should not be called when FullValidation fails")
 	assert.False(t, sender.broadcastAddedBlocksCalled, "BroadcastAddedBlocks should not be called when FullValidation fails")
+
+	// Assert: The peer is blamed for the invalid block and the block is marked invalid
+	assert.Equal(t, common.MisbehaviorInvalidBlock, misbehaviorReporter.scores[peerID])
+	assert.Equal(t, []common.Hash{testBlock.Hash()}, store.markedInvalid)
+}
+
+// TestBlockchain_Block_FullValidationLookupFailureIsNotScored verifies that a block failing only because
+// the UTXO set of its previous block is not reachable is neither scored nor marked invalid, e.g. a block of a side chain.
+// It is validated again once the reorganization connects it.
+func TestBlockchain_Block_FullValidationLookupFailureIsNotScored(t *testing.T) {
+	// Arrange
+	validator := &mockBlockValidator{
+		sanityCheckResult:    true,
+		validateHeaderResult: true,
+		fullValidationResult: false,
+		fullValidationErr:    fmt.Errorf("failed to validate transaction 0: %w", validation.ErrUTXONotFound),
+	}
+	store := &mockBlockStore{
+		isOrphanResult:    false,
+		mainChainTip:      createTestBlock(common.Hash{}, 1),
+		getBlockByHashErr: errors.New("block not found"),
+	}
+	reorg := &mockChainReorganization{}
+	misbehaviorReporter := &mockMisbehaviorReporter{}
+	peerRetriever := newMockPeerRetriever()
+	peerID := common.PeerId("peer-side-chain")
+	peerRetriever.AddPeer(peerID, &common.Peer{State: common.StateConnected})
+
+	bc := &Blockchain{
+		blockchainMsgSender: &mockBlockchainSender{},
+		blockValidator:      validator,
+		blockStore:          store,
+		chainReorganization: reorg,
+		mempool:             NewMempool(nil, nil),
+		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
+		peerRetriever:       peerRetriever,
+		errorMsgSender:      &mockErrorMsgSender{},
+		misbehaviorReporter: misbehaviorReporter,
+	}
+
+	// Act
+	bc.Block(createTestBlock(common.Hash{}, 123), peerID)
+
+	// Assert
+	assert.True(t, validator.fullValidationCalled, "FullValidation should be called")
+	assert.True(t, reorg.checkAndReorganizeCalled, "CheckAndReorganize should be called for a block on a side chain")
+	assert.Zero(t, misbehaviorReporter.scores[peerID], "a missing UTXO set must not be scored")
+	assert.Empty(t, store.markedInvalid, "a missing UTXO set must not mark the block invalid")
 }
 
 // TestBlockchain_Block_SuccessfulProcessing verifies that a valid block
@@ -560,6 +745,7 @@ func TestBlockchain_Block_SuccessfulProcessing(t *testing.T) {
 		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
 		peerRetriever:       peerRetriever,
 		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: &mockMisbehaviorReporter{},
 	}
 
 	testBlock := createTestBlock(common.Hash{}, 123)
@@ -586,6 +772,59 @@ func TestBlockchain_Block_SuccessfulProcessing(t *testing.T) {
 	assert.Equal(t, peerID, sender.broadcastAddedBlocksExcluded, "Excluded peer should match sender")
 }
 
+// mockTipObserver records the tips it was notified about.
+type mockTipObserver struct {
+	tips []common.Hash
+}
+
+func (m *mockTipObserver) TipChanged(tip common.Hash) {
+	m.tips = append(m.tips, tip)
+}
+
+// TestBlockchain_Block_NotifiesTipObservers verifies that the tip observers are notified once the block became the new tip,
+// but not for a block that leaves the tip unchanged.
+func TestBlockchain_Block_NotifiesTipObservers(t *testing.T) {
+	// Arrange
+	validator := &mockBlockValidator{
+		sanityCheckResult:    true,
+		validateHeaderResult: true,
+		fullValidationResult: true,
+	}
+	store := &mockBlockStore{
+		mainChainTip:       createTestBlock(common.Hash{}, 1),
+		getBlockByHashErr:  errors.New("block not found"),
+		addBlockBecomesTip: true,
+	}
+	peerRetriever := newMockPeerRetriever()
+	peerID := common.PeerId("peer-tip")
+	peerRetriever.AddPeer(peerID, &common.Peer{State: common.StateConnected})
+
+	bc := &Blockchain{
+		blockchainMsgSender: &mockBlockchainSender{},
+		blockValidator:      validator,
+		blockStore:          store,
+		chainReorganization: &mockChainReorganization{},
+		mempool:             NewMempool(nil, &mockBlockStore{}),
+		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
+		tipObservers:        mapset.NewSet[observer.ChainTipObserverAPI](),
+		peerRetriever:       peerRetriever,
+		errorMsgSender:      &mockErrorMsgSender{},
+		misbehaviorReporter: &mockMisbehaviorReporter{},
+	}
+	tipObserver := &mockTipObserver{}
+	bc.AttachTipObserver(tipObserver)
+
+	newTip := createTestBlock(common.Hash{}, 2)
+
+	// Act
+	bc.Block(newTip, peerID)
+	store.addBlockBecomesTip = false
+	bc.Block(createTestBlock(common.Hash{}, 3), peerID)
+
+	// Assert
+	assert.Equal(t, []common.Hash{newTip.Hash()}, tipObserver.tips, "tip observers should be notified only about the new tip")
+}
+
 // TestBlockchain_Block_WithChainReorganization verifies that when reorganization
 // occurs, the block is still broadcast correctly.
 func TestBlockchain_Block_WithChainReorganization(t *testing.T) {
@@ -619,6 +858,7 @@ func TestBlockchain_Block_WithChainReorganization(t *testing.T) {
 		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
 		peerRetriever:       peerRetriever,
 		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: &mockMisbehaviorReporter{},
 	}
 
 	testBlock := createTestBlock(common.Hash{}, 123)
@@ -672,6 +912,7 @@ func TestBlockchain_Block_AddedBlocksBroadcast(t *testing.T) {
 		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
 		peerRetriever:       peerRetriever,
 		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: &mockMisbehaviorReporter{},
 	}
 
 	// Act
@@ -715,6 +956,7 @@ func TestBlockchain_Block_ExcludedPeerInBroadcast(t *testing.T) {
 		observers:           mapset.NewSet[observer.BlockchainObserverAPI](),
 		peerRetriever:       peerRetriever,
 		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: &mockMisbehaviorReporter{},
 	}
 
 	testBlock := createTestBlock(common.Hash{}, 123)
@@ -1,12 +1,22 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/utxo"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/validation"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/blockchain"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+
+	"bjoernblessin.de/go-utils/util/logger"
 )
 
+// errBlockFailedValidation indicates that a block of the new chain failed full validation when it was connected.
+// The block is marked invalid in the block store then.
+var errBlockFailedValidation = errors.New("block failed full validation")
+
 // ChainReorganizationAPI defines the interface for chain reorganization handling.
 // This is afaik the only place, where the tx-mempool is directly manipulated
 type ChainReorganizationAPI interface {
@@ -19,24 +29,27 @@ type ChainReorganizationAPI interface {
 	CheckAndReorganize(newTip common.Hash) (bool, error)
 }
 
+// ChainReorganization moves the UTXO set (and mempool) to a new main chain tip.
+// The last known tip is the chain tip of the UTXO set, so there is a single source of truth that also survives restarts.
+// Every block is fully validated right before it is connected, as only then the UTXO set of its previous block is available for sure.
 type ChainReorganization struct {
-	lastKnownTip common.Hash
-	blockStore   blockchain.BlockStoreAPI
-	utxoService  utxo.UtxoStoreAPI
-	mempool      *Mempool
+	blockStore     blockchain.BlockStoreAPI
+	utxoService    utxo.UtxoStoreAPI
+	mempool        *Mempool
+	blockValidator validation.BlockValidationAPI
 }
 
 func NewChainReorganization(
 	blockStore blockchain.BlockStoreAPI,
 	utxoService utxo.UtxoStoreAPI,
 	mempool *Mempool,
-	genesisBlock common.Hash,
+	blockValidator validation.BlockValidationAPI,
 ) *ChainReorganization {
 	return &ChainReorganization{
-		blockStore:   blockStore,
-		utxoService:  utxoService,
-		mempool:      mempool,
-		lastKnownTip: genesisBlock,
+		blockStore:     blockStore,
+		utxoService:    utxoService,
+		mempool:        mempool,
+		blockValidator: blockValidator,
 	}
 }
 
@@ -46,10 +59,30 @@ func NewChainReorganization(
 // Returns false if no change occurred or if the new blocks are a simple extension of the current chain.
 // This includes updating the UTXO set and mempool accordingly in both cases.
 // This is usually called after one or more blocks are added to the block store.
+//
+// If a block of the new chain fails full validation, it is marked invalid and the UTXO set is moved to the best remaining tip instead.
 func (cr *ChainReorganization) CheckAndReorganize(newTip common.Hash) (bool, error) {
+	reorganized, err := cr.reorganize(newTip)
+
+	// Each round marks another block invalid, so this ends once a chain of valid blocks is connected
+	for errors.Is(err, errBlockFailedValidation) {
+		logger.Warnf("[chain_reorganization] %v, moving to the best remaining chain", err)
+
+		tip := cr.blockStore.GetMainChainTip()
+		var reorganizedAgain bool
+		reorganizedAgain, err = cr.reorganize(tip.Hash())
+		reorganized = reorganized || reorganizedAgain
+	}
+
+	return reorganized, err
+}
+
+// reorganize moves the UTXO set and mempool to the given tip, see CheckAndReorganize.
+func (cr *ChainReorganization) reorganize(newTip common.Hash) (bool, error) {
+	lastKnownTip := cr.utxoService.GetChainTip()
 
 	// No change, no reorganization needed
-	if cr.lastKnownTip == newTip {
+	if lastKnownTip == newTip {
 		return false, nil
 	}
 
@@ -62,25 +95,24 @@ func (cr *ChainReorganization) CheckAndReorganize(newTip common.Hash) (bool, err
 
 	// Case 1: Simple chain extension - NOT a reorganization
 	// The new block(s) build directly on top of our current tip
-	if newBlock.Header.PreviousBlockHash == cr.lastKnownTip {
+	if newBlock.Header.PreviousBlockHash == lastKnownTip {
 		// Just apply the new block(s) to UTXO set and mempool
-		err := cr.connectNewChain(newTip)
+		err := cr.connectNewChain(lastKnownTip, newTip)
 		if err != nil {
 			return false, err
 		}
-		cr.updateLastKnownTip(newTip)
 		return false, nil // NO reorg occurred, just chain extension
 	}
 
 	// Case 2: Reorganization needed - the new chain diverges from our current chain
 	// Find the fork point
-	forkPoint, err := cr.findForkPoint(cr.lastKnownTip, newTip)
+	forkPoint, err := cr.findForkPoint(lastKnownTip, newTip)
 	if err != nil {
 		return false, err
 	}
 
 	// Phase 1: Disconnect old chain
-	disconnectedBlocks, err := cr.disconnectBlocks(cr.lastKnownTip, forkPoint)
+	disconnectedBlocks, disconnectedTransactions, err := cr.disconnectBlocks(lastKnownTip, forkPoint)
 	if err != nil {
 		return false, err
 	}
@@ -91,19 +123,17 @@ func (cr *ChainReorganization) CheckAndReorganize(newTip common.Hash) (bool, err
 		return false, err
 	}
 
-	err = cr.connectBlocks(newChainPath)
-	if err != nil {
-		return false, err
-	}
+	// The old chain is disconnected already, so the mempool is updated even if not all blocks of the new chain could be connected
+	connectedBlocks, err := cr.connectBlocks(newChainPath)
 
 	// Cleanup mempool - remove confirmed transactions and re-validate remaining ones
-	allAffectedBlocks := append(disconnectedBlocks, cr.blockHashesFromBlocks(newChainPath)...)
+	allAffectedBlocks := append(disconnectedBlocks, cr.blockHashesFromBlocks(connectedBlocks)...)
 	cr.cleanMempool(allAffectedBlocks)
 
-	// Update the tip
-	cr.updateLastKnownTip(newTip)
+	// Transactions of the old chain that are still valid on the new chain go back to the mempool
+	cr.moveTransactionsToMempool(disconnectedTransactions)
 
-	return true, nil // Reorg occurred
+	return true, err // Reorg occurred
 }
 
 // =========================================================================
@@ -158,59 +188,54 @@ func (cr *ChainReorganization) findForkPoint(oldTip, newTip common.Hash) (common
 }
 
 // disconnectBlocks rolls back blocks from the current tip to the fork point.
-// Returns the list of disconnected block hashes.
-func (cr *ChainReorganization) disconnectBlocks(fromTip, toForkPoint common.Hash) ([]common.Hash, error) {
+// Returns the list of disconnected block hashes and the non-coinbase transactions of these blocks.
+// The transactions are ordered as in the chain (oldest block first), so parents precede their children.
+func (cr *ChainReorganization) disconnectBlocks(fromTip, toForkPoint common.Hash) ([]common.Hash, []transaction.Transaction, error) {
 	disconnectedHashes := make([]common.Hash, 0)
+	disconnectedTransactions := make([]transaction.Transaction, 0)
 	currentHash := fromTip
 
 	// Walk backwards from tip to fork point
 	for currentHash != toForkPoint {
+		currentBlock, err := cr.blockStore.GetBlockByHash(currentHash)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		// Disconnect the current block
-		err := cr.disconnectBlock(currentHash)
+		err = cr.disconnectBlock(currentBlock)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		disconnectedHashes = append(disconnectedHashes, currentHash)
-
-		// Move to previous block
-		currentBlock, err := cr.blockStore.GetBlockByHash(currentHash)
-		if err != nil {
-			return nil, err
+		blockTransactions := make([]transaction.Transaction, 0, len(currentBlock.Transactions))
+		for _, tx := range currentBlock.Transactions {
+			// Skip coinbase transactions (they are block-specific and cannot be in mempool)
+			if !tx.IsCoinbase() {
+				blockTransactions = append(blockTransactions, tx)
+			}
 		}
+		// Prepend, as the blocks are disconnected from the tip backwards
+		disconnectedTransactions = append(blockTransactions, disconnectedTransactions...)
 
+		// Move to previous block
 		currentHash = currentBlock.Header.PreviousBlockHash
 	}
 
-	return disconnectedHashes, nil
+	return disconnectedHashes, disconnectedTransactions, nil
 }
 
-// disconnectBlock performs rollback operations for a single block:
-// - Moves transactions back to mempool
-// Note: With the new UTXO store design, each block maintains its own immutable UTXO pool snapshot,
-// so there's no need to revert UTXO state changes. The previous block's UTXO pool is still available.
-func (cr *ChainReorganization) disconnectBlock(blockHash common.Hash) error {
-	blk, err := cr.blockStore.GetBlockByHash(blockHash)
-	if err != nil {
-		return err
-	}
-
-	// Move transactions back to mempool
-	cr.moveTransactionsToMempool(blk)
-
-	return nil
+// disconnectBlock reverts the UTXO set changes of a single block by replaying its undo record.
+func (cr *ChainReorganization) disconnectBlock(blk block.Block) error {
+	return cr.utxoService.DisconnectBlock(blk)
 }
 
-// moveTransactionsToMempool moves all non-coinbase transactions from a block
-// back to the mempool.
-func (cr *ChainReorganization) moveTransactionsToMempool(blk block.Block) {
-	for _, tx := range blk.Transactions {
-		// Skip coinbase transactions (they are block-specific and cannot be in mempool)
-		if tx.IsCoinbase() {
-			continue
-		}
-
-		// Add transaction to mempool (if it's still valid, it will be accepted)
+// moveTransactionsToMempool adds the transactions of disconnected blocks back to the mempool.
+// Transactions that are invalid on the new chain (e.g. because they were included again or conflict with it) are dropped by the mempool.
+// The transactions must be ordered parents first, so children can spend the outputs of their re-added parents.
+func (cr *ChainReorganization) moveTransactionsToMempool(transactions []transaction.Transaction) {
+	for _, tx := range transactions {
 		cr.mempool.AddTransaction(tx)
 	}
 }
@@ -222,13 +247,13 @@ func (cr *ChainReorganization) moveTransactionsToMempool(blk block.Block) {
 // connectNewChain applies new blocks that extend the current chain.
 // This is NOT a reorganization - the new blocks build directly on top of the current tip.
 // This method is called when newTip.PreviousBlockHash == lastKnownTip.
-func (cr *ChainReorganization) connectNewChain(newTip common.Hash) error {
+func (cr *ChainReorganization) connectNewChain(lastKnownTip, newTip common.Hash) error {
 	// Walk backwards from new tip to lastKnownTip, collecting blocks
 	// We need to collect them first so we can apply them in forward order
 	var blocksToApply []block.Block
 	currentHash := newTip
 
-	for currentHash != cr.lastKnownTip {
+	for currentHash != lastKnownTip {
 		blk, err := cr.blockStore.GetBlockByHash(currentHash)
 		if err != nil {
 			return err
@@ -274,22 +299,45 @@ func (cr *ChainReorganization) getNewChainPath(forkPoint, newTip common.Hash) ([
 }
 
 // connectBlocks applies blocks from the new chain path.
-func (cr *ChainReorganization) connectBlocks(blocks []block.Block) error {
-	for _, blk := range blocks {
+// Returns the blocks that were connected, which are all blocks unless an error is returned.
+func (cr *ChainReorganization) connectBlocks(blocks []block.Block) ([]block.Block, error) {
+	for i, blk := range blocks {
 		err := cr.connectBlock(blk)
 		if err != nil {
-			return err
+			return blocks[:i], err
 		}
 	}
-	return nil
+	return blocks, nil
 }
 
 // connectBlock performs operations to apply a single block:
+// - Refuses blocks marked invalid by the block store
+// - Fully validates the block against the UTXO set of its previous block, which is the current tip of the UTXO set,
+// and marks it invalid if validation fails
 // - Applies transactions (updates UTXO set)
 // - Removes confirmed transactions from mempool
 func (cr *ChainReorganization) connectBlock(blk block.Block) error {
+	isInvalid, err := cr.blockStore.IsBlockInvalid(blk)
+	if err != nil {
+		return err
+	}
+	if isInvalid {
+		return fmt.Errorf("block %v is marked invalid and can't be connected", blk.Hash())
+	}
+
+	if ok, err := cr.blockValidator.FullValidation(blk); !ok {
+		// A lookup error doesn't prove the block invalid, e.g. the UTXO set may be unavailable, so the block is kept for a later attempt
+		if validation.IsLookupError(err) {
+			return fmt.Errorf("block %v can't be validated: %w", blk.Hash(), err)
+		}
+		if markErr := cr.blockStore.MarkBlockInvalid(blk.Hash()); markErr != nil {
+			logger.Errorf("[chain_reorganization] %v", markErr)
+		}
+		return fmt.Errorf("%w: block %v: %v", errBlockFailedValidation, blk.Hash(), err)
+	}
+
 	// Apply the block's transactions to the UTXO set
-	err := cr.applyBlock(blk)
+	err = cr.applyBlock(blk)
 	if err != nil {
 		return err
 	}
@@ -327,8 +375,3 @@ func (cr *ChainReorganization) blockHashesFromBlocks(blocks []block.Block) []com
 	}
 	return hashes
 }
-
-// updateLastKnownTip updates the last known chain tip.
-func (cr *ChainReorganization) updateLastKnownTip(newTip common.Hash) {
-	cr.lastKnownTip = newTip
-}
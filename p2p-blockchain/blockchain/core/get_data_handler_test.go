@@ -10,6 +10,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,6 +42,14 @@ func (m *mockUtxoStoreForGetData) AddNewBlock(_ block.Block) error {
 	return nil
 }
 
+func (m *mockUtxoStoreForGetData) DisconnectBlock(_ block.Block) error {
+	return nil
+}
+
+func (m *mockUtxoStoreForGetData) GetChainTip() common.Hash {
+	return common.Hash{}
+}
+
 func (m *mockUtxoStoreForGetData) GetUtxoFromBlock(txID transaction.TransactionID, outputIndex uint32, _ common.Hash) (transaction.Output, error) {
 	key := string(txID[:]) + string(rune(outputIndex))
 	if output, ok := m.utxos[key]; ok {
@@ -49,6 +58,11 @@ func (m *mockUtxoStoreForGetData) GetUtxoFromBlock(txID transaction.TransactionI
 	return transaction.Output{}, errors.New("UTXO not found")
 }
 
+func (m *mockUtxoStoreForGetData) GetUtxoEntryFromBlock(txID transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.UTXO, error) {
+	output, err := m.GetUtxoFromBlock(txID, outputIndex, blockHash)
+	return transaction.UTXO{TxID: txID, OutputIndex: outputIndex, Output: output}, err
+}
+
 func (m *mockUtxoStoreForGetData) ValidateBlock(_ block.Block) bool {
 	return true
 }
@@ -61,6 +75,14 @@ func (m *mockUtxoStoreForGetData) GetUtxosByPubKeyHashFromBlock(_ transaction.Pu
 	return []transaction.UTXO{}, nil
 }
 
+func (m *mockUtxoStoreForGetData) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+
+func (m *mockUtxoStoreForGetData) GetAncestorMedianTimePast(_ common.Hash, _ uint64) (int64, error) {
+	return 0, nil
+}
+
 type mockBlockchainMsgSender struct {
 	mu              sync.RWMutex
 	sendBlockCalled bool
@@ -136,6 +158,10 @@ func (m *mockBlockStoreGetData) GetMainChainHeight() uint64 {
 	return 0
 }
 
+func (m *mockBlockStoreGetData) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+
 func (m *mockBlockStoreGetData) GetMainChainTip() block.Block {
 	return block.Block{}
 }
@@ -156,6 +182,10 @@ func (m *mockBlockStoreGetData) IsBlockInvalid(_ block.Block) (bool, error) {
 	return false, nil
 }
 
+func (m *mockBlockStoreGetData) MarkBlockInvalid(_ common.Hash) error {
+	return nil
+}
+
 func createTestBlockForGetData(nonce uint32) block.Block {
 	var merkleRoot common.Hash
 	for i := range 32 {
@@ -183,30 +213,45 @@ func (m *mockBlockStoreGetData) IsTransactionAccepted(txID transaction.Transacti
 	return false, nil
 }
 
-func createTestTransactionForGetData() transaction.Transaction {
-	// Create a PubKey for the transaction input
-	pubKey := transaction.PubKey{0x03, 0x04}
+func (m *mockBlockStoreGetData) GetNextDifficultyTarget(_ common.Hash) (uint8, error) {
+	return 0, nil
+}
 
-	// The PubKeyHash should be HASH160(pubKey) for validation to pass
-	// For testing, we'll compute it properly
-	pubKeyHash := transaction.Hash160(pubKey)
+func (m *mockBlockStoreGetData) GetMedianTimePast(_ common.Hash) (int64, error) {
+	return 0, nil
+}
 
-	return transaction.Transaction{
+func createTestTransactionForGetData() transaction.Transaction {
+	txID, outputIndex, output := getTestUtxoForTransaction()
+
+	tx := transaction.Transaction{
 		Inputs: []transaction.Input{
 			{
-				PrevTxID:    transaction.TransactionID{1, 2, 3, 4, 5},
-				OutputIndex: 0,
-				Signature:   []byte{0x01, 0x02},
-				PubKey:      pubKey,
+				PrevTxID:    txID,
+				OutputIndex: outputIndex,
 			},
 		},
 		Outputs: []transaction.Output{
 			{
 				Value:      50, // Less than the input value (100) to account for fee
-				PubKeyHash: pubKeyHash,
+				PubKeyHash: output.PubKeyHash,
 			},
 		},
 	}
+
+	// The mempool verifies the signatures, so the transaction is signed with the key owning the UTXO
+	utxo := transaction.UTXO{TxID: txID, OutputIndex: outputIndex, Output: output}
+	if err := tx.Sign(testPrivateKeyForGetData(), []transaction.UTXO{utxo}); err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+// testPrivateKeyForGetData returns the private key owning the UTXO of getTestUtxoForTransaction.
+func testPrivateKeyForGetData() transaction.PrivateKey {
+	var privateKey transaction.PrivateKey
+	privateKey[31] = 1
+	return privateKey
 }
 
 // mockPeerRetrieverForGetData is a mock for the peerRetriever interface that always returns a connected peer
@@ -222,14 +267,16 @@ func (m *mockPeerRetrieverForGetData) GetPeer(_ common.PeerId) (*common.Peer, bo
 
 // Helper function to get the expected UTXO for the test transaction
 func getTestUtxoForTransaction() (transaction.TransactionID, uint32, transaction.Output) {
-	pubKey := transaction.PubKey{0x03, 0x04}
-	pubKeyHash := transaction.Hash160(pubKey)
+	privateKey := testPrivateKeyForGetData()
+	_, publicKey := btcec.PrivKeyFromBytes(privateKey[:])
+	var pubKey transaction.PubKey
+	copy(pubKey[:], publicKey.SerializeCompressed())
 
 	txID := transaction.TransactionID{1, 2, 3, 4, 5}
 	outputIndex := uint32(0)
 	output := transaction.Output{
 		Value:      100,
-		PubKeyHash: pubKeyHash,
+		PubKeyHash: transaction.Hash160(pubKey),
 	}
 
 	return txID, outputIndex, output
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"errors"
+	"fmt"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/validation"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/inv"
@@ -24,12 +27,17 @@ func (b *Blockchain) Headers(blockHeaders []*block.BlockHeader, peerID common.Pe
 	}
 
 	unknownValidHeaders := make([]*inv.InvVector, 0)
+	invalidHeaders := 0
 
 	for i, header := range blockHeaders {
 		if ok, err := b.blockValidator.ValidateHeaderOnly(*header); !ok {
 			logger.Warnf("[headers_handler] Invalid header at index %d from %v: %v", i, peerID, err)
 			headerHash := header.Hash()
 			b.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "headers", headerHash[:])
+			// A timestamp too far in the future depends on the local clock, the peer is not to blame for it
+			if !errors.Is(err, validation.ErrTimestampTooFarInFuture) {
+				invalidHeaders++
+			}
 			continue
 		}
 
@@ -44,6 +52,11 @@ func (b *Blockchain) Headers(blockHeaders []*block.BlockHeader, peerID common.Pe
 		})
 	}
 
+	// A Headers message is scored once, the invalid headers of a batch usually share the same cause
+	if invalidHeaders > 0 {
+		b.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorInvalidBlockHeader, fmt.Sprintf("%d invalid headers", invalidHeaders))
+	}
+
 	if len(unknownValidHeaders) > 0 {
 		logger.Infof("[headers_handler] Requesting %d unknown blocks from %v", len(unknownValidHeaders), peerID)
 		b.blockchainMsgSender.SendGetData(unknownValidHeaders, peerID)
@@ -27,6 +27,7 @@ func TestOnPeerConnected_OutboundSendsGetHeaders(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
 		mockBlockStore,
 		nil,
 		validation.NewTransactionValidator(nil),
@@ -79,6 +80,7 @@ func TestOnPeerConnected_InboundDoesNotTriggerIBD(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
 		mockBlockStore,
 		nil,
 		validation.NewTransactionValidator(nil),
@@ -119,6 +121,7 @@ func TestOnPeerConnected_BlockLocatorUsesFibonacci(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
 		mockBlockStore,
 		nil,
 		validation.NewTransactionValidator(nil),
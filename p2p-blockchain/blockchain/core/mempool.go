@@ -1,34 +1,141 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/validation"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/blockchain"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 
+	"sort"
 	"sync"
+	"time"
 
-	"bjoernblessin.de/go-utils/util/assert"
 	"bjoernblessin.de/go-utils/util/logger"
 )
 
+var (
+	// ErrTransactionTooLarge indicates a transaction that exceeds transaction.MaxTransactionSize.
+	ErrTransactionTooLarge = errors.New("transaction exceeds the maximum transaction size")
+	// ErrFeeRateTooLow indicates a transaction whose fee rate is below the current minimum fee rate of the mempool.
+	ErrFeeRateTooLow = errors.New("transaction fee rate is below the minimum fee rate of the mempool")
+	// ErrMempoolFull indicates a transaction that was evicted right away because its fee rate is the lowest in a full mempool.
+	ErrMempoolFull = errors.New("mempool is full and the transaction fee rate is too low")
+	// ErrTooManyAncestors indicates a transaction with more than maxAncestorCount unconfirmed ancestors (including itself).
+	ErrTooManyAncestors = errors.New("transaction has too many unconfirmed ancestors in the mempool")
+	// ErrTooManyDescendants indicates a transaction that would give one of its unconfirmed ancestors
+	// more than maxDescendantCount descendants (including the ancestor).
+	ErrTooManyDescendants = errors.New("an unconfirmed ancestor of the transaction has too many descendants in the mempool")
+	// ErrNotReplaceable indicates a transaction that conflicts with a mempool transaction not signaling replaceability.
+	ErrNotReplaceable = errors.New("transaction conflicts with a mempool transaction that does not signal replaceability")
+	// ErrTooManyReplacements indicates a replacement that would evict more than maxReplacedCount mempool transactions.
+	ErrTooManyReplacements = errors.New("replacement would evict too many mempool transactions")
+	// ErrReplacementFeeTooLow indicates a replacement that does not pay more than the transactions it replaces, see checkReplacementFee.
+	ErrReplacementFeeTooLow = errors.New("replacement does not pay a higher fee and fee rate than the replaced transactions")
+)
+
+// MempoolConfig contains the limits of the mempool.
+type MempoolConfig struct {
+	// MaxSize is the maximum total serialized size in bytes of all transactions in the mempool.
+	// If the limit is exceeded, the transactions with the lowest fee rate are evicted.
+	MaxSize int
+	// Expiry is the maximum time a transaction stays in the mempool without being mined.
+	Expiry time.Duration
+}
+
+// DefaultMempoolConfig returns the config with common.DefaultMempoolMaxSize and common.DefaultMempoolExpiry.
+func DefaultMempoolConfig() MempoolConfig {
+	return MempoolConfig{
+		MaxSize: common.DefaultMempoolMaxSize,
+		Expiry:  common.DefaultMempoolExpiry,
+	}
+}
+
+// mempoolEntry is a transaction in the mempool together with the data needed for fee rate ordering and expiry.
+// parents are the mempool transactions whose outputs the transaction spends, children the ones spending its outputs.
+// local is set for transactions created by the wallet of this node, those are rebroadcast until they are mined.
+// evictionFee and evictionSize are the cached eviction fee rate, see evictionFeeRate, and evictionIndex the position in the evictionOrder.
+type mempoolEntry struct {
+	id       transaction.TransactionID
+	tx       transaction.Transaction
+	fee      uint64
+	size     int
+	addedAt  time.Time
+	local    bool
+	parents  map[transaction.TransactionID]struct{}
+	children map[transaction.TransactionID]struct{}
+
+	evictionFee   uint64
+	evictionSize  int
+	evictionIndex int
+}
+
+func newMempoolEntry(id transaction.TransactionID, tx transaction.Transaction, fee uint64, addedAt time.Time, local bool) *mempoolEntry {
+	return &mempoolEntry{
+		id:       id,
+		tx:       tx,
+		fee:      fee,
+		size:     tx.SerializedSize(),
+		addedAt:  addedAt,
+		local:    local,
+		parents:  make(map[transaction.TransactionID]struct{}),
+		children: make(map[transaction.TransactionID]struct{}),
+	}
+}
+
+// hasHigherFeeRateThan compares the fee per serialized byte of both entries.
+// Entries with the same fee rate are ordered by age, so older transactions are mined first and evicted last.
+func (e *mempoolEntry) hasHigherFeeRateThan(other *mempoolEntry) bool {
+	if cmp := compareFeeRates(e.fee, e.size, other.fee, other.size); cmp != 0 {
+		return cmp > 0
+	}
+	return e.addedAt.Before(other.addedAt)
+}
+
+// Mempool holds the valid transactions that are not yet part of the main chain.
+// Transactions may spend outputs of other mempool transactions, so the mempool is an overlay on top of the UTXO set
+// at the main chain tip, see mempoolView. The length of such unconfirmed chains is limited, see checkChainLimits.
+// A transaction spending the same outputs as mempool transactions replaces them if they signal replaceability
+// and it pays a higher fee (replace-by-fee), see replacedBy and checkReplacementFee.
+// The total size of the transactions is limited, see MempoolConfig. If the mempool is full, the transactions with the lowest
+// fee rate are evicted together with their descendants and the minimum fee rate for new transactions is raised, see MinFeeRate.
 type Mempool struct {
 	validator  validation.TransactionValidatorAPI
 	blockStore blockchain.BlockStoreAPI
+	config     MempoolConfig
+
+	transactions map[transaction.TransactionID]*mempoolEntry
+	// spentBy maps each outpoint spent by a mempool transaction to the spending transaction.
+	spentBy       map[outpoint]transaction.TransactionID
+	evictionOrder evictionOrder
+	totalSize     int
+	minFee        rollingMinFeeRate
+	lock          sync.Mutex
 
-	transactions map[transaction.TransactionID]transaction.Transaction
-	lock         sync.Mutex
+	// now returns the current time, replaced in tests.
+	now func() time.Time
 }
 
+// NewMempool creates a mempool with the DefaultMempoolConfig.
 func NewMempool(validator validation.TransactionValidatorAPI, blockStore blockchain.BlockStoreAPI) *Mempool {
+	return NewMempoolWithConfig(validator, blockStore, DefaultMempoolConfig())
+}
+
+// NewMempoolWithConfig creates a mempool with the given limits.
+func NewMempoolWithConfig(validator validation.TransactionValidatorAPI, blockStore blockchain.BlockStoreAPI, config MempoolConfig) *Mempool {
 	return &Mempool{
 		validator:    validator,
 		blockStore:   blockStore,
-		transactions: make(map[transaction.TransactionID]transaction.Transaction),
+		config:       config,
+		transactions: make(map[transaction.TransactionID]*mempoolEntry),
+		spentBy:      make(map[outpoint]transaction.TransactionID),
+		now:          time.Now,
 	}
 }
 
-// GetTransactionsForMining returns all transactions that are eligible for mining
+// GetTransactionsForMining returns all transactions that are eligible for mining, ordered by fee rate (highest first).
+// Parents always precede their children, even if a child pays a higher fee rate.
 func (m *Mempool) GetTransactionsForMining() []transaction.Transaction {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -40,98 +147,360 @@ func (m *Mempool) GetTransactionsForMining() []transaction.Transaction {
 }
 
 func (m *Mempool) getTransactionsForMining() []transaction.Transaction {
-	txs := make([]transaction.Transaction, 0, len(m.transactions))
-	for _, tx := range m.transactions {
-		txs = append(txs, tx)
+	entries := m.sortedForMining()
+	txs := make([]transaction.Transaction, 0, len(entries))
+	for _, entry := range entries {
+		txs = append(txs, entry.tx)
 	}
-
 	return txs
 }
 
+// sortedForMining returns all entries ordered by fee rate (highest first), parents always precede their children.
+// Caller must hold the lock.
+func (m *Mempool) sortedForMining() []*mempoolEntry {
+	entries := m.sortedByFeeRate()
+	sorted := make([]*mempoolEntry, 0, len(entries))
+	added := make(map[transaction.TransactionID]struct{}, len(entries))
+
+	var add func(entry *mempoolEntry)
+	add = func(entry *mempoolEntry) {
+		if _, ok := added[entry.id]; ok {
+			return
+		}
+		added[entry.id] = struct{}{}
+		for parentId := range entry.parents {
+			add(m.transactions[parentId])
+		}
+		sorted = append(sorted, entry)
+	}
+
+	for _, entry := range entries {
+		add(entry)
+	}
+
+	return sorted
+}
+
+// sortedByFeeRate returns all entries ordered by fee rate (highest first).
+func (m *Mempool) sortedByFeeRate() []*mempoolEntry {
+	entries := make([]*mempoolEntry, 0, len(m.transactions))
+	for _, entry := range m.transactions {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].hasHigherFeeRateThan(entries[j])
+	})
+	return entries
+}
+
+// MinFeeRate returns the current minimum fee rate in V$Goin per 1000 bytes a new transaction must pay to be admitted.
+// It is raised whenever transactions are evicted because the mempool is full and decays afterward.
+func (m *Mempool) MinFeeRate() uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.minFee.get(m.now())
+}
+
 // IsKnownTransactionHash returns true if the transaction with the given Hash is known to the mempool.
 func (m *Mempool) IsKnownTransactionHash(hash common.Hash) bool {
-	txId := getTransactionIdFromHash(hash)
-	_, ok := m.transactions[txId]
-	return ok
+	return m.IsKnownTransactionId(getTransactionIdFromHash(hash))
 }
 
 // IsKnownTransactionId returns true if the transaction with the given ID is known to the mempool.
 func (m *Mempool) IsKnownTransactionId(txId transaction.TransactionID) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	_, ok := m.transactions[txId]
 	return ok
 }
 
-func (m *Mempool) AddTransaction(tx transaction.Transaction) (isNew bool) {
+// checkMempoolPolicy checks the rules a valid transaction additionally has to satisfy to be admitted to the mempool.
+func checkMempoolPolicy(tx transaction.Transaction) error {
+	if size := tx.SerializedSize(); size > transaction.MaxTransactionSize {
+		return fmt.Errorf("%w: %d bytes", ErrTransactionTooLarge, size)
+	}
+
+	return nil
+}
+
+// ValidateTransaction validates the transaction and its signatures against the UTXO set at the main chain tip with the mempool transactions
+// applied on top. So the transaction may spend outputs of mempool transactions, but no outputs they already spend,
+// unless the spending transactions signal replaceability. Whether the fee suffices for the replacement is checked by AddTransaction.
+func (m *Mempool) ValidateTransaction(tx transaction.Transaction) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	replaced, err := m.replacedBy(tx)
+	if err != nil {
+		return false, err
+	}
+
 	mainChainTip := m.blockStore.GetMainChainTip()
 	mainChainTipHash := mainChainTip.Hash()
-	ok, err := m.validator.ValidateTransaction(tx, mainChainTipHash)
-	assert.Assert(ok && err == nil, "Transaction is invalid: %v", err)
+	view := m.replacementView(tx.TransactionId(), replaced)
+	if ok, err := m.validator.ValidateTransactionWithOverlay(tx, mainChainTipHash, view); !ok {
+		return false, err
+	}
+	if err := m.validator.VerifySignatures(tx, mainChainTipHash, view); err != nil {
+		return false, err
+	}
+	return true, nil
+}
 
+// AddTransaction adds a valid transaction to the mempool, see ValidateTransaction. The signatures are always verified,
+// so an unsigned transaction can neither enter the mempool nor replace mempool transactions.
+// Transactions violating the mempool policy or the limits for unconfirmed chains or paying less than the minimum fee rate are not added.
+// A transaction conflicting with mempool transactions replaces them and their descendants if the replacement rules are met,
+// see replacedBy and checkReplacementFee.
+// If the mempool exceeds its size limit afterward, the transactions with the lowest fee rate are evicted.
+func (m *Mempool) AddTransaction(tx transaction.Transaction) (isNew bool) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
+	return m.addTransaction(tx, m.now(), false)
+}
+
+// AddLocalTransaction is AddTransaction for a transaction created by the wallet of this node.
+// Local transactions are rebroadcast until they are mined, see GetLocalTransactionHashes.
+func (m *Mempool) AddLocalTransaction(tx transaction.Transaction) (isNew bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.addTransaction(tx, m.now(), true)
+}
+
+// addTransaction adds the transaction as if it was received at addedAt, see AddTransaction.
+// Caller must hold the lock.
+func (m *Mempool) addTransaction(tx transaction.Transaction, addedAt time.Time, local bool) (isNew bool) {
+	if err := checkMempoolPolicy(tx); err != nil {
+		logger.Warnf("[mempool] Transaction %v not added to the mempool: %v", tx.TransactionId(), err)
+		return false
+	}
+
+	m.removeExpired()
+
 	txId := tx.TransactionId()
-	_, ok = m.transactions[txId]
-	if !ok {
-		logger.Infof("[mempool] Adding new transaction %v to the mempool", txId)
-		m.transactions[txId] = tx
-	} else {
+	if _, ok := m.transactions[txId]; ok {
 		logger.Infof("[mempool] Transaction %v is already known to the mempool", txId)
+		return false
 	}
 
-	return !ok
+	replaced, err := m.replacedBy(tx)
+	if err != nil {
+		logger.Warnf("[mempool] Transaction %v not added to the mempool: %v", txId, err)
+		return false
+	}
+
+	mainChainTip := m.blockStore.GetMainChainTip()
+	mainChainTipHash := mainChainTip.Hash()
+	view := m.replacementView(txId, replaced)
+	if ok, err := m.validator.ValidateTransactionWithOverlay(tx, mainChainTipHash, view); !ok {
+		logger.Warnf("[mempool] Transaction %v not added to the mempool, it is invalid: %v", txId, err)
+		return false
+	}
+
+	fee, err := m.validator.GetTransactionFee(tx, mainChainTipHash, view)
+	if err != nil {
+		logger.Warnf("[mempool] Transaction %v not added to the mempool: %v", txId, err)
+		return false
+	}
+
+	entry := newMempoolEntry(txId, tx, fee, addedAt, local)
+	if minFeeRate := m.minFee.get(m.now()); !meetsFeeRate(entry.fee, entry.size, minFeeRate) {
+		logger.Warnf("[mempool] Transaction %v not added to the mempool: %v (%d per 1000 bytes required)", txId, ErrFeeRateTooLow, minFeeRate)
+		return false
+	}
+
+	// Verified only now, as it is the most expensive check, but before a replacement can affect the mempool
+	if err = m.validator.VerifySignatures(tx, mainChainTipHash, view); err != nil {
+		logger.Warnf("[mempool] Transaction %v not added to the mempool, it is invalid: %v", txId, err)
+		return false
+	}
+
+	if len(replaced) > 0 {
+		if err = m.checkReplacementFee(entry, replaced); err != nil {
+			logger.Warnf("[mempool] Transaction %v not added to the mempool: %v", txId, err)
+			return false
+		}
+	}
+
+	if err = m.checkChainLimits(tx); err != nil {
+		logger.Warnf("[mempool] Transaction %v not added to the mempool: %v", txId, err)
+		return false
+	}
+
+	removed := m.removeReplaced(replaced)
+	m.insertEntry(entry)
+
+	minFee := m.minFee
+	for _, evicted := range m.trimToSize() {
+		if evicted != entry {
+			removed = append(removed, evicted)
+		}
+	}
+	if _, ok := m.transactions[txId]; !ok {
+		// The transaction did not survive the trimming. Everything it displaced is restored,
+		// otherwise a replacement could remove the replaced transactions without taking their place.
+		m.restoreEntries(removed)
+		// Nothing stays evicted, so the minimum fee rate is not raised either
+		m.minFee = minFee
+		logger.Warnf("[mempool] Transaction %v not added to the mempool: %v", txId, ErrMempoolFull)
+		return false
+	}
+
+	if len(replaced) > 0 {
+		logger.Infof("[mempool] Transaction %v replaced %d transactions in the mempool", txId, len(replaced))
+	}
+	logger.Infof("[mempool] Added new transaction %v to the mempool", txId)
+	return true
+}
+
+// trimToSize evicts the transactions with the lowest fee rate together with their descendants
+// until the mempool no longer exceeds its size limit, see evictionFeeRate and evictionOrder.
+// The minimum fee rate is raised above the fee rate of every evicted transaction,
+// the caller resets it if the evicted entries are restored.
+// Returns the evicted entries.
+// Caller must hold the lock.
+func (m *Mempool) trimToSize() []*mempoolEntry {
+	if m.totalSize <= m.config.MaxSize {
+		return nil
+	}
+
+	var evicted []*mempoolEntry
+	for m.totalSize > m.config.MaxSize {
+		worst := m.evictionOrder[0]
+		worstFeeRate := feeRatePerKB(worst.evictionFee, worst.evictionSize)
+
+		evicted = append(evicted, m.removeWithDescendants(worst.id)...)
+		m.minFee.bump(worstFeeRate, m.now())
+	}
+
+	logger.Infof("[mempool] Evicted %d transactions from the full mempool, minimum fee rate is now %d per 1000 bytes", len(evicted), m.minFee.get(m.now()))
+	return evicted
+}
+
+// removeExpired removes all transactions that stayed longer than the configured expiry in the mempool together with their descendants.
+// Caller must hold the lock.
+func (m *Mempool) removeExpired() {
+	cutoff := m.now().Add(-m.config.Expiry)
+
+	expired := 0
+	for txId, entry := range m.transactions {
+		if entry.addedAt.Before(cutoff) {
+			expired += len(m.removeWithDescendants(txId))
+		}
+	}
+
+	if expired > 0 {
+		logger.Infof("[mempool] Removed %d expired transactions from the mempool", expired)
+	}
 }
 
 // Remove removes all transactions from the mempool that are included in the given block hashes.
-// Also removes transactions that conflict with confirmed transactions (spend the same UTXOs).
-// Finally, re-validates all remaining transactions in the mempool.
+// Their children stay in the mempool, as they now spend confirmed outputs.
+// Then re-validates all remaining transactions and removes invalid ones together with their descendants,
+// e.g. transactions that conflict with confirmed transactions (spend the same UTXOs). Finally removes expired transactions.
 func (m *Mempool) Remove(blockHashes []common.Hash) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	// Track all transaction IDs to remove
-	toRemove := make(map[transaction.TransactionID]bool)
+	removeCount := 0
 
-	// Collect all transactions from the given blocks
+	// Remove all transactions of the given blocks
 	for _, blockHash := range blockHashes {
 		blk, err := m.blockStore.GetBlockByHash(blockHash)
 		if err == nil {
-			// Mark all transactions in this block for removal
 			for _, tx := range blk.Transactions {
-				txId := tx.TransactionId()
-				toRemove[txId] = true
+				if m.removeEntry(tx.TransactionId()) {
+					removeCount++
+				}
 			}
 		}
 	}
 
-	for txId := range m.transactions {
-		// Skip if already marked for removal
-		if toRemove[txId] {
-			continue
-		}
-
-		tx := m.transactions[txId]
-
+	for txId, entry := range m.transactions {
 		mainChainTip := m.blockStore.GetMainChainTip()
 		mainChainTipHash := mainChainTip.Hash()
-		ok, _ := m.validator.ValidateTransaction(tx, mainChainTipHash)
+		ok, _ := m.validator.ValidateTransactionWithOverlay(entry.tx, mainChainTipHash, m.view(txId))
 		if !ok {
-			// Transaction is no longer valid (UTXOs spent, conflicts with confirmed tx)
-			toRemove[txId] = true
+			// Transaction is no longer valid (UTXOs spent, conflicts with confirmed tx), neither are its descendants
+			removeCount += len(m.removeWithDescendants(txId))
 		}
 	}
 
-	removeCount := 0
-	// Remove all marked transactions
-	for txId := range toRemove {
-		_, exists := m.transactions[txId]
-		if exists {
-			removeCount++
+	logger.Infof("[mempool] Removed %d transactions from the mempool after new block arrived", removeCount)
+
+	m.removeExpired()
+}
+
+// ApplyToUtxos returns the given confirmed UTXOs of an address as they are after the mempool transactions:
+// outputs spent by mempool transactions are removed and the unspent outputs of mempool transactions paying to the address
+// (see transaction.Output.AddressHash) are added. The added outputs have the height of the block following the main chain tip.
+func (m *Mempool) ApplyToUtxos(utxos []transaction.UTXO, addressHash transaction.PubKeyHash) []transaction.UTXO {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.applyToUtxos(utxos, addressHash, nil)
+}
+
+// ApplyToUtxosReplacing is ApplyToUtxos as if the given transaction and its descendants were not in the mempool.
+// This returns the UTXOs a replacement of the transaction can spend (replace-by-fee).
+func (m *Mempool) ApplyToUtxosReplacing(utxos []transaction.UTXO, addressHash transaction.PubKeyHash, replacedId transaction.TransactionID) []transaction.UTXO {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	replaced := m.descendantsOf(replacedId)
+	replaced[replacedId] = struct{}{}
+	return m.applyToUtxos(utxos, addressHash, replaced)
+}
+
+// applyToUtxos applies the mempool transactions except the ignored ones to the UTXOs, see ApplyToUtxos.
+// Caller must hold the lock.
+func (m *Mempool) applyToUtxos(utxos []transaction.UTXO, addressHash transaction.PubKeyHash, ignored map[transaction.TransactionID]struct{}) []transaction.UTXO {
+	view := m.replacementView(transaction.TransactionID{}, ignored)
+
+	result := make([]transaction.UTXO, 0, len(utxos))
+	for _, utxo := range utxos {
+		if !view.IsSpent(utxo.TxID, utxo.OutputIndex) {
+			result = append(result, utxo)
 		}
-		delete(m.transactions, txId)
 	}
 
-	logger.Infof("[mempool] Removed %d transactions from the mempool after new block arrived", removeCount)
+	nextHeight := m.blockStore.GetMainChainHeight() + 1
+	for _, entry := range m.sortedByFeeRate() {
+		if _, ok := ignored[entry.id]; ok {
+			continue
+		}
+		for i, output := range entry.tx.Outputs {
+			index := uint32(i)
+			if output.IsUnspendable() || output.AddressHash() != addressHash {
+				continue
+			}
+			if view.IsSpent(entry.id, index) {
+				continue
+			}
+			result = append(result, transaction.UTXO{TxID: entry.id, OutputIndex: index, Output: output, Height: nextHeight})
+		}
+	}
+
+	return result
+}
+
+// GetLocalTransactionHashes returns the hashes of the transactions in the mempool that were created by the wallet of this node,
+// see AddLocalTransaction. Parents precede their children.
+func (m *Mempool) GetLocalTransactionHashes() []common.Hash {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	hashes := make([]common.Hash, 0)
+	for _, entry := range m.sortedForMining() {
+		if entry.local {
+			hashes = append(hashes, entry.tx.Hash())
+		}
+	}
+	return hashes
 }
 
 // GetAllTransactionHashes returns all transaction hashes currently in the mempool.
@@ -157,10 +526,18 @@ func getTransactionIdFromHash(hash common.Hash) transaction.TransactionID {
 // GetTransactionByHash retrieves the transaction with the given hash from the mempool.
 // Returns the transaction and true if found, or false if not found.
 func (m *Mempool) GetTransactionByHash(hash common.Hash) (transaction.Transaction, bool) {
-	txId := getTransactionIdFromHash(hash)
+	return m.GetTransaction(getTransactionIdFromHash(hash))
+}
+
+// GetTransaction retrieves the transaction with the given ID from the mempool.
+// Returns the transaction and true if found, or false if not found.
+func (m *Mempool) GetTransaction(txId transaction.TransactionID) (transaction.Transaction, bool) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	tx, ok := m.transactions[txId]
-	return tx, ok
+	entry, ok := m.transactions[txId]
+	if !ok {
+		return transaction.Transaction{}, false
+	}
+	return entry.tx, true
 }
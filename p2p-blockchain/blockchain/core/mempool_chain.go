@@ -0,0 +1,224 @@
+package core
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+)
+
+const (
+	// maxAncestorCount is the maximum number of unconfirmed ancestors of a mempool transaction, including itself.
+	maxAncestorCount = 25
+	// maxDescendantCount is the maximum number of unconfirmed descendants of a mempool transaction, including itself.
+	maxDescendantCount = 25
+)
+
+// outpoint identifies an output by the ID of its transaction and its index.
+type outpoint struct {
+	txID  transaction.TransactionID
+	index uint32
+}
+
+// mempoolView is the validation.UtxoOverlay of the mempool transactions.
+// The spends of the transaction self are ignored, so a transaction that is already in the mempool can be validated again.
+// The replaced transactions are treated as if they were not in the mempool, see replacedBy.
+// Caller must hold the lock of the mempool while the view is used.
+type mempoolView struct {
+	mempool  *Mempool
+	self     transaction.TransactionID
+	replaced map[transaction.TransactionID]struct{}
+}
+
+// view returns the mempoolView ignoring the spends of the given transaction.
+func (m *Mempool) view(self transaction.TransactionID) mempoolView {
+	return mempoolView{mempool: m, self: self}
+}
+
+// replacementView returns the mempoolView ignoring the spends of the given transaction and the replaced transactions.
+func (m *Mempool) replacementView(self transaction.TransactionID, replaced map[transaction.TransactionID]struct{}) mempoolView {
+	return mempoolView{mempool: m, self: self, replaced: replaced}
+}
+
+func (v mempoolView) GetOutput(txID transaction.TransactionID, outputIndex uint32) (transaction.Output, bool) {
+	if _, ok := v.replaced[txID]; ok {
+		return transaction.Output{}, false
+	}
+
+	entry, ok := v.mempool.transactions[txID]
+	if !ok || int(outputIndex) >= len(entry.tx.Outputs) {
+		return transaction.Output{}, false
+	}
+
+	output := entry.tx.Outputs[outputIndex]
+	if output.IsUnspendable() {
+		return transaction.Output{}, false
+	}
+	return output, true
+}
+
+func (v mempoolView) IsSpent(txID transaction.TransactionID, outputIndex uint32) bool {
+	spender, ok := v.mempool.spentBy[outpoint{txID: txID, index: outputIndex}]
+	if !ok || spender == v.self {
+		return false
+	}
+	_, replaced := v.replaced[spender]
+	return !replaced
+}
+
+// parentsOf returns the IDs of the mempool transactions whose outputs the transaction spends.
+// Caller must hold the lock.
+func (m *Mempool) parentsOf(tx transaction.Transaction) map[transaction.TransactionID]struct{} {
+	parents := make(map[transaction.TransactionID]struct{})
+	for _, input := range tx.Inputs {
+		if _, ok := m.transactions[input.PrevTxID]; ok {
+			parents[input.PrevTxID] = struct{}{}
+		}
+	}
+	return parents
+}
+
+// ancestorsOf returns the IDs of the given mempool transactions and all of their ancestors in the mempool.
+// Caller must hold the lock.
+func (m *Mempool) ancestorsOf(txIds map[transaction.TransactionID]struct{}) map[transaction.TransactionID]struct{} {
+	ancestors := make(map[transaction.TransactionID]struct{})
+	stack := make([]transaction.TransactionID, 0, len(txIds))
+	for txId := range txIds {
+		stack = append(stack, txId)
+	}
+
+	for len(stack) > 0 {
+		txId := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := ancestors[txId]; ok {
+			continue
+		}
+		ancestors[txId] = struct{}{}
+		for parentId := range m.transactions[txId].parents {
+			stack = append(stack, parentId)
+		}
+	}
+	return ancestors
+}
+
+// descendantsOf returns the IDs of all descendants of the mempool transaction, excluding the transaction itself.
+// Caller must hold the lock.
+func (m *Mempool) descendantsOf(txId transaction.TransactionID) map[transaction.TransactionID]struct{} {
+	descendants := make(map[transaction.TransactionID]struct{})
+	stack := []transaction.TransactionID{txId}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for childId := range m.transactions[current].children {
+			if _, ok := descendants[childId]; ok {
+				continue
+			}
+			descendants[childId] = struct{}{}
+			stack = append(stack, childId)
+		}
+	}
+	return descendants
+}
+
+// checkChainLimits checks that the new transaction has at most maxAncestorCount ancestors and that none of its ancestors
+// exceeds maxDescendantCount descendants with the transaction (both including themselves).
+// Returns ErrTooManyAncestors or ErrTooManyDescendants otherwise.
+// Caller must hold the lock.
+func (m *Mempool) checkChainLimits(tx transaction.Transaction) error {
+	ancestors := m.ancestorsOf(m.parentsOf(tx))
+	if len(ancestors)+1 > maxAncestorCount {
+		return ErrTooManyAncestors
+	}
+
+	for ancestorId := range ancestors {
+		// The ancestor, its current descendants and the new transaction
+		if 1+len(m.descendantsOf(ancestorId))+1 > maxDescendantCount {
+			return ErrTooManyDescendants
+		}
+	}
+
+	return nil
+}
+
+// evictionFeeRate returns the fee rate of the entry used to choose the transactions to evict from a full mempool.
+// This is the higher one of the fee rate of the transaction alone and of the transaction with all of its descendants,
+// because the descendants are evicted with it: a child paying a high fee protects its parent, a cheap child does not drag it down.
+// Caller must hold the lock.
+func (m *Mempool) evictionFeeRate(entry *mempoolEntry) (fee uint64, size int) {
+	packageFee, packageSize := entry.fee, entry.size
+	for descendantId := range m.descendantsOf(entry.id) {
+		descendant := m.transactions[descendantId]
+		packageFee += descendant.fee
+		packageSize += descendant.size
+	}
+
+	if compareFeeRates(packageFee, packageSize, entry.fee, entry.size) > 0 {
+		return packageFee, packageSize
+	}
+	return entry.fee, entry.size
+}
+
+// insertEntry adds the entry to the mempool and links it with its parents.
+// Caller must hold the lock.
+func (m *Mempool) insertEntry(entry *mempoolEntry) {
+	for _, input := range entry.tx.Inputs {
+		m.spentBy[outpoint{txID: input.PrevTxID, index: input.OutputIndex}] = entry.id
+	}
+
+	entry.parents = m.parentsOf(entry.tx)
+	for parentId := range entry.parents {
+		m.transactions[parentId].children[entry.id] = struct{}{}
+	}
+
+	m.transactions[entry.id] = entry
+	m.totalSize += entry.size
+	m.pushEvictionOrder(entry)
+}
+
+// removeEntry removes the transaction with the given ID from the mempool and unlinks it from its parents and children.
+// Its descendants stay in the mempool, see removeWithDescendants.
+// Caller must hold the lock.
+func (m *Mempool) removeEntry(txId transaction.TransactionID) bool {
+	entry, exists := m.transactions[txId]
+	if !exists {
+		return false
+	}
+	ancestors := m.ancestorsOf(entry.parents)
+
+	for _, input := range entry.tx.Inputs {
+		spent := outpoint{txID: input.PrevTxID, index: input.OutputIndex}
+		if m.spentBy[spent] == txId {
+			delete(m.spentBy, spent)
+		}
+	}
+	for parentId := range entry.parents {
+		delete(m.transactions[parentId].children, txId)
+	}
+	for childId := range entry.children {
+		delete(m.transactions[childId].parents, txId)
+	}
+
+	m.totalSize -= entry.size
+	delete(m.transactions, txId)
+	m.removeEvictionOrder(entry, ancestors)
+	return true
+}
+
+// removeWithDescendants removes the transaction with the given ID and all of its descendants from the mempool.
+// Returns the removed entries.
+// Caller must hold the lock.
+func (m *Mempool) removeWithDescendants(txId transaction.TransactionID) []*mempoolEntry {
+	entry, exists := m.transactions[txId]
+	if !exists {
+		return nil
+	}
+
+	var removed []*mempoolEntry
+	for descendantId := range m.descendantsOf(txId) {
+		if descendant, ok := m.transactions[descendantId]; ok && m.removeEntry(descendantId) {
+			removed = append(removed, descendant)
+		}
+	}
+	if m.removeEntry(txId) {
+		removed = append(removed, entry)
+	}
+	return removed
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"container/heap"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+)
+
+// evictionOrder orders the mempool entries by their eviction fee rate (lowest first), see evictionFeeRate.
+// Of two entries with the same fee rate the younger one comes first. It is a heap, so the next transaction to evict
+// is found without rescanning the mempool. The cached fee rate of an entry is refreshed whenever its descendants change.
+type evictionOrder []*mempoolEntry
+
+func (o evictionOrder) Len() int { return len(o) }
+
+func (o evictionOrder) Less(i, j int) bool {
+	if cmp := compareFeeRates(o[i].evictionFee, o[i].evictionSize, o[j].evictionFee, o[j].evictionSize); cmp != 0 {
+		return cmp < 0
+	}
+	return o[i].addedAt.After(o[j].addedAt)
+}
+
+func (o evictionOrder) Swap(i, j int) {
+	o[i], o[j] = o[j], o[i]
+	o[i].evictionIndex = i
+	o[j].evictionIndex = j
+}
+
+func (o *evictionOrder) Push(x any) {
+	entry := x.(*mempoolEntry)
+	entry.evictionIndex = len(*o)
+	*o = append(*o, entry)
+}
+
+func (o *evictionOrder) Pop() any {
+	old := *o
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*o = old[:len(old)-1]
+	entry.evictionIndex = -1
+	return entry
+}
+
+// pushEvictionOrder adds the newly inserted entry to the eviction order and refreshes the eviction fee rates of its ancestors.
+// Caller must hold the lock.
+func (m *Mempool) pushEvictionOrder(entry *mempoolEntry) {
+	entry.evictionFee, entry.evictionSize = m.evictionFeeRate(entry)
+	heap.Push(&m.evictionOrder, entry)
+	m.refreshEvictionFeeRates(m.ancestorsOf(entry.parents))
+}
+
+// removeEvictionOrder removes the entry from the eviction order and refreshes the eviction fee rates of its former ancestors.
+// Caller must hold the lock.
+func (m *Mempool) removeEvictionOrder(entry *mempoolEntry, ancestors map[transaction.TransactionID]struct{}) {
+	heap.Remove(&m.evictionOrder, entry.evictionIndex)
+	m.refreshEvictionFeeRates(ancestors)
+}
+
+// refreshEvictionFeeRates recalculates the eviction fee rates of the given mempool transactions and restores the eviction order.
+// IDs of transactions that are no longer in the mempool are ignored.
+// Caller must hold the lock.
+func (m *Mempool) refreshEvictionFeeRates(txIds map[transaction.TransactionID]struct{}) {
+	for txId := range txIds {
+		entry, ok := m.transactions[txId]
+		if !ok {
+			continue
+		}
+		entry.evictionFee, entry.evictionSize = m.evictionFeeRate(entry)
+		heap.Fix(&m.evictionOrder, entry.evictionIndex)
+	}
+}
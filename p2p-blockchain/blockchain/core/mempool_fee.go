@@ -0,0 +1,79 @@
+package core
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// incrementalRelayFeeRate is added to the fee rate of an evicted transaction to get the new minimum fee rate of the mempool.
+	// This way a replacement needs to pay at least a little more than the evicted transaction. In V$Goin per 1000 bytes.
+	incrementalRelayFeeRate = 1
+	// minFeeRateHalfLife is the time after which a raised minimum fee rate has decayed to half of its value.
+	minFeeRateHalfLife = 12 * time.Hour
+)
+
+// feeRatePerKB returns the fee rate of a transaction in V$Goin per 1000 bytes, rounded down.
+func feeRatePerKB(fee uint64, size int) uint64 {
+	if size <= 0 {
+		return 0
+	}
+	return fee * 1000 / uint64(size)
+}
+
+// compareFeeRates compares the fee rate feeA / sizeA with feeB / sizeB without integer division.
+// Returns a positive number if the first fee rate is higher, a negative number if it is lower and zero if both are equal.
+func compareFeeRates(feeA uint64, sizeA int, feeB uint64, sizeB int) int {
+	left := feeA * uint64(sizeB)
+	right := feeB * uint64(sizeA)
+	switch {
+	case left > right:
+		return 1
+	case left < right:
+		return -1
+	}
+	return 0
+}
+
+// meetsFeeRate reports whether a transaction with the given fee and size pays at least the fee rate (V$Goin per 1000 bytes).
+func meetsFeeRate(fee uint64, size int, feeRate uint64) bool {
+	return fee*1000 >= feeRate*uint64(size)
+}
+
+// rollingMinFeeRate is the dynamic minimum fee rate of the mempool in V$Goin per 1000 bytes.
+// It is raised when transactions are evicted from a full mempool and halves every minFeeRateHalfLife afterward,
+// until it drops below incrementalRelayFeeRate / 2 and is reset to zero.
+type rollingMinFeeRate struct {
+	rate       float64
+	lastUpdate time.Time
+}
+
+// get returns the decayed minimum fee rate at the given time.
+func (r *rollingMinFeeRate) get(now time.Time) uint64 {
+	r.decay(now)
+	return uint64(math.Ceil(r.rate))
+}
+
+// bump raises the minimum fee rate above the fee rate of an evicted transaction.
+func (r *rollingMinFeeRate) bump(evictedFeeRate uint64, now time.Time) {
+	r.decay(now)
+	r.rate = max(r.rate, float64(evictedFeeRate+incrementalRelayFeeRate))
+}
+
+func (r *rollingMinFeeRate) decay(now time.Time) {
+	if r.rate == 0 {
+		r.lastUpdate = now
+		return
+	}
+
+	elapsed := now.Sub(r.lastUpdate)
+	if elapsed <= 0 {
+		return
+	}
+
+	r.rate /= math.Pow(2, float64(elapsed)/float64(minFeeRateHalfLife))
+	if r.rate < incrementalRelayFeeRate/2.0 {
+		r.rate = 0
+	}
+	r.lastUpdate = now
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/storage"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"time"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+const mempoolFileName = "mempool.dat"
+
+// Record kinds of the mempool file.
+const (
+	// recordKindMempoolTransaction records contain the time the transaction was added (unix nanoseconds),
+	// the flags of the entry and the serialized transaction.
+	recordKindMempoolTransaction uint8 = iota + 1
+)
+
+// mempoolFlagLocal marks a transaction created by the wallet of this node, see AddLocalTransaction.
+const mempoolFlagLocal uint8 = 1 << 0
+
+// mempoolRecordHeaderSize is the size of the data preceding the serialized transaction: added at (8) + flags (1).
+const mempoolRecordHeaderSize = 8 + 1
+
+var errMalformedMempoolRecord = errors.New("malformed mempool record")
+
+// Dump writes all transactions of the mempool to the mempool file in the given data directory, replacing a previous dump.
+// Parents are written before their children, so Load can add the transactions in file order.
+// The file is written next to the old one and renamed afterward, so a crash never leaves a partial dump behind.
+func (m *Mempool) Dump(dataDir string) error {
+	m.lock.Lock()
+	entries := m.sortedForMining()
+	m.lock.Unlock()
+
+	records := make([]storage.Record, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, storage.Record{Kind: recordKindMempoolTransaction, Payload: serializeMempoolEntry(entry)})
+	}
+
+	path := filepath.Join(dataDir, mempoolFileName)
+	tempPath := path + ".tmp"
+	if err := os.Remove(tempPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale mempool file %s: %w", tempPath, err)
+	}
+
+	file, _, err := storage.OpenRecordFile(tempPath)
+	if err != nil {
+		return err
+	}
+	if err := file.Append(records...); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write mempool file %s: %w", tempPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close mempool file %s: %w", tempPath, err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to replace mempool file %s: %w", path, err)
+	}
+
+	logger.Infof("[mempool] Dumped %d transactions to %s", len(records), path)
+	return nil
+}
+
+// Load adds the transactions of the mempool file in the given data directory to the mempool, see Dump.
+// The file is not trusted: every transaction is validated and its signatures are verified against the current main chain tip
+// like a newly received one, see AddTransaction. So transactions mined or invalidated in the meantime are dropped,
+// and so are transactions spending outputs of blocks that are not known yet, e.g. before the initial block download.
+// Transactions keep the time they were added to the mempool, so expired transactions are dropped as well. A missing file is not an error.
+// Returns the number of transactions added to the mempool.
+func (m *Mempool) Load(dataDir string) (int, error) {
+	path := filepath.Join(dataDir, mempoolFileName)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	file, records, err := storage.OpenRecordFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close mempool file %s: %w", path, err)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	cutoff := m.now().Add(-m.config.Expiry)
+	added := 0
+	for i, record := range records {
+		if record.Kind != recordKindMempoolTransaction {
+			return added, fmt.Errorf("mempool record %d has unknown kind %d", i, record.Kind)
+		}
+
+		tx, addedAt, local, err := deserializeMempoolRecord(record.Payload)
+		if err != nil {
+			return added, fmt.Errorf("mempool record %d: %w", i, err)
+		}
+		if addedAt.Before(cutoff) {
+			continue
+		}
+
+		if m.addTransaction(tx, addedAt, local) {
+			added++
+		}
+	}
+
+	logger.Infof("[mempool] Loaded %d of %d transactions from %s", added, len(records), path)
+	return added, nil
+}
+
+func serializeMempoolEntry(entry *mempoolEntry) []byte {
+	var flags uint8
+	if entry.local {
+		flags |= mempoolFlagLocal
+	}
+
+	payload := make([]byte, 0, mempoolRecordHeaderSize+entry.size)
+	payload = binary.LittleEndian.AppendUint64(payload, uint64(entry.addedAt.UnixNano()))
+	payload = append(payload, flags)
+	return append(payload, entry.tx.Serialize()...)
+}
+
+func deserializeMempoolRecord(payload []byte) (transaction.Transaction, time.Time, bool, error) {
+	if len(payload) < mempoolRecordHeaderSize {
+		return transaction.Transaction{}, time.Time{}, false, errMalformedMempoolRecord
+	}
+
+	addedAt := time.Unix(0, int64(binary.LittleEndian.Uint64(payload[:8])))
+	flags := payload[8]
+
+	r := bytes.NewReader(payload[mempoolRecordHeaderSize:])
+	tx, err := transaction.DeserializeTransaction(r)
+	if err != nil {
+		return transaction.Transaction{}, time.Time{}, false, fmt.Errorf("%w: %v", errMalformedMempoolRecord, err)
+	}
+	if r.Len() != 0 {
+		return transaction.Transaction{}, time.Time{}, false, errMalformedMempoolRecord
+	}
+
+	return tx, addedAt, flags&mempoolFlagLocal != 0, nil
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"fmt"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+)
+
+// maxReplacedCount is the maximum number of mempool transactions (conflicts and their descendants) a single replacement may evict.
+const maxReplacedCount = 100
+
+// conflictsOf returns the IDs of the mempool transactions that spend an outpoint also spent by the transaction.
+// Caller must hold the lock.
+func (m *Mempool) conflictsOf(tx transaction.Transaction) map[transaction.TransactionID]struct{} {
+	txId := tx.TransactionId()
+	conflicts := make(map[transaction.TransactionID]struct{})
+	for _, input := range tx.Inputs {
+		spender, ok := m.spentBy[outpoint{txID: input.PrevTxID, index: input.OutputIndex}]
+		if ok && spender != txId {
+			conflicts[spender] = struct{}{}
+		}
+	}
+	return conflicts
+}
+
+// replacedBy returns the IDs of the mempool transactions the transaction would replace (replace-by-fee):
+// the conflicting transactions together with all of their descendants, as those spend outputs that no longer exist.
+// Returns ErrNotReplaceable if a conflicting transaction does not signal replaceability (see transaction.SequenceReplaceableFlag)
+// and ErrTooManyReplacements if more than maxReplacedCount transactions would be replaced.
+// Caller must hold the lock.
+func (m *Mempool) replacedBy(tx transaction.Transaction) (map[transaction.TransactionID]struct{}, error) {
+	conflicts := m.conflictsOf(tx)
+
+	replaced := make(map[transaction.TransactionID]struct{}, len(conflicts))
+	for conflictId := range conflicts {
+		conflict := m.transactions[conflictId]
+		if !conflict.tx.SignalsReplaceability() {
+			return nil, fmt.Errorf("%w: %v", ErrNotReplaceable, conflictId)
+		}
+
+		replaced[conflictId] = struct{}{}
+		for descendantId := range m.descendantsOf(conflictId) {
+			replaced[descendantId] = struct{}{}
+		}
+	}
+
+	if len(replaced) > maxReplacedCount {
+		return nil, fmt.Errorf("%w: %d transactions", ErrTooManyReplacements, len(replaced))
+	}
+	return replaced, nil
+}
+
+// removeReplaced removes the replaced transactions from the mempool.
+// Returns their entries, so they can be restored if the replacement does not stay in the mempool, see restoreEntries.
+// Caller must hold the lock.
+func (m *Mempool) removeReplaced(replaced map[transaction.TransactionID]struct{}) []*mempoolEntry {
+	removed := make([]*mempoolEntry, 0, len(replaced))
+	for replacedId := range replaced {
+		removed = append(removed, m.transactions[replacedId])
+		m.removeEntry(replacedId)
+	}
+	return removed
+}
+
+// restoreEntries inserts the entries displaced by a transaction that did not stay in the mempool again,
+// i.e. the transactions it replaced and those evicted to make room for it. Parents are always restored before their children.
+// Caller must hold the lock.
+func (m *Mempool) restoreEntries(entries []*mempoolEntry) {
+	pending := make(map[transaction.TransactionID]*mempoolEntry, len(entries))
+	for _, entry := range entries {
+		pending[entry.id] = entry
+	}
+
+	var restore func(entry *mempoolEntry)
+	restore = func(entry *mempoolEntry) {
+		delete(pending, entry.id)
+		for _, input := range entry.tx.Inputs {
+			if parent, ok := pending[input.PrevTxID]; ok {
+				restore(parent)
+			}
+		}
+		// The children are linked again when they are restored
+		entry.children = make(map[transaction.TransactionID]struct{})
+		m.insertEntry(entry)
+	}
+
+	for _, entry := range entries {
+		if _, ok := pending[entry.id]; ok {
+			restore(entry)
+		}
+	}
+}
+
+// checkReplacementFee checks that the replacement pays a strictly higher absolute fee than all replaced transactions together
+// and a strictly higher fee rate than each transaction it directly conflicts with. Otherwise relaying replacements would be free
+// and a replacement could make the mempool worse for miners. Returns ErrReplacementFeeTooLow otherwise.
+// Caller must hold the lock.
+func (m *Mempool) checkReplacementFee(replacement *mempoolEntry, replaced map[transaction.TransactionID]struct{}) error {
+	var replacedFee uint64
+	for replacedId := range replaced {
+		replacedFee += m.transactions[replacedId].fee
+	}
+	if replacement.fee <= replacedFee {
+		return fmt.Errorf("%w: fee %d does not exceed the fee %d of the replaced transactions", ErrReplacementFeeTooLow, replacement.fee, replacedFee)
+	}
+
+	for conflictId := range m.conflictsOf(replacement.tx) {
+		conflict := m.transactions[conflictId]
+		if compareFeeRates(replacement.fee, replacement.size, conflict.fee, conflict.size) <= 0 {
+			return fmt.Errorf("%w: fee rate does not exceed the fee rate of %v", ErrReplacementFeeTooLow, conflictId)
+		}
+	}
+
+	return nil
+}
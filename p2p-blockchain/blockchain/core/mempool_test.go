@@ -2,10 +2,12 @@ package core
 
 import (
 	"fmt"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/validation"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 	"testing"
+	"time"
 )
 
 // txIDToBlockHash converts a TransactionID (32 bytes) into the block.Hash type used by Mempool.IsKnownTransaction.
@@ -15,12 +17,40 @@ func txIDToBlockHash(id transaction.TransactionID) common.Hash {
 	return h
 }
 
-type mockValidator struct{}
+// mockValidator accepts every transaction that does not spend an output already spent by the overlay.
+// The fee of a transaction is taken from fees and defaults to 1. The signatures of the transactions in unsigned are invalid.
+type mockValidator struct {
+	fees     map[transaction.TransactionID]uint64
+	unsigned map[transaction.TransactionID]struct{}
+}
+
+func (m *mockValidator) ValidateTransaction(tx transaction.Transaction, blockHash common.Hash) (bool, error) {
+	return m.ValidateTransactionWithOverlay(tx, blockHash, nil)
+}
 
-func (m *mockValidator) ValidateTransaction(_ transaction.Transaction, _ common.Hash) (bool, error) {
+func (m *mockValidator) ValidateTransactionWithOverlay(tx transaction.Transaction, _ common.Hash, overlay validation.UtxoOverlay) (bool, error) {
+	for _, input := range tx.Inputs {
+		if overlay != nil && overlay.IsSpent(input.PrevTxID, input.OutputIndex) {
+			return false, validation.ErrOutputAlreadySpent
+		}
+	}
 	return true, nil
 }
 
+func (m *mockValidator) GetTransactionFee(tx transaction.Transaction, _ common.Hash, _ validation.UtxoOverlay) (uint64, error) {
+	if fee, ok := m.fees[tx.TransactionId()]; ok {
+		return fee, nil
+	}
+	return 1, nil
+}
+
+func (m *mockValidator) VerifySignatures(tx transaction.Transaction, _ common.Hash, _ validation.UtxoOverlay) error {
+	if _, ok := m.unsigned[tx.TransactionId()]; ok {
+		return validation.ErrInvalidSignature
+	}
+	return nil
+}
+
 // mockBlockStore is a mock implementation of blockchain.BlockStoreAPI for testing
 type mockBlockStore2 struct{}
 
@@ -52,6 +82,10 @@ func (m *mockBlockStore2) GetMainChainHeight() uint64 {
 	return 0
 }
 
+func (m *mockBlockStore2) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+
 func (m *mockBlockStore2) GetMainChainTip() block.Block {
 	return block.Block{}
 }
@@ -64,10 +98,27 @@ func (m *mockBlockStore2) IsBlockInvalid(_ block.Block) (bool, error) {
 	return false, nil
 }
 
+func (m *mockBlockStore2) MarkBlockInvalid(_ common.Hash) error {
+	return nil
+}
+
 func newMockBlockStore() BlockStoreAPI {
 	return &mockBlockStore2{}
 }
 
+// mockBlockStoreWithBlock is a mockBlockStore2 that knows a single block.
+type mockBlockStoreWithBlock struct {
+	mockBlockStore2
+	blk block.Block
+}
+
+func (m *mockBlockStoreWithBlock) GetBlockByHash(hash common.Hash) (block.Block, error) {
+	if hash != m.blk.Hash() {
+		return block.Block{}, fmt.Errorf("block not found")
+	}
+	return m.blk, nil
+}
+
 func TestNewMempool_StartsEmpty(t *testing.T) {
 	m := NewMempool(&mockValidator{}, newMockBlockStore())
 
@@ -90,6 +141,14 @@ func (m *mockBlockStore2) IsTransactionAccepted(txID transaction.TransactionID)
 	return false, nil
 }
 
+func (m *mockBlockStore2) GetNextDifficultyTarget(_ common.Hash) (uint8, error) {
+	return 0, nil
+}
+
+func (m *mockBlockStore2) GetMedianTimePast(_ common.Hash) (int64, error) {
+	return 0, nil
+}
+
 func TestMempool_AddTransaction_MakesTransactionKnownByHash(t *testing.T) {
 	m := NewMempool(&mockValidator{}, newMockBlockStore())
 
@@ -183,3 +242,732 @@ func TestMempool_IsKnownTransaction_UnknownHashReturnsFalse(t *testing.T) {
 		t.Fatalf("expected unknown hash to be reported as not known")
 	}
 }
+
+func TestMempool_AddTransaction_RejectsTooLargeTransaction(t *testing.T) {
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	tx := transaction.Transaction{
+		Inputs: []transaction.Input{
+			{PrevTxID: transaction.TransactionID{7}, Signature: make([]byte, transaction.MaxTransactionSize)},
+		},
+		Outputs: []transaction.Output{
+			{Value: 10},
+		},
+	}
+
+	if isNew := m.AddTransaction(tx); isNew {
+		t.Fatalf("expected transaction exceeding the maximum size not to be added")
+	}
+	if m.IsKnownTransactionId(tx.TransactionId()) {
+		t.Fatalf("expected transaction exceeding the maximum size to be unknown")
+	}
+}
+
+// newTestTransaction creates a transaction with a unique input and an output with the given value.
+func newTestTransaction(seed byte, value uint64) transaction.Transaction {
+	return transaction.Transaction{
+		Inputs: []transaction.Input{
+			{PrevTxID: transaction.TransactionID{seed}},
+		},
+		Outputs: []transaction.Output{
+			{Value: value},
+		},
+	}
+}
+
+func TestMempool_GetTransactionsForMining_OrdersByFeeRate(t *testing.T) {
+	low := newTestTransaction(1, 10)
+	high := newTestTransaction(2, 10)
+	medium := newTestTransaction(3, 10)
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		low.TransactionId():    1,
+		high.TransactionId():   30,
+		medium.TransactionId(): 20,
+	}}
+	m := NewMempool(validator, newMockBlockStore())
+
+	m.AddTransaction(low)
+	m.AddTransaction(high)
+	m.AddTransaction(medium)
+
+	txs := m.GetTransactionsForMining()
+	if len(txs) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(txs))
+	}
+	expected := []transaction.TransactionID{high.TransactionId(), medium.TransactionId(), low.TransactionId()}
+	for i, tx := range txs {
+		if tx.TransactionId() != expected[i] {
+			t.Fatalf("expected transaction %d to be %v, got %v", i, expected[i], tx.TransactionId())
+		}
+	}
+}
+
+func TestMempool_AddTransaction_EvictsLowestFeeRateWhenFull(t *testing.T) {
+	low := newTestTransaction(1, 10)
+	high := newTestTransaction(2, 10)
+	medium := newTestTransaction(3, 10)
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		low.TransactionId():    1,
+		high.TransactionId():   30,
+		medium.TransactionId(): 20,
+	}}
+	m := NewMempoolWithConfig(validator, newMockBlockStore(), MempoolConfig{
+		MaxSize: 2 * low.SerializedSize(),
+		Expiry:  time.Hour,
+	})
+
+	m.AddTransaction(low)
+	m.AddTransaction(high)
+	if m.MinFeeRate() != 0 {
+		t.Fatalf("expected no minimum fee rate before the mempool is full, got %d", m.MinFeeRate())
+	}
+
+	if isNew := m.AddTransaction(medium); !isNew {
+		t.Fatalf("expected transaction with a higher fee rate to be added to the full mempool")
+	}
+	if m.IsKnownTransactionId(low.TransactionId()) {
+		t.Fatalf("expected transaction with the lowest fee rate to be evicted")
+	}
+	if m.totalSize > m.config.MaxSize {
+		t.Fatalf("expected mempool size %d not to exceed the limit %d", m.totalSize, m.config.MaxSize)
+	}
+
+	expectedMinFeeRate := feeRatePerKB(1, low.SerializedSize()) + incrementalRelayFeeRate
+	if m.MinFeeRate() != expectedMinFeeRate {
+		t.Fatalf("expected minimum fee rate %d after eviction, got %d", expectedMinFeeRate, m.MinFeeRate())
+	}
+	if isNew := m.AddTransaction(low); isNew {
+		t.Fatalf("expected evicted transaction to be rejected because of the raised minimum fee rate")
+	}
+}
+
+func TestMempool_AddTransaction_RejectsTransactionWithLowestFeeRateWhenFull(t *testing.T) {
+	high := newTestTransaction(1, 10)
+	low := newTestTransaction(2, 10)
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		high.TransactionId(): 30,
+		low.TransactionId():  1,
+	}}
+	m := NewMempoolWithConfig(validator, newMockBlockStore(), MempoolConfig{
+		MaxSize: high.SerializedSize(),
+		Expiry:  time.Hour,
+	})
+
+	m.AddTransaction(high)
+
+	if isNew := m.AddTransaction(low); isNew {
+		t.Fatalf("expected transaction with the lowest fee rate not to be added to the full mempool")
+	}
+	if !m.IsKnownTransactionId(high.TransactionId()) {
+		t.Fatalf("expected transaction with the higher fee rate to stay in the mempool")
+	}
+	if m.MinFeeRate() != 0 {
+		t.Fatalf("expected no minimum fee rate if only the rejected transaction was evicted, got %d", m.MinFeeRate())
+	}
+}
+
+func TestMempool_MinFeeRate_DecaysOverTime(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+	m.now = func() time.Time { return now }
+
+	m.minFee.bump(99, now)
+	if got := m.MinFeeRate(); got != 100 {
+		t.Fatalf("expected minimum fee rate 100 after bump, got %d", got)
+	}
+
+	now = now.Add(minFeeRateHalfLife)
+	if got := m.MinFeeRate(); got != 50 {
+		t.Fatalf("expected minimum fee rate 50 after one half-life, got %d", got)
+	}
+
+	now = now.Add(10 * minFeeRateHalfLife)
+	if got := m.MinFeeRate(); got != 0 {
+		t.Fatalf("expected minimum fee rate to be reset to 0, got %d", got)
+	}
+}
+
+func TestMempool_AddTransaction_RemovesExpiredTransactions(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	m := NewMempoolWithConfig(&mockValidator{}, newMockBlockStore(), MempoolConfig{
+		MaxSize: common.DefaultMempoolMaxSize,
+		Expiry:  time.Hour,
+	})
+	m.now = func() time.Time { return now }
+
+	old := newTestTransaction(1, 10)
+	m.AddTransaction(old)
+
+	now = now.Add(2 * time.Hour)
+	fresh := newTestTransaction(2, 10)
+	m.AddTransaction(fresh)
+
+	if m.IsKnownTransactionId(old.TransactionId()) {
+		t.Fatalf("expected expired transaction to be removed")
+	}
+	if !m.IsKnownTransactionId(fresh.TransactionId()) {
+		t.Fatalf("expected new transaction to be in the mempool")
+	}
+	if m.totalSize != fresh.SerializedSize() {
+		t.Fatalf("expected mempool size %d, got %d", fresh.SerializedSize(), m.totalSize)
+	}
+}
+
+// newChildTransaction creates a transaction spending the first output of the parent with an output with the given value.
+func newChildTransaction(parent transaction.Transaction, value uint64) transaction.Transaction {
+	return transaction.Transaction{
+		Inputs: []transaction.Input{
+			{PrevTxID: parent.TransactionId(), OutputIndex: 0},
+		},
+		Outputs: []transaction.Output{
+			{Value: value, PubKeyHash: transaction.PubKeyHash{1}},
+		},
+	}
+}
+
+func TestMempool_AddTransaction_LinksChainOfUnconfirmedTransactions(t *testing.T) {
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	parent := newTestTransaction(1, 10)
+	child := newChildTransaction(parent, 9)
+	m.AddTransaction(parent)
+
+	if isNew := m.AddTransaction(child); !isNew {
+		t.Fatalf("expected child of a mempool transaction to be added")
+	}
+
+	parentEntry := m.transactions[parent.TransactionId()]
+	childEntry := m.transactions[child.TransactionId()]
+	if _, ok := parentEntry.children[child.TransactionId()]; !ok {
+		t.Fatalf("expected child to be linked to its parent")
+	}
+	if _, ok := childEntry.parents[parent.TransactionId()]; !ok {
+		t.Fatalf("expected parent to be linked to its child")
+	}
+}
+
+func TestMempool_AddTransaction_RejectsConflictingTransaction(t *testing.T) {
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	first := newTestTransaction(1, 10)
+	conflicting := newTestTransaction(1, 9) // spends the same output
+
+	m.AddTransaction(first)
+
+	if isNew := m.AddTransaction(conflicting); isNew {
+		t.Fatalf("expected transaction spending an output already spent in the mempool to be rejected")
+	}
+}
+
+func TestMempool_ValidateTransaction_RejectsOutputSpentInMempool(t *testing.T) {
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	parent := newTestTransaction(1, 10)
+	m.AddTransaction(parent)
+	m.AddTransaction(newChildTransaction(parent, 9))
+
+	ok, err := m.ValidateTransaction(newChildTransaction(parent, 8))
+	if ok || err == nil {
+		t.Fatalf("expected second child of the same output to be invalid")
+	}
+}
+
+func TestMempool_GetTransactionsForMining_ParentBeforeChild(t *testing.T) {
+	parent := newTestTransaction(1, 10)
+	child := newChildTransaction(parent, 9)
+	other := newTestTransaction(2, 10)
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		parent.TransactionId(): 1,
+		child.TransactionId():  50,
+		other.TransactionId():  20,
+	}}
+	m := NewMempool(validator, newMockBlockStore())
+
+	m.AddTransaction(parent)
+	m.AddTransaction(child)
+	m.AddTransaction(other)
+
+	txs := m.GetTransactionsForMining()
+	expected := []transaction.TransactionID{parent.TransactionId(), child.TransactionId(), other.TransactionId()}
+	if len(txs) != len(expected) {
+		t.Fatalf("expected %d transactions, got %d", len(expected), len(txs))
+	}
+	for i, tx := range txs {
+		if tx.TransactionId() != expected[i] {
+			t.Fatalf("expected transaction %d to be %v, got %v", i, expected[i], tx.TransactionId())
+		}
+	}
+}
+
+func TestMempool_AddTransaction_LimitsAncestors(t *testing.T) {
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	tx := newTestTransaction(1, 100)
+	m.AddTransaction(tx)
+	for i := 1; i < maxAncestorCount; i++ {
+		tx = newChildTransaction(tx, 100)
+		if isNew := m.AddTransaction(tx); !isNew {
+			t.Fatalf("expected transaction %d of the chain to be added", i+1)
+		}
+	}
+
+	if isNew := m.AddTransaction(newChildTransaction(tx, 100)); isNew {
+		t.Fatalf("expected transaction exceeding the ancestor limit to be rejected")
+	}
+	if got := len(m.transactions); got != maxAncestorCount {
+		t.Fatalf("expected %d transactions in the mempool, got %d", maxAncestorCount, got)
+	}
+}
+
+func TestMempool_AddTransaction_LimitsDescendants(t *testing.T) {
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	// A parent with one output per child
+	parent := newTestTransaction(1, 100)
+	parent.Outputs = make([]transaction.Output, maxDescendantCount)
+	for i := range parent.Outputs {
+		parent.Outputs[i] = transaction.Output{Value: 100, PubKeyHash: transaction.PubKeyHash{1}}
+	}
+	m.AddTransaction(parent)
+
+	spendOutput := func(index uint32) transaction.Transaction {
+		return transaction.Transaction{
+			Inputs:  []transaction.Input{{PrevTxID: parent.TransactionId(), OutputIndex: index}},
+			Outputs: []transaction.Output{{Value: 99}},
+		}
+	}
+
+	for i := uint32(0); i < maxDescendantCount-1; i++ {
+		if isNew := m.AddTransaction(spendOutput(i)); !isNew {
+			t.Fatalf("expected child %d to be added", i+1)
+		}
+	}
+
+	if isNew := m.AddTransaction(spendOutput(maxDescendantCount - 1)); isNew {
+		t.Fatalf("expected child exceeding the descendant limit of the parent to be rejected")
+	}
+}
+
+func TestMempool_Remove_KeepsChildrenOfConfirmedTransactions(t *testing.T) {
+	parent := newTestTransaction(1, 10)
+	child := newChildTransaction(parent, 9)
+	blockStore := &mockBlockStoreWithBlock{
+		blk: block.Block{Transactions: []transaction.Transaction{parent}},
+	}
+	m := NewMempool(&mockValidator{}, blockStore)
+
+	m.AddTransaction(parent)
+	m.AddTransaction(child)
+
+	m.Remove([]common.Hash{blockStore.blk.Hash()})
+
+	if m.IsKnownTransactionId(parent.TransactionId()) {
+		t.Fatalf("expected confirmed transaction to be removed")
+	}
+	if !m.IsKnownTransactionId(child.TransactionId()) {
+		t.Fatalf("expected child of the confirmed transaction to stay in the mempool")
+	}
+	if got := len(m.transactions[child.TransactionId()].parents); got != 0 {
+		t.Fatalf("expected child to have no unconfirmed parents anymore, got %d", got)
+	}
+}
+
+func TestMempool_AddTransaction_EvictsDescendantsWithParent(t *testing.T) {
+	parent := newTestTransaction(1, 10)
+	child := newChildTransaction(parent, 9)
+	high := newTestTransaction(2, 10)
+	// The package of parent and child has the lowest fee rate, although the child alone pays more than the parent
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		parent.TransactionId(): 1,
+		child.TransactionId():  5,
+		high.TransactionId():   30,
+	}}
+	m := NewMempoolWithConfig(validator, newMockBlockStore(), MempoolConfig{
+		MaxSize: parent.SerializedSize() + child.SerializedSize(),
+		Expiry:  time.Hour,
+	})
+
+	m.AddTransaction(parent)
+	m.AddTransaction(child)
+
+	if isNew := m.AddTransaction(high); !isNew {
+		t.Fatalf("expected transaction with a higher fee rate to be added to the full mempool")
+	}
+	if m.IsKnownTransactionId(parent.TransactionId()) || m.IsKnownTransactionId(child.TransactionId()) {
+		t.Fatalf("expected parent to be evicted together with its child")
+	}
+	if len(m.spentBy) != 1 {
+		t.Fatalf("expected only the outpoint of the remaining transaction to be marked as spent, got %d", len(m.spentBy))
+	}
+}
+
+func TestMempool_ApplyToUtxos(t *testing.T) {
+	address := transaction.PubKeyHash{1}
+	confirmedSpent := transaction.UTXO{TxID: transaction.TransactionID{1}, Output: transaction.Output{Value: 10, PubKeyHash: address}}
+	confirmedUnspent := transaction.UTXO{TxID: transaction.TransactionID{2}, Output: transaction.Output{Value: 20, PubKeyHash: address}}
+
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	// Spends the first UTXO, pays 3 to another address and the change of 6 back to the address
+	tx := transaction.Transaction{
+		Inputs: []transaction.Input{{PrevTxID: confirmedSpent.TxID}},
+		Outputs: []transaction.Output{
+			{Value: 3, PubKeyHash: transaction.PubKeyHash{2}},
+			{Value: 6, PubKeyHash: address},
+		},
+	}
+	m.AddTransaction(tx)
+
+	utxos := m.ApplyToUtxos([]transaction.UTXO{confirmedSpent, confirmedUnspent}, address)
+
+	if len(utxos) != 2 {
+		t.Fatalf("expected 2 UTXOs, got %d", len(utxos))
+	}
+	if utxos[0].TxID != confirmedUnspent.TxID {
+		t.Fatalf("expected the unspent confirmed UTXO first, got %v", utxos[0].TxID)
+	}
+	change := utxos[1]
+	if change.TxID != tx.TransactionId() || change.OutputIndex != 1 || change.Output.Value != 6 || change.Height != 1 {
+		t.Fatalf("expected the unconfirmed change output at the next height, got %+v", change)
+	}
+}
+
+func newReplaceableTestTransaction(seed byte, value uint64) transaction.Transaction {
+	tx := newTestTransaction(seed, value)
+	tx.Inputs[0].Sequence = transaction.SequenceReplaceableFlag
+	return tx
+}
+
+func TestMempool_AddTransaction_ReplacesReplaceableTransaction(t *testing.T) {
+	original := newReplaceableTestTransaction(1, 10)
+	replacement := newReplaceableTestTransaction(1, 9) // spends the same output
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		original.TransactionId():    1,
+		replacement.TransactionId(): 2,
+	}}
+	m := NewMempool(validator, newMockBlockStore())
+
+	m.AddTransaction(original)
+
+	if ok, err := m.ValidateTransaction(replacement); !ok {
+		t.Fatalf("expected replacement of a replaceable transaction to be valid, got %v", err)
+	}
+	if isNew := m.AddTransaction(replacement); !isNew {
+		t.Fatalf("expected replacement paying a higher fee to be added")
+	}
+	if m.IsKnownTransactionId(original.TransactionId()) {
+		t.Fatalf("expected replaced transaction to be removed")
+	}
+	if spender := m.spentBy[outpoint{txID: transaction.TransactionID{1}}]; spender != replacement.TransactionId() {
+		t.Fatalf("expected the outpoint to be spent by the replacement, got %v", spender)
+	}
+}
+
+func TestMempool_AddTransaction_RejectsReplacementWithoutHigherFee(t *testing.T) {
+	original := newReplaceableTestTransaction(1, 10)
+	replacement := newReplaceableTestTransaction(1, 9)
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		original.TransactionId():    2,
+		replacement.TransactionId(): 2,
+	}}
+	m := NewMempool(validator, newMockBlockStore())
+
+	m.AddTransaction(original)
+
+	if isNew := m.AddTransaction(replacement); isNew {
+		t.Fatalf("expected replacement paying the same fee to be rejected")
+	}
+	if !m.IsKnownTransactionId(original.TransactionId()) {
+		t.Fatalf("expected original transaction to stay in the mempool")
+	}
+}
+
+func TestMempool_AddTransaction_RejectsUnsignedReplacement(t *testing.T) {
+	original := newReplaceableTestTransaction(1, 10)
+	replacement := newReplaceableTestTransaction(1, 9)
+	validator := &mockValidator{
+		fees: map[transaction.TransactionID]uint64{
+			original.TransactionId():    1,
+			replacement.TransactionId(): 5,
+		},
+		unsigned: map[transaction.TransactionID]struct{}{replacement.TransactionId(): {}},
+	}
+	m := NewMempool(validator, newMockBlockStore())
+
+	m.AddTransaction(original)
+
+	if ok, _ := m.ValidateTransaction(replacement); ok {
+		t.Fatalf("expected replacement with invalid signatures to be invalid")
+	}
+	if isNew := m.AddTransaction(replacement); isNew {
+		t.Fatalf("expected replacement with invalid signatures to be rejected")
+	}
+	if !m.IsKnownTransactionId(original.TransactionId()) {
+		t.Fatalf("expected original transaction to stay in the mempool")
+	}
+}
+
+func TestMempool_AddTransaction_ReplacementEvictsDescendants(t *testing.T) {
+	original := newReplaceableTestTransaction(1, 10)
+	child := newChildTransaction(original, 9)
+	cheapReplacement := newReplaceableTestTransaction(1, 8)
+	replacement := newReplaceableTestTransaction(1, 7)
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		original.TransactionId():         1,
+		child.TransactionId():            1,
+		cheapReplacement.TransactionId(): 2,
+		replacement.TransactionId():      3,
+	}}
+	m := NewMempool(validator, newMockBlockStore())
+
+	m.AddTransaction(original)
+	m.AddTransaction(child)
+
+	// The replacement has to pay more than the original and its child together
+	if isNew := m.AddTransaction(cheapReplacement); isNew {
+		t.Fatalf("expected replacement not paying more than the replaced descendants to be rejected")
+	}
+	if isNew := m.AddTransaction(replacement); !isNew {
+		t.Fatalf("expected replacement paying more than all replaced transactions to be added")
+	}
+	if m.IsKnownTransactionId(original.TransactionId()) || m.IsKnownTransactionId(child.TransactionId()) {
+		t.Fatalf("expected original transaction to be replaced together with its child")
+	}
+	if len(m.transactions) != 1 || len(m.spentBy) != 1 {
+		t.Fatalf("expected only the replacement to remain, got %d transactions and %d spent outpoints", len(m.transactions), len(m.spentBy))
+	}
+}
+
+func TestMempool_AddTransaction_RestoresReplacedTransactionsIfReplacementIsEvicted(t *testing.T) {
+	original := newReplaceableTestTransaction(1, 10)
+	child := newChildTransaction(original, 9)
+	high := newTestTransaction(2, 10)
+	// The replacement pays more than the replaced transactions, but is too large to stay in the full mempool
+	replacement := newReplaceableTestTransaction(1, 1)
+	for i := 0; i < 10; i++ {
+		replacement.Outputs = append(replacement.Outputs, transaction.Output{Value: 1})
+	}
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		original.TransactionId():    10,
+		child.TransactionId():       10,
+		high.TransactionId():        1000,
+		replacement.TransactionId(): 21,
+	}}
+	m := NewMempoolWithConfig(validator, newMockBlockStore(), MempoolConfig{
+		MaxSize: original.SerializedSize() + child.SerializedSize() + high.SerializedSize(),
+		Expiry:  time.Hour,
+	})
+
+	m.AddTransaction(original)
+	m.AddTransaction(child)
+	m.AddTransaction(high)
+
+	if isNew := m.AddTransaction(replacement); isNew {
+		t.Fatalf("expected replacement that does not fit into the full mempool to be rejected")
+	}
+	if !m.IsKnownTransactionId(original.TransactionId()) || !m.IsKnownTransactionId(child.TransactionId()) {
+		t.Fatalf("expected replaced transactions to be restored")
+	}
+	if _, ok := m.transactions[child.TransactionId()].parents[original.TransactionId()]; !ok {
+		t.Fatalf("expected restored child to be linked to its restored parent")
+	}
+	if spender := m.spentBy[outpoint{txID: transaction.TransactionID{1}}]; spender != original.TransactionId() {
+		t.Fatalf("expected the outpoint to be spent by the original transaction again, got %v", spender)
+	}
+	if m.totalSize != m.config.MaxSize {
+		t.Fatalf("expected mempool size %d, got %d", m.config.MaxSize, m.totalSize)
+	}
+	if m.MinFeeRate() != 0 {
+		t.Fatalf("expected no minimum fee rate if the replaced transactions are restored, got %d", m.MinFeeRate())
+	}
+	assertEvictionOrder(t, m)
+}
+
+func TestMempool_EvictionOrder_FollowsDescendants(t *testing.T) {
+	parent := newTestTransaction(1, 10)
+	child := newChildTransaction(parent, 9)
+	other := newTestTransaction(2, 10)
+	validator := &mockValidator{fees: map[transaction.TransactionID]uint64{
+		parent.TransactionId(): 1,
+		child.TransactionId():  50,
+		other.TransactionId():  10,
+	}}
+	m := NewMempool(validator, newMockBlockStore())
+
+	m.AddTransaction(parent)
+	m.AddTransaction(other)
+	if m.evictionOrder[0].id != parent.TransactionId() {
+		t.Fatalf("expected the parent to be evicted first")
+	}
+
+	// The child paying a high fee protects its parent
+	m.AddTransaction(child)
+	assertEvictionOrder(t, m)
+	if m.evictionOrder[0].id != other.TransactionId() {
+		t.Fatalf("expected the parent to be protected by its child")
+	}
+
+	m.removeEntry(child.TransactionId())
+	assertEvictionOrder(t, m)
+	if m.evictionOrder[0].id != parent.TransactionId() {
+		t.Fatalf("expected the parent to be evicted first again after its child was removed")
+	}
+}
+
+// assertEvictionOrder checks that the eviction order contains every mempool entry with its current eviction fee rate.
+func assertEvictionOrder(t *testing.T, m *Mempool) {
+	t.Helper()
+	if len(m.evictionOrder) != len(m.transactions) {
+		t.Fatalf("expected %d entries in the eviction order, got %d", len(m.transactions), len(m.evictionOrder))
+	}
+	for i, entry := range m.evictionOrder {
+		if m.transactions[entry.id] != entry || entry.evictionIndex != i {
+			t.Fatalf("unexpected entry %v at position %d of the eviction order", entry.id, i)
+		}
+		if fee, size := m.evictionFeeRate(entry); fee != entry.evictionFee || size != entry.evictionSize {
+			t.Fatalf("expected cached eviction fee rate %d/%d of %v, got %d/%d", fee, size, entry.id, entry.evictionFee, entry.evictionSize)
+		}
+	}
+}
+
+func TestMempool_ReplacementView_HidesReplacedTransactions(t *testing.T) {
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	original := newReplaceableTestTransaction(1, 10)
+	m.AddTransaction(original)
+	replaced := map[transaction.TransactionID]struct{}{original.TransactionId(): {}}
+	view := m.replacementView(transaction.TransactionID{2}, replaced)
+
+	if _, ok := view.GetOutput(original.TransactionId(), 0); ok {
+		t.Fatalf("expected outputs of the replaced transaction to be unavailable to the replacement")
+	}
+	if view.IsSpent(transaction.TransactionID{1}, 0) {
+		t.Fatalf("expected outpoint spent by the replaced transaction to be unspent for the replacement")
+	}
+}
+
+func TestMempool_ApplyToUtxosReplacing(t *testing.T) {
+	address := transaction.PubKeyHash{1}
+	confirmed := transaction.UTXO{TxID: transaction.TransactionID{1}, Output: transaction.Output{Value: 10, PubKeyHash: address}}
+
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	tx := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: confirmed.TxID, Sequence: transaction.SequenceReplaceableFlag}},
+		Outputs: []transaction.Output{{Value: 9, PubKeyHash: address}},
+	}
+	m.AddTransaction(tx)
+	m.AddTransaction(newChildTransaction(tx, 8))
+
+	utxos := m.ApplyToUtxosReplacing([]transaction.UTXO{confirmed}, address, tx.TransactionId())
+
+	if len(utxos) != 1 || utxos[0].TxID != confirmed.TxID {
+		t.Fatalf("expected only the confirmed UTXO spent by the replaced transaction, got %+v", utxos)
+	}
+}
+
+func TestMempool_DumpAndLoad_RestoresTransactions(t *testing.T) {
+	dataDir := t.TempDir()
+	now := time.Unix(1_700_000_000, 0)
+
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+	m.now = func() time.Time { return now }
+	parent := newTestTransaction(1, 10)
+	child := newChildTransaction(parent, 9)
+	other := newTestTransaction(2, 10)
+	m.AddLocalTransaction(parent)
+	m.AddLocalTransaction(child)
+	m.AddTransaction(other)
+
+	if err := m.Dump(dataDir); err != nil {
+		t.Fatalf("unexpected error dumping the mempool: %v", err)
+	}
+
+	restored := NewMempool(&mockValidator{}, newMockBlockStore())
+	restored.now = func() time.Time { return now.Add(time.Hour) }
+	loaded, err := restored.Load(dataDir)
+	if err != nil {
+		t.Fatalf("unexpected error loading the mempool: %v", err)
+	}
+
+	if loaded != 3 {
+		t.Fatalf("expected 3 loaded transactions, got %d", loaded)
+	}
+	for _, tx := range []transaction.Transaction{parent, child, other} {
+		entry, ok := restored.transactions[tx.TransactionId()]
+		if !ok {
+			t.Fatalf("expected transaction %v to be restored", tx.TransactionId())
+		}
+		if !entry.addedAt.Equal(now) {
+			t.Fatalf("expected transaction to keep the time it was added %v, got %v", now, entry.addedAt)
+		}
+	}
+	if _, ok := restored.transactions[parent.TransactionId()].children[child.TransactionId()]; !ok {
+		t.Fatalf("expected restored child to be linked to its parent")
+	}
+
+	local := restored.GetLocalTransactionHashes()
+	if len(local) != 2 || local[0] != parent.Hash() || local[1] != child.Hash() {
+		t.Fatalf("expected the local parent and child to stay local, got %v", local)
+	}
+}
+
+func TestMempool_Load_DropsExpiredAndInvalidTransactions(t *testing.T) {
+	dataDir := t.TempDir()
+	now := time.Unix(1_700_000_000, 0)
+
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+	m.now = func() time.Time { return now }
+	old := newTestTransaction(1, 10)
+	m.AddTransaction(old)
+	now = now.Add(2 * time.Hour)
+	fresh := newTestTransaction(2, 10)
+	m.AddTransaction(fresh)
+	conflicting := newTestTransaction(3, 10)
+	m.AddTransaction(conflicting)
+	tampered := newTestTransaction(4, 10)
+	m.AddTransaction(tampered)
+
+	if err := m.Dump(dataDir); err != nil {
+		t.Fatalf("unexpected error dumping the mempool: %v", err)
+	}
+
+	// The mempool file is not trusted, the signatures of the loaded transactions are verified again
+	validator := &mockValidator{unsigned: map[transaction.TransactionID]struct{}{tampered.TransactionId(): {}}}
+	restored := NewMempoolWithConfig(validator, newMockBlockStore(), MempoolConfig{
+		MaxSize: common.DefaultMempoolMaxSize,
+		Expiry:  90 * time.Minute,
+	})
+	restored.now = func() time.Time { return now }
+	// Spends the same output as conflicting, which is therefore invalid when loaded
+	spender := newTestTransaction(3, 5)
+	restored.AddTransaction(spender)
+
+	loaded, err := restored.Load(dataDir)
+	if err != nil {
+		t.Fatalf("unexpected error loading the mempool: %v", err)
+	}
+
+	if loaded != 1 || !restored.IsKnownTransactionId(fresh.TransactionId()) {
+		t.Fatalf("expected only the fresh transaction to be loaded, loaded %d", loaded)
+	}
+	if restored.IsKnownTransactionId(old.TransactionId()) {
+		t.Fatalf("expected expired transaction to be dropped")
+	}
+	if restored.IsKnownTransactionId(conflicting.TransactionId()) {
+		t.Fatalf("expected transaction conflicting with the mempool to be dropped")
+	}
+	if restored.IsKnownTransactionId(tampered.TransactionId()) {
+		t.Fatalf("expected transaction with invalid signatures to be dropped")
+	}
+}
+
+func TestMempool_Load_MissingFile(t *testing.T) {
+	m := NewMempool(&mockValidator{}, newMockBlockStore())
+
+	loaded, err := m.Load(t.TempDir())
+
+	if err != nil || loaded != 0 {
+		t.Fatalf("expected nothing to be loaded without error, got %d and %v", loaded, err)
+	}
+}
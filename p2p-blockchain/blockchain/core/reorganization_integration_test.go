@@ -1,6 +1,8 @@
 package core
 
 import (
+	"fmt"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/validation"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/blockchain"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
@@ -22,18 +24,78 @@ type testOutpoint struct {
 
 type blockValidatorForTests struct{}
 
+func (b *blockValidatorForTests) SanityCheck(blockToValidate block.Block) (bool, error) {
+	return true, nil
+}
+
+func (b *blockValidatorForTests) ValidateHeaderOnly(header block.BlockHeader) (bool, error) {
+	return true, nil
+}
+
 func (b *blockValidatorForTests) FullValidation(blockToValidate block.Block) (bool, error) {
 	return true, nil
 }
 
+func (b *blockValidatorForTests) ValidateDifficultyTarget(header block.BlockHeader, requiredTarget uint8) (bool, error) {
+	return true, nil
+}
+
+func (b *blockValidatorForTests) ValidateTimestamp(header block.BlockHeader, medianTimePast int64) (bool, error) {
+	return true, nil
+}
+
+// difficultyCheckingValidatorForTests accepts every block but enforces the required difficulty target.
+type difficultyCheckingValidatorForTests struct {
+	blockValidatorForTests
+}
+
+func (b *difficultyCheckingValidatorForTests) ValidateDifficultyTarget(header block.BlockHeader, requiredTarget uint8) (bool, error) {
+	if header.DifficultyTarget != requiredTarget {
+		return false, fmt.Errorf("difficulty target %d does not match required target %d", header.DifficultyTarget, requiredTarget)
+	}
+	return true, nil
+}
+
+// timestampCheckingValidatorForTests accepts every block but enforces the median-time-past rule.
+type timestampCheckingValidatorForTests struct {
+	blockValidatorForTests
+}
+
+func (b *timestampCheckingValidatorForTests) ValidateTimestamp(header block.BlockHeader, medianTimePast int64) (bool, error) {
+	if header.Timestamp <= medianTimePast {
+		return false, fmt.Errorf("timestamp %d is not later than the median-time-past %d", header.Timestamp, medianTimePast)
+	}
+	return true, nil
+}
+
+// chainStateValidatorForTests fully validates a block only if the UTXO set is at its previous block, like the real validator.
+// Blocks listed in invalid fail full validation.
+type chainStateValidatorForTests struct {
+	blockValidatorForTests
+	utxoService *mockUTXOService
+	invalid     map[common.Hash]bool
+}
+
+func (b *chainStateValidatorForTests) FullValidation(blockToValidate block.Block) (bool, error) {
+	if b.utxoService.GetChainTip() != blockToValidate.Header.PreviousBlockHash {
+		return false, fmt.Errorf("%w: block %v", validation.ErrChainStateUnavailable, blockToValidate.Hash())
+	}
+	if b.invalid[blockToValidate.Hash()] {
+		return false, fmt.Errorf("block %v is invalid", blockToValidate.Hash())
+	}
+	return true, nil
+}
+
 // mockUTXOService is a mock for UtxoStoreAPI that tracks state changes
 type mockUTXOService struct {
 	// blockHashToPool maps block hash to its UTXO pool
 	blockHashToPool map[common.Hash]map[testOutpoint]transaction.Output
 	blockStore      blockchain.BlockStoreAPI
+	tip             common.Hash
 
 	// Tracking for test assertions
 	addNewBlockCalled           int
+	disconnectBlockCalled       int
 	initializeGenesisPoolCalled int
 }
 
@@ -53,6 +115,7 @@ func (m *mockUTXOService) InitializeGenesisPool(genesisBlock block.Block) error
 	m.initializeGenesisPoolCalled++
 
 	genesisHash := genesisBlock.Hash()
+	m.tip = genesisHash
 	if _, exists := m.blockHashToPool[genesisHash]; exists {
 		return nil
 	}
@@ -78,6 +141,7 @@ func (m *mockUTXOService) AddNewBlock(newBlock block.Block) error {
 	m.addNewBlockCalled++
 
 	newBlockHash := newBlock.Hash()
+	m.tip = newBlockHash
 
 	// Skip if already exists
 	if _, exists := m.blockHashToPool[newBlockHash]; exists {
@@ -121,6 +185,18 @@ func (m *mockUTXOService) AddNewBlock(newBlock block.Block) error {
 	return nil
 }
 
+// DisconnectBlock moves the tip back to the previous block. The pool of the block is kept.
+func (m *mockUTXOService) DisconnectBlock(blk block.Block) error {
+	m.disconnectBlockCalled++
+	m.tip = blk.Header.PreviousBlockHash
+	return nil
+}
+
+// GetChainTip returns the last connected block.
+func (m *mockUTXOService) GetChainTip() common.Hash {
+	return m.tip
+}
+
 // GetUtxoFromBlock retrieves a specific UTXO from a given block's UTXO pool.
 func (m *mockUTXOService) GetUtxoFromBlock(prevTxID transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.Output, error) {
 	pool, exists := m.blockHashToPool[blockHash]
@@ -135,6 +211,12 @@ func (m *mockUTXOService) GetUtxoFromBlock(prevTxID transaction.TransactionID, o
 	return output, nil
 }
 
+// GetUtxoEntryFromBlock wraps GetUtxoFromBlock, creation heights are not tracked by this mock.
+func (m *mockUTXOService) GetUtxoEntryFromBlock(prevTxID transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.UTXO, error) {
+	output, err := m.GetUtxoFromBlock(prevTxID, outputIndex, blockHash)
+	return transaction.UTXO{TxID: prevTxID, OutputIndex: outputIndex, Output: output}, err
+}
+
 // ValidateBlock checks if all inputs reference valid UTXOs.
 func (m *mockUTXOService) ValidateBlock(blockToValidate block.Block) bool {
 	prevBlockHash := blockToValidate.Header.PreviousBlockHash
@@ -194,6 +276,15 @@ func (m *mockUTXOService) GetUtxosByPubKeyHashFromBlock(pubKeyHash transaction.P
 	return utxos, nil
 }
 
+// GetBlockHeight returns 0, block heights are not tracked by this mock.
+func (m *mockUTXOService) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+
+func (m *mockUTXOService) GetAncestorMedianTimePast(_ common.Hash, _ uint64) (int64, error) {
+	return 0, nil
+}
+
 // mockValidatorForMempool is a mock that always validates successfully
 type mockValidatorForMempool struct{}
 
@@ -201,6 +292,18 @@ func (m *mockValidatorForMempool) ValidateTransaction(_ transaction.Transaction,
 	return true, nil
 }
 
+func (m *mockValidatorForMempool) ValidateTransactionWithOverlay(_ transaction.Transaction, _ common.Hash, _ validation.UtxoOverlay) (bool, error) {
+	return true, nil
+}
+
+func (m *mockValidatorForMempool) GetTransactionFee(_ transaction.Transaction, _ common.Hash, _ validation.UtxoOverlay) (uint64, error) {
+	return 1, nil
+}
+
+func (m *mockValidatorForMempool) VerifySignatures(_ transaction.Transaction, _ common.Hash, _ validation.UtxoOverlay) error {
+	return nil
+}
+
 func (m *mockValidatorForMempool) GetBlockHeightDifferenceByTxId(txID transaction.TransactionID) (int, error) {
 	return -1, nil
 }
@@ -271,12 +374,12 @@ func createNonCoinbaseTransaction(prevTxID transaction.TransactionID, outputInde
 func TestBlockStore_ReorganizationNoReorg(t *testing.T) {
 	// Arrange
 	genesis := createBlockWithDifficulty([32]byte{}, 0, 10)
-	store := blockchain.NewBlockStore(genesis, nil)
+	store := blockchain.NewBlockStore(genesis, &blockValidatorForTests{})
 	utxoService := newMockUTXOService(store)
 	_ = utxoService.InitializeGenesisPool(genesis)
 	mempool := NewMempool(&mockValidatorForMempool{}, store)
 
-	reorg := NewChainReorganization(store, utxoService, mempool, genesis.Hash())
+	reorg := NewChainReorganization(store, utxoService, mempool, &blockValidatorForTests{})
 
 	// Build main chain
 	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 10)
@@ -314,7 +417,7 @@ func TestBlockStore_ReorganizationSimpleFork(t *testing.T) {
 	_ = utxoService.InitializeGenesisPool(genesis)
 	mempool := NewMempool(&mockValidatorForMempool{}, store)
 
-	reorg := NewChainReorganization(store, utxoService, mempool, genesis.Hash())
+	reorg := NewChainReorganization(store, utxoService, mempool, &blockValidatorForTests{})
 
 	// Build initial main chain (lower difficulty)
 	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
@@ -342,8 +445,10 @@ func TestBlockStore_ReorganizationSimpleFork(t *testing.T) {
 	tip := store.GetMainChainTip()
 	assert.Equal(t, side2.Hash(), tip.Hash(), "New tip should be side2")
 
-	// Verify UTXO pools were created for the new chain
+	// Verify old chain was disconnected and UTXO pools were created for the new chain
+	assert.Equal(t, 2, utxoService.disconnectBlockCalled, "Should have disconnected b2 and b1 from UTXO store")
 	assert.True(t, utxoService.addNewBlockCalled >= 2, "Should have added blocks to UTXO store")
+	assert.Equal(t, side2.Hash(), utxoService.GetChainTip(), "UTXO chain tip should be side2")
 }
 
 // TestBlockStore_ReorganizationWithUTXOState tests that UTXO state is correctly managed during reorganization
@@ -359,7 +464,7 @@ func TestBlockStore_ReorganizationWithUTXOState(t *testing.T) {
 	_ = utxoService.InitializeGenesisPool(genesis)
 	mempool := NewMempool(&mockValidatorForMempool{}, store)
 
-	reorg := NewChainReorganization(store, utxoService, mempool, genesis.Hash())
+	reorg := NewChainReorganization(store, utxoService, mempool, &blockValidatorForTests{})
 
 	// Create block with a specific coinbase output
 	pubKeyHash1 := transaction.PubKeyHash{1, 2, 3}
@@ -433,7 +538,7 @@ func TestBlockStore_ReorganizationLongerChainRollback(t *testing.T) {
 	_ = utxoService.InitializeGenesisPool(genesis)
 	mempool := NewMempool(&mockValidatorForMempool{}, store)
 
-	reorg := NewChainReorganization(store, utxoService, mempool, genesis.Hash())
+	reorg := NewChainReorganization(store, utxoService, mempool, &blockValidatorForTests{})
 
 	// Build initial chain: g -> b1 -> b2 -> b3
 	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
@@ -488,7 +593,7 @@ func TestBlockStore_ReorganizationWithMempool(t *testing.T) {
 	// Create a mempool with a validator that will pass
 	mempool := NewMempool(&mockValidatorForMempool{}, store)
 
-	reorg := NewChainReorganization(store, utxoService, mempool, genesis.Hash())
+	reorg := NewChainReorganization(store, utxoService, mempool, &blockValidatorForTests{})
 
 	// Create blocks with non-coinbase transactions
 	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
@@ -531,7 +636,7 @@ func TestBlockStore_ReorganizationIdempotent(t *testing.T) {
 	_ = utxoService.InitializeGenesisPool(genesis)
 	mempool := NewMempool(&mockValidatorForMempool{}, store)
 
-	reorg := NewChainReorganization(store, utxoService, mempool, genesis.Hash())
+	reorg := NewChainReorganization(store, utxoService, mempool, &blockValidatorForTests{})
 
 	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
 	store.AddBlock(block1)
@@ -562,7 +667,7 @@ func TestBlockStore_ReorganizationStateConsistency(t *testing.T) {
 	_ = utxoService.InitializeGenesisPool(genesis)
 	mempool := NewMempool(&mockValidatorForMempool{}, store)
 
-	reorg := NewChainReorganization(store, utxoService, mempool, genesis.Hash())
+	reorg := NewChainReorganization(store, utxoService, mempool, &blockValidatorForTests{})
 
 	// Build chain
 	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
@@ -602,6 +707,162 @@ func TestBlockStore_ReorganizationStateConsistency(t *testing.T) {
 	assert.True(t, block1PoolStillExists, "Block1 UTXO pool should still exist (immutable)")
 }
 
+// TestChainReorganization_RefusesInvalidBlock verifies that a block marked invalid by the block store is never connected,
+// even if it is passed as the new tip
+// Chain structure:
+// (g) -> (b1) -> (b2) [b2 added before b1, b2 has a wrong difficulty target]
+func TestChainReorganization_RefusesInvalidBlock(t *testing.T) {
+	// Arrange
+	genesis := createBlockWithDifficulty([32]byte{}, 0, 5)
+	store := blockchain.NewBlockStore(genesis, &difficultyCheckingValidatorForTests{})
+	utxoService := newMockUTXOService(store)
+	_ = utxoService.InitializeGenesisPool(genesis)
+	reorg := NewChainReorganization(store, utxoService, NewMempool(&mockValidatorForMempool{}, store), &blockValidatorForTests{})
+
+	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
+	block2 := createBlockWithDifficulty(block1.Hash(), 2, 10)
+	store.AddBlock(block2)
+	store.AddBlock(block1)
+
+	// Act
+	tip := store.GetMainChainTip()
+	_, errTip := reorg.CheckAndReorganize(tip.Hash())
+	_, errInvalid := reorg.CheckAndReorganize(block2.Hash())
+
+	// Assert
+	assert.NoError(t, errTip)
+	assert.Equal(t, block1.Hash(), utxoService.GetChainTip(), "The main chain should end before the invalid block")
+	assert.Error(t, errInvalid, "Connecting an invalid block should fail")
+	assert.Equal(t, block1.Hash(), utxoService.GetChainTip(), "The invalid block must not be connected")
+}
+
+// TestChainReorganization_SkipsBlockBeforeMedianTimePast verifies that a block failing the median-time-past rule
+// only once its parent arrives is not connected to the UTXO set
+// Chain structure:
+// (g) -> (b1) -> (b2) [b2 added before b1, b2 has a timestamp before the median-time-past]
+func TestChainReorganization_SkipsBlockBeforeMedianTimePast(t *testing.T) {
+	// Arrange
+	genesis := createBlockWithDifficulty([32]byte{}, 0, 5)
+	store := blockchain.NewBlockStore(genesis, &timestampCheckingValidatorForTests{})
+	utxoService := newMockUTXOService(store)
+	_ = utxoService.InitializeGenesisPool(genesis)
+	reorg := NewChainReorganization(store, utxoService, NewMempool(&mockValidatorForMempool{}, store), &blockValidatorForTests{})
+
+	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
+	block2 := createBlockWithDifficulty(block1.Hash(), 2, 5)
+	block2.Header.Timestamp = genesis.Header.Timestamp - 1
+	store.AddBlock(block2)
+	store.AddBlock(block1)
+
+	// Act
+	tip := store.GetMainChainTip()
+	_, err := reorg.CheckAndReorganize(tip.Hash())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, block1.Hash(), tip.Hash(), "The invalid block must not become the main chain tip")
+	assert.Equal(t, block1.Hash(), utxoService.GetChainTip(), "The invalid block must not be connected")
+}
+
+// TestChainReorganization_ConnectsBlocksDeliveredOutOfOrder verifies that blocks arriving before their parent
+// are validated and connected once the parent arrives
+// Chain structure:
+// (g) -> (b1) -> (b2) [b2 added before b1]
+func TestChainReorganization_ConnectsBlocksDeliveredOutOfOrder(t *testing.T) {
+	// Arrange
+	genesis := createBlockWithDifficulty([32]byte{}, 0, 5)
+	store := blockchain.NewBlockStore(genesis, &blockValidatorForTests{})
+	utxoService := newMockUTXOService(store)
+	_ = utxoService.InitializeGenesisPool(genesis)
+	validator := &chainStateValidatorForTests{utxoService: utxoService}
+	reorg := NewChainReorganization(store, utxoService, NewMempool(&mockValidatorForMempool{}, store), validator)
+
+	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
+	block2 := createBlockWithDifficulty(block1.Hash(), 2, 5)
+	store.AddBlock(block2)
+	store.AddBlock(block1)
+
+	// Act
+	tip := store.GetMainChainTip()
+	_, err := reorg.CheckAndReorganize(tip.Hash())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, block2.Hash(), tip.Hash())
+	assert.Equal(t, block2.Hash(), utxoService.GetChainTip(), "Both blocks should be connected")
+	isInvalid, _ := store.IsBlockInvalid(block2)
+	assert.False(t, isInvalid, "A block arriving before its parent must not be marked invalid")
+}
+
+// TestChainReorganization_ValidatesSideChainOnConnect verifies that a side chain deeper than one block
+// is validated block by block while it is connected
+// Chain structure:
+// Initial: (g) -> (b1) [main chain]
+// New chain: (g) -> (s1) -> (s2) [becomes main with higher work]
+func TestChainReorganization_ValidatesSideChainOnConnect(t *testing.T) {
+	// Arrange
+	genesis := createBlockWithDifficulty([32]byte{}, 0, 5)
+	store := blockchain.NewBlockStore(genesis, &blockValidatorForTests{})
+	utxoService := newMockUTXOService(store)
+	_ = utxoService.InitializeGenesisPool(genesis)
+	validator := &chainStateValidatorForTests{utxoService: utxoService}
+	reorg := NewChainReorganization(store, utxoService, NewMempool(&mockValidatorForMempool{}, store), validator)
+
+	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
+	store.AddBlock(block1)
+	_, _ = reorg.CheckAndReorganize(block1.Hash())
+
+	side1 := createBlockWithDifficulty(genesis.Hash(), 10, 5)
+	side2 := createBlockWithDifficulty(side1.Hash(), 11, 5)
+	store.AddBlock(side1)
+	store.AddBlock(side2)
+
+	// Act
+	didReorg, err := reorg.CheckAndReorganize(side2.Hash())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, didReorg)
+	assert.Equal(t, side2.Hash(), utxoService.GetChainTip(), "The side chain should be connected")
+}
+
+// TestChainReorganization_MarksFailingBlockInvalid verifies that a block failing full validation while it is connected
+// is marked invalid and the UTXO set moves to the best remaining chain
+// Chain structure:
+// Initial: (g) -> (b1) [main chain]
+// New chain: (g) -> (s1) -> (s2) [s2 fails full validation]
+func TestChainReorganization_MarksFailingBlockInvalid(t *testing.T) {
+	// Arrange
+	genesis := createBlockWithDifficulty([32]byte{}, 0, 5)
+	store := blockchain.NewBlockStore(genesis, &blockValidatorForTests{})
+	utxoService := newMockUTXOService(store)
+	_ = utxoService.InitializeGenesisPool(genesis)
+
+	block1 := createBlockWithDifficulty(genesis.Hash(), 1, 5)
+	side1 := createBlockWithDifficulty(genesis.Hash(), 10, 10)
+	side2 := createBlockWithDifficulty(side1.Hash(), 11, 10)
+
+	validator := &chainStateValidatorForTests{utxoService: utxoService, invalid: map[common.Hash]bool{side2.Hash(): true}}
+	reorg := NewChainReorganization(store, utxoService, NewMempool(&mockValidatorForMempool{}, store), validator)
+
+	store.AddBlock(block1)
+	_, _ = reorg.CheckAndReorganize(block1.Hash())
+	store.AddBlock(side1)
+	store.AddBlock(side2)
+
+	// Act
+	didReorg, err := reorg.CheckAndReorganize(side2.Hash())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, didReorg)
+	isInvalid, _ := store.IsBlockInvalid(side2)
+	assert.True(t, isInvalid, "The failing block should be marked invalid")
+	tip := store.GetMainChainTip()
+	assert.Equal(t, side1.Hash(), tip.Hash(), "The best remaining chain should end before the invalid block")
+	assert.Equal(t, side1.Hash(), utxoService.GetChainTip(), "The UTXO set should move to the best remaining chain")
+}
+
 // TestBlockStore_AccumulatedWorkSelection verifies that main chain selection is based on accumulated work
 func TestBlockStore_AccumulatedWorkSelection(t *testing.T) {
 	// Arrange
@@ -630,7 +891,7 @@ func TestBlockStore_AccumulatedWorkSelection(t *testing.T) {
 	assert.True(t, tipHash == block2b.Hash() || tipHash == block2a.Hash(),
 		"Tip should be one of the chain tips")
 
-	// Since chain B has much higher accumulated work (15+15=30 vs 3+3=6),
+	// Since chain B has much higher accumulated work (2^15+2^15 vs 2^3+2^3),
 	// block2b should be the main chain tip
 	assert.Equal(t, block2b.Hash(), tipHash,
 		"Higher accumulated work chain should be main chain")
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/validation"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/inv"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
@@ -17,20 +18,31 @@ func (b *Blockchain) Tx(tx transaction.Transaction, peerID common.PeerId) {
 
 	logger.Infof("[transaction_handler] Tx Message received: %v from %v", &tx, peerID)
 
-	mainChainTip := b.blockStore.GetMainChainTip()
-	mainChainTipHash := mainChainTip.Hash()
-	isValid, err := b.transactionValidator.ValidateTransaction(tx, mainChainTipHash)
-	if !isValid {
-		logger.Warnf("[transaction_handler] Tx Message received from %v is invalid: %v", peerID, err)
+	if err := checkMempoolPolicy(tx); err != nil {
+		logger.Warnf("[transaction_handler] Tx Message received from %v is not accepted: %v", peerID, err)
 		txId := tx.TransactionId()
 		b.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "tx", txId[:])
 		return
 	}
+
 	if b.mempool.IsKnownTransactionId(tx.TransactionId()) {
 		logger.Infof("[transaction_handler] Tx Message already known: %v from %v", &tx, peerID)
 		return
 	}
 
+	// The transaction may spend outputs of unconfirmed transactions in the mempool
+	isValid, err := b.mempool.ValidateTransaction(tx)
+	if !isValid {
+		logger.Warnf("[transaction_handler] Tx Message received from %v is invalid: %v", peerID, err)
+		txId := tx.TransactionId()
+		b.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "tx", txId[:])
+		// Missing inputs, conflicts and policy violations may be caused by a different view of the chain or the mempool
+		if validation.IsInvalidTransaction(err) {
+			b.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorInvalidTransaction, "invalid transaction")
+		}
+		return
+	}
+
 	isNew := b.mempool.AddTransaction(tx)
 	if isNew {
 		invVectors := make([]*inv.InvVector, 0)
@@ -0,0 +1,183 @@
+package utxo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/storage"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// maxReorgDepth is the number of blocks below the chain tip whose undo records are kept in full.
+// Older undo records are pruned, so reorganizations deeper than this are not possible.
+const maxReorgDepth = 100
+
+// defaultCompactionInterval is the number of connected and disconnected blocks after which the chain state is compacted.
+const defaultCompactionInterval = 1000
+
+// compactIfDue counts a change of the chain state and compacts the chain state every compactionInterval changes.
+// Caller must hold the lock.
+func (us *UtxoStore) compactIfDue() {
+	us.changesSinceCompaction++
+	if us.changesSinceCompaction < us.compactionInterval {
+		return
+	}
+
+	if err := us.compact(); err != nil {
+		// The old chain state file is still intact, so compacting is tried again after the next change
+		logger.Errorf("[utxoStore] failed to compact chain state: %v", err)
+		return
+	}
+	us.changesSinceCompaction = 0
+}
+
+// compact prunes the undo records that are more than maxReorgDepth blocks below the chain tip
+// and replaces the chain state file by a snapshot of the current chain state.
+// Without compaction the undo records and the file, which contains every block ever connected or disconnected, would grow forever.
+// Caller must hold the lock.
+func (us *UtxoStore) compact() error {
+	us.pruneUndo()
+
+	if us.file == nil {
+		return nil
+	}
+
+	records := us.snapshotRecords()
+
+	tempPath := us.path + ".tmp"
+	if err := os.Remove(tempPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale chain state file %s: %w", tempPath, err)
+	}
+
+	file, _, err := storage.OpenRecordFile(tempPath)
+	if err != nil {
+		return err
+	}
+	if err := file.Append(records...); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write chain state file %s: %w", tempPath, err)
+	}
+	// The open file keeps pointing to the snapshot after the rename, so new records are appended to it
+	if err := os.Rename(tempPath, us.path); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to replace chain state file %s: %w", us.path, err)
+	}
+
+	_ = us.file.Close()
+	us.file = file
+
+	logger.Infof("[utxoStore] compacted chain state at block %v with %d UTXOs", us.tip, len(us.utxos))
+	return nil
+}
+
+// pruneUndo drops the spent and created outputs of all undo records more than maxReorgDepth blocks below the chain tip.
+// Caller must hold the lock.
+func (us *UtxoStore) pruneUndo() {
+	tipHeight := us.undo[us.tip].Height
+	if tipHeight <= maxReorgDepth {
+		return
+	}
+
+	for blockHash, undo := range us.undo {
+		if undo.Pruned || undo.Height >= tipHeight-maxReorgDepth {
+			continue
+		}
+		undo.Spent = nil
+		undo.Created = nil
+		undo.Pruned = true
+		us.undo[blockHash] = undo
+	}
+	us.cachedView = nil
+}
+
+// snapshotRecords returns the records of a snapshot of the chain state:
+// a snapshot record with the tip and the connected blocks, followed by a record per UTXO and a record per undo record.
+// Caller must hold the lock.
+func (us *UtxoStore) snapshotRecords() []storage.Record {
+	records := make([]storage.Record, 0, 1+len(us.utxos)+len(us.undo))
+	records = append(records, storage.Record{Kind: recordKindSnapshot, Payload: serializeSnapshotRecord(us.tip, us.connected)})
+
+	for op, utxoCoin := range us.utxos {
+		records = append(records, storage.Record{Kind: recordKindCoin, Payload: appendEntry(nil, utxoEntry{Outpoint: op, Coin: utxoCoin})})
+	}
+
+	for blockHash, undo := range us.undo {
+		kind := recordKindUndo
+		if undo.Pruned {
+			kind = recordKindPrunedUndo
+		}
+		records = append(records, storage.Record{Kind: kind, Payload: serializeConnectRecord(blockHash, undo)})
+	}
+
+	return records
+}
+
+// restoreSnapshotRecord restores a record of a snapshot, see snapshotRecords.
+// A snapshot record replaces the whole chain state restored so far.
+func (us *UtxoStore) restoreSnapshotRecord(record storage.Record) error {
+	switch record.Kind {
+	case recordKindSnapshot:
+		tip, connected, err := deserializeSnapshotRecord(record.Payload)
+		if err != nil {
+			return err
+		}
+		us.tip = tip
+		us.connected = connected
+		us.utxos = make(map[outpoint]coin)
+		us.undo = make(map[common.Hash]blockUndo)
+		us.cachedView = nil
+		us.changesSinceCompaction = 0
+	case recordKindCoin:
+		var entry utxoEntry
+		r := bytes.NewReader(record.Payload)
+		if err := readEntry(r, &entry); err != nil || r.Len() != 0 {
+			return errMalformedUndo
+		}
+		us.utxos[entry.Outpoint] = entry.Coin
+	case recordKindUndo, recordKindPrunedUndo:
+		blockHash, undo, err := deserializeConnectRecord(record.Payload)
+		if err != nil {
+			return err
+		}
+		undo.Pruned = record.Kind == recordKindPrunedUndo
+		us.undo[blockHash] = undo
+	}
+	return nil
+}
+
+// serializeSnapshotRecord encodes the tip and the connected blocks of the chain state.
+// Format: [tip hash][connected count uint32][connected block hashes]
+func serializeSnapshotRecord(tip common.Hash, connected map[common.Hash]struct{}) []byte {
+	buffer := make([]byte, 0, common.HashSize+4+len(connected)*common.HashSize)
+	buffer = append(buffer, tip[:]...)
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(len(connected)))
+	for blockHash := range connected {
+		buffer = append(buffer, blockHash[:]...)
+	}
+	return buffer
+}
+
+// deserializeSnapshotRecord decodes a record produced by serializeSnapshotRecord.
+func deserializeSnapshotRecord(data []byte) (common.Hash, map[common.Hash]struct{}, error) {
+	if len(data) < common.HashSize+4 {
+		return common.Hash{}, nil, errMalformedUndo
+	}
+
+	tip := common.Hash(data[:common.HashSize])
+	count := binary.LittleEndian.Uint32(data[common.HashSize:])
+	data = data[common.HashSize+4:]
+	if uint64(len(data)) != uint64(count)*common.HashSize {
+		return common.Hash{}, nil, errMalformedUndo
+	}
+
+	connected := make(map[common.Hash]struct{}, count)
+	for i := 0; i < len(data); i += common.HashSize {
+		connected[common.Hash(data[i:i+common.HashSize])] = struct{}{}
+	}
+
+	return tip, connected, nil
+}
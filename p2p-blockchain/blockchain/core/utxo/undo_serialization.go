@@ -0,0 +1,142 @@
+package utxo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+)
+
+var errMalformedUndo = errors.New("malformed undo record")
+
+// minUtxoEntrySize is the minimum size of a serialized utxoEntry:
+// txID (32) + output index (4) + height (8) + coinbase flag (1) + output with a public key hash (value (8) + type (1) + public key hash (20)).
+// Outputs with a bare multisig script are larger, see transaction.Output.Serialize.
+const minUtxoEntrySize = common.HashSize + 4 + 8 + 1 + 8 + 1 + common.PublicKeyHashSize
+
+// connectRecordHeaderSize is the size of the fixed part of a connect record: block hash + prev block hash + height + timestamp.
+const connectRecordHeaderSize = 2*common.HashSize + 8 + 8
+
+// serializeConnectRecord encodes the hash of a connected block and its undo record.
+// Format: [block hash][prev block hash][height uint64][timestamp int64][spent count uint32][spent entries][created count uint32][created entries]
+// Entry format: [txID][output index uint32][height uint64][coinbase flag uint8][output]
+func serializeConnectRecord(blockHash common.Hash, undo blockUndo) []byte {
+	buffer := make([]byte, 0, connectRecordHeaderSize+8+(len(undo.Spent)+len(undo.Created))*minUtxoEntrySize)
+	buffer = append(buffer, blockHash[:]...)
+	buffer = append(buffer, undo.PrevBlockHash[:]...)
+	buffer = binary.LittleEndian.AppendUint64(buffer, undo.Height)
+	buffer = binary.LittleEndian.AppendUint64(buffer, uint64(undo.Timestamp))
+	buffer = appendEntries(buffer, undo.Spent)
+	buffer = appendEntries(buffer, undo.Created)
+	return buffer
+}
+
+func appendEntries(buffer []byte, entries []utxoEntry) []byte {
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(len(entries)))
+	for _, entry := range entries {
+		buffer = appendEntry(buffer, entry)
+	}
+	return buffer
+}
+
+func appendEntry(buffer []byte, entry utxoEntry) []byte {
+	buffer = append(buffer, entry.Outpoint.TxID[:]...)
+	buffer = binary.LittleEndian.AppendUint32(buffer, entry.Outpoint.OutputIndex)
+	buffer = binary.LittleEndian.AppendUint64(buffer, entry.Coin.Height)
+	buffer = append(buffer, boolToByte(entry.Coin.IsCoinbase))
+	buffer = append(buffer, entry.Coin.Output.Serialize()...)
+	return buffer
+}
+
+// deserializeConnectRecord decodes a record produced by serializeConnectRecord.
+func deserializeConnectRecord(data []byte) (common.Hash, blockUndo, error) {
+	if len(data) < connectRecordHeaderSize {
+		return common.Hash{}, blockUndo{}, errMalformedUndo
+	}
+
+	blockHash := common.Hash(data[:common.HashSize])
+	undo := blockUndo{
+		PrevBlockHash: common.Hash(data[common.HashSize : 2*common.HashSize]),
+		Height:        binary.LittleEndian.Uint64(data[2*common.HashSize:]),
+		Timestamp:     int64(binary.LittleEndian.Uint64(data[2*common.HashSize+8:])),
+	}
+	data = data[connectRecordHeaderSize:]
+
+	var err error
+	undo.Spent, data, err = readEntries(data)
+	if err != nil {
+		return common.Hash{}, blockUndo{}, err
+	}
+
+	undo.Created, data, err = readEntries(data)
+	if err != nil {
+		return common.Hash{}, blockUndo{}, err
+	}
+
+	if len(data) != 0 {
+		return common.Hash{}, blockUndo{}, errMalformedUndo
+	}
+
+	return blockHash, undo, nil
+}
+
+// readEntries reads a length prefixed list of entries and returns the remaining data.
+func readEntries(data []byte) ([]utxoEntry, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, errMalformedUndo
+	}
+
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	if uint64(count)*minUtxoEntrySize > uint64(len(data)) {
+		return nil, nil, errMalformedUndo
+	}
+
+	r := bytes.NewReader(data)
+	entries := make([]utxoEntry, count)
+	for i := range entries {
+		if err := readEntry(r, &entries[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return entries, data[len(data)-r.Len():], nil
+}
+
+func readEntry(r *bytes.Reader, entry *utxoEntry) error {
+	var header [common.HashSize + 4 + 8 + 1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return errMalformedUndo
+	}
+
+	offset := copy(entry.Outpoint.TxID[:], header[:])
+	entry.Outpoint.OutputIndex = binary.LittleEndian.Uint32(header[offset:])
+	offset += 4
+	entry.Coin.Height = binary.LittleEndian.Uint64(header[offset:])
+	offset += 8
+	switch header[offset] {
+	case 0:
+	case 1:
+		entry.Coin.IsCoinbase = true
+	default:
+		return errMalformedUndo
+	}
+
+	output, err := transaction.DeserializeOutput(r)
+	if err != nil {
+		return errMalformedUndo
+	}
+	entry.Coin.Output = output
+
+	return nil
+}
+
+func boolToByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
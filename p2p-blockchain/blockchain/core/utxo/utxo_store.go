@@ -2,39 +2,78 @@ package utxo
 
 import (
 	"fmt"
+	"path/filepath"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/blockchain"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/storage"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"slices"
 	"sync"
 
-	"bjoernblessin.de/go-utils/util/assert"
 	"bjoernblessin.de/go-utils/util/logger"
 )
 
 // UtxoStoreAPI provides UTXO set management for the blockchain.
+//
+// The store holds a single UTXO set (the chain state) for the block it was last moved to, the chain tip.
+// Blocks are connected on top of the chain tip with AddNewBlock and disconnected from it with DisconnectBlock.
+// Each connected block leaves an undo record (the outputs it spent and created), so the UTXO set of any
+// block that was connected at some point can be reconstructed without storing a copy of the whole set per block.
+// Undo records of blocks more than maxReorgDepth blocks below the chain tip are pruned to their height and timestamp.
 type UtxoStoreAPI interface {
-	// InitializeGenesisPool creates the UTXO pool for the genesis block.
+	// InitializeGenesisPool connects the genesis block as the first block of the chain state.
 	// This must be called before AddNewBlock can process any blocks.
+	// Does nothing if the chain state already contains the genesis block (e.g. after a restart).
 	InitializeGenesisPool(genesisBlock block.Block) error
 
-	// AddNewBlock updates the UTXO set with a new block's transactions.
-	// Precondition: the previous block's UTXO pool must exist and it must have been added to the block_store via the
-	// AddBlock() method on the blockStore. For this the previous block must have been added already.
+	// AddNewBlock connects the block on top of the chain tip.
+	// Spent outputs are removed from the UTXO set, new outputs are added and an undo record is stored.
+	// Precondition: the block must have been added to the block store via AddBlock and its previous block must be the current chain tip.
+	// Orphan blocks and blocks that are already connected are skipped.
+	// If the change can't be persisted, the chain state is left unchanged and an error is returned.
 	AddNewBlock(block block.Block) error
 
-	// GetUtxoFromBlock retrieves a specific UTXO from a given block's UTXO pool.
+	// DisconnectBlock disconnects the block from the top of the chain state by replaying its undo record.
+	// Outputs created by the block are removed and outputs spent by the block are restored.
+	// Afterward the previous block of the given block is the chain tip.
+	// Precondition: the block must be the current chain tip and its undo record must not be pruned.
+	// If the change can't be persisted, the chain state is left unchanged and an error is returned.
+	DisconnectBlock(block block.Block) error
+
+	// GetChainTip returns the hash of the block the UTXO set currently belongs to.
+	GetChainTip() common.Hash
+
+	// GetUtxoFromBlock retrieves a specific UTXO from the UTXO set as of the given block.
+	// Works for the chain tip and for all blocks that can be reached from the tip by replaying undo records.
 	GetUtxoFromBlock(prevTxID transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.Output, error)
 
+	// GetUtxoEntryFromBlock retrieves a specific UTXO together with its creation height and coinbase flag from the UTXO set as of the given block.
+	// Works for the chain tip and for all blocks that can be reached from the tip by replaying undo records.
+	GetUtxoEntryFromBlock(prevTxID transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.UTXO, error)
+
 	// ValidateTransactionsOfBlock checks if all inputs in the block's transactions reference valid UTXOs.
-	// Precondition: the previous block's UTXO pool must exist. For this the previous block must have been added already.
+	// Precondition: the UTXO set of the previous block must be reachable, see GetUtxoFromBlock.
 	ValidateTransactionsOfBlock(blockToValidate block.Block) bool
 
 	// ValidateTransactionFromBlock checks if a transaction is valid against the UTXO set at a specific block.
 	ValidateTransactionFromBlock(tx transaction.Transaction, blockHash common.Hash) bool
 
-	// GetUtxosByPubKeyHashFromBlock retrieves all UTXOs associated with a public key hash from a specific block's UTXO pool.
+	// GetUtxosByPubKeyHashFromBlock retrieves all UTXOs associated with a public key hash from the UTXO set as of the given block.
+	// For a multisig script hash the UTXOs of both multisig output types with that script are returned, see transaction.Output.AddressHash.
+	// The UTXOs include their creation height and coinbase flag.
+	// Works for the chain tip and for all blocks that can be reached from the tip by replaying undo records.
 	GetUtxosByPubKeyHashFromBlock(pubKeyHash transaction.PubKeyHash, blockHash common.Hash) ([]transaction.UTXO, error)
+
+	// GetBlockHeight returns the height of the given block.
+	// Works for all blocks whose UTXO set is reachable, see GetUtxoFromBlock.
+	GetBlockHeight(blockHash common.Hash) (uint64, error)
+
+	// GetAncestorMedianTimePast returns the median-time-past of the ancestor at the given height of the given block,
+	// i.e. the median timestamp of that ancestor and its block.MedianTimePastWindow-1 previous blocks.
+	// Pass the height of the block itself to get its own median-time-past.
+	// Works for all blocks whose UTXO set is reachable, see GetUtxoFromBlock.
+	GetAncestorMedianTimePast(blockHash common.Hash, height uint64) (int64, error)
 }
 
 // outpoint uniquely identifies a UTXO by transaction ID and output index
@@ -43,71 +82,228 @@ type outpoint struct {
 	OutputIndex uint32
 }
 
-// utxoPool holds the UTXO set for a specific block.
-type utxoPool struct {
-	UtxoData map[outpoint]transaction.Output
+// coin is an unspent output together with the metadata of the transaction that created it.
+type coin struct {
+	Output transaction.Output
+	// Height is the height of the block that created the output.
+	Height uint64
+	// IsCoinbase is true if the output was created by a coinbase transaction.
+	IsCoinbase bool
+}
+
+// utxoEntry is a single UTXO stored in an undo record.
+type utxoEntry struct {
+	Outpoint outpoint
+	Coin     coin
+}
+
+// toUTXO converts the coin at the given outpoint to a transaction.UTXO.
+func (c coin) toUTXO(op outpoint) transaction.UTXO {
+	return transaction.UTXO{
+		TxID:        op.TxID,
+		OutputIndex: op.OutputIndex,
+		Output:      c.Output,
+		Height:      c.Height,
+		IsCoinbase:  c.IsCoinbase,
+	}
 }
 
-// UtxoStore manages UTXO pools for each block in the blockchain.
+// blockUndo holds everything needed to move the UTXO set across a block in both directions.
+type blockUndo struct {
+	PrevBlockHash common.Hash
+	// Height is the height of the block (genesis has height 0).
+	Height uint64
+	// Timestamp is the timestamp of the block header. Needed for the median-time-past of time locks.
+	Timestamp int64
+	// Spent are the outputs spent by the block. Restored when the block is disconnected.
+	Spent []utxoEntry
+	// Created are the outputs created by the block. Removed when the block is disconnected.
+	Created []utxoEntry
+	// Pruned is true if Spent and Created were dropped because the block is more than maxReorgDepth blocks below the chain tip.
+	// The block can neither be disconnected nor be part of a view anymore, only its height and timestamp are known.
+	Pruned bool
+}
+
+const utxoFileName = "chainstate.dat"
+
+// Record kinds of the chain state file.
+const (
+	// recordKindConnect records contain the block hash followed by the serialized undo record of a connected block.
+	recordKindConnect uint8 = iota + 1
+	// recordKindDisconnect records contain the hash of a disconnected block.
+	recordKindDisconnect
+	// recordKindSnapshot records start a snapshot of the chain state, see compact. They contain the tip and the connected blocks.
+	recordKindSnapshot
+	// recordKindCoin records of a snapshot contain a single UTXO.
+	recordKindCoin
+	// recordKindUndo records of a snapshot contain the block hash followed by the serialized undo record of a block that is not connected anew.
+	recordKindUndo
+	// recordKindPrunedUndo records of a snapshot are recordKindUndo records of pruned undo records.
+	recordKindPrunedUndo
+)
+
+// UtxoStore manages the UTXO set of the chain tip and the undo records of all connected blocks.
 type UtxoStore struct {
-	blockHashToPool   map[common.Hash]utxoPool // maps block hash to its UTXO pool
-	blockHashToPoolMu sync.RWMutex             // protects blockHashToPool
-	blockStore        blockchain.BlockStoreAPI
+	mu sync.RWMutex
+	// tip is the hash of the block the utxos belong to.
+	tip common.Hash
+	// utxos is the UTXO set as of tip.
+	utxos map[outpoint]coin
+	// undo maps the hash of every block that was connected at some point to its undo record.
+	// Kept after a block is disconnected so that side chains stay reachable.
+	undo map[common.Hash]blockUndo
+	// connected contains the hashes of all blocks between the genesis block and tip (inclusive).
+	connected map[common.Hash]struct{}
+	// cachedView caches the last view of a block other than the tip. Reset on every change of the chain state.
+	cachedView *utxoView
+
+	blockStore blockchain.BlockStoreAPI
+	// file persists all changes of the chain state. Nil if the store is in-memory only.
+	file *storage.RecordFile
+	// path is the path of the chain state file. Empty if the store is in-memory only.
+	path string
+	// changesSinceCompaction counts the blocks connected and disconnected since the last compaction.
+	changesSinceCompaction int
+	// compactionInterval is the number of changes after which the chain state is compacted, see compact.
+	compactionInterval int
 }
 
-// NewUtxoStore creates a new UTXO store with the given block store.
+// NewUtxoStore creates a new in-memory UTXO store with the given block store.
 func NewUtxoStore(blockStore blockchain.BlockStoreAPI) UtxoStoreAPI {
+	return newUtxoStore(blockStore)
+}
+
+func newUtxoStore(blockStore blockchain.BlockStoreAPI) *UtxoStore {
 	return &UtxoStore{
-		blockHashToPool: make(map[common.Hash]utxoPool),
-		blockStore:      blockStore,
+		utxos:      make(map[outpoint]coin),
+		undo:       make(map[common.Hash]blockUndo),
+		connected:  make(map[common.Hash]struct{}),
+		blockStore: blockStore,
+
+		compactionInterval: defaultCompactionInterval,
+	}
+}
+
+// NewPersistentUtxoStore creates a UTXO store that persists the chain state in the given data directory.
+// The chain state of a previous run is restored, GetChainTip returns the block it belonged to.
+func NewPersistentUtxoStore(blockStore blockchain.BlockStoreAPI, dataDir string) (*UtxoStore, error) {
+	path := filepath.Join(dataDir, utxoFileName)
+	file, records, err := storage.OpenRecordFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	us := newUtxoStore(blockStore)
+	if err := us.restore(records); err != nil {
+		_ = file.Close()
+		return nil, err
 	}
+	us.file = file
+	us.path = path
+
+	logger.Infof("[utxoStore] restored chain state at block %v with %d UTXOs", us.tip, len(us.utxos))
+
+	return us, nil
 }
 
-// InitializeGenesisPool creates the UTXO pool for the genesis block.
+// restore replays the records of the chain state file.
+// The file starts with a snapshot if the chain state was compacted before, see compact.
+func (us *UtxoStore) restore(records []storage.Record) error {
+	for i, record := range records {
+		switch record.Kind {
+		case recordKindConnect:
+			blockHash, undo, err := deserializeConnectRecord(record.Payload)
+			if err != nil {
+				return fmt.Errorf("chain state record %d: %w", i, err)
+			}
+			if err := us.connect(blockHash, undo); err != nil {
+				return fmt.Errorf("chain state record %d: %w", i, err)
+			}
+			us.changesSinceCompaction++
+		case recordKindDisconnect:
+			if len(record.Payload) != common.HashSize {
+				return fmt.Errorf("chain state record %d: %w", i, errMalformedUndo)
+			}
+			if err := us.disconnect(common.Hash(record.Payload)); err != nil {
+				return fmt.Errorf("chain state record %d: %w", i, err)
+			}
+			us.changesSinceCompaction++
+		case recordKindSnapshot, recordKindCoin, recordKindUndo, recordKindPrunedUndo:
+			if err := us.restoreSnapshotRecord(record); err != nil {
+				return fmt.Errorf("chain state record %d: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("chain state record %d has unknown kind %d", i, record.Kind)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the underlying chain state file of a persistent UTXO store.
+// Does nothing for an in-memory UTXO store.
+func (us *UtxoStore) Close() error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if us.file == nil {
+		return nil
+	}
+
+	err := us.file.Close()
+	us.file = nil
+	return err
+}
+
+// InitializeGenesisPool connects the genesis block as the first block of the chain state.
 // This must be called before AddNewBlock can process any blocks.
-// The genesis block's previous block hash is empty, so we create an empty previous pool
-// and then build the genesis pool from it.
+// Does nothing if the chain state already contains the genesis block (e.g. after a restart).
 func (us *UtxoStore) InitializeGenesisPool(genesisBlock block.Block) error {
 	genesisHash := genesisBlock.Hash()
 
-	us.blockHashToPoolMu.Lock()
-	defer us.blockHashToPoolMu.Unlock()
+	us.mu.Lock()
+	defer us.mu.Unlock()
 
 	// Check if already initialized
-	if _, exists := us.blockHashToPool[genesisHash]; exists {
-		logger.Warnf("[utxoStore] genesis block %v already exists in UTXO store, skipping", genesisHash)
+	if _, exists := us.undo[genesisHash]; exists {
+		logger.Infof("[utxoStore] genesis block %v already exists in UTXO store, skipping", genesisHash)
 		return nil
 	}
 
-	// Create an empty pool for the "previous" block (which doesn't exist for genesis)
-	emptyPrevPool := &utxoPool{UtxoData: make(map[outpoint]transaction.Output)}
+	undo := createUndoFromBlock(genesisBlock, 0, us.utxos)
+	if err := us.connectAndPersist(genesisHash, undo); err != nil {
+		return err
+	}
 
-	// Build the genesis pool using the standard method
-	genesisPool := us.createUtxoPoolFromBlock(emptyPrevPool, genesisBlock)
+	logger.Infof("[utxoStore] initialized genesis block UTXO pool with %d UTXOs", len(us.utxos))
 
-	// Store the genesis pool
-	us.blockHashToPool[genesisHash] = *genesisPool
+	return nil
+}
 
-	logger.Infof("[utxoStore] initialized genesis block UTXO pool with %d UTXOs", len(genesisPool.UtxoData))
+// GetChainTip returns the hash of the block the UTXO set currently belongs to.
+func (us *UtxoStore) GetChainTip() common.Hash {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
 
-	return nil
+	return us.tip
 }
 
 // ValidateTransactionFromBlock checks if all inputs in the transaction reference valid UTXOs
-// from the UTXO pool at the specified block hash.
+// from the UTXO set at the specified block hash.
 func (us *UtxoStore) ValidateTransactionFromBlock(tx transaction.Transaction, blockHash common.Hash) bool {
-	us.blockHashToPoolMu.RLock()
-	defer us.blockHashToPoolMu.RUnlock()
+	us.mu.Lock()
+	defer us.mu.Unlock()
 
-	return us.validateTransactionFromBlockLocked(tx, blockHash)
+	return us.validateTransactionFromBlockLocked(tx, blockHash, nil)
 }
 
-// validateTransactionFromBlockLocked checks if all inputs in the transaction reference valid UTXOs.
+// validateTransactionFromBlockLocked checks if all inputs in the transaction reference valid UTXOs
+// or outputs created by preceding transactions of the same block (createdInBlock, may be nil).
 // Caller must hold the lock.
-func (us *UtxoStore) validateTransactionFromBlockLocked(tx transaction.Transaction, blockHash common.Hash) bool {
-	prevPool, exists := us.blockHashToPool[blockHash]
-	if !exists {
-		return false // Previous block's UTXO pool not found; block is invalid
+func (us *UtxoStore) validateTransactionFromBlockLocked(tx transaction.Transaction, blockHash common.Hash, createdInBlock map[outpoint]struct{}) bool {
+	view, err := us.viewAt(blockHash)
+	if err != nil {
+		return false // UTXO set of the block not reachable; block is invalid
 	}
 
 	for _, input := range tx.Inputs {
@@ -115,7 +311,10 @@ func (us *UtxoStore) validateTransactionFromBlockLocked(tx transaction.Transacti
 			TxID:        input.PrevTxID,
 			OutputIndex: input.OutputIndex,
 		}
-		if _, exists := prevPool.UtxoData[outpoint]; !exists {
+		if _, exists := createdInBlock[outpoint]; exists {
+			continue
+		}
+		if _, exists := view.get(outpoint); !exists {
 			return false // Referenced UTXO not found; block is invalid
 		}
 	}
@@ -124,10 +323,11 @@ func (us *UtxoStore) validateTransactionFromBlockLocked(tx transaction.Transacti
 }
 
 // ValidateTransactionsOfBlock checks if all inputs in the block's transactions reference valid UTXOs.
-// Precondition: the previous block's UTXO pool must exist. For this the previous block must have been added already.
+// Inputs may also reference outputs of preceding transactions of the same block.
+// Precondition: the UTXO set of the previous block must be reachable.
 func (us *UtxoStore) ValidateTransactionsOfBlock(blockToValidate block.Block) bool {
-	us.blockHashToPoolMu.RLock()
-	defer us.blockHashToPoolMu.RUnlock()
+	us.mu.Lock()
+	defer us.mu.Unlock()
 
 	return us.validateTransactionsOfBlockLocked(blockToValidate)
 }
@@ -135,28 +335,38 @@ func (us *UtxoStore) ValidateTransactionsOfBlock(blockToValidate block.Block) bo
 // validateTransactionsOfBlockLocked checks if all inputs in the block's transactions reference valid UTXOs.
 // Caller must hold the lock.
 func (us *UtxoStore) validateTransactionsOfBlockLocked(blockToValidate block.Block) bool {
+	createdInBlock := make(map[outpoint]struct{})
+
 	for _, tx := range blockToValidate.Transactions {
 		// Skip coinbase transactions as they don't have real UTXO inputs
-		if tx.IsCoinbase() {
-			continue
+		if !tx.IsCoinbase() {
+			valid := us.validateTransactionFromBlockLocked(tx, blockToValidate.Header.PreviousBlockHash, createdInBlock)
+			if !valid {
+				return false // Invalid transaction found
+			}
 		}
-		valid := us.validateTransactionFromBlockLocked(tx, blockToValidate.Header.PreviousBlockHash)
-		if !valid {
-			return false // Invalid transaction found
+
+		txID := tx.TransactionId()
+		for i, output := range tx.Outputs {
+			// Data outputs never become UTXOs, so they can't be spent within the block either
+			if output.IsUnspendable() {
+				continue
+			}
+			createdInBlock[outpoint{TxID: txID, OutputIndex: uint32(i)}] = struct{}{}
 		}
 	}
 
 	return true // All inputs are valid
 }
 
-// GetUtxoFromBlock retrieves a specific UTXO from a given block's UTXO pool.
+// GetUtxoFromBlock retrieves a specific UTXO from the UTXO set as of the given block.
 func (us *UtxoStore) GetUtxoFromBlock(id transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.Output, error) {
-	us.blockHashToPoolMu.RLock()
-	defer us.blockHashToPoolMu.RUnlock()
+	us.mu.Lock()
+	defer us.mu.Unlock()
 
-	blockPool, exists := us.blockHashToPool[blockHash]
-	if !exists {
-		return transaction.Output{}, fmt.Errorf("UTXO pool for block %v not found", blockHash)
+	view, err := us.viewAt(blockHash)
+	if err != nil {
+		return transaction.Output{}, err
 	}
 
 	outpoint := outpoint{
@@ -164,42 +374,104 @@ func (us *UtxoStore) GetUtxoFromBlock(id transaction.TransactionID, outputIndex
 		OutputIndex: outputIndex,
 	}
 
-	output, exists := blockPool.UtxoData[outpoint]
+	utxoCoin, exists := view.get(outpoint)
 	if !exists {
 		return transaction.Output{}, fmt.Errorf("UTXO %v:%d not found in block %v", id, outputIndex, blockHash)
 	}
 
-	return output, nil
+	return utxoCoin.Output, nil
 }
 
-// GetUtxosByPubKeyHashFromBlock retrieves all UTXOs associated with a public key hash from a specific block's UTXO pool.
-func (us *UtxoStore) GetUtxosByPubKeyHashFromBlock(pubKeyHash transaction.PubKeyHash, blockHash common.Hash) ([]transaction.UTXO, error) {
-	us.blockHashToPoolMu.RLock()
-	defer us.blockHashToPoolMu.RUnlock()
+// GetUtxoEntryFromBlock retrieves a specific UTXO together with its creation height and coinbase flag from the UTXO set as of the given block.
+func (us *UtxoStore) GetUtxoEntryFromBlock(id transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.UTXO, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
 
-	blockPool, exists := us.blockHashToPool[blockHash]
+	view, err := us.viewAt(blockHash)
+	if err != nil {
+		return transaction.UTXO{}, err
+	}
+
+	outpoint := outpoint{
+		TxID:        id,
+		OutputIndex: outputIndex,
+	}
+
+	utxoCoin, exists := view.get(outpoint)
 	if !exists {
-		return nil, fmt.Errorf("UTXO pool for block %v not found", blockHash)
+		return transaction.UTXO{}, fmt.Errorf("UTXO %v:%d not found in block %v", id, outputIndex, blockHash)
+	}
+
+	return utxoCoin.toUTXO(outpoint), nil
+}
+
+// GetUtxosByPubKeyHashFromBlock retrieves all UTXOs associated with a public key hash from the UTXO set as of the given block.
+func (us *UtxoStore) GetUtxosByPubKeyHashFromBlock(pubKeyHash transaction.PubKeyHash, blockHash common.Hash) ([]transaction.UTXO, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	view, err := us.viewAt(blockHash)
+	if err != nil {
+		return nil, err
 	}
 
 	utxos := make([]transaction.UTXO, 0)
 
-	for outpoint, output := range blockPool.UtxoData {
-		if output.PubKeyHash == pubKeyHash {
-			utxo := transaction.UTXO{
-				TxID:        outpoint.TxID,
-				OutputIndex: outpoint.OutputIndex,
-				Output:      output,
-			}
-			utxos = append(utxos, utxo)
+	view.forEach(func(outpoint outpoint, utxoCoin coin) {
+		if utxoCoin.Output.AddressHash() == pubKeyHash {
+			utxos = append(utxos, utxoCoin.toUTXO(outpoint))
 		}
-	}
+	})
 
 	return utxos, nil
 }
 
-// AddNewBlock creates a new UTXO pool for the block by removing spent UTXOs and adding new outputs.
-// Skips orphan blocks and blocks already in the store.
+// GetBlockHeight returns the height of the given block.
+// Works for all blocks that were connected at some point, as their undo records contain the height.
+func (us *UtxoStore) GetBlockHeight(blockHash common.Hash) (uint64, error) {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	undo, exists := us.undo[blockHash]
+	if !exists {
+		return 0, fmt.Errorf("height of block %v not known, block was never connected", blockHash)
+	}
+
+	return undo.Height, nil
+}
+
+// GetAncestorMedianTimePast returns the median-time-past of the ancestor at the given height of the given block.
+// Follows the undo records backwards, so it works for all blocks that were connected at some point.
+func (us *UtxoStore) GetAncestorMedianTimePast(blockHash common.Hash, height uint64) (int64, error) {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	undo, exists := us.undo[blockHash]
+	if !exists {
+		return 0, fmt.Errorf("block %v not known, block was never connected", blockHash)
+	}
+	if height > undo.Height {
+		return 0, fmt.Errorf("block %v has no ancestor at height %d", blockHash, height)
+	}
+
+	for undo.Height > height {
+		undo = us.undo[undo.PrevBlockHash]
+	}
+
+	timestamps := make([]int64, 0, block.MedianTimePastWindow)
+	for len(timestamps) < block.MedianTimePastWindow {
+		timestamps = append(timestamps, undo.Timestamp)
+		if undo.Height == 0 {
+			break
+		}
+		undo = us.undo[undo.PrevBlockHash]
+	}
+
+	return block.MedianTimestamp(timestamps), nil
+}
+
+// AddNewBlock connects the block on top of the chain tip.
+// Skips orphan blocks and blocks that are already connected.
 func (us *UtxoStore) AddNewBlock(newBlock block.Block) error {
 	logger.Infof("[utxoStore] adding new block %v to UTXO store", newBlock.Header.Hash())
 
@@ -209,72 +481,208 @@ func (us *UtxoStore) AddNewBlock(newBlock block.Block) error {
 		return nil
 	}
 
-	us.blockHashToPoolMu.Lock()
-	defer us.blockHashToPoolMu.Unlock()
+	us.mu.Lock()
+	defer us.mu.Unlock()
 
-	if _, exists := us.blockHashToPool[newBlockHash]; exists {
+	if _, exists := us.connected[newBlockHash]; exists {
 		logger.Warnf("[utxoStore] block %v already exists in UTXO store, skipping", newBlockHash)
 		return nil
 	}
 
-	prevBlockHash := newBlock.Header.PreviousBlockHash
-	prevPool, exists := us.blockHashToPool[prevBlockHash]
-	assert.Assert(exists, "previous block UTXO pool not found, but must exist, as block is no orphan")
+	if newBlock.Header.PreviousBlockHash != us.tip {
+		return fmt.Errorf("block %v does not extend the UTXO chain tip %v", newBlockHash, us.tip)
+	}
 
 	valid := us.validateTransactionsOfBlockLocked(newBlock)
 	if !valid {
 		return fmt.Errorf("block %v is invalid, cannot add to UTXO store", newBlockHash)
 	}
 
-	newPool := us.createUtxoPoolFromBlock(&prevPool, newBlock)
-
-	// Store the new pool
-	us.blockHashToPool[newBlockHash] = *newPool
+	undo := createUndoFromBlock(newBlock, us.undo[us.tip].Height+1, us.utxos)
 
-	return nil
+	return us.connectAndPersist(newBlockHash, undo)
 }
 
-// createUtxoPoolFromBlock builds a new UTXO pool by copying the previous pool,
-// removing UTXOs spent by the block's inputs, and adding the block's new outputs.
-func (us *UtxoStore) createUtxoPoolFromBlock(prevPool *utxoPool, newBlock block.Block) *utxoPool {
-	newUtxoData := make(map[outpoint]transaction.Output)
+// DisconnectBlock disconnects the chain tip by replaying its undo record.
+func (us *UtxoStore) DisconnectBlock(blockToDisconnect block.Block) error {
+	blockHash := blockToDisconnect.Hash()
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
 
-	// Add all previous UTXOs to the new pool
-	for outpoint, output := range prevPool.UtxoData {
-		newUtxoData[outpoint] = output
+	if err := us.disconnect(blockHash); err != nil {
+		return err
 	}
 
-	// Remove all spent UTXOs from the new block
-	for _, tx := range newBlock.Transactions {
-		// Skip coinbase transaction inputs (they don't reference real UTXOs)
-		if tx.IsCoinbase() {
-			continue
+	if us.file != nil {
+		record := storage.Record{Kind: recordKindDisconnect, Payload: blockHash[:]}
+		if err := us.file.Append(record); err != nil {
+			// Connect the block again, so the chain state in memory doesn't diverge from the file
+			undo := us.undo[blockHash]
+			us.apply(blockHash, undo)
+			return fmt.Errorf("failed to persist disconnect of block %v: %w", blockHash, err)
 		}
+	}
+
+	logger.Infof("[utxoStore] disconnected block %v, new chain tip %v", blockHash, us.tip)
+
+	us.compactIfDue()
+	return nil
+}
+
+// connectAndPersist connects the block and appends it to the chain state file.
+// If the block can't be persisted, it is disconnected again and an error is returned.
+// Caller must hold the lock.
+func (us *UtxoStore) connectAndPersist(blockHash common.Hash, undo blockUndo) error {
+	previousUndo, wasConnectedBefore := us.undo[blockHash]
+	if err := us.connect(blockHash, undo); err != nil {
+		return err
+	}
 
-		for _, input := range tx.Inputs {
-			outpoint := outpoint{
-				TxID:        input.PrevTxID,
-				OutputIndex: input.OutputIndex,
+	if us.file != nil {
+		record := storage.Record{Kind: recordKindConnect, Payload: serializeConnectRecord(blockHash, undo)}
+		if err := us.file.Append(record); err != nil {
+			// Disconnect the block again, so the chain state in memory doesn't diverge from the file
+			us.revert(undo)
+			delete(us.connected, blockHash)
+			if wasConnectedBefore {
+				us.undo[blockHash] = previousUndo
+			} else {
+				delete(us.undo, blockHash)
 			}
-			_, exists := newUtxoData[outpoint]
-			assert.Assert(exists, "referenced UTXO not found in pool")
-			delete(newUtxoData, outpoint)
+			return fmt.Errorf("failed to persist block %v: %w", blockHash, err)
+		}
+	}
+
+	us.compactIfDue()
+	return nil
+}
+
+// connect applies the undo record of a block to the UTXO set and makes the block the new tip.
+// Caller must hold the lock.
+func (us *UtxoStore) connect(blockHash common.Hash, undo blockUndo) error {
+	if len(us.connected) > 0 && undo.PrevBlockHash != us.tip {
+		return fmt.Errorf("block %v does not extend the UTXO chain tip %v", blockHash, us.tip)
+	}
+
+	for _, spent := range undo.Spent {
+		if _, exists := us.utxos[spent.Outpoint]; !exists {
+			return fmt.Errorf("block %v spends unknown UTXO %v:%d", blockHash, spent.Outpoint.TxID, spent.Outpoint.OutputIndex)
 		}
 	}
 
-	// Add all new outputs from the new block
+	us.apply(blockHash, undo)
+	return nil
+}
+
+// apply applies the undo record of a block to the UTXO set and makes the block the new tip without any checks.
+// Caller must hold the lock.
+func (us *UtxoStore) apply(blockHash common.Hash, undo blockUndo) {
+	for _, spent := range undo.Spent {
+		delete(us.utxos, spent.Outpoint)
+	}
+	for _, created := range undo.Created {
+		us.utxos[created.Outpoint] = created.Coin
+	}
+
+	us.undo[blockHash] = undo
+	us.connected[blockHash] = struct{}{}
+	us.tip = blockHash
+	us.cachedView = nil
+}
+
+// disconnect reverts the undo record of the tip and makes its previous block the new tip.
+// Caller must hold the lock.
+func (us *UtxoStore) disconnect(blockHash common.Hash) error {
+	if blockHash != us.tip {
+		return fmt.Errorf("block %v is not the UTXO chain tip %v", blockHash, us.tip)
+	}
+
+	undo, exists := us.undo[blockHash]
+	if !exists {
+		return fmt.Errorf("undo record for block %v not found", blockHash)
+	}
+
+	if _, hasParent := us.connected[undo.PrevBlockHash]; !hasParent {
+		return fmt.Errorf("cannot disconnect the first block %v of the chain state", blockHash)
+	}
+
+	if undo.Pruned {
+		return fmt.Errorf("cannot disconnect block %v, its undo record was pruned as it is more than %d blocks deep", blockHash, maxReorgDepth)
+	}
+
+	us.revert(undo)
+	delete(us.connected, blockHash)
+	return nil
+}
+
+// revert reverts the undo record of the tip and makes its previous block the new tip without any checks.
+// Caller must hold the lock.
+func (us *UtxoStore) revert(undo blockUndo) {
+	for _, created := range undo.Created {
+		delete(us.utxos, created.Outpoint)
+	}
+	for _, spent := range undo.Spent {
+		us.utxos[spent.Outpoint] = spent.Coin
+	}
+
+	us.tip = undo.PrevBlockHash
+	us.cachedView = nil
+}
+
+// createUndoFromBlock creates the undo record for a block at the given height that is connected on top of the given UTXO set.
+// Coinbase inputs are skipped as they don't reference real UTXOs.
+// Unspendable outputs (data outputs) are never added to the UTXO set.
+// Outputs that are created and spent within the block never enter the UTXO set, so they are neither created nor spent.
+func createUndoFromBlock(newBlock block.Block, height uint64, utxos map[outpoint]coin) blockUndo {
+	undo := blockUndo{
+		PrevBlockHash: newBlock.Header.PreviousBlockHash,
+		Height:        height,
+		Timestamp:     newBlock.Header.Timestamp,
+		Spent:         make([]utxoEntry, 0),
+		Created:       make([]utxoEntry, 0),
+	}
+
+	createdInBlock := make(map[outpoint]struct{})
+	spentInBlock := make(map[outpoint]struct{})
+
 	for _, tx := range newBlock.Transactions {
+		if !tx.IsCoinbase() {
+			for _, input := range tx.Inputs {
+				spent := outpoint{
+					TxID:        input.PrevTxID,
+					OutputIndex: input.OutputIndex,
+				}
+				if _, ok := createdInBlock[spent]; ok {
+					spentInBlock[spent] = struct{}{}
+					continue
+				}
+				undo.Spent = append(undo.Spent, utxoEntry{Outpoint: spent, Coin: utxos[spent]})
+			}
+		}
+
 		txID := tx.TransactionId()
+		isCoinbase := tx.IsCoinbase()
 		for i, output := range tx.Outputs {
-			outpoint := outpoint{
+			if output.IsUnspendable() {
+				continue
+			}
+			created := outpoint{
 				TxID:        txID,
 				OutputIndex: uint32(i),
 			}
-			newUtxoData[outpoint] = output
+			createdCoin := coin{Output: output, Height: height, IsCoinbase: isCoinbase}
+			undo.Created = append(undo.Created, utxoEntry{Outpoint: created, Coin: createdCoin})
+			createdInBlock[created] = struct{}{}
 		}
 	}
 
-	return &utxoPool{
-		UtxoData: newUtxoData,
+	if len(spentInBlock) > 0 {
+		undo.Created = slices.DeleteFunc(undo.Created, func(entry utxoEntry) bool {
+			_, ok := spentInBlock[entry.Outpoint]
+			return ok
+		})
 	}
+
+	return undo
 }
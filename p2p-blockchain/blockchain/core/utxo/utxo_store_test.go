@@ -1,6 +1,7 @@
 package utxo
 
 import (
+	"maps"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
@@ -51,6 +52,10 @@ func (m *mockBlockStore) GetMainChainHeight() uint64 {
 	return 0
 }
 
+func (m *mockBlockStore) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+
 func (m *mockBlockStore) GetMainChainTip() block.Block {
 	return block.Block{}
 }
@@ -63,6 +68,10 @@ func (m *mockBlockStore) IsBlockInvalid(_ block.Block) (bool, error) {
 	return false, nil
 }
 
+func (m *mockBlockStore) MarkBlockInvalid(_ common.Hash) error {
+	return nil
+}
+
 func (m *mockBlockStore) GetBlockHeightDifferenceByTxId(txID transaction.TransactionID) (int, error) {
 	return -1, nil
 }
@@ -71,6 +80,14 @@ func (m *mockBlockStore) IsTransactionAccepted(txID transaction.TransactionID) (
 	return false, nil
 }
 
+func (m *mockBlockStore) GetNextDifficultyTarget(_ common.Hash) (uint8, error) {
+	return 0, nil
+}
+
+func (m *mockBlockStore) GetMedianTimePast(_ common.Hash) (int64, error) {
+	return 0, nil
+}
+
 // =========================================================================
 // Test Helpers
 // =========================================================================
@@ -122,6 +139,18 @@ func TestNewUtxoStore(t *testing.T) {
 	assert.NotNil(t, utxoStore)
 }
 
+// newUtxoStoreAt creates a UTXO store whose chain tip is the given block with the given UTXO set.
+func newUtxoStoreAt(mockStore *mockBlockStore, tip common.Hash, utxos map[outpoint]transaction.Output) *UtxoStore {
+	utxoStore := NewUtxoStore(mockStore).(*UtxoStore)
+	utxoStore.tip = tip
+	utxoStore.connected[tip] = struct{}{}
+	utxoStore.undo[tip] = blockUndo{}
+	for op, output := range utxos {
+		utxoStore.utxos[op] = coin{Output: output}
+	}
+	return utxoStore
+}
+
 func TestAddNewBlock_SkipsOrphanBlock(t *testing.T) {
 	// Arrange
 	mockStore := newMockBlockStore()
@@ -138,83 +167,90 @@ func TestAddNewBlock_SkipsOrphanBlock(t *testing.T) {
 
 	// Assert
 	assert.NoError(t, err)
-	assert.Empty(t, utxoStore.blockHashToPool)
+	assert.Empty(t, utxoStore.undo)
+	assert.Empty(t, utxoStore.utxos)
 }
 
 func TestAddNewBlock_SkipsDuplicateBlock(t *testing.T) {
 	// Arrange
 	mockStore := newMockBlockStore()
-	utxoStore := NewUtxoStore(mockStore).(*UtxoStore)
-
 	genesisHash := common.Hash{}
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, nil)
+
 	coinbaseTx := createCoinbaseTx(transaction.PubKeyHash{1, 2, 3}, 50)
 	testBlock := createTestBlock(genesisHash, []transaction.Transaction{coinbaseTx})
+	assert.NoError(t, utxoStore.AddNewBlock(testBlock))
 
-	// Pre-populate with genesis pool and the block's pool
-	utxoStore.blockHashToPool[genesisHash] = utxoPool{UtxoData: make(map[outpoint]transaction.Output)}
-	utxoStore.blockHashToPool[testBlock.Hash()] = utxoPool{UtxoData: make(map[outpoint]transaction.Output)}
-
-	initialPoolCount := len(utxoStore.blockHashToPool)
+	initialUndoCount := len(utxoStore.undo)
 
 	// Act
 	err := utxoStore.AddNewBlock(testBlock)
 
 	// Assert
 	assert.NoError(t, err)
-	assert.Equal(t, initialPoolCount, len(utxoStore.blockHashToPool))
+	assert.Equal(t, initialUndoCount, len(utxoStore.undo))
+	assert.Len(t, utxoStore.utxos, 1)
 }
 
-func TestAddNewBlock_AddsCoinbaseOutputsToPool(t *testing.T) {
+func TestAddNewBlock_RejectsBlockNotExtendingTip(t *testing.T) {
 	// Arrange
 	mockStore := newMockBlockStore()
-	utxoStore := NewUtxoStore(mockStore).(*UtxoStore)
+	utxoStore := newUtxoStoreAt(mockStore, common.Hash{}, nil)
+
+	coinbaseTx := createCoinbaseTx(transaction.PubKeyHash{1, 2, 3}, 50)
+	testBlock := createTestBlock(common.Hash{0xaa}, []transaction.Transaction{coinbaseTx})
 
+	// Act
+	err := utxoStore.AddNewBlock(testBlock)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, common.Hash{}, utxoStore.GetChainTip())
+}
+
+func TestAddNewBlock_AddsCoinbaseOutputsToPool(t *testing.T) {
+	// Arrange
+	mockStore := newMockBlockStore()
 	genesisHash := common.Hash{}
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, nil)
+
 	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
 	coinbaseTx := createCoinbaseTx(pubKeyHash, 50)
 	testBlock := createTestBlock(genesisHash, []transaction.Transaction{coinbaseTx})
 
-	// Pre-populate with empty genesis pool
-	utxoStore.blockHashToPool[genesisHash] = utxoPool{UtxoData: make(map[outpoint]transaction.Output)}
-
 	// Act
 	err := utxoStore.AddNewBlock(testBlock)
 
 	// Assert
 	assert.NoError(t, err)
-
-	newPool := utxoStore.blockHashToPool[testBlock.Hash()]
-	assert.Len(t, newPool.UtxoData, 1)
+	assert.Equal(t, testBlock.Hash(), utxoStore.GetChainTip())
+	assert.Len(t, utxoStore.utxos, 1)
 
 	// Check the coinbase output is in the pool
-	txID := coinbaseTx.TransactionId()
-	outpoint := outpoint{TxID: txID, OutputIndex: 0}
-	output, exists := newPool.UtxoData[outpoint]
-	assert.True(t, exists)
+	output, err := utxoStore.GetUtxoFromBlock(coinbaseTx.TransactionId(), 0, testBlock.Hash())
+	assert.NoError(t, err)
 	assert.Equal(t, uint64(50), output.Value)
 	assert.Equal(t, pubKeyHash, output.PubKeyHash)
+
+	// Coinbase outputs are marked with their creation height
+	entry, err := utxoStore.GetUtxoEntryFromBlock(coinbaseTx.TransactionId(), 0, testBlock.Hash())
+	assert.NoError(t, err)
+	assert.True(t, entry.IsCoinbase)
+	assert.Equal(t, uint64(1), entry.Height)
 }
 
 func TestAddNewBlock_RemovesSpentUtxos(t *testing.T) {
 	// Arrange
 	mockStore := newMockBlockStore()
-	utxoStore := NewUtxoStore(mockStore).(*UtxoStore)
-
 	genesisHash := common.Hash{}
 	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
 
 	// Create a previous UTXO
 	prevTxID := transaction.TransactionID{0x01, 0x02, 0x03}
-	prevoutpoint := outpoint{TxID: prevTxID, OutputIndex: 0}
+	prevOutpoint := outpoint{TxID: prevTxID, OutputIndex: 0}
 	prevOutput := transaction.Output{Value: 100, PubKeyHash: pubKeyHash}
 
-	// Pre-populate genesis pool with existing UTXO
-	genesisPool := utxoPool{
-		UtxoData: map[outpoint]transaction.Output{
-			prevoutpoint: prevOutput,
-		},
-	}
-	utxoStore.blockHashToPool[genesisHash] = genesisPool
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, map[outpoint]transaction.Output{prevOutpoint: prevOutput})
 
 	// Create a block that spends the previous UTXO
 	spendingTx := createRegularTx(prevTxID, 0, 90, pubKeyHash)
@@ -226,40 +262,71 @@ func TestAddNewBlock_RemovesSpentUtxos(t *testing.T) {
 	// Assert
 	assert.NoError(t, err)
 
-	newPool := utxoStore.blockHashToPool[testBlock.Hash()]
-
 	// Previous UTXO should be removed
-	_, exists := newPool.UtxoData[prevoutpoint]
+	_, exists := utxoStore.utxos[prevOutpoint]
 	assert.False(t, exists, "spent UTXO should be removed")
 
 	// New output should be added
-	newTxID := spendingTx.TransactionId()
-	newoutpoint := outpoint{TxID: newTxID, OutputIndex: 0}
-	newOutput, exists := newPool.UtxoData[newoutpoint]
+	newOutput, exists := utxoStore.utxos[outpoint{TxID: spendingTx.TransactionId(), OutputIndex: 0}]
 	assert.True(t, exists, "new UTXO should be added")
-	assert.Equal(t, uint64(90), newOutput.Value)
+	assert.Equal(t, uint64(90), newOutput.Output.Value)
+	assert.Equal(t, uint64(1), newOutput.Height)
+	assert.False(t, newOutput.IsCoinbase)
+
+	// Spent UTXO should be recorded in the undo record
+	undo := utxoStore.undo[testBlock.Hash()]
+	assert.Equal(t, []utxoEntry{{Outpoint: prevOutpoint, Coin: coin{Output: prevOutput}}}, undo.Spent)
 }
 
-func TestAddNewBlock_HandlesMultipleOutputs(t *testing.T) {
+func TestAddNewBlock_ChainWithinBlock(t *testing.T) {
 	// Arrange
 	mockStore := newMockBlockStore()
-	utxoStore := NewUtxoStore(mockStore).(*UtxoStore)
+	genesisHash := common.Hash{}
+	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
+
+	prevTxID := transaction.TransactionID{0x01, 0x02, 0x03}
+	prevOutpoint := outpoint{TxID: prevTxID, OutputIndex: 0}
+	prevOutput := transaction.Output{Value: 100, PubKeyHash: pubKeyHash}
+
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, map[outpoint]transaction.Output{prevOutpoint: prevOutput})
 
+	// The child spends the output of its parent within the same block
+	parentTx := createRegularTx(prevTxID, 0, 90, pubKeyHash)
+	childTx := createRegularTx(parentTx.TransactionId(), 0, 80, pubKeyHash)
+	testBlock := createTestBlock(genesisHash, []transaction.Transaction{parentTx, childTx})
+
+	// Act
+	err := utxoStore.AddNewBlock(testBlock)
+
+	// Assert
+	assert.NoError(t, err)
+
+	childOutpoint := outpoint{TxID: childTx.TransactionId(), OutputIndex: 0}
+	assert.Equal(t, map[outpoint]coin{childOutpoint: {Output: childTx.Outputs[0], Height: 1}}, utxoStore.utxos)
+
+	// The output of the parent never entered the UTXO set, so it is neither created nor spent
+	undo := utxoStore.undo[testBlock.Hash()]
+	assert.Equal(t, []utxoEntry{{Outpoint: prevOutpoint, Coin: coin{Output: prevOutput}}}, undo.Spent)
+	assert.Equal(t, []utxoEntry{{Outpoint: childOutpoint, Coin: coin{Output: childTx.Outputs[0], Height: 1}}}, undo.Created)
+
+	// Disconnecting restores the previous UTXO set
+	assert.NoError(t, utxoStore.DisconnectBlock(testBlock))
+	assert.Equal(t, map[outpoint]coin{prevOutpoint: {Output: prevOutput}}, utxoStore.utxos)
+}
+
+func TestAddNewBlock_HandlesMultipleOutputs(t *testing.T) {
+	// Arrange
+	mockStore := newMockBlockStore()
 	genesisHash := common.Hash{}
 	pubKeyHash1 := transaction.PubKeyHash{1, 2, 3}
 	pubKeyHash2 := transaction.PubKeyHash{4, 5, 6}
 
 	// Create a previous UTXO
 	prevTxID := transaction.TransactionID{0x01, 0x02, 0x03}
-	prevoutpoint := outpoint{TxID: prevTxID, OutputIndex: 0}
+	prevOutpoint := outpoint{TxID: prevTxID, OutputIndex: 0}
 	prevOutput := transaction.Output{Value: 100, PubKeyHash: pubKeyHash1}
 
-	genesisPool := utxoPool{
-		UtxoData: map[outpoint]transaction.Output{
-			prevoutpoint: prevOutput,
-		},
-	}
-	utxoStore.blockHashToPool[genesisHash] = genesisPool
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, map[outpoint]transaction.Output{prevOutpoint: prevOutput})
 
 	// Create a transaction with multiple outputs
 	tx := transaction.Transaction{
@@ -283,43 +350,103 @@ func TestAddNewBlock_HandlesMultipleOutputs(t *testing.T) {
 
 	// Assert
 	assert.NoError(t, err)
-
-	newPool := utxoStore.blockHashToPool[testBlock.Hash()]
-	assert.Len(t, newPool.UtxoData, 2)
+	assert.Len(t, utxoStore.utxos, 2)
 
 	txID := tx.TransactionId()
-	output0, exists := newPool.UtxoData[outpoint{TxID: txID, OutputIndex: 0}]
+	output0, exists := utxoStore.utxos[outpoint{TxID: txID, OutputIndex: 0}]
 	assert.True(t, exists)
-	assert.Equal(t, uint64(60), output0.Value)
+	assert.Equal(t, uint64(60), output0.Output.Value)
 
-	output1, exists := newPool.UtxoData[outpoint{TxID: txID, OutputIndex: 1}]
+	output1, exists := utxoStore.utxos[outpoint{TxID: txID, OutputIndex: 1}]
 	assert.True(t, exists)
-	assert.Equal(t, uint64(30), output1.Value)
+	assert.Equal(t, uint64(30), output1.Output.Value)
 }
 
-func TestAddNewBlock_PreservesPreviousPool(t *testing.T) {
+func TestAddNewBlock_SkipsDataOutputs(t *testing.T) {
 	// Arrange
 	mockStore := newMockBlockStore()
-	utxoStore := NewUtxoStore(mockStore).(*UtxoStore)
+	genesisHash := common.Hash{}
+	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
+
+	prevTxID := transaction.TransactionID{0x01, 0x02, 0x03}
+	prevOutpoint := outpoint{TxID: prevTxID, OutputIndex: 0}
+	prevOutput := transaction.Output{Value: 100, PubKeyHash: pubKeyHash}
 
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, map[outpoint]transaction.Output{prevOutpoint: prevOutput})
+
+	tx := transaction.Transaction{
+		Inputs: []transaction.Input{
+			{
+				PrevTxID:    prevTxID,
+				OutputIndex: 0,
+				Signature:   []byte("sig"),
+				PubKey:      transaction.PubKey{1, 2, 3},
+			},
+		},
+		Outputs: []transaction.Output{
+			transaction.NewDataOutput([]byte("document hash")),
+			{Value: 90, PubKeyHash: pubKeyHash},
+		},
+	}
+	testBlock := createTestBlock(genesisHash, []transaction.Transaction{tx})
+
+	// Act
+	err := utxoStore.AddNewBlock(testBlock)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, utxoStore.utxos, 1)
+
+	txID := tx.TransactionId()
+	_, exists := utxoStore.utxos[outpoint{TxID: txID, OutputIndex: 0}]
+	assert.False(t, exists)
+
+	change, exists := utxoStore.utxos[outpoint{TxID: txID, OutputIndex: 1}]
+	assert.True(t, exists)
+	assert.Equal(t, uint64(90), change.Output.Value)
+}
+
+func TestValidateTransactionsOfBlock_RejectsSpendingDataOutputWithinBlock(t *testing.T) {
+	// Arrange
+	mockStore := newMockBlockStore()
 	genesisHash := common.Hash{}
 	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
 
-	// Create existing UTXOs in genesis pool
+	prevTxID := transaction.TransactionID{0x01, 0x02, 0x03}
+	prevOutpoint := outpoint{TxID: prevTxID, OutputIndex: 0}
+	prevOutput := transaction.Output{Value: 100, PubKeyHash: pubKeyHash}
+
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, map[outpoint]transaction.Output{prevOutpoint: prevOutput})
+
+	parentTx := createRegularTx(prevTxID, 0, 90, pubKeyHash)
+	parentTx.Outputs = append([]transaction.Output{transaction.NewDataOutput([]byte("document hash"))}, parentTx.Outputs...)
+	// The child spends the data output of its parent within the same block
+	childTx := createRegularTx(parentTx.TransactionId(), 0, 80, pubKeyHash)
+	testBlock := createTestBlock(genesisHash, []transaction.Transaction{parentTx, childTx})
+
+	// Act
+	valid := utxoStore.ValidateTransactionsOfBlock(testBlock)
+
+	// Assert
+	assert.False(t, valid)
+}
+
+func TestAddNewBlock_PreviousBlockStaysReachable(t *testing.T) {
+	// Arrange
+	mockStore := newMockBlockStore()
+	genesisHash := common.Hash{}
+	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
+
+	// Create existing UTXO spent by the new block
 	existingTxID := transaction.TransactionID{0xaa, 0xbb, 0xcc}
-	existingoutpoint := outpoint{TxID: existingTxID, OutputIndex: 0}
 	existingOutput := transaction.Output{Value: 200, PubKeyHash: pubKeyHash}
 
-	genesisPool := utxoPool{
-		UtxoData: map[outpoint]transaction.Output{
-			existingoutpoint: existingOutput,
-		},
-	}
-	utxoStore.blockHashToPool[genesisHash] = genesisPool
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, map[outpoint]transaction.Output{
+		{TxID: existingTxID, OutputIndex: 0}: existingOutput,
+	})
 
-	// Create a block with only coinbase (doesn't spend existing UTXO)
-	coinbaseTx := createCoinbaseTx(pubKeyHash, 50)
-	testBlock := createTestBlock(genesisHash, []transaction.Transaction{coinbaseTx})
+	spendingTx := createRegularTx(existingTxID, 0, 150, pubKeyHash)
+	testBlock := createTestBlock(genesisHash, []transaction.Transaction{createCoinbaseTx(pubKeyHash, 50), spendingTx})
 
 	// Act
 	err := utxoStore.AddNewBlock(testBlock)
@@ -327,38 +454,266 @@ func TestAddNewBlock_PreservesPreviousPool(t *testing.T) {
 	// Assert
 	assert.NoError(t, err)
 
-	newPool := utxoStore.blockHashToPool[testBlock.Hash()]
+	// The spent UTXO is still visible as of the previous block
+	output, err := utxoStore.GetUtxoFromBlock(existingTxID, 0, genesisHash)
+	assert.NoError(t, err)
+	assert.Equal(t, existingOutput, output)
 
-	// Previous UTXO should still exist
-	_, exists := newPool.UtxoData[existingoutpoint]
-	assert.True(t, exists, "unspent UTXO should be preserved")
+	// Outputs of the new block are not visible as of the previous block
+	utxos, err := utxoStore.GetUtxosByPubKeyHashFromBlock(pubKeyHash, genesisHash)
+	assert.NoError(t, err)
+	assert.Len(t, utxos, 1)
 
-	// Plus the new coinbase output
-	assert.Len(t, newPool.UtxoData, 2)
+	utxos, err = utxoStore.GetUtxosByPubKeyHashFromBlock(pubKeyHash, testBlock.Hash())
+	assert.NoError(t, err)
+	assert.Len(t, utxos, 2)
 }
 
-func TestCreateUtxoPoolFromBlock_EmptyPrevPool(t *testing.T) {
+func TestDisconnectBlock_RestoresSpentUtxos(t *testing.T) {
 	// Arrange
 	mockStore := newMockBlockStore()
-	utxoStore := NewUtxoStore(mockStore).(*UtxoStore)
+	genesisHash := common.Hash{}
+	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
+
+	prevTxID := transaction.TransactionID{0x01, 0x02, 0x03}
+	prevOutpoint := outpoint{TxID: prevTxID, OutputIndex: 0}
+	prevOutput := transaction.Output{Value: 100, PubKeyHash: pubKeyHash}
+
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, map[outpoint]transaction.Output{prevOutpoint: prevOutput})
+
+	spendingTx := createRegularTx(prevTxID, 0, 90, pubKeyHash)
+	testBlock := createTestBlock(genesisHash, []transaction.Transaction{spendingTx})
+	assert.NoError(t, utxoStore.AddNewBlock(testBlock))
+
+	// Act
+	err := utxoStore.DisconnectBlock(testBlock)
 
-	prevPool := utxoPool{UtxoData: make(map[outpoint]transaction.Output)}
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, genesisHash, utxoStore.GetChainTip())
+	assert.Equal(t, map[outpoint]coin{prevOutpoint: {Output: prevOutput}}, utxoStore.utxos)
+}
+
+func TestDisconnectBlock_RejectsBlockThatIsNotTheTip(t *testing.T) {
+	// Arrange
+	mockStore := newMockBlockStore()
+	genesisHash := common.Hash{}
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, nil)
+
+	block1 := createTestBlock(genesisHash, []transaction.Transaction{createCoinbaseTx(transaction.PubKeyHash{1}, 50)})
+	assert.NoError(t, utxoStore.AddNewBlock(block1))
+	block2 := createTestBlock(block1.Hash(), []transaction.Transaction{createCoinbaseTx(transaction.PubKeyHash{2}, 50)})
+	assert.NoError(t, utxoStore.AddNewBlock(block2))
+
+	// Act
+	err := utxoStore.DisconnectBlock(block1)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, block2.Hash(), utxoStore.GetChainTip())
+}
+
+// TestGetUtxoFromBlock_SideChain tests that the UTXO set of a disconnected side chain block stays reachable
+// (g) -> (b1)
+// (g) -> (s1)  [connected first, then disconnected]
+func TestGetUtxoFromBlock_SideChain(t *testing.T) {
+	// Arrange
+	mockStore := newMockBlockStore()
+	genesisHash := common.Hash{}
+	utxoStore := newUtxoStoreAt(mockStore, genesisHash, nil)
+
+	sideCoinbase := createCoinbaseTx(transaction.PubKeyHash{1}, 50)
+	side1 := createTestBlock(genesisHash, []transaction.Transaction{sideCoinbase})
+	mainCoinbase := createCoinbaseTx(transaction.PubKeyHash{2}, 50)
+	block1 := createTestBlock(genesisHash, []transaction.Transaction{mainCoinbase})
+	block1.Header.Nonce = 2
+
+	assert.NoError(t, utxoStore.AddNewBlock(side1))
+	assert.NoError(t, utxoStore.DisconnectBlock(side1))
+	assert.NoError(t, utxoStore.AddNewBlock(block1))
+
+	// Act
+	sideOutput, sideErr := utxoStore.GetUtxoFromBlock(sideCoinbase.TransactionId(), 0, side1.Hash())
+	_, mainErr := utxoStore.GetUtxoFromBlock(mainCoinbase.TransactionId(), 0, side1.Hash())
+	_, unknownErr := utxoStore.GetUtxoFromBlock(sideCoinbase.TransactionId(), 0, common.Hash{0xee})
+
+	// Assert
+	assert.NoError(t, sideErr)
+	assert.Equal(t, uint64(50), sideOutput.Value)
+	assert.Error(t, mainErr, "main chain output must not be visible on the side chain")
+	assert.Error(t, unknownErr, "unknown blocks are not reachable")
+}
+
+func TestPersistentUtxoStore_RestoresChainState(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	mockStore := newMockBlockStore()
 	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
 
-	coinbaseTx := createCoinbaseTx(pubKeyHash, 50)
-	testBlock := createTestBlock(common.Hash{}, []transaction.Transaction{coinbaseTx})
+	genesis := createTestBlock(common.Hash{}, []transaction.Transaction{createCoinbaseTx(pubKeyHash, 100)})
+	genesisTxID := genesis.Transactions[0].TransactionId()
+	block1 := createTestBlock(genesis.Hash(), []transaction.Transaction{createRegularTx(genesisTxID, 0, 90, pubKeyHash)})
+	block2 := createTestBlock(block1.Hash(), []transaction.Transaction{createCoinbaseTx(pubKeyHash, 50)})
+
+	utxoStore, err := NewPersistentUtxoStore(mockStore, dataDir)
+	assert.NoError(t, err)
+	assert.NoError(t, utxoStore.InitializeGenesisPool(genesis))
+	assert.NoError(t, utxoStore.AddNewBlock(block1))
+	assert.NoError(t, utxoStore.AddNewBlock(block2))
+	assert.NoError(t, utxoStore.DisconnectBlock(block2))
+	expectedUtxos := utxoStore.utxos
+	assert.NoError(t, utxoStore.Close())
 
 	// Act
-	newPool := utxoStore.createUtxoPoolFromBlock(&prevPool, testBlock)
+	restored, err := NewPersistentUtxoStore(mockStore, dataDir)
+	assert.NoError(t, err)
+	defer restored.Close()
 
 	// Assert
-	assert.Len(t, newPool.UtxoData, 1)
+	assert.Equal(t, block1.Hash(), restored.GetChainTip())
+	assert.Equal(t, expectedUtxos, restored.utxos)
+	assert.NoError(t, restored.InitializeGenesisPool(genesis), "genesis must not be connected twice")
+	assert.Equal(t, block1.Hash(), restored.GetChainTip())
 
-	txID := coinbaseTx.TransactionId()
-	outpoint := outpoint{TxID: txID, OutputIndex: 0}
-	output, exists := newPool.UtxoData[outpoint]
-	assert.True(t, exists)
-	assert.Equal(t, uint64(50), output.Value)
+	// Undo records survive the restart, so the genesis UTXO is still reachable
+	output, err := restored.GetUtxoFromBlock(genesisTxID, 0, genesis.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), output.Value)
+
+	// Heights are part of the undo records, including the disconnected block2
+	height, err := restored.GetBlockHeight(block2.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), height)
+}
+
+func TestPersistentUtxoStore_FailedWriteKeepsChainState(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	mockStore := newMockBlockStore()
+	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
+
+	genesis := createTestBlock(common.Hash{}, []transaction.Transaction{createCoinbaseTx(pubKeyHash, 100)})
+	genesisTxID := genesis.Transactions[0].TransactionId()
+	block1 := createTestBlock(genesis.Hash(), []transaction.Transaction{createRegularTx(genesisTxID, 0, 90, pubKeyHash)})
+
+	utxoStore, err := NewPersistentUtxoStore(mockStore, dataDir)
+	assert.NoError(t, err)
+	assert.NoError(t, utxoStore.InitializeGenesisPool(genesis))
+	expectedUtxos := maps.Clone(utxoStore.utxos)
+
+	// Writes to a closed file fail
+	assert.NoError(t, utxoStore.file.Close())
+
+	// Act
+	err = utxoStore.AddNewBlock(block1)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, genesis.Hash(), utxoStore.GetChainTip())
+	assert.Equal(t, expectedUtxos, utxoStore.utxos)
+	_, err = utxoStore.GetBlockHeight(block1.Hash())
+	assert.Error(t, err, "the undo record of the block must be dropped again")
+}
+
+func TestPersistentUtxoStore_CompactsAndPrunesUndo(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	mockStore := newMockBlockStore()
+	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
+
+	utxoStore, err := NewPersistentUtxoStore(mockStore, dataDir)
+	assert.NoError(t, err)
+	utxoStore.compactionInterval = 50
+
+	blocks := []block.Block{createTestBlock(common.Hash{}, []transaction.Transaction{createCoinbaseTx(pubKeyHash, 100)})}
+	assert.NoError(t, utxoStore.InitializeGenesisPool(blocks[0]))
+	for i := uint64(1); i <= maxReorgDepth+50; i++ {
+		next := createTestBlock(blocks[len(blocks)-1].Hash(), []transaction.Transaction{transaction.NewCoinbaseTransaction(pubKeyHash, 50, i)})
+		assert.NoError(t, utxoStore.AddNewBlock(next))
+		blocks = append(blocks, next)
+	}
+	tip := blocks[len(blocks)-1]
+	expectedUtxos := utxoStore.utxos
+	assert.NoError(t, utxoStore.Close())
+
+	// Act
+	restored, err := NewPersistentUtxoStore(mockStore, dataDir)
+	assert.NoError(t, err)
+	defer restored.Close()
+
+	// Assert
+	assert.Equal(t, tip.Hash(), restored.GetChainTip())
+	assert.Equal(t, expectedUtxos, restored.utxos)
+	assert.Less(t, restored.changesSinceCompaction, 50, "the file must start with a snapshot")
+
+	assert.True(t, restored.undo[blocks[1].Hash()].Pruned, "undo records deeper than maxReorgDepth must be pruned")
+	assert.False(t, restored.undo[tip.Hash()].Pruned)
+	height, err := restored.GetBlockHeight(blocks[1].Hash())
+	assert.NoError(t, err, "pruned undo records keep the height")
+	assert.Equal(t, uint64(1), height)
+	_, err = restored.GetUtxoFromBlock(blocks[1].Transactions[0].TransactionId(), 0, blocks[1].Hash())
+	assert.Error(t, err, "the UTXO set of a pruned block is not reachable")
+
+	assert.NoError(t, restored.DisconnectBlock(tip))
+	assert.Equal(t, blocks[len(blocks)-2].Hash(), restored.GetChainTip())
+}
+
+func TestConnectRecordSerialization_RoundTrip(t *testing.T) {
+	// Arrange
+	script := transaction.MultisigScript{Required: 1, PubKeys: []transaction.PubKey{{2}, {3}}}
+	undo := blockUndo{
+		PrevBlockHash: common.Hash{1},
+		Height:        7,
+		Timestamp:     1000,
+		Spent: []utxoEntry{
+			{Outpoint: outpoint{TxID: transaction.TransactionID{2}, OutputIndex: 1}, Coin: coin{Output: transaction.Output{Value: 10, PubKeyHash: transaction.PubKeyHash{3}}, Height: 5, IsCoinbase: true}},
+		},
+		Created: []utxoEntry{
+			{Outpoint: outpoint{TxID: transaction.TransactionID{4}}, Coin: coin{Output: transaction.NewMultisigOutput(20, script), Height: 7}},
+			{Outpoint: outpoint{TxID: transaction.TransactionID{4}, OutputIndex: 1}, Coin: coin{Output: transaction.NewMultisigHashOutput(30, script.Hash()), Height: 7}},
+		},
+	}
+
+	// Act
+	blockHash, decoded, err := deserializeConnectRecord(serializeConnectRecord(common.Hash{9}, undo))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, common.Hash{9}, blockHash)
+	assert.Equal(t, undo, decoded)
+}
+
+func TestGetAncestorMedianTimePast(t *testing.T) {
+	// Arrange
+	mockStore := newMockBlockStore()
+	pubKeyHash := transaction.PubKeyHash{1, 2, 3}
+
+	genesis := createTestBlock(common.Hash{}, []transaction.Transaction{createCoinbaseTx(pubKeyHash, 50)})
+	utxoStore := NewUtxoStore(mockStore).(*UtxoStore)
+	assert.NoError(t, utxoStore.InitializeGenesisPool(genesis))
+
+	// Blocks 1 to 12 with timestamps 1010 to 1120
+	tip := genesis
+	for i := int64(1); i <= 12; i++ {
+		next := createTestBlock(tip.Hash(), []transaction.Transaction{createCoinbaseTx(pubKeyHash, uint64(i))})
+		next.Header.Timestamp = 1000 + 10*i
+		assert.NoError(t, utxoStore.AddNewBlock(next))
+		tip = next
+	}
+
+	// Act
+	tipMedianTimePast, errTip := utxoStore.GetAncestorMedianTimePast(tip.Hash(), 12)
+	ancestorMedianTimePast, errAncestor := utxoStore.GetAncestorMedianTimePast(tip.Hash(), 2)
+	_, errTooHigh := utxoStore.GetAncestorMedianTimePast(tip.Hash(), 13)
+
+	// Assert
+	assert.NoError(t, errTip)
+	assert.NoError(t, errAncestor)
+	assert.Error(t, errTooHigh)
+	// Median of the timestamps of blocks 2 to 12
+	assert.Equal(t, int64(1070), tipMedianTimePast)
+	// Median of the timestamps of genesis, block 1 and block 2
+	assert.Equal(t, int64(1010), ancestorMedianTimePast)
 }
 
 func TestOutpoint_equality(t *testing.T) {
@@ -0,0 +1,113 @@
+package utxo
+
+import (
+	"fmt"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+)
+
+// overlayEntry is a change of a single UTXO relative to the chain state.
+type overlayEntry struct {
+	Coin    coin
+	Removed bool
+}
+
+// utxoView is a read-only view of the UTXO set as of a specific block.
+// It consists of the UTXO set of the chain tip and an overlay with all changes needed to get from the tip to the block.
+type utxoView struct {
+	blockHash common.Hash
+	base      map[outpoint]coin
+	// overlay takes precedence over base. Nil for a view of the chain tip.
+	overlay map[outpoint]overlayEntry
+}
+
+// get returns the unspent output at the given outpoint.
+func (v *utxoView) get(op outpoint) (coin, bool) {
+	if entry, exists := v.overlay[op]; exists {
+		return entry.Coin, !entry.Removed
+	}
+
+	utxoCoin, exists := v.base[op]
+	return utxoCoin, exists
+}
+
+// forEach calls fn for every unspent output in the view.
+func (v *utxoView) forEach(fn func(outpoint, coin)) {
+	for op, utxoCoin := range v.base {
+		if _, changed := v.overlay[op]; !changed {
+			fn(op, utxoCoin)
+		}
+	}
+
+	for op, entry := range v.overlay {
+		if !entry.Removed {
+			fn(op, entry.Coin)
+		}
+	}
+}
+
+// viewAt returns the UTXO set as of the given block.
+//
+// For the chain tip the UTXO set is used directly.
+// For other blocks the undo records are replayed: blocks from the tip back to the fork point are reverted,
+// then the blocks from the fork point to the requested block are applied again.
+// This only works for blocks that were connected at some point, as only those have undo records,
+// and only as long as no undo record on the way was pruned.
+// Caller must hold the (write) lock.
+func (us *UtxoStore) viewAt(blockHash common.Hash) (*utxoView, error) {
+	if blockHash == us.tip {
+		return &utxoView{blockHash: blockHash, base: us.utxos}, nil
+	}
+
+	if us.cachedView != nil && us.cachedView.blockHash == blockHash {
+		return us.cachedView, nil
+	}
+
+	// Walk back from the requested block until a block of the current chain is reached
+	sidePath := make([]common.Hash, 0)
+	forkPoint := blockHash
+	for {
+		if _, isConnected := us.connected[forkPoint]; isConnected {
+			break
+		}
+
+		undo, exists := us.undo[forkPoint]
+		if !exists || undo.Pruned {
+			return nil, fmt.Errorf("UTXO set for block %v not reachable", blockHash)
+		}
+
+		sidePath = append(sidePath, forkPoint)
+		forkPoint = undo.PrevBlockHash
+	}
+
+	overlay := make(map[outpoint]overlayEntry)
+
+	// Revert the current chain from the tip down to the fork point
+	for current := us.tip; current != forkPoint; {
+		undo := us.undo[current]
+		if undo.Pruned {
+			return nil, fmt.Errorf("UTXO set for block %v not reachable, undo record of block %v was pruned", blockHash, current)
+		}
+		for _, created := range undo.Created {
+			overlay[created.Outpoint] = overlayEntry{Removed: true}
+		}
+		for _, spent := range undo.Spent {
+			overlay[spent.Outpoint] = overlayEntry{Coin: spent.Coin}
+		}
+		current = undo.PrevBlockHash
+	}
+
+	// Apply the side path from the fork point up to the requested block
+	for i := len(sidePath) - 1; i >= 0; i-- {
+		undo := us.undo[sidePath[i]]
+		for _, spent := range undo.Spent {
+			overlay[spent.Outpoint] = overlayEntry{Removed: true}
+		}
+		for _, created := range undo.Created {
+			overlay[created.Outpoint] = overlayEntry{Coin: created.Coin}
+		}
+	}
+
+	us.cachedView = &utxoView{blockHash: blockHash, base: us.utxos, overlay: overlay}
+
+	return us.cachedView, nil
+}
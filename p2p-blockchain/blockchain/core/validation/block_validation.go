@@ -2,9 +2,12 @@ package validation
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/utxo"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/blockchain"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
@@ -15,6 +18,10 @@ import (
 
 const minutesAheadLimit = 5
 
+// ErrTimestampTooFarInFuture indicates a header timestamp more than minutesAheadLimit ahead of the local clock.
+// This depends on the local clock, the block may become valid later on and the sending peer is not to blame for it.
+var ErrTimestampTooFarInFuture = errors.New("header timestamp is too far in the future")
+
 // BlockValidationAPI defines the interface for block validation
 // There are three levels of validation:
 //  1. Sanity Check: Basic checks on the block structure and content
@@ -23,9 +30,9 @@ const minutesAheadLimit = 5
 //  3. Full Validation: Comprehensive validation including transactions and UTXO set
 //     -> Block must be valid to be added to the main chain
 type BlockValidationAPI interface {
-	// SanityCheck Sanity Check: Basic checks on the block structure and content
+	// SanityCheck Sanity Check: Basic checks on the block structure and content (size limit, coinbase)
 	SanityCheck(block block.Block) (bool, error)
-	// ValidateHeaderOnly Validates a standalone block header (proof of work, timestamp)
+	// ValidateHeaderOnly Validates a standalone block header (proof of work, timestamp, difficulty target and median-time-past if the previous block is known)
 	ValidateHeaderOnly(header block.BlockHeader) (bool, error)
 	// FullValidation Comprehensive validation including transactions and UTXO set
 	FullValidation(block block.Block) (bool, error)
@@ -34,6 +41,7 @@ type BlockValidationAPI interface {
 type BlockValidationService struct {
 	txValidator TransactionValidatorAPI
 	utxoStore   utxo.UtxoStoreAPI
+	blockStore  blockchain.BlockStoreAPI
 }
 
 // NewBlockValidationService creates new BlockValidationService
@@ -45,30 +53,69 @@ func NewBlockValidationService() *BlockValidationService {
 func (bvs *BlockValidationService) SetDependencies(
 	txValidator TransactionValidatorAPI,
 	utxoStore utxo.UtxoStoreAPI,
+	blockStore blockchain.BlockStoreAPI,
 ) {
 	bvs.txValidator = txValidator
 	bvs.utxoStore = utxoStore
+	bvs.blockStore = blockStore
 }
 
-// SanityCheck Sanity Check: Basic checks on the block structure and content
-func (bvs *BlockValidationService) SanityCheck(block block.Block) (bool, error) {
-	if len(block.Transactions) < 1 {
+// SanityCheck Sanity Check: Basic checks on the block structure and content (size limit, coinbase)
+func (bvs *BlockValidationService) SanityCheck(blk block.Block) (bool, error) {
+	if size := blk.SerializedSize(); size > block.MaxBlockSize {
+		return false, fmt.Errorf("block size %d exceeds the maximum block size %d", size, block.MaxBlockSize)
+	}
+	if len(blk.Transactions) < 1 {
 		return false, fmt.Errorf("block must contain at least one transaction")
 	}
-	if !block.Transactions[0].IsCoinbase() {
+	if !blk.Transactions[0].IsCoinbase() {
 		return false, fmt.Errorf("first transaction must be the coinbase transaction")
 	}
 
 	return true, nil
 }
 
-// ValidateHeaderOnly Validates a standalone block header (proof of work, timestamp)
+// ValidateHeaderOnly Validates a standalone block header (proof of work, timestamp, difficulty target and median-time-past if the previous block is known)
+// The difficulty target and median-time-past of headers with an unknown or orphan previous block are checked by the block store once the block is connected.
+// A block failing them then is marked invalid and never becomes part of the main chain.
 func (bvs *BlockValidationService) ValidateHeaderOnly(header block.BlockHeader) (bool, error) {
 	if !headerHashSmallerThanTarget(header) {
 		return false, fmt.Errorf("header hash does not meet difficulty target")
 	}
 	if headerTimeIsTooFarInFuture(header) {
-		return false, fmt.Errorf("header timestamp is too far in the future")
+		return false, ErrTimestampTooFarInFuture
+	}
+
+	if requiredTarget, err := bvs.blockStore.GetNextDifficultyTarget(header.PreviousBlockHash); err == nil {
+		if ok, err := bvs.ValidateDifficultyTarget(header, requiredTarget); !ok {
+			return false, err
+		}
+	}
+
+	if medianTimePast, err := bvs.blockStore.GetMedianTimePast(header.PreviousBlockHash); err == nil {
+		if ok, err := bvs.ValidateTimestamp(header, medianTimePast); !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// ValidateDifficultyTarget checks that the header claims exactly the difficulty target required by the retarget rule.
+// Together with the proof of work check this prevents blocks that are cheaper to mine than the chain allows.
+func (bvs *BlockValidationService) ValidateDifficultyTarget(header block.BlockHeader, requiredTarget uint8) (bool, error) {
+	if header.DifficultyTarget != requiredTarget {
+		return false, fmt.Errorf("header difficulty target %d does not match required target %d", header.DifficultyTarget, requiredTarget)
+	}
+
+	return true, nil
+}
+
+// ValidateTimestamp checks that the header timestamp is later than the median-time-past of the previous blocks.
+// Together with the future limit this keeps block timestamps close to the real time, which the retarget rule depends on.
+func (bvs *BlockValidationService) ValidateTimestamp(header block.BlockHeader, medianTimePast int64) (bool, error) {
+	if header.Timestamp <= medianTimePast {
+		return false, fmt.Errorf("header timestamp %d is not later than the median-time-past %d", header.Timestamp, medianTimePast)
 	}
 
 	return true, nil
@@ -100,8 +147,10 @@ func headerHashSmallerThanTarget(header block.BlockHeader) bool {
 //  2. For each transaction:
 //     a. Ensure only one coinbase transaction exists (first tx)
 //     b. Check for double-spending within the block
-//     c. Validate transaction via txValidator
+//     c. Validate transaction via txValidator (UTXOs, maturity, lock times), outputs of preceding transactions of the block can be spent
 //     d. Verify all input signatures against referenced UTXOs
+//     e. Sum up the fee (referenced UTXOs minus outputs)
+//  3. Verify the coinbase transaction (output types, multisig scripts and data outputs, embedded height, value <= subsidy + fees)
 func (bvs *BlockValidationService) FullValidation(block block.Block) (bool, error) {
 	// Validate merkle root
 	if !isMerkleRootValid(block) {
@@ -109,16 +158,18 @@ func (bvs *BlockValidationService) FullValidation(block block.Block) (bool, erro
 	}
 
 	usedInputs := mapset.NewSet[string]()
-	var coinbaseFound = false
+	precedingTransactions := newBlockOverlay()
+	var coinbase *transaction.Transaction
+	var fees uint64
 	prevBlockHash := block.Header.PreviousBlockHash
 
 	for i, tx := range block.Transactions {
 		if tx.IsCoinbase() {
-			if coinbaseFound {
+			if coinbase != nil {
 				return false, fmt.Errorf("multiple coinbase transactions in block %v", block.Header.Hash())
 			}
 
-			coinbaseFound = true
+			coinbase = &block.Transactions[i]
 			continue
 		}
 
@@ -131,7 +182,7 @@ func (bvs *BlockValidationService) FullValidation(block block.Block) (bool, erro
 			usedInputs.Add(key)
 		}
 
-		valid, err := bvs.txValidator.ValidateTransaction(tx, prevBlockHash)
+		valid, err := bvs.txValidator.ValidateTransactionWithOverlay(tx, prevBlockHash, precedingTransactions)
 		if err != nil {
 			return false, fmt.Errorf("transaction %d validation failed: %w", i, err)
 		}
@@ -139,33 +190,131 @@ func (bvs *BlockValidationService) FullValidation(block block.Block) (bool, erro
 			return false, fmt.Errorf("transaction %d is invalid", i)
 		}
 
+		referencedOutputs, err := getReferencedOutputs(bvs.utxoStore, tx, prevBlockHash, precedingTransactions)
+		if err != nil {
+			return false, fmt.Errorf("signature verification failed for transaction %d: %w", i, err)
+		}
+
 		// Verify all signatures in the transaction
-		valid, err = bvs.verifyTransactionSignatures(tx, prevBlockHash)
+		valid, err = tx.VerifyAllSignatures(referencedOutputs)
 		if err != nil {
 			return false, fmt.Errorf("signature verification failed for transaction %d: %w", i, err)
 		}
 		if !valid {
 			return false, fmt.Errorf("invalid transaction signature in block %v for tx %v", block.Header.Hash(), tx.Hash())
 		}
+
+		fees, err = addFee(fees, referencedOutputs, tx.Outputs)
+		if err != nil {
+			return false, fmt.Errorf("fee of transaction %d: %w", i, err)
+		}
+
+		precedingTransactions.add(tx)
+	}
+
+	if coinbase == nil {
+		return false, fmt.Errorf("block %v has no coinbase transaction", block.Header.Hash())
+	}
+
+	// The height is part of the block tree, only the UTXO view depends on the chain state
+	prevHeight, err := bvs.blockStore.GetBlockHeight(prevBlockHash)
+	if err != nil {
+		return false, fmt.Errorf("%w: height of block %v unknown: %v", ErrChainStateUnavailable, block.Header.Hash(), err)
+	}
+
+	return validateCoinbase(*coinbase, prevHeight+1, fees)
+}
+
+// validateCoinbase checks the outputs of the coinbase transaction like those of any other transaction, the height embedded in it and
+// that the coinbase does not create more than the block subsidy plus the fees of all transactions in the block.
+func validateCoinbase(coinbase transaction.Transaction, height uint64, fees uint64) (bool, error) {
+	if err := validateOutputs(coinbase.Outputs); err != nil {
+		return false, fmt.Errorf("coinbase output: %w", err)
+	}
+
+	coinbaseHeight, ok := coinbase.CoinbaseHeight()
+	if !ok {
+		return false, fmt.Errorf("coinbase transaction does not contain a block height")
+	}
+	if coinbaseHeight != height {
+		return false, fmt.Errorf("coinbase height %d does not match block height %d", coinbaseHeight, height)
+	}
+
+	coinbaseValue, err := sumOfOutputs(coinbase.Outputs)
+	if err != nil {
+		return false, fmt.Errorf("coinbase value: %w", err)
+	}
+
+	maxValue, err := addValue(block.BlockSubsidy(height), fees)
+	if err != nil {
+		return false, fmt.Errorf("coinbase value: %w", err)
+	}
+	if coinbaseValue > maxValue {
+		return false, fmt.Errorf("coinbase value %d exceeds block subsidy %d plus fees %d", coinbaseValue, block.BlockSubsidy(height), fees)
 	}
 
 	return true, nil
 }
 
-// verifyTransactionSignatures verifies all signatures in a transaction.
-func (bvs *BlockValidationService) verifyTransactionSignatures(tx transaction.Transaction, blockHash common.Hash) (bool, error) {
-	// Collect referenced outputs for all inputs
+// getReferencedOutputs collects the outputs referenced by all inputs of the transaction from the overlay
+// or the UTXO set as of the given block. The overlay may be nil.
+func getReferencedOutputs(utxoStore utxo.UtxoStoreAPI, tx transaction.Transaction, blockHash common.Hash, overlay UtxoOverlay) ([]transaction.Output, error) {
 	referencedOutputs := make([]transaction.Output, len(tx.Inputs))
 
 	for i, input := range tx.Inputs {
-		output, err := bvs.utxoStore.GetUtxoFromBlock(input.PrevTxID, input.OutputIndex, blockHash)
+		if overlay != nil {
+			if output, ok := overlay.GetOutput(input.PrevTxID, input.OutputIndex); ok {
+				referencedOutputs[i] = output
+				continue
+			}
+		}
+
+		output, err := utxoStore.GetUtxoFromBlock(input.PrevTxID, input.OutputIndex, blockHash)
 		if err != nil {
-			return false, fmt.Errorf("failed to get UTXO for input %d: %w", i, err)
+			return nil, fmt.Errorf("%w: input %d: %v", ErrUTXONotFound, i, err)
 		}
 		referencedOutputs[i] = output
 	}
 
-	return tx.VerifyAllSignatures(referencedOutputs)
+	return referencedOutputs, nil
+}
+
+// addFee adds the fee of a transaction (referenced outputs minus outputs) to the given fees.
+func addFee(fees uint64, referencedOutputs []transaction.Output, outputs []transaction.Output) (uint64, error) {
+	inputSum, err := sumOfOutputs(referencedOutputs)
+	if err != nil {
+		return 0, err
+	}
+	outputSum, err := sumOfOutputs(outputs)
+	if err != nil {
+		return 0, err
+	}
+	if inputSum < outputSum {
+		return 0, fmt.Errorf("outputs %d exceed inputs %d", outputSum, inputSum)
+	}
+
+	return addValue(fees, inputSum-outputSum)
+}
+
+// sumOfOutputs returns the total value of the given outputs.
+func sumOfOutputs(outputs []transaction.Output) (uint64, error) {
+	var sum uint64
+	for _, output := range outputs {
+		var err error
+		sum, err = addValue(sum, output.Value)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return sum, nil
+}
+
+// addValue adds two amounts and returns an error if the sum overflows.
+func addValue(a uint64, b uint64) (uint64, error) {
+	if a > math.MaxUint64-b {
+		return 0, fmt.Errorf("value overflow")
+	}
+	return a + b, nil
 }
 
 // createInputKey creates a unique key for an input to detect double-spends.
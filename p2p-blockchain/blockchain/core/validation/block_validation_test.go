@@ -0,0 +1,299 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/blockchain"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+// mockBlockStore is a mock for the block heights of blockchain.BlockStoreAPI, other methods are not implemented.
+type mockBlockStore struct {
+	blockchain.BlockStoreAPI
+	// heights maps the known, non-orphan blocks to their height
+	heights map[common.Hash]uint64
+}
+
+func (m *mockBlockStore) GetBlockHeight(hash common.Hash) (uint64, error) {
+	height, exists := m.heights[hash]
+	if !exists {
+		return 0, fmt.Errorf("block with hash %v not found", hash)
+	}
+	return height, nil
+}
+
+// createSpendingBlockValidator creates a BlockValidationService whose UTXO set contains a single output of 100 owned by the returned private key.
+// The previous block of the validated blocks has height 4.
+func createSpendingBlockValidator() (*BlockValidationService, transaction.UTXO, transaction.PrivateKey) {
+	var privateKey transaction.PrivateKey
+	privateKey[31] = 1
+	_, publicKey := btcec.PrivKeyFromBytes(privateKey[:])
+	var pubKey transaction.PubKey
+	copy(pubKey[:], publicKey.SerializeCompressed())
+
+	utxo := transaction.UTXO{
+		TxID:        createTestTransactionID(1),
+		OutputIndex: 0,
+		Output:      transaction.Output{Value: 100, PubKeyHash: transaction.Hash160(pubKey)},
+	}
+
+	mockStore := &MockUtxoStore{
+		utxos:       map[string]transaction.Output{makeOutpointKey(utxo.TxID, utxo.OutputIndex): utxo.Output},
+		blockHeight: 4,
+	}
+
+	bvs := NewBlockValidationService()
+	bvs.SetDependencies(NewTransactionValidator(mockStore), mockStore, &mockBlockStore{heights: map[common.Hash]uint64{{}: 4}})
+
+	return bvs, utxo, privateKey
+}
+
+// createBlockWithFee creates a block at height 5 with a transaction paying a fee of 10 and a coinbase with the given value and height.
+func createBlockWithFee(t *testing.T, utxo transaction.UTXO, privateKey transaction.PrivateKey, coinbaseValue uint64, coinbaseHeight uint64) block.Block {
+	tx, err := transaction.NewTransaction([]transaction.UTXO{utxo}, transaction.PubKeyHash{9}, 90, 10, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	transactions := []transaction.Transaction{
+		transaction.NewCoinbaseTransaction(transaction.PubKeyHash{1}, coinbaseValue, coinbaseHeight),
+		*tx,
+	}
+
+	return block.Block{
+		Header:       block.BlockHeader{MerkleRoot: block.MerkleRootFromTransactions(transactions)},
+		Transactions: transactions,
+	}
+}
+
+// TestFullValidation_CoinbaseClaimsSubsidyAndFees tests that the coinbase may claim exactly the subsidy plus all fees.
+func TestFullValidation_CoinbaseClaimsSubsidyAndFees(t *testing.T) {
+	bvs, utxo, privateKey := createSpendingBlockValidator()
+	blk := createBlockWithFee(t, utxo, privateKey, block.BlockSubsidy(5)+10, 5)
+
+	valid, err := bvs.FullValidation(blk)
+	if err != nil {
+		t.Errorf("Valid block returned error: %v", err)
+	}
+	if !valid {
+		t.Error("Block claiming subsidy plus fees should be valid")
+	}
+}
+
+// TestFullValidation_CoinbaseExceedsSubsidyAndFees tests that a coinbase paying more than subsidy plus fees is rejected.
+func TestFullValidation_CoinbaseExceedsSubsidyAndFees(t *testing.T) {
+	bvs, utxo, privateKey := createSpendingBlockValidator()
+	blk := createBlockWithFee(t, utxo, privateKey, block.BlockSubsidy(5)+11, 5)
+
+	valid, err := bvs.FullValidation(blk)
+	if valid || err == nil {
+		t.Error("Block whose coinbase exceeds subsidy plus fees should be invalid")
+	}
+}
+
+// TestFullValidation_CoinbaseHeightMismatch tests that a coinbase with a height other than the block height is rejected.
+func TestFullValidation_CoinbaseHeightMismatch(t *testing.T) {
+	bvs, utxo, privateKey := createSpendingBlockValidator()
+	blk := createBlockWithFee(t, utxo, privateKey, block.BlockSubsidy(5), 6)
+
+	valid, err := bvs.FullValidation(blk)
+	if valid || err == nil {
+		t.Error("Block with wrong coinbase height should be invalid")
+	}
+}
+
+// TestFullValidation_CoinbaseOutputStructure tests that coinbase outputs of an unknown type or with a malformed multisig script are rejected.
+func TestFullValidation_CoinbaseOutputStructure(t *testing.T) {
+	tests := map[string]transaction.Output{
+		"unknown output type": {Value: 10, Type: 42},
+		"malformed multisig":  {Value: 10, Type: transaction.OutputTypeMultisig, Multisig: transaction.MultisigScript{Required: 3, PubKeys: []transaction.PubKey{{2}}}},
+	}
+
+	for name, output := range tests {
+		t.Run(name, func(t *testing.T) {
+			bvs, utxo, privateKey := createSpendingBlockValidator()
+			blk := createBlockWithFee(t, utxo, privateKey, block.BlockSubsidy(5), 5)
+			blk.Transactions[0].Outputs = append(blk.Transactions[0].Outputs, output)
+			blk.Header.MerkleRoot = block.MerkleRootFromTransactions(blk.Transactions)
+
+			valid, err := bvs.FullValidation(blk)
+			if valid || !errors.Is(err, ErrInvalidOutput) {
+				t.Errorf("Block with coinbase output of %s should be invalid: got %v", name, err)
+			}
+		})
+	}
+}
+
+// TestFullValidation_CoinbaseDataOutput tests that the data output rules apply to coinbase outputs as well.
+func TestFullValidation_CoinbaseDataOutput(t *testing.T) {
+	tests := map[string]struct {
+		output transaction.Output
+		valid  bool
+	}{
+		"data output of maximum size": {output: transaction.Output{Type: transaction.OutputTypeData, Data: make([]byte, transaction.MaxDataSize)}, valid: true},
+		"oversized data output":       {output: transaction.Output{Type: transaction.OutputTypeData, Data: make([]byte, transaction.MaxDataSize+1)}},
+		"data output with value":      {output: transaction.Output{Value: 1, Type: transaction.OutputTypeData, Data: []byte{1}}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			bvs, utxo, privateKey := createSpendingBlockValidator()
+			blk := createBlockWithFee(t, utxo, privateKey, block.BlockSubsidy(5), 5)
+			blk.Transactions[0].Outputs = append(blk.Transactions[0].Outputs, tt.output)
+			blk.Header.MerkleRoot = block.MerkleRootFromTransactions(blk.Transactions)
+
+			valid, err := bvs.FullValidation(blk)
+			if tt.valid && !valid {
+				t.Errorf("Block with coinbase %s should be valid: got %v", name, err)
+			}
+			if !tt.valid && (valid || !errors.Is(err, ErrInvalidDataOutput)) {
+				t.Errorf("Block with coinbase %s should be invalid: got %v", name, err)
+			}
+		})
+	}
+}
+
+// createBlockWithChain creates a block at height 5 with a transaction paying a fee of 10 back to the owner of the UTXO
+// and a child transaction spending its output with another fee of 10. If childFirst is set, the child precedes its parent.
+func createBlockWithChain(t *testing.T, utxo transaction.UTXO, privateKey transaction.PrivateKey, childFirst bool) block.Block {
+	parent, err := transaction.NewTransaction([]transaction.UTXO{utxo}, utxo.Output.PubKeyHash, 90, 10, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create parent transaction: %v", err)
+	}
+	parentOutput := transaction.UTXO{TxID: parent.TransactionId(), OutputIndex: 0, Output: parent.Outputs[0]}
+	child, err := transaction.NewTransaction([]transaction.UTXO{parentOutput}, transaction.PubKeyHash{9}, 80, 10, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create child transaction: %v", err)
+	}
+
+	transactions := []transaction.Transaction{transaction.NewCoinbaseTransaction(transaction.PubKeyHash{1}, block.BlockSubsidy(5)+20, 5)}
+	if childFirst {
+		transactions = append(transactions, *child, *parent)
+	} else {
+		transactions = append(transactions, *parent, *child)
+	}
+
+	return block.Block{
+		Header:       block.BlockHeader{MerkleRoot: block.MerkleRootFromTransactions(transactions)},
+		Transactions: transactions,
+	}
+}
+
+// TestFullValidation_ChainWithinBlock tests that a transaction may spend the output of a preceding transaction of the same block.
+func TestFullValidation_ChainWithinBlock(t *testing.T) {
+	bvs, utxo, privateKey := createSpendingBlockValidator()
+	blk := createBlockWithChain(t, utxo, privateKey, false)
+
+	valid, err := bvs.FullValidation(blk)
+	if err != nil {
+		t.Errorf("Block with parent before child returned error: %v", err)
+	}
+	if !valid {
+		t.Error("Block with parent before child should be valid")
+	}
+}
+
+// TestFullValidation_ChildBeforeParent tests that a transaction must not spend the output of a following transaction of the same block.
+func TestFullValidation_ChildBeforeParent(t *testing.T) {
+	bvs, utxo, privateKey := createSpendingBlockValidator()
+	blk := createBlockWithChain(t, utxo, privateKey, true)
+
+	valid, err := bvs.FullValidation(blk)
+	if valid || err == nil {
+		t.Error("Block with child before parent should be invalid")
+	}
+}
+
+// TestFullValidation_ReplacementTransaction tests that a fee bump of a replaceable transaction is signed correctly
+// and pays the higher fee from the change.
+func TestFullValidation_ReplacementTransaction(t *testing.T) {
+	bvs, utxo, privateKey := createSpendingBlockValidator()
+	utxos := []transaction.UTXO{utxo}
+	original, err := transaction.NewReplaceableTransactionToOutput(utxos, transaction.Output{Value: 50, PubKeyHash: transaction.PubKeyHash{9}}, 10, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	if _, err = transaction.NewReplacementTransaction(original, utxos, 10, privateKey); !errors.Is(err, transaction.ErrReplacementFeeTooLow) {
+		t.Errorf("Expected ErrReplacementFeeTooLow for the same fee, got: %v", err)
+	}
+
+	replacement, err := transaction.NewReplacementTransaction(original, utxos, 20, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create replacement: %v", err)
+	}
+	if !replacement.SignalsReplaceability() || replacement.Inputs[0].PrevTxID != utxo.TxID {
+		t.Error("Replacement should spend the same UTXO and signal replaceability")
+	}
+	if replacement.Outputs[0].Value != 50 || replacement.Outputs[1].Value != 30 {
+		t.Errorf("Replacement should pay the recipient 50 and a change of 30, got %+v", replacement.Outputs)
+	}
+
+	transactions := []transaction.Transaction{
+		transaction.NewCoinbaseTransaction(transaction.PubKeyHash{1}, block.BlockSubsidy(5)+20, 5),
+		*replacement,
+	}
+	blk := block.Block{
+		Header:       block.BlockHeader{MerkleRoot: block.MerkleRootFromTransactions(transactions)},
+		Transactions: transactions,
+	}
+
+	valid, err := bvs.FullValidation(blk)
+	if err != nil {
+		t.Errorf("Block with replacement returned error: %v", err)
+	}
+	if !valid {
+		t.Error("Block with replacement should be valid")
+	}
+}
+
+// TestFullValidation_HeightFromBlockStore tests that the height of the coinbase is checked against the height of the previous block
+// in the block store, so a coinbase-only block of a side chain validates even though its previous block was never connected to the UTXO set.
+func TestFullValidation_HeightFromBlockStore(t *testing.T) {
+	sideChainBlock := common.Hash{7}
+	bvs := NewBlockValidationService()
+	mockStore := &MockUtxoStore{}
+	bvs.SetDependencies(NewTransactionValidator(mockStore), mockStore, &mockBlockStore{heights: map[common.Hash]uint64{sideChainBlock: 7}})
+
+	transactions := []transaction.Transaction{transaction.NewCoinbaseTransaction(transaction.PubKeyHash{1}, block.BlockSubsidy(8), 8)}
+	blk := block.Block{
+		Header:       block.BlockHeader{PreviousBlockHash: sideChainBlock, MerkleRoot: block.MerkleRootFromTransactions(transactions)},
+		Transactions: transactions,
+	}
+
+	valid, err := bvs.FullValidation(blk)
+	if err != nil {
+		t.Errorf("Coinbase-only block of a side chain returned error: %v", err)
+	}
+	if !valid {
+		t.Error("Coinbase-only block of a side chain should be valid")
+	}
+
+	blk.Header.PreviousBlockHash = common.Hash{8}
+	if valid, err = bvs.FullValidation(blk); valid || !errors.Is(err, ErrChainStateUnavailable) {
+		t.Errorf("Block with an unknown previous block should fail with ErrChainStateUnavailable, got: %v", err)
+	}
+}
+
+// TestSanityCheck_BlockTooLarge tests that blocks exceeding the maximum block size are rejected.
+func TestSanityCheck_BlockTooLarge(t *testing.T) {
+	bvs := NewBlockValidationService()
+
+	coinbase := transaction.NewCoinbaseTransaction(transaction.PubKeyHash{1}, 50, 1)
+	blk := block.Block{Transactions: []transaction.Transaction{coinbase}}
+	if valid, err := bvs.SanityCheck(blk); !valid {
+		t.Fatalf("Small block should pass the sanity check: %v", err)
+	}
+
+	blk.Transactions[0].Inputs[0].Signature = make([]byte, block.MaxBlockSize)
+	valid, err := bvs.SanityCheck(blk)
+	if valid || err == nil {
+		t.Error("Block exceeding the maximum block size should be invalid")
+	}
+}
@@ -3,6 +3,7 @@ package validation
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/core/utxo"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
@@ -15,14 +16,63 @@ var (
 	ErrInsufficientInputs = errors.New("sum of inputs is less than sum of outputs")
 	ErrNoInputs           = errors.New("transaction has no inputs")
 	ErrNoOutputs          = errors.New("transaction has no outputs")
+	ErrImmatureCoinbase   = errors.New("referenced coinbase output is not mature yet")
+	ErrLockTimeNotReached = errors.New("lock time of the transaction is not reached yet")
+	ErrRelativeLockTime   = errors.New("relative lock time of an input is not reached yet")
+	ErrInvalidOutput      = errors.New("transaction has an output with an unknown type or an invalid multisig script")
+	ErrInvalidDataOutput  = errors.New("data output carries a value or exceeds the maximum data size")
+	ErrOutputAlreadySpent = errors.New("referenced output is already spent by an unconfirmed transaction")
+	ErrInvalidSignature   = errors.New("transaction has an invalid or missing signature")
+	// ErrChainStateUnavailable indicates that the height or the UTXO set of the previous block is not available,
+	// e.g. for a block of a side chain whose UTXO set can't be reached from the current one.
+	ErrChainStateUnavailable = errors.New("chain state of the previous block is not available")
 )
 
+// IsLookupError reports whether a validation failed because a referenced output or the chain state of the previous block
+// was not found. This does not prove the block or transaction invalid, the node may simply not know the outputs yet,
+// e.g. a transaction spending outputs of a transaction not received yet or a block of a side chain.
+func IsLookupError(err error) bool {
+	return errors.Is(err, ErrUTXONotFound) || errors.Is(err, ErrChainStateUnavailable)
+}
+
+// IsInvalidTransaction reports whether a validation error proves the transaction invalid independent of the chain state
+// and the mempool of this node: a malformed structure, an input spent twice, a spender not owning the output, outputs
+// exceeding the inputs or an invalid signature. Missing or already spent inputs, lock times and mempool policy are not included,
+// as the transaction may be valid for another node or later on.
+func IsInvalidTransaction(err error) bool {
+	for _, target := range []error{
+		ErrNoInputs, ErrNoOutputs, ErrInvalidOutput, ErrInvalidDataOutput, ErrDuplicateInput,
+		ErrPubKeyHashMismatch, ErrInsufficientInputs, ErrInvalidSignature,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // TransactionValidatorAPI defines the interface for validating transactions against the UTXO set.
 type TransactionValidatorAPI interface {
 	// ValidateTransaction validates a transaction against the UTXO set at a specific block.
+	// The transaction is validated for inclusion in the block following the given block (relevant for coinbase maturity and lock times).
 	// It returns true if the transaction is valid, false otherwise.
 	// For coinbase transactions, validation is always successful (reward validation is done at block level).
 	ValidateTransaction(tx transaction.Transaction, blockHash common.Hash) (bool, error)
+
+	// ValidateTransactionWithOverlay validates a transaction like ValidateTransaction, but against the UTXO set at the given block
+	// with the overlay applied on top. Outputs created by the overlay can be spent and are treated as confirmed in the
+	// block following the given block. Outputs spent by the overlay cannot be spent again (ErrOutputAlreadySpent).
+	ValidateTransactionWithOverlay(tx transaction.Transaction, blockHash common.Hash, overlay UtxoOverlay) (bool, error)
+
+	// GetTransactionFee returns the fee of a transaction, the sum of the referenced outputs minus the sum of its outputs,
+	// using the UTXO set at the given block and the outputs created by the overlay. The overlay may be nil.
+	// Returns ErrUTXONotFound if a referenced output does not exist and ErrInsufficientInputs if the outputs exceed the inputs.
+	GetTransactionFee(tx transaction.Transaction, blockHash common.Hash, overlay UtxoOverlay) (uint64, error)
+
+	// VerifySignatures verifies the signatures of all inputs of a transaction against the outputs they spend,
+	// taken from the overlay or the UTXO set at the given block. The overlay may be nil.
+	// Returns ErrUTXONotFound if a referenced output does not exist and ErrInvalidSignature if a signature is invalid.
+	VerifySignatures(tx transaction.Transaction, blockHash common.Hash, overlay UtxoOverlay) error
 }
 
 // TransactionValidator implements TransactionValidatorAPI and validates transactions
@@ -40,13 +90,22 @@ func NewTransactionValidator(utxoStoreAPI utxo.UtxoStoreAPI) TransactionValidato
 
 // ValidateTransaction validates a transaction against the UTXO set at the specified block.
 // It performs the following checks:
-//  1. Coinbase transactions are automatically valid (no UTXO validation needed)
-//  2. Basic sanity checks (non-empty inputs and outputs)
+//  1. Coinbase transactions are automatically valid (no UTXO validation needed), their outputs are checked by the block validation
+//  2. Basic sanity checks (non-empty inputs and outputs, known output types, valid multisig scripts and data outputs)
 //  3. Duplicate input detection (prevents double-spending within the same transaction)
-//  4. UTXO existence verification
-//  5. Public key hash validation (ensures the spender owns the referenced output)
-//  6. Value conservation (inputs >= outputs, difference is the transaction fee)
+//  4. Lock time (the transaction must be final in the block following blockHash)
+//  5. UTXO existence verification
+//  6. Coinbase maturity (referenced coinbase outputs must be spendable in the block following blockHash)
+//  7. Relative lock times (referenced outputs must be old enough for the sequence numbers of the inputs)
+//  8. Public key hash validation (ensures the spender owns the referenced output or reveals the script of a multisig hash output)
+//  9. Value conservation (inputs >= outputs, difference is the transaction fee)
 func (t *TransactionValidator) ValidateTransaction(tx transaction.Transaction, blockHash common.Hash) (bool, error) {
+	return t.ValidateTransactionWithOverlay(tx, blockHash, nil)
+}
+
+// ValidateTransactionWithOverlay implements TransactionValidatorAPI.ValidateTransactionWithOverlay.
+// The overlay may be nil, which is the same as ValidateTransaction.
+func (t *TransactionValidator) ValidateTransactionWithOverlay(tx transaction.Transaction, blockHash common.Hash, overlay UtxoOverlay) (bool, error) {
 	// Coinbase transactions are valid by structure (no UTXO validation needed)
 	if tx.IsCoinbase() {
 		return true, nil
@@ -56,7 +115,21 @@ func (t *TransactionValidator) ValidateTransaction(tx transaction.Transaction, b
 		return false, err
 	}
 
-	inputSum, err := t.validateInputs(tx, blockHash)
+	blockHeight, err := t.utxoStore.GetBlockHeight(blockHash)
+	if err != nil {
+		return false, ErrUTXONotFound // UTXO set of the block not reachable
+	}
+	medianTimePast, err := t.utxoStore.GetAncestorMedianTimePast(blockHash, blockHeight)
+	if err != nil {
+		return false, ErrUTXONotFound
+	}
+	spend := spendContext{blockHash: blockHash, height: blockHeight + 1, medianTimePast: medianTimePast, overlay: overlay}
+
+	if !tx.IsFinal(spend.height, spend.medianTimePast) {
+		return false, ErrLockTimeNotReached
+	}
+
+	inputSum, err := t.validateInputs(tx, spend)
 	if err != nil {
 		return false, err
 	}
@@ -68,8 +141,63 @@ func (t *TransactionValidator) ValidateTransaction(tx transaction.Transaction, b
 	return true, nil
 }
 
+// GetTransactionFee implements TransactionValidatorAPI.GetTransactionFee.
+func (t *TransactionValidator) GetTransactionFee(tx transaction.Transaction, blockHash common.Hash, overlay UtxoOverlay) (uint64, error) {
+	var inputSum uint64
+	for _, input := range tx.Inputs {
+		if overlay != nil {
+			if output, ok := overlay.GetOutput(input.PrevTxID, input.OutputIndex); ok {
+				inputSum += output.Value
+				continue
+			}
+		}
+
+		referencedOutput, err := t.utxoStore.GetUtxoFromBlock(input.PrevTxID, input.OutputIndex, blockHash)
+		if err != nil {
+			return 0, ErrUTXONotFound
+		}
+		inputSum += referencedOutput.Value
+	}
+
+	outputSum := t.calculateOutputSum(tx)
+	if inputSum < outputSum {
+		return 0, ErrInsufficientInputs
+	}
+	return inputSum - outputSum, nil
+}
+
+// VerifySignatures implements TransactionValidatorAPI.VerifySignatures.
+func (t *TransactionValidator) VerifySignatures(tx transaction.Transaction, blockHash common.Hash, overlay UtxoOverlay) error {
+	referencedOutputs, err := getReferencedOutputs(t.utxoStore, tx, blockHash, overlay)
+	if err != nil {
+		return ErrUTXONotFound
+	}
+
+	valid, err := tx.VerifyAllSignatures(referencedOutputs)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// spendContext describes the block a transaction is validated for.
+type spendContext struct {
+	// blockHash is the hash of the previous block, whose UTXO set is used.
+	blockHash common.Hash
+	// height is the height of the block that would include the transaction.
+	height uint64
+	// medianTimePast is the median-time-past of the previous block.
+	medianTimePast int64
+	// overlay contains the unconfirmed transactions applied on top of the UTXO set, may be nil.
+	overlay UtxoOverlay
+}
+
 // validateBasicStructure performs basic sanity checks on the transaction structure.
-// It ensures the transaction has at least one input and one output.
+// It ensures the transaction has at least one input and one output and that every output except data outputs can be spent.
+// Data outputs must not carry a value, as it would be burned, and at most transaction.MaxDataSize bytes of data.
 func (t *TransactionValidator) validateBasicStructure(tx transaction.Transaction) error {
 	if len(tx.Inputs) == 0 {
 		return ErrNoInputs
@@ -77,13 +205,35 @@ func (t *TransactionValidator) validateBasicStructure(tx transaction.Transaction
 	if len(tx.Outputs) == 0 {
 		return ErrNoOutputs
 	}
+	return validateOutputs(tx.Outputs)
+}
+
+// validateOutputs checks that every output has a known type, multisig outputs contain a valid script
+// and data outputs carry no value and at most transaction.MaxDataSize bytes of data.
+// Used for the outputs of regular and coinbase transactions.
+func validateOutputs(outputs []transaction.Output) error {
+	for _, output := range outputs {
+		switch output.Type {
+		case transaction.OutputTypePubKeyHash, transaction.OutputTypeMultisigHash:
+		case transaction.OutputTypeMultisig:
+			if err := output.Multisig.Validate(); err != nil {
+				return ErrInvalidOutput
+			}
+		case transaction.OutputTypeData:
+			if output.Value != 0 || len(output.Data) > transaction.MaxDataSize {
+				return ErrInvalidDataOutput
+			}
+		default:
+			return ErrInvalidOutput
+		}
+	}
 	return nil
 }
 
 // validateInputs validates all inputs in the transaction.
-// It checks for duplicate inputs, verifies UTXO existence, and validates public key hash bindings.
+// It checks for duplicate inputs, verifies UTXO existence, maturity and relative lock times, and validates public key hash bindings.
 // Returns the total sum of input values if successful.
-func (t *TransactionValidator) validateInputs(tx transaction.Transaction, blockHash common.Hash) (uint64, error) {
+func (t *TransactionValidator) validateInputs(tx transaction.Transaction, spend spendContext) (uint64, error) {
 	seenOutpoints := make(map[string]struct{})
 	var inputSum uint64
 
@@ -95,7 +245,7 @@ func (t *TransactionValidator) validateInputs(tx transaction.Transaction, blockH
 		}
 		seenOutpoints[outpointKey] = struct{}{}
 
-		referencedOutput, err := t.validateAndGetReferencedOutput(tx, input, blockHash)
+		referencedOutput, err := t.validateAndGetReferencedOutput(input, spend)
 		if err != nil {
 			return 0, err
 		}
@@ -127,20 +277,92 @@ func (t *TransactionValidator) checkDuplicateInput(outpointKey string, seenOutpo
 	return nil
 }
 
-// validateAndGetReferencedOutput validates that the referenced UTXO exists and returns it.
+// validateAndGetReferencedOutput validates that the referenced UTXO exists and can be spent at spendHeight and returns it.
+// Outputs of the overlay are treated as UTXOs created at spendHeight.
 // Returns ErrUTXONotFound if the UTXO does not exist or the transaction is invalid.
-func (t *TransactionValidator) validateAndGetReferencedOutput(tx transaction.Transaction, input transaction.Input, blockHash common.Hash) (transaction.Output, error) {
-	referencedOutput, err := t.utxoStore.GetUtxoFromBlock(input.PrevTxID, input.OutputIndex, blockHash)
+// Returns ErrOutputAlreadySpent if the UTXO is spent by the overlay.
+// Returns ErrImmatureCoinbase if the UTXO is a coinbase output that has not reached transaction.CoinbaseMaturity.
+// Returns ErrRelativeLockTime if the UTXO is younger than the relative lock time of the input.
+func (t *TransactionValidator) validateAndGetReferencedOutput(input transaction.Input, spend spendContext) (transaction.Output, error) {
+	referencedUtxo, err := t.getReferencedUtxo(input, spend)
+	if err != nil {
+		return transaction.Output{}, err
+	}
+
+	if !referencedUtxo.IsSpendableAt(spend.height) {
+		return transaction.Output{}, ErrImmatureCoinbase
+	}
+
+	if err := t.validateRelativeLockTime(input, referencedUtxo, spend); err != nil {
+		return transaction.Output{}, err
+	}
+
+	return referencedUtxo.Output, nil
+}
+
+// getReferencedUtxo looks up the UTXO referenced by the input, first in the overlay and then in the UTXO set.
+func (t *TransactionValidator) getReferencedUtxo(input transaction.Input, spend spendContext) (transaction.UTXO, error) {
+	if spend.overlay != nil {
+		if spend.overlay.IsSpent(input.PrevTxID, input.OutputIndex) {
+			return transaction.UTXO{}, ErrOutputAlreadySpent
+		}
+		if output, ok := spend.overlay.GetOutput(input.PrevTxID, input.OutputIndex); ok {
+			return transaction.UTXO{
+				TxID:        input.PrevTxID,
+				OutputIndex: input.OutputIndex,
+				Output:      output,
+				Height:      spend.height,
+			}, nil
+		}
+	}
+
+	referencedUtxo, err := t.utxoStore.GetUtxoEntryFromBlock(input.PrevTxID, input.OutputIndex, spend.blockHash)
 	if err != nil {
-		return transaction.Output{}, ErrUTXONotFound
+		return transaction.UTXO{}, ErrUTXONotFound
+	}
+	return referencedUtxo, nil
+}
+
+// validateRelativeLockTime checks that the referenced UTXO is at least as old as the relative lock time of the input.
+// Block based lock times compare heights. Time based lock times compare the median-time-past of the previous block
+// with the median-time-past of the block before the block that created the UTXO.
+func (t *TransactionValidator) validateRelativeLockTime(input transaction.Input, referencedUtxo transaction.UTXO, spend spendContext) error {
+	blocks, seconds := input.RelativeLockTime()
+
+	if blocks > 0 && spend.height < referencedUtxo.Height+blocks {
+		return ErrRelativeLockTime
 	}
 
-	return referencedOutput, nil
+	if seconds > 0 {
+		// The block before the creating block; outputs of the genesis block use the genesis block itself
+		baseHeight := max(referencedUtxo.Height, 1) - 1
+		baseMedianTimePast, err := t.utxoStore.GetAncestorMedianTimePast(spend.blockHash, baseHeight)
+		if err != nil {
+			return ErrUTXONotFound
+		}
+		if spend.medianTimePast < baseMedianTimePast+seconds {
+			return ErrRelativeLockTime
+		}
+	}
+
+	return nil
 }
 
 // validatePubKeyHash verifies that the input's public key hashes to the referenced output's public key hash.
 // This ensures that the spender actually owns the UTXO they are trying to spend.
+// For OutputTypeMultisigHash outputs the redeem script of the input has to be valid and hash to the referenced output's script hash instead.
+// OutputTypeMultisig outputs contain their script, so there is nothing to bind; the signatures are checked against the script.
 func (t *TransactionValidator) validatePubKeyHash(input transaction.Input, referencedOutput transaction.Output) error {
+	switch referencedOutput.Type {
+	case transaction.OutputTypeMultisig:
+		return nil
+	case transaction.OutputTypeMultisigHash:
+		if input.RedeemScript.Validate() != nil || input.RedeemScript.Hash() != referencedOutput.PubKeyHash {
+			return ErrPubKeyHashMismatch
+		}
+		return nil
+	}
+
 	computedPubKeyHash := transaction.Hash160(input.PubKey)
 	if computedPubKeyHash != referencedOutput.PubKeyHash {
 		return ErrPubKeyHashMismatch
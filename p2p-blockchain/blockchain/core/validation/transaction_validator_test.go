@@ -2,11 +2,14 @@ package validation
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+
+	"github.com/btcsuite/btcd/btcec/v2"
 )
 
 // MockUtxoStore is a mock implementation of utxo.UtxoStoreAPI for testing.
@@ -17,6 +20,12 @@ type MockUtxoStore struct {
 	validateResult bool
 	// getUtxoError if set, GetUtxoFromBlock will return this error
 	getUtxoError error
+	// blockHeight is returned by GetBlockHeight
+	blockHeight uint64
+	// coinbaseHeights maps outpoint keys of coinbase outputs to their creation height
+	coinbaseHeights map[string]uint64
+	// medianTimePasts maps block heights to the median-time-past returned by GetAncestorMedianTimePast
+	medianTimePasts map[uint64]int64
 }
 
 func (m *MockUtxoStore) ValidateTransactionsOfBlock(_ block.Block) bool {
@@ -38,6 +47,16 @@ func (m *MockUtxoStore) AddNewBlock(_ block.Block) error {
 	return nil
 }
 
+// DisconnectBlock is a mock implementation.
+func (m *MockUtxoStore) DisconnectBlock(_ block.Block) error {
+	return nil
+}
+
+// GetChainTip is a mock implementation.
+func (m *MockUtxoStore) GetChainTip() common.Hash {
+	return common.Hash{}
+}
+
 // GetUtxoFromBlock returns a mock UTXO based on the stored utxos map.
 func (m *MockUtxoStore) GetUtxoFromBlock(prevTxID transaction.TransactionID, outputIndex uint32, _ common.Hash) (transaction.Output, error) {
 	if m.getUtxoError != nil {
@@ -50,6 +69,17 @@ func (m *MockUtxoStore) GetUtxoFromBlock(prevTxID transaction.TransactionID, out
 	return transaction.Output{}, errors.New("UTXO not found")
 }
 
+// GetUtxoEntryFromBlock returns a mock UTXO based on the stored utxos map.
+// UTXOs listed in coinbaseHeights are coinbase outputs created at the given height.
+func (m *MockUtxoStore) GetUtxoEntryFromBlock(prevTxID transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.UTXO, error) {
+	output, err := m.GetUtxoFromBlock(prevTxID, outputIndex, blockHash)
+	if err != nil {
+		return transaction.UTXO{}, err
+	}
+	height, isCoinbase := m.coinbaseHeights[makeOutpointKey(prevTxID, outputIndex)]
+	return transaction.UTXO{TxID: prevTxID, OutputIndex: outputIndex, Output: output, Height: height, IsCoinbase: isCoinbase}, nil
+}
+
 // ValidateTransactionFromBlock returns the configured validateResult.
 func (m *MockUtxoStore) ValidateTransactionFromBlock(_ transaction.Transaction, _ common.Hash) bool {
 	return m.validateResult
@@ -60,6 +90,16 @@ func (m *MockUtxoStore) GetUtxosByPubKeyHashFromBlock(_ transaction.PubKeyHash,
 	return nil, nil
 }
 
+// GetBlockHeight returns the configured blockHeight.
+func (m *MockUtxoStore) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return m.blockHeight, nil
+}
+
+// GetAncestorMedianTimePast returns the configured median-time-past of the given height (0 if not configured).
+func (m *MockUtxoStore) GetAncestorMedianTimePast(_ common.Hash, height uint64) (int64, error) {
+	return m.medianTimePasts[height], nil
+}
+
 // createTestPubKeyAndHash creates a test public key and its corresponding hash.
 func createTestPubKeyAndHash() (transaction.PubKey, transaction.PubKeyHash) {
 	var pubKey transaction.PubKey
@@ -70,6 +110,16 @@ func createTestPubKeyAndHash() (transaction.PubKey, transaction.PubKeyHash) {
 	return pubKey, pubKeyHash
 }
 
+// createTestMultisigScript creates a valid 2-of-2 multisig script.
+func createTestMultisigScript() transaction.MultisigScript {
+	pubKeys := make([]transaction.PubKey, 2)
+	for i := range pubKeys {
+		_, pubKey := btcec.PrivKeyFromBytes([]byte{byte(i + 1)})
+		copy(pubKeys[i][:], pubKey.SerializeCompressed())
+	}
+	return transaction.MultisigScript{Required: 2, PubKeys: pubKeys}
+}
+
 // createTestTransactionID creates a test transaction ID.
 func createTestTransactionID(seed byte) transaction.TransactionID {
 	var txID transaction.TransactionID
@@ -351,6 +401,147 @@ func TestValidateTransaction_Success(t *testing.T) {
 	}
 }
 
+// TestValidateTransaction_CoinbaseMaturity tests that coinbase outputs can only be spent after CoinbaseMaturity blocks.
+func TestValidateTransaction_CoinbaseMaturity(t *testing.T) {
+	pubKey, pubKeyHash := createTestPubKeyAndHash()
+	txID := createTestTransactionID(1)
+
+	mockStore := &MockUtxoStore{
+		utxos: map[string]transaction.Output{
+			makeOutpointKey(txID, 0): {
+				Value:      50,
+				PubKeyHash: pubKeyHash,
+			},
+		},
+		coinbaseHeights: map[string]uint64{
+			makeOutpointKey(txID, 0): 5,
+		},
+		validateResult: true,
+	}
+	validator := NewTransactionValidator(mockStore)
+
+	tx := transaction.Transaction{
+		Inputs: []transaction.Input{
+			{
+				PrevTxID:    txID,
+				OutputIndex: 0,
+				PubKey:      pubKey,
+			},
+		},
+		Outputs: []transaction.Output{
+			{Value: 40, PubKeyHash: transaction.PubKeyHash{}},
+		},
+	}
+
+	// The spending block would be at height 14, one block too early
+	mockStore.blockHeight = 5 + transaction.CoinbaseMaturity - 2
+	valid, err := validator.ValidateTransaction(tx, common.Hash{})
+	if !errors.Is(err, ErrImmatureCoinbase) {
+		t.Errorf("Expected ErrImmatureCoinbase, got: %v", err)
+	}
+	if valid {
+		t.Error("Transaction spending an immature coinbase output should be invalid")
+	}
+
+	// The spending block would be at height 15, the coinbase output is mature
+	mockStore.blockHeight = 5 + transaction.CoinbaseMaturity - 1
+	valid, err = validator.ValidateTransaction(tx, common.Hash{})
+	if err != nil {
+		t.Errorf("Transaction spending a mature coinbase output returned error: %v", err)
+	}
+	if !valid {
+		t.Error("Transaction spending a mature coinbase output should be valid")
+	}
+}
+
+// TestValidateTransaction_LockTime tests that transactions can only be included after their absolute lock time.
+func TestValidateTransaction_LockTime(t *testing.T) {
+	pubKey, pubKeyHash := createTestPubKeyAndHash()
+	txID := createTestTransactionID(1)
+
+	mockStore := &MockUtxoStore{
+		utxos: map[string]transaction.Output{
+			makeOutpointKey(txID, 0): {Value: 50, PubKeyHash: pubKeyHash},
+		},
+		blockHeight:     9,
+		medianTimePasts: map[uint64]int64{9: transaction.LockTimeThreshold + 1000},
+		validateResult:  true,
+	}
+	validator := NewTransactionValidator(mockStore)
+
+	tests := []struct {
+		name     string
+		lockTime uint32
+		valid    bool
+	}{
+		{name: "height reached", lockTime: 9, valid: true},
+		{name: "height not reached", lockTime: 10, valid: false},
+		{name: "time reached", lockTime: transaction.LockTimeThreshold + 999, valid: true},
+		{name: "time not reached", lockTime: transaction.LockTimeThreshold + 1000, valid: false},
+	}
+
+	for _, tt := range tests {
+		tx := transaction.Transaction{
+			Inputs:   []transaction.Input{{PrevTxID: txID, OutputIndex: 0, PubKey: pubKey}},
+			Outputs:  []transaction.Output{{Value: 40, PubKeyHash: transaction.PubKeyHash{}}},
+			LockTime: tt.lockTime,
+		}
+
+		valid, err := validator.ValidateTransaction(tx, common.Hash{})
+		if valid != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v (%v)", tt.name, tt.valid, valid, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrLockTimeNotReached) {
+			t.Errorf("%s: expected ErrLockTimeNotReached, got: %v", tt.name, err)
+		}
+	}
+}
+
+// TestValidateTransaction_RelativeLockTime tests that inputs can only spend outputs older than their relative lock time.
+func TestValidateTransaction_RelativeLockTime(t *testing.T) {
+	pubKey, pubKeyHash := createTestPubKeyAndHash()
+	txID := createTestTransactionID(1)
+
+	// The referenced output is a mature coinbase output of the genesis block, the spending block has height 10
+	mockStore := &MockUtxoStore{
+		utxos: map[string]transaction.Output{
+			makeOutpointKey(txID, 0): {Value: 50, PubKeyHash: pubKeyHash},
+		},
+		coinbaseHeights: map[string]uint64{makeOutpointKey(txID, 0): 0},
+		blockHeight:     9,
+		medianTimePasts: map[uint64]int64{0: 1000, 9: 1000 + 3*512},
+		validateResult:  true,
+	}
+	validator := NewTransactionValidator(mockStore)
+
+	tests := []struct {
+		name     string
+		sequence uint32
+		valid    bool
+	}{
+		{name: "blocks reached", sequence: 10, valid: true},
+		{name: "blocks not reached", sequence: 11, valid: false},
+		{name: "time reached", sequence: transaction.SequenceLockTimeTypeFlag | 3, valid: true},
+		{name: "time not reached", sequence: transaction.SequenceLockTimeTypeFlag | 4, valid: false},
+		{name: "disabled", sequence: transaction.SequenceLockTimeDisableFlag | 100, valid: true},
+	}
+
+	for _, tt := range tests {
+		tx := transaction.Transaction{
+			Inputs:  []transaction.Input{{PrevTxID: txID, OutputIndex: 0, PubKey: pubKey, Sequence: tt.sequence}},
+			Outputs: []transaction.Output{{Value: 40, PubKeyHash: transaction.PubKeyHash{}}},
+		}
+
+		valid, err := validator.ValidateTransaction(tx, common.Hash{})
+		if valid != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v (%v)", tt.name, tt.valid, valid, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrRelativeLockTime) {
+			t.Errorf("%s: expected ErrRelativeLockTime, got: %v", tt.name, err)
+		}
+	}
+}
+
 // TestValidateTransaction_ExactInputsMatchOutputs tests a transaction where inputs exactly match outputs.
 func TestValidateTransaction_ExactInputsMatchOutputs(t *testing.T) {
 	pubKey, pubKeyHash := createTestPubKeyAndHash()
@@ -439,6 +630,222 @@ func TestValidateTransaction_MultipleInputsAndOutputs(t *testing.T) {
 	}
 }
 
+// TestTransactionValidator_GetTransactionFee tests that the fee is the difference between inputs and outputs.
+func TestTransactionValidator_GetTransactionFee(t *testing.T) {
+	txID1 := createTestTransactionID(1)
+	txID2 := createTestTransactionID(2)
+
+	mockStore := &MockUtxoStore{
+		utxos: map[string]transaction.Output{
+			makeOutpointKey(txID1, 0): {Value: 50},
+			makeOutpointKey(txID2, 0): {Value: 60},
+		},
+	}
+	validator := NewTransactionValidator(mockStore)
+
+	unconfirmedTx := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: createTestTransactionID(3)}},
+		Outputs: []transaction.Output{{Value: 30}},
+	}
+	overlay := newBlockOverlay()
+	overlay.add(unconfirmedTx)
+
+	tests := []struct {
+		name        string
+		tx          transaction.Transaction
+		expectedFee uint64
+		expectedErr error
+	}{
+		{
+			name: "inputs greater than outputs",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: txID1}, {PrevTxID: txID2}},
+				Outputs: []transaction.Output{{Value: 40}, {Value: 50}},
+			},
+			expectedFee: 20,
+		},
+		{
+			name: "referenced output does not exist",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: txID1, OutputIndex: 1}},
+				Outputs: []transaction.Output{{Value: 40}},
+			},
+			expectedErr: ErrUTXONotFound,
+		},
+		{
+			name: "inputs less than outputs",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: txID1}},
+				Outputs: []transaction.Output{{Value: 51}},
+			},
+			expectedErr: ErrInsufficientInputs,
+		},
+		{
+			name: "input from overlay",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: txID1}, {PrevTxID: unconfirmedTx.TransactionId()}},
+				Outputs: []transaction.Output{{Value: 70}},
+			},
+			expectedFee: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee, err := validator.GetTransactionFee(tt.tx, common.Hash{}, overlay)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("GetTransactionFee() error = %v, want %v", err, tt.expectedErr)
+			}
+			if fee != tt.expectedFee {
+				t.Errorf("GetTransactionFee() fee = %d, want %d", fee, tt.expectedFee)
+			}
+		})
+	}
+}
+
+func TestTransactionValidator_VerifySignatures(t *testing.T) {
+	var privateKey transaction.PrivateKey
+	privateKey[31] = 1
+	_, publicKey := btcec.PrivKeyFromBytes(privateKey[:])
+	var pubKey transaction.PubKey
+	copy(pubKey[:], publicKey.SerializeCompressed())
+
+	utxo := transaction.UTXO{
+		TxID:   createTestTransactionID(1),
+		Output: transaction.Output{Value: 100, PubKeyHash: transaction.Hash160(pubKey)},
+	}
+	validator := NewTransactionValidator(&MockUtxoStore{
+		utxos: map[string]transaction.Output{makeOutpointKey(utxo.TxID, 0): utxo.Output},
+	})
+
+	tx := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: utxo.TxID}},
+		Outputs: []transaction.Output{{Value: 90, PubKeyHash: utxo.Output.PubKeyHash}},
+	}
+	if err := validator.VerifySignatures(tx, common.Hash{}, nil); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected unsigned transaction to fail with ErrInvalidSignature, got %v", err)
+	}
+
+	if err := tx.Sign(privateKey, []transaction.UTXO{utxo}); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := validator.VerifySignatures(tx, common.Hash{}, nil); err != nil {
+		t.Fatalf("expected signed transaction to be valid, got %v", err)
+	}
+
+	tx.Outputs[0].Value = 80
+	if err := validator.VerifySignatures(tx, common.Hash{}, nil); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected modified transaction to fail with ErrInvalidSignature, got %v", err)
+	}
+
+	missing := transaction.Transaction{Inputs: []transaction.Input{{PrevTxID: createTestTransactionID(2)}}}
+	if err := validator.VerifySignatures(missing, common.Hash{}, nil); !errors.Is(err, ErrUTXONotFound) {
+		t.Fatalf("expected ErrUTXONotFound for a missing referenced output, got %v", err)
+	}
+}
+
+// TestValidateTransactionWithOverlay_SpendsUnconfirmedOutput tests that an output created by the overlay can be spent.
+func TestValidateTransactionWithOverlay_SpendsUnconfirmedOutput(t *testing.T) {
+	pubKey, pubKeyHash := createTestPubKeyAndHash()
+	confirmedTxID := createTestTransactionID(1)
+
+	mockStore := &MockUtxoStore{
+		utxos: map[string]transaction.Output{
+			makeOutpointKey(confirmedTxID, 0): {Value: 100, PubKeyHash: pubKeyHash},
+		},
+		blockHeight: 4,
+	}
+	validator := NewTransactionValidator(mockStore)
+
+	parent := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: confirmedTxID, PubKey: pubKey}},
+		Outputs: []transaction.Output{{Value: 90, PubKeyHash: pubKeyHash}},
+	}
+	child := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: parent.TransactionId(), PubKey: pubKey}},
+		Outputs: []transaction.Output{{Value: 80, PubKeyHash: transaction.PubKeyHash{}}},
+	}
+
+	valid, err := validator.ValidateTransaction(child, common.Hash{})
+	if !errors.Is(err, ErrUTXONotFound) || valid {
+		t.Errorf("Expected ErrUTXONotFound without overlay, got: %v", err)
+	}
+
+	overlay := newBlockOverlay()
+	overlay.add(parent)
+
+	valid, err = validator.ValidateTransactionWithOverlay(child, common.Hash{}, overlay)
+	if err != nil {
+		t.Errorf("Spending an output of the overlay returned error: %v", err)
+	}
+	if !valid {
+		t.Error("Spending an output of the overlay should be valid")
+	}
+}
+
+// TestValidateTransactionWithOverlay_OutputSpentByOverlay tests that an output spent by the overlay cannot be spent again.
+func TestValidateTransactionWithOverlay_OutputSpentByOverlay(t *testing.T) {
+	pubKey, pubKeyHash := createTestPubKeyAndHash()
+	confirmedTxID := createTestTransactionID(1)
+
+	mockStore := &MockUtxoStore{
+		utxos: map[string]transaction.Output{
+			makeOutpointKey(confirmedTxID, 0): {Value: 100, PubKeyHash: pubKeyHash},
+		},
+		blockHeight: 4,
+	}
+	validator := NewTransactionValidator(mockStore)
+
+	spending := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: confirmedTxID, PubKey: pubKey}},
+		Outputs: []transaction.Output{{Value: 90, PubKeyHash: transaction.PubKeyHash{1}}},
+	}
+	conflicting := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: confirmedTxID, PubKey: pubKey}},
+		Outputs: []transaction.Output{{Value: 90, PubKeyHash: transaction.PubKeyHash{2}}},
+	}
+
+	overlay := newBlockOverlay()
+	overlay.add(spending)
+
+	valid, err := validator.ValidateTransactionWithOverlay(conflicting, common.Hash{}, overlay)
+	if !errors.Is(err, ErrOutputAlreadySpent) {
+		t.Errorf("Expected ErrOutputAlreadySpent, got: %v", err)
+	}
+	if valid {
+		t.Error("Spending an output spent by the overlay should be invalid")
+	}
+}
+
+// TestValidateTransactionWithOverlay_RelativeLockTimeOfUnconfirmedOutput tests that outputs of the overlay
+// count as created in the next block, so they cannot satisfy a relative lock time.
+func TestValidateTransactionWithOverlay_RelativeLockTimeOfUnconfirmedOutput(t *testing.T) {
+	pubKey, pubKeyHash := createTestPubKeyAndHash()
+
+	mockStore := &MockUtxoStore{utxos: map[string]transaction.Output{}, blockHeight: 4}
+	validator := NewTransactionValidator(mockStore)
+
+	parent := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: createTestTransactionID(1), PubKey: pubKey}},
+		Outputs: []transaction.Output{{Value: 90, PubKeyHash: pubKeyHash}},
+	}
+	overlay := newBlockOverlay()
+	overlay.add(parent)
+
+	child := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: parent.TransactionId(), PubKey: pubKey, Sequence: 1}},
+		Outputs: []transaction.Output{{Value: 80, PubKeyHash: transaction.PubKeyHash{}}},
+	}
+
+	valid, err := validator.ValidateTransactionWithOverlay(child, common.Hash{}, overlay)
+	if !errors.Is(err, ErrRelativeLockTime) {
+		t.Errorf("Expected ErrRelativeLockTime, got: %v", err)
+	}
+	if valid {
+		t.Error("Unconfirmed output should not satisfy a relative lock time")
+	}
+}
+
 // TestTransactionValidator_CreateOutpointKey tests that the outpoint key creation is consistent.
 func TestTransactionValidator_CreateOutpointKey(t *testing.T) {
 	mockStore := &MockUtxoStore{}
@@ -574,6 +981,54 @@ func TestTransactionValidator_ValidateBasicStructure(t *testing.T) {
 			},
 			expectedErr: ErrNoOutputs,
 		},
+		{
+			name: "valid multisig outputs",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: transaction.TransactionID{1}}},
+				Outputs: []transaction.Output{transaction.NewMultisigOutput(100, createTestMultisigScript()), transaction.NewMultisigHashOutput(100, transaction.PubKeyHash{1})},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "multisig output requires more signatures than keys",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: transaction.TransactionID{1}}},
+				Outputs: []transaction.Output{transaction.NewMultisigOutput(100, transaction.MultisigScript{Required: 3, PubKeys: createTestMultisigScript().PubKeys})},
+			},
+			expectedErr: ErrInvalidOutput,
+		},
+		{
+			name: "unknown output type",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: transaction.TransactionID{1}}},
+				Outputs: []transaction.Output{{Value: 100, Type: 4}},
+			},
+			expectedErr: ErrInvalidOutput,
+		},
+		{
+			name: "valid data output",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: transaction.TransactionID{1}}},
+				Outputs: []transaction.Output{transaction.NewDataOutput(make([]byte, transaction.MaxDataSize)), {Value: 100}},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "data output exceeds maximum data size",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: transaction.TransactionID{1}}},
+				Outputs: []transaction.Output{transaction.NewDataOutput(make([]byte, transaction.MaxDataSize+1))},
+			},
+			expectedErr: ErrInvalidDataOutput,
+		},
+		{
+			name: "data output with value",
+			tx: transaction.Transaction{
+				Inputs:  []transaction.Input{{PrevTxID: transaction.TransactionID{1}}},
+				Outputs: []transaction.Output{{Value: 100, Type: transaction.OutputTypeData, Data: []byte{1}}},
+			},
+			expectedErr: ErrInvalidDataOutput,
+		},
 	}
 
 	for _, tt := range tests {
@@ -639,6 +1094,7 @@ func TestTransactionValidator_ValidatePubKeyHash(t *testing.T) {
 	validator := &TransactionValidator{utxoStore: mockStore}
 
 	pubKey, pubKeyHash := createTestPubKeyAndHash()
+	script := createTestMultisigScript()
 
 	tests := []struct {
 		name        string
@@ -646,6 +1102,30 @@ func TestTransactionValidator_ValidatePubKeyHash(t *testing.T) {
 		output      transaction.Output
 		expectedErr error
 	}{
+		{
+			name:        "bare multisig output",
+			input:       transaction.Input{},
+			output:      transaction.NewMultisigOutput(100, script),
+			expectedErr: nil,
+		},
+		{
+			name:        "matching redeem script",
+			input:       transaction.Input{RedeemScript: script},
+			output:      transaction.NewMultisigHashOutput(100, script.Hash()),
+			expectedErr: nil,
+		},
+		{
+			name:        "mismatched redeem script",
+			input:       transaction.Input{RedeemScript: transaction.MultisigScript{Required: 1, PubKeys: script.PubKeys}},
+			output:      transaction.NewMultisigHashOutput(100, script.Hash()),
+			expectedErr: ErrPubKeyHashMismatch,
+		},
+		{
+			name:        "missing redeem script",
+			input:       transaction.Input{PubKey: pubKey},
+			output:      transaction.NewMultisigHashOutput(100, pubKeyHash),
+			expectedErr: ErrPubKeyHashMismatch,
+		},
 		{
 			name:        "matching public key hash",
 			input:       transaction.Input{PubKey: pubKey},
@@ -672,3 +1152,30 @@ func TestTransactionValidator_ValidatePubKeyHash(t *testing.T) {
 		})
 	}
 }
+
+func TestIsInvalidTransactionAndIsLookupError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantInvalid   bool
+		wantLookupErr bool
+	}{
+		{name: "invalid signature", err: fmt.Errorf("%w: input 0", ErrInvalidSignature), wantInvalid: true},
+		{name: "insufficient inputs", err: ErrInsufficientInputs, wantInvalid: true},
+		{name: "duplicate input", err: ErrDuplicateInput, wantInvalid: true},
+		{name: "missing input", err: fmt.Errorf("%w: input 0", ErrUTXONotFound), wantLookupErr: true},
+		{name: "unknown previous block", err: fmt.Errorf("%w: height of block unknown", ErrChainStateUnavailable), wantLookupErr: true},
+		{name: "policy", err: errors.New("fee rate below minimum"), wantInvalid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsInvalidTransaction(tt.err); got != tt.wantInvalid {
+				t.Errorf("IsInvalidTransaction() = %v, want %v", got, tt.wantInvalid)
+			}
+			if got := IsLookupError(tt.err); got != tt.wantLookupErr {
+				t.Errorf("IsLookupError() = %v, want %v", got, tt.wantLookupErr)
+			}
+		})
+	}
+}
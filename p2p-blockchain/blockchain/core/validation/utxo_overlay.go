@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+)
+
+// UtxoOverlay describes unconfirmed transactions on top of the UTXO set of a block,
+// e.g. the transactions of the mempool or the preceding transactions of the same block.
+type UtxoOverlay interface {
+	// GetOutput returns the spendable output at the given outpoint if it was created by one of the unconfirmed transactions.
+	GetOutput(txID transaction.TransactionID, outputIndex uint32) (transaction.Output, bool)
+	// IsSpent reports whether the output at the given outpoint is spent by one of the unconfirmed transactions.
+	IsSpent(txID transaction.TransactionID, outputIndex uint32) bool
+}
+
+// outpoint identifies an output by the ID of its transaction and its index.
+type outpoint struct {
+	txID  transaction.TransactionID
+	index uint32
+}
+
+// blockOverlay is the UtxoOverlay of the transactions of a block that were already validated.
+// It allows a transaction to spend the outputs of a preceding transaction of the same block.
+type blockOverlay struct {
+	created map[outpoint]transaction.Output
+	spent   map[outpoint]struct{}
+}
+
+func newBlockOverlay() *blockOverlay {
+	return &blockOverlay{
+		created: make(map[outpoint]transaction.Output),
+		spent:   make(map[outpoint]struct{}),
+	}
+}
+
+// add applies a validated transaction to the overlay.
+func (o *blockOverlay) add(tx transaction.Transaction) {
+	for _, input := range tx.Inputs {
+		o.spent[outpoint{txID: input.PrevTxID, index: input.OutputIndex}] = struct{}{}
+	}
+
+	txID := tx.TransactionId()
+	for i, output := range tx.Outputs {
+		if output.IsUnspendable() {
+			continue
+		}
+		o.created[outpoint{txID: txID, index: uint32(i)}] = output
+	}
+}
+
+func (o *blockOverlay) GetOutput(txID transaction.TransactionID, outputIndex uint32) (transaction.Output, bool) {
+	output, ok := o.created[outpoint{txID: txID, index: outputIndex}]
+	return output, ok
+}
+
+func (o *blockOverlay) IsSpent(txID transaction.TransactionID, outputIndex uint32) bool {
+	_, ok := o.spent[outpoint{txID: txID, index: outputIndex}]
+	return ok
+}
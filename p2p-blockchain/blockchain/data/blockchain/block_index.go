@@ -0,0 +1,192 @@
+package blockchain
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/storage"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"slices"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// indexEntrySize is the size of an encoded index entry:
+// header + offset (8) + height (8) + accumulated work (8) + invalid flag (1).
+const indexEntrySize = block.HeaderSize + 8 + 8 + 8 + 1
+
+var errMalformedIndexEntry = errors.New("malformed index entry")
+
+// indexEntry describes a stored block, so the block forest can be rebuilt without reading the block file.
+//
+// An entry is appended to the index file when a block is stored and whenever its position in the forest changes,
+// i.e. when a stored orphan is connected. The last entry of a block is the valid one.
+type indexEntry struct {
+	header block.BlockHeader
+	// offset is the position of the block record in the block file.
+	offset          int64
+	height          uint64
+	accumulatedWork uint64
+	isInvalid       bool
+}
+
+// indexRecord returns the record of the index entry of the given stored node.
+func indexRecord(node *blockNode) storage.Record {
+	payload := make([]byte, 0, indexEntrySize)
+	payload = append(payload, node.Header.Serialize()...)
+	payload = binary.LittleEndian.AppendUint64(payload, uint64(node.offset))
+	payload = binary.LittleEndian.AppendUint64(payload, node.Height)
+	payload = binary.LittleEndian.AppendUint64(payload, node.AccumulatedWork)
+	if node.IsInvalid {
+		payload = append(payload, 1)
+	} else {
+		payload = append(payload, 0)
+	}
+	return storage.Record{Kind: recordKindIndex, Payload: payload}
+}
+
+// decodeIndexEntry decodes the payload of an index record.
+func decodeIndexEntry(payload []byte) (indexEntry, error) {
+	if len(payload) != indexEntrySize {
+		return indexEntry{}, errMalformedIndexEntry
+	}
+
+	header, err := block.DeserializeBlockHeader(payload[:block.HeaderSize])
+	if err != nil {
+		return indexEntry{}, err
+	}
+
+	rest := payload[block.HeaderSize:]
+	return indexEntry{
+		header:          header,
+		offset:          int64(binary.LittleEndian.Uint64(rest[0:8])),
+		height:          binary.LittleEndian.Uint64(rest[8:16]),
+		accumulatedWork: binary.LittleEndian.Uint64(rest[16:24]),
+		isInvalid:       rest[24] != 0,
+	}, nil
+}
+
+// restore rebuilds the block forest from the records of the index file.
+//
+// Blocks are written to the block file before their index entry, so a crash can only leave blocks without an entry at the end of the block file.
+// These blocks are cut off and requested again from peers.
+// Entries whose block is missing in the block file are dropped, blocks that were connected to a dropped block become orphans again.
+func (s *BlockStore) restore(records []storage.Record) error {
+	entries := make(map[common.Hash]indexEntry)
+	for _, record := range records {
+		if record.Kind != recordKindIndex {
+			logger.Warnf("[block_store] Skipping index record of unknown kind %d", record.Kind)
+			continue
+		}
+		entry, err := decodeIndexEntry(record.Payload)
+		if err != nil {
+			logger.Warnf("[block_store] Skipping malformed index record: %v", err)
+			continue
+		}
+		entries[entry.header.Hash()] = entry
+	}
+
+	sorted := slices.SortedFunc(maps.Values(entries), func(a, b indexEntry) int {
+		return cmp.Compare(a.offset, b.offset)
+	})
+
+	sorted, end := s.dropMissingBlocks(sorted)
+	if err := s.file.Truncate(end); err != nil {
+		return fmt.Errorf("failed to cut off unindexed blocks of the block file: %w", err)
+	}
+
+	for _, entry := range sorted {
+		hash := entry.header.Hash()
+		if _, exists := s.hashToHeaders[hash]; exists {
+			// The genesis block is never stored, but guard against a corrupt index anyway
+			continue
+		}
+		s.hashToHeaders[hash] = &blockNode{
+			AccumulatedWork: entry.accumulatedWork,
+			Height:          entry.height,
+			Header:          entry.header,
+			Hash:            hash,
+			offset:          entry.offset,
+			Children:        []*blockNode{},
+			IsInvalid:       entry.isInvalid,
+		}
+	}
+
+	s.linkRestoredNodes(sorted)
+	return nil
+}
+
+// dropMissingBlocks drops the entries at the end of the block file whose block can't be read.
+// Returns the remaining entries and the end of the last stored block.
+func (s *BlockStore) dropMissingBlocks(sorted []indexEntry) ([]indexEntry, int64) {
+	for len(sorted) > 0 {
+		last := sorted[len(sorted)-1]
+		_, size, err := s.file.ReadAt(last.offset)
+		if err == nil {
+			return sorted, last.offset + size
+		}
+
+		logger.Warnf("[block_store] Dropping block %v, it is missing in the block file: %v", last.header.Hash(), err)
+		sorted = sorted[:len(sorted)-1]
+	}
+	return sorted, 0
+}
+
+// linkRestoredNodes links the restored nodes to their parents and rebuilds the roots and leaves of the block forest.
+// The stored height and accumulated work of each node are checked against its parent,
+// a mismatch means the parent was dropped and the stored values are recalculated.
+func (s *BlockStore) linkRestoredNodes(sorted []indexEntry) {
+	genesis := s.blockForest.Roots[0]
+
+	for _, entry := range sorted {
+		node := s.hashToHeaders[entry.header.Hash()]
+		if parent, exists := s.hashToHeaders[node.Header.PreviousBlockHash]; exists {
+			node.Parent = parent
+			parent.Children = append(parent.Children, node)
+		} else {
+			s.blockForest.Roots = append(s.blockForest.Roots, node)
+		}
+	}
+
+	s.blockForest.Leaves = []*blockNode{}
+	for _, root := range s.blockForest.Roots {
+		s.restoreSubtree(root, root == genesis)
+	}
+}
+
+// restoreSubtree checks the stored values of the nodes below the given root and collects the leaves.
+func (s *BlockStore) restoreSubtree(root *blockNode, isGenesis bool) {
+	if !isGenesis && (root.Height != 0 || root.AccumulatedWork != 0) {
+		logger.Warnf("[block_store] Block %v lost its parent and is an orphan again", root.Hash)
+		root.Height = 0
+		root.AccumulatedWork = 0
+	}
+	if isGenesis && len(root.Children) == 0 {
+		s.blockForest.Leaves = append(s.blockForest.Leaves, root)
+	}
+
+	stack := slices.Clone(root.Children)
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		height := node.Parent.Height + 1
+		accumulatedWork := node.Parent.AccumulatedWork + blockWork(node.Header)
+		if node.Height != height || node.AccumulatedWork != accumulatedWork {
+			logger.Warnf("[block_store] Recalculating height and work of block %v", node.Hash)
+			node.Height = height
+			node.AccumulatedWork = accumulatedWork
+		}
+		if node.Parent.IsInvalid {
+			node.IsInvalid = true
+		}
+
+		if len(node.Children) == 0 {
+			s.blockForest.Leaves = append(s.blockForest.Leaves, node)
+		}
+		stack = append(stack, node.Children...)
+	}
+}
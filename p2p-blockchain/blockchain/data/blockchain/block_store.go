@@ -2,7 +2,10 @@
 package blockchain
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"s3b/vsp-blockchain/p2p-blockchain/blockchain/data/storage"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
@@ -14,11 +17,14 @@ import (
 	mapset "github.com/deckarep/golang-set/v2"
 )
 
-// BlockFullValidator is the interface for full block validation.
+// BlockContextValidator is the interface for the validation of a block against its previous blocks.
 // Defined here to avoid circular dependency with the validation package.
-type BlockFullValidator interface {
-	// FullValidation performs comprehensive validation including transactions and UTXO set.
-	FullValidation(block block.Block) (bool, error)
+// Full validation needs the UTXO set of the previous block and is done when the block is connected to the UTXO set.
+type BlockContextValidator interface {
+	// ValidateDifficultyTarget checks the difficulty target of the header against the target required by the retarget rule.
+	ValidateDifficultyTarget(header block.BlockHeader, requiredTarget uint8) (bool, error)
+	// ValidateTimestamp checks that the timestamp of the header is later than the median-time-past of its previous blocks.
+	ValidateTimestamp(header block.BlockHeader, medianTimePast int64) (bool, error)
 }
 
 type BlockStoreAPI interface {
@@ -63,11 +69,16 @@ type BlockStoreAPI interface {
 	GetCurrentHeight() uint64
 
 	// GetMainChainHeight returns the height of the main chain tip.
-	// The main chain is defined as the chain with the highest accumulated work.
+	// The main chain is defined as the chain of valid blocks with the highest accumulated work.
 	GetMainChainHeight() uint64
 
+	// GetBlockHeight returns the height of the block with the given hash, also for blocks of side chains.
+	// Returns an error if the block is not found or is an orphan, as its height is unknown then.
+	// O(1) time complexity.
+	GetBlockHeight(hash common.Hash) (uint64, error)
+
 	// GetMainChainTip returns the tip block of the main chain.
-	// The main chain is defined as the chain with the highest accumulated work.
+	// The main chain is defined as the chain of valid blocks with the highest accumulated work.
 	// In case of multiple chains with the same accumulated work, one of them is returned arbitrarily(!).
 	GetMainChainTip() block.Block
 
@@ -79,18 +90,34 @@ type BlockStoreAPI interface {
 	// Returns an error if the block is not found in the block store.
 	IsBlockInvalid(block block.Block) (bool, error)
 
+	// MarkBlockInvalid marks the block with the given hash and all its descendants as invalid, e.g. after the block failed full validation.
+	// Invalid blocks never become part of the main chain.
+	// Returns an error if the block is not found in the block store or the mark can't be persisted.
+	MarkBlockInvalid(hash common.Hash) error
+
 	// GetBlockHeightDifferenceByTxId returns the height difference between the block identified by the given hash and the main chain tip.
 	// If the Block is not found on the MainChain -1 gets returned
 	GetBlockHeightDifferenceByTxId(txID transaction.TransactionID) (int, error)
 
 	// IsTransactionAccepted returns if the transaction is accepted in the network.
 	IsTransactionAccepted(txID transaction.TransactionID) (bool, error)
+
+	// GetNextDifficultyTarget returns the difficulty target required for a block whose previous block is the block with the given hash.
+	// Returns an error if the previous block is not found or is an orphan, as the retarget window is unknown then.
+	// O(RetargetInterval) time complexity.
+	GetNextDifficultyTarget(prevBlockHash common.Hash) (uint8, error)
+
+	// GetMedianTimePast returns the median timestamp of the block with the given hash and its MedianTimePastWindow-1 ancestors.
+	// A block whose previous block is the block with the given hash must have a timestamp later than this value.
+	// Returns an error if the block is not found or is an orphan, as its ancestors are unknown then.
+	// O(MedianTimePastWindow) time complexity.
+	GetMedianTimePast(prevBlockHash common.Hash) (int64, error)
 }
 
 // blockForest represents a collection of trees structures representing the blockchain.
 //
 // There is one main chain (starting with the genesis as root), potentially several side chains and orphans.
-//   - The main chain is the chain with the highest accumulated work. This also means that the main chain does not have to be the longest chain but typically is.
+//   - The main chain is the chain of valid blocks with the highest accumulated work. This also means that the main chain does not have to be the longest chain but typically is.
 //   - Side chains are chains that branch off from the main chain at some point.
 //   - Orphans are blocks that don't have the genesis block as an ancestor. Their highest ancestor is a root blockNode in the forest which is not the genesis block.
 type blockForest struct {
@@ -111,7 +138,14 @@ type blockNode struct {
 	// Height is the number of blocks from genesis to this header (genesis has height 0).
 	Height uint64
 
-	Block *block.Block
+	// Header is the header of the block. Kept in memory to walk the chain without reading the block.
+	Header block.BlockHeader
+	// Hash is the hash of the header.
+	Hash common.Hash
+	// block is the block itself if it is held in memory, nil if it is stored in the block file and read on demand.
+	block *block.Block
+	// offset is the position of the block in the block file. Only set if block is nil.
+	offset int64
 
 	Parent   *blockNode
 	Children []*blockNode
@@ -129,10 +163,27 @@ type BlockStore struct {
 	blockForest blockForest
 	// hashToHeaders provides fast lookup of blockNodes by their hash.
 	hashToHeaders map[common.Hash]*blockNode
-	// blockValidator validates blocks when they are connected to the chain.
-	blockValidator BlockFullValidator
+	// blockValidator validates blocks against their previous blocks when they are connected to the chain.
+	blockValidator BlockContextValidator
+	// file stores all added blocks. Nil if the block store is in-memory only.
+	file *storage.RecordFile
+	// indexFile stores the index entries of the stored blocks, see indexEntry. Nil if the block store is in-memory only.
+	indexFile *storage.RecordFile
 }
 
+const (
+	blockFileName      = "blocks.dat"
+	blockIndexFileName = "blocks.idx"
+)
+
+// Record kinds of the block file and the index file.
+const (
+	// recordKindBlock records of the block file contain a serialized block.
+	recordKindBlock uint8 = iota + 1
+	// recordKindIndex records of the index file contain an index entry.
+	recordKindIndex
+)
+
 func (s *BlockStore) IsTransactionAccepted(txID transaction.TransactionID) (bool, error) {
 
 	heightDifference, err := s.GetBlockHeightDifferenceByTxId(txID)
@@ -144,27 +195,20 @@ func (s *BlockStore) IsTransactionAccepted(txID transaction.TransactionID) (bool
 }
 
 func (s *BlockStore) GetBlockHeightDifferenceByTxId(txID transaction.TransactionID) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	// Get all blocks with metadata to identify main chain blocks
-	allBlocks := s.GetAllBlocksWithMetadata()
-
-	var blockWithTransaction block.BlockWithMetadata
-
-	for _, blockWithMeta := range allBlocks {
-		// Only index main chain transactions
-		if !blockWithMeta.IsMainChain {
-			continue
+	// Walk the main chain from the tip, recent transactions are found after reading only a few blocks
+	tip := s.getMainChainTipNode()
+	for node := tip; node != nil; node = node.Parent {
+		mainChainBlock, err := s.readBlock(node)
+		if err != nil {
+			return -1, err
 		}
 
-		for _, tx := range blockWithMeta.Block.Transactions {
-			txIDTemp := tx.TransactionId()
-			if txIDTemp == txID {
-				//Block with matching transaction found
-				blockWithTransaction = blockWithMeta
-
-				//calculate height difference
-				heightDifference := s.GetMainChainHeight() - blockWithTransaction.Height
-				return int(heightDifference), nil
+		for _, tx := range mainChainBlock.Transactions {
+			if tx.TransactionId() == txID {
+				return int(tip.Height - node.Height), nil
 			}
 		}
 	}
@@ -173,11 +217,13 @@ func (s *BlockStore) GetBlockHeightDifferenceByTxId(txID transaction.Transaction
 	return -1, nil
 }
 
-func NewBlockStore(genesis block.Block, blockValidator BlockFullValidator) *BlockStore {
+func NewBlockStore(genesis block.Block, blockValidator BlockContextValidator) *BlockStore {
 	genesisNode := blockNode{
-		AccumulatedWork: uint64(genesis.BlockDifficulty()),
+		AccumulatedWork: blockWork(genesis.Header),
 		Height:          0,
-		Block:           &genesis,
+		Header:          genesis.Header,
+		Hash:            genesis.Hash(),
+		block:           &genesis,
 		Parent:          nil,
 		Children:        []*blockNode{},
 	}
@@ -194,6 +240,51 @@ func NewBlockStore(genesis block.Block, blockValidator BlockFullValidator) *Bloc
 	}
 }
 
+// NewPersistentBlockStore creates a block store that persists all added blocks in the given data directory.
+// The block forest of a previous run is rebuilt from the index file without reading or re-validating the blocks,
+// invalid blocks stay invalid. Blocks are read from the block file on demand.
+// The returned block store must be closed with Close.
+func NewPersistentBlockStore(genesis block.Block, blockValidator BlockContextValidator, dataDir string) (*BlockStore, error) {
+	indexFile, records, err := storage.OpenRecordFile(filepath.Join(dataDir, blockIndexFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := storage.OpenRecordFileForAppend(filepath.Join(dataDir, blockFileName))
+	if err != nil {
+		_ = indexFile.Close()
+		return nil, err
+	}
+
+	store := NewBlockStore(genesis, blockValidator)
+	store.file = file
+	store.indexFile = indexFile
+	if err := store.restore(records); err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+
+	logger.Infof("[block_store] Loaded %d blocks from %s, main chain height %d", len(store.hashToHeaders)-1, dataDir, store.getMainChainTipNode().Height)
+
+	return store, nil
+}
+
+// Close closes the underlying files of a persistent block store.
+// Does nothing for an in-memory block store.
+func (s *BlockStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := errors.Join(s.file.Close(), s.indexFile.Close())
+	s.file = nil
+	s.indexFile = nil
+	return err
+}
+
 // AddBlock adds a new block to the block store.
 //
 // The block is linked to its parent based on the PreviousBlockHash field.
@@ -208,6 +299,56 @@ func (s *BlockStore) AddBlock(block block.Block) (addedBlockHashes []common.Hash
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	isNew := !s.contains(block.Hash())
+
+	addedBlockHashes = s.addBlock(block)
+
+	if isNew && s.file != nil {
+		s.persist(block.Hash(), addedBlockHashes)
+	}
+
+	return
+}
+
+// persist appends the block to the block file and afterward the index entries of the block and of all blocks connected because of it to the index file.
+// Once the block and its index entry are written it is dropped from memory and read from the block file on demand.
+// A failed write is logged, the block stays in memory and is requested again from peers after a restart.
+func (s *BlockStore) persist(hash common.Hash, connectedBlockHashes []common.Hash) {
+	node := s.hashToHeaders[hash]
+
+	offset, err := s.file.Size()
+	if err == nil {
+		err = s.file.Append(storage.Record{Kind: recordKindBlock, Payload: node.block.Serialize()})
+	}
+	if err != nil {
+		logger.Errorf("[block_store] Failed to persist block %v: %v", hash, err)
+		return
+	}
+	node.offset = offset
+
+	records := []storage.Record{indexRecord(node)}
+	for _, connectedHash := range connectedBlockHashes {
+		// Connected blocks that are still held in memory have no index entry yet
+		if connected := s.hashToHeaders[connectedHash]; connected != node && connected.block == nil {
+			records = append(records, indexRecord(connected))
+		}
+	}
+
+	if err := s.indexFile.Append(records...); err != nil {
+		logger.Errorf("[block_store] Failed to persist index of block %v: %v", hash, err)
+		return
+	}
+	// Only drop the block once its index entry is written, a block without an entry is cut off the block file on restart
+	node.block = nil
+}
+
+func (s *BlockStore) contains(hash common.Hash) bool {
+	_, exists := s.hashToHeaders[hash]
+	return exists
+}
+
+// addBlock is the internal implementation of AddBlock without locking and persistence.
+func (s *BlockStore) addBlock(block block.Block) (addedBlockHashes []common.Hash) {
 	blockHash := block.Hash()
 
 	addedBlockHashes = []common.Hash{}
@@ -220,15 +361,12 @@ func (s *BlockStore) AddBlock(block block.Block) (addedBlockHashes []common.Hash
 	// Find parent
 	parentHash := block.Header.PreviousBlockHash
 	parent, parentExists := s.hashToHeaders[parentHash]
-	isParentOrphan := false
-	if parentExists {
-		var err error
-		isParentOrphan, err = s.isOrphanBlock(*parent.Block)
-		assert.IsNil(err, "error checking if parent is orphan")
-	}
+	isParentOrphan := parentExists && isOrphanNode(parent)
 
 	newNode := blockNode{
-		Block:    &block,
+		Header:   block.Header,
+		Hash:     blockHash,
+		block:    &block,
 		Children: []*blockNode{},
 	}
 
@@ -257,7 +395,7 @@ func (s *BlockStore) connectOrphanBlock(newNode *blockNode) (addedBlockHashes []
 	// Collect orphans to connect first to avoid modifying slice during iteration (connectNodes modifies s.blockForest.Roots)
 	var orphansToConnect []*blockNode
 	for _, orphanRoot := range s.blockForest.Roots {
-		if orphanRoot.Block.Header.PreviousBlockHash == newNode.Block.Hash() {
+		if orphanRoot.Header.PreviousBlockHash == newNode.Hash {
 			orphansToConnect = append(orphansToConnect, orphanRoot)
 		}
 	}
@@ -265,7 +403,7 @@ func (s *BlockStore) connectOrphanBlock(newNode *blockNode) (addedBlockHashes []
 	for _, orphanRoot := range orphansToConnect {
 		// Connects (newNode) --> (orphanRoot)
 		s.connectNodes(newNode, orphanRoot)
-		addedBlockHashes = append(addedBlockHashes, orphanRoot.Block.Hash())
+		addedBlockHashes = append(addedBlockHashes, orphanRoot.Hash)
 
 		// Recursively try to connect further orphans
 		addedBlockHashes = append(addedBlockHashes, s.connectOrphanBlock(orphanRoot)...)
@@ -276,25 +414,27 @@ func (s *BlockStore) connectOrphanBlock(newNode *blockNode) (addedBlockHashes []
 
 // connectNodes connects a parent blockNode to a child blockNode.
 // Updates (1) accumulated work, (2) height, (3) leaves, (4) roots, (5) connection relation and (6) validity accordingly.
-// Performs full validation on the child block and marks it as invalid if validation fails or if the parent is invalid.
+// Checks the difficulty target and the timestamp of the child block and marks it as invalid if a check fails or if the parent is invalid.
+// Full validation is left to the connection of the block to the UTXO set, as the UTXO set of the parent is usually not available here.
 func (s *BlockStore) connectNodes(parent *blockNode, child *blockNode) {
-	assert.Assert(child.Block.Header.PreviousBlockHash == parent.Block.Hash())
+	assert.Assert(child.Header.PreviousBlockHash == parent.Hash)
 
 	child.Parent = parent
 	parent.Children = append(parent.Children, child)
 
-	child.AccumulatedWork = parent.AccumulatedWork + uint64(child.Block.BlockDifficulty())
+	child.AccumulatedWork = parent.AccumulatedWork + blockWork(child.Header)
 	child.Height = parent.Height + 1
 
 	// Validate the child block and propagate invalidity from parent
 	if parent.IsInvalid {
 		child.IsInvalid = true
-		logger.Warnf("[block_store] Block %v marked invalid due to invalid parent %v", child.Block.Hash(), parent.Block.Hash())
-	} else {
-		if ok, err := s.blockValidator.FullValidation(*child.Block); !ok {
-			child.IsInvalid = true
-			logger.Warnf("[block_store] Block %v marked invalid: %v", child.Block.Hash(), err)
-		}
+		logger.Warnf("[block_store] Block %v marked invalid due to invalid parent %v", child.Hash, parent.Hash)
+	} else if ok, err := s.blockValidator.ValidateDifficultyTarget(child.Header, nextDifficultyTarget(parent)); !ok {
+		child.IsInvalid = true
+		logger.Warnf("[block_store] Block %v marked invalid: %v", child.Hash, err)
+	} else if ok, err := s.blockValidator.ValidateTimestamp(child.Header, medianTimePast(parent)); !ok {
+		child.IsInvalid = true
+		logger.Warnf("[block_store] Block %v marked invalid: %v", child.Hash, err)
 	}
 
 	// Remove parent from leaves (if it was a leaf)
@@ -331,9 +471,25 @@ func (s *BlockStore) isOrphanBlock(block block.Block) (bool, error) {
 		return false, fmt.Errorf("block with hash %v not found", block.Hash())
 	}
 
-	// A block is an orphan if it has no parent and no accumulated work (genesis has accumulated work from its difficulty)
-	isOrphan := blockNode.Parent == nil && blockNode.AccumulatedWork == 0
-	return isOrphan, nil
+	return isOrphanNode(blockNode), nil
+}
+
+// isOrphanNode checks if the given node is an orphan.
+// A block is an orphan if it has no parent and no accumulated work (genesis has accumulated work from its difficulty).
+func isOrphanNode(node *blockNode) bool {
+	return node.Parent == nil && node.AccumulatedWork == 0
+}
+
+// maxWorkBits bounds the work counted for a single block to 2^maxWorkBits.
+// Targets above are far beyond what can be mined, the bound only keeps the accumulated work from overflowing:
+// a chain needs more than 2^(64-maxWorkBits) blocks at the bound to exceed the range of uint64.
+const maxWorkBits = 40
+
+// blockWork returns the work of the block with the given header, i.e. the expected number of hashes to mine it.
+// Every bit of the difficulty target doubles the expected work, so the work is 2^target (bounded by maxWorkBits).
+// The target is used instead of the actual difficulty of the hash, so a lucky hash doesn't outweigh a chain of blocks.
+func blockWork(header block.BlockHeader) uint64 {
+	return 1 << min(header.DifficultyTarget, maxWorkBits)
 }
 
 // IsBlockInvalid checks if the given block is marked as invalid.
@@ -351,6 +507,133 @@ func (s *BlockStore) IsBlockInvalid(block block.Block) (bool, error) {
 	return blockNode.IsInvalid, nil
 }
 
+// MarkBlockInvalid marks the block with the given hash and all its descendants as invalid, e.g. after the block failed full validation.
+// Invalid blocks never become part of the main chain.
+// Returns an error if the block is not found in the block store or the mark can't be persisted.
+func (s *BlockStore) MarkBlockInvalid(hash common.Hash) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	node, exists := s.hashToHeaders[hash]
+	if !exists {
+		return fmt.Errorf("block with hash %v not found", hash)
+	}
+
+	records := []storage.Record{}
+	stack := []*blockNode{node}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// Descendants of an invalid block are invalid already
+		if current.IsInvalid {
+			continue
+		}
+		current.IsInvalid = true
+		logger.Warnf("[block_store] Block %v marked invalid", current.Hash)
+
+		// Blocks still held in memory have no index entry
+		if s.indexFile != nil && current.block == nil {
+			records = append(records, indexRecord(current))
+		}
+		stack = append(stack, current.Children...)
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+	if err := s.indexFile.Append(records...); err != nil {
+		return fmt.Errorf("failed to persist invalid block %v: %w", hash, err)
+	}
+	return nil
+}
+
+// GetBlockHeight returns the height of the block with the given hash, also for blocks of side chains.
+// Returns an error if the block is not found or is an orphan, as its height is unknown then.
+// O(1) time complexity.
+func (s *BlockStore) GetBlockHeight(hash common.Hash) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	node, exists := s.hashToHeaders[hash]
+	if !exists {
+		return 0, fmt.Errorf("block with hash %v not found", hash)
+	}
+
+	if isOrphanNode(node) {
+		return 0, fmt.Errorf("block with hash %v is an orphan", hash)
+	}
+
+	return node.Height, nil
+}
+
+// GetNextDifficultyTarget returns the difficulty target required for a block whose previous block is the block with the given hash.
+// Returns an error if the previous block is not found or is an orphan, as the retarget window is unknown then.
+// O(RetargetInterval) time complexity.
+func (s *BlockStore) GetNextDifficultyTarget(prevBlockHash common.Hash) (uint8, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parent, exists := s.hashToHeaders[prevBlockHash]
+	if !exists {
+		return 0, fmt.Errorf("block with hash %v not found", prevBlockHash)
+	}
+
+	if isOrphanNode(parent) {
+		return 0, fmt.Errorf("block with hash %v is an orphan", prevBlockHash)
+	}
+
+	return nextDifficultyTarget(parent), nil
+}
+
+// nextDifficultyTarget returns the difficulty target required for a child of the given (non-orphan) node.
+// At retarget heights the target is calculated from the timestamps of the last RetargetInterval blocks, otherwise the target of the parent is kept.
+func nextDifficultyTarget(parent *blockNode) uint8 {
+	parentHeader := parent.Header
+	height := parent.Height + 1
+	if !block.IsRetargetHeight(height) {
+		return parentHeader.DifficultyTarget
+	}
+
+	// First block of the last window
+	first := parent
+	for first.Height > height-block.RetargetInterval {
+		first = first.Parent
+	}
+
+	return block.RetargetDifficulty(parentHeader.DifficultyTarget, first.Header.Timestamp, parentHeader.Timestamp)
+}
+
+// GetMedianTimePast returns the median timestamp of the block with the given hash and its MedianTimePastWindow-1 ancestors.
+// Returns an error if the block is not found or is an orphan, as its ancestors are unknown then.
+// O(MedianTimePastWindow) time complexity.
+func (s *BlockStore) GetMedianTimePast(prevBlockHash common.Hash) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parent, exists := s.hashToHeaders[prevBlockHash]
+	if !exists {
+		return 0, fmt.Errorf("block with hash %v not found", prevBlockHash)
+	}
+
+	if isOrphanNode(parent) {
+		return 0, fmt.Errorf("block with hash %v is an orphan", prevBlockHash)
+	}
+
+	return medianTimePast(parent), nil
+}
+
+// medianTimePast returns the median timestamp of the given (non-orphan) node and its MedianTimePastWindow-1 ancestors.
+// Close to genesis fewer blocks are available, then the median of all blocks up to genesis is used.
+func medianTimePast(parent *blockNode) int64 {
+	timestamps := make([]int64, 0, block.MedianTimePastWindow)
+	for node := parent; node != nil && len(timestamps) < block.MedianTimePastWindow; node = node.Parent {
+		timestamps = append(timestamps, node.Header.Timestamp)
+	}
+
+	return block.MedianTimestamp(timestamps)
+}
+
 // IsPartOfMainChain checks if the given block is part of the main chain.
 // Returns false if the block is not found in the block store.
 // O(h) time complexity, with h being the height of the main chain.
@@ -364,8 +647,7 @@ func (s *BlockStore) IsPartOfMainChain(block block.Block) bool {
 	}
 
 	// Get main chain tip
-	mainChainTip := s.getMainChainTip()
-	mainChainTipNode := s.hashToHeaders[mainChainTip.Hash()]
+	mainChainTipNode := s.getMainChainTipNode()
 
 	// Traverse up from main chain tip to genesis, checking if we encounter the blockNode
 	currentNode := mainChainTipNode
@@ -396,7 +678,7 @@ func (s *BlockStore) getBlockByHash(hash common.Hash) (block.Block, error) {
 		return block.Block{}, fmt.Errorf("block with hash %v not found", hash)
 	}
 
-	return copyOfBlock(blockNode), nil
+	return s.readBlock(blockNode)
 }
 
 // GetBlocksByHeight retrieves all blocks at the specified height.
@@ -416,10 +698,8 @@ func (s *BlockStore) GetBlocksByHeight(height uint64) []block.Block {
 		for currentNode != nil && currentNode.Height >= height {
 			if currentNode.Height == height {
 				// isOrphan removes orphans from the result (orphans may have the default height 0)
-				isOrphan, err := s.isOrphanBlock(*currentNode.Block)
-				assert.IsNil(err, "error checking if block is orphan")
-				if !isOrphan {
-					blockHashesAtHeight.Add(currentNode.Block.Hash())
+				if !isOrphanNode(currentNode) {
+					blockHashesAtHeight.Add(currentNode.Hash)
 				}
 
 				break
@@ -459,7 +739,7 @@ func (s *BlockStore) GetCurrentHeight() uint64 {
 }
 
 // GetMainChainHeight returns the height of the main chain tip.
-// The main chain is defined as the chain with the highest accumulated work.
+// The main chain is defined as the chain of valid blocks with the highest accumulated work.
 func (s *BlockStore) GetMainChainHeight() uint64 {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -467,7 +747,7 @@ func (s *BlockStore) GetMainChainHeight() uint64 {
 }
 
 // GetMainChainTip returns the tip block of the main chain.
-// The main chain is defined as the chain with the highest accumulated work.
+// The main chain is defined as the chain of valid blocks with the highest accumulated work.
 // In the case of multiple chains with the same accumulated work, one of them is returned arbitrarily(!).
 func (s *BlockStore) GetMainChainTip() block.Block {
 	s.mu.RLock()
@@ -476,14 +756,21 @@ func (s *BlockStore) GetMainChainTip() block.Block {
 }
 
 // getMainChainTipNode is the internal implementation without locking that returns the node.
+// Invalid blocks are never part of the main chain. As invalidity is propagated to all descendants,
+// the candidate of each leaf is its last valid ancestor, i.e. the tip of the leaf's chain without its invalid blocks.
 func (s *BlockStore) getMainChainTipNode() *blockNode {
 	var mainChainTip *blockNode
 	var maxAccumulatedWork uint64
 
 	for _, leaf := range s.blockForest.Leaves {
-		if leaf.AccumulatedWork > maxAccumulatedWork {
-			maxAccumulatedWork = leaf.AccumulatedWork
-			mainChainTip = leaf
+		candidate := leaf
+		for candidate != nil && candidate.IsInvalid {
+			candidate = candidate.Parent
+		}
+
+		if candidate != nil && candidate.AccumulatedWork > maxAccumulatedWork {
+			maxAccumulatedWork = candidate.AccumulatedWork
+			mainChainTip = candidate
 		}
 	}
 	return mainChainTip
@@ -495,13 +782,44 @@ func (s *BlockStore) getMainChainTip() block.Block {
 
 	assert.IsNotNil(mainChainTip, "no main chain tip found in block store")
 
-	return copyOfBlock(mainChainTip)
+	return s.blockOf(mainChainTip)
+}
+
+// readBlock returns the block of the given node.
+// A block held in memory is copied, a stored block is read from the block file.
+// Either way external modifications of the returned block don't affect the internal state of the BlockStore.
+func (s *BlockStore) readBlock(node *blockNode) (block.Block, error) {
+	if node.block != nil {
+		return copyOfBlock(node.block), nil
+	}
+	if s.file == nil {
+		return block.Block{}, fmt.Errorf("block %v can't be read: block store is closed", node.Hash)
+	}
+
+	record, _, err := s.file.ReadAt(node.offset)
+	if err != nil {
+		return block.Block{}, fmt.Errorf("failed to read block %v: %w", node.Hash, err)
+	}
+	b, err := block.DeserializeBlock(record.Payload)
+	if err != nil {
+		return block.Block{}, fmt.Errorf("failed to read block %v: %w", node.Hash, err)
+	}
+	if b.Hash() != node.Hash {
+		return block.Block{}, fmt.Errorf("failed to read block %v: block file contains block %v at its offset", node.Hash, b.Hash())
+	}
+	return b, nil
+}
+
+// blockOf is readBlock for blocks that must be readable, i.e. all blocks known to the BlockStore.
+// Panics if a stored block can't be read, the block file is corrupt then.
+func (s *BlockStore) blockOf(node *blockNode) block.Block {
+	b, err := s.readBlock(node)
+	assert.IsNil(err, "error reading stored block")
+	return b
 }
 
-// copyOfBlock creates and returns a deep copy of the block contained in the given blockNode.
-// Should be used to prevent external modification of the internal state of the BlockStore.
-func copyOfBlock(node *blockNode) block.Block {
-	originalBlock := node.Block
+// copyOfBlock creates and returns a deep copy of the given block.
+func copyOfBlock(originalBlock *block.Block) block.Block {
 
 	// Deep copy transactions
 	copiedTransactions := make([]transaction.Transaction, len(originalBlock.Transactions))
@@ -541,13 +859,12 @@ func (s *BlockStore) GetAllBlocksWithMetadata() []block.BlockWithMetadata {
 func (s *BlockStore) getMainChainHashes() mapset.Set[common.Hash] {
 	mainChainHashes := mapset.NewSet[common.Hash]()
 
-	mainChainTip := s.getMainChainTip()
-	mainChainTipNode := s.hashToHeaders[mainChainTip.Hash()]
+	mainChainTipNode := s.getMainChainTipNode()
 
 	// Traverse from tip to genesis
 	currentNode := mainChainTipNode
 	for currentNode != nil {
-		mainChainHashes.Add(currentNode.Block.Hash())
+		mainChainHashes.Add(currentNode.Hash)
 		currentNode = currentNode.Parent
 	}
 
@@ -556,24 +873,24 @@ func (s *BlockStore) getMainChainHashes() mapset.Set[common.Hash] {
 
 // collectBlocksWithMetadata recursively collects blocks with their metadata.
 func (s *BlockStore) collectBlocksWithMetadata(node *blockNode, mainChainHashes mapset.Set[common.Hash], visited mapset.Set[common.Hash], result *[]block.BlockWithMetadata) {
-	blockHash := node.Block.Hash()
+	blockHash := node.Hash
 
 	if visited.Contains(blockHash) {
 		return
 	}
 	visited.Add(blockHash)
 
-	isOrphan, _ := s.isOrphanBlock(*node.Block)
+	isOrphan := isOrphanNode(node)
 	isMainChain := mainChainHashes.Contains(blockHash)
 
 	var parentHash *common.Hash
 	if node.Parent != nil {
-		h := node.Parent.Block.Hash()
+		h := node.Parent.Hash
 		parentHash = &h
 	}
 
 	metadata := block.BlockWithMetadata{
-		Block:           copyOfBlock(node),
+		Block:           s.blockOf(node),
 		Height:          node.Height,
 		AccumulatedWork: node.AccumulatedWork,
 		ParentHash:      parentHash,
@@ -1,6 +1,8 @@
 package blockchain
 
 import (
+	"os"
+	"path/filepath"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
@@ -9,7 +11,11 @@ import (
 
 type blockValidatorForTests struct{}
 
-func (b *blockValidatorForTests) FullValidation(blockToValidate block.Block) (bool, error) {
+func (b *blockValidatorForTests) ValidateDifficultyTarget(header block.BlockHeader, requiredTarget uint8) (bool, error) {
+	return true, nil
+}
+
+func (b *blockValidatorForTests) ValidateTimestamp(header block.BlockHeader, medianTimePast int64) (bool, error) {
 	return true, nil
 }
 
@@ -871,3 +877,542 @@ func TestAddBlock_ChainedOrphans(t *testing.T) {
 		}
 	}
 }
+
+// TestPersistentBlockStore_RestoresBlocks tests that a persistent block store restores all blocks after reopening
+// (g) -> (b1) -> (b2)
+// (g) -> (s1)          [invalid]
+// (o1)                 [orphan]
+func TestPersistentBlockStore_RestoresBlocks(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	block1 := createTestBlockWithLeadingZeros(genesis.Hash(), 1)
+	block2 := createTestBlockWithLeadingZeros(block1.Hash(), 2)
+	side1 := createTestBlock(genesis.Hash(), 3)
+	orphan1 := createTestBlock(common.Hash{0xff}, 4)
+
+	store, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create persistent block store: %v", err)
+	}
+	store.AddBlock(block1)
+	store.AddBlock(block2)
+	store.AddBlock(side1)
+	store.AddBlock(orphan1)
+	if err := store.MarkBlockInvalid(side1.Hash()); err != nil {
+		t.Fatalf("Failed to mark side1 invalid: %v", err)
+	}
+	expectedTip := store.GetMainChainTip()
+	expectedHeight := store.GetMainChainHeight()
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close block store: %v", err)
+	}
+
+	// Act
+	restored, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen persistent block store: %v", err)
+	}
+	defer restored.Close()
+
+	// Assert
+	tip := restored.GetMainChainTip()
+	if tip.Hash() != expectedTip.Hash() {
+		t.Errorf("Main chain tip changed after restore")
+	}
+	if restored.GetMainChainHeight() != expectedHeight {
+		t.Errorf("Main chain height should be %d: got %d", expectedHeight, restored.GetMainChainHeight())
+	}
+	if len(restored.GetAllBlocksWithMetadata()) != 5 {
+		t.Errorf("Expected 5 blocks: got %d", len(restored.GetAllBlocksWithMetadata()))
+	}
+
+	isInvalid, err := restored.IsBlockInvalid(side1)
+	if err != nil || !isInvalid {
+		t.Errorf("Side chain block should still be invalid")
+	}
+
+	isOrphan, err := restored.IsOrphanBlock(orphan1)
+	if err != nil || !isOrphan {
+		t.Errorf("Orphan should still be an orphan")
+	}
+}
+
+// TestMarkBlockInvalid tests that marking a block invalid also marks its descendants and moves the main chain tip back
+// (g) -> (b1) -> (b2) -> (b3)  [b2 marked invalid]
+func TestMarkBlockInvalid(t *testing.T) {
+	// Arrange
+	genesis := createTestBlock([32]byte{}, 0)
+	store := NewBlockStore(genesis, &blockValidatorForTests{})
+	block1 := createTestBlock(genesis.Hash(), 1)
+	block2 := createTestBlock(block1.Hash(), 2)
+	block3 := createTestBlock(block2.Hash(), 3)
+	store.AddBlock(block1)
+	store.AddBlock(block2)
+	store.AddBlock(block3)
+
+	// Act
+	err := store.MarkBlockInvalid(block2.Hash())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("MarkBlockInvalid returned error: %v", err)
+	}
+	for _, b := range []block.Block{block2, block3} {
+		if isInvalid, _ := store.IsBlockInvalid(b); !isInvalid {
+			t.Errorf("Block %v should be invalid", b.Hash())
+		}
+	}
+	if isInvalid, _ := store.IsBlockInvalid(block1); isInvalid {
+		t.Errorf("Ancestor b1 should stay valid")
+	}
+	tip := store.GetMainChainTip()
+	if tip.Hash() != block1.Hash() {
+		t.Errorf("Main chain tip should move back to b1")
+	}
+	if err := store.MarkBlockInvalid(common.Hash{1, 2, 3}); err == nil {
+		t.Errorf("Expected error for unknown block")
+	}
+}
+
+// TestPersistentBlockStore_TruncatesCorruptTail tests that an incomplete record at the end of the block file is dropped
+func TestPersistentBlockStore_TruncatesCorruptTail(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	block1 := createTestBlockWithLeadingZeros(genesis.Hash(), 1)
+	block2 := createTestBlockWithLeadingZeros(block1.Hash(), 2)
+
+	store, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create persistent block store: %v", err)
+	}
+	store.AddBlock(block1)
+	store.AddBlock(block2)
+	_ = store.Close()
+
+	// Simulate a crash while writing block2 by cutting off its last bytes
+	path := filepath.Join(dataDir, blockFileName)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat block file: %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatalf("Failed to truncate block file: %v", err)
+	}
+
+	// Act
+	restored, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen persistent block store: %v", err)
+	}
+	restored.AddBlock(block2)
+	_ = restored.Close()
+
+	reopened, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen persistent block store: %v", err)
+	}
+	defer reopened.Close()
+
+	// Assert
+	tip := reopened.GetMainChainTip()
+	if tip.Hash() != block2.Hash() {
+		t.Errorf("Main chain tip should be block2 after re-adding it")
+	}
+}
+
+// TestPersistentBlockStore_ReadsBlocksOnDemand tests that stored blocks are not held in memory
+// and that an orphan connected later is restored at its final position
+// (g) -> (b1) -> (b2)  [b2 added before b1]
+func TestPersistentBlockStore_ReadsBlocksOnDemand(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	block1 := createTestBlockWithLeadingZeros(genesis.Hash(), 1)
+	block2 := createTestBlockWithLeadingZeros(block1.Hash(), 2)
+
+	store, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create persistent block store: %v", err)
+	}
+	store.AddBlock(block2)
+	store.AddBlock(block1)
+	_ = store.Close()
+
+	// Act
+	restored, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen persistent block store: %v", err)
+	}
+	defer restored.Close()
+
+	// Assert
+	for _, b := range []block.Block{block1, block2} {
+		if restored.hashToHeaders[b.Hash()].block != nil {
+			t.Errorf("Stored block %v should not be held in memory", b.Hash())
+		}
+	}
+
+	if restored.GetMainChainHeight() != 2 {
+		t.Errorf("Main chain height should be 2: got %d", restored.GetMainChainHeight())
+	}
+
+	read, err := restored.GetBlockByHash(block2.Hash())
+	if err != nil {
+		t.Fatalf("Failed to read stored block: %v", err)
+	}
+	if read.Hash() != block2.Hash() || len(read.Transactions) != len(block2.Transactions) {
+		t.Errorf("Read block differs from the stored block")
+	}
+	if read.Transactions[0].Outputs[0].Value != block2.Transactions[0].Outputs[0].Value {
+		t.Errorf("Transactions of the read block differ from the stored block")
+	}
+}
+
+// TestPersistentBlockStore_KeepsBlockIfIndexWriteFails tests that a block whose index entry can't be written stays in memory
+func TestPersistentBlockStore_KeepsBlockIfIndexWriteFails(t *testing.T) {
+	// Arrange
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	block1 := createTestBlockWithLeadingZeros(genesis.Hash(), 1)
+
+	store, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create persistent block store: %v", err)
+	}
+	defer store.Close()
+	_ = store.indexFile.Close()
+
+	// Act
+	store.AddBlock(block1)
+
+	// Assert
+	if store.hashToHeaders[block1.Hash()].block == nil {
+		t.Fatalf("Block without index entry should stay in memory")
+	}
+	read, err := store.GetBlockByHash(block1.Hash())
+	if err != nil || read.Hash() != block1.Hash() {
+		t.Errorf("Block without index entry should still be readable: %v", err)
+	}
+}
+
+// TestAddBlock_WorkDoublesPerBit tests that the work of a block doubles with every bit of its difficulty target,
+// so a single block at a high target outweighs several blocks at a low target
+// (g) -> (a1) -> (a2)  [target 20 each]
+// (g) -> (b1)          [target 30]
+func TestAddBlock_WorkDoublesPerBit(t *testing.T) {
+	// Arrange
+	genesis := createTestBlock([32]byte{}, 0)
+	store := NewBlockStore(genesis, &blockValidatorForTests{})
+
+	withTarget := func(b block.Block, target uint8) block.Block {
+		b.Header.DifficultyTarget = target
+		return b
+	}
+	a1 := withTarget(createTestBlock(genesis.Hash(), 1), 20)
+	a2 := withTarget(createTestBlock(a1.Hash(), 2), 20)
+	b1 := withTarget(createTestBlock(genesis.Hash(), 3), 30)
+
+	// Act
+	store.AddBlock(a1)
+	store.AddBlock(a2)
+	store.AddBlock(b1)
+
+	// Assert
+	tip := store.GetMainChainTip()
+	if tip.Hash() != b1.Hash() {
+		t.Errorf("Main chain tip should be b1 with 2^30 work instead of a2 with 2*2^20 work")
+	}
+	if work := store.hashToHeaders[a2.Hash()].AccumulatedWork; work != 1+2<<20 {
+		t.Errorf("Accumulated work of a2 should be %d: got %d", 1+2<<20, work)
+	}
+}
+
+// difficultyCheckingValidatorForTests accepts every block but enforces the required difficulty target.
+type difficultyCheckingValidatorForTests struct{}
+
+func (b *difficultyCheckingValidatorForTests) ValidateDifficultyTarget(header block.BlockHeader, requiredTarget uint8) (bool, error) {
+	return header.DifficultyTarget == requiredTarget, nil
+}
+
+func (b *difficultyCheckingValidatorForTests) ValidateTimestamp(header block.BlockHeader, medianTimePast int64) (bool, error) {
+	return true, nil
+}
+
+// TestGetNextDifficultyTarget_Retarget tests that the target is kept within a retarget window and recalculated at retarget heights
+// (g) -> (b1) -> ... -> (b9) -> (b10)       [b10 has the target of b9, invalid]
+//
+//	-> (b10') [b10' has the recalculated target]
+func TestGetNextDifficultyTarget_Retarget(t *testing.T) {
+	// Arrange
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	store := NewBlockStore(genesis, &difficultyCheckingValidatorForTests{})
+
+	prev := genesis
+	for i := uint32(1); i < block.RetargetInterval; i++ {
+		target, err := store.GetNextDifficultyTarget(prev.Hash())
+		if err != nil {
+			t.Fatalf("GetNextDifficultyTarget returned error: %v", err)
+		}
+		if target != genesis.Header.DifficultyTarget {
+			t.Fatalf("Target within the first window should stay %d: got %d", genesis.Header.DifficultyTarget, target)
+		}
+
+		next := createTestBlock(prev.Hash(), i)
+		store.AddBlock(next)
+		prev = next
+	}
+
+	// Act
+	target, err := store.GetNextDifficultyTarget(prev.Hash())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("GetNextDifficultyTarget returned error: %v", err)
+	}
+	// Blocks were only one second apart, so the difficulty increases, but never below the minimum
+	expected := block.RetargetDifficulty(genesis.Header.DifficultyTarget, genesis.Header.Timestamp, prev.Header.Timestamp)
+	if target != expected || target != block.MinDifficultyTarget {
+		t.Fatalf("Target at retarget height should be %d: got %d", block.MinDifficultyTarget, target)
+	}
+
+	unchanged := createTestBlock(prev.Hash(), block.RetargetInterval)
+	store.AddBlock(unchanged)
+	if isInvalid, _ := store.IsBlockInvalid(unchanged); !isInvalid {
+		t.Error("Block keeping the old target at a retarget height should be invalid")
+	}
+
+	retargeted := createTestBlock(prev.Hash(), block.RetargetInterval+1)
+	retargeted.Header.DifficultyTarget = target
+	store.AddBlock(retargeted)
+	if isInvalid, _ := store.IsBlockInvalid(retargeted); isInvalid {
+		t.Error("Block with the recalculated target should be valid")
+	}
+	if store.GetMainChainHeight() != block.RetargetInterval {
+		t.Errorf("Main chain height should be %d: got %d", block.RetargetInterval, store.GetMainChainHeight())
+	}
+}
+
+// TestAddBlock_InvalidChildDeliveredFirstIsNotTip tests that a block failing the difficulty check only when its parent arrives
+// never becomes the main chain tip, even though its chain has the most work
+// (g) -> (a1) -> (a2)  [a2 added before a1, a2 claims a higher target than required]
+func TestAddBlock_InvalidChildDeliveredFirstIsNotTip(t *testing.T) {
+	// Arrange
+	genesis := createTestBlock([32]byte{}, 0)
+	store := NewBlockStore(genesis, &difficultyCheckingValidatorForTests{})
+	a1 := createTestBlock(genesis.Hash(), 1)
+	a2 := createTestBlock(a1.Hash(), 2)
+	a2.Header.DifficultyTarget = 5
+
+	// Act
+	store.AddBlock(a2)
+	store.AddBlock(a1)
+
+	// Assert
+	if isInvalid, _ := store.IsBlockInvalid(a2); !isInvalid {
+		t.Fatalf("a2 should be invalid")
+	}
+	tip := store.GetMainChainTip()
+	if tip.Hash() != a1.Hash() {
+		t.Errorf("Main chain tip should be a1, the last valid block of the chain")
+	}
+	if store.IsPartOfMainChain(a2) {
+		t.Errorf("Invalid block a2 should not be part of the main chain")
+	}
+}
+
+// TestGetNextDifficultyTarget_UnknownOrOrphan tests that no target can be calculated without a known retarget window
+// (o1) [orphan]
+func TestGetNextDifficultyTarget_UnknownOrOrphan(t *testing.T) {
+	// Arrange
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	store := NewBlockStore(genesis, &blockValidatorForTests{})
+	orphan := createTestBlock(common.Hash{9, 9, 9}, 1)
+	store.AddBlock(orphan)
+
+	// Act
+	_, errUnknown := store.GetNextDifficultyTarget(common.Hash{1, 2, 3})
+	_, errOrphan := store.GetNextDifficultyTarget(orphan.Hash())
+
+	// Assert
+	if errUnknown == nil {
+		t.Error("Expected error for unknown previous block")
+	}
+	if errOrphan == nil {
+		t.Error("Expected error for orphan previous block")
+	}
+}
+
+// timestampCheckingValidatorForTests accepts every block but enforces the median-time-past rule.
+type timestampCheckingValidatorForTests struct{}
+
+func (b *timestampCheckingValidatorForTests) ValidateDifficultyTarget(header block.BlockHeader, requiredTarget uint8) (bool, error) {
+	return true, nil
+}
+
+func (b *timestampCheckingValidatorForTests) ValidateTimestamp(header block.BlockHeader, medianTimePast int64) (bool, error) {
+	return header.Timestamp > medianTimePast, nil
+}
+
+// TestGetMedianTimePast tests that the median-time-past covers the last MedianTimePastWindow blocks
+// and that blocks with a timestamp not later than it are marked invalid
+// (g) -> (b1) -> ... -> (b12) -> (old) [invalid]
+func TestGetMedianTimePast(t *testing.T) {
+	// Arrange
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	store := NewBlockStore(genesis, &timestampCheckingValidatorForTests{})
+
+	prev := genesis
+	for i := uint32(1); i <= block.MedianTimePastWindow+1; i++ {
+		next := createTestBlock(prev.Hash(), i)
+		store.AddBlock(next)
+		prev = next
+	}
+
+	// Act
+	medianTimePast, err := store.GetMedianTimePast(prev.Hash())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("GetMedianTimePast returned error: %v", err)
+	}
+	// Timestamps of b2 to b12 are 1002 to 1012
+	if medianTimePast != 1007 {
+		t.Fatalf("Median-time-past should be 1007: got %d", medianTimePast)
+	}
+
+	old := createTestBlock(prev.Hash(), 100)
+	old.Header.Timestamp = medianTimePast
+	store.AddBlock(old)
+	if isInvalid, _ := store.IsBlockInvalid(old); !isInvalid {
+		t.Error("Block with a timestamp not later than the median-time-past should be invalid")
+	}
+
+	_, errUnknown := store.GetMedianTimePast(common.Hash{1, 2, 3})
+	if errUnknown == nil {
+		t.Error("Expected error for unknown previous block")
+	}
+}
+
+// TestGetBlockHeight tests that the height is known for blocks of the main chain and side chains, but not for orphans
+// (g) -> (b1) -> (b2)  [main chain]
+// (g) -> (s1)          [side chain]
+// (o1) -> (o2)         [o2 orphan, o1 unknown]
+func TestGetBlockHeight(t *testing.T) {
+	// Arrange
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	store := NewBlockStore(genesis, &blockValidatorForTests{})
+
+	b1 := createTestBlock(genesis.Hash(), 1)
+	b2 := createTestBlock(b1.Hash(), 2)
+	s1 := createTestBlock(genesis.Hash(), 3)
+	o1 := createTestBlock(genesis.Hash(), 4)
+	o2 := createTestBlock(o1.Hash(), 5)
+	store.AddBlock(b1)
+	store.AddBlock(b2)
+	store.AddBlock(s1)
+	store.AddBlock(o2)
+
+	// Act & Assert
+	for hash, want := range map[common.Hash]uint64{genesis.Hash(): 0, b2.Hash(): 2, s1.Hash(): 1} {
+		height, err := store.GetBlockHeight(hash)
+		if err != nil || height != want {
+			t.Errorf("Height of %v should be %d: got %d, %v", hash, want, height, err)
+		}
+	}
+
+	if _, err := store.GetBlockHeight(o2.Hash()); err == nil {
+		t.Error("Expected error for orphan block")
+	}
+	if _, err := store.GetBlockHeight(common.Hash{1, 2, 3}); err == nil {
+		t.Error("Expected error for unknown block")
+	}
+}
+
+// TestGetMedianTimePast_ChildDeliveredFirst tests that a block failing the median-time-past check only when its parent arrives
+// is marked invalid and never becomes the main chain tip
+// (g) -> (b1) -> ... -> (b12) -> (old) [old added before b12]
+func TestGetMedianTimePast_ChildDeliveredFirst(t *testing.T) {
+	// Arrange
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	store := NewBlockStore(genesis, &timestampCheckingValidatorForTests{})
+
+	prev := genesis
+	for i := uint32(1); i <= block.MedianTimePastWindow; i++ {
+		next := createTestBlock(prev.Hash(), i)
+		store.AddBlock(next)
+		prev = next
+	}
+	parent := createTestBlock(prev.Hash(), block.MedianTimePastWindow+1)
+	old := createTestBlock(parent.Hash(), 100)
+	// Timestamps of b2 to b12 are 1002 to 1012, so the median-time-past of b12 is 1007
+	old.Header.Timestamp = 1007
+
+	// Act
+	store.AddBlock(old)
+	store.AddBlock(parent)
+
+	// Assert
+	if isInvalid, _ := store.IsBlockInvalid(old); !isInvalid {
+		t.Fatalf("Block with a timestamp not later than the median-time-past should be invalid")
+	}
+	tip := store.GetMainChainTip()
+	if tip.Hash() != parent.Hash() {
+		t.Errorf("Main chain tip should be b12, not the invalid block delivered before it")
+	}
+}
+
+// TestGetBlockHeightDifferenceByTxId tests that only transactions of the main chain are found
+// (g) -> (b1) -> (b2) -> (b3)  [main chain]
+//
+//	\-> (s1)                [side chain]
+func TestGetBlockHeightDifferenceByTxId(t *testing.T) {
+	// Arrange
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	store := NewBlockStore(genesis, &blockValidatorForTests{})
+
+	mainChainTx := transaction.Transaction{Outputs: []transaction.Output{{Value: 7, PubKeyHash: transaction.PubKeyHash{4}}}}
+	sideChainTx := transaction.Transaction{Outputs: []transaction.Output{{Value: 8, PubKeyHash: transaction.PubKeyHash{5}}}}
+
+	b1 := createTestBlock(genesis.Hash(), 1)
+	b1.Transactions = append(b1.Transactions, mainChainTx)
+	b2 := createTestBlock(b1.Hash(), 2)
+	b3 := createTestBlock(b2.Hash(), 3)
+	s1 := createTestBlock(genesis.Hash(), 4)
+	s1.Transactions = append(s1.Transactions, sideChainTx)
+	store.AddBlock(b1)
+	store.AddBlock(b2)
+	store.AddBlock(b3)
+	store.AddBlock(s1)
+
+	// Act & Assert
+	if difference, err := store.GetBlockHeightDifferenceByTxId(mainChainTx.TransactionId()); err != nil || difference != 2 {
+		t.Errorf("Height difference of the main chain transaction should be 2: got %d, %v", difference, err)
+	}
+	if difference, err := store.GetBlockHeightDifferenceByTxId(sideChainTx.TransactionId()); err != nil || difference != -1 {
+		t.Errorf("Side chain transaction should not be found: got %d, %v", difference, err)
+	}
+}
+
+// TestGetBlockHeightDifferenceByTxId_ReadError tests that a stored block that can't be read is reported as error
+func TestGetBlockHeightDifferenceByTxId_ReadError(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	genesis := createTestBlockWithLeadingZeros([32]byte{}, 0)
+	block1 := createTestBlockWithLeadingZeros(genesis.Hash(), 1)
+
+	store, err := NewPersistentBlockStore(genesis, &blockValidatorForTests{}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create persistent block store: %v", err)
+	}
+	store.AddBlock(block1)
+	_ = store.Close()
+
+	// Act
+	_, err = store.GetBlockHeightDifferenceByTxId(transaction.TransactionID{9})
+
+	// Assert
+	if err == nil {
+		t.Error("Expected error for a block store that can't read its blocks")
+	}
+}
@@ -34,7 +34,7 @@ func GenesisBlock() block.Block {
 
 	// Sanity check: Verify merkle root
 	merkleRoot := block.MerkleRootFromTransactions([]transaction.Transaction{coinbaseTx})
-	expectedMerkleRoot, err := hex.DecodeString("6eb30762d7b80c291cab70c869fb8e7fc4f6a29295bb3f62cb82e89e9634aeb9")
+	expectedMerkleRoot, err := hex.DecodeString("d7f5550b435f028672e12c57c4dd03ec6b4a623b8966557049de8cc5caaf578a")
 	assert.IsNil(err, "failed to decode expected merkle root")
 	assert.Assert(slices.Compare(merkleRoot[:], expectedMerkleRoot) == 0, "calculated merkle root does not match expected merkle root")
 
@@ -44,7 +44,7 @@ func GenesisBlock() block.Block {
 		MerkleRoot:        merkleRoot,
 		Timestamp:         time.Date(2025, 12, 19, 8, 0, 0, 0, time.UTC).Unix(),
 		DifficultyTarget:  28, // StandardDifficultyTarget
-		Nonce:             1389802128,
+		Nonce:             328501057,
 	}
 
 	genesisBlock := block.Block{
@@ -53,7 +53,7 @@ func GenesisBlock() block.Block {
 	}
 
 	// Sanity check: Verify genesis block hash
-	expectedGenesisHash, err := hex.DecodeString("00000000961cc158f2d959ef5980346066a2cc4aa7b3b0eb0d91f83fb918f292")
+	expectedGenesisHash, err := hex.DecodeString("0000000925855b2fa6e5c22445f394138e48ab7343794c8d2c572a2b3722eaba")
 	assert.IsNil(err, "failed to decode expected genesis hash")
 	actualGenesisHash := genesisBlock.Hash()
 	assert.Assert(slices.Compare(actualGenesisHash[:], expectedGenesisHash) == 0, "genesis block hash does not match expected hash")
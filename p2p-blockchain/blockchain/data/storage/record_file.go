@@ -0,0 +1,219 @@
+// Package storage provides a crash-safe append-only record file used to persist blockchain state.
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// recordHeaderSize is the size of the record header: kind (1) + payload length (4) + CRC32 checksum (4).
+const recordHeaderSize = 1 + 4 + 4
+
+// maxRecordPayloadSize limits the payload size of a single record.
+// Protects against huge allocations caused by a corrupted length field.
+const maxRecordPayloadSize = 64 << 20
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// Record is a single entry of a RecordFile.
+// Kind is defined by the user of the file and identifies the type of the payload.
+type Record struct {
+	Kind    uint8
+	Payload []byte
+}
+
+// RecordFile is an append-only log of records.
+//
+// Each record is encoded as [kind uint8][payload length uint32][crc32 uint32][payload].
+// The checksum covers the kind and the payload.
+// Replaying all records in order reproduces the persisted state.
+// Files that are too large to be replayed can be opened with OpenRecordFileForAppend and read record by record with ReadAt.
+//
+// Records are only appended and synced to disk before Append returns.
+// A crash during a write can therefore only leave an incomplete record at the end of the file.
+// Such a record is detected by its checksum and truncated when the file is opened again.
+type RecordFile struct {
+	file *os.File
+}
+
+// OpenRecordFile opens (or creates) the record file at the given path and reads all intact records.
+// Missing parent directories are created.
+// An incomplete or corrupt tail is truncated, so new records are appended directly after the last intact record.
+func OpenRecordFile(path string) (*RecordFile, []Record, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open record file %s: %w", path, err)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, fmt.Errorf("failed to read record file %s: %w", path, err)
+	}
+
+	records, validLength := decodeRecords(data)
+
+	if validLength < int64(len(data)) {
+		if err := file.Truncate(validLength); err != nil {
+			_ = file.Close()
+			return nil, nil, fmt.Errorf("failed to truncate corrupt tail of record file %s: %w", path, err)
+		}
+	}
+
+	if _, err := file.Seek(validLength, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, nil, fmt.Errorf("failed to seek record file %s: %w", path, err)
+	}
+
+	return &RecordFile{file: file}, records, nil
+}
+
+// OpenRecordFileForAppend opens (or creates) the record file at the given path without reading its records.
+// Missing parent directories are created. New records are appended at the end of the file.
+// The user of the file is responsible for knowing the offsets of the records, e.g. by keeping an index.
+func OpenRecordFileForAppend(path string) (*RecordFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record file %s: %w", path, err)
+	}
+
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to seek record file %s: %w", path, err)
+	}
+
+	return &RecordFile{file: file}, nil
+}
+
+// decodeRecords decodes records until the end of data or the first incomplete or corrupt record.
+// Returns the decoded records and the number of bytes they occupy.
+func decodeRecords(data []byte) ([]Record, int64) {
+	records := make([]Record, 0)
+	offset := 0
+
+	for offset < len(data) {
+		record, size, err := decodeRecord(data[offset:])
+		if err != nil {
+			break
+		}
+
+		records = append(records, record)
+		offset += size
+	}
+
+	return records, int64(offset)
+}
+
+// decodeRecord decodes the record at the start of data.
+// Returns the record and its encoded size.
+func decodeRecord(data []byte) (Record, int, error) {
+	if len(data) < recordHeaderSize {
+		return Record{}, 0, errCorruptRecord
+	}
+
+	kind := data[0]
+	payloadLength := binary.LittleEndian.Uint32(data[1:5])
+	checksum := binary.LittleEndian.Uint32(data[5:9])
+
+	if payloadLength > maxRecordPayloadSize || int(payloadLength) > len(data)-recordHeaderSize {
+		return Record{}, 0, errCorruptRecord
+	}
+
+	payload := data[recordHeaderSize : recordHeaderSize+int(payloadLength)]
+	if recordChecksum(kind, payload) != checksum {
+		return Record{}, 0, errCorruptRecord
+	}
+
+	return Record{Kind: kind, Payload: bytes.Clone(payload)}, recordHeaderSize + int(payloadLength), nil
+}
+
+// Append appends the given records to the file and syncs the file to disk.
+// All records are written with a single write call.
+func (f *RecordFile) Append(records ...Record) error {
+	buffer := make([]byte, 0)
+	for _, record := range records {
+		buffer = append(buffer, record.Kind)
+		buffer = binary.LittleEndian.AppendUint32(buffer, uint32(len(record.Payload)))
+		buffer = binary.LittleEndian.AppendUint32(buffer, recordChecksum(record.Kind, record.Payload))
+		buffer = append(buffer, record.Payload...)
+	}
+
+	offset, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.file.Write(buffer); err != nil {
+		// Remove a partially written record, otherwise all following records would be lost on the next start
+		_ = f.file.Truncate(offset)
+		_, _ = f.file.Seek(offset, io.SeekStart)
+		return err
+	}
+
+	return f.file.Sync()
+}
+
+// Size returns the size of the file, which is the offset the next appended record is written to.
+func (f *RecordFile) Size() (int64, error) {
+	return f.file.Seek(0, io.SeekCurrent)
+}
+
+// ReadAt reads the record starting at the given offset.
+// Returns the record and its encoded size, or an error if there is no intact record at the offset.
+// Safe to call concurrently with Append.
+func (f *RecordFile) ReadAt(offset int64) (Record, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := f.file.ReadAt(header, offset); err != nil {
+		return Record{}, 0, fmt.Errorf("failed to read record at offset %d: %w", offset, err)
+	}
+
+	payloadLength := binary.LittleEndian.Uint32(header[1:5])
+	if payloadLength > maxRecordPayloadSize {
+		return Record{}, 0, fmt.Errorf("record at offset %d: %w", offset, errCorruptRecord)
+	}
+
+	data := make([]byte, recordHeaderSize+int(payloadLength))
+	if _, err := f.file.ReadAt(data, offset); err != nil {
+		return Record{}, 0, fmt.Errorf("failed to read record at offset %d: %w", offset, err)
+	}
+
+	record, size, err := decodeRecord(data)
+	if err != nil {
+		return Record{}, 0, fmt.Errorf("record at offset %d: %w", offset, err)
+	}
+	return record, int64(size), nil
+}
+
+// Truncate cuts off the file at the given size, e.g. to drop records that were appended but never referenced.
+// New records are appended at the new end of the file.
+func (f *RecordFile) Truncate(size int64) error {
+	if err := f.file.Truncate(size); err != nil {
+		return err
+	}
+	_, err := f.file.Seek(size, io.SeekStart)
+	return err
+}
+
+// Close closes the underlying file.
+func (f *RecordFile) Close() error {
+	return f.file.Close()
+}
+
+func recordChecksum(kind uint8, payload []byte) uint32 {
+	checksum := crc32.ChecksumIEEE([]byte{kind})
+	return crc32.Update(checksum, crc32.IEEETable, payload)
+}
@@ -0,0 +1,35 @@
+// gencert creates a self-signed client certificate for the app server, e.g. for the REST gateway or the registry crawler,
+// and prints its fingerprint to be added to APP_CLIENT_CERTS of the node. An existing certificate in the directory is kept.
+//
+// Usage: go run ./cmd/gencert -dir ./certs/rest-api
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/nodecert"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory the certificate (node.crt) and key (node.key) are written to")
+	flag.Parse()
+
+	if *dir == "" {
+		logger.Errorf("[gencert] -dir is required")
+		os.Exit(1)
+	}
+
+	cert, err := nodecert.LoadOrCreate(*dir)
+	if err != nil {
+		logger.Errorf("[gencert] %v", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("certificate: %s\n", filepath.Join(*dir, "node.crt"))
+	fmt.Printf("key:         %s\n", filepath.Join(*dir, "node.key"))
+	fmt.Printf("fingerprint: %s\n", nodecert.Fingerprint(cert.Leaf))
+}
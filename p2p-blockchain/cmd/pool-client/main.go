@@ -0,0 +1,107 @@
+// pool-client is a simple CPU worker for the mining pool, used to test the pool locally or across machines.
+//
+// Usage: go run ./cmd/pool-client -pool localhost:3333 -worker <V$Address>.rig1
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"os"
+	"os/signal"
+	"s3b/vsp-blockchain/p2p-blockchain/pool/core"
+	"s3b/vsp-blockchain/p2p-blockchain/pool/infrastructure/stratum"
+	"slices"
+	"syscall"
+	"time"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// noncesPerCheck is the number of nonces tried before checking for a new job.
+const noncesPerCheck = 1 << 16
+
+func main() {
+	poolAddress := flag.String("pool", "localhost:3333", "address of the pool")
+	workerName := flag.String("worker", "", "worker name, <V$Address>[.<rig>]")
+	flag.Parse()
+
+	if *workerName == "" {
+		logger.Errorf("[pool-client] -worker is required")
+		os.Exit(1)
+	}
+
+	client, err := stratum.Dial(*poolAddress)
+	if err != nil {
+		logger.Errorf("[pool-client] %v", err)
+		os.Exit(1)
+	}
+	defer func() { _ = client.Close() }()
+
+	extranonce1, err := client.Subscribe()
+	if err != nil {
+		logger.Errorf("[pool-client] Failed to subscribe: %v", err)
+		os.Exit(1)
+	}
+	if err := client.Authorize(*workerName); err != nil {
+		logger.Errorf("[pool-client] Failed to authorize: %v", err)
+		os.Exit(1)
+	}
+	logger.Infof("[pool-client] Connected to %s as %s", *poolAddress, *workerName)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		_ = client.Close()
+	}()
+
+	mine(client, extranonce1, *workerName)
+}
+
+// mine works on the latest job until the connection is closed.
+func mine(client *stratum.Client, extranonce1 []byte, workerName string) {
+	job, ok := <-client.Jobs()
+	var extranonce2 uint32
+	for ok {
+		extranonce2++
+		job, ok = searchShares(client, job, extranonce1, extranonce2, workerName)
+	}
+	logger.Infof("[pool-client] Disconnected from pool")
+}
+
+// searchShares tries all nonces for the extranonce2 and submits the shares found.
+// Returns the job to continue with, which is a new job of the pool if one arrived in between.
+func searchShares(client *stratum.Client, job core.Job, extranonce1 []byte, extranonce2 uint32, workerName string) (core.Job, bool) {
+	extranonce2Bytes := binary.BigEndian.AppendUint32(nil, extranonce2)
+	extranonce := slices.Concat(extranonce1, extranonce2Bytes)
+	timestamp := max(time.Now().Unix(), job.MinTimestamp)
+	header, _, err := job.BuildBlock(extranonce, timestamp, 0)
+	if err != nil {
+		logger.Warnf("[pool-client] Skipping job %s: %v", job.ID, err)
+		next, ok := <-client.Jobs()
+		return next, ok
+	}
+
+	for nonce := uint32(0); ; nonce++ {
+		if nonce%noncesPerCheck == noncesPerCheck-1 {
+			select {
+			case next, ok := <-client.Jobs():
+				return next, ok
+			default:
+			}
+		}
+
+		header.Nonce = nonce
+		if core.HeaderDifficulty(header) >= client.ShareDifficulty() {
+			if err := client.Submit(workerName, job.ID, extranonce2Bytes, timestamp, nonce); err != nil {
+				logger.Warnf("[pool-client] Share rejected: %v", err)
+			} else {
+				logger.Infof("[pool-client] Share accepted for job %s", job.ID)
+			}
+		}
+
+		if nonce == ^uint32(0) {
+			return job, true
+		}
+	}
+}
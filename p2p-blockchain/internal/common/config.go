@@ -5,14 +5,39 @@ import (
 	"net/netip"
 	"slices"
 	"sync/atomic"
+	"time"
 )
 
 const (
 	DefaultP2PPort = 50051
 	defaultAppPort = 50050
 	VersionNumber  = 1
+
+	// DefaultMempoolMaxSize is the default limit for the total serialized size of all mempool transactions (50 full blocks).
+	DefaultMempoolMaxSize = 5_000_000
+	// DefaultMempoolExpiry is the default time after which a transaction that was not mined is removed from the mempool.
+	DefaultMempoolExpiry = 14 * 24 * time.Hour
+
+	// DefaultPoolPort is the default port of the Stratum-like mining pool server.
+	DefaultPoolPort = 3333
+	// DefaultPoolShareDifficulty is the default number of leading zero bits of a share accepted by the mining pool.
+	DefaultPoolShareDifficulty = 16
+	// DefaultPoolPPLNSWindow is the default number of last shares the reward of a block found by the pool is split among.
+	DefaultPoolPPLNSWindow = 1000
+
+	// DefaultBanThreshold is the default misbehavior score at which a peer is disconnected and banned, see Peer.MisbehaviorScore.
+	DefaultBanThreshold = 100
+	// DefaultBanDuration is the default time the address of a misbehaving peer stays banned.
+	DefaultBanDuration = 24 * time.Hour
 )
 
+// PayoutAddress is a V$Address receiving a share of the rewards of mined blocks.
+type PayoutAddress struct {
+	VSAddress string
+	// Percent is the share of the reward in percent.
+	Percent uint32
+}
+
 var (
 	VersionString = fmt.Sprintf("vsgoin-%d.0", VersionNumber)
 )
@@ -35,13 +60,13 @@ func SetP2PListeningIpAddr(ip netip.Addr) {
 
 // EnabledTeilsystemeNames returns the names of all enabled subsystems.
 // I.e. ["blockchain_full", "wallet", ...].
-// Never includes "app" (which is not a subsystem).
+// Never includes "app" and "pool" (which are not announced to peers).
 // Always includes "netzwerkrouting".
 // All available subsystems are: "blockchain_full", "wallet", "miner", "netzwerkrouting".
 // The slice is not sorted in any particular order.
 func EnabledTeilsystemeNames() []string {
 	services := getAdditionalServices()
-	services = slices.DeleteFunc(services, func(s string) bool { return s == "app" })
+	services = slices.DeleteFunc(services, func(s string) bool { return s == "app" || s == "pool" })
 	services = append(services, "netzwerkrouting")
 	return services
 }
@@ -61,3 +86,7 @@ func BlockchainFullEnabled() bool {
 func AppEnabled() bool {
 	return slices.Contains(getAdditionalServices(), "app")
 }
+
+func PoolEnabled() bool {
+	return slices.Contains(getAdditionalServices(), "pool")
+}
@@ -1,7 +1,11 @@
 package block
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 
@@ -9,6 +13,10 @@ import (
 	"bjoernblessin.de/go-utils/util/logger"
 )
 
+var (
+	ErrMalformedBlock = errors.New("malformed block encoding")
+)
+
 // Block represents a block in the blockchain.
 // It consists of a BlockHeader and a list of Transactions.
 type Block struct {
@@ -113,6 +121,53 @@ func merkleRootFromHashes(hashes []common.Hash) common.Hash {
 	return hashes[0]
 }
 
+// CoinbaseMerkleBranch returns the hashes needed to calculate the Merkle root of a block from the hash of its coinbase
+// transaction alone, see MerkleRootFromCoinbase. The given transactions are the transactions following the coinbase.
+// The branch does not depend on the coinbase, so an external miner can exchange the coinbase without rehashing all transactions.
+func CoinbaseMerkleBranch(txs []transaction.Transaction) []common.Hash {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	// The coinbase is always the first hash of its level, so its placeholder never ends up in the branch
+	hashes := make([]common.Hash, 1, len(txs)+2)
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash())
+	}
+
+	var branch []common.Hash
+	for len(hashes) != 1 {
+		if len(hashes)%2 == 1 {
+			hashes = append(hashes, hashes[len(hashes)-1])
+		}
+		branch = append(branch, hashes[1])
+
+		tmpHashes := make([]common.Hash, 0, len(hashes)/2)
+		for i := 0; i < len(hashes); i += 2 {
+			combinedData := append(hashes[i][:], hashes[i+1][:]...)
+			tmpHashes = append(tmpHashes, doubleSHA256(combinedData))
+		}
+		hashes = tmpHashes
+	}
+	return branch
+}
+
+// MerkleRootFromCoinbase calculates the Merkle root of a block from the hash of its coinbase transaction
+// and the branch of the other transactions, see CoinbaseMerkleBranch.
+// The result equals MerkleRootFromTransactions of all transactions of the block.
+func MerkleRootFromCoinbase(coinbaseHash common.Hash, branch []common.Hash) common.Hash {
+	if len(branch) == 0 {
+		// A block with only the coinbase duplicates it, see MerkleRootFromTransactions
+		return doubleSHA256(append(coinbaseHash[:], coinbaseHash[:]...))
+	}
+
+	root := coinbaseHash
+	for _, hash := range branch {
+		root = doubleSHA256(append(root[:], hash[:]...))
+	}
+	return root
+}
+
 func doubleSHA256(data []byte) common.Hash {
 	first := sha256.Sum256(data)
 	second := sha256.Sum256(first[:])
@@ -130,3 +185,65 @@ type BlockWithMetadata struct {
 	IsOrphan        bool
 	IsMainChain     bool
 }
+
+// MaxBlockSize is the maximum serialized size of a block in bytes.
+// Blocks exceeding it are rejected before any further validation.
+// As every transaction needs at least a few bytes, this also limits the number of transactions per block.
+const MaxBlockSize = 100_000
+
+// SerializedSize returns the size in bytes of the binary encoding of the block.
+// Equal to len(b.Serialize()) without encoding the block.
+func (b *Block) SerializedSize() int {
+	size := HeaderSize + 4 // transaction count
+	for _, tx := range b.Transactions {
+		size += tx.SerializedSize()
+	}
+
+	return size
+}
+
+// Serialize returns the binary encoding of the block.
+// The encoding consists of the serialized header, the number of transactions (uint32) and the serialized transactions.
+func (b *Block) Serialize() []byte {
+	buffer := b.Header.Serialize()
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		buffer = append(buffer, tx.Serialize()...)
+	}
+	return buffer
+}
+
+// DeserializeBlock decodes a block in the format produced by Serialize.
+// Returns ErrMalformedBlock if data is not exactly one encoded block.
+func DeserializeBlock(data []byte) (Block, error) {
+	if len(data) < HeaderSize+4 {
+		return Block{}, ErrMalformedBlock
+	}
+
+	header, err := DeserializeBlockHeader(data[:HeaderSize])
+	if err != nil {
+		return Block{}, err
+	}
+
+	txCount := binary.LittleEndian.Uint32(data[HeaderSize:])
+	reader := bytes.NewReader(data[HeaderSize+4:])
+
+	// Every transaction needs at least its two length prefixes and the lock time
+	if uint64(txCount)*12 > uint64(reader.Len()) {
+		return Block{}, ErrMalformedBlock
+	}
+
+	transactions := make([]transaction.Transaction, txCount)
+	for i := range transactions {
+		transactions[i], err = transaction.DeserializeTransaction(reader)
+		if err != nil {
+			return Block{}, fmt.Errorf("%w: transaction %d: %w", ErrMalformedBlock, i, err)
+		}
+	}
+
+	if reader.Len() != 0 {
+		return Block{}, fmt.Errorf("%w: %d trailing bytes", ErrMalformedBlock, reader.Len())
+	}
+
+	return Block{Header: header, Transactions: transactions}, nil
+}
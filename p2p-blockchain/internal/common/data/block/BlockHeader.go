@@ -21,16 +21,43 @@ type BlockHeader struct {
 	Nonce uint32
 }
 
-// Hash computes the double SHA-256 hash of the block header.
-func (h *BlockHeader) Hash() common.Hash {
-	var buffer = make([]byte, 0)
+// HeaderSize is the size of a serialized block header in bytes.
+const HeaderSize = 2*common.HashSize + 8 + 4 + 1
+
+// Serialize returns the canonical binary encoding of the block header.
+// This is the same encoding that is hashed to compute the block hash.
+func (h *BlockHeader) Serialize() []byte {
+	var buffer = make([]byte, 0, HeaderSize)
 	buffer = append(buffer, h.PreviousBlockHash[:]...)
 	buffer = append(buffer, h.MerkleRoot[:]...)
 	buffer = binary.LittleEndian.AppendUint64(buffer, uint64(h.Timestamp))
 	buffer = binary.LittleEndian.AppendUint32(buffer, h.Nonce)
 	buffer = append(buffer, h.DifficultyTarget)
+	return buffer
+}
+
+// DeserializeBlockHeader decodes a block header in the format produced by Serialize.
+func DeserializeBlockHeader(data []byte) (BlockHeader, error) {
+	if len(data) != HeaderSize {
+		return BlockHeader{}, ErrMalformedBlock
+	}
 
-	first := sha256.Sum256(buffer)
+	h := BlockHeader{}
+	offset := 0
+	offset += copy(h.PreviousBlockHash[:], data[offset:])
+	offset += copy(h.MerkleRoot[:], data[offset:])
+	h.Timestamp = int64(binary.LittleEndian.Uint64(data[offset:]))
+	offset += 8
+	h.Nonce = binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+	h.DifficultyTarget = data[offset]
+
+	return h, nil
+}
+
+// Hash computes the double SHA-256 hash of the block header.
+func (h *BlockHeader) Hash() common.Hash {
+	first := sha256.Sum256(h.Serialize())
 	second := sha256.Sum256(first[:])
 	var hash common.Hash
 	copy(hash[:], second[:])
@@ -0,0 +1,51 @@
+package block
+
+const (
+	// RetargetInterval is the number of blocks after which the difficulty target is recalculated.
+	// Blocks at heights that are a multiple of RetargetInterval get a new target, all other blocks keep the target of their parent.
+	RetargetInterval = 10
+	// TargetBlockTime is the desired average time between two blocks in seconds.
+	TargetBlockTime = 60
+	// MinDifficultyTarget is the lowest difficulty target a retarget can produce.
+	MinDifficultyTarget uint8 = 20
+	// maxRetargetStep is the maximum number of bits the difficulty target can change per retarget.
+	// Limits the adjustment to a factor of 4 in either direction.
+	maxRetargetStep = 2
+)
+
+// IsRetargetHeight reports whether the difficulty target is recalculated for the block at the given height.
+func IsRetargetHeight(height uint64) bool {
+	return height > 0 && height%RetargetInterval == 0
+}
+
+// RetargetDifficulty calculates the difficulty target of the first block of a new retarget window.
+//
+// previousTarget is the target of the last window.
+// firstTimestamp and lastTimestamp are the timestamps of the first and the last block of the last window (RetargetInterval blocks).
+//
+// The difficulty target is the number of leading zero bits, so every bit doubles the expected work.
+// The target goes up by one bit for each factor of 2 the window was faster than expected
+// and down by one bit for each factor of 2 it was slower, limited to maxRetargetStep bits.
+// Integer arithmetic only, so that all nodes calculate the same target.
+func RetargetDifficulty(previousTarget uint8, firstTimestamp int64, lastTimestamp int64) uint8 {
+	expectedTimespan := int64(RetargetInterval-1) * TargetBlockTime
+	actualTimespan := lastTimestamp - firstTimestamp
+
+	// Clamping also covers timestamps that are not in order
+	actualTimespan = max(actualTimespan, expectedTimespan>>maxRetargetStep)
+	actualTimespan = min(actualTimespan, expectedTimespan<<maxRetargetStep)
+
+	step := 0
+	for timespan := actualTimespan * 2; timespan <= expectedTimespan && step < maxRetargetStep; timespan *= 2 {
+		step++ // Blocks were too fast, increase difficulty
+	}
+	for timespan := actualTimespan; timespan >= expectedTimespan*2 && step > -maxRetargetStep; timespan /= 2 {
+		step-- // Blocks were too slow, decrease difficulty
+	}
+
+	newTarget := int(previousTarget) + step
+	newTarget = max(newTarget, int(MinDifficultyTarget))
+	newTarget = min(newTarget, 255)
+
+	return uint8(newTarget)
+}
@@ -0,0 +1,20 @@
+package block
+
+import "slices"
+
+// MedianTimePastWindow is the number of previous blocks whose median timestamp is the median-time-past of the next block.
+const MedianTimePastWindow = 11
+
+// MedianTimestamp returns the median of the given block timestamps.
+// For an even number of timestamps the upper median is returned, so that the result is always one of the timestamps.
+// Returns 0 if no timestamps are given.
+func MedianTimestamp(timestamps []int64) int64 {
+	if len(timestamps) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(timestamps)
+	slices.Sort(sorted)
+
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,19 @@
+package block
+
+const (
+	// InitialSubsidy is the amount of new coins a block may create before the first halving.
+	InitialSubsidy uint64 = 50
+	// HalvingInterval is the number of blocks after which the block subsidy is halved.
+	HalvingInterval = 10_000
+)
+
+// BlockSubsidy returns the amount of new coins the coinbase transaction of the block at the given height may create.
+// The subsidy starts at InitialSubsidy and is halved every HalvingInterval blocks until it reaches 0.
+func BlockSubsidy(height uint64) uint64 {
+	halvings := height / HalvingInterval
+	if halvings >= 64 {
+		return 0
+	}
+
+	return InitialSubsidy >> halvings
+}
@@ -6,6 +6,8 @@ import (
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
 )
 
 func TestEvenBlock(t *testing.T) {
@@ -239,6 +241,45 @@ func makeTx(lockTime uint64) transaction.Transaction {
 	}
 }
 
+func TestMerkleRootFromCoinbase_MatchesMerkleRoot(t *testing.T) {
+	for count := 1; count <= 9; count++ {
+		txs := make([]transaction.Transaction, count)
+		for i := range txs {
+			txs[i] = transaction.NewCoinbaseTransaction(transaction.PubKeyHash{}, uint64(i), uint64(i))
+		}
+
+		branch := CoinbaseMerkleBranch(txs[1:])
+		got := MerkleRootFromCoinbase(txs[0].Hash(), branch)
+
+		if want := MerkleRootFromTransactions(txs); got != want {
+			t.Errorf("%d transactions: merkle root from coinbase = %x, want %x", count, got, want)
+		}
+	}
+}
+
+func TestCoinbaseMerkleBranch_IndependentOfCoinbase(t *testing.T) {
+	coinbase := transaction.NewCoinbaseTransaction(transaction.PubKeyHash{}, 1, 1)
+	otherCoinbase := transaction.NewCoinbaseTransaction(transaction.PubKeyHash{}, 2, 1)
+	txs := []transaction.Transaction{
+		transaction.NewCoinbaseTransaction(transaction.PubKeyHash{}, 3, 3),
+		transaction.NewCoinbaseTransaction(transaction.PubKeyHash{}, 4, 4),
+	}
+
+	branch := CoinbaseMerkleBranch(txs)
+	if len(branch) != 2 {
+		t.Fatalf("branch has %d hashes, want 2", len(branch))
+	}
+
+	got := MerkleRootFromCoinbase(otherCoinbase.Hash(), branch)
+	want := MerkleRootFromTransactions(append([]transaction.Transaction{otherCoinbase}, txs...))
+	if got != want {
+		t.Errorf("merkle root with exchanged coinbase = %x, want %x", got, want)
+	}
+	if got == MerkleRootFromCoinbase(coinbase.Hash(), branch) {
+		t.Error("merkle root does not depend on the coinbase")
+	}
+}
+
 func TestCountLeadingZeros(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -296,3 +337,251 @@ func TestCountLeadingZeros(t *testing.T) {
 		})
 	}
 }
+
+func TestBlockSerializationRoundTrip(t *testing.T) {
+	original := Block{
+		Header: BlockHeader{
+			PreviousBlockHash: common.Hash{1, 2, 3},
+			MerkleRoot:        common.Hash{4, 5, 6},
+			Timestamp:         1700000000,
+			DifficultyTarget:  28,
+			Nonce:             42,
+		},
+		Transactions: []transaction.Transaction{
+			{
+				Inputs:   []transaction.Input{{PrevTxID: transaction.TransactionID{7}, OutputIndex: 1, Signature: []byte{1, 2, 3}, PubKey: transaction.PubKey{2}, Sequence: 6}},
+				Outputs:  []transaction.Output{{Value: 50, PubKeyHash: transaction.PubKeyHash{9}}},
+				LockTime: 100,
+			},
+			{
+				Inputs:  []transaction.Input{},
+				Outputs: []transaction.Output{{Value: 1, PubKeyHash: transaction.PubKeyHash{8}}, {Value: 2, PubKeyHash: transaction.PubKeyHash{7}}},
+			},
+			{
+				Inputs: []transaction.Input{{
+					PrevTxID:     transaction.TransactionID{3},
+					Signature:    []byte{},
+					Signatures:   [][]byte{{4, 5}, {}},
+					RedeemScript: transaction.MultisigScript{Required: 1, PubKeys: []transaction.PubKey{{2}, {3}}},
+				}},
+				Outputs: []transaction.Output{
+					transaction.NewMultisigOutput(3, transaction.MultisigScript{Required: 2, PubKeys: []transaction.PubKey{{4}, {5}, {6}}}),
+					transaction.NewMultisigHashOutput(4, transaction.PubKeyHash{6}),
+					transaction.NewDataOutput([]byte("document hash")),
+				},
+			},
+		},
+	}
+
+	decoded, err := DeserializeBlock(original.Serialize())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if decoded.Hash() != original.Hash() {
+		t.Fatalf("header changed during round trip")
+	}
+	if !reflect.DeepEqual(decoded.Transactions, original.Transactions) {
+		t.Fatalf("transactions changed during round trip")
+	}
+}
+
+func TestDeserializeBlockRejectsTruncatedData(t *testing.T) {
+	original := Block{Transactions: []transaction.Transaction{makeTx(1)}}
+	data := original.Serialize()
+
+	if _, err := DeserializeBlock(data[:len(data)-1]); err == nil {
+		t.Fatalf("expected error for truncated block")
+	}
+}
+
+func TestRetargetDifficulty(t *testing.T) {
+	expectedTimespan := int64(RetargetInterval-1) * TargetBlockTime
+
+	tests := []struct {
+		name           string
+		previousTarget uint8
+		actualTimespan int64
+		expected       uint8
+	}{
+		{name: "on time", previousTarget: 28, actualTimespan: expectedTimespan, expected: 28},
+		{name: "slightly fast", previousTarget: 28, actualTimespan: expectedTimespan * 3 / 4, expected: 28},
+		{name: "twice as fast", previousTarget: 28, actualTimespan: expectedTimespan / 2, expected: 29},
+		{name: "four times as fast", previousTarget: 28, actualTimespan: expectedTimespan / 4, expected: 30},
+		{name: "much faster is clamped", previousTarget: 28, actualTimespan: 1, expected: 30},
+		{name: "timestamps out of order are clamped", previousTarget: 28, actualTimespan: -expectedTimespan, expected: 30},
+		{name: "slightly slow", previousTarget: 28, actualTimespan: expectedTimespan * 3 / 2, expected: 28},
+		{name: "twice as slow", previousTarget: 28, actualTimespan: expectedTimespan * 2, expected: 27},
+		{name: "four times as slow", previousTarget: 28, actualTimespan: expectedTimespan * 4, expected: 26},
+		{name: "much slower is clamped", previousTarget: 28, actualTimespan: expectedTimespan * 100, expected: 26},
+		{name: "never below minimum", previousTarget: MinDifficultyTarget, actualTimespan: expectedTimespan * 4, expected: MinDifficultyTarget},
+		{name: "never above maximum", previousTarget: 255, actualTimespan: 1, expected: 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RetargetDifficulty(tt.previousTarget, 1000, 1000+tt.actualTimespan)
+			if got != tt.expected {
+				t.Fatalf("RetargetDifficulty(%d, timespan %d) = %d, expected %d", tt.previousTarget, tt.actualTimespan, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsRetargetHeight(t *testing.T) {
+	if IsRetargetHeight(0) {
+		t.Fatalf("genesis height must not be a retarget height")
+	}
+	if IsRetargetHeight(RetargetInterval - 1) {
+		t.Fatalf("height %d must not be a retarget height", RetargetInterval-1)
+	}
+	if !IsRetargetHeight(RetargetInterval) {
+		t.Fatalf("height %d must be a retarget height", RetargetInterval)
+	}
+}
+
+func TestBlockSubsidy(t *testing.T) {
+	tests := []struct {
+		height   uint64
+		expected uint64
+	}{
+		{height: 0, expected: InitialSubsidy},
+		{height: HalvingInterval - 1, expected: InitialSubsidy},
+		{height: HalvingInterval, expected: InitialSubsidy / 2},
+		{height: 2 * HalvingInterval, expected: InitialSubsidy / 4},
+		{height: 64 * HalvingInterval, expected: 0},
+	}
+
+	for _, tt := range tests {
+		got := BlockSubsidy(tt.height)
+		if got != tt.expected {
+			t.Fatalf("BlockSubsidy(%d) = %d, expected %d", tt.height, got, tt.expected)
+		}
+	}
+}
+
+func TestMedianTimestamp(t *testing.T) {
+	tests := []struct {
+		timestamps []int64
+		expected   int64
+	}{
+		{timestamps: nil, expected: 0},
+		{timestamps: []int64{5}, expected: 5},
+		{timestamps: []int64{30, 10, 20}, expected: 20},
+		{timestamps: []int64{40, 10, 30, 20}, expected: 30},
+	}
+
+	for _, tt := range tests {
+		got := MedianTimestamp(tt.timestamps)
+		if got != tt.expected {
+			t.Fatalf("MedianTimestamp(%v) = %d, expected %d", tt.timestamps, got, tt.expected)
+		}
+	}
+}
+
+func TestSerializedSizeMatchesSerialize(t *testing.T) {
+	b := Block{
+		Header: BlockHeader{Timestamp: 1, DifficultyTarget: 8},
+		Transactions: []transaction.Transaction{
+			transaction.NewCoinbaseTransaction(transaction.PubKeyHash{1}, 50, 1),
+			{
+				Inputs:  []transaction.Input{{PrevTxID: transaction.TransactionID{1}, Signature: []byte("signature")}},
+				Outputs: []transaction.Output{{Value: 10}, {Value: 20}},
+			},
+			{
+				Inputs: []transaction.Input{{
+					Signatures:   [][]byte{[]byte("signature"), nil},
+					RedeemScript: transaction.MultisigScript{Required: 1, PubKeys: []transaction.PubKey{{2}, {3}}},
+				}},
+				Outputs: []transaction.Output{
+					transaction.NewMultisigOutput(10, transaction.MultisigScript{Required: 1, PubKeys: []transaction.PubKey{{4}}}),
+					transaction.NewDataOutput([]byte("document hash")),
+				},
+			},
+		},
+	}
+
+	if got, expected := b.SerializedSize(), len(b.Serialize()); got != expected {
+		t.Fatalf("Block.SerializedSize() = %d, expected %d", got, expected)
+	}
+	for i, tx := range b.Transactions {
+		if got, expected := tx.SerializedSize(), len(tx.Serialize()); got != expected {
+			t.Fatalf("Transaction %d SerializedSize() = %d, expected %d", i, got, expected)
+		}
+	}
+}
+
+func TestTransactionIsFinal(t *testing.T) {
+	tests := []struct {
+		lockTime uint32
+		expected bool
+	}{
+		{lockTime: 0, expected: true},
+		{lockTime: 9, expected: true},
+		{lockTime: 10, expected: false},
+		{lockTime: transaction.LockTimeThreshold + 99, expected: true},
+		{lockTime: transaction.LockTimeThreshold + 100, expected: false},
+	}
+
+	for _, tt := range tests {
+		tx := transaction.Transaction{LockTime: tt.lockTime}
+		if got := tx.IsFinal(10, transaction.LockTimeThreshold+100); got != tt.expected {
+			t.Fatalf("IsFinal() with lock time %d = %v, expected %v", tt.lockTime, got, tt.expected)
+		}
+	}
+}
+
+func TestMultisigCoSign(t *testing.T) {
+	privateKeys := []transaction.PrivateKey{{1}, {2}, {3}}
+	pubKeys := make([]transaction.PubKey, len(privateKeys))
+	for i, privateKey := range privateKeys {
+		_, pubKey := btcec.PrivKeyFromBytes(privateKey[:])
+		copy(pubKeys[i][:], pubKey.SerializeCompressed())
+	}
+
+	script, err := transaction.NewMultisigScript(2, pubKeys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	utxos := []transaction.UTXO{
+		{TxID: transaction.TransactionID{1}, Output: transaction.NewMultisigOutput(30, script)},
+		{TxID: transaction.TransactionID{2}, Output: transaction.NewMultisigHashOutput(30, script.Hash())},
+		{TxID: transaction.TransactionID{3}, Output: transaction.Output{Value: 100, PubKeyHash: transaction.Hash160(pubKeys[0])}},
+	}
+
+	tx, err := transaction.NewMultisigTransaction(utxos, script, transaction.Output{Value: 40, PubKeyHash: transaction.PubKeyHash{9}}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tx.Inputs) != 2 {
+		t.Fatalf("expected only the 2 multisig UTXOs to be spent, got %d inputs", len(tx.Inputs))
+	}
+	if len(tx.Outputs) != 2 || tx.Outputs[1].Type != transaction.OutputTypeMultisigHash || tx.Outputs[1].Value != 10 {
+		t.Fatalf("expected the change to be paid back to the multisig address, got %+v", tx.Outputs)
+	}
+	referenced := []transaction.Output{utxos[0].Output, utxos[1].Output}
+
+	if err := tx.CoSign(privateKeys[0], utxos); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if valid, err := tx.VerifyAllSignatures(referenced); err != nil || valid {
+		t.Fatalf("expected 1 of 2 signatures to be insufficient, got valid=%v err=%v", valid, err)
+	}
+
+	if err := tx.CoSign(privateKeys[2], utxos); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if valid, err := tx.VerifyAllSignatures(referenced); err != nil || !valid {
+		t.Fatalf("expected 2 of 2 signatures to be valid, got valid=%v err=%v", valid, err)
+	}
+
+	if err := tx.CoSign(transaction.PrivateKey{4}, utxos); err != transaction.ErrNotACosigner {
+		t.Fatalf("expected ErrNotACosigner, got %v", err)
+	}
+
+	tampered := tx.Clone()
+	tampered.Outputs[0].Value++
+	if valid, _ := tampered.VerifyAllSignatures(referenced); valid {
+		t.Fatalf("expected signatures to be invalid after changing an output")
+	}
+}
@@ -3,6 +3,8 @@ package konto
 // Asset represents a single unspent output value belonging to an address.
 type Asset struct {
 	Value uint64
+	// Immature is true for coinbase outputs that cannot be spent yet because they have not reached the coinbase maturity.
+	Immature bool
 }
 
 // AssetsResult represents the outcome of an assets query.
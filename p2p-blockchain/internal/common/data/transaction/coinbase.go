@@ -0,0 +1,142 @@
+package transaction
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// coinbaseDataSize is the size of the data in the input of a coinbase transaction:
+	// the block height (8 bytes), the length of the tag (1 byte), the tag and random bytes filling the rest.
+	// The random bytes make the coinbase transaction and thus the block unique.
+	coinbaseDataSize = 100
+	// coinbaseTagOffset is the offset of the tag length in the coinbase data.
+	coinbaseTagOffset = 8
+	// MaxCoinbaseTagSize is the maximum size of the tag in the coinbase data, see NewCoinbaseTransactionWithPayouts.
+	MaxCoinbaseTagSize = 64
+	// MaxCoinbasePayouts is the maximum number of addresses the reward of a coinbase transaction is split among.
+	MaxCoinbasePayouts = 16
+)
+
+var (
+	// ErrInvalidCoinbasePayouts indicates payouts that do not split the whole reward, see ValidateCoinbasePayouts.
+	ErrInvalidCoinbasePayouts = errors.New("invalid coinbase payouts")
+	// ErrCoinbaseTagTooLong indicates a coinbase tag longer than MaxCoinbaseTagSize.
+	ErrCoinbaseTagTooLong = fmt.Errorf("coinbase tag exceeds %d bytes", MaxCoinbaseTagSize)
+)
+
+// CoinbasePayout is the share of the reward of a coinbase transaction paid to an address.
+type CoinbasePayout struct {
+	PubKeyHash PubKeyHash
+	// Percent is the share of the reward in percent [1; 100].
+	Percent uint32
+}
+
+// ValidateCoinbasePayouts checks that there are 1 to MaxCoinbasePayouts payouts, each with a positive share,
+// and that the shares add up to 100 percent. Returns ErrInvalidCoinbasePayouts otherwise.
+func ValidateCoinbasePayouts(payouts []CoinbasePayout) error {
+	if len(payouts) == 0 || len(payouts) > MaxCoinbasePayouts {
+		return fmt.Errorf("%w: expected 1 to %d payouts, got %d", ErrInvalidCoinbasePayouts, MaxCoinbasePayouts, len(payouts))
+	}
+
+	var total uint32
+	for _, payout := range payouts {
+		if payout.Percent == 0 || payout.Percent > 100 {
+			return fmt.Errorf("%w: share of %d percent, must be 1 to 100", ErrInvalidCoinbasePayouts, payout.Percent)
+		}
+		total += payout.Percent
+	}
+	if total != 100 {
+		return fmt.Errorf("%w: shares add up to %d percent instead of 100", ErrInvalidCoinbasePayouts, total)
+	}
+
+	return nil
+}
+
+// ValidateCoinbaseTag checks that the tag fits into the coinbase data. Returns ErrCoinbaseTagTooLong otherwise.
+func ValidateCoinbaseTag(tag []byte) error {
+	if len(tag) > MaxCoinbaseTagSize {
+		return fmt.Errorf("%w: %d bytes", ErrCoinbaseTagTooLong, len(tag))
+	}
+	return nil
+}
+
+// NewCoinbaseTransactionWithPayouts creates a coinbase transaction that splits the block reward among the payouts,
+// one output per payout in the given order. The payouts must be valid, see ValidateCoinbasePayouts.
+// Shares are rounded down, the remainder is paid to the first payout, so the outputs always add up to the reward.
+// The tag is an arbitrary operator-defined message (e.g. the name of a mining team), stored in the coinbase data
+// after the block height, see CoinbaseTag. Longer tags than MaxCoinbaseTagSize are truncated.
+func NewCoinbaseTransactionWithPayouts(payouts []CoinbasePayout, blockReward uint64, height uint64, tag []byte) Transaction {
+	tag = tag[:min(len(tag), MaxCoinbaseTagSize)]
+
+	var data [coinbaseDataSize]byte
+	binary.LittleEndian.PutUint64(data[:], height)
+	data[coinbaseTagOffset] = byte(len(tag))
+	randomOffset := coinbaseTagOffset + 1 + copy(data[coinbaseTagOffset+1:], tag)
+	rand.Read(data[randomOffset:]) //nolint:errcheck
+
+	outputs := make([]Output, 0, len(payouts))
+	var paid uint64
+	for _, payout := range payouts {
+		value := blockReward / 100 * uint64(payout.Percent)
+		value += blockReward % 100 * uint64(payout.Percent) / 100
+		outputs = append(outputs, Output{Value: value, PubKeyHash: payout.PubKeyHash})
+		paid += value
+	}
+	if len(outputs) > 0 {
+		outputs[0].Value += blockReward - paid
+	}
+
+	return Transaction{
+		Inputs: []Input{
+			{
+				PrevTxID:    TransactionID{},
+				OutputIndex: 0xFFFFFFFF,
+				Signature:   data[:],
+				PubKey:      PubKey{},
+			},
+		},
+		Outputs: outputs,
+	}
+}
+
+// CoinbaseTag returns the tag embedded in the coinbase data by NewCoinbaseTransactionWithPayouts.
+// Returns false if the transaction is not a coinbase transaction or the coinbase data does not contain a tag.
+func (tx *Transaction) CoinbaseTag() ([]byte, bool) {
+	if !tx.IsCoinbase() || len(tx.Inputs[0].Signature) <= coinbaseTagOffset {
+		return nil, false
+	}
+
+	data := tx.Inputs[0].Signature
+	tagLength := int(data[coinbaseTagOffset])
+	if tagLength == 0 || tagLength > MaxCoinbaseTagSize || coinbaseTagOffset+1+tagLength > len(data) {
+		return nil, false
+	}
+	return data[coinbaseTagOffset+1 : coinbaseTagOffset+1+tagLength], true
+}
+
+// coinbaseDataSerializedOffset is the offset of the coinbase data in the serialized coinbase transaction:
+// input count (4), previous transaction ID, output index (4) and data length (4).
+const coinbaseDataSerializedOffset = 4 + len(TransactionID{}) + 4 + 4
+
+// SplitCoinbase splits the serialized coinbase transaction around an extranonce of the given size, placed at the start
+// of the random bytes of the coinbase data. Any prefix + extranonce + suffix is a valid serialized coinbase transaction,
+// so mining pools can hand out disjoint search spaces by assigning different extranonces to their workers.
+// The coinbase must have been created by NewCoinbaseTransactionWithPayouts.
+func (tx *Transaction) SplitCoinbase(extranonceSize int) (prefix []byte, suffix []byte, err error) {
+	tag, _ := tx.CoinbaseTag()
+	if !tx.IsCoinbase() || len(tx.Inputs[0].Signature) != coinbaseDataSize {
+		return nil, nil, errors.New("not a coinbase transaction with coinbase data")
+	}
+
+	offset := coinbaseTagOffset + 1 + len(tag)
+	if extranonceSize < 0 || offset+extranonceSize > coinbaseDataSize {
+		return nil, nil, fmt.Errorf("extranonce of %d bytes does not fit into the coinbase data", extranonceSize)
+	}
+
+	serialized := tx.Serialize()
+	start := coinbaseDataSerializedOffset + offset
+	return serialized[:start], serialized[start+extranonceSize:], nil
+}
@@ -5,6 +5,7 @@ type Input struct {
 	OutputIndex uint32
 	// Signature is the digital signature proving ownership of the referenced output.
 	// The referenced output is the combination of PrevTxID and OutputIndex.
+	// Unused if the referenced output is a multisig output, see Signatures.
 	Signature []byte
 	// PubKey is the public key corresponding to the private key that created the signature.
 	// This is also the public key that corresponds to the public key hash in the referenced output.
@@ -12,13 +13,44 @@ type Input struct {
 	//
 	// (In Bitcoin, this is not needed as the public key is included in the scriptSig of the input.)
 	PubKey PubKey
+	// Sequence encodes a relative lock time for the referenced output, see RelativeLockTime.
+	// The zero value requires no minimum age.
+	Sequence uint32
+	// Signatures are the signatures for a multisig output, one entry per public key of the multisig script in the same order.
+	// Keys that did not sign have an empty entry, so each co-signer can add its signature independently, see CoSign.
+	Signatures [][]byte
+	// RedeemScript is the multisig script of a referenced OutputTypeMultisigHash output.
+	// The output only contains the hash of the script, so the script has to be revealed when spending it.
+	// Empty for all other output types.
+	RedeemScript MultisigScript
 }
 
 func (in *Input) Clone() Input {
+	var signatures [][]byte
+	if in.Signatures != nil {
+		signatures = make([][]byte, len(in.Signatures))
+		for i, signature := range in.Signatures {
+			signatures[i] = append([]byte(nil), signature...) //deep copy
+		}
+	}
+
 	return Input{
-		PrevTxID:    in.PrevTxID,
-		OutputIndex: in.OutputIndex,
-		Signature:   append([]byte(nil), in.Signature...), //deep copy
-		PubKey:      in.PubKey,
+		PrevTxID:     in.PrevTxID,
+		OutputIndex:  in.OutputIndex,
+		Signature:    append([]byte(nil), in.Signature...), //deep copy
+		PubKey:       in.PubKey,
+		Sequence:     in.Sequence,
+		Signatures:   signatures,
+		RedeemScript: in.RedeemScript.Clone(),
+	}
+}
+
+// AddressHash returns the hash of the address that spends the referenced output with this input, if it can be derived from the input.
+// This is the multisig script hash if a redeem script is given and the hash of PubKey otherwise.
+// Inputs spending OutputTypeMultisig outputs contain neither, see Output.AddressHash for those.
+func (in *Input) AddressHash() PubKeyHash {
+	if !in.RedeemScript.IsEmpty() {
+		return in.RedeemScript.Hash()
 	}
+	return Hash160(in.PubKey)
 }
@@ -15,7 +15,12 @@ type PrivateKey [common.PrivateKeySize]byte
 
 // Hash160 Uses SHA256 to double Hash the 33 Byte public key to a 20 Byte public key hash also known as Address
 func Hash160(pub PubKey) PubKeyHash {
-	sha := sha256.Sum256(pub[:])
+	return hash160(pub[:])
+}
+
+// hash160 double hashes the data with SHA256 and truncates the result to a 20 Byte hash
+func hash160(data []byte) PubKeyHash {
+	sha := sha256.Sum256(data)
 
 	double := sha256.Sum256(sha[:])
 
@@ -0,0 +1,47 @@
+package transaction
+
+const (
+	// LockTimeThreshold separates the two meanings of Transaction.LockTime.
+	// Lock times below the threshold are block heights, lock times at or above it are unix timestamps.
+	LockTimeThreshold = 500_000_000
+
+	// SequenceLockTimeDisableFlag disables the relative lock time of an input if set in Input.Sequence.
+	SequenceLockTimeDisableFlag uint32 = 1 << 31
+	// SequenceLockTimeTypeFlag marks the relative lock time of an input as time based if set in Input.Sequence.
+	// Otherwise the relative lock time is a number of blocks.
+	SequenceLockTimeTypeFlag uint32 = 1 << 22
+	// SequenceLockTimeMask extracts the relative lock time value from Input.Sequence.
+	SequenceLockTimeMask uint32 = 0x0000ffff
+	// SequenceLockTimeGranularity is the number of bits time based relative lock times are shifted by.
+	// Time based relative lock times are given in units of 512 seconds.
+	SequenceLockTimeGranularity = 9
+)
+
+// IsFinal reports whether the lock time of the transaction allows its inclusion in the block at the given height.
+// medianTimePast is the median-time-past of the previous block, which is used instead of the block timestamp,
+// so that miners cannot include time locked transactions early by setting a timestamp in the future.
+func (tx *Transaction) IsFinal(height uint64, medianTimePast int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+
+	if tx.LockTime < LockTimeThreshold {
+		return uint64(tx.LockTime) < height
+	}
+	return int64(tx.LockTime) < medianTimePast
+}
+
+// RelativeLockTime decodes the relative lock time of the input from its sequence number.
+// Returns the number of blocks or the number of seconds the referenced output must be old before the input can spend it.
+// At most one of both is non-zero. Both are zero if the relative lock time is disabled.
+func (in *Input) RelativeLockTime() (blocks uint64, seconds int64) {
+	if in.Sequence&SequenceLockTimeDisableFlag != 0 {
+		return 0, 0
+	}
+
+	value := in.Sequence & SequenceLockTimeMask
+	if in.Sequence&SequenceLockTimeTypeFlag != 0 {
+		return 0, int64(value) << SequenceLockTimeGranularity
+	}
+	return uint64(value), 0
+}
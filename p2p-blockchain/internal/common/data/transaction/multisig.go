@@ -0,0 +1,190 @@
+package transaction
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+var (
+	ErrInvalidMultisigScript = errors.New("invalid multisig script")
+	ErrNotACosigner          = errors.New("private key is not part of the multisig script")
+)
+
+// MaxMultisigKeys is the maximum number of public keys (N) of a multisig script.
+const MaxMultisigKeys = 15
+
+// MultisigScript is the M-of-N condition of a multisig output.
+// An output locked by the script can be spent with valid signatures of at least Required of the PubKeys.
+type MultisigScript struct {
+	// Required is the number of signatures (M) needed to spend the output.
+	Required uint8
+	// PubKeys are the public keys (N) allowed to sign. Their order is part of the script.
+	PubKeys []PubKey
+}
+
+// NewMultisigScript creates an M-of-N multisig script and checks it with Validate.
+func NewMultisigScript(required int, pubKeys []PubKey) (MultisigScript, error) {
+	if required < 0 || required > MaxMultisigKeys {
+		return MultisigScript{}, ErrInvalidMultisigScript
+	}
+
+	script := MultisigScript{
+		Required: uint8(required),
+		PubKeys:  append([]PubKey(nil), pubKeys...),
+	}
+	if err := script.Validate(); err != nil {
+		return MultisigScript{}, err
+	}
+	return script, nil
+}
+
+// Validate checks that 1 <= M <= N <= MaxMultisigKeys and that every public key is a valid, distinct key.
+func (s *MultisigScript) Validate() error {
+	if s.Required == 0 || int(s.Required) > len(s.PubKeys) || len(s.PubKeys) > MaxMultisigKeys {
+		return ErrInvalidMultisigScript
+	}
+
+	seen := make(map[PubKey]struct{}, len(s.PubKeys))
+	for _, pubKey := range s.PubKeys {
+		if _, ok := seen[pubKey]; ok {
+			return ErrInvalidMultisigScript
+		}
+		seen[pubKey] = struct{}{}
+
+		if _, err := btcec.ParsePubKey(pubKey[:]); err != nil {
+			return ErrInvalidMultisigScript
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the script is the zero value, i.e. no script is set.
+func (s *MultisigScript) IsEmpty() bool {
+	return s.Required == 0 && len(s.PubKeys) == 0
+}
+
+// Serialize returns the binary encoding of the script: [M uint8][N uint8][N public keys].
+// The encoding is also known as the redeem script and is hashed to compute the multisig address, see Hash.
+func (s *MultisigScript) Serialize() []byte {
+	buf := new(bytes.Buffer)
+	writeMultisigScript(buf, *s)
+	return buf.Bytes()
+}
+
+// DeserializeMultisigScript decodes a script produced by Serialize.
+func DeserializeMultisigScript(data []byte) (MultisigScript, error) {
+	r := bytes.NewReader(data)
+	script, err := readMultisigScript(r)
+	if err != nil || r.Len() != 0 {
+		return MultisigScript{}, ErrInvalidMultisigScript
+	}
+	return script, nil
+}
+
+// Hash returns the 20 byte hash of the serialized script.
+// Outputs of type OutputTypeMultisigHash are locked to this hash.
+func (s *MultisigScript) Hash() PubKeyHash {
+	return hash160(s.Serialize())
+}
+
+// IndexOf returns the position of the public key in the script or -1 if the key is not part of the script.
+func (s *MultisigScript) IndexOf(pubKey PubKey) int {
+	for i, key := range s.PubKeys {
+		if key == pubKey {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MultisigScript) Clone() MultisigScript {
+	return MultisigScript{
+		Required: s.Required,
+		PubKeys:  append([]PubKey(nil), s.PubKeys...),
+	}
+}
+
+// serializedSize returns the size in bytes of the encoding written by writeMultisigScript.
+func (s *MultisigScript) serializedSize() int {
+	return 1 + 1 + len(s.PubKeys)*len(PubKey{})
+}
+
+func writeMultisigScript(buf *bytes.Buffer, s MultisigScript) {
+	buf.WriteByte(s.Required)
+	buf.WriteByte(uint8(len(s.PubKeys)))
+	for _, pubKey := range s.PubKeys {
+		buf.Write(pubKey[:])
+	}
+}
+
+func readMultisigScript(r *bytes.Reader) (MultisigScript, error) {
+	s := MultisigScript{}
+
+	var err error
+	if s.Required, err = r.ReadByte(); err != nil {
+		return MultisigScript{}, ErrMalformedTransaction
+	}
+	count, err := r.ReadByte()
+	if err != nil {
+		return MultisigScript{}, ErrMalformedTransaction
+	}
+
+	if count > 0 {
+		s.PubKeys = make([]PubKey, count)
+	}
+	for i := range s.PubKeys {
+		if _, err := io.ReadFull(r, s.PubKeys[i][:]); err != nil {
+			return MultisigScript{}, ErrMalformedTransaction
+		}
+	}
+	return s, nil
+}
+
+// spendingMultisigScript returns the multisig script that has to be satisfied to spend the referenced output with the input.
+// Returns false if the referenced output is not a multisig output.
+// For OutputTypeMultisigHash outputs the script is revealed in the input, its hash is checked by the transaction validation.
+func spendingMultisigScript(input Input, referenced Output) (MultisigScript, bool) {
+	switch referenced.Type {
+	case OutputTypeMultisig:
+		return referenced.Multisig, true
+	case OutputTypeMultisigHash:
+		return input.RedeemScript, true
+	default:
+		return MultisigScript{}, false
+	}
+}
+
+// verifyMultisig checks the signatures of a multisig input.
+// signatures are aligned with the public keys of the script, keys that did not sign have an empty signature.
+// All given signatures must be valid and at least script.Required signatures must be given.
+func verifyMultisig(sighash []byte, script MultisigScript, signatures [][]byte) (bool, error) {
+	if script.Validate() != nil || len(signatures) != len(script.PubKeys) {
+		return false, nil
+	}
+
+	signed := 0
+	for i, signature := range signatures {
+		if len(signature) == 0 {
+			continue
+		}
+
+		pubKey, err := btcec.ParsePubKey(script.PubKeys[i][:])
+		if err != nil {
+			return false, err
+		}
+		sig, err := ecdsa.ParseDERSignature(signature)
+		if err != nil {
+			return false, err
+		}
+		if !sig.Verify(sighash, pubKey) {
+			return false, nil
+		}
+		signed++
+	}
+
+	return signed >= int(script.Required), nil
+}
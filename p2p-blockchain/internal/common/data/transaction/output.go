@@ -1,17 +1,98 @@
 package transaction
 
+// OutputType determines the condition under which an output can be spent.
+type OutputType uint8
+
+const (
+	// OutputTypePubKeyHash outputs can be spent with a signature of the key whose public key hashes to Output.PubKeyHash.
+	OutputTypePubKeyHash OutputType = 0
+	// OutputTypeMultisig outputs contain an M-of-N Output.Multisig script and can be spent with M signatures of its keys.
+	OutputTypeMultisig OutputType = 1
+	// OutputTypeMultisigHash outputs only contain the hash of an M-of-N multisig script in Output.PubKeyHash.
+	// The script itself is revealed by the spending input, see Input.RedeemScript.
+	OutputTypeMultisigHash OutputType = 2
+	// OutputTypeData outputs carry up to MaxDataSize bytes of arbitrary Output.Data, e.g. the hash of a document.
+	// They are provably unspendable and therefore never added to the UTXO set.
+	OutputTypeData OutputType = 3
+)
+
+// MaxDataSize is the maximum number of bytes an OutputTypeData output can carry.
+const MaxDataSize = 80
+
 type Output struct {
 	// Value is the amount of cryptocurrency in smallest units.
 	// The smallest unit is 1 V$Goin. This means fractional amounts of V$Goins are not allowed.
 	Value uint64
+	// Type is the kind of condition that locks this output. The zero value is OutputTypePubKeyHash.
+	Type OutputType
 	// PubKeyHash is the 20 bytes hash of the public key that can spend this output.
 	// Also known as the "vs address".
+	// For OutputTypeMultisigHash outputs this is the hash of the multisig script instead, see MultisigScript.Hash.
+	// Unused for OutputTypeMultisig outputs.
 	PubKeyHash PubKeyHash
+	// Multisig is the M-of-N script that locks an OutputTypeMultisig output.
+	// Unused for all other output types.
+	Multisig MultisigScript
+	// Data is the arbitrary payload of an OutputTypeData output.
+	// Unused for all other output types.
+	Data []byte
+}
+
+// NewMultisigOutput creates an output that is locked by the given multisig script.
+func NewMultisigOutput(value uint64, script MultisigScript) Output {
+	return Output{
+		Value:    value,
+		Type:     OutputTypeMultisig,
+		Multisig: script.Clone(),
+	}
+}
+
+// NewMultisigHashOutput creates an output that is locked by the multisig script with the given hash.
+func NewMultisigHashOutput(value uint64, scriptHash PubKeyHash) Output {
+	return Output{
+		Value:      value,
+		Type:       OutputTypeMultisigHash,
+		PubKeyHash: scriptHash,
+	}
+}
+
+// NewDataOutput creates an unspendable output that carries the given data.
+// The output has no value, the data is anchored in the chain by the transaction containing it.
+func NewDataOutput(data []byte) Output {
+	return Output{
+		Type: OutputTypeData,
+		Data: append([]byte(nil), data...),
+	}
+}
+
+// IsUnspendable reports whether the output can never be spent, i.e. it is an OutputTypeData output.
+func (out *Output) IsUnspendable() bool {
+	return out.Type == OutputTypeData
+}
+
+// AddressHash returns the hash the output is paid to.
+// This is the public key hash for OutputTypePubKeyHash outputs and the multisig script hash for both multisig output types.
+// So both multisig output types with the same script belong to the same multisig address.
+func (out *Output) AddressHash() PubKeyHash {
+	if out.Type == OutputTypeMultisig {
+		return out.Multisig.Hash()
+	}
+	return out.PubKeyHash
 }
 
 func (out *Output) Clone() Output {
 	return Output{
 		Value:      out.Value,
+		Type:       out.Type,
 		PubKeyHash: out.PubKeyHash,
+		Multisig:   out.Multisig.Clone(),
+		Data:       cloneData(out.Data),
+	}
+}
+
+func cloneData(data []byte) []byte {
+	if data == nil {
+		return nil
 	}
+	return append([]byte(nil), data...) //deep copy
 }
@@ -0,0 +1,113 @@
+package transaction
+
+import "errors"
+
+// SequenceReplaceableFlag in Input.Sequence signals that the transaction may be replaced in the mempool
+// by a conflicting transaction paying a higher fee (replace-by-fee), see SignalsReplaceability.
+// The flag is only mempool policy and does not affect the relative lock time of the input.
+const SequenceReplaceableFlag uint32 = 1 << 30
+
+var (
+	// ErrReplacementFeeTooLow indicates a replacement transaction that does not pay a higher fee than the original transaction.
+	ErrReplacementFeeTooLow = errors.New("replacement must pay a higher fee than the original transaction")
+	// ErrOriginalUTXONotFound indicates that a UTXO spent by the original transaction is missing from the given UTXOs.
+	ErrOriginalUTXONotFound = errors.New("UTXO spent by the original transaction not found")
+)
+
+// SignalsReplaceability reports whether at least one input of the transaction sets SequenceReplaceableFlag.
+// Only mempool transactions signaling replaceability can be replaced by a conflicting transaction.
+func (tx *Transaction) SignalsReplaceability() bool {
+	for _, input := range tx.Inputs {
+		if input.Sequence&SequenceReplaceableFlag != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// NewReplaceableTransactionToOutput is NewTransactionToOutput, but the transaction signals replaceability,
+// so its fee can be bumped later with NewReplacementTransaction.
+func NewReplaceableTransactionToOutput(
+	utxos []UTXO,
+	recipient Output,
+	fee uint64,
+	privateKey PrivateKey,
+) (*Transaction, error) {
+	return newTransactionToOutput(utxos, recipient, fee, privateKey, SequenceReplaceableFlag)
+}
+
+// NewReplacementTransaction creates and signs a transaction that replaces the original transaction of the private key
+// with a higher fee. It spends the same UTXOs and pays the same recipients, only the change back to the private key is reduced.
+// If the change does not cover the higher fee, further UTXOs are added.
+// utxos must contain the UTXOs spent by the original transaction. The replacement signals replaceability as well.
+// Returns ErrReplacementFeeTooLow if the fee is not higher than the fee of the original transaction.
+func NewReplacementTransaction(
+	original *Transaction,
+	utxos []UTXO,
+	fee uint64,
+	privateKey PrivateKey,
+) (*Transaction, error) {
+	ownAddress := Hash160(pubFromPriv(privateKey))
+	tx := &Transaction{LockTime: original.LockTime}
+
+	selected := make([]UTXO, 0, len(original.Inputs))
+	var total uint64
+	for _, input := range original.Inputs {
+		output, ok := findUTXO(input.PrevTxID, input.OutputIndex, utxos)
+		if !ok {
+			return nil, ErrOriginalUTXONotFound
+		}
+		selected = append(selected, UTXO{TxID: input.PrevTxID, OutputIndex: input.OutputIndex, Output: output})
+		total += output.Value
+		tx.Inputs = append(tx.Inputs, Input{
+			PrevTxID:    input.PrevTxID,
+			OutputIndex: input.OutputIndex,
+			Sequence:    input.Sequence | SequenceReplaceableFlag,
+		})
+	}
+
+	var originalOutputSum, paid uint64
+	for _, output := range original.Outputs {
+		originalOutputSum += output.Value
+		if output.Type == OutputTypePubKeyHash && output.PubKeyHash == ownAddress {
+			// Change, recalculated below
+			continue
+		}
+		tx.Outputs = append(tx.Outputs, output.Clone())
+		paid += output.Value
+	}
+
+	if fee <= total-originalOutputSum {
+		return nil, ErrReplacementFeeTooLow
+	}
+
+	if total < paid+fee {
+		remaining := make([]UTXO, 0, len(utxos))
+		for _, u := range utxos {
+			if _, ok := findUTXO(u.TxID, u.OutputIndex, selected); !ok {
+				remaining = append(remaining, u)
+			}
+		}
+
+		additional, additionalTotal := selectUTXOs(remaining, paid+fee-total)
+		if total+additionalTotal < paid+fee {
+			return nil, ErrInsufficientFunds
+		}
+		for _, u := range additional {
+			tx.addInput(u)
+			tx.Inputs[len(tx.Inputs)-1].Sequence = SequenceReplaceableFlag
+		}
+		selected = append(selected, additional...)
+		total += additionalTotal
+	}
+
+	if change := total - paid - fee; change > 0 {
+		tx.addChange(change, privateKey)
+	}
+
+	if err := tx.Sign(privateKey, selected); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
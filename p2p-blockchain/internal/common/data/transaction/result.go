@@ -8,6 +8,16 @@ type TransactionResult struct {
 	TransactionID string
 }
 
+// MultisigTransactionResult contains the result of creating or co-signing a multisig transaction.
+type MultisigTransactionResult struct {
+	TransactionResult
+	// Complete is true if the transaction has enough signatures and was broadcast.
+	Complete bool
+	// SerializedTransaction is the hex encoded transaction with all signatures so far.
+	// If the transaction is not complete yet, it has to be passed on to the next co-signer.
+	SerializedTransaction string
+}
+
 // TransactionErrorCode categorizes transaction creation failures.
 type TransactionErrorCode int
 
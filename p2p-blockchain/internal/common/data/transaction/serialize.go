@@ -0,0 +1,214 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var (
+	ErrMalformedTransaction = errors.New("malformed transaction encoding")
+)
+
+// MaxTransactionSize is the maximum serialized size in bytes of a transaction that is admitted to the mempool.
+const MaxTransactionSize = 10_000
+
+// Serialize returns the canonical binary encoding of the transaction.
+// This is the same encoding that is hashed to compute the TransactionID.
+func (tx *Transaction) Serialize() []byte {
+	return serializeTransaction(tx).Bytes()
+}
+
+// SerializedSize returns the size in bytes of the canonical binary encoding of the transaction.
+// Equal to len(tx.Serialize()) without encoding the transaction.
+func (tx *Transaction) SerializedSize() int {
+	size := 4 + 4 + 4 // input count, output count and lock time
+	for _, in := range tx.Inputs {
+		size += minInputSize + len(in.Signature) + len(in.RedeemScript.PubKeys)*len(PubKey{})
+		for _, signature := range in.Signatures {
+			size += 4 + len(signature)
+		}
+	}
+	for _, out := range tx.Outputs {
+		size += out.serializedSize()
+	}
+
+	return size
+}
+
+// Serialize returns the binary encoding of the output as it is used within an encoded transaction.
+func (out *Output) Serialize() []byte {
+	buf := new(bytes.Buffer)
+	serializeOutput(buf, *out)
+	return buf.Bytes()
+}
+
+// DeserializeOutput decodes a single output produced by Output.Serialize from r.
+// Bytes following the output are left unread in r.
+func DeserializeOutput(r *bytes.Reader) (Output, error) {
+	return deserializeOutput(r)
+}
+
+// serializedSize returns the size in bytes of the encoding of the output.
+func (out *Output) serializedSize() int {
+	switch out.Type {
+	case OutputTypeMultisig:
+		return 8 + 1 + out.Multisig.serializedSize()
+	case OutputTypeData:
+		return 8 + 1 + 1 + len(out.Data)
+	default:
+		return pubKeyHashOutputSize
+	}
+}
+
+// DeserializeTransaction decodes a single transaction from r.
+// The encoding must have been produced by Serialize.
+// Bytes following the transaction are left unread in r.
+func DeserializeTransaction(r *bytes.Reader) (Transaction, error) {
+	tx := Transaction{}
+
+	inputCount, err := readLength(r, minInputSize)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx.Inputs = make([]Input, inputCount)
+	for i := range tx.Inputs {
+		tx.Inputs[i], err = deserializeInput(r)
+		if err != nil {
+			return Transaction{}, err
+		}
+	}
+
+	outputCount, err := readLength(r, minOutputSize)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx.Outputs = make([]Output, outputCount)
+	for i := range tx.Outputs {
+		tx.Outputs[i], err = deserializeOutput(r)
+		if err != nil {
+			return Transaction{}, err
+		}
+	}
+
+	if err := binary.Read(r, binary.LittleEndian, &tx.LockTime); err != nil {
+		return Transaction{}, ErrMalformedTransaction
+	}
+
+	return tx, nil
+}
+
+const (
+	// minInputSize is the size of an encoded input with an empty signature, no multisig signatures and an empty redeem script.
+	minInputSize = len(TransactionID{}) + 4 + 4 + len(PubKey{}) + 4 + 4 + 2
+	// minOutputSize is the size of an encoded data output without data. All other outputs are larger.
+	minOutputSize = 8 + 1 + 1
+	// pubKeyHashOutputSize is the size of an encoded output with a public key hash.
+	pubKeyHashOutputSize = 8 + 1 + len(PubKeyHash{})
+)
+
+func deserializeInput(r *bytes.Reader) (Input, error) {
+	in := Input{}
+
+	if _, err := io.ReadFull(r, in.PrevTxID[:]); err != nil {
+		return Input{}, ErrMalformedTransaction
+	}
+	if err := binary.Read(r, binary.LittleEndian, &in.OutputIndex); err != nil {
+		return Input{}, ErrMalformedTransaction
+	}
+
+	signatureLength, err := readLength(r, 1)
+	if err != nil {
+		return Input{}, err
+	}
+	in.Signature = make([]byte, signatureLength)
+	if _, err := io.ReadFull(r, in.Signature); err != nil {
+		return Input{}, ErrMalformedTransaction
+	}
+
+	if _, err := io.ReadFull(r, in.PubKey[:]); err != nil {
+		return Input{}, ErrMalformedTransaction
+	}
+	if err := binary.Read(r, binary.LittleEndian, &in.Sequence); err != nil {
+		return Input{}, ErrMalformedTransaction
+	}
+
+	signatureCount, err := readLength(r, 4)
+	if err != nil {
+		return Input{}, err
+	}
+	if signatureCount > 0 {
+		in.Signatures = make([][]byte, signatureCount)
+	}
+	for i := range in.Signatures {
+		length, err := readLength(r, 1)
+		if err != nil {
+			return Input{}, err
+		}
+		in.Signatures[i] = make([]byte, length)
+		if _, err := io.ReadFull(r, in.Signatures[i]); err != nil {
+			return Input{}, ErrMalformedTransaction
+		}
+	}
+
+	if in.RedeemScript, err = readMultisigScript(r); err != nil {
+		return Input{}, err
+	}
+
+	return in, nil
+}
+
+func deserializeOutput(r *bytes.Reader) (Output, error) {
+	out := Output{}
+
+	if err := binary.Read(r, binary.LittleEndian, &out.Value); err != nil {
+		return Output{}, ErrMalformedTransaction
+	}
+	outputType, err := r.ReadByte()
+	if err != nil {
+		return Output{}, ErrMalformedTransaction
+	}
+	out.Type = OutputType(outputType)
+
+	switch out.Type {
+	case OutputTypePubKeyHash, OutputTypeMultisigHash:
+		if _, err := io.ReadFull(r, out.PubKeyHash[:]); err != nil {
+			return Output{}, ErrMalformedTransaction
+		}
+	case OutputTypeMultisig:
+		if out.Multisig, err = readMultisigScript(r); err != nil {
+			return Output{}, err
+		}
+	case OutputTypeData:
+		length, err := r.ReadByte()
+		if err != nil {
+			return Output{}, ErrMalformedTransaction
+		}
+		out.Data = make([]byte, length)
+		if _, err := io.ReadFull(r, out.Data); err != nil {
+			return Output{}, ErrMalformedTransaction
+		}
+	default:
+		return Output{}, ErrMalformedTransaction
+	}
+
+	return out, nil
+}
+
+// readLength reads a uint32 length prefix and checks that r still contains at least length * minElementSize bytes.
+// This prevents huge allocations caused by corrupted or malicious length prefixes.
+func readLength(r *bytes.Reader, minElementSize int) (int, error) {
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return 0, ErrMalformedTransaction
+	}
+
+	if uint64(length)*uint64(minElementSize) > uint64(r.Len()) {
+		return 0, ErrMalformedTransaction
+	}
+
+	return int(length), nil
+}
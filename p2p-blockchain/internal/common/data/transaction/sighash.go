@@ -8,7 +8,8 @@ import (
 )
 
 // SigHash computes the Hash of a Transaction used for the Signature of an Input.
-// It follows the SIGHASH_ALL scheme, including all inputs and outputs
+// It follows the SIGHASH_ALL scheme, including all inputs (with their sequence numbers), outputs and the lock time.
+// Signatures and redeem scripts of the inputs are not included, so all co-signers of a multisig input sign the same hash.
 func (tx *Transaction) SigHash(inputIndex int, referenced Output) ([]byte, error) {
 	if inputIndex >= len(tx.Inputs) {
 		return nil, errors.New("input index out of range")
@@ -20,6 +21,8 @@ func (tx *Transaction) SigHash(inputIndex int, referenced Output) ([]byte, error
 
 	addOutputList(buf, tx)
 
+	writeUint32(buf, tx.LockTime)
+
 	return doubleSHA256Hash(buf)
 }
 
@@ -38,7 +41,7 @@ func addOutputList(buf *bytes.Buffer, tx *Transaction) {
 
 func addOutput(buf *bytes.Buffer, out Output) {
 	writeUint64(buf, out.Value)
-	writeBytes(buf, out.PubKeyHash[:])
+	writeLockingScript(buf, out)
 }
 
 func addInputList(buf *bytes.Buffer, inputIndex int, referenced Output, tx *Transaction) {
@@ -53,11 +56,12 @@ func addInput(buf *bytes.Buffer, referenced Output, in Input, toBeSigned bool) {
 	buf.Write(in.PrevTxID[:])
 	writeUint32(buf, in.OutputIndex)
 	if toBeSigned {
-		writeBytes(buf, referenced.PubKeyHash[:])
+		writeLockingScript(buf, referenced)
 		writeUint64(buf, referenced.Value)
 	} else {
 		writeUint64(buf, uint64(0))
 	}
+	writeUint32(buf, in.Sequence)
 }
 
 // Helper is used to ignore Error for binary.Write() since bytes.Buffer doesnt fail
@@ -32,19 +32,65 @@ func (tx *Transaction) signInput(privateKey PrivateKey, utxos []UTXO, inputIndex
 		return err
 	}
 
-	privKey, pubKey := btcec.PrivKeyFromBytes(privateKey[:])
+	input.Signature = signHash(privateKey, sighash)
+	input.PubKey = pubFromPriv(privateKey)
 
-	sig := ecdsa.Sign(privKey, sighash)
+	return nil
+}
 
-	// DER encode
-	derSig := sig.Serialize()
-	input.Signature = derSig
+// CoSign adds the signature of the private key to all inputs that spend a multisig output the key is part of.
+// The signature is placed at the position of the key in the multisig script, signatures of the other co-signers are kept.
+// Inputs that do not spend a multisig output of the key are left unchanged.
+// Returns ErrNotACosigner if the key is not part of the multisig script of any input.
+func (tx *Transaction) CoSign(privateKey PrivateKey, utxos []UTXO) error {
+	pubKey := pubFromPriv(privateKey)
+	signed := false
+
+	for i := range tx.Inputs {
+		input := &tx.Inputs[i]
+
+		referenced, ok := findUTXO(input.PrevTxID, input.OutputIndex, utxos)
+		if !ok {
+			return errors.New("UTXO not found")
+		}
+
+		script, ok := spendingMultisigScript(*input, referenced)
+		if !ok {
+			continue
+		}
+		keyIndex := script.IndexOf(pubKey)
+		if keyIndex < 0 {
+			continue
+		}
+
+		sighash, err := tx.SigHash(i, referenced)
+		if err != nil {
+			return err
+		}
 
-	copy(input.PubKey[:], pubKey.SerializeCompressed())
+		if len(input.Signatures) != len(script.PubKeys) {
+			input.Signatures = make([][]byte, len(script.PubKeys))
+		}
+		input.Signatures[keyIndex] = signHash(privateKey, sighash)
+		signed = true
+	}
 
+	if !signed {
+		return ErrNotACosigner
+	}
 	return nil
 }
 
+// signHash signs the hash with the private key and returns the DER encoded signature.
+func signHash(privateKey PrivateKey, hash []byte) []byte {
+	privKey, _ := btcec.PrivKeyFromBytes(privateKey[:])
+
+	sig := ecdsa.Sign(privKey, hash)
+
+	// DER encode
+	return sig.Serialize()
+}
+
 func findUTXO(id TransactionID, index uint32, utxos []UTXO) (Output, bool) {
 	for _, u := range utxos {
 		if u.TxID == id && u.OutputIndex == index {
@@ -2,7 +2,6 @@ package transaction
 
 import (
 	"bytes"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
@@ -17,6 +16,9 @@ var (
 type Transaction struct {
 	Inputs  []Input
 	Outputs []Output
+	// LockTime is the block height (below LockTimeThreshold) or unix timestamp after which the transaction can be included in a block, see IsFinal.
+	// The zero value does not lock the transaction.
+	LockTime uint32
 }
 
 func NewTransaction(
@@ -26,20 +28,38 @@ func NewTransaction(
 	fee uint64,
 	privateKey PrivateKey,
 ) (*Transaction, error) {
+	return NewTransactionToOutput(utxos, Output{Value: amount, PubKeyHash: toPubKeyHash}, fee, privateKey)
+}
 
-	selected, total := selectUTXOs(utxos, amount+fee)
-	if total < amount+fee {
+// NewTransactionToOutput creates and signs a transaction that pays the given recipient output from the UTXOs of the private key.
+// Use this instead of NewTransaction to pay to a multisig address, see NewMultisigHashOutput.
+// The change is paid back to the public key hash of the private key.
+func NewTransactionToOutput(
+	utxos []UTXO,
+	recipient Output,
+	fee uint64,
+	privateKey PrivateKey,
+) (*Transaction, error) {
+	return newTransactionToOutput(utxos, recipient, fee, privateKey, 0)
+}
+
+// newTransactionToOutput creates and signs a transaction like NewTransactionToOutput, all inputs have the given sequence.
+func newTransactionToOutput(utxos []UTXO, recipient Output, fee uint64, privateKey PrivateKey, sequence uint32) (*Transaction, error) {
+	selected, total := selectUTXOs(utxos, recipient.Value+fee)
+	if total < recipient.Value+fee {
 		return &Transaction{}, ErrInsufficientFunds
 	}
-	change := total - amount - fee
+	change := total - recipient.Value - fee
 
 	tx := fillInTransactionData(
 		selected,
-		amount,
-		toPubKeyHash,
+		recipient,
 		privateKey,
 		change,
 	)
+	for i := range tx.Inputs {
+		tx.Inputs[i].Sequence = sequence
+	}
 
 	if err := tx.Sign(privateKey, selected); err != nil {
 		return nil, err
@@ -48,12 +68,60 @@ func NewTransaction(
 	return tx, nil
 }
 
-func fillInTransactionData(selected []UTXO, amount uint64, toPubKeyHash PubKeyHash, privateKey PrivateKey, change uint64) *Transaction {
+// NewMultisigTransaction creates an unsigned transaction that pays the given recipient output from UTXOs locked by the multisig script.
+// UTXOs of other addresses are ignored. The change is paid back to the multisig address of the script (OutputTypeMultisigHash).
+// The transaction has to be signed by at least script.Required co-signers before it is valid, see CoSign.
+func NewMultisigTransaction(
+	utxos []UTXO,
+	script MultisigScript,
+	recipient Output,
+	fee uint64,
+) (*Transaction, error) {
+	if err := script.Validate(); err != nil {
+		return nil, err
+	}
+
+	scriptHash := script.Hash()
+	owned := make([]UTXO, 0, len(utxos))
+	for _, u := range utxos {
+		if u.Output.Type != OutputTypePubKeyHash && u.Output.AddressHash() == scriptHash {
+			owned = append(owned, u)
+		}
+	}
+
+	selected, total := selectUTXOs(owned, recipient.Value+fee)
+	if total < recipient.Value+fee {
+		return &Transaction{}, ErrInsufficientFunds
+	}
+	change := total - recipient.Value - fee
+
+	tx := &Transaction{}
+	for _, u := range selected {
+		input := Input{
+			PrevTxID:    u.TxID,
+			OutputIndex: u.OutputIndex,
+			Signatures:  make([][]byte, len(script.PubKeys)),
+		}
+		if u.Output.Type == OutputTypeMultisigHash {
+			input.RedeemScript = script.Clone()
+		}
+		tx.Inputs = append(tx.Inputs, input)
+	}
+
+	tx.Outputs = append(tx.Outputs, recipient)
+	if change > 0 {
+		tx.Outputs = append(tx.Outputs, NewMultisigHashOutput(change, scriptHash))
+	}
+
+	return tx, nil
+}
+
+func fillInTransactionData(selected []UTXO, recipient Output, privateKey PrivateKey, change uint64) *Transaction {
 	tx := &Transaction{}
 
 	tx.addUnsignedInputs(selected)
 
-	tx.addOutput(amount, toPubKeyHash)
+	tx.Outputs = append(tx.Outputs, recipient)
 	if change > 0 {
 		tx.addChange(change, privateKey)
 	}
@@ -70,13 +138,6 @@ func (tx *Transaction) addChange(change uint64, privateKey PrivateKey) {
 	})
 }
 
-func (tx *Transaction) addOutput(amount uint64, toPubKeyHash PubKeyHash) {
-	tx.Outputs = append(tx.Outputs, Output{
-		Value:      amount,
-		PubKeyHash: toPubKeyHash,
-	})
-}
-
 func (tx *Transaction) addUnsignedInputs(selected []UTXO) {
 	for _, u := range selected {
 		tx.addInput(u)
@@ -91,7 +152,7 @@ func (tx *Transaction) addInput(u UTXO) {
 }
 
 func (tx *Transaction) Clone() *Transaction {
-	clone := &Transaction{}
+	clone := &Transaction{LockTime: tx.LockTime}
 
 	// Deep copy inputs
 	clone.Inputs = make([]Input, len(tx.Inputs))
@@ -130,13 +191,33 @@ func serializeTransaction(tx *Transaction) *bytes.Buffer {
 	buf := new(bytes.Buffer)
 	serializeInputs(tx, buf)
 	serializeOutputs(tx, buf)
+	writeUint32(buf, tx.LockTime)
 	return buf
 }
 
 func serializeOutputs(tx *Transaction, buf *bytes.Buffer) {
 	writeUint32(buf, uint32(len(tx.Outputs)))
 	for _, out := range tx.Outputs {
-		writeUint64(buf, out.Value)
+		serializeOutput(buf, out)
+	}
+}
+
+func serializeOutput(buf *bytes.Buffer, out Output) {
+	writeUint64(buf, out.Value)
+	writeLockingScript(buf, out)
+}
+
+// writeLockingScript writes the output type followed by the public key hash, the multisig script for bare multisig outputs
+// or the length prefixed data for data outputs.
+func writeLockingScript(buf *bytes.Buffer, out Output) {
+	buf.WriteByte(byte(out.Type))
+	switch out.Type {
+	case OutputTypeMultisig:
+		writeMultisigScript(buf, out.Multisig)
+	case OutputTypeData:
+		buf.WriteByte(uint8(len(out.Data)))
+		buf.Write(out.Data)
+	default:
 		buf.Write(out.PubKeyHash[:])
 	}
 }
@@ -154,6 +235,13 @@ func serializeInput(buf *bytes.Buffer, in Input) {
 	writeUint32(buf, uint32(len(in.Signature)))
 	writeBytes(buf, in.Signature)
 	buf.Write(in.PubKey[:])
+	writeUint32(buf, in.Sequence)
+	writeUint32(buf, uint32(len(in.Signatures)))
+	for _, signature := range in.Signatures {
+		writeUint32(buf, uint32(len(signature)))
+		writeBytes(buf, signature)
+	}
+	writeMultisigScript(buf, in.RedeemScript)
 }
 
 func doubleSHA256(data []byte) []byte {
@@ -191,36 +279,27 @@ func (tx *Transaction) IsCoinbase() bool {
 // Parameters:
 //   - receiverPubKeyHash: The 20-byte public key hash (address) of the miner who will receive the reward
 //   - blockReward: The total amount to reward the miner (block subsidy + transaction fees)
-//   - coinbaseData: Optional arbitrary data to include in the coinbase input (e.g., block height, nonce, or a message).
-//     If empty, a default message will be used. Max 100 bytes recommended.
+//   - height: The height of the block, embedded in the coinbase data, see CoinbaseHeight
 //
 // The coinbase transaction has:
 //   - One input with PrevTxID = 0, OutputIndex = 0xFFFFFFFF (coinbase specific)
-//   - One or more outputs paying to the miner's address
+//   - One output paying the whole reward to the miner's address
 //   - No signature verification needed (it's newly created coins)
+//
+// See NewCoinbaseTransactionWithPayouts for splitting the reward and tagging the coinbase.
 func NewCoinbaseTransaction(receiverPubKeyHash PubKeyHash, blockReward uint64, height uint64) Transaction {
-	// Build coinbase data (signature field can contain arbitrary data in coinbase)
-	var signature [100]byte
-	binary.LittleEndian.PutUint64(signature[:], height)
-
-	rand.Read(signature[8:]) //nolint:errcheck
-
-	return Transaction{
-		Inputs: []Input{
-			{
-				PrevTxID:    TransactionID{},
-				OutputIndex: 0xFFFFFFFF,
-				Signature:   signature[:],
-				PubKey:      PubKey{},
-			},
-		},
-		Outputs: []Output{
-			{
-				Value:      blockReward,
-				PubKeyHash: receiverPubKeyHash,
-			},
-		},
+	payouts := []CoinbasePayout{{PubKeyHash: receiverPubKeyHash, Percent: 100}}
+	return NewCoinbaseTransactionWithPayouts(payouts, blockReward, height, nil)
+}
+
+// CoinbaseHeight returns the block height embedded in the coinbase data by NewCoinbaseTransaction.
+// Returns false if the transaction is not a coinbase transaction or the coinbase data is too short to contain a height.
+func (tx *Transaction) CoinbaseHeight() (uint64, bool) {
+	if !tx.IsCoinbase() || len(tx.Inputs[0].Signature) < 8 {
+		return 0, false
 	}
+
+	return binary.LittleEndian.Uint64(tx.Inputs[0].Signature), true
 }
 
 func (tx *Transaction) String() string {
@@ -6,11 +6,27 @@ import (
 	"sort"
 )
 
+// CoinbaseMaturity is the number of blocks a coinbase output has to be buried under before it can be spent.
+// An output created by the coinbase transaction of the block at height h can be spent in blocks at height h + CoinbaseMaturity and above.
+// This prevents transactions that spend block rewards from becoming invalid when the rewarding block is reorganized away.
+const CoinbaseMaturity = 10
+
 type UTXO struct {
 	TxID        TransactionID
 	OutputIndex uint32
 	Output      Output
+	// Height is the height of the block that created the output.
+	Height uint64
+	// IsCoinbase is true if the output was created by a coinbase transaction.
+	IsCoinbase bool
+}
+
+// IsSpendableAt reports whether the UTXO can be spent by a transaction in the block at the given height.
+// Only coinbase outputs have to reach CoinbaseMaturity first.
+func (u *UTXO) IsSpendableAt(height uint64) bool {
+	return !u.IsCoinbase || height >= u.Height+CoinbaseMaturity
 }
+
 type TransactionID [common.HashSize]byte
 
 func selectUTXOs(utxos []UTXO, amount uint64) (selected []UTXO, total uint64) {
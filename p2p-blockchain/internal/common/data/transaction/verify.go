@@ -8,6 +8,8 @@ import (
 // VerifySignature verifies the signature of a specific input in the transaction.
 // It returns true if the signature is valid, false otherwise.
 // The referencedOutput is the UTXO being spent by this input.
+// For multisig outputs the Signatures of the input are checked against the multisig script, see verifyMultisig.
+// The script of an OutputTypeMultisigHash output is taken from the input, matching it with the output is part of the transaction validation.
 func (tx *Transaction) VerifySignature(inputIndex int, referencedOutput Output) (bool, error) {
 	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
 		return false, nil
@@ -21,6 +23,10 @@ func (tx *Transaction) VerifySignature(inputIndex int, referencedOutput Output)
 		return false, err
 	}
 
+	if script, ok := spendingMultisigScript(input, referencedOutput); ok {
+		return verifyMultisig(sighash, script, input.Signatures)
+	}
+
 	// Parse the public key from the input
 	pubKey, err := btcec.ParsePubKey(input.PubKey[:])
 	if err != nil {
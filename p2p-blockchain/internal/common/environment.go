@@ -4,10 +4,12 @@ import (
 	"math"
 	"net/netip"
 	"os"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"bjoernblessin.de/go-utils/util/assert"
 	"bjoernblessin.de/go-utils/util/env"
@@ -21,6 +23,20 @@ const (
 	p2pListenAddrEnvVar        = "P2P_LISTEN_ADDR" // a routable IP address the P2P server binds to
 	additionalServicesEnvVar   = "ADDITIONAL_SERVICES"
 	registrySeedHostnameEnvVar = "REGISTRY_SEED_HOSTNAME" // default: "miner-seed.seed.local."
+	dataDirEnvVar              = "DATA_DIR"               // directory for persistent node data, default: "" (in-memory only)
+	mempoolMaxSizeEnvVar       = "MEMPOOL_MAX_SIZE"       // maximum total size of the mempool in bytes, default: DefaultMempoolMaxSize
+	mempoolExpiryEnvVar        = "MEMPOOL_EXPIRY"         // maximum age of a mempool transaction as Go duration (e.g. "72h"), default: DefaultMempoolExpiry
+	minerWorkersEnvVar         = "MINER_WORKERS"          // number of goroutines searching for a valid nonce in parallel, default: number of CPUs
+	minerPayoutEnvVar          = "MINER_PAYOUT_ADDRESSES" // V$Addresses receiving the block rewards with their share in percent (e.g. "1A...:70,1B...:30"), default: one of the built-in addresses
+	minerCoinbaseTagEnvVar     = "MINER_COINBASE_TAG"     // message embedded in the coinbase of mined blocks, default: ""
+	poolPortEnvVar             = "POOL_PORT"              // port of the Stratum-like mining pool server, default: DefaultPoolPort
+	poolShareDifficultyEnvVar  = "POOL_SHARE_DIFFICULTY"  // leading zero bits of a share accepted by the pool, default: DefaultPoolShareDifficulty
+	poolPPLNSWindowEnvVar      = "POOL_PPLNS_WINDOW"      // number of last shares a block reward is split among, default: DefaultPoolPPLNSWindow
+	banThresholdEnvVar         = "BAN_THRESHOLD"          // misbehavior score at which a peer is disconnected and banned, default: DefaultBanThreshold
+	banDurationEnvVar          = "BAN_DURATION"           // time a misbehaving peer stays banned as Go duration (e.g. "24h"), default: DefaultBanDuration
+	p2pTLSEnvVar               = "P2P_TLS"                // "true" to use TLS with the node certificate between nodes, default: false
+	appTLSEnvVar               = "APP_TLS"                // "true" to use TLS with the node certificate for the app server, default: false
+	appClientCertsEnvVar       = "APP_CLIENT_CERTS"       // SHA-256 fingerprints of the client certificates allowed to use the app server, requires APP_TLS, default: "" (no client certificates)
 )
 
 var (
@@ -31,6 +47,20 @@ var (
 	additionalServices   atomic.Value // []string
 	initialized          atomic.Bool
 	registrySeedHostname atomic.Value // string
+	dataDir              atomic.Value // string
+	mempoolMaxSize       atomic.Int64
+	mempoolExpiry        atomic.Int64 // time.Duration
+	minerWorkers         atomic.Int64
+	minerPayoutAddresses atomic.Value // []PayoutAddress
+	minerCoinbaseTag     atomic.Value // string
+	poolPort             atomic.Uint32
+	poolShareDifficulty  atomic.Uint32
+	poolPPLNSWindow      atomic.Int64
+	banThreshold         atomic.Int64
+	banDuration          atomic.Int64 // time.Duration
+	p2pTLS               atomic.Bool
+	appTLS               atomic.Bool
+	appClientCerts       atomic.Value // []string
 )
 
 // Init reads all environment variables at startup.
@@ -53,7 +83,26 @@ func Init() {
 		appListenAddr.Store(readListenAddr(appListenAddrEnvVar))
 	}
 
+	if PoolEnabled() {
+		poolPort.Store(uint32(readUint16EnvOrDefault(poolPortEnvVar, DefaultPoolPort)))
+		poolShareDifficulty.Store(uint32(readPoolShareDifficulty()))
+		poolPPLNSWindow.Store(int64(readPoolPPLNSWindow()))
+	}
+
 	registrySeedHostname.Store(readRegistrySeedHostname())
+	dataDir.Store(readDataDir())
+	mempoolMaxSize.Store(int64(readMempoolMaxSize()))
+	mempoolExpiry.Store(int64(readMempoolExpiry()))
+	minerWorkers.Store(int64(readMinerWorkers()))
+	minerPayoutAddresses.Store(readMinerPayoutAddresses())
+	// Unlike the own miner, the pool has no built-in payout address, the rewards of the pool belong to its operator
+	assert.Assert(!PoolEnabled() || len(MinerPayoutAddresses()) > 0, "pool service requires %s to be set", minerPayoutEnvVar)
+	minerCoinbaseTag.Store(readMinerCoinbaseTag())
+	banThreshold.Store(int64(readBanThreshold()))
+	banDuration.Store(int64(readBanDuration()))
+	p2pTLS.Store(readBool(p2pTLSEnvVar))
+	appTLS.Store(readBool(appTLSEnvVar))
+	appClientCerts.Store(readAppClientCerts())
 }
 
 func readAdditionalServices() []string {
@@ -68,7 +117,7 @@ func readAdditionalServices() []string {
 		svc := strings.TrimSpace(part)
 
 		switch svc {
-		case "blockchain_full", "wallet", "miner", "app":
+		case "blockchain_full", "wallet", "miner", "app", "pool":
 			services = append(services, svc)
 		default:
 			logger.Errorf("unknown service in %s: %s", additionalServicesEnvVar, svc)
@@ -92,6 +141,209 @@ func readRegistrySeedHostname() string {
 	return raw
 }
 
+// readDataDir reads the data directory from the environment variable dataDirEnvVar.
+// Environment variable is optional. If no value is provided, an empty string is returned and all data is kept in memory.
+func readDataDir() string {
+	raw, found := env.ReadOptionalEnv(dataDirEnvVar)
+	if !found {
+		return ""
+	}
+
+	return strings.TrimSpace(raw)
+}
+
+// readMempoolMaxSize reads the maximum mempool size in bytes from the environment variable mempoolMaxSizeEnvVar.
+// Environment variable is optional. If no value is provided, DefaultMempoolMaxSize is used.
+func readMempoolMaxSize() int {
+	raw, found := env.ReadOptionalEnv(mempoolMaxSizeEnvVar)
+	if !found {
+		return DefaultMempoolMaxSize
+	}
+
+	size, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || size <= 0 {
+		logger.Errorf("invalid %s value: %s, must be a positive number of bytes", mempoolMaxSizeEnvVar, raw)
+	}
+
+	return size
+}
+
+// readMempoolExpiry reads the maximum age of mempool transactions from the environment variable mempoolExpiryEnvVar.
+// Environment variable is optional. If no value is provided, DefaultMempoolExpiry is used.
+func readMempoolExpiry() time.Duration {
+	raw, found := env.ReadOptionalEnv(mempoolExpiryEnvVar)
+	if !found {
+		return DefaultMempoolExpiry
+	}
+
+	expiry, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || expiry <= 0 {
+		logger.Errorf("invalid %s value: %s, must be a positive duration (e.g. 72h)", mempoolExpiryEnvVar, raw)
+	}
+
+	return expiry
+}
+
+// readMinerWorkers reads the number of mining goroutines from the environment variable minerWorkersEnvVar.
+// Environment variable is optional. If no value is provided, one goroutine per CPU is used.
+func readMinerWorkers() int {
+	raw, found := env.ReadOptionalEnv(minerWorkersEnvVar)
+	if !found {
+		return runtime.NumCPU()
+	}
+
+	workers, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || workers <= 0 {
+		logger.Errorf("invalid %s value: %s, must be a positive number of goroutines", minerWorkersEnvVar, raw)
+	}
+
+	return workers
+}
+
+// readPoolShareDifficulty reads the share difficulty of the mining pool from the environment variable poolShareDifficultyEnvVar.
+// Environment variable is optional. If no value is provided, DefaultPoolShareDifficulty is returned.
+func readPoolShareDifficulty() uint8 {
+	raw, found := env.ReadOptionalEnv(poolShareDifficultyEnvVar)
+	if !found {
+		return DefaultPoolShareDifficulty
+	}
+
+	difficulty, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 8)
+	if err != nil || difficulty == 0 {
+		logger.Errorf("invalid %s value: %s, must be a number of leading zero bits between 1 and 255", poolShareDifficultyEnvVar, raw)
+	}
+
+	return uint8(difficulty)
+}
+
+// readPoolPPLNSWindow reads the PPLNS window of the mining pool from the environment variable poolPPLNSWindowEnvVar.
+// Environment variable is optional. If no value is provided, DefaultPoolPPLNSWindow is returned.
+func readPoolPPLNSWindow() int {
+	raw, found := env.ReadOptionalEnv(poolPPLNSWindowEnvVar)
+	if !found {
+		return DefaultPoolPPLNSWindow
+	}
+
+	window, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || window <= 0 {
+		logger.Errorf("invalid %s value: %s, must be a positive number of shares", poolPPLNSWindowEnvVar, raw)
+	}
+
+	return window
+}
+
+// readMinerPayoutAddresses reads the payout addresses of the miner from the environment variable minerPayoutEnvVar.
+// The value is a comma separated list of V$Addresses, each followed by a colon and its share in percent.
+// The share may be omitted for a single address, which then receives the whole reward.
+// Environment variable is optional unless the pool is enabled. If no value is provided, an empty list is returned and the miner uses a built-in address.
+// The addresses are decoded and the shares are validated by the miner. A malformed or missing share aborts the startup,
+// so the rewards are never paid out with a share different from the configured one.
+func readMinerPayoutAddresses() []PayoutAddress {
+	raw, found := env.ReadOptionalEnv(minerPayoutEnvVar)
+	if !found || strings.TrimSpace(raw) == "" {
+		return []PayoutAddress{}
+	}
+
+	parts := strings.Split(raw, ",")
+	payouts := make([]PayoutAddress, 0, len(parts))
+	for _, part := range parts {
+		address, share, hasShare := strings.Cut(strings.TrimSpace(part), ":")
+		payout := PayoutAddress{VSAddress: strings.TrimSpace(address), Percent: 100}
+
+		if hasShare {
+			percent, err := strconv.ParseUint(strings.TrimSpace(share), 10, 32)
+			assert.IsNil(err, "invalid share in %s: %s, must be a number of percent", minerPayoutEnvVar, part)
+			payout.Percent = uint32(percent)
+		} else {
+			assert.Assert(len(parts) == 1, "missing share in %s: %s, required if more than one address is given", minerPayoutEnvVar, part)
+		}
+
+		payouts = append(payouts, payout)
+	}
+
+	return payouts
+}
+
+// readMinerCoinbaseTag reads the message embedded in the coinbase of mined blocks from the environment variable minerCoinbaseTagEnvVar.
+// Environment variable is optional. If no value is provided, no message is embedded.
+func readMinerCoinbaseTag() string {
+	raw, found := env.ReadOptionalEnv(minerCoinbaseTagEnvVar)
+	if !found {
+		return ""
+	}
+
+	return strings.TrimSpace(raw)
+}
+
+// readBanThreshold reads the misbehavior score at which a peer is banned from the environment variable banThresholdEnvVar.
+// Environment variable is optional. If no value is provided, DefaultBanThreshold is used.
+func readBanThreshold() int {
+	raw, found := env.ReadOptionalEnv(banThresholdEnvVar)
+	if !found {
+		return DefaultBanThreshold
+	}
+
+	threshold, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || threshold <= 0 {
+		logger.Errorf("invalid %s value: %s, must be a positive misbehavior score", banThresholdEnvVar, raw)
+	}
+
+	return threshold
+}
+
+// readBanDuration reads the time a misbehaving peer stays banned from the environment variable banDurationEnvVar.
+// Environment variable is optional. If no value is provided, DefaultBanDuration is used.
+func readBanDuration() time.Duration {
+	raw, found := env.ReadOptionalEnv(banDurationEnvVar)
+	if !found {
+		return DefaultBanDuration
+	}
+
+	duration, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || duration <= 0 {
+		logger.Errorf("invalid %s value: %s, must be a positive duration (e.g. 24h)", banDurationEnvVar, raw)
+	}
+
+	return duration
+}
+
+// readBool reads an optional boolean environment variable, defaulting to false.
+func readBool(envVar string) bool {
+	raw, found := env.ReadOptionalEnv(envVar)
+	if !found {
+		return false
+	}
+
+	value, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		logger.Errorf("invalid %s value: %s, must be true or false", envVar, raw)
+	}
+
+	return value
+}
+
+// readAppClientCerts reads the comma separated fingerprints of the allowed app client certificates.
+// Client certificates can only be checked on TLS connections, so appTLSEnvVar must be set as well.
+func readAppClientCerts() []string {
+	raw := strings.TrimSpace(os.Getenv(appClientCertsEnvVar))
+	if raw == "" {
+		return []string{}
+	}
+
+	if !readBool(appTLSEnvVar) {
+		logger.Errorf("%s requires %s to be enabled", appClientCertsEnvVar, appTLSEnvVar)
+	}
+
+	parts := strings.Split(raw, ",")
+	fingerprints := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if fingerprint := strings.TrimSpace(part); fingerprint != "" {
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+	return fingerprints
+}
+
 func validateAddionalServices(services []string) {
 	seen := make(map[string]struct{})
 	for _, svc := range services {
@@ -105,10 +357,14 @@ func validateAddionalServices(services []string) {
 	_, hasMiner := seen["miner"]
 	_, hasBlockchainFull := seen["blockchain_full"]
 
+	_, hasPool := seen["pool"]
+
 	needsBlockchain := hasWallet || hasMiner
 	if needsBlockchain && !hasBlockchainFull {
 		logger.Errorf("wallet or miner service requires blockchain_full to be enabled")
 	}
+	// The pool gets its jobs from the block templates of the full blockchain, it can't run without
+	assert.Assert(!hasPool || hasBlockchainFull, "pool service requires blockchain_full to be enabled")
 }
 
 func getAdditionalServices() []string {
@@ -141,6 +397,94 @@ func RegistrySeedHostnameEnv() string {
 	return registrySeedHostname.Load().(string)
 }
 
+// DataDir returns the directory used for persistent node data.
+// Returns an empty string if persistence is disabled.
+func DataDir() string {
+	assertInitialized()
+	return dataDir.Load().(string)
+}
+
+// MempoolMaxSize returns the maximum total size of all transactions in the mempool in bytes.
+func MempoolMaxSize() int {
+	assertInitialized()
+	return int(mempoolMaxSize.Load())
+}
+
+// MempoolExpiry returns the maximum time a transaction stays in the mempool without being mined.
+func MempoolExpiry() time.Duration {
+	assertInitialized()
+	return time.Duration(mempoolExpiry.Load())
+}
+
+// MinerWorkers returns the number of goroutines the miner uses to search for a valid nonce in parallel.
+func MinerWorkers() int {
+	assertInitialized()
+	return int(minerWorkers.Load())
+}
+
+// MinerPayoutAddresses returns the V$Addresses receiving the rewards of mined blocks with their share in percent.
+// Empty if no addresses are configured.
+func MinerPayoutAddresses() []PayoutAddress {
+	assertInitialized()
+	return slices.Clone(minerPayoutAddresses.Load().([]PayoutAddress))
+}
+
+// MinerCoinbaseTag returns the message embedded in the coinbase of mined blocks.
+func MinerCoinbaseTag() string {
+	assertInitialized()
+	return minerCoinbaseTag.Load().(string)
+}
+
+// PoolPort returns the port of the mining pool server. Only set if the pool is enabled.
+func PoolPort() uint16 {
+	assertInitialized()
+	return uint16(poolPort.Load())
+}
+
+// PoolShareDifficulty returns the number of leading zero bits of a share accepted by the mining pool.
+func PoolShareDifficulty() uint8 {
+	assertInitialized()
+	return uint8(poolShareDifficulty.Load())
+}
+
+// PoolPPLNSWindow returns the number of last shares the reward of a block found by the mining pool is split among.
+func PoolPPLNSWindow() int {
+	assertInitialized()
+	return int(poolPPLNSWindow.Load())
+}
+
+// BanThreshold returns the misbehavior score at which a peer is disconnected and its address is banned.
+func BanThreshold() int {
+	assertInitialized()
+	return int(banThreshold.Load())
+}
+
+// BanDuration returns the time the address of a misbehaving peer stays banned.
+func BanDuration() time.Duration {
+	assertInitialized()
+	return time.Duration(banDuration.Load())
+}
+
+// P2PTLSEnabled returns whether connections between nodes use TLS with the node certificate.
+// All nodes of a network must agree on this setting.
+func P2PTLSEnabled() bool {
+	assertInitialized()
+	return p2pTLS.Load()
+}
+
+// AppTLSEnabled returns whether the app server uses TLS with the node certificate.
+func AppTLSEnabled() bool {
+	assertInitialized()
+	return appTLS.Load()
+}
+
+// AppClientCerts returns the SHA-256 fingerprints of the client certificates allowed to use the app server.
+// If empty, the app server does not require client certificates.
+func AppClientCerts() []string {
+	assertInitialized()
+	return slices.Clone(appClientCerts.Load().([]string))
+}
+
 func assertInitialized() {
 	assert.Assert(initialized.Load(), "common.Init() must be called before accessing environment variables")
 }
@@ -0,0 +1,25 @@
+package common
+
+// Misbehavior scores a peer's score is increased by for an offence, see Peer.MisbehaviorScore.
+// Once the score of a peer reaches the ban threshold (see BanThreshold), the peer is disconnected and its address is banned.
+// Offences that can only be committed on purpose reach the default threshold at once,
+// offences that may also be caused by a race or a different view of the chain need to be repeated.
+const (
+	// MisbehaviorMalformedBlock is a block that fails the sanity checks, e.g. without a coinbase or with a wrong merkle root.
+	MisbehaviorMalformedBlock = 100
+
+	// MisbehaviorInvalidBlock is a block that fails the full validation, e.g. spending unknown outputs.
+	MisbehaviorInvalidBlock = 100
+
+	// MisbehaviorInvalidBlockHeader is a header that fails the validation, e.g. with insufficient proof of work.
+	MisbehaviorInvalidBlockHeader = 20
+
+	// MisbehaviorInvalidTransaction is a transaction that fails the validation against the chain and the mempool.
+	MisbehaviorInvalidTransaction = 10
+
+	// MisbehaviorHandshakeViolation is a handshake message that is not expected in the peer's connection state.
+	MisbehaviorHandshakeViolation = 10
+
+	// MisbehaviorInvalidHandshakeSignature is a handshake signature that doesn't prove the identity the peer claims.
+	MisbehaviorInvalidHandshakeSignature = 100
+)
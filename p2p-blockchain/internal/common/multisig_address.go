@@ -0,0 +1,9 @@
+package common
+
+// MultisigAddress is an M-of-N multisig address together with the script needed to spend from it.
+type MultisigAddress struct {
+	// Address is the Base58Check encoded hash of the multisig script
+	Address string
+	// RedeemScript is the hex encoded multisig script. Every co-signer needs it to spend from the address.
+	RedeemScript string
+}
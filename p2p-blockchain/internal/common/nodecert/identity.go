@@ -0,0 +1,54 @@
+package nodecert
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// Identity is the keypair of the node certificate. Its public key identifies the node in the handshake,
+// with or without TLS. The node proves its identity by signing the handshake transcript: its role in the handshake,
+// the public keys and the nonces of both nodes.
+type Identity struct {
+	publicKey []byte
+	signer    crypto.Signer
+}
+
+// NewIdentity returns the identity of the node owning the certificate.
+func NewIdentity(cert tls.Certificate) (*Identity, error) {
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("node key of type %T can't sign", cert.PrivateKey)
+	}
+	return &Identity{
+		publicKey: LocalPublicKey(cert),
+		signer:    signer,
+	}, nil
+}
+
+// PublicKey returns the PKIX encoded public key of the node.
+func (i *Identity) PublicKey() []byte {
+	return i.publicKey
+}
+
+// Sign signs a message with the node key.
+func (i *Identity) Sign(message []byte) ([]byte, error) {
+	// Ed25519 signs the message itself, not a hash of it
+	return i.signer.Sign(rand.Reader, message, crypto.Hash(0))
+}
+
+// VerifySignature checks that signature is a signature of message by the owner of the PKIX encoded Ed25519 public key.
+func VerifySignature(publicKey, message, signature []byte) bool {
+	key, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return false
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return false
+	}
+	return ed25519.Verify(edKey, message, signature)
+}
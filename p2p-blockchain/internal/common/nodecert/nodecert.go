@@ -0,0 +1,205 @@
+// Package nodecert manages the self-signed TLS certificate of the node.
+// The public key of the certificate identifies the node: it is exchanged in the handshake and must match the
+// certificate the peer presents on its TLS connections. Certificates are not signed by a CA, so the TLS configs
+// don't verify certificate chains, they only require proof of possession of the private key, which TLS provides.
+package nodecert
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	certFileName = "node.crt"
+	keyFileName  = "node.key"
+
+	// validity is the lifetime of a generated certificate. The certificate is the node identity, so it does not expire in practice.
+	validity = 100 * 365 * 24 * time.Hour
+)
+
+// LoadOrCreate loads the node certificate from dataDir or, on first start, generates a new one and stores it there.
+// With an empty dataDir the certificate is only kept in memory and the node gets a new identity on every start.
+func LoadOrCreate(dataDir string) (tls.Certificate, error) {
+	if dataDir == "" {
+		cert, _, _, err := generate()
+		return cert, err
+	}
+
+	certPath := filepath.Join(dataDir, certFileName)
+	keyPath := filepath.Join(dataDir, keyFileName)
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err == nil {
+		return cert, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return tls.Certificate{}, fmt.Errorf("failed to load node certificate: %w", err)
+	}
+
+	cert, certPEM, keyPEM, err := generate()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to write node key: %w", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to write node certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// generate creates a self-signed Ed25519 certificate and returns it together with its PEM encoded certificate and key.
+func generate() (tls.Certificate, []byte, []byte, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, nil, fmt.Errorf("failed to generate node key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "vsgoin-node"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, publicKey, privateKey)
+	if err != nil {
+		return tls.Certificate{}, nil, nil, fmt.Errorf("failed to create node certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return tls.Certificate{}, nil, nil, fmt.Errorf("failed to encode node key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, nil, nil, fmt.Errorf("failed to load generated node certificate: %w", err)
+	}
+	return cert, certPEM, keyPEM, nil
+}
+
+// PublicKey returns the PKIX encoded public key of a certificate, i.e. the identity of the node owning it.
+func PublicKey(cert *x509.Certificate) []byte {
+	return cert.RawSubjectPublicKeyInfo
+}
+
+// LocalPublicKey returns the PKIX encoded public key of the node certificate.
+func LocalPublicKey(cert tls.Certificate) []byte {
+	return PublicKey(cert.Leaf)
+}
+
+// Fingerprint returns the hex encoded SHA-256 hash of the DER encoded certificate, as printed by
+// "openssl x509 -in cert.pem -noout -fingerprint -sha256" without colons.
+func Fingerprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(hash[:])
+}
+
+// PeerPublicKey returns the public key of the certificate the remote side presented on a TLS connection.
+func PeerPublicKey(state tls.ConnectionState) ([]byte, bool) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, false
+	}
+	return PublicKey(state.PeerCertificates[0]), true
+}
+
+// P2PServerConfig returns the TLS config of the P2P server. Peers must present a certificate, it identifies them.
+func P2PServerConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates:          []tls.Certificate{cert},
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: verifySelfSigned,
+		MinVersion:            tls.VersionTLS13,
+	}
+}
+
+// P2PClientConfig returns the TLS config of connections to other nodes. The node presents its certificate to identify itself.
+func P2PClientConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		// Node certificates are self-signed, verifySelfSigned replaces the chain verification.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifySelfSigned,
+		MinVersion:            tls.VersionTLS13,
+	}
+}
+
+// AppServerConfig returns the TLS config of the app server.
+// If allowedFingerprints is not empty, clients must present one of the listed certificates, see Fingerprint.
+func AppServerConfig(cert tls.Certificate, allowedFingerprints []string) *tls.Config {
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+	}
+	if len(allowedFingerprints) == 0 {
+		return config
+	}
+
+	allowed := make([]string, 0, len(allowedFingerprints))
+	for _, fingerprint := range allowedFingerprints {
+		allowed = append(allowed, normalizeFingerprint(fingerprint))
+	}
+	config.ClientAuth = tls.RequireAnyClientCert
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+		if !slices.Contains(allowed, Fingerprint(cert)) {
+			return fmt.Errorf("client certificate %s is not allowed", Fingerprint(cert))
+		}
+		return nil
+	}
+	return config
+}
+
+// normalizeFingerprint accepts fingerprints in upper case and with colons.
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
+
+// verifySelfSigned accepts a single self-signed certificate that is currently valid.
+func verifySelfSigned(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) != 1 {
+		return fmt.Errorf("expected a single certificate, got %d", len(rawCerts))
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return fmt.Errorf("invalid certificate: %w", err)
+	}
+	if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		return fmt.Errorf("certificate is not self-signed: %w", err)
+	}
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return errors.New("certificate is not valid at this time")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package nodecert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestLoadOrCreate_PersistsIdentity(t *testing.T) {
+	dir := t.TempDir()
+
+	cert, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatalf("LoadOrCreate() returned error: %v", err)
+	}
+	reloaded, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatalf("LoadOrCreate() returned error on reload: %v", err)
+	}
+
+	if !bytes.Equal(LocalPublicKey(cert), LocalPublicKey(reloaded)) {
+		t.Error("expected the same identity after reloading the certificate")
+	}
+}
+
+func TestLoadOrCreate_InMemory(t *testing.T) {
+	first, err := LoadOrCreate("")
+	if err != nil {
+		t.Fatalf("LoadOrCreate() returned error: %v", err)
+	}
+	second, err := LoadOrCreate("")
+	if err != nil {
+		t.Fatalf("LoadOrCreate() returned error: %v", err)
+	}
+
+	if bytes.Equal(LocalPublicKey(first), LocalPublicKey(second)) {
+		t.Error("expected a new identity without data directory")
+	}
+}
+
+// handshake runs a TLS handshake between the configs and returns the state seen by the server.
+func handshake(t *testing.T, serverConfig, clientConfig *tls.Config) (tls.ConnectionState, error) {
+	t.Helper()
+
+	serverConn, clientConn := net.Pipe()
+	defer func() { _ = serverConn.Close() }()
+	defer func() { _ = clientConn.Close() }()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		client := tls.Client(clientConn, clientConfig)
+		err := client.Handshake()
+		if err == nil {
+			// The server verifies the client certificate after the client finished its side of the handshake
+			_, err = client.Read(make([]byte, 1))
+		}
+		clientErr <- err
+	}()
+
+	server := tls.Server(serverConn, serverConfig)
+	if err := server.Handshake(); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	_, _ = server.Write([]byte{1})
+	return server.ConnectionState(), <-clientErr
+}
+
+func TestP2PConfigs_ExchangeNodeCertificates(t *testing.T) {
+	serverCert, _ := LoadOrCreate("")
+	clientCert, _ := LoadOrCreate("")
+
+	state, err := handshake(t, P2PServerConfig(serverCert), P2PClientConfig(clientCert))
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+
+	key, ok := PeerPublicKey(state)
+	if !ok || !bytes.Equal(key, LocalPublicKey(clientCert)) {
+		t.Error("expected the server to see the public key of the client certificate")
+	}
+}
+
+func TestAppServerConfig_ClientCertAllowlist(t *testing.T) {
+	serverCert, _ := LoadOrCreate("")
+	allowedCert, _ := LoadOrCreate("")
+	otherCert, _ := LoadOrCreate("")
+	allowlist := []string{strings.ToUpper(Fingerprint(allowedCert.Leaf))}
+
+	clientConfig := func(cert tls.Certificate) *tls.Config {
+		return &tls.Config{Certificates: []tls.Certificate{cert}, InsecureSkipVerify: true}
+	}
+
+	if _, err := handshake(t, AppServerConfig(serverCert, allowlist), clientConfig(allowedCert)); err != nil {
+		t.Errorf("expected allowed client certificate to be accepted, got %v", err)
+	}
+	if _, err := handshake(t, AppServerConfig(serverCert, allowlist), clientConfig(otherCert)); err == nil {
+		t.Error("expected other client certificate to be refused")
+	}
+	if _, err := handshake(t, AppServerConfig(serverCert, nil), &tls.Config{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("expected clients without certificate to be accepted without allowlist, got %v", err)
+	}
+}
+
+func TestIdentity_SignAndVerify(t *testing.T) {
+	cert, _ := LoadOrCreate("")
+	otherCert, _ := LoadOrCreate("")
+	identity, err := NewIdentity(cert)
+	if err != nil {
+		t.Fatalf("NewIdentity() returned error: %v", err)
+	}
+
+	signature, err := identity.Sign([]byte("nonce"))
+	if err != nil {
+		t.Fatalf("Sign() returned error: %v", err)
+	}
+
+	if !VerifySignature(identity.PublicKey(), []byte("nonce"), signature) {
+		t.Error("expected signature to be valid")
+	}
+	if VerifySignature(identity.PublicKey(), []byte("other nonce"), signature) {
+		t.Error("expected signature of another message to be invalid")
+	}
+	if VerifySignature(LocalPublicKey(otherCert), []byte("nonce"), signature) {
+		t.Error("expected signature to be invalid for another key")
+	}
+	if VerifySignature([]byte("no key"), []byte("nonce"), signature) {
+		t.Error("expected malformed key to be refused")
+	}
+}
@@ -15,6 +15,13 @@ type Peer struct {
 	Version           string
 	SupportedServices []ServiceType
 	State             PeerConnectionState
+	// PublicKey is the PKIX encoded public key identifying the remote node, exchanged in the handshake.
+	// It is claimed by the Version resp. Verack message and verified once the peer signed the handshake transcript.
+	PublicKey []byte
+	// HandshakeNonce is the random challenge this node sent to the peer in the handshake, the peer must sign it as part of the transcript.
+	HandshakeNonce []byte
+	// RemoteHandshakeNonce is the random challenge the peer sent to this node in the handshake, it is part of the signed transcript as well.
+	RemoteHandshakeNonce []byte
 	// LastSeen is a Unix timestamp indicating the last time the peer was seen active.
 	// Seen active means, that a heartbeat message was received from the peer.
 	// It's not updated on every interaction with the peer,
@@ -28,6 +35,9 @@ type Peer struct {
 	// After the holddown period expires, the peer is permanently removed from the store.
 	// Zero value indicates the peer is not in holddown.
 	HolddownStartTime int64
+	// MisbehaviorScore is the sum of the scores of the offences the peer committed, see MisbehaviorInvalidBlock etc.
+	// The peer is disconnected and its address is banned once the score reaches the ban threshold.
+	MisbehaviorScore int
 	// AddrsSentTo tracks PeerIds whose addresses have been sent to this peer.
 	// Prevents sending the same address twice to the same recipient.
 	AddrsSentTo mapset.Set[PeerId]
@@ -0,0 +1,22 @@
+package common
+
+// Limits of P2P messages. Messages exceeding them are dropped by the P2P server before they reach a handler,
+// senders split or truncate their messages to stay within them.
+const (
+	// MaxInvVectors is the maximum number of inventory vectors in an Inv or GetData message.
+	MaxInvVectors = 20_000
+
+	// MaxAddrListSize is the maximum number of addresses in an Addr message.
+	MaxAddrListSize = 1_000
+
+	// MaxHeadersCount is the maximum number of block headers in a Headers message.
+	MaxHeadersCount = 2_000
+
+	// MaxBlockLocatorHashes is the maximum number of hashes in the block locator of a GetHeaders message.
+	// Locators sample the chain with growing gaps, so even long chains need far fewer hashes.
+	MaxBlockLocatorHashes = 101
+
+	// MaxMessageSize is the maximum size of a P2P message in bytes.
+	// It fits an Inv message with MaxInvVectors vectors and a block of the maximum block size (see block.MaxBlockSize).
+	MaxMessageSize = 1 << 20
+)
@@ -16,4 +16,7 @@ const (
 
 	// ErrorTypeRejectNotConnected indicates that a message was received from a peer that is not in an established connection state
 	ErrorTypeRejectNotConnected = 3
+
+	// ErrorTypeRejectThrottled indicates that a message was dropped because the peer exceeded its rate limit
+	ErrorTypeRejectThrottled = 4
 )
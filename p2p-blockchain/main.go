@@ -11,14 +11,17 @@ import (
 	blockchainData "s3b/vsp-blockchain/p2p-blockchain/blockchain/data/blockchain"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/nodecert"
 	minerapi "s3b/vsp-blockchain/p2p-blockchain/miner/api"
 	minerCore "s3b/vsp-blockchain/p2p-blockchain/miner/core"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/api"
+	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/addrman"
 	networkBlockchain "s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/blockchain"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/connectioncheck"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/disconnect"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/handshake"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/keepalive"
+	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/misbehavior"
 	corepeer "s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/peer"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/peer/discovery"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/peermanagement"
@@ -26,10 +29,14 @@ import (
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/infrastructure/middleware/grpc"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/infrastructure/middleware/grpc/networkinfo"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/infrastructure/registry"
+	poolCore "s3b/vsp-blockchain/p2p-blockchain/pool/core"
+	"s3b/vsp-blockchain/p2p-blockchain/pool/infrastructure/stratum"
 	walletApi "s3b/vsp-blockchain/p2p-blockchain/wallet/api"
 	walletcore "s3b/vsp-blockchain/p2p-blockchain/wallet/core"
 	"s3b/vsp-blockchain/p2p-blockchain/wallet/core/keys"
 
+	"crypto/tls"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
@@ -45,38 +52,86 @@ func main() {
 
 	logger.Infof("[main] Loglevel set to %v", logger.CurrentLevel())
 
+	banList := misbehavior.NewBanList()
+	if common.DataDir() != "" {
+		var err error
+		banList, err = misbehavior.NewPersistentBanList(common.DataDir())
+		assert.IsNil(err, "Failed to open persistent ban list")
+	}
+
+	// The node certificate holds the keypair identifying the node in the handshake, it is needed with and without TLS
+	nodeCert, err := nodecert.LoadOrCreate(common.DataDir())
+	assert.IsNil(err, "Failed to load node certificate")
+	logger.Infof("[main] Node certificate fingerprint: %s", nodecert.Fingerprint(nodeCert.Leaf))
+	nodeIdentity, err := nodecert.NewIdentity(nodeCert)
+	assert.IsNil(err, "Failed to load node identity")
+
+	var p2pServerTLS, p2pClientTLS, appServerTLS *tls.Config
+	if common.P2PTLSEnabled() {
+		p2pServerTLS = nodecert.P2PServerConfig(nodeCert)
+		p2pClientTLS = nodecert.P2PClientConfig(nodeCert)
+	}
+	if common.AppTLSEnabled() {
+		appServerTLS = nodecert.AppServerConfig(nodeCert, common.AppClientCerts())
+	}
+
 	peerStore := peer.NewPeerStore()
-	networkInfoRegistry := networkinfo.NewNetworkInfoRegistry(peerStore)
+	networkInfoRegistry := networkinfo.NewNetworkInfoRegistry(peerStore, banList)
 	disconnectService := disconnect.NewDisconnectService(networkInfoRegistry, peerStore)
-	grpcClient := grpc.NewClient(networkInfoRegistry, disconnectService)
-	handshakeService := handshake.NewHandshakeService(grpcClient, peerStore, grpcClient)
+	misbehaviorService := misbehavior.NewMisbehaviorService(peerStore, networkInfoRegistry, disconnectService, banList, misbehavior.MisbehaviorConfig{
+		BanThreshold: common.BanThreshold(),
+		BanDuration:  common.BanDuration(),
+	})
+	grpcClient := grpc.NewClient(networkInfoRegistry, disconnectService, p2pClientTLS)
+	handshakeService := handshake.NewHandshakeService(grpcClient, peerStore, grpcClient, misbehaviorService, nodeIdentity, disconnectService)
 	handshakeAPI := api.NewHandshakeAPIService(networkInfoRegistry, peerStore, handshakeService)
 	peerRetrieverAdapter := corepeer.NewPeerRetrieverAdapter(peerStore)
 	networkRegistryAPI := api.NewNetworkRegistryService(networkInfoRegistry, peerRetrieverAdapter)
 	registryQuerier := registry.NewDNSRegistryQuerier(networkInfoRegistry)
 	queryRegistryAPI := api.NewQueryRegistryAPIService(registryQuerier)
 
-	discoveryService := discovery.NewDiscoveryService(registryQuerier, grpcClient, peerStore, grpcClient, grpcClient)
+	addrManager := addrman.NewAddrManager(networkInfoRegistry, peerStore)
+	if common.DataDir() != "" {
+		addrManager, err = addrman.NewPersistentAddrManager(networkInfoRegistry, peerStore, common.DataDir())
+		assert.IsNil(err, "Failed to open persistent address tables")
+	}
+	// Promotes the addresses of successful outbound handshakes to the tried table
+	handshakeService.Attach(addrManager)
+
+	discoveryService := discovery.NewDiscoveryService(registryQuerier, grpcClient, peerStore, grpcClient, grpcClient, addrManager)
 	discoveryAPI := api.NewDiscoveryAPIService(discoveryService)
 	periodicDiscoveryService := discovery.NewPeriodicDiscoveryService(peerStore, grpcClient, discoveryService)
 	keepaliveService := keepalive.NewKeepaliveService(peerStore, grpcClient, grpcClient)
 	connectionCheckService := connectioncheck.NewConnectionCheckService(peerStore, disconnectService, networkInfoRegistry)
-	peerManagementService := peermanagement.NewPeerManagementService(peerStore, discoveryService, peerStore, handshakeService, peerStore)
+	peerManagementService := peermanagement.NewPeerManagementService(peerStore, discoveryService, peerStore, handshakeService, addrManager)
 
 	genesisBlock := blockchainData.GenesisBlock()
 	blockValidator := validation.NewBlockValidationService()
 	blockStore := blockchainData.NewBlockStore(genesisBlock, blockValidator)
+	if common.DataDir() != "" {
+		var err error
+		blockStore, err = blockchainData.NewPersistentBlockStore(genesisBlock, blockValidator, common.DataDir())
+		assert.IsNil(err, "Failed to open persistent block store")
+	}
 
 	utxoStore := utxo.NewUtxoStore(blockStore)
+	if common.DataDir() != "" {
+		persistentUtxoStore, err := utxo.NewPersistentUtxoStore(blockStore, common.DataDir())
+		assert.IsNil(err, "Failed to open persistent UTXO store")
+		utxoStore = persistentUtxoStore
+	}
 	transactionValidator := validation.NewTransactionValidator(utxoStore)
-	err := utxoStore.InitializeGenesisPool(genesisBlock)
+	err = utxoStore.InitializeGenesisPool(genesisBlock)
 	assert.IsNil(err, "Failed to initialize genesis UTXO pool")
 
 	blockchainMsgService := networkBlockchain.NewBlockchainService(grpcClient, peerStore)
 
-	blockValidator.SetDependencies(transactionValidator, utxoStore)
+	blockValidator.SetDependencies(transactionValidator, utxoStore, blockStore)
 
-	mempool := core.NewMempool(transactionValidator, blockStore)
+	mempool := core.NewMempoolWithConfig(transactionValidator, blockStore, core.MempoolConfig{
+		MaxSize: common.MempoolMaxSize(),
+		Expiry:  common.MempoolExpiry(),
+	})
 
 	var blockchain *core.Blockchain
 	if common.BlockchainFullEnabled() {
@@ -84,6 +139,7 @@ func main() {
 			blockchainMsgService,
 			grpcClient,
 			grpcClient,
+			misbehaviorService,
 			blockValidator,
 			blockStore,
 			peerStore,
@@ -92,22 +148,88 @@ func main() {
 			mempool,
 		)
 
+		// Catch up the UTXO set with blocks loaded from disk
+		err = blockchain.ConnectStoredChain()
+		assert.IsNil(err, "Failed to connect stored chain")
+
 		// Attach blockchain as connection observer to trigger Initial Block Download (IBD)
 		// when new peers connect. This implements Headers-First IBD as per Bitcoin protocol.
 		handshakeService.Attach(blockchain)
 	}
 
+	// Reload the mempool of the previous run. The transactions are validated and their signatures verified against the chain
+	// stored on disk, as the initial block download has not started yet. Transactions depending on newer blocks are dropped.
+	if common.DataDir() != "" {
+		_, err = mempool.Load(common.DataDir())
+		if err != nil {
+			logger.Warnf("[main] couldn't load mempool: %v", err)
+		}
+	}
+
 	keyEncodingsImpl := keys.NewKeyEncodingsImpl()
 	keyGeneratorImpl := keys.NewKeyGeneratorImpl(keyEncodingsImpl, keyEncodingsImpl)
 	keyGeneratorApiImpl := walletApi.NewKeyGeneratorApiImpl(keyGeneratorImpl)
 
+	// Block templates for external miners and the pool, available without the own miner
+	var blockTemplateAPI minerapi.BlockTemplateAPI
+	var blockTemplateService *minerCore.BlockTemplateService
+	if common.BlockchainFullEnabled() {
+		blockTemplateService = minerCore.NewBlockTemplateService(blockchain, utxoStore, blockStore, blockapi.NewMempoolAPI(mempool))
+		blockchain.AttachTipObserver(blockTemplateService)
+		blockTemplateAPI = blockTemplateService
+	}
+
 	var minerImpl minerapi.MinerAPI
 	if common.MinerEnabled() {
-		minerImpl = minerCore.NewMinerService(blockchain, utxoStore, blockStore)
+		minerService := minerCore.NewMinerServiceWithConfig(blockchain, utxoStore, blockStore, minerCore.MinerConfig{
+			Workers: common.MinerWorkers(),
+		})
+		if payoutAddresses := common.MinerPayoutAddresses(); len(payoutAddresses) > 0 {
+			payouts, err := appcore.DecodePayoutAddresses(keyEncodingsImpl, payoutAddresses)
+			assert.IsNil(err, "Invalid miner payout addresses")
+			err = minerService.SetPayouts(payouts)
+			assert.IsNil(err, "Invalid miner payout addresses")
+		}
+		err = minerService.SetCoinbaseTag([]byte(common.MinerCoinbaseTag()))
+		assert.IsNil(err, "Invalid miner coinbase tag")
+		minerImpl = minerService
 		blockchain.Attach(minerImpl)
+		// Blocks of external miners and the pool show up in the mining status as well
+		if blockTemplateService != nil {
+			blockTemplateService.SetMinedBlockRecorder(minerService)
+		}
 		minerImpl.StartMining(make([]transaction.Transaction, 0))
 	}
 
+	var poolService *poolCore.PoolService
+	var stratumServer *stratum.Server
+	if common.PoolEnabled() {
+		// Checked by common.Init, the pool requires blockchain_full and configured payout addresses
+		assert.IsNotNil(blockTemplateAPI, "Pool requires the block templates of blockchain_full")
+		// The pool pays the found blocks to the operator, the workers are credited by the PPLNS accounting
+		payouts, err := appcore.DecodePayoutAddresses(keyEncodingsImpl, common.MinerPayoutAddresses())
+		assert.IsNil(err, "Invalid miner payout addresses")
+		poolService = poolCore.NewPoolService(blockTemplateAPI, blockStore, keyEncodingsImpl, poolCore.PoolConfig{
+			ShareDifficulty: common.PoolShareDifficulty(),
+			PPLNSWindow:     common.PoolPPLNSWindow(),
+			Payouts:         payouts,
+			CoinbaseTag:     []byte(common.MinerCoinbaseTag()),
+		})
+		poolService.Start()
+
+		stratumServer = stratum.NewServer(poolService)
+		err = stratumServer.Start(common.PoolPort())
+		if err != nil {
+			logger.Warnf("[main] couldn't start pool server: %v", err)
+			stratumServer = nil
+		} else {
+			addrPort, err := stratumServer.ListeningEndpoint()
+			assert.IsNil(err)
+			logger.Infof("[main] Pool server started on port %v", addrPort)
+		}
+	}
+
+	var rebroadcastService *walletcore.TransactionRebroadcastService
 	if common.AppEnabled() {
 		logger.Infof("[main] Starting App server...")
 
@@ -120,6 +242,10 @@ func main() {
 			transactionCreationService := walletcore.NewTransactionCreationService(keyGeneratorImpl, keyEncodingsImpl, blockchainMsgService, utxoStore, blockStore, *mempoolApi)
 			transactionCreationAPI = walletApi.NewTransactionCreationAPIImpl(transactionCreationService)
 
+			// Rebroadcast pending transactions of the wallet until they are mined
+			rebroadcastService = walletcore.NewTransactionRebroadcastService(blockchainMsgService, mempoolApi)
+			rebroadcastService.Start()
+
 			// Initialize konto API
 			kontoAPI = walletApi.NewKontoAPIImpl(utxoStore, keyEncodingsImpl, blockStore)
 
@@ -133,7 +259,7 @@ func main() {
 
 		var miningService *appcore.MiningService
 		if common.MinerEnabled() {
-			miningService = appcore.NewMiningService(minerImpl)
+			miningService = appcore.NewMiningService(minerImpl, keyEncodingsImpl)
 		}
 
 		connService := appcore.NewConnectionEstablishmentService(handshakeAPI)
@@ -142,6 +268,8 @@ func main() {
 		discoveryAppService := appcore.NewDiscoveryService(discoveryAPI)
 		disconnectAPI := api.NewDisconnectAPIService(networkInfoRegistry, disconnectService)
 		disconnectAppService := appcore.NewDisconnectService(disconnectAPI)
+		banAPI := api.NewBanAPIService(misbehaviorService)
+		banAppService := appcore.NewBanService(banAPI)
 
 		appServer := appgrpc.NewServer(
 			connService,
@@ -154,8 +282,11 @@ func main() {
 			historyAPI,
 			visualizationHandler,
 			miningService,
+			blockTemplateAPI,
 			disconnectAppService,
+			banAppService,
 			blockStore,
+			appServerTLS,
 		)
 		err := appServer.Start(common.AppPort())
 		if err != nil {
@@ -170,7 +301,7 @@ func main() {
 
 	logger.Infof("[main] Starting P2P server...")
 
-	grpcServer := grpc.NewServer(handshakeService, networkInfoRegistry, discoveryService, keepaliveService, peerStore)
+	grpcServer := grpc.NewServer(handshakeService, networkInfoRegistry, discoveryService, keepaliveService, peerStore, grpcClient, p2pServerTLS)
 
 	if common.BlockchainFullEnabled() {
 		grpcServer.Attach(blockchain)
@@ -199,6 +330,9 @@ func main() {
 	// Start peer management service
 	peerManagementService.Start()
 
+	// Start saving the address tables
+	addrManager.Start()
+
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -209,5 +343,28 @@ func main() {
 	connectionCheckService.Stop()
 	periodicDiscoveryService.Stop()
 	peerManagementService.Stop()
+	addrManager.Stop()
+	if rebroadcastService != nil {
+		rebroadcastService.Stop()
+	}
+	if stratumServer != nil {
+		stratumServer.Stop()
+	}
+	if poolService != nil {
+		poolService.Stop()
+	}
+	if common.DataDir() != "" {
+		if err := mempool.Dump(common.DataDir()); err != nil {
+			logger.Warnf("[main] couldn't dump mempool: %v", err)
+		}
+	}
+	if err := blockStore.Close(); err != nil {
+		logger.Warnf("[main] couldn't close block store: %v", err)
+	}
+	if closer, ok := utxoStore.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Warnf("[main] couldn't close UTXO store: %v", err)
+		}
+	}
 	logger.Infof("[main] Shutdown complete")
 }
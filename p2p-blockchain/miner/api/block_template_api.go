@@ -0,0 +1,19 @@
+package api
+
+import (
+	"context"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"s3b/vsp-blockchain/p2p-blockchain/miner/core"
+)
+
+// BlockTemplateAPI provides block templates to external miners and accepts the blocks they solved.
+// It is available without the own miner.
+type BlockTemplateAPI interface {
+	// GetBlockTemplate returns a template for the next block on top of the main chain tip.
+	// If longPollID is the LongPollID of the current tip, it waits for the tip to change first.
+	GetBlockTemplate(ctx context.Context, longPollID string) (core.BlockTemplate, error)
+	// SubmitBlock adds the block built from the template with the solved header and the coinbase of the miner to the blockchain.
+	SubmitBlock(templateID uint64, header block.BlockHeader, coinbase transaction.Transaction) (common.Hash, error)
+}
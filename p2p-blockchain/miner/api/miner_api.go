@@ -2,6 +2,23 @@ package api
 
 import (
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"s3b/vsp-blockchain/p2p-blockchain/miner/core"
+)
+
+// MiningStatus describes what the miner is doing and what it mined, see core.MiningStatus.
+type MiningStatus = core.MiningStatus
+
+// MinedBlock is a block mined by this node with its status in the block store, see core.MinedBlock.
+type MinedBlock = core.MinedBlock
+
+// MinedBlockStatus is the state of a block mined by this node in the block store, see core.MinedBlockStatus.
+type MinedBlockStatus = core.MinedBlockStatus
+
+const (
+	MinedBlockMainChain = core.MinedBlockMainChain
+	MinedBlockSideChain = core.MinedBlockSideChain
+	MinedBlockOrphan    = core.MinedBlockOrphan
+	MinedBlockUnknown   = core.MinedBlockUnknown
 )
 
 // MinerAPI provides methods to control the mining process.
@@ -15,4 +32,10 @@ type MinerAPI interface {
 	EnableMining()
 	// DisableMining disables mining capability and stops any ongoing mining
 	DisableMining()
+	// SetPayouts sets the addresses receiving the rewards of mined blocks and their shares in percent
+	SetPayouts(payouts []transaction.CoinbasePayout) error
+	// SetCoinbaseTag sets the message embedded in the coinbase of mined blocks
+	SetCoinbaseTag(tag []byte) error
+	// GetMiningStatus returns the current state of the miner and the blocks it mined
+	GetMiningStatus() MiningStatus
 }
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/miner/core"
+	"testing"
+)
+
+func TestMinerAPI_Interface(t *testing.T) {
+	// Compile-time check that the miner service implements MinerAPI
+	var _ MinerAPI = core.NewMinerService(nil, nil, nil)
+}
+
+func TestBlockTemplateAPI_Interface(t *testing.T) {
+	// Compile-time check that BlockTemplateService implements BlockTemplateAPI
+	var _ BlockTemplateAPI = &core.BlockTemplateService{}
+}
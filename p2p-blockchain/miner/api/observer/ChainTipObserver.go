@@ -0,0 +1,10 @@
+package observer
+
+import "s3b/vsp-blockchain/p2p-blockchain/internal/common"
+
+// ChainTipObserverAPI is notified whenever the main chain tip changes.
+// It is implemented by the BlockTemplateService, which drops the templates of the old tip and wakes up long-polling miners.
+type ChainTipObserverAPI interface {
+	// TipChanged is called after the blocks up to the new main chain tip were connected.
+	TipChanged(tip common.Hash)
+}
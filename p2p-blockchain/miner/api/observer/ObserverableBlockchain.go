@@ -1,5 +1,7 @@
 package observer
 
+import "s3b/vsp-blockchain/p2p-blockchain/internal/common"
+
 type ObservableBlockchain interface {
 	// Attach is called by the observer to attach itself to the server.
 	Attach(o BlockchainObserverAPI)
@@ -10,4 +12,12 @@ type ObservableBlockchain interface {
 	NotifyStartMining()
 	// NotifyStopMining Stops the mining process
 	NotifyStopMining()
+
+	// AttachTipObserver is called by the observer to be notified about changes of the main chain tip.
+	AttachTipObserver(o ChainTipObserverAPI)
+	// DetachTipObserver is called by the observer to stop the notifications about changes of the main chain tip.
+	DetachTipObserver(o ChainTipObserverAPI)
+
+	// NotifyTipChanged notifies the tip observers about the new main chain tip
+	NotifyTipChanged(tip common.Hash)
 }
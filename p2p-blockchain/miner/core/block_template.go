@@ -0,0 +1,158 @@
+package core
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"slices"
+)
+
+const (
+	templatePending = iota
+	templateSelected
+	templateSkipped
+)
+
+// templateEntry is a transaction considered for the block template together with its unconfirmed relatives among the given transactions.
+type templateEntry struct {
+	transactionWithFee
+	// ancestors are the indices of the ancestors that are not selected yet
+	ancestors   map[int]struct{}
+	descendants []int
+	// packageFee and packageSize include the transaction and all of its ancestors that are not selected yet
+	packageFee  uint64
+	packageSize int
+	state       int
+}
+
+// blockTemplateBuilder selects the transactions of a candidate block by ancestor fee rate (child-pays-for-parent).
+// A transaction is selected as a package together with its unselected ancestors, rated by the fee rate of the whole package.
+// So a child paying a high fee pulls its low-fee parent into the block, while the parent alone would be left out.
+type blockTemplateBuilder struct {
+	entries       []*templateEntry
+	availableSize int
+}
+
+// newBlockTemplateBuilder links the transactions with the other given transactions whose outputs they spend.
+// The transactions must be free of cycles, which holds for every set of valid transactions.
+func newBlockTemplateBuilder(transactions []transactionWithFee, availableSize int) *blockTemplateBuilder {
+	indexById := make(map[transaction.TransactionID]int, len(transactions))
+	for i, tx := range transactions {
+		indexById[tx.tx.TransactionId()] = i
+	}
+
+	entries := make([]*templateEntry, len(transactions))
+	parents := make([][]int, len(transactions))
+	for i, tx := range transactions {
+		entries[i] = &templateEntry{transactionWithFee: tx}
+		for _, input := range tx.tx.Inputs {
+			parent, ok := indexById[input.PrevTxID]
+			if ok && parent != i && !slices.Contains(parents[i], parent) {
+				parents[i] = append(parents[i], parent)
+			}
+		}
+	}
+
+	b := &blockTemplateBuilder{entries: entries, availableSize: availableSize}
+	for i, entry := range entries {
+		entry.ancestors = b.collectAncestors(i, parents)
+		entry.packageFee, entry.packageSize = entry.Fee, entry.Size
+		for ancestor := range entry.ancestors {
+			entries[ancestor].descendants = append(entries[ancestor].descendants, i)
+			entry.packageFee += entries[ancestor].Fee
+			entry.packageSize += entries[ancestor].Size
+		}
+	}
+	return b
+}
+
+// collectAncestors returns the indices of all ancestors of the entry with the given index.
+func (b *blockTemplateBuilder) collectAncestors(index int, parents [][]int) map[int]struct{} {
+	ancestors := make(map[int]struct{})
+	stack := slices.Clone(parents[index])
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := ancestors[current]; ok {
+			continue
+		}
+		ancestors[current] = struct{}{}
+		stack = append(stack, parents[current]...)
+	}
+	return ancestors
+}
+
+// build repeatedly selects the package with the highest ancestor fee rate that still fits into the available size.
+// Packages that do not fit are skipped, so smaller packages can still fill the remaining space.
+// The selected transactions are returned in dependency order: parents always precede their children.
+func (b *blockTemplateBuilder) build() []transactionWithFee {
+	selected := make([]transactionWithFee, 0, len(b.entries))
+
+	for {
+		best := b.bestPendingPackage()
+		if best < 0 {
+			return selected
+		}
+
+		entry := b.entries[best]
+		if entry.packageSize > b.availableSize {
+			entry.state = templateSkipped
+			continue
+		}
+
+		for _, index := range b.packageInDependencyOrder(best) {
+			selected = append(selected, b.entries[index].transactionWithFee)
+			b.markSelected(index)
+		}
+	}
+}
+
+// bestPendingPackage returns the index of the pending entry with the highest package fee rate or -1 if no entry is pending.
+// Of two packages with the same fee rate the one of the earlier given transaction is chosen.
+func (b *blockTemplateBuilder) bestPendingPackage() int {
+	best := -1
+	for i, entry := range b.entries {
+		if entry.state != templatePending {
+			continue
+		}
+		if best < 0 || hasHigherFeeRate(entry.packageFee, entry.packageSize, b.entries[best].packageFee, b.entries[best].packageSize) {
+			best = i
+		}
+	}
+	return best
+}
+
+// packageInDependencyOrder returns the indices of the entry and its unselected ancestors, ordered so that parents precede their children.
+// An ancestor always has fewer ancestors than its descendants, so ordering by the number of ancestors is a valid order.
+func (b *blockTemplateBuilder) packageInDependencyOrder(index int) []int {
+	pkg := make([]int, 0, len(b.entries[index].ancestors)+1)
+	for ancestor := range b.entries[index].ancestors {
+		pkg = append(pkg, ancestor)
+	}
+	pkg = append(pkg, index)
+
+	slices.SortFunc(pkg, func(i, j int) int {
+		if diff := len(b.entries[i].ancestors) - len(b.entries[j].ancestors); diff != 0 {
+			return diff
+		}
+		return i - j
+	})
+	return pkg
+}
+
+// markSelected marks the entry as selected and removes it from the packages of its descendants.
+func (b *blockTemplateBuilder) markSelected(index int) {
+	entry := b.entries[index]
+	entry.state = templateSelected
+	b.availableSize -= entry.Size
+
+	for _, descendant := range entry.descendants {
+		descendantEntry := b.entries[descendant]
+		delete(descendantEntry.ancestors, index)
+		descendantEntry.packageFee -= entry.Fee
+		descendantEntry.packageSize -= entry.Size
+	}
+}
+
+// hasHigherFeeRate reports whether feeA / sizeA > feeB / sizeB without integer division.
+func hasHigherFeeRate(feeA uint64, sizeA int, feeB uint64, sizeB int) bool {
+	return feeA*uint64(sizeB) > feeB*uint64(sizeA)
+}
@@ -0,0 +1,329 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	blockchainApi "s3b/vsp-blockchain/p2p-blockchain/blockchain/api"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"sync"
+	"time"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// MaxTemplateCoinbaseSize is the space reserved for the coinbase transaction of an external miner in a block template.
+// It fits a coinbase with transaction.MaxCoinbasePayouts payouts and a tag of transaction.MaxCoinbaseTagSize bytes.
+const MaxTemplateCoinbaseSize = 1000
+
+// DefaultLongPollTimeout is the longest time GetBlockTemplate waits for the chain tip to change.
+const DefaultLongPollTimeout = time.Minute
+
+// maxStoredTemplates is the number of templates kept per chain tip for SubmitBlock.
+// Older templates are dropped, so miners have to fetch a new template regularly.
+const maxStoredTemplates = 16
+
+var (
+	ErrUnknownTemplate         = errors.New("unknown or stale block template")
+	ErrTemplateMismatch        = errors.New("block does not match the block template")
+	ErrInvalidTemplateCoinbase = errors.New("invalid coinbase transaction")
+	ErrInsufficientProofOfWork = errors.New("block hash does not meet the difficulty target")
+	ErrBlockRejected           = errors.New("block was not accepted into the main chain")
+)
+
+// transactionSource returns the pending transactions to include in new blocks.
+// It is implemented by blockapi.MempoolAPI.
+type transactionSource interface {
+	GetTransactionsForMining() []transaction.Transaction
+}
+
+// minedBlockRecorder records the blocks of block templates solved by external miners and the pool in the mining status.
+// It is implemented by minerService.
+type minedBlockRecorder interface {
+	recordSubmittedBlock(submitted block.Block, height uint64)
+}
+
+// TemplateTransaction is a transaction of a block template with the fee it pays.
+type TemplateTransaction struct {
+	Transaction transaction.Transaction
+	Fee         uint64
+}
+
+// BlockTemplate contains everything an external miner needs to build and solve a block on top of the main chain tip.
+// The miner creates its own coinbase paying CoinbaseValue, calculates the merkle root with block.MerkleRootFromCoinbase
+// and searches nonce and timestamp until the header meets DifficultyTarget.
+type BlockTemplate struct {
+	// ID identifies the template in SubmitBlock.
+	ID                uint64
+	PreviousBlockHash common.Hash
+	Height            uint64
+	DifficultyTarget  uint8
+	// MinTimestamp is the earliest valid timestamp of the block (median-time-past + 1).
+	MinTimestamp int64
+	// CurrentTimestamp is the suggested timestamp of the block.
+	CurrentTimestamp int64
+	// CoinbaseValue is the block subsidy plus the fees of all transactions.
+	CoinbaseValue uint64
+	// MaxCoinbaseSize is the largest serialized coinbase the block has room for.
+	MaxCoinbaseSize int
+	// Transactions are the transactions following the coinbase, in block order.
+	Transactions []TemplateTransaction
+	// MerkleBranch is the branch of the transactions, see block.CoinbaseMerkleBranch.
+	MerkleBranch []common.Hash
+	// LongPollID identifies the chain tip the template builds on, see GetBlockTemplate.
+	LongPollID string
+}
+
+// BlockTemplateService provides block templates to external miners and accepts the blocks they solved.
+// It works without the miner service. The service is a chain tip observer to learn about new chain tips:
+// Templates of an old tip are dropped and long-polling miners are woken up.
+type BlockTemplateService struct {
+	blockchain      blockchainApi.BlockchainAPI
+	utxoService     blockchainApi.UtxoStoreAPI
+	blockStore      blockchainApi.BlockStoreAPI
+	transactions    transactionSource
+	longPollTimeout time.Duration
+
+	mu sync.Mutex
+	// recorder records the submitted blocks, nil if the own miner is disabled, see SetMinedBlockRecorder.
+	recorder minedBlockRecorder
+	// tip is the main chain tip the stored templates build on. It is only changed by TipChanged.
+	tip       common.Hash
+	templates map[uint64]BlockTemplate
+	nextID    uint64
+	// tipChanged is closed and replaced once the main chain tip changes.
+	tipChanged chan struct{}
+}
+
+// NewBlockTemplateService creates a BlockTemplateService with the DefaultLongPollTimeout.
+func NewBlockTemplateService(
+	blockchain blockchainApi.BlockchainAPI,
+	utxoService blockchainApi.UtxoStoreAPI,
+	blockStore blockchainApi.BlockStoreAPI,
+	transactions transactionSource,
+) *BlockTemplateService {
+	tip := blockStore.GetMainChainTip()
+	return &BlockTemplateService{
+		blockchain:      blockchain,
+		utxoService:     utxoService,
+		blockStore:      blockStore,
+		transactions:    transactions,
+		longPollTimeout: DefaultLongPollTimeout,
+		tip:             tip.Hash(),
+		templates:       make(map[uint64]BlockTemplate),
+		tipChanged:      make(chan struct{}),
+	}
+}
+
+// SetMinedBlockRecorder sets the miner recording the submitted blocks in its mining status, see minerService.GetMiningStatus.
+func (s *BlockTemplateService) SetMinedBlockRecorder(recorder minedBlockRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorder = recorder
+}
+
+// mainChainTip returns the hash of the current main chain tip.
+func (s *BlockTemplateService) mainChainTip() common.Hash {
+	tip := s.blockStore.GetMainChainTip()
+	return tip.Hash()
+}
+
+// TipChanged is called by the blockchain after the main chain tip changed.
+func (s *BlockTemplateService) TipChanged(tip common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setTip(tip)
+}
+
+// setTip drops the templates of the previous tip and wakes up long-polling miners if the tip changed. mu must be held.
+func (s *BlockTemplateService) setTip(tip common.Hash) {
+	if tip == s.tip {
+		return
+	}
+
+	logger.Debugf("[miner] Chain tip changed to %v, dropping %d block templates", tip, len(s.templates))
+	s.tip = tip
+	clear(s.templates)
+	close(s.tipChanged)
+	s.tipChanged = make(chan struct{})
+}
+
+// GetBlockTemplate creates a block template on top of the current main chain tip.
+// The transactions are selected from the mempool by ancestor fee rate like for the own miner.
+// If longPollID is the LongPollID of the current tip, the call blocks until the tip changes, the longPollTimeout
+// elapsed or the context is done. So a miner can wait for a new template right after fetching one.
+func (s *BlockTemplateService) GetBlockTemplate(ctx context.Context, longPollID string) (BlockTemplate, error) {
+	if longPollID != "" {
+		if err := s.waitForNewTip(ctx, longPollID); err != nil {
+			return BlockTemplate{}, err
+		}
+	}
+
+	for {
+		s.mu.Lock()
+		tipChanged := s.tipChanged
+		s.mu.Unlock()
+
+		template, err := s.createTemplate()
+		if err != nil {
+			return BlockTemplate{}, err
+		}
+
+		s.mu.Lock()
+		if template.PreviousBlockHash == s.tip {
+			template = s.storeTemplate(template)
+			s.mu.Unlock()
+			return template, nil
+		}
+		currentTipChanged := s.tipChanged
+		s.mu.Unlock()
+
+		// The template is stale if the tip changed while it was created. Otherwise the block store is ahead of
+		// the notification of the new tip, which drops the templates of the old tip, so wait for it before rebuilding.
+		if currentTipChanged == tipChanged {
+			select {
+			case <-tipChanged:
+			case <-ctx.Done():
+				return BlockTemplate{}, ctx.Err()
+			}
+		}
+	}
+}
+
+// storeTemplate assigns the next ID to a template on the current tip and stores it for SubmitBlock. mu must be held.
+func (s *BlockTemplateService) storeTemplate(template BlockTemplate) BlockTemplate {
+	s.nextID++
+	template.ID = s.nextID
+	s.templates[template.ID] = template
+	// IDs of the templates of a tip are consecutive, so this drops the oldest one
+	delete(s.templates, template.ID-maxStoredTemplates)
+
+	logger.Debugf("[miner] Created block template %d at height %d with %d transactions", template.ID, template.Height, len(template.Transactions))
+	return template
+}
+
+// waitForNewTip blocks until the main chain tip differs from the tip with the given long poll ID or the longPollTimeout elapsed.
+func (s *BlockTemplateService) waitForNewTip(ctx context.Context, longPollID string) error {
+	s.mu.Lock()
+	tip := s.tip
+	tipChanged := s.tipChanged
+	s.mu.Unlock()
+
+	if tip.String() != longPollID {
+		return nil
+	}
+
+	timer := time.NewTimer(s.longPollTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-tipChanged:
+		return nil
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// createTemplate selects the transactions of a new block template, leaving MaxTemplateCoinbaseSize for the coinbase.
+func (s *BlockTemplateService) createTemplate() (BlockTemplate, error) {
+	tip := s.blockStore.GetMainChainTip()
+	previousBlockHash := tip.Hash()
+	height := s.blockStore.GetMainChainHeight() + 1
+
+	targetBits, err := s.blockStore.GetNextDifficultyTarget(previousBlockHash)
+	if err != nil {
+		return BlockTemplate{}, err
+	}
+
+	medianTimePast, err := s.blockStore.GetMedianTimePast(previousBlockHash)
+	if err != nil {
+		return BlockTemplate{}, err
+	}
+
+	transactionsWithFees, err := getTransactionWithFee(s.utxoService, s.transactions.GetTransactionsForMining(), previousBlockHash)
+	if err != nil {
+		return BlockTemplate{}, err
+	}
+
+	availableSize := block.MaxBlockSize - block.HeaderSize - 4 - MaxTemplateCoinbaseSize
+	selected := newBlockTemplateBuilder(transactionsWithFees, availableSize).build()
+
+	coinbaseValue := block.BlockSubsidy(height)
+	templateTransactions := make([]TemplateTransaction, 0, len(selected))
+	txs := make([]transaction.Transaction, 0, len(selected))
+	for _, tx := range selected {
+		coinbaseValue += tx.Fee
+		templateTransactions = append(templateTransactions, TemplateTransaction{Transaction: tx.tx, Fee: tx.Fee})
+		txs = append(txs, tx.tx)
+	}
+
+	return BlockTemplate{
+		PreviousBlockHash: previousBlockHash,
+		Height:            height,
+		DifficultyTarget:  targetBits,
+		MinTimestamp:      medianTimePast + 1,
+		CurrentTimestamp:  max(time.Now().Unix(), medianTimePast+1),
+		CoinbaseValue:     coinbaseValue,
+		MaxCoinbaseSize:   MaxTemplateCoinbaseSize,
+		Transactions:      templateTransactions,
+		MerkleBranch:      block.CoinbaseMerkleBranch(txs),
+		LongPollID:        previousBlockHash.String(),
+	}, nil
+}
+
+// SubmitBlock assembles the block of the template with the given ID from the solved header and the coinbase of the miner
+// and hands it to the blockchain like a self-mined block. Header and coinbase are checked against the template first,
+// the block is fully validated by the blockchain. Returns the hash of the block if it became the new main chain tip.
+// An accepted block is recorded in the mining status like a block of the own miner, see SetMinedBlockRecorder.
+func (s *BlockTemplateService) SubmitBlock(templateID uint64, header block.BlockHeader, coinbase transaction.Transaction) (common.Hash, error) {
+	s.mu.Lock()
+	template, ok := s.templates[templateID]
+	recorder := s.recorder
+	s.mu.Unlock()
+	if !ok {
+		return common.Hash{}, ErrUnknownTemplate
+	}
+
+	if !coinbase.IsCoinbase() {
+		return common.Hash{}, ErrInvalidTemplateCoinbase
+	}
+	if size := coinbase.SerializedSize(); size > template.MaxCoinbaseSize {
+		return common.Hash{}, fmt.Errorf("%w: size %d exceeds %d bytes", ErrInvalidTemplateCoinbase, size, template.MaxCoinbaseSize)
+	}
+	if header.PreviousBlockHash != template.PreviousBlockHash {
+		return common.Hash{}, fmt.Errorf("%w: previous block hash %v, want %v", ErrTemplateMismatch, header.PreviousBlockHash, template.PreviousBlockHash)
+	}
+	if header.DifficultyTarget != template.DifficultyTarget {
+		return common.Hash{}, fmt.Errorf("%w: difficulty target %d, want %d", ErrTemplateMismatch, header.DifficultyTarget, template.DifficultyTarget)
+	}
+	if merkleRoot := block.MerkleRootFromCoinbase(coinbase.Hash(), template.MerkleBranch); header.MerkleRoot != merkleRoot {
+		return common.Hash{}, fmt.Errorf("%w: merkle root %v, want %v", ErrTemplateMismatch, header.MerkleRoot, merkleRoot)
+	}
+
+	transactions := make([]transaction.Transaction, 0, len(template.Transactions)+1)
+	transactions = append(transactions, coinbase)
+	for _, tx := range template.Transactions {
+		transactions = append(transactions, tx.Transaction)
+	}
+	solvedBlock := block.Block{Header: header, Transactions: transactions}
+
+	if solvedBlock.BlockDifficulty() < header.DifficultyTarget {
+		return common.Hash{}, ErrInsufficientProofOfWork
+	}
+
+	logger.Infof("[miner] Block %v of template %d submitted by external miner", &solvedBlock.Header, templateID)
+	s.blockchain.AddSelfMinedBlock(solvedBlock)
+
+	blockHash := solvedBlock.Hash()
+	if s.mainChainTip() != blockHash {
+		return common.Hash{}, ErrBlockRejected
+	}
+	if recorder != nil {
+		recorder.recordSubmittedBlock(solvedBlock, template.Height)
+	}
+	return blockHash, nil
+}
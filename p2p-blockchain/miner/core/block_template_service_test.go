@@ -0,0 +1,337 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"testing"
+	"time"
+)
+
+// mockTransactionSource is a mock implementation of transactionSource
+type mockTransactionSource struct {
+	transactions []transaction.Transaction
+	// beforeNextGet is called once by the next GetTransactionsForMining, if set
+	beforeNextGet func()
+}
+
+func (m *mockTransactionSource) GetTransactionsForMining() []transaction.Transaction {
+	if beforeNextGet := m.beforeNextGet; beforeNextGet != nil {
+		m.beforeNextGet = nil
+		beforeNextGet()
+	}
+	return m.transactions
+}
+
+// tipUpdatingBlockchain accepts every block as the new main chain tip of the block store
+type tipUpdatingBlockchain struct {
+	blockStore *mockBlockStore
+}
+
+func (b *tipUpdatingBlockchain) AddSelfMinedBlock(selfMinedBlock block.Block) {
+	b.blockStore.tip = selfMinedBlock
+}
+
+// createTestBlockTemplateService creates a template service on top of a tip with low difficulty.
+// The mempool contains a transaction spending a UTXO of 100 with an output of 90.
+func createTestBlockTemplateService() (*BlockTemplateService, *mockBlockStore) {
+	service, blockStore, _ := createTestBlockTemplateServiceWithSource()
+	return service, blockStore
+}
+
+// createTestBlockTemplateServiceWithSource is createTestBlockTemplateService also returning the transaction source.
+func createTestBlockTemplateServiceWithSource() (*BlockTemplateService, *mockBlockStore, *mockTransactionSource) {
+	tip := createGenesisBlock()
+	tip.Header.DifficultyTarget = 4
+
+	prevTxID := transaction.TransactionID{}
+	prevTxID[0] = 0xAA
+	utxos := map[utxoOutpoint]transaction.Output{
+		{txID: prevTxID, outputIndex: 0}: {Value: 100},
+	}
+
+	blockStore := &mockBlockStore{tip: tip}
+	source := &mockTransactionSource{transactions: []transaction.Transaction{createTestTransaction(100, 90)}}
+	service := NewBlockTemplateService(&tipUpdatingBlockchain{blockStore: blockStore}, &mockUtxoStoreAPI{utxos: utxos}, blockStore, source)
+	return service, blockStore, source
+}
+
+// solveTemplate builds the block of the template with a coinbase paying the coinbase value and searches a valid nonce.
+func solveTemplate(t *testing.T, template BlockTemplate) (block.BlockHeader, transaction.Transaction) {
+	t.Helper()
+
+	coinbase := transaction.NewCoinbaseTransaction(transaction.PubKeyHash{}, template.CoinbaseValue, template.Height)
+	header := block.BlockHeader{
+		PreviousBlockHash: template.PreviousBlockHash,
+		MerkleRoot:        block.MerkleRootFromCoinbase(coinbase.Hash(), template.MerkleBranch),
+		Timestamp:         template.CurrentTimestamp,
+		DifficultyTarget:  template.DifficultyTarget,
+	}
+
+	for nonce := uint32(0); nonce < 1<<20; nonce++ {
+		header.Nonce = nonce
+		solved := block.Block{Header: header}
+		if solved.BlockDifficulty() >= header.DifficultyTarget {
+			return header, coinbase
+		}
+	}
+	t.Fatal("no valid nonce found")
+	return block.BlockHeader{}, transaction.Transaction{}
+}
+
+func TestBlockTemplateService_GetBlockTemplate(t *testing.T) {
+	service, blockStore := createTestBlockTemplateService()
+
+	template, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+
+	tipHash := blockStore.tip.Hash()
+	if template.PreviousBlockHash != tipHash {
+		t.Errorf("PreviousBlockHash = %v, want %v", template.PreviousBlockHash, tipHash)
+	}
+	if template.Height != 1 {
+		t.Errorf("Height = %d, want 1", template.Height)
+	}
+	if template.LongPollID != tipHash.String() {
+		t.Errorf("LongPollID = %q, want %q", template.LongPollID, tipHash.String())
+	}
+	if len(template.Transactions) != 1 || template.Transactions[0].Fee != 10 {
+		t.Fatalf("Transactions = %+v, want one transaction with fee 10", template.Transactions)
+	}
+	if want := block.BlockSubsidy(1) + 10; template.CoinbaseValue != want {
+		t.Errorf("CoinbaseValue = %d, want %d", template.CoinbaseValue, want)
+	}
+	if template.MinTimestamp != blockStore.tip.Header.Timestamp+1 {
+		t.Errorf("MinTimestamp = %d, want %d", template.MinTimestamp, blockStore.tip.Header.Timestamp+1)
+	}
+
+	second, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+	if second.ID == template.ID {
+		t.Error("Templates share the same ID")
+	}
+}
+
+func TestBlockTemplateService_SubmitBlock_AcceptsSolvedBlock(t *testing.T) {
+	service, blockStore := createTestBlockTemplateService()
+
+	template, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+	header, coinbase := solveTemplate(t, template)
+
+	blockHash, err := service.SubmitBlock(template.ID, header, coinbase)
+	if err != nil {
+		t.Fatalf("SubmitBlock() returned error: %v", err)
+	}
+
+	if blockHash != header.Hash() {
+		t.Errorf("SubmitBlock() = %v, want %v", blockHash, header.Hash())
+	}
+	submitted := blockStore.tip
+	if len(submitted.Transactions) != 2 || submitted.Transactions[0].Hash() != coinbase.Hash() {
+		t.Fatalf("Submitted block has transactions %+v, want coinbase and template transaction", submitted.Transactions)
+	}
+	if submitted.MerkleRoot() != header.MerkleRoot {
+		t.Error("Merkle root of the submitted block does not match its transactions")
+	}
+}
+
+func TestBlockTemplateService_SubmitBlock_RecordsMinedBlock(t *testing.T) {
+	service, blockStore := createTestBlockTemplateService()
+	miner := createTestMinerService(blockStore.tip, nil)
+	miner.blockStore = blockStore
+	service.SetMinedBlockRecorder(miner)
+
+	template, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+	header, coinbase := solveTemplate(t, template)
+
+	if _, err := service.SubmitBlock(template.ID, header, coinbase); err != nil {
+		t.Fatalf("SubmitBlock() returned error: %v", err)
+	}
+
+	status := miner.GetMiningStatus()
+	if len(status.MinedBlocks) != 1 {
+		t.Fatalf("expected 1 mined block, got %d", len(status.MinedBlocks))
+	}
+	mined := status.MinedBlocks[0]
+	if mined.Hash != header.Hash() || mined.Height != template.Height || mined.Reward != template.CoinbaseValue {
+		t.Errorf("expected block %v at height %d with reward %d, got %+v", header.Hash(), template.Height, template.CoinbaseValue, mined)
+	}
+	if status.TotalRewards != template.CoinbaseValue {
+		t.Errorf("expected total rewards of %d, got %d", template.CoinbaseValue, status.TotalRewards)
+	}
+}
+
+func TestBlockTemplateService_SubmitBlock_DoesNotRecordRejectedBlock(t *testing.T) {
+	service, blockStore := createTestBlockTemplateService()
+	// The blockchain does not accept the block as the new main chain tip
+	service.blockchain = &mockBlockchainAPI{}
+	miner := createTestMinerService(blockStore.tip, nil)
+	service.SetMinedBlockRecorder(miner)
+
+	template, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+	header, coinbase := solveTemplate(t, template)
+
+	if _, err := service.SubmitBlock(template.ID, header, coinbase); !errors.Is(err, ErrBlockRejected) {
+		t.Fatalf("SubmitBlock() returned %v, want %v", err, ErrBlockRejected)
+	}
+
+	if mined := miner.GetMiningStatus().MinedBlocks; len(mined) != 0 {
+		t.Errorf("expected no mined blocks for a rejected block, got %+v", mined)
+	}
+}
+
+func TestBlockTemplateService_SubmitBlock_RejectsInvalidBlocks(t *testing.T) {
+	service, _ := createTestBlockTemplateService()
+
+	template, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+	header, coinbase := solveTemplate(t, template)
+
+	if _, err := service.SubmitBlock(template.ID+1, header, coinbase); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("SubmitBlock() with unknown template returned %v, want %v", err, ErrUnknownTemplate)
+	}
+
+	if _, err := service.SubmitBlock(template.ID, header, createTestTransaction(0, 1)); !errors.Is(err, ErrInvalidTemplateCoinbase) {
+		t.Errorf("SubmitBlock() without coinbase returned %v, want %v", err, ErrInvalidTemplateCoinbase)
+	}
+
+	otherCoinbase := transaction.NewCoinbaseTransaction(transaction.PubKeyHash{}, template.CoinbaseValue, template.Height)
+	if _, err := service.SubmitBlock(template.ID, header, otherCoinbase); !errors.Is(err, ErrTemplateMismatch) {
+		t.Errorf("SubmitBlock() with other coinbase returned %v, want %v", err, ErrTemplateMismatch)
+	}
+
+	unsolved := header
+	for {
+		unsolved.Nonce++
+		candidate := block.Block{Header: unsolved}
+		if candidate.BlockDifficulty() < unsolved.DifficultyTarget {
+			break
+		}
+	}
+	if _, err := service.SubmitBlock(template.ID, unsolved, coinbase); !errors.Is(err, ErrInsufficientProofOfWork) {
+		t.Errorf("SubmitBlock() without proof of work returned %v, want %v", err, ErrInsufficientProofOfWork)
+	}
+}
+
+func TestBlockTemplateService_LongPoll_ReturnsOnTipChange(t *testing.T) {
+	service, blockStore := createTestBlockTemplateService()
+
+	template, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+
+	results := make(chan BlockTemplate, 1)
+	go func() {
+		next, err := service.GetBlockTemplate(context.Background(), template.LongPollID)
+		if err != nil {
+			t.Errorf("GetBlockTemplate() returned error: %v", err)
+		}
+		results <- next
+	}()
+
+	select {
+	case <-results:
+		t.Fatal("Long poll returned before the tip changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	header, coinbase := solveTemplate(t, template)
+	newTip := block.Block{Header: header, Transactions: []transaction.Transaction{coinbase}}
+	// The lock orders the write before the read of the woken long poll, the mock block store is not synchronized
+	service.mu.Lock()
+	blockStore.tip = newTip
+	service.mu.Unlock()
+	service.TipChanged(newTip.Hash())
+
+	select {
+	case next := <-results:
+		if next.PreviousBlockHash != newTip.Hash() {
+			t.Errorf("Long poll returned template on %v, want %v", next.PreviousBlockHash, newTip.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Long poll did not return after the tip changed")
+	}
+
+	if _, err := service.SubmitBlock(template.ID, header, coinbase); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("SubmitBlock() for template of old tip returned %v, want %v", err, ErrUnknownTemplate)
+	}
+}
+
+func TestBlockTemplateService_GetBlockTemplate_TipChangesDuringCreation(t *testing.T) {
+	service, blockStore, source := createTestBlockTemplateServiceWithSource()
+
+	current, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+	header, coinbase := solveTemplate(t, current)
+	newTip := block.Block{Header: header, Transactions: []transaction.Transaction{coinbase}}
+	// The new tip arrives after the template creation read the old tip
+	source.beforeNextGet = func() {
+		service.mu.Lock()
+		blockStore.tip = newTip
+		service.mu.Unlock()
+		service.TipChanged(newTip.Hash())
+	}
+
+	template, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+
+	if template.PreviousBlockHash != newTip.Hash() {
+		t.Errorf("GetBlockTemplate() returned template on %v, want %v", template.PreviousBlockHash, newTip.Hash())
+	}
+	service.mu.Lock()
+	tip := service.tip
+	_, stored := service.templates[template.ID]
+	service.mu.Unlock()
+	if tip != newTip.Hash() {
+		t.Errorf("Tip of the service moved back to %v, want %v", tip, newTip.Hash())
+	}
+	if !stored {
+		t.Error("Template on the new tip was not stored")
+	}
+}
+
+func TestBlockTemplateService_LongPoll_Timeout(t *testing.T) {
+	service, _ := createTestBlockTemplateService()
+	service.longPollTimeout = 10 * time.Millisecond
+
+	template, err := service.GetBlockTemplate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+
+	next, err := service.GetBlockTemplate(context.Background(), template.LongPollID)
+	if err != nil {
+		t.Fatalf("GetBlockTemplate() returned error: %v", err)
+	}
+	if next.PreviousBlockHash != template.PreviousBlockHash {
+		t.Error("Template after long poll timeout is not on the same tip")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	service.longPollTimeout = time.Minute
+	if _, err := service.GetBlockTemplate(ctx, template.LongPollID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetBlockTemplate() with cancelled context returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"testing"
+)
+
+// syntheticMempool builds transactions with the given fee and size for the block template builder.
+// Transactions spend a distinct confirmed output or the outputs of the given parents.
+type syntheticMempool struct {
+	transactions []transactionWithFee
+	nextSeed     byte
+}
+
+// add adds a transaction spending the first output of each parent (or a confirmed output without parents) and returns it.
+func (s *syntheticMempool) add(fee uint64, size int, parents ...transaction.Transaction) transaction.Transaction {
+	s.nextSeed++
+	tx := transaction.Transaction{Outputs: []transaction.Output{{Value: uint64(s.nextSeed)}}}
+	for _, parent := range parents {
+		tx.Inputs = append(tx.Inputs, transaction.Input{PrevTxID: parent.TransactionId()})
+	}
+	if len(parents) == 0 {
+		tx.Inputs = append(tx.Inputs, transaction.Input{PrevTxID: transaction.TransactionID{s.nextSeed}})
+	}
+
+	s.transactions = append(s.transactions, transactionWithFee{tx: tx, Fee: fee, Size: size})
+	return tx
+}
+
+func (s *syntheticMempool) build(availableSize int) []transactionWithFee {
+	return newBlockTemplateBuilder(s.transactions, availableSize).build()
+}
+
+func assertSelected(t *testing.T, selected []transactionWithFee, expected ...transaction.Transaction) {
+	t.Helper()
+	if len(selected) != len(expected) {
+		t.Fatalf("build() returned %d transactions, want %d", len(selected), len(expected))
+	}
+	for i, tx := range expected {
+		if selected[i].tx.TransactionId() != tx.TransactionId() {
+			t.Errorf("Transaction %d = %v, want %v", i, selected[i].tx.TransactionId(), tx.TransactionId())
+		}
+	}
+}
+
+func TestBlockTemplateBuilder_OrdersByFeeRate(t *testing.T) {
+	mempool := &syntheticMempool{}
+	// tx2 pays the highest fee but is large, so its fee rate is the lowest
+	tx1 := mempool.add(10, 100)
+	tx2 := mempool.add(30, 1000)
+	tx3 := mempool.add(20, 100)
+
+	assertSelected(t, mempool.build(10_000), tx3, tx1, tx2)
+}
+
+func TestBlockTemplateBuilder_ChildPaysForParent(t *testing.T) {
+	mempool := &syntheticMempool{}
+	parent := mempool.add(1, 100)
+	other := mempool.add(20, 100)
+	child := mempool.add(50, 100, parent)
+
+	// Only two transactions fit. The package of parent and child (51 / 200) beats the other transaction (20 / 100),
+	// although the parent alone pays the lowest fee rate.
+	assertSelected(t, mempool.build(200), parent, child)
+
+	// With enough space everything is selected, the parent still precedes its child
+	assertSelected(t, mempool.build(1000), parent, child, other)
+}
+
+func TestBlockTemplateBuilder_ChainInDependencyOrder(t *testing.T) {
+	mempool := &syntheticMempool{}
+	grandparent := mempool.add(1, 100)
+	parent := mempool.add(1, 100, grandparent)
+	grandchild := mempool.add(100, 100, parent)
+	other := mempool.add(30, 100)
+
+	// The package of the grandchild (102 / 300) has a higher fee rate than the other transaction (30 / 100)
+	assertSelected(t, mempool.build(1000), grandparent, parent, grandchild, other)
+}
+
+func TestBlockTemplateBuilder_SharedParentIsSelectedOnce(t *testing.T) {
+	mempool := &syntheticMempool{}
+	parent := mempool.add(1, 100)
+	highChild := mempool.add(60, 100, parent)
+	lowChild := mempool.add(5, 100, parent)
+	other := mempool.add(10, 100)
+
+	// After the package of parent and highChild is selected, lowChild (5 / 100) is rated alone and follows other (10 / 100)
+	assertSelected(t, mempool.build(1000), parent, highChild, other, lowChild)
+}
+
+func TestBlockTemplateBuilder_ChildWithTwoParents(t *testing.T) {
+	mempool := &syntheticMempool{}
+	parentA := mempool.add(1, 100)
+	parentB := mempool.add(1, 100)
+	child := mempool.add(100, 100, parentA, parentB)
+
+	selected := mempool.build(1000)
+
+	if len(selected) != 3 || selected[2].tx.TransactionId() != child.TransactionId() {
+		t.Fatalf("Child should be selected after both of its parents, got %d transactions", len(selected))
+	}
+}
+
+func TestBlockTemplateBuilder_RespectsAvailableSize(t *testing.T) {
+	mempool := &syntheticMempool{}
+	large := mempool.add(100, 600)
+	mempool.add(20, 300)
+	small := mempool.add(5, 200)
+
+	selected := mempool.build(850)
+
+	// The large transaction has the highest fee rate. The medium one does not fit anymore, but the small one does.
+	assertSelected(t, selected, large, small)
+	totalSize := 0
+	for _, tx := range selected {
+		totalSize += tx.Size
+	}
+	if totalSize > 850 {
+		t.Errorf("Selected transactions have a size of %d, exceeding the available size of 850", totalSize)
+	}
+}
+
+func TestBlockTemplateBuilder_SkipsPackageThatDoesNotFit(t *testing.T) {
+	mempool := &syntheticMempool{}
+	parent := mempool.add(10, 500)
+	mempool.add(50, 100, parent)
+
+	// The parent does not fit, so the child cannot be included either
+	assertSelected(t, mempool.build(300))
+}
@@ -1,23 +1,21 @@
 package core
 
 import (
+	blockchainApi "s3b/vsp-blockchain/p2p-blockchain/blockchain/api"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
-	"slices"
-	"sort"
 	"time"
 
 	"bjoernblessin.de/go-utils/util/logger"
 )
 
 type transactionWithFee struct {
-	tx  transaction.Transaction
-	Fee uint64
+	tx   transaction.Transaction
+	Fee  uint64
+	Size int
 }
 
-const TxPerBlock = 100
-
 func (m *minerService) createCandidateBlock(transactions []transaction.Transaction, height uint64, currentTip common.Hash) (block.Block, error) {
 	tx, err := m.buildTransactions(transactions, height, currentTip)
 	if err != nil {
@@ -39,48 +37,54 @@ func (m *minerService) createCandidateBlockHeader(transactions []transaction.Tra
 	merkleRoot := block.MerkleRootFromTransactions(transactions)
 	logger.Tracef("[miner] Calculated merkle root: %v", merkleRoot)
 
-	targetBits, err := GetCurrentTargetBits()
+	targetBits, err := m.blockStore.GetNextDifficultyTarget(previousBlockHash)
+	if err != nil {
+		return block.BlockHeader{}, err
+	}
+
+	medianTimePast, err := m.blockStore.GetMedianTimePast(previousBlockHash)
 	if err != nil {
 		return block.BlockHeader{}, err
 	}
 
+	// The timestamp has to be later than the median-time-past, even if the local clock is behind
+	timestamp := max(time.Now().Unix(), medianTimePast+1)
+
 	blockHeader := block.BlockHeader{
 		PreviousBlockHash: previousBlockHash,
 		MerkleRoot:        merkleRoot,
-		Timestamp:         time.Now().Unix(),
+		Timestamp:         timestamp,
 		DifficultyTarget:  targetBits,
 		Nonce:             0,
 	}
 	return blockHeader, nil
 }
 
+// buildTransactions selects the transactions of the candidate block by ancestor fee rate until the block reaches block.MaxBlockSize
+// and prepends the coinbase transaction that collects the fees of the selected transactions, see blockTemplateBuilder.
+// Transactions may spend outputs of other given transactions (unconfirmed chains), these parents are placed before their children.
 func (m *minerService) buildTransactions(transactions []transaction.Transaction, height uint64, currentTip common.Hash) ([]transaction.Transaction, error) {
-	transactionsWithFees, err := m.getTransactionWithFee(transactions, currentTip)
+	transactionsWithFees, err := getTransactionWithFee(m.utxoService, transactions, currentTip)
 	if err != nil {
 		return nil, err
 	}
 
-	transactionsSorted := m.sortAndReversedTransactions(transactionsWithFees)
+	// The coinbase size does not depend on the reward, only on the number of payouts and the coinbase data
+	placeholderCoinbase := transaction.NewCoinbaseTransactionWithPayouts(m.payouts, 0, height, m.coinbaseTag)
+	availableSize := block.MaxBlockSize - block.HeaderSize - 4 - placeholderCoinbase.SerializedSize()
+	selected := newBlockTemplateBuilder(transactionsWithFees, availableSize).build()
 
-	coinbaseTx, err := m.createCoinbaseTransaction(transactionsWithFees, height)
+	coinbaseTx, err := m.createCoinbaseTransaction(selected, height)
 	if err != nil {
 		return nil, err
 	}
-	transactionCount := int(min(TxPerBlock-1, float64(len(transactionsSorted))))
-	txToPutInBlock := append([]transaction.Transaction{coinbaseTx}, transactionsSorted[:transactionCount]...)
-	return txToPutInBlock, nil
-}
 
-func (m *minerService) sortAndReversedTransactions(transactionsWithFees []transactionWithFee) []transaction.Transaction {
-	sort.Slice(transactionsWithFees[:], func(i, j int) bool {
-		return transactionsWithFees[i].Fee < transactionsWithFees[j].Fee
-	})
-	transactionsSorted := make([]transaction.Transaction, len(transactionsWithFees))
-	for i, tx := range transactionsWithFees {
-		transactionsSorted[i] = tx.tx
+	txToPutInBlock := make([]transaction.Transaction, 0, len(selected)+1)
+	txToPutInBlock = append(txToPutInBlock, coinbaseTx)
+	for _, tx := range selected {
+		txToPutInBlock = append(txToPutInBlock, tx.tx)
 	}
-	slices.Reverse(transactionsSorted)
-	return transactionsSorted
+	return txToPutInBlock, nil
 }
 
 func (m *minerService) createCoinbaseTransaction(transactions []transactionWithFee, height uint64) (transaction.Transaction, error) {
@@ -89,21 +93,25 @@ func (m *minerService) createCoinbaseTransaction(transactions []transactionWithF
 		sumOfFees += tx.Fee
 	}
 
-	reward, err := GetCurrentReward()
-	if err != nil {
-		return transaction.Transaction{}, err
-	}
+	reward := block.BlockSubsidy(height)
 
-	coinbaseTransaction := transaction.NewCoinbaseTransaction(m.ownPubKeyHash, sumOfFees+reward, height)
+	coinbaseTransaction := transaction.NewCoinbaseTransactionWithPayouts(m.payouts, sumOfFees+reward, height, m.coinbaseTag)
 
 	return coinbaseTransaction, nil
 }
 
-func (m *minerService) getTransactionWithFee(transactions []transaction.Transaction, currentTip common.Hash) ([]transactionWithFee, error) {
+// getTransactionWithFee calculates the fee and size of the transactions.
+// Inputs are looked up in the UTXO set at currentTip and in the outputs of the other given transactions.
+func getTransactionWithFee(utxoService blockchainApi.UtxoStoreAPI, transactions []transaction.Transaction, currentTip common.Hash) ([]transactionWithFee, error) {
+	unconfirmed := make(map[transaction.TransactionID]transaction.Transaction, len(transactions))
+	for _, tx := range transactions {
+		unconfirmed[tx.TransactionId()] = tx
+	}
+
 	transactionsWithFees := make([]transactionWithFee, len(transactions))
 	for i, tx := range transactions {
 		var inputSum uint64
-		inputSum, err := m.getInputSum(tx, currentTip)
+		inputSum, err := getInputSum(utxoService, tx, currentTip, unconfirmed)
 		if err != nil {
 			return nil, err
 		}
@@ -111,15 +119,22 @@ func (m *minerService) getTransactionWithFee(transactions []transaction.Transact
 		for _, output := range tx.Outputs {
 			outputSum += output.Value
 		}
-		transactionsWithFees[i] = transactionWithFee{tx, inputSum - outputSum}
+		transactionsWithFees[i] = transactionWithFee{tx: tx, Fee: inputSum - outputSum, Size: tx.SerializedSize()}
 	}
 
 	return transactionsWithFees, nil
 }
 
-func (m *minerService) getInputSum(tx transaction.Transaction, currentTip common.Hash) (inputSum uint64, err error) {
+// getInputSum sums up the referenced outputs of the inputs of the transaction.
+// Outputs of the unconfirmed transactions are used first, all other outputs are looked up in the UTXO set at currentTip.
+func getInputSum(utxoService blockchainApi.UtxoStoreAPI, tx transaction.Transaction, currentTip common.Hash, unconfirmed map[transaction.TransactionID]transaction.Transaction) (inputSum uint64, err error) {
 	for _, input := range tx.Inputs {
-		utxoResult, err := m.utxoService.GetUtxoFromBlock(input.PrevTxID, input.OutputIndex, currentTip)
+		if parent, ok := unconfirmed[input.PrevTxID]; ok && int(input.OutputIndex) < len(parent.Outputs) {
+			inputSum += parent.Outputs[input.OutputIndex].Value
+			continue
+		}
+
+		utxoResult, err := utxoService.GetUtxoFromBlock(input.PrevTxID, input.OutputIndex, currentTip)
 		if err != nil {
 			return 0, err
 		}
@@ -127,11 +142,3 @@ func (m *minerService) getInputSum(tx transaction.Transaction, currentTip common
 	}
 	return inputSum, nil
 }
-
-func GetCurrentTargetBits() (uint8, error) {
-	return 28, nil
-}
-
-func GetCurrentReward() (uint64, error) {
-	return 50, nil
-}
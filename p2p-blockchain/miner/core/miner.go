@@ -5,15 +5,35 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
+	"runtime"
 	blockchainApi "s3b/vsp-blockchain/p2p-blockchain/blockchain/api"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 	"s3b/vsp-blockchain/p2p-blockchain/miner/data"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"bjoernblessin.de/go-utils/util/logger"
 )
 
+// hashRateInterval is the interval in which the hash rate is updated while mining.
+const hashRateInterval = 10 * time.Second
+
+// MinerConfig contains the settings of the miner.
+type MinerConfig struct {
+	// Workers is the number of goroutines searching for a valid nonce in parallel.
+	Workers int
+}
+
+// DefaultMinerConfig returns the config with one worker per CPU.
+func DefaultMinerConfig() MinerConfig {
+	return MinerConfig{
+		Workers: runtime.NumCPU(),
+	}
+}
+
 type minerService struct {
 	mu            sync.RWMutex
 	miningEnabled bool
@@ -21,21 +41,45 @@ type minerService struct {
 	blockchain    blockchainApi.BlockchainAPI
 	utxoService   blockchainApi.UtxoStoreAPI
 	blockStore    blockchainApi.BlockStoreAPI
+	config        MinerConfig
 
-	ownPubKeyHash transaction.PubKeyHash
+	// payouts split the rewards of mined blocks, see SetPayouts. Protected by mu.
+	payouts []transaction.CoinbasePayout
+	// coinbaseTag is embedded in the coinbase of mined blocks, see SetCoinbaseTag. Protected by mu.
+	coinbaseTag []byte
+	// hashRate is the number of hashes per second, see HashRate.
+	hashRate atomic.Uint64
+	// hashesTried is the number of hashes computed since the start of the node, see GetMiningStatus.
+	hashesTried atomic.Uint64
+	// candidate is the candidate block whose nonce is searched, nil if not mining. Protected by mu.
+	candidate *candidateInfo
+	// minedBlocks are the blocks mined since the start of the node. Protected by mu.
+	minedBlocks []minedBlock
 }
 
+// NewMinerService creates a miner with the DefaultMinerConfig.
 func NewMinerService(
 	blockchain blockchainApi.BlockchainAPI,
 	utxoServiceAPI blockchainApi.UtxoStoreAPI,
 	blockStore blockchainApi.BlockStoreAPI,
+) *minerService {
+	return NewMinerServiceWithConfig(blockchain, utxoServiceAPI, blockStore, DefaultMinerConfig())
+}
+
+// NewMinerServiceWithConfig creates a miner with the given settings.
+func NewMinerServiceWithConfig(
+	blockchain blockchainApi.BlockchainAPI,
+	utxoServiceAPI blockchainApi.UtxoStoreAPI,
+	blockStore blockchainApi.BlockStoreAPI,
+	config MinerConfig,
 ) *minerService {
 	return &minerService{
 		blockchain:    blockchain,
 		utxoService:   utxoServiceAPI,
 		blockStore:    blockStore,
+		config:        config,
 		miningEnabled: true,
-		ownPubKeyHash: ChoosePubKeyHash(),
+		payouts:       []transaction.CoinbasePayout{{PubKeyHash: ChoosePubKeyHash(), Percent: 100}},
 	}
 }
 
@@ -46,6 +90,36 @@ func ChoosePubKeyHash() transaction.PubKeyHash {
 	return keys[index]
 }
 
+// SetPayouts sets the addresses receiving the rewards of mined blocks and their shares, see transaction.ValidateCoinbasePayouts.
+// The payouts apply from the next candidate block on.
+func (m *minerService) SetPayouts(payouts []transaction.CoinbasePayout) error {
+	if err := transaction.ValidateCoinbasePayouts(payouts); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logger.Infof("[miner] Paying block rewards to %d addresses", len(payouts))
+	m.payouts = slices.Clone(payouts)
+	return nil
+}
+
+// SetCoinbaseTag sets the message embedded in the coinbase of mined blocks, at most transaction.MaxCoinbaseTagSize bytes.
+// An empty tag embeds no message. The tag applies from the next candidate block on.
+func (m *minerService) SetCoinbaseTag(tag []byte) error {
+	if err := transaction.ValidateCoinbaseTag(tag); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logger.Infof("[miner] Tagging coinbase transactions with %q", tag)
+	m.coinbaseTag = slices.Clone(tag)
+	return nil
+}
+
 func (m *minerService) StartMining(transactions []transaction.Transaction) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -62,7 +136,8 @@ func (m *minerService) StartMining(transactions []transaction.Transaction) {
 	tip := m.blockStore.GetMainChainTip()
 	previousBlockHash := tip.Hash()
 	logger.Infof("[miner] Started mining new block with %d transactions (+1 Coinbase) and PrevBlockHash %v", len(transactions), previousBlockHash)
-	candidateBlock, err := m.createCandidateBlock(transactions, m.blockStore.GetCurrentHeight()+1, previousBlockHash)
+	height := m.blockStore.GetMainChainHeight() + 1
+	candidateBlock, err := m.createCandidateBlock(transactions, height, previousBlockHash)
 	if err != nil {
 		logger.Warnf("[miner] Failed to create candidate block: %v", err)
 		return
@@ -70,16 +145,24 @@ func (m *minerService) StartMining(transactions []transaction.Transaction) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	m.cancelMining = cancel
+	candidate := &candidateInfo{
+		height:            height,
+		previousBlockHash: previousBlockHash,
+		transactionCount:  len(candidateBlock.Transactions) - 1,
+	}
+	m.candidate = candidate
 
 	go func() {
 		nonce, timestamp, err := m.mineBlock(candidateBlock, ctx)
 		if err != nil {
+			m.clearCandidate(candidate)
 			logger.Infof("[miner] Mining stopped: %v", err)
 			return
 		}
 		candidateBlock.Header.Nonce = nonce
 		candidateBlock.Header.Timestamp = timestamp
 		logger.Infof("[miner] Mined new block: %v", &candidateBlock.Header)
+		m.recordMinedBlock(candidate, candidateBlock)
 		m.blockchain.AddSelfMinedBlock(candidateBlock)
 	}()
 }
@@ -123,40 +206,73 @@ func (m *minerService) DisableMining() {
 	}
 }
 
-// MineBlock Mines a block by change the nonce until the block matches the given difficulty target
+// mineBlock searches a nonce and timestamp for which the block hash meets the difficulty target of the block.
+// The search is split among the configured number of workers (see MinerConfig), each in its own goroutine.
+// All workers stop as soon as one of them found a valid nonce or the context is cancelled.
+// The hash rate and the number of tried hashes are updated every hashRateInterval while mining and once the search ended, see HashRate.
 func (m *minerService) mineBlock(candidateBlock block.Block, ctx context.Context) (nonce uint32, timestamp int64, err error) {
-	target := getTarget(candidateBlock.Header.DifficultyTarget)
-	timestamp = candidateBlock.Header.Timestamp
+	workers := max(m.config.Workers, 1)
+
+	workerCtx, cancelWorkers := context.WithCancel(ctx)
+	results := make(chan powResult, workers)
+	var hashes atomic.Uint64
+	var wg sync.WaitGroup
+
+	startNonce := rand.Uint32()
+	for index := range workers {
+		wg.Go(func() {
+			searchNonces(workerCtx, candidateBlock.Header, startNonce, index, workers, &hashes, results)
+		})
+	}
 
-	var counter uint64 = 0
-	var hashInt big.Int
-	nonce = rand.Uint32()
+	start := time.Now()
+	ticker := time.NewTicker(hashRateInterval)
+	defer ticker.Stop()
+
+	// reportHashes updates the hash rate and adds the hashes since the last report to the total
+	var reported uint64
+	reportHashes := func() {
+		total := hashes.Load()
+		m.hashesTried.Add(total - reported)
+		reported = total
+		m.updateHashRate(total, time.Since(start))
+	}
+	stopWorkers := func() {
+		cancelWorkers()
+		wg.Wait()
+		reportHashes()
+	}
 
 	for {
 		select {
+		case result := <-results:
+			stopWorkers()
+			logger.Infof("[miner] Found valid nonce after %d hashes at %d H/s", hashes.Load(), m.HashRate())
+			return result.nonce, result.timestamp, nil
 		case <-ctx.Done():
+			stopWorkers()
 			logger.Infof("[miner] Mining cancelled")
 			return 0, 0, fmt.Errorf("mining cancelled")
-		default:
-			if counter > uint64(^uint32(0)) {
-				timestamp++
-				candidateBlock.Header.Timestamp = timestamp
-				counter = 0
-			}
-
-			candidateBlock.Header.Nonce = nonce
-			hash := candidateBlock.Hash()
-
-			hashInt.SetBytes(hash[:])
-			if hashInt.Cmp(&target) == -1 {
-				return nonce, timestamp, nil
-			}
-			nonce++
-			counter++
+		case <-ticker.C:
+			reportHashes()
+			logger.Debugf("[miner] Mining with %d workers at %d H/s", workers, m.HashRate())
 		}
 	}
 }
 
+// updateHashRate sets the hash rate to the number of hashes computed in the elapsed time.
+func (m *minerService) updateHashRate(hashes uint64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	m.hashRate.Store(uint64(float64(hashes) / elapsed.Seconds()))
+}
+
+// HashRate returns the number of block header hashes per second of the current or, if not mining, the last nonce search.
+func (m *minerService) HashRate() uint64 {
+	return m.hashRate.Load()
+}
+
 // getTarget calculates the target for the proof of work algorithm
 // It does so by shifting a one in a 256 bit number to the left by 256 - difficultyBits.
 // Theory: 0b1 << (256 - difficultyBits) But this is not possible as Go has no operator overloading :( and so big.Int is used
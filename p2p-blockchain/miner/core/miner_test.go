@@ -2,13 +2,13 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"s3b/vsp-blockchain/p2p-blockchain/blockchain/api"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
-	minerApi "s3b/vsp-blockchain/p2p-blockchain/miner/api"
 	"testing"
 	"time"
 )
@@ -41,6 +41,14 @@ func (m *mockUtxoStoreAPI) AddNewBlock(_ block.Block) error {
 	return nil
 }
 
+func (m *mockUtxoStoreAPI) DisconnectBlock(_ block.Block) error {
+	return nil
+}
+
+func (m *mockUtxoStoreAPI) GetChainTip() common.Hash {
+	return common.Hash{}
+}
+
 func (m *mockUtxoStoreAPI) GetUtxoFromBlock(prevTxID transaction.TransactionID, outputIndex uint32, _ common.Hash) (transaction.Output, error) {
 	outpoint := utxoOutpoint{txID: prevTxID, outputIndex: outputIndex}
 	if output, exists := m.utxos[outpoint]; exists {
@@ -49,6 +57,11 @@ func (m *mockUtxoStoreAPI) GetUtxoFromBlock(prevTxID transaction.TransactionID,
 	return transaction.Output{}, &utxoNotFoundError{}
 }
 
+func (m *mockUtxoStoreAPI) GetUtxoEntryFromBlock(prevTxID transaction.TransactionID, outputIndex uint32, blockHash common.Hash) (transaction.UTXO, error) {
+	output, err := m.GetUtxoFromBlock(prevTxID, outputIndex, blockHash)
+	return transaction.UTXO{TxID: prevTxID, OutputIndex: outputIndex, Output: output}, err
+}
+
 func (m *mockUtxoStoreAPI) ValidateTransactionFromBlock(_ transaction.Transaction, _ common.Hash) bool {
 	return true
 }
@@ -57,6 +70,14 @@ func (m *mockUtxoStoreAPI) GetUtxosByPubKeyHashFromBlock(_ transaction.PubKeyHas
 	return nil, nil
 }
 
+func (m *mockUtxoStoreAPI) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+
+func (m *mockUtxoStoreAPI) GetAncestorMedianTimePast(_ common.Hash, _ uint64) (int64, error) {
+	return 0, nil
+}
+
 type utxoOutpoint struct {
 	txID        transaction.TransactionID
 	outputIndex uint32
@@ -77,6 +98,10 @@ func (m *mockBlockStore) IsBlockInvalid(block block.Block) (bool, error) {
 	return false, nil
 }
 
+func (m *mockBlockStore) MarkBlockInvalid(_ common.Hash) error {
+	return nil
+}
+
 func (m *mockBlockStore) AddBlock(_ block.Block) []common.Hash {
 	return nil
 }
@@ -105,6 +130,10 @@ func (m *mockBlockStore) GetMainChainHeight() uint64 {
 	return 0
 }
 
+func (m *mockBlockStore) GetBlockHeight(_ common.Hash) (uint64, error) {
+	return 0, nil
+}
+
 func (m *mockBlockStore) GetMainChainTip() block.Block {
 	return m.tip
 }
@@ -121,6 +150,14 @@ func (m *mockBlockStore) IsTransactionAccepted(txID transaction.TransactionID) (
 	return false, nil
 }
 
+func (m *mockBlockStore) GetNextDifficultyTarget(_ common.Hash) (uint8, error) {
+	return m.tip.Header.DifficultyTarget, nil
+}
+
+func (m *mockBlockStore) GetMedianTimePast(_ common.Hash) (int64, error) {
+	return m.tip.Header.Timestamp, nil
+}
+
 // Helper function to create a test miner service
 func createTestMinerService(tip block.Block, utxos map[utxoOutpoint]transaction.Output) *minerService {
 	mockBlockchain := &mockBlockchainAPI{}
@@ -131,6 +168,7 @@ func createTestMinerService(tip block.Block, utxos map[utxoOutpoint]transaction.
 		blockchain:  mockBlockchain,
 		utxoService: mockUTXO,
 		blockStore:  mockBlockStore,
+		payouts:     []transaction.CoinbasePayout{{Percent: 100}},
 	}
 }
 
@@ -216,26 +254,6 @@ func TestGetTarget(t *testing.T) {
 	}
 }
 
-func TestGetCurrentTargetBits(t *testing.T) {
-	target, err := GetCurrentTargetBits()
-	if err != nil {
-		t.Errorf("GetCurrentTargetBits() returned error: %v", err)
-	}
-	if target != 28 {
-		t.Errorf("GetCurrentTargetBits() = %d, want 28", target)
-	}
-}
-
-func TestGetCurrentReward(t *testing.T) {
-	reward, err := GetCurrentReward()
-	if err != nil {
-		t.Errorf("GetCurrentReward() returned error: %v", err)
-	}
-	if reward != 50 {
-		t.Errorf("GetCurrentReward() = %d, want 50", reward)
-	}
-}
-
 func TestCreateCandidateBlockHeader(t *testing.T) {
 	genesis := createGenesisBlock()
 	miner := createTestMinerService(genesis, nil)
@@ -303,7 +321,7 @@ func TestGetInputSum(t *testing.T) {
 	}
 
 	tip := miner.blockStore.GetMainChainTip()
-	sum, err := miner.getInputSum(tx, tip.Hash())
+	sum, err := getInputSum(miner.utxoService, tx, tip.Hash(), nil)
 	if err != nil {
 		t.Fatalf("getInputSum() returned error: %v", err)
 	}
@@ -326,7 +344,7 @@ func TestGetInputSum_UTXONotFound(t *testing.T) {
 	}
 
 	tip := miner.blockStore.GetMainChainTip()
-	_, err := miner.getInputSum(tx, tip.Hash())
+	_, err := getInputSum(miner.utxoService, tx, tip.Hash(), nil)
 	if err == nil {
 		t.Error("getInputSum() should return error when UTXO not found")
 	}
@@ -359,7 +377,7 @@ func TestGetTransactionWithFee(t *testing.T) {
 	}
 
 	tip := miner.blockStore.GetMainChainTip()
-	txFees, err := miner.getTransactionWithFee(txs, tip.Hash())
+	txFees, err := getTransactionWithFee(miner.utxoService, txs, tip.Hash())
 	if err != nil {
 		t.Fatalf("getTransactionWithFee() returned error: %v", err)
 	}
@@ -379,35 +397,37 @@ func TestGetTransactionWithFee(t *testing.T) {
 	}
 }
 
-func TestSortAndReversedTransactions(t *testing.T) {
-	miner := createTestMinerService(createGenesisBlock(), nil)
-
-	tx1 := transaction.Transaction{Inputs: []transaction.Input{{PrevTxID: transaction.TransactionID{1}}}}
-	tx2 := transaction.Transaction{Inputs: []transaction.Input{{PrevTxID: transaction.TransactionID{2}}}}
-	tx3 := transaction.Transaction{Inputs: []transaction.Input{{PrevTxID: transaction.TransactionID{3}}}}
-
-	txFee1 := transactionWithFee{tx: tx1, Fee: 10}
-	txFee2 := transactionWithFee{tx: tx2, Fee: 30}
-	txFee3 := transactionWithFee{tx: tx3, Fee: 20}
+func TestGetTransactionWithFee_UnconfirmedParent(t *testing.T) {
+	prevTxID := transaction.TransactionID{}
+	prevTxID[0] = 0xCC
 
-	txsWithFees := []transactionWithFee{txFee1, txFee2, txFee3}
+	utxos := map[utxoOutpoint]transaction.Output{
+		{txID: prevTxID, outputIndex: 0}: {Value: 100, PubKeyHash: transaction.PubKeyHash{}},
+	}
 
-	sorted := miner.sortAndReversedTransactions(txsWithFees)
+	miner := createTestMinerService(createGenesisBlock(), utxos)
 
-	// Should be sorted by fee in descending order: 30, 20, 10
-	if len(sorted) != 3 {
-		t.Fatalf("sortAndReversedTransactions() returned %d transactions, want 3", len(sorted))
+	parent := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: prevTxID, OutputIndex: 0}},
+		Outputs: []transaction.Output{{Value: 90, PubKeyHash: transaction.PubKeyHash{}}},
+	}
+	child := transaction.Transaction{
+		Inputs:  []transaction.Input{{PrevTxID: parent.TransactionId(), OutputIndex: 0}},
+		Outputs: []transaction.Output{{Value: 75, PubKeyHash: transaction.PubKeyHash{}}},
 	}
 
-	// Check by comparing transaction IDs (tx2 has highest fee, tx3 second, tx1 third)
-	if sorted[0].TransactionId() != tx2.TransactionId() {
-		t.Errorf("First transaction should be tx2 (fee 30), got different transaction")
+	tip := miner.blockStore.GetMainChainTip()
+	txFees, err := getTransactionWithFee(miner.utxoService, []transaction.Transaction{child, parent}, tip.Hash())
+	if err != nil {
+		t.Fatalf("getTransactionWithFee() returned error: %v", err)
 	}
-	if sorted[1].TransactionId() != tx3.TransactionId() {
-		t.Errorf("Second transaction should be tx3 (fee 20), got different transaction")
+
+	// The child spends the output of its unconfirmed parent: input 90, output 75, fee = 15
+	if txFees[0].Fee != 15 {
+		t.Errorf("Child fee = %d, want 15", txFees[0].Fee)
 	}
-	if sorted[2].TransactionId() != tx1.TransactionId() {
-		t.Errorf("Third transaction should be tx1 (fee 10), got different transaction")
+	if txFees[1].Fee != 10 {
+		t.Errorf("Parent fee = %d, want 10", txFees[1].Fee)
 	}
 }
 
@@ -437,6 +457,84 @@ func TestCreateCoinbaseTransaction(t *testing.T) {
 	}
 }
 
+func TestCreateCoinbaseTransaction_SplitsRewardAmongPayouts(t *testing.T) {
+	miner := createTestMinerService(createGenesisBlock(), nil)
+	payouts := []transaction.CoinbasePayout{
+		{PubKeyHash: transaction.PubKeyHash{1}, Percent: 70},
+		{PubKeyHash: transaction.PubKeyHash{2}, Percent: 30},
+	}
+	if err := miner.SetPayouts(payouts); err != nil {
+		t.Fatalf("SetPayouts() returned error: %v", err)
+	}
+
+	txFee := transactionWithFee{Fee: 1, tx: transaction.Transaction{}}
+	coinbase, err := miner.createCoinbaseTransaction([]transactionWithFee{txFee}, 1)
+	if err != nil {
+		t.Fatalf("createCoinbaseTransaction() returned error: %v", err)
+	}
+
+	// Reward 51 (50 + 1): 70% = 35.7 and 30% = 15.3 are rounded down, the remainder goes to the first payout
+	if len(coinbase.Outputs) != 2 {
+		t.Fatalf("Coinbase transaction has %d outputs, want 2", len(coinbase.Outputs))
+	}
+	if coinbase.Outputs[0].PubKeyHash != payouts[0].PubKeyHash || coinbase.Outputs[0].Value != 36 {
+		t.Errorf("First output = %+v, want 36 to %v", coinbase.Outputs[0], payouts[0].PubKeyHash)
+	}
+	if coinbase.Outputs[1].PubKeyHash != payouts[1].PubKeyHash || coinbase.Outputs[1].Value != 15 {
+		t.Errorf("Second output = %+v, want 15 to %v", coinbase.Outputs[1], payouts[1].PubKeyHash)
+	}
+}
+
+func TestCreateCoinbaseTransaction_EmbedsTag(t *testing.T) {
+	miner := createTestMinerService(createGenesisBlock(), nil)
+	if err := miner.SetCoinbaseTag([]byte("team s3b")); err != nil {
+		t.Fatalf("SetCoinbaseTag() returned error: %v", err)
+	}
+
+	coinbase, err := miner.createCoinbaseTransaction(nil, 7)
+	if err != nil {
+		t.Fatalf("createCoinbaseTransaction() returned error: %v", err)
+	}
+
+	tag, ok := coinbase.CoinbaseTag()
+	if !ok || string(tag) != "team s3b" {
+		t.Errorf("CoinbaseTag() = %q, %v, want %q", tag, ok, "team s3b")
+	}
+	if height, ok := coinbase.CoinbaseHeight(); !ok || height != 7 {
+		t.Errorf("CoinbaseHeight() = %d, %v, want 7", height, ok)
+	}
+}
+
+func TestSetPayouts_RejectsInvalidShares(t *testing.T) {
+	miner := createTestMinerService(createGenesisBlock(), nil)
+
+	tests := map[string][]transaction.CoinbasePayout{
+		"no payouts":       nil,
+		"less than 100%":   {{Percent: 60}, {Percent: 30}},
+		"more than 100%":   {{Percent: 60}, {Percent: 50}},
+		"zero share":       {{Percent: 100}, {Percent: 0}},
+		"share above 100%": {{Percent: 101}},
+	}
+	for name, payouts := range tests {
+		if err := miner.SetPayouts(payouts); !errors.Is(err, transaction.ErrInvalidCoinbasePayouts) {
+			t.Errorf("%s: SetPayouts() error = %v, want %v", name, err, transaction.ErrInvalidCoinbasePayouts)
+		}
+	}
+
+	if len(miner.payouts) != 1 || miner.payouts[0].Percent != 100 {
+		t.Errorf("Payouts changed by invalid payouts: %+v", miner.payouts)
+	}
+}
+
+func TestSetCoinbaseTag_RejectsTooLongTag(t *testing.T) {
+	miner := createTestMinerService(createGenesisBlock(), nil)
+
+	err := miner.SetCoinbaseTag(make([]byte, transaction.MaxCoinbaseTagSize+1))
+	if !errors.Is(err, transaction.ErrCoinbaseTagTooLong) {
+		t.Errorf("SetCoinbaseTag() error = %v, want %v", err, transaction.ErrCoinbaseTagTooLong)
+	}
+}
+
 func TestBuildTransactions(t *testing.T) {
 	// Set up UTXOs for the transactions (all use the same prevTxID from createTestTransaction)
 	prevTxID := transaction.TransactionID{}
@@ -469,7 +567,7 @@ func TestBuildTransactions(t *testing.T) {
 	}
 }
 
-func TestBuildTransactions_LimitToTxPerBlock(t *testing.T) {
+func TestBuildTransactions_LimitToMaxBlockSize(t *testing.T) {
 	// Set up UTXOs for the transactions
 	prevTxID := transaction.TransactionID{}
 	prevTxID[0] = 0xAA
@@ -479,10 +577,11 @@ func TestBuildTransactions_LimitToTxPerBlock(t *testing.T) {
 	}
 	miner := createTestMinerService(createGenesisBlock(), utxos)
 
-	// Create more transactions than TxPerBlock
-	txs := make([]transaction.Transaction, TxPerBlock+10)
+	// Create more transactions than fit into a block
+	txs := make([]transaction.Transaction, 30)
 	for i := 0; i < len(txs); i++ {
 		txs[i] = createTestTransaction(100, 90)
+		txs[i].Inputs[0].Signature = make([]byte, block.MaxBlockSize/20)
 	}
 
 	tip := miner.blockStore.GetMainChainTip()
@@ -491,9 +590,18 @@ func TestBuildTransactions_LimitToTxPerBlock(t *testing.T) {
 		t.Fatalf("buildTransactions() returned error: %v", err)
 	}
 
-	// Should have exactly TxPerBlock transactions (1 coinbase + TxPerBlock-1 regular)
-	if len(builtTxs) != TxPerBlock {
-		t.Errorf("buildTransactions() returned %d transactions, want %d", len(builtTxs), TxPerBlock)
+	candidate := block.Block{Transactions: builtTxs}
+	if candidate.SerializedSize() > block.MaxBlockSize {
+		t.Errorf("Block size %d exceeds the maximum block size %d", candidate.SerializedSize(), block.MaxBlockSize)
+	}
+	if len(builtTxs) >= len(txs)+1 {
+		t.Errorf("buildTransactions() returned all %d transactions, expected some to be left out", len(builtTxs))
+	}
+
+	// The coinbase only collects the fees of the included transactions
+	expectedReward := block.BlockSubsidy(1) + uint64(len(builtTxs)-1)*10
+	if builtTxs[0].Outputs[0].Value != expectedReward {
+		t.Errorf("Coinbase output value = %d, want %d", builtTxs[0].Outputs[0].Value, expectedReward)
 	}
 }
 
@@ -656,6 +764,7 @@ func TestStartMining_StopsMining(t *testing.T) {
 		utxoService:   mockUTXO,
 		blockStore:    mockBlockStore,
 		miningEnabled: true,
+		payouts:       []transaction.CoinbasePayout{{Percent: 100}},
 	}
 
 	// Start mining
@@ -700,11 +809,6 @@ func TestNewMinerService(t *testing.T) {
 	}
 }
 
-func TestMinerAPI_Interface(t *testing.T) {
-	// Compile-time check that minerService implements MinerAPI
-	var _ minerApi.MinerAPI = &minerService{}
-}
-
 func TestMinerBlockchainAPI_Interface(t *testing.T) {
 	// Compile-time check that mockBlockchainAPI implements api.BlockchainAPI
 	var _ api.BlockchainAPI = &mockBlockchainAPI{}
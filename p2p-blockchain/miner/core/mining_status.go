@@ -0,0 +1,180 @@
+package core
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"slices"
+)
+
+// MinedBlockStatus is the state of a block mined by this node in the block store.
+type MinedBlockStatus int
+
+const (
+	// MinedBlockMainChain is a block on the main chain, its reward counts.
+	MinedBlockMainChain MinedBlockStatus = iota
+	// MinedBlockSideChain is a block that lost a race or was reorganized away.
+	MinedBlockSideChain
+	// MinedBlockOrphan is a block whose parent is unknown.
+	MinedBlockOrphan
+	// MinedBlockUnknown is a block that is not in the block store, e.g. because it was rejected.
+	MinedBlockUnknown
+)
+
+func (s MinedBlockStatus) String() string {
+	switch s {
+	case MinedBlockMainChain:
+		return "main_chain"
+	case MinedBlockSideChain:
+		return "side_chain"
+	case MinedBlockOrphan:
+		return "orphan"
+	default:
+		return "unknown"
+	}
+}
+
+// MinedBlock is a block mined by this node, by the own miner or by an external miner or the pool using a block template.
+type MinedBlock struct {
+	Hash   common.Hash
+	Height uint64
+	// Reward is the value of the coinbase (subsidy and fees) paid to the payout addresses of this node, see minerService.SetPayouts.
+	Reward uint64
+	Status MinedBlockStatus
+}
+
+// MiningStatus describes what the miner is doing and what it mined since the start of the node.
+// The mined blocks include the blocks of block templates submitted by external miners and the pool, see BlockTemplateService.SubmitBlock.
+type MiningStatus struct {
+	Enabled bool
+	// Mining is set while a nonce for a candidate block is searched.
+	Mining bool
+	// CandidateHeight, PreviousBlockHash and TransactionCount (without the coinbase) describe the candidate block, if mining.
+	CandidateHeight   uint64
+	PreviousBlockHash common.Hash
+	TransactionCount  int
+	// HashesTried is the number of block header hashes computed since the start of the node.
+	HashesTried uint64
+	// HashRate is the number of hashes per second, see minerService.HashRate.
+	HashRate    uint64
+	MinedBlocks []MinedBlock
+	// TotalRewards is the sum of the rewards of the mined blocks on the main chain.
+	TotalRewards uint64
+}
+
+// candidateInfo describes the candidate block the miner is working on.
+type candidateInfo struct {
+	height            uint64
+	previousBlockHash common.Hash
+	transactionCount  int
+}
+
+// minedBlock is a block mined by this node, its status is looked up when the status is requested.
+type minedBlock struct {
+	hash   common.Hash
+	height uint64
+	reward uint64
+}
+
+// recordMinedBlock records a mined candidate block and marks the candidate as done. mu must not be held.
+func (m *minerService) recordMinedBlock(candidate *candidateInfo, mined block.Block) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addMinedBlock(mined, candidate.height)
+	if m.candidate == candidate {
+		m.candidate = nil
+	}
+}
+
+// recordSubmittedBlock records a block of a block template solved by an external miner or the pool. mu must not be held.
+func (m *minerService) recordSubmittedBlock(submitted block.Block, height uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addMinedBlock(submitted, height)
+}
+
+// addMinedBlock records a mined block, only the part of the coinbase paid to the payout addresses of this node counts as reward.
+// mu must be held.
+func (m *minerService) addMinedBlock(mined block.Block, height uint64) {
+	var reward uint64
+	for _, output := range mined.Transactions[0].Outputs {
+		if m.isPayoutAddress(output) {
+			reward += output.Value
+		}
+	}
+
+	m.minedBlocks = append(m.minedBlocks, minedBlock{hash: mined.Hash(), height: height, reward: reward})
+}
+
+// isPayoutAddress reports whether the output pays one of the payout addresses of this node, see SetPayouts. mu must be held.
+func (m *minerService) isPayoutAddress(output transaction.Output) bool {
+	if output.Type != transaction.OutputTypePubKeyHash {
+		return false
+	}
+	for _, payout := range m.payouts {
+		if output.PubKeyHash == payout.PubKeyHash {
+			return true
+		}
+	}
+	return false
+}
+
+// clearCandidate marks the candidate as done, unless the miner already works on a newer one. mu must not be held.
+func (m *minerService) clearCandidate(candidate *candidateInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.candidate == candidate {
+		m.candidate = nil
+	}
+}
+
+// GetMiningStatus returns the current state of the miner and the blocks it mined with their status in the block store.
+func (m *minerService) GetMiningStatus() MiningStatus {
+	m.mu.RLock()
+	status := MiningStatus{
+		Enabled:     m.miningEnabled,
+		Mining:      m.candidate != nil,
+		HashesTried: m.hashesTried.Load(),
+		HashRate:    m.HashRate(),
+	}
+	if m.candidate != nil {
+		status.CandidateHeight = m.candidate.height
+		status.PreviousBlockHash = m.candidate.previousBlockHash
+		status.TransactionCount = m.candidate.transactionCount
+	}
+	minedBlocks := slices.Clone(m.minedBlocks)
+	m.mu.RUnlock()
+
+	status.MinedBlocks = make([]MinedBlock, 0, len(minedBlocks))
+	for _, mined := range minedBlocks {
+		blockStatus := m.minedBlockStatus(mined.hash)
+		if blockStatus == MinedBlockMainChain {
+			status.TotalRewards += mined.reward
+		}
+		status.MinedBlocks = append(status.MinedBlocks, MinedBlock{
+			Hash:   mined.hash,
+			Height: mined.height,
+			Reward: mined.reward,
+			Status: blockStatus,
+		})
+	}
+	return status
+}
+
+// minedBlockStatus looks up the status of a mined block in the block store.
+func (m *minerService) minedBlockStatus(hash common.Hash) MinedBlockStatus {
+	b, err := m.blockStore.GetBlockByHash(hash)
+	if err != nil {
+		return MinedBlockUnknown
+	}
+	if orphan, err := m.blockStore.IsOrphanBlock(b); err == nil && orphan {
+		return MinedBlockOrphan
+	}
+	if m.blockStore.IsPartOfMainChain(b) {
+		return MinedBlockMainChain
+	}
+	return MinedBlockSideChain
+}
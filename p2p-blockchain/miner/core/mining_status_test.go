@@ -0,0 +1,118 @@
+package core
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	"testing"
+	"time"
+)
+
+// sideChainBlockStore knows all blocks, but none of them is part of the main chain
+type sideChainBlockStore struct {
+	*mockBlockStore
+}
+
+func (m *sideChainBlockStore) IsPartOfMainChain(_ block.Block) bool {
+	return false
+}
+
+func createMinedBlock(reward uint64) block.Block {
+	coinbase := transaction.NewCoinbaseTransaction(transaction.PubKeyHash{}, reward, 1)
+	return block.Block{Header: block.BlockHeader{Nonce: 42}, Transactions: []transaction.Transaction{coinbase}}
+}
+
+func TestGetMiningStatus_TracksCandidateAndHashes(t *testing.T) {
+	prevTxID := transaction.TransactionID{}
+	prevTxID[0] = 0xAA
+	utxos := map[utxoOutpoint]transaction.Output{
+		{txID: prevTxID, outputIndex: 0}: {Value: 100},
+	}
+	tip := createGenesisBlock()
+	tip.Header.DifficultyTarget = 255 // never solved
+	miner := createTestMinerService(tip, utxos)
+	miner.miningEnabled = true
+
+	miner.StartMining([]transaction.Transaction{createTestTransaction(100, 90)})
+
+	status := miner.GetMiningStatus()
+	if !status.Enabled || !status.Mining {
+		t.Fatalf("expected enabled miner working on a candidate, got %+v", status)
+	}
+	if status.CandidateHeight != 1 || status.TransactionCount != 1 || status.PreviousBlockHash != tip.Hash() {
+		t.Errorf("expected candidate at height 1 with 1 transaction on top of the tip, got %+v", status)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	miner.StopMining()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for miner.GetMiningStatus().Mining {
+		if time.Now().After(deadline) {
+			t.Fatal("expected mining to stop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if miner.GetMiningStatus().HashesTried == 0 {
+		t.Error("expected tried hashes")
+	}
+}
+
+func TestGetMiningStatus_MinedBlocks(t *testing.T) {
+	miner := createTestMinerService(createGenesisBlock(), nil)
+	candidate := &candidateInfo{height: 1}
+	miner.candidate = candidate
+
+	miner.recordMinedBlock(candidate, createMinedBlock(50))
+
+	status := miner.GetMiningStatus()
+	if status.Mining {
+		t.Error("expected the candidate to be done")
+	}
+	if len(status.MinedBlocks) != 1 {
+		t.Fatalf("expected 1 mined block, got %d", len(status.MinedBlocks))
+	}
+	mined := status.MinedBlocks[0]
+	if mined.Height != 1 || mined.Reward != 50 || mined.Status != MinedBlockMainChain {
+		t.Errorf("expected main chain block at height 1 with reward 50, got %+v", mined)
+	}
+	if status.TotalRewards != 50 {
+		t.Errorf("expected total rewards of 50, got %d", status.TotalRewards)
+	}
+}
+
+func TestGetMiningStatus_SideChainBlocksEarnNoRewards(t *testing.T) {
+	miner := createTestMinerService(createGenesisBlock(), nil)
+	miner.blockStore = &sideChainBlockStore{mockBlockStore: &mockBlockStore{tip: createGenesisBlock()}}
+
+	miner.recordMinedBlock(&candidateInfo{height: 1}, createMinedBlock(50))
+
+	status := miner.GetMiningStatus()
+	if status.MinedBlocks[0].Status != MinedBlockSideChain {
+		t.Errorf("expected side chain block, got %v", status.MinedBlocks[0].Status)
+	}
+	if status.TotalRewards != 0 {
+		t.Errorf("expected no rewards, got %d", status.TotalRewards)
+	}
+}
+
+func TestGetMiningStatus_CountsOnlyOwnPayouts(t *testing.T) {
+	miner := createTestMinerService(createGenesisBlock(), nil)
+	foreign := transaction.PubKeyHash{}
+	foreign[0] = 0x01
+	// A pool block pays most of the coinbase to its miners
+	coinbase := transaction.NewCoinbaseTransactionWithPayouts([]transaction.CoinbasePayout{
+		{PubKeyHash: transaction.PubKeyHash{}, Percent: 20},
+		{PubKeyHash: foreign, Percent: 80},
+	}, 50, 1, nil)
+	mined := block.Block{Header: block.BlockHeader{Nonce: 42}, Transactions: []transaction.Transaction{coinbase}}
+
+	miner.recordSubmittedBlock(mined, 1)
+
+	status := miner.GetMiningStatus()
+	if len(status.MinedBlocks) != 1 {
+		t.Fatalf("expected 1 mined block, got %d", len(status.MinedBlocks))
+	}
+	if status.MinedBlocks[0].Reward != 10 || status.TotalRewards != 10 {
+		t.Errorf("expected a reward of 10 paid to the own address, got %+v with total %d", status.MinedBlocks[0], status.TotalRewards)
+	}
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"hash"
+	"math"
+	"math/big"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"sync/atomic"
+
+	"bjoernblessin.de/go-utils/util/assert"
+)
+
+// hashBatchSize is the number of hashes a worker computes before it checks for cancellation and reports its hash count.
+const hashBatchSize = 1 << 12
+
+// headerPrefixSize is the size of the part of the serialized header that is the same for every nonce and timestamp
+// (previous block hash and merkle root). It is exactly one SHA-256 block, so its midstate can be reused.
+const headerPrefixSize = 2 * common.HashSize
+
+// headerHasher computes the hash of a block header for changing nonces and timestamps without allocating.
+// The SHA-256 state after the constant header prefix (midstate) is computed once and restored for every hash,
+// so only the remaining bytes of the header are hashed per nonce. The result equals block.BlockHeader.Hash.
+type headerHasher struct {
+	digest    hash.Hash
+	restorer  encoding.BinaryUnmarshaler
+	midstate  []byte
+	suffix    [block.HeaderSize - headerPrefixSize]byte
+	firstHash [sha256.Size]byte
+}
+
+// newHeaderHasher creates a headerHasher for the header. Nonce and timestamp are set by hash and setTimestamp.
+func newHeaderHasher(header *block.BlockHeader) *headerHasher {
+	serialized := header.Serialize()
+
+	digest := sha256.New()
+	digest.Write(serialized[:headerPrefixSize])
+	midstate, err := digest.(encoding.BinaryMarshaler).MarshalBinary()
+	assert.IsNil(err, "SHA-256 state must be serializable")
+	// Restoring the midstate once here proves it restorable, so hash doesn't check it for every nonce
+	err = digest.(encoding.BinaryUnmarshaler).UnmarshalBinary(midstate)
+	assert.IsNil(err, "SHA-256 midstate must be restorable")
+
+	h := &headerHasher{
+		digest:   digest,
+		restorer: digest.(encoding.BinaryUnmarshaler),
+		midstate: midstate,
+	}
+	copy(h.suffix[:], serialized[headerPrefixSize:])
+	return h
+}
+
+// setTimestamp sets the timestamp of the hashed header.
+func (h *headerHasher) setTimestamp(timestamp int64) {
+	binary.LittleEndian.PutUint64(h.suffix[0:8], uint64(timestamp))
+}
+
+// hash returns the double SHA-256 hash of the header with the given nonce.
+func (h *headerHasher) hash(nonce uint32) common.Hash {
+	binary.LittleEndian.PutUint32(h.suffix[8:12], nonce)
+
+	_ = h.restorer.UnmarshalBinary(h.midstate)
+	h.digest.Write(h.suffix[:])
+	first := h.digest.Sum(h.firstHash[:0])
+
+	return sha256.Sum256(first)
+}
+
+// powResult is a nonce and timestamp for which the header hash meets the difficulty target.
+type powResult struct {
+	nonce     uint32
+	timestamp int64
+}
+
+// searchNonces searches a valid nonce and timestamp for the header until one is found or the context is cancelled.
+// The nonce space of every timestamp is partitioned among the workers: the worker with the given index tries the nonces
+// startNonce + index, startNonce + index + workers, ... (wrapping around). Once its share is exhausted, it continues with the
+// next timestamp. So no two workers ever hash the same header. The number of computed hashes is added to hashes.
+func searchNonces(ctx context.Context, header block.BlockHeader, startNonce uint32, index, workers int, hashes *atomic.Uint64, results chan<- powResult) {
+	target := getTarget(header.DifficultyTarget)
+	hasher := newHeaderHasher(&header)
+	var hashInt big.Int
+	var batch uint64
+
+	for timestamp := header.Timestamp; ; timestamp++ {
+		hasher.setTimestamp(timestamp)
+
+		for offset := uint64(index); offset <= math.MaxUint32; offset += uint64(workers) {
+			nonce := startNonce + uint32(offset)
+			hash := hasher.hash(nonce)
+
+			hashInt.SetBytes(hash[:])
+			if hashInt.Cmp(&target) == -1 {
+				hashes.Add(batch + 1)
+				results <- powResult{nonce: nonce, timestamp: timestamp}
+				return
+			}
+
+			batch++
+			if batch == hashBatchSize {
+				hashes.Add(batch)
+				batch = 0
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}
+	}
+}
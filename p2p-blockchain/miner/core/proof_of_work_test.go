@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"sync/atomic"
+	"testing"
+)
+
+func createTestHeader(difficulty uint8) block.BlockHeader {
+	return block.BlockHeader{
+		PreviousBlockHash: common.Hash{1, 2, 3},
+		MerkleRoot:        common.Hash{4, 5, 6},
+		Timestamp:         1_700_000_000,
+		DifficultyTarget:  difficulty,
+	}
+}
+
+func TestHeaderHasher_MatchesHeaderHash(t *testing.T) {
+	header := createTestHeader(12)
+	hasher := newHeaderHasher(&header)
+
+	for _, timestamp := range []int64{header.Timestamp, header.Timestamp + 1, -1} {
+		hasher.setTimestamp(timestamp)
+		for _, nonce := range []uint32{0, 1, 0xDEADBEEF, ^uint32(0)} {
+			header.Timestamp = timestamp
+			header.Nonce = nonce
+
+			if got, want := hasher.hash(nonce), header.Hash(); got != want {
+				t.Errorf("hash(nonce=%d, timestamp=%d) = %x, want %x", nonce, timestamp, got, want)
+			}
+		}
+	}
+}
+
+func TestHeaderHasher_DoesNotAllocate(t *testing.T) {
+	header := createTestHeader(12)
+	hasher := newHeaderHasher(&header)
+
+	var nonce uint32
+	allocs := testing.AllocsPerRun(100, func() {
+		hasher.hash(nonce)
+		nonce++
+	})
+
+	if allocs != 0 {
+		t.Errorf("hash() allocates %.1f times per call, want 0", allocs)
+	}
+}
+
+func TestSearchNonces_StaysInOwnShare(t *testing.T) {
+	const workers = 4
+	header := createTestHeader(6)
+	startNonce := uint32(100)
+
+	for index := range workers {
+		results := make(chan powResult, 1)
+		var hashes atomic.Uint64
+		searchNonces(context.Background(), header, startNonce, index, workers, &hashes, results)
+
+		result := <-results
+		if offset := result.nonce - startNonce; int(offset%workers) != index {
+			t.Errorf("worker %d found nonce at offset %d outside of its share", index, offset)
+		}
+		if hashes.Load() == 0 {
+			t.Errorf("worker %d did not count its hashes", index)
+		}
+	}
+}
+
+func TestMineBlock_MultipleWorkers(t *testing.T) {
+	miner := createTestMinerService(createGenesisBlock(), nil)
+	miner.config.Workers = 4
+
+	candidateBlock := block.Block{Header: createTestHeader(12)}
+
+	nonce, timestamp, err := miner.mineBlock(candidateBlock, context.Background())
+	if err != nil {
+		t.Fatalf("mineBlock() returned error: %v", err)
+	}
+
+	candidateBlock.Header.Nonce = nonce
+	candidateBlock.Header.Timestamp = timestamp
+	if difficulty := candidateBlock.BlockDifficulty(); difficulty < candidateBlock.Header.DifficultyTarget {
+		t.Errorf("Mined block has difficulty %d, want at least %d", difficulty, candidateBlock.Header.DifficultyTarget)
+	}
+	if miner.HashRate() == 0 {
+		t.Error("Hash rate not reported after mining")
+	}
+}
+
+func BenchmarkBlockHeaderHash(b *testing.B) {
+	header := createTestHeader(12)
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		header.Nonce = uint32(i)
+		header.Hash()
+	}
+}
+
+func BenchmarkHeaderHasher(b *testing.B) {
+	header := createTestHeader(12)
+	hasher := newHeaderHasher(&header)
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		hasher.hash(uint32(i))
+	}
+}
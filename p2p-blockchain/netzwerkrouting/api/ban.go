@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/netip"
+	"time"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/misbehavior"
+)
+
+// MisbehaviorReporterAPI is used by other subsystems to report offences of peers, e.g. invalid blocks.
+// Peers whose misbehavior score reaches the ban threshold are disconnected and their addresses are banned.
+// It is implemented by misbehavior.MisbehaviorService.
+type MisbehaviorReporterAPI interface {
+	// ReportMisbehavior increases the misbehavior score of a peer by the score of its offence (see common.MisbehaviorInvalidBlock etc.).
+	// Offences of the node itself (empty peerID) are ignored.
+	ReportMisbehavior(peerID common.PeerId, score int, reason string)
+}
+
+// BanInfo describes a banned address.
+type BanInfo struct {
+	Addr   netip.Addr
+	Reason string
+	// CreatedAt and ExpiresAt are Unix timestamps.
+	CreatedAt int64
+	ExpiresAt int64
+}
+
+// BanAPI is the external API for managing the ban list.
+// Connections from and to banned addresses are refused.
+// Part of NetworkroutingAppAPI.
+type BanAPI interface {
+	// GetBans returns all bans that have not expired.
+	GetBans() []BanInfo
+	// Ban bans an address for the given duration and disconnects all peers known by the address.
+	Ban(addr netip.Addr, duration time.Duration, reason string) error
+	// Unban lifts the ban of an address. Returns an error if the address is not banned.
+	Unban(addr netip.Addr) error
+}
+
+// banAPIService implements BanAPI.
+type banAPIService struct {
+	misbehaviorService misbehavior.MisbehaviorService
+}
+
+func NewBanAPIService(misbehaviorService misbehavior.MisbehaviorService) BanAPI {
+	return &banAPIService{
+		misbehaviorService: misbehaviorService,
+	}
+}
+
+func (s *banAPIService) GetBans() []BanInfo {
+	bans := s.misbehaviorService.GetBans()
+	infos := make([]BanInfo, 0, len(bans))
+	for _, ban := range bans {
+		infos = append(infos, BanInfo{
+			Addr:      ban.Addr,
+			Reason:    ban.Reason,
+			CreatedAt: ban.CreatedAt,
+			ExpiresAt: ban.ExpiresAt,
+		})
+	}
+	return infos
+}
+
+func (s *banAPIService) Ban(addr netip.Addr, duration time.Duration, reason string) error {
+	return s.misbehaviorService.BanAddress(addr, duration, reason)
+}
+
+func (s *banAPIService) Unban(addr netip.Addr) error {
+	return s.misbehaviorService.UnbanAddress(addr)
+}
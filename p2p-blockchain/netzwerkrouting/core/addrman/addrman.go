@@ -0,0 +1,407 @@
+// Package addrman manages the addresses of known nodes and selects the nodes to connect to.
+// It is modelled on the address manager of Bitcoin Core: addresses are kept in two tables of fixed size,
+// the new table for addresses learned via Addr messages or the DNS registry and the tried table for addresses
+// of nodes a handshake succeeded with. An address is placed in a bucket that depends on its network group and,
+// in the new table, on the network group of the node it was learned from. This way a single party can only fill
+// a small part of the tables, no matter how many addresses it announces, which makes eclipse attacks expensive.
+//
+// The selection of outbound peers prefers tried addresses and addresses of network groups the node is not connected to yet.
+// The tables are persisted to the data directory, so the node keeps its view of the network across restarts.
+package addrman
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	mathrand "math/rand"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+const (
+	// newBucketCount and triedBucketCount are the numbers of buckets of the new and the tried table.
+	newBucketCount   = 256
+	triedBucketCount = 64
+	// bucketSize is the number of addresses a bucket holds.
+	bucketSize = 64
+
+	// newBucketsPerSourceGroup is the number of new buckets the addresses learned from one source group are spread over.
+	newBucketsPerSourceGroup = 32
+	// triedBucketsPerGroup is the number of tried buckets the addresses of one network group are spread over.
+	triedBucketsPerGroup = 8
+
+	// triedSelectionChance is the chance to select a tried address if both tables have candidates.
+	triedSelectionChance = 0.7
+	// recentAttemptInterval is the interval in which an address that was just attempted is rarely selected again.
+	recentAttemptInterval = 10 * time.Minute
+	// maxPenalizedAttempts caps the number of failed attempts that lower the chance to select an address.
+	maxPenalizedAttempts = 8
+)
+
+// peerResolver resolves between the addresses of the tables and the peers of the domain.
+// It is implemented by networkinfo.NetworkInfoRegistry.
+type peerResolver interface {
+	GetListeningEndpoint(peerID common.PeerId) (netip.AddrPort, bool)
+	GetOutboundPeer(addrPort netip.AddrPort) (common.PeerId, bool)
+	GetOrRegisterPeer(inboundAddr netip.AddrPort, listeningEndpoint netip.AddrPort) common.PeerId
+	IsBanned(addrPort netip.AddrPort) bool
+}
+
+// peerRetriever is an interface for retrieving peers.
+// It is implemented by peer.PeerStore.
+type peerRetriever interface {
+	GetPeer(id common.PeerId) (*common.Peer, bool)
+}
+
+// addrInfo is an address in one of the tables.
+type addrInfo struct {
+	addr netip.AddrPort
+	// sourceGroup is the network group of the node the address was learned from, see netGroup.
+	sourceGroup string
+	tried       bool
+	// attempts is the number of connection attempts since the last successful handshake.
+	attempts int
+	// lastAttempt and lastSuccess are Unix timestamps.
+	lastAttempt int64
+	lastSuccess int64
+}
+
+// AddrManager holds the new and tried tables.
+// It implements handshake.ConnectionObserver to promote addresses to the tried table.
+type AddrManager struct {
+	mu           sync.Mutex
+	key          [32]byte
+	newBuckets   [newBucketCount][]*addrInfo
+	triedBuckets [triedBucketCount][]*addrInfo
+	addrs        map[netip.AddrPort]*addrInfo
+
+	// path is the file the tables are persisted to, empty if the tables are in-memory only.
+	path  string
+	dirty bool
+
+	peerResolver  peerResolver
+	peerRetriever peerRetriever
+	now           func() time.Time
+
+	stopChan chan struct{}
+	ticker   *time.Ticker
+}
+
+// NewAddrManager creates empty in-memory tables.
+func NewAddrManager(peerResolver peerResolver, peerRetriever peerRetriever) *AddrManager {
+	m := &AddrManager{
+		addrs:         make(map[netip.AddrPort]*addrInfo),
+		peerResolver:  peerResolver,
+		peerRetriever: peerRetriever,
+		now:           time.Now,
+		stopChan:      make(chan struct{}),
+	}
+	// The key makes the bucket placement unpredictable for other nodes
+	_, _ = rand.Read(m.key[:]) // never returns an error
+	return m
+}
+
+// AddFromPeer adds the addresses of the peers announced in an Addr message of source to the new table.
+func (m *AddrManager) AddFromPeer(source common.PeerId, peers []common.PeerId) {
+	sourceEndpoint, ok := m.peerResolver.GetListeningEndpoint(source)
+	if !ok {
+		return
+	}
+	m.add(netGroup(sourceEndpoint), m.endpointsOf(peers))
+}
+
+// AddFromRegistry adds the addresses of the peers returned by the DNS registry to the new table.
+func (m *AddrManager) AddFromRegistry(peers []common.PeerId) {
+	m.add(registryGroup, m.endpointsOf(peers))
+}
+
+func (m *AddrManager) endpointsOf(peers []common.PeerId) []netip.AddrPort {
+	endpoints := make([]netip.AddrPort, 0, len(peers))
+	for _, peerID := range peers {
+		if endpoint, ok := m.peerResolver.GetListeningEndpoint(peerID); ok {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+func (m *AddrManager) add(sourceGroup string, endpoints []netip.AddrPort) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, endpoint := range endpoints {
+		if !endpoint.IsValid() || endpoint.Port() == 0 {
+			continue
+		}
+		if _, known := m.addrs[endpoint]; known {
+			continue
+		}
+		m.insertNew(&addrInfo{addr: endpoint, sourceGroup: sourceGroup})
+		m.dirty = true
+	}
+}
+
+// OnPeerConnected promotes the address of an outbound peer to the tried table once the handshake succeeded.
+// Inbound peers are not promoted, their listening endpoint was not proven reachable by this node.
+func (m *AddrManager) OnPeerConnected(peerID common.PeerId, isOutbound bool) {
+	if !isOutbound {
+		return
+	}
+	endpoint, ok := m.peerResolver.GetListeningEndpoint(peerID)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	info, known := m.addrs[endpoint]
+	if !known {
+		// Only addresses selected from the tables are promoted, e.g. not addresses connected to by the app
+		return
+	}
+	info.attempts = 0
+	info.lastSuccess = m.now().Unix()
+	m.dirty = true
+	if info.tried {
+		return
+	}
+
+	m.removeFromBucket(&m.newBuckets[m.newBucket(info)], info)
+	m.insertTried(info)
+}
+
+// SelectPeers selects up to count addresses to connect to and returns their peers.
+// It prefers tried addresses and addresses of network groups none of the connected peers belongs to.
+// Addresses of peers that are connected, in the handshake or in holddown and banned addresses are skipped.
+func (m *AddrManager) SelectPeers(count int, connected []common.PeerId) []common.PeerId {
+	usedGroups := make(map[string]bool)
+	for _, endpoint := range m.endpointsOf(connected) {
+		usedGroups[netGroup(endpoint)] = true
+	}
+
+	m.mu.Lock()
+	var tried, fresh []*addrInfo
+	for _, info := range m.addrs {
+		if info.tried {
+			tried = append(tried, info)
+		} else {
+			fresh = append(fresh, info)
+		}
+	}
+	m.mu.Unlock()
+
+	tried = slices.DeleteFunc(tried, m.isUnavailable)
+	fresh = slices.DeleteFunc(fresh, m.isUnavailable)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	selected := make([]common.PeerId, 0, count)
+	for len(selected) < count && len(tried)+len(fresh) > 0 {
+		table := &fresh
+		if len(fresh) == 0 || (len(tried) > 0 && mathrand.Float64() < triedSelectionChance) {
+			table = &tried
+		}
+
+		info := m.pick(*table, usedGroups, now)
+		*table = slices.DeleteFunc(*table, func(other *addrInfo) bool { return other == info })
+
+		usedGroups[netGroup(info.addr)] = true
+		info.attempts++
+		info.lastAttempt = now.Unix()
+		m.dirty = true
+
+		selected = append(selected, m.peerResolver.GetOrRegisterPeer(netip.AddrPort{}, info.addr))
+	}
+	return selected
+}
+
+// isUnavailable reports whether an address must not be selected. Called without mu held, it locks the peer.
+func (m *AddrManager) isUnavailable(info *addrInfo) bool {
+	if m.peerResolver.IsBanned(info.addr) {
+		return true
+	}
+	peerID, known := m.peerResolver.GetOutboundPeer(info.addr)
+	if !known {
+		return false
+	}
+	p, ok := m.peerRetriever.GetPeer(peerID)
+	if !ok {
+		return false
+	}
+	p.Lock()
+	defer p.Unlock()
+	return p.State != common.StateNew
+}
+
+// pick selects an address of the candidates at random, weighted by chance.
+// Candidates of network groups that are not used yet are preferred. mu must be held.
+func (m *AddrManager) pick(candidates []*addrInfo, usedGroups map[string]bool, now time.Time) *addrInfo {
+	preferred := slices.DeleteFunc(slices.Clone(candidates), func(info *addrInfo) bool {
+		return usedGroups[netGroup(info.addr)]
+	})
+	if len(preferred) > 0 {
+		candidates = preferred
+	}
+
+	total := 0.0
+	for _, info := range candidates {
+		total += chance(info, now)
+	}
+	r := mathrand.Float64() * total
+	for _, info := range candidates {
+		r -= chance(info, now)
+		if r < 0 {
+			return info
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// chance is the relative chance to select an address. It decreases with the number of failed attempts,
+// addresses attempted in the last recentAttemptInterval are almost never selected.
+func chance(info *addrInfo, now time.Time) float64 {
+	c := 1.0
+	if now.Unix()-info.lastAttempt < int64(recentAttemptInterval.Seconds()) {
+		c *= 0.01
+	}
+	for range min(info.attempts, maxPenalizedAttempts) {
+		c *= 0.66
+	}
+	return c
+}
+
+// insertNew adds an address to its new bucket. If the bucket is full, the address with the most failed attempts,
+// or the oldest one, is evicted. mu must be held.
+func (m *AddrManager) insertNew(info *addrInfo) {
+	info.tried = false
+	bucket := &m.newBuckets[m.newBucket(info)]
+	if len(*bucket) >= bucketSize {
+		evicted := (*bucket)[evictionIndex(*bucket)]
+		m.removeFromBucket(bucket, evicted)
+		delete(m.addrs, evicted.addr)
+	}
+	*bucket = append(*bucket, info)
+	m.addrs[info.addr] = info
+}
+
+// insertTried adds an address to its tried bucket. If the bucket is full, the address with the oldest successful
+// handshake is moved back to the new table. mu must be held.
+func (m *AddrManager) insertTried(info *addrInfo) {
+	info.tried = true
+	bucket := &m.triedBuckets[m.triedBucket(info.addr)]
+	if len(*bucket) >= bucketSize {
+		oldest := slices.MinFunc(*bucket, func(a, b *addrInfo) int { return cmp.Compare(a.lastSuccess, b.lastSuccess) })
+		m.removeFromBucket(bucket, oldest)
+		m.insertNew(oldest)
+	}
+	*bucket = append(*bucket, info)
+	m.addrs[info.addr] = info
+}
+
+func (m *AddrManager) removeFromBucket(bucket *[]*addrInfo, info *addrInfo) {
+	*bucket = slices.DeleteFunc(*bucket, func(other *addrInfo) bool { return other == info })
+}
+
+// evictionIndex returns the index of the address to evict from a full new bucket:
+// the one with the most failed attempts, on a tie the one added first.
+func evictionIndex(bucket []*addrInfo) int {
+	index := 0
+	for i, info := range bucket {
+		if info.attempts > bucket[index].attempts {
+			index = i
+		}
+	}
+	return index
+}
+
+// newBucket returns the new bucket of an address. The addresses learned from one source group
+// are spread over newBucketsPerSourceGroup buckets only.
+func (m *AddrManager) newBucket(info *addrInfo) int {
+	slot := m.hash("new-slot", info.sourceGroup, netGroup(info.addr)) % newBucketsPerSourceGroup
+	return int(m.hash("new", info.sourceGroup, slot) % newBucketCount)
+}
+
+// triedBucket returns the tried bucket of an address. The addresses of one network group
+// are spread over triedBucketsPerGroup buckets only.
+func (m *AddrManager) triedBucket(addr netip.AddrPort) int {
+	slot := m.hash("tried-slot", addr.String()) % triedBucketsPerGroup
+	return int(m.hash("tried", netGroup(addr), slot) % triedBucketCount)
+}
+
+// hash hashes the parts together with the secret key.
+func (m *AddrManager) hash(parts ...any) uint64 {
+	h := sha256.New()
+	h.Write(m.key[:])
+	for _, part := range parts {
+		switch v := part.(type) {
+		case string:
+			h.Write([]byte(v))
+		case uint64:
+			_ = binary.Write(h, binary.BigEndian, v)
+		}
+		h.Write([]byte{0})
+	}
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
+// Size returns the numbers of addresses in the new and the tried table.
+func (m *AddrManager) Size() (newCount int, triedCount int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, info := range m.addrs {
+		if info.tried {
+			triedCount++
+		} else {
+			newCount++
+		}
+	}
+	return newCount, triedCount
+}
+
+// Start begins saving the tables periodically, see saveInterval.
+func (m *AddrManager) Start() {
+	if m.path == "" {
+		return
+	}
+	logger.Infof("[addrman] Saving address tables to %s every %v", m.path, saveInterval)
+	m.ticker = time.NewTicker(saveInterval)
+	go m.run()
+}
+
+// Stop halts the periodic saving and saves the tables a last time.
+func (m *AddrManager) Stop() {
+	if m.ticker == nil {
+		return
+	}
+	m.ticker.Stop()
+	select {
+	case <-m.stopChan:
+		// Channel already closed, do nothing
+		return
+	default:
+		close(m.stopChan)
+	}
+	m.saveIfDirty()
+}
+
+func (m *AddrManager) run() {
+	for {
+		select {
+		case <-m.ticker.C:
+			m.saveIfDirty()
+		case <-m.stopChan:
+			return
+		}
+	}
+}
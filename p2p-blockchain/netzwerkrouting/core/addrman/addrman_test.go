@@ -0,0 +1,241 @@
+package addrman
+
+import (
+	"fmt"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/data/peer"
+)
+
+type peerStore interface {
+	NewPeer() common.PeerId
+	GetPeer(id common.PeerId) (*common.Peer, bool)
+}
+
+// mockPeerResolver maps peers to endpoints like the NetworkInfoRegistry.
+type mockPeerResolver struct {
+	mu        sync.Mutex
+	peerStore peerStore
+	endpoints map[common.PeerId]netip.AddrPort
+	peers     map[netip.AddrPort]common.PeerId
+	banned    map[netip.Addr]bool
+}
+
+func newMockPeerResolver(peerStore peerStore) *mockPeerResolver {
+	return &mockPeerResolver{
+		peerStore: peerStore,
+		endpoints: make(map[common.PeerId]netip.AddrPort),
+		peers:     make(map[netip.AddrPort]common.PeerId),
+		banned:    make(map[netip.Addr]bool),
+	}
+}
+
+func (r *mockPeerResolver) GetListeningEndpoint(peerID common.PeerId) (netip.AddrPort, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	endpoint, ok := r.endpoints[peerID]
+	return endpoint, ok
+}
+
+func (r *mockPeerResolver) GetOutboundPeer(addrPort netip.AddrPort) (common.PeerId, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peerID, ok := r.peers[addrPort]
+	return peerID, ok
+}
+
+func (r *mockPeerResolver) GetOrRegisterPeer(_ netip.AddrPort, listeningEndpoint netip.AddrPort) common.PeerId {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if peerID, ok := r.peers[listeningEndpoint]; ok {
+		return peerID
+	}
+	peerID := r.peerStore.NewPeer()
+	r.peers[listeningEndpoint] = peerID
+	r.endpoints[peerID] = listeningEndpoint
+	return peerID
+}
+
+func (r *mockPeerResolver) IsBanned(addrPort netip.AddrPort) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.banned[addrPort.Addr()]
+}
+
+func (r *mockPeerResolver) register(endpoints ...string) []common.PeerId {
+	peerIDs := make([]common.PeerId, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		peerIDs = append(peerIDs, r.GetOrRegisterPeer(netip.AddrPort{}, netip.MustParseAddrPort(endpoint)))
+	}
+	return peerIDs
+}
+
+func newTestAddrManager() (*AddrManager, *mockPeerResolver, peerStore) {
+	store := peer.NewPeerStore()
+	resolver := newMockPeerResolver(store)
+	return NewAddrManager(resolver, store), resolver, store
+}
+
+func setState(t *testing.T, store peerStore, peerID common.PeerId, state common.PeerConnectionState) {
+	t.Helper()
+	p, ok := store.GetPeer(peerID)
+	if !ok {
+		t.Fatalf("peer %s not found", peerID)
+	}
+	p.Lock()
+	p.State = state
+	p.Unlock()
+}
+
+func TestAddFromPeer_LimitsBucketsOfSourceGroup(t *testing.T) {
+	m, resolver, _ := newTestAddrManager()
+	source := resolver.register("80.1.1.1:8333")[0]
+
+	// A single party announcing addresses of many different networks
+	endpoints := make([]string, 0, 4000)
+	for i := range 4000 {
+		endpoints = append(endpoints, fmt.Sprintf("%d.%d.1.1:8333", 1+i/250, i%250))
+	}
+	m.AddFromPeer(source, resolver.register(endpoints...))
+
+	usedBuckets := 0
+	for _, bucket := range m.newBuckets {
+		if len(bucket) > 0 {
+			usedBuckets++
+		}
+	}
+	if usedBuckets > newBucketsPerSourceGroup {
+		t.Errorf("expected at most %d buckets for one source group, got %d", newBucketsPerSourceGroup, usedBuckets)
+	}
+	newCount, _ := m.Size()
+	if newCount > newBucketsPerSourceGroup*bucketSize {
+		t.Errorf("expected at most %d addresses from one source group, got %d", newBucketsPerSourceGroup*bucketSize, newCount)
+	}
+}
+
+func TestOnPeerConnected_PromotesOutboundPeers(t *testing.T) {
+	m, resolver, _ := newTestAddrManager()
+	peers := resolver.register("10.0.0.1:8333", "10.0.0.2:8333")
+	m.AddFromRegistry(peers)
+
+	m.OnPeerConnected(peers[0], true)
+	m.OnPeerConnected(peers[1], false)
+
+	newCount, triedCount := m.Size()
+	if newCount != 1 || triedCount != 1 {
+		t.Errorf("expected 1 new and 1 tried address, got %d new and %d tried", newCount, triedCount)
+	}
+	if !m.addrs[netip.MustParseAddrPort("10.0.0.1:8333")].tried {
+		t.Error("expected the outbound peer to be tried")
+	}
+}
+
+func TestSelectPeers_PrefersUnusedNetworkGroups(t *testing.T) {
+	m, resolver, store := newTestAddrManager()
+	connected := resolver.register("80.1.1.1:8333")
+	setState(t, store, connected[0], common.StateConnected)
+	peers := resolver.register("80.1.2.2:8333", "90.1.1.1:8333")
+	m.AddFromRegistry(peers)
+
+	for range 20 {
+		m.mu.Lock()
+		for _, info := range m.addrs {
+			info.attempts = 0
+			info.lastAttempt = 0
+		}
+		m.mu.Unlock()
+
+		selected := m.SelectPeers(1, connected)
+		if len(selected) != 1 || selected[0] != peers[1] {
+			t.Fatalf("expected the peer of the unused network group, got %v", selected)
+		}
+	}
+}
+
+func TestSelectPeers_SkipsUnavailablePeers(t *testing.T) {
+	m, resolver, store := newTestAddrManager()
+	peers := resolver.register("10.0.0.1:8333", "10.0.0.2:8333", "10.0.0.3:8333", "10.0.0.4:8333")
+	m.AddFromRegistry(peers)
+	setState(t, store, peers[0], common.StateConnected)
+	setState(t, store, peers[1], common.StateHolddown)
+	resolver.banned[netip.MustParseAddr("10.0.0.3")] = true
+
+	selected := m.SelectPeers(4, nil)
+
+	if len(selected) != 1 || selected[0] != peers[3] {
+		t.Errorf("expected only the available peer, got %v", selected)
+	}
+	if m.addrs[netip.MustParseAddrPort("10.0.0.4:8333")].attempts != 1 {
+		t.Error("expected the attempt to be recorded")
+	}
+}
+
+func TestSelectPeers_RegistersPeersOfKnownAddresses(t *testing.T) {
+	m, resolver, store := newTestAddrManager()
+	m.add(registryGroup, []netip.AddrPort{netip.MustParseAddrPort("10.0.0.1:8333")})
+
+	selected := m.SelectPeers(1, nil)
+
+	if len(selected) != 1 {
+		t.Fatalf("expected 1 peer, got %v", selected)
+	}
+	if _, ok := store.GetPeer(selected[0]); !ok {
+		t.Error("expected the peer to be created")
+	}
+	if endpoint, _ := resolver.GetListeningEndpoint(selected[0]); endpoint != netip.MustParseAddrPort("10.0.0.1:8333") {
+		t.Errorf("expected the peer to be registered with the address, got %v", endpoint)
+	}
+}
+
+func TestPersistentAddrManager_RestoresTables(t *testing.T) {
+	dir := t.TempDir()
+	store := peer.NewPeerStore()
+	resolver := newMockPeerResolver(store)
+	m, err := NewPersistentAddrManager(resolver, store, dir)
+	if err != nil {
+		t.Fatalf("NewPersistentAddrManager() returned error: %v", err)
+	}
+	peers := resolver.register("10.0.0.1:8333", "10.0.0.2:8333", "10.0.0.3:8333")
+	m.AddFromRegistry(peers)
+	m.OnPeerConnected(peers[0], true)
+	m.saveIfDirty()
+
+	reloaded, err := NewPersistentAddrManager(resolver, store, dir)
+	if err != nil {
+		t.Fatalf("NewPersistentAddrManager() returned error on reload: %v", err)
+	}
+
+	newCount, triedCount := reloaded.Size()
+	if newCount != 2 || triedCount != 1 {
+		t.Errorf("expected 2 new and 1 tried address, got %d new and %d tried", newCount, triedCount)
+	}
+	if reloaded.key != m.key {
+		t.Error("expected the key to be restored")
+	}
+	tried := netip.MustParseAddrPort("10.0.0.1:8333")
+	if !reloaded.addrs[tried].tried || reloaded.triedBucket(tried) != m.triedBucket(tried) {
+		t.Error("expected the tried address in the same bucket")
+	}
+}
+
+func TestNetGroup(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"80.1.1.1:8333", "80.1.200.3:9000", true},
+		{"80.1.1.1:8333", "80.2.1.1:8333", false},
+		{"[2001:db8:1::1]:8333", "[2001:db8:2::1]:8333", true},
+		{"10.0.0.1:8333", "10.0.0.2:8333", false},
+		{"127.0.0.1:8333", "127.0.0.1:8334", false},
+	}
+	for _, tt := range tests {
+		same := netGroup(netip.MustParseAddrPort(tt.a)) == netGroup(netip.MustParseAddrPort(tt.b))
+		if same != tt.same {
+			t.Errorf("netGroup(%s) == netGroup(%s) is %v, expected %v", tt.a, tt.b, same, tt.same)
+		}
+	}
+}
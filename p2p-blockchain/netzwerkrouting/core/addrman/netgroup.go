@@ -0,0 +1,31 @@
+package addrman
+
+import "net/netip"
+
+const (
+	// ipv4GroupBits and ipv6GroupBits are the prefix lengths of the network groups of public addresses.
+	ipv4GroupBits = 16
+	ipv6GroupBits = 32
+
+	// registryGroup is the source group of the addresses returned by the DNS registry.
+	registryGroup = "registry"
+)
+
+// netGroup returns the network group of an endpoint. Addresses of the same group are likely operated by the same party,
+// e.g. a provider or an attacker renting many servers in the same network, so the address manager limits their influence.
+// Public IPv4 addresses are grouped by /16 and public IPv6 addresses by /32.
+// Loopback, private and link-local endpoints are not grouped, every endpoint forms its own group.
+// Otherwise all nodes of a test network or a local cluster would share a single group.
+func netGroup(endpoint netip.AddrPort) string {
+	addr := endpoint.Addr().Unmap()
+	switch {
+	case !addr.IsValid():
+		return "invalid"
+	case addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast():
+		return endpoint.String()
+	case addr.Is4():
+		return netip.PrefixFrom(addr, ipv4GroupBits).Masked().String()
+	default:
+		return netip.PrefixFrom(addr, ipv6GroupBits).Masked().String()
+	}
+}
@@ -0,0 +1,131 @@
+package addrman
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"time"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+const (
+	// addrManFileName is the name of the file in the data directory the tables are persisted to.
+	addrManFileName = "peers.json"
+	// saveInterval is the interval in which changed tables are saved.
+	// Addresses change with every Addr message, so they are not saved on every change like the ban list.
+	saveInterval = 2 * time.Minute
+)
+
+// persistedTables is the file format of the tables.
+type persistedTables struct {
+	// Key is the hex encoded secret key, it is kept so the addresses stay in their buckets.
+	Key       string          `json:"key"`
+	Addresses []persistedAddr `json:"addresses"`
+}
+
+type persistedAddr struct {
+	Addr        netip.AddrPort `json:"addr"`
+	SourceGroup string         `json:"sourceGroup"`
+	Tried       bool           `json:"tried"`
+	Attempts    int            `json:"attempts"`
+	LastAttempt int64          `json:"lastAttempt"`
+	LastSuccess int64          `json:"lastSuccess"`
+}
+
+// NewPersistentAddrManager creates tables that are persisted to the given data directory.
+// The tables of a previous run are loaded. A missing file is not an error.
+func NewPersistentAddrManager(peerResolver peerResolver, peerRetriever peerRetriever, dataDir string) (*AddrManager, error) {
+	m := NewAddrManager(peerResolver, peerRetriever)
+	m.path = filepath.Join(dataDir, addrManFileName)
+
+	content, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read address tables %s: %w", m.path, err)
+	}
+
+	var tables persistedTables
+	if err := json.Unmarshal(content, &tables); err != nil {
+		return nil, fmt.Errorf("failed to parse address tables %s: %w", m.path, err)
+	}
+	key, err := hex.DecodeString(tables.Key)
+	if err != nil || len(key) != len(m.key) {
+		return nil, fmt.Errorf("invalid key in address tables %s", m.path)
+	}
+	copy(m.key[:], key)
+
+	for _, addr := range tables.Addresses {
+		if _, known := m.addrs[addr.Addr]; known {
+			continue
+		}
+		info := &addrInfo{
+			addr:        addr.Addr,
+			sourceGroup: addr.SourceGroup,
+			attempts:    addr.Attempts,
+			lastAttempt: addr.LastAttempt,
+			lastSuccess: addr.LastSuccess,
+		}
+		if addr.Tried {
+			m.insertTried(info)
+		} else {
+			m.insertNew(info)
+		}
+	}
+
+	newCount, triedCount := m.Size()
+	logger.Infof("[addrman] Loaded %d new and %d tried addresses from %s", newCount, triedCount, m.path)
+	return m, nil
+}
+
+// saveIfDirty writes the tables to the file if they changed since the last save.
+func (m *AddrManager) saveIfDirty() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.dirty {
+		return
+	}
+	if err := m.save(); err != nil {
+		logger.Warnf("[addrman] %v", err)
+		return
+	}
+	m.dirty = false
+}
+
+// save writes the tables to the file. mu must be held.
+func (m *AddrManager) save() error {
+	tables := persistedTables{
+		Key:       hex.EncodeToString(m.key[:]),
+		Addresses: make([]persistedAddr, 0, len(m.addrs)),
+	}
+	for _, info := range m.addrs {
+		tables.Addresses = append(tables.Addresses, persistedAddr{
+			Addr:        info.addr,
+			SourceGroup: info.sourceGroup,
+			Tried:       info.tried,
+			Attempts:    info.attempts,
+			LastAttempt: info.lastAttempt,
+			LastSuccess: info.lastSuccess,
+		})
+	}
+
+	content, err := json.Marshal(tables)
+	if err != nil {
+		return fmt.Errorf("failed to serialize address tables: %w", err)
+	}
+	tempPath := m.path + ".tmp"
+	if err := os.WriteFile(tempPath, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write address tables %s: %w", tempPath, err)
+	}
+	if err := os.Rename(tempPath, m.path); err != nil {
+		return fmt.Errorf("failed to replace address tables %s: %w", m.path, err)
+	}
+	return nil
+}
@@ -18,7 +18,7 @@ type errorMsgSender interface {
 type HandshakeMsgHandler interface {
 	HandleVersion(peerID common.PeerId, info VersionInfo)
 	HandleVerack(peerID common.PeerId, info VersionInfo)
-	HandleAck(peerID common.PeerId)
+	HandleAck(peerID common.PeerId, signature []byte)
 }
 
 func checkVersionCompatibility(string) bool {
@@ -33,31 +33,68 @@ func (h *handshakeService) HandleVersion(peerID common.PeerId, info VersionInfo)
 		return
 	}
 
-	p.Lock()
-	defer p.Unlock()
-
-	if p.State != common.StateNew {
-		logger.Warnf("[handshake_handler] peer %s sent Version message in invalid state %v", peerID, p.State)
-		h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "version", []byte(p.State.String()))
+	if h.isSelf(info) {
+		h.rejectSelfConnection(peerID)
 		return
 	}
 
-	if !checkVersionCompatibility(info.Version) {
-		logger.Warnf("[handshake_handler] peer %s has incompatible version %s", peerID, info.Version)
-		h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "version", []byte(info.Version))
-		return
-	}
+	isViolation := func() bool {
+		p.Lock()
+		defer p.Unlock()
+
+		if p.State != common.StateNew {
+			logger.Warnf("[handshake_handler] peer %s sent Version message in invalid state %v", peerID, p.State)
+			h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "version", []byte(p.State.String()))
+			return true
+		}
+
+		if !checkVersionCompatibility(info.Version) {
+			logger.Warnf("[handshake_handler] peer %s has incompatible version %s", peerID, info.Version)
+			h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "version", []byte(info.Version))
+			return false
+		}
+
+		if len(info.PublicKey) == 0 || len(info.Nonce) == 0 {
+			logger.Warnf("[handshake_handler] peer %s sent Version message without node identity", peerID)
+			h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "version", []byte("missing node identity"))
+			return false
+		}
 
-	// Valid
+		versionInfo, nonce := h.newVersionInfo()
+		transcript := handshakeTranscript{
+			initiatorKey:   info.PublicKey,
+			initiatorNonce: info.Nonce,
+			responderKey:   versionInfo.PublicKey,
+			responderNonce: nonce,
+		}
+		signature, err := h.signTranscript(transcript, roleResponder)
+		if err != nil {
+			logger.Errorf("[handshake_handler] failed to sign handshake of peer %s: %v", peerID, err)
+			return false
+		}
 
-	p.Version = info.Version
-	p.SupportedServices = info.SupportedServices()
+		// Valid
 
-	versionInfo := NewLocalVersionInfo()
+		p.Version = info.Version
+		p.SupportedServices = info.SupportedServices()
+		// Claimed until the peer signed the transcript in its Ack
+		p.PublicKey = info.PublicKey
+
+		versionInfo.Signature = signature
+		p.HandshakeNonce = nonce
+		p.RemoteHandshakeNonce = info.Nonce
+
+		p.State = common.StateAwaitingAck
 
-	p.State = common.StateAwaitingAck
+		go h.handshakeMsgSender.SendVerack(peerID, versionInfo)
 
-	go h.handshakeMsgSender.SendVerack(peerID, versionInfo)
+		return false
+	}()
+
+	if isViolation {
+		// Reported after the lock is released, the reporter locks the peer itself
+		h.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorHandshakeViolation, "unexpected Version message")
+	}
 }
 
 func (h *handshakeService) HandleVerack(peerID common.PeerId, info VersionInfo) {
@@ -68,13 +105,22 @@ func (h *handshakeService) HandleVerack(peerID common.PeerId, info VersionInfo)
 		return
 	}
 
-	shouldNotify := func() bool {
+	if h.isSelf(info) {
+		h.rejectSelfConnection(peerID)
+		return
+	}
+
+	isViolation := false
+	isInvalidSignature := false
+	var ackSignature []byte
+	isVerified := func() bool {
 		p.Lock()
 		defer p.Unlock()
 
 		if p.State != common.StateAwaitingVerack {
 			logger.Warnf("[handshake_handler] peer %s sent Verack message in invalid state %v", peerID, p.State)
 			h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "verack", []byte(p.State.String()))
+			isViolation = true
 			return false
 		}
 
@@ -84,27 +130,58 @@ func (h *handshakeService) HandleVerack(peerID common.PeerId, info VersionInfo)
 			return false
 		}
 
+		transcript := handshakeTranscript{
+			initiatorKey:   h.identity.PublicKey(),
+			initiatorNonce: p.HandshakeNonce,
+			responderKey:   info.PublicKey,
+			responderNonce: info.Nonce,
+		}
+		if !verifyTranscript(transcript, roleResponder, info.Signature) {
+			logger.Warnf("[handshake_handler] peer %s sent Verack message with invalid node identity", peerID)
+			h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "verack", []byte("invalid signature"))
+			isInvalidSignature = true
+			return false
+		}
+
+		signature, err := h.signTranscript(transcript, roleInitiator)
+		if err != nil {
+			logger.Errorf("[handshake_handler] failed to sign handshake of peer %s: %v", peerID, err)
+			return false
+		}
+
 		// Valid
 
-		p.State = common.StateConnected
 		p.Version = info.Version
 		p.SupportedServices = info.SupportedServices()
-
-		go h.handshakeMsgSender.SendAck(peerID)
+		p.PublicKey = info.PublicKey
+		p.RemoteHandshakeNonce = info.Nonce
+		ackSignature = signature
 
 		return true
 	}()
 
-	if shouldNotify {
-		// Notify observers that outbound connection is established (isOutbound=true)
-		// Only outbound connections trigger the Initial Block Download (IBD) process
-		// Called after lock is released to avoid deadlocks caused by notification callbacks
-		// which might try to access the peer again
-		h.notifyPeerConnected(peerID, true)
+	if isViolation {
+		h.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorHandshakeViolation, "unexpected Verack message")
+	}
+	if isInvalidSignature {
+		h.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorInvalidHandshakeSignature, "invalid Verack signature")
 	}
+
+	// Completed after the lock is released, the duplicate check locks the other peers
+	if !isVerified || !h.completeHandshake(p, common.StateAwaitingVerack, "verack") {
+		return
+	}
+
+	go h.handshakeMsgSender.SendAck(peerID, ackSignature)
+
+	// Notify observers that outbound connection is established (isOutbound=true)
+	// Only outbound connections trigger the Initial Block Download (IBD) process
+	// Called after lock is released to avoid deadlocks caused by notification callbacks
+	// which might try to access the peer again
+	h.notifyPeerConnected(peerID, true)
 }
 
-func (h *handshakeService) HandleAck(peerID common.PeerId) {
+func (h *handshakeService) HandleAck(peerID common.PeerId, signature []byte) {
 	p, ok := h.peerRetriever.GetPeer(peerID)
 	if !ok {
 		logger.Warnf("[handshake_handler] unknown peer %s sent Ack message", peerID)
@@ -112,26 +189,49 @@ func (h *handshakeService) HandleAck(peerID common.PeerId) {
 		return
 	}
 
-	shouldNotify := func() bool {
+	isViolation := false
+	isInvalidSignature := false
+	isVerified := func() bool {
 		p.Lock()
 		defer p.Unlock()
 
 		if p.State != common.StateAwaitingAck {
 			logger.Warnf("[handshake_handler] peer %s sent Ack message in invalid state %v", peerID, p.State)
 			h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "ack", []byte(p.State.String()))
+			isViolation = true
 			return false
 		}
 
-		p.State = common.StateConnected
+		transcript := handshakeTranscript{
+			initiatorKey:   p.PublicKey,
+			initiatorNonce: p.RemoteHandshakeNonce,
+			responderKey:   h.identity.PublicKey(),
+			responderNonce: p.HandshakeNonce,
+		}
+		if !verifyTranscript(transcript, roleInitiator, signature) {
+			logger.Warnf("[handshake_handler] peer %s sent Ack message with invalid signature", peerID)
+			h.errorMsgSender.SendReject(peerID, common.ErrorTypeRejectInvalid, "ack", []byte("invalid signature"))
+			isInvalidSignature = true
+			return false
+		}
 
 		return true
 	}()
 
-	if shouldNotify {
-		// Notify observers that inbound connection is established (isOutbound=false)
-		// Inbound connections do NOT trigger IBD - only the initiating node syncs
-		// Called after lock is released to avoid deadlocks caused by notification callbacks
-		// which might try to access the peer again
-		h.notifyPeerConnected(peerID, false)
+	if isViolation {
+		h.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorHandshakeViolation, "unexpected Ack message")
+	}
+	if isInvalidSignature {
+		h.misbehaviorReporter.ReportMisbehavior(peerID, common.MisbehaviorInvalidHandshakeSignature, "invalid Ack signature")
+	}
+
+	if !isVerified || !h.completeHandshake(p, common.StateAwaitingAck, "ack") {
+		return
 	}
+
+	// Notify observers that inbound connection is established (isOutbound=false)
+	// Inbound connections do NOT trigger IBD - only the initiating node syncs
+	// Called after lock is released to avoid deadlocks caused by notification callbacks
+	// which might try to access the peer again
+	h.notifyPeerConnected(peerID, false)
 }
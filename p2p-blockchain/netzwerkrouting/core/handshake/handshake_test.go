@@ -1,12 +1,14 @@
 package handshake
 
 import (
+	"bytes"
 	"os"
 	"sync"
 	"testing"
 	"time"
 
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/nodecert"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/data/peer"
 )
 
@@ -24,6 +26,9 @@ type mockHandshakeMsgSender struct {
 	versionCalls []common.PeerId
 	verackCalls  []common.PeerId
 	ackCalls     []common.PeerId
+	lastVersion  VersionInfo
+	lastVerack   VersionInfo
+	lastAck      []byte
 }
 
 func newMockHandshakeMsgSender() *mockHandshakeMsgSender {
@@ -38,18 +43,21 @@ func (m *mockHandshakeMsgSender) SendVersion(peerID common.PeerId, info VersionI
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.versionCalls = append(m.versionCalls, peerID)
+	m.lastVersion = info
 }
 
 func (m *mockHandshakeMsgSender) SendVerack(peerID common.PeerId, info VersionInfo) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.verackCalls = append(m.verackCalls, peerID)
+	m.lastVerack = info
 }
 
-func (m *mockHandshakeMsgSender) SendAck(peerID common.PeerId) {
+func (m *mockHandshakeMsgSender) SendAck(peerID common.PeerId, signature []byte) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.ackCalls = append(m.ackCalls, peerID)
+	m.lastAck = signature
 }
 
 func (m *mockHandshakeMsgSender) getVersionCallCount() int {
@@ -70,10 +78,52 @@ func (m *mockHandshakeMsgSender) getAckCallCount() int {
 	return len(m.ackCalls)
 }
 
+type mockPeerDisconnector struct {
+	mu           sync.Mutex
+	disconnected []common.PeerId
+}
+
+func (m *mockPeerDisconnector) Disconnect(peerID common.PeerId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disconnected = append(m.disconnected, peerID)
+	return nil
+}
+
+func (m *mockPeerDisconnector) getDisconnectCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.disconnected)
+}
+
+// newTestIdentity creates a node identity that is only kept in memory.
+func newTestIdentity(t *testing.T) *nodecert.Identity {
+	t.Helper()
+	cert, err := nodecert.LoadOrCreate("")
+	if err != nil {
+		t.Fatalf("failed to create node certificate: %v", err)
+	}
+	identity, err := nodecert.NewIdentity(cert)
+	if err != nil {
+		t.Fatalf("failed to create node identity: %v", err)
+	}
+	return identity
+}
+
+// sign signs a handshake transcript in the given role the way a remote node answers the handshake challenge.
+func sign(t *testing.T, identity *nodecert.Identity, transcript handshakeTranscript, role string) []byte {
+	t.Helper()
+	signature, err := identity.Sign(transcript.message(role))
+	if err != nil {
+		t.Fatalf("failed to sign transcript: %v", err)
+	}
+	return signature
+}
+
 func TestInitiateHandshake(t *testing.T) {
 	peerStore := peer.NewPeerStore()
 	sender := newMockHandshakeMsgSender()
-	service := NewHandshakeService(sender, peerStore, nil)
+	service := NewHandshakeService(sender, peerStore, nil, nil, newTestIdentity(t), &mockPeerDisconnector{})
 
 	peerID := peerStore.NewPeer()
 
@@ -95,12 +145,15 @@ func TestInitiateHandshake(t *testing.T) {
 	if p.State != common.StateAwaitingVerack {
 		t.Errorf("expected state StateAwaitingVerack, got %v", p.State)
 	}
+	if len(p.HandshakeNonce) != nonceSize {
+		t.Errorf("expected a nonce of %d bytes, got %d", nonceSize, len(p.HandshakeNonce))
+	}
 }
 
 func TestInitiateHandshake_RejectsWhenAlreadyConnected(t *testing.T) {
 	peerStore := peer.NewPeerStore()
 	sender := newMockHandshakeMsgSender()
-	service := NewHandshakeService(sender, peerStore, nil)
+	service := NewHandshakeService(sender, peerStore, nil, nil, newTestIdentity(t), &mockPeerDisconnector{})
 
 	peerID := peerStore.NewPeer()
 
@@ -123,12 +176,15 @@ func TestInitiateHandshake_RejectsWhenAlreadyConnected(t *testing.T) {
 func TestHandleVersion(t *testing.T) {
 	peerStore := peer.NewPeerStore()
 	sender := newMockHandshakeMsgSender()
-	service := NewHandshakeService(sender, peerStore, nil)
+	service := NewHandshakeService(sender, peerStore, nil, nil, newTestIdentity(t), &mockPeerDisconnector{})
 
 	peerID := peerStore.NewPeer()
 
+	remote := newTestIdentity(t)
 	versionInfo := VersionInfo{
-		Version: "2.5.1",
+		Version:   "2.5.1",
+		PublicKey: remote.PublicKey(),
+		Nonce:     newNonce(),
 	}
 	versionInfo.AddService(common.ServiceType_Netzwerkrouting, common.ServiceType_BlockchainFull)
 
@@ -150,22 +206,53 @@ func TestHandleVersion(t *testing.T) {
 	if len(p.SupportedServices) != 2 {
 		t.Errorf("expected 2 supported services, got %d", len(p.SupportedServices))
 	}
+
+	sender.mu.Lock()
+	verack := sender.lastVerack
+	sender.mu.Unlock()
+	transcript := handshakeTranscript{
+		initiatorKey:   remote.PublicKey(),
+		initiatorNonce: versionInfo.Nonce,
+		responderKey:   verack.PublicKey,
+		responderNonce: verack.Nonce,
+	}
+	if !verifyTranscript(transcript, roleResponder, verack.Signature) {
+		t.Error("expected Verack to carry a valid signature of the handshake transcript")
+	}
+	if !bytes.Equal(p.RemoteHandshakeNonce, versionInfo.Nonce) {
+		t.Error("expected the Version nonce to be stored")
+	}
+	if !bytes.Equal(verack.Nonce, p.HandshakeNonce) {
+		t.Error("expected Verack to carry the stored nonce")
+	}
 }
 
 func TestHandleVerack(t *testing.T) {
 	peerStore := peer.NewPeerStore()
 	sender := newMockHandshakeMsgSender()
-	service := NewHandshakeService(sender, peerStore, nil)
+	service := NewHandshakeService(sender, peerStore, nil, nil, newTestIdentity(t), &mockPeerDisconnector{})
 
 	peerID := peerStore.NewPeer()
 
+	nonce := newNonce()
 	p, _ := peerStore.GetPeer(peerID)
 	p.Lock()
 	p.State = common.StateAwaitingVerack
+	p.HandshakeNonce = nonce
 	p.Unlock()
 
+	remote := newTestIdentity(t)
+	transcript := handshakeTranscript{
+		initiatorKey:   service.identity.PublicKey(),
+		initiatorNonce: nonce,
+		responderKey:   remote.PublicKey(),
+		responderNonce: newNonce(),
+	}
 	versionInfo := VersionInfo{
-		Version: "1.5.0",
+		Version:   "1.5.0",
+		PublicKey: remote.PublicKey(),
+		Nonce:     transcript.responderNonce,
+		Signature: sign(t, remote, transcript, roleResponder),
 	}
 	versionInfo.AddService(common.ServiceType_BlockchainFull, common.ServiceType_Netzwerkrouting, common.ServiceType_Miner)
 
@@ -185,23 +272,270 @@ func TestHandleVerack(t *testing.T) {
 	if len(p.SupportedServices) != 3 {
 		t.Errorf("expected 3 supported service, got %d", len(p.SupportedServices))
 	}
+	if !bytes.Equal(p.PublicKey, remote.PublicKey()) {
+		t.Error("expected the verified public key to be stored")
+	}
+
+	sender.mu.Lock()
+	ackSignature := sender.lastAck
+	sender.mu.Unlock()
+	if !verifyTranscript(transcript, roleInitiator, ackSignature) {
+		t.Error("expected Ack to carry a valid signature of the handshake transcript")
+	}
 }
 
 func TestHandleAck(t *testing.T) {
 	peerStore := peer.NewPeerStore()
 	sender := newMockHandshakeMsgSender()
-	service := NewHandshakeService(sender, peerStore, nil)
+	service := NewHandshakeService(sender, peerStore, nil, nil, newTestIdentity(t), &mockPeerDisconnector{})
 
 	peerID := peerStore.NewPeer()
 
+	remote := newTestIdentity(t)
+	transcript := handshakeTranscript{
+		initiatorKey:   remote.PublicKey(),
+		initiatorNonce: newNonce(),
+		responderKey:   service.identity.PublicKey(),
+		responderNonce: newNonce(),
+	}
 	p, _ := peerStore.GetPeer(peerID)
 	p.Lock()
 	p.State = common.StateAwaitingAck
+	p.PublicKey = remote.PublicKey()
+	p.HandshakeNonce = transcript.responderNonce
+	p.RemoteHandshakeNonce = transcript.initiatorNonce
 	p.Unlock()
 
-	service.HandleAck(peerID)
+	service.HandleAck(peerID, sign(t, remote, transcript, roleInitiator))
 
 	if p.State != common.StateConnected {
 		t.Errorf("expected state StateConnected, got %v", p.State)
 	}
 }
+
+type mockErrorMsgSender struct{}
+
+func (mockErrorMsgSender) SendReject(common.PeerId, int32, string, []byte) {}
+
+type mockMisbehaviorReporter struct {
+	mu     sync.Mutex
+	scores map[common.PeerId]int
+}
+
+func (m *mockMisbehaviorReporter) ReportMisbehavior(peerID common.PeerId, score int, _ string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scores[peerID] += score
+}
+
+func TestHandleAck_ReportsMisbehaviorInInvalidState(t *testing.T) {
+	peerStore := peer.NewPeerStore()
+	sender := newMockHandshakeMsgSender()
+	reporter := &mockMisbehaviorReporter{scores: make(map[common.PeerId]int)}
+	service := NewHandshakeService(sender, peerStore, mockErrorMsgSender{}, reporter, newTestIdentity(t), &mockPeerDisconnector{})
+
+	peerID := peerStore.NewPeer()
+
+	service.HandleAck(peerID, nil)
+	service.HandleVerack(peerID, VersionInfo{Version: "1.0.0"})
+
+	if reporter.scores[peerID] != 2*common.MisbehaviorHandshakeViolation {
+		t.Errorf("expected score %d, got %d", 2*common.MisbehaviorHandshakeViolation, reporter.scores[peerID])
+	}
+	p, _ := peerStore.GetPeer(peerID)
+	if p.State != common.StateNew {
+		t.Errorf("expected state StateNew, got %v", p.State)
+	}
+}
+
+func TestHandshake_BothSidesVerifyIdentity(t *testing.T) {
+	initiatorStore := peer.NewPeerStore()
+	initiatorSender := newMockHandshakeMsgSender()
+	initiatorIdentity := newTestIdentity(t)
+	initiator := NewHandshakeService(initiatorSender, initiatorStore, mockErrorMsgSender{}, nil, initiatorIdentity, &mockPeerDisconnector{})
+
+	responderStore := peer.NewPeerStore()
+	responderSender := newMockHandshakeMsgSender()
+	responderIdentity := newTestIdentity(t)
+	responder := NewHandshakeService(responderSender, responderStore, mockErrorMsgSender{}, nil, responderIdentity, &mockPeerDisconnector{})
+
+	responderID := initiatorStore.NewPeer()
+	initiatorID := responderStore.NewPeer()
+
+	if err := initiator.InitiateHandshake(responderID); err != nil {
+		t.Fatalf("unexpected error initiating handshake: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	initiatorSender.mu.Lock()
+	version := initiatorSender.lastVersion
+	initiatorSender.mu.Unlock()
+
+	responder.HandleVersion(initiatorID, version)
+	time.Sleep(10 * time.Millisecond)
+	responderSender.mu.Lock()
+	verack := responderSender.lastVerack
+	responderSender.mu.Unlock()
+
+	initiator.HandleVerack(responderID, verack)
+	time.Sleep(10 * time.Millisecond)
+	initiatorSender.mu.Lock()
+	ack := initiatorSender.lastAck
+	initiatorSender.mu.Unlock()
+
+	responder.HandleAck(initiatorID, ack)
+
+	initiatorPeer, _ := initiatorStore.GetPeer(responderID)
+	responderPeer, _ := responderStore.GetPeer(initiatorID)
+	if initiatorPeer.State != common.StateConnected || responderPeer.State != common.StateConnected {
+		t.Fatalf("expected both sides connected, got %v and %v", initiatorPeer.State, responderPeer.State)
+	}
+	if !bytes.Equal(initiatorPeer.PublicKey, responderIdentity.PublicKey()) {
+		t.Error("expected the initiator to know the identity of the responder")
+	}
+	if !bytes.Equal(responderPeer.PublicKey, initiatorIdentity.PublicKey()) {
+		t.Error("expected the responder to know the identity of the initiator")
+	}
+}
+
+func TestHandleVersion_DropsConnectionToSelf(t *testing.T) {
+	peerStore := peer.NewPeerStore()
+	sender := newMockHandshakeMsgSender()
+	disconnector := &mockPeerDisconnector{}
+	identity := newTestIdentity(t)
+	service := NewHandshakeService(sender, peerStore, mockErrorMsgSender{}, nil, identity, disconnector)
+
+	peerID := peerStore.NewPeer()
+
+	service.HandleVersion(peerID, VersionInfo{Version: "1.0.0", PublicKey: identity.PublicKey(), Nonce: newNonce()})
+	time.Sleep(10 * time.Millisecond)
+
+	if disconnector.getDisconnectCount() != 1 {
+		t.Errorf("expected connection to self to be disconnected, got %d disconnects", disconnector.getDisconnectCount())
+	}
+	if sender.getVerackCallCount() != 0 {
+		t.Errorf("expected no Verack to self, got %d", sender.getVerackCallCount())
+	}
+}
+
+func TestHandleVerack_InvalidSignature(t *testing.T) {
+	peerStore := peer.NewPeerStore()
+	sender := newMockHandshakeMsgSender()
+	reporter := &mockMisbehaviorReporter{scores: make(map[common.PeerId]int)}
+	service := NewHandshakeService(sender, peerStore, mockErrorMsgSender{}, reporter, newTestIdentity(t), &mockPeerDisconnector{})
+
+	peerID := peerStore.NewPeer()
+	p, _ := peerStore.GetPeer(peerID)
+	p.Lock()
+	p.State = common.StateAwaitingVerack
+	p.HandshakeNonce = newNonce()
+	p.Unlock()
+
+	// Signs another nonce than the one sent, e.g. replaying a Verack recorded from another connection
+	remote := newTestIdentity(t)
+	transcript := handshakeTranscript{
+		initiatorKey:   service.identity.PublicKey(),
+		initiatorNonce: newNonce(),
+		responderKey:   remote.PublicKey(),
+		responderNonce: newNonce(),
+	}
+	service.HandleVerack(peerID, VersionInfo{
+		Version:   "1.0.0",
+		PublicKey: remote.PublicKey(),
+		Nonce:     transcript.responderNonce,
+		Signature: sign(t, remote, transcript, roleResponder),
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if p.State != common.StateAwaitingVerack {
+		t.Errorf("expected state StateAwaitingVerack, got %v", p.State)
+	}
+	if sender.getAckCallCount() != 0 {
+		t.Errorf("expected no Ack, got %d", sender.getAckCallCount())
+	}
+	if reporter.scores[peerID] != common.MisbehaviorInvalidHandshakeSignature {
+		t.Errorf("expected score %d, got %d", common.MisbehaviorInvalidHandshakeSignature, reporter.scores[peerID])
+	}
+}
+
+func TestHandleVerack_RelayedSignature(t *testing.T) {
+	peerStore := peer.NewPeerStore()
+	sender := newMockHandshakeMsgSender()
+	reporter := &mockMisbehaviorReporter{scores: make(map[common.PeerId]int)}
+	service := NewHandshakeService(sender, peerStore, mockErrorMsgSender{}, reporter, newTestIdentity(t), &mockPeerDisconnector{})
+
+	nonce := newNonce()
+	peerID := peerStore.NewPeer()
+	p, _ := peerStore.GetPeer(peerID)
+	p.Lock()
+	p.State = common.StateAwaitingVerack
+	p.HandshakeNonce = nonce
+	p.Unlock()
+
+	// A relaying node passed our nonce on under its own key, the remote signed the handshake with the relaying node
+	remote := newTestIdentity(t)
+	relay := newTestIdentity(t)
+	transcript := handshakeTranscript{
+		initiatorKey:   relay.PublicKey(),
+		initiatorNonce: nonce,
+		responderKey:   remote.PublicKey(),
+		responderNonce: newNonce(),
+	}
+	service.HandleVerack(peerID, VersionInfo{
+		Version:   "1.0.0",
+		PublicKey: remote.PublicKey(),
+		Nonce:     transcript.responderNonce,
+		Signature: sign(t, remote, transcript, roleResponder),
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if p.State != common.StateAwaitingVerack {
+		t.Errorf("expected state StateAwaitingVerack, got %v", p.State)
+	}
+	if reporter.scores[peerID] != common.MisbehaviorInvalidHandshakeSignature {
+		t.Errorf("expected score %d, got %d", common.MisbehaviorInvalidHandshakeSignature, reporter.scores[peerID])
+	}
+}
+
+func TestHandleAck_RejectsDuplicateIdentity(t *testing.T) {
+	peerStore := peer.NewPeerStore()
+	sender := newMockHandshakeMsgSender()
+	disconnector := &mockPeerDisconnector{}
+	service := NewHandshakeService(sender, peerStore, mockErrorMsgSender{}, nil, newTestIdentity(t), disconnector)
+
+	remote := newTestIdentity(t)
+
+	// The remote node is already connected over another address
+	connectedID := peerStore.NewPeer()
+	connected, _ := peerStore.GetPeer(connectedID)
+	connected.Lock()
+	connected.State = common.StateConnected
+	connected.PublicKey = remote.PublicKey()
+	connected.Unlock()
+
+	transcript := handshakeTranscript{
+		initiatorKey:   remote.PublicKey(),
+		initiatorNonce: newNonce(),
+		responderKey:   service.identity.PublicKey(),
+		responderNonce: newNonce(),
+	}
+	peerID := peerStore.NewPeer()
+	p, _ := peerStore.GetPeer(peerID)
+	p.Lock()
+	p.State = common.StateAwaitingAck
+	p.PublicKey = remote.PublicKey()
+	p.HandshakeNonce = transcript.responderNonce
+	p.RemoteHandshakeNonce = transcript.initiatorNonce
+	p.Unlock()
+
+	service.HandleAck(peerID, sign(t, remote, transcript, roleInitiator))
+
+	if p.State == common.StateConnected {
+		t.Error("expected duplicate connection not to be established")
+	}
+	if disconnector.getDisconnectCount() != 1 || disconnector.disconnected[0] != peerID {
+		t.Errorf("expected the duplicate connection to be disconnected, got %v", disconnector.disconnected)
+	}
+	if connected.State != common.StateConnected {
+		t.Error("expected the existing connection to be kept")
+	}
+}
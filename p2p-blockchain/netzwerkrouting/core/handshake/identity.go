@@ -0,0 +1,156 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/nodecert"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// nonceSize is the size of the handshake challenge in bytes.
+const nonceSize = 32
+
+// challengePrefix is prepended to the handshake transcript before signing it,
+// so a signature made in the handshake is not valid for any other purpose.
+const challengePrefix = "vsgoin-handshake-challenge:"
+
+// Roles of the nodes in a handshake. Each node signs the transcript together with its own role,
+// so the signature of one node can't be passed off as the signature of the other.
+const (
+	roleInitiator = "initiator"
+	roleResponder = "responder"
+)
+
+// nodeIdentity provides the keypair identifying this node.
+// It is implemented by nodecert.Identity.
+type nodeIdentity interface {
+	PublicKey() []byte
+	Sign(message []byte) ([]byte, error)
+}
+
+// peerDisconnector is an interface for disconnecting peers.
+// It is implemented by disconnect.DisconnectService.
+type peerDisconnector interface {
+	Disconnect(peerID common.PeerId) error
+}
+
+// newNonce creates a random handshake challenge.
+func newNonce() []byte {
+	nonce := make([]byte, nonceSize)
+	_, _ = rand.Read(nonce) // never returns an error
+	return nonce
+}
+
+// handshakeTranscript holds the public keys and nonces both nodes sent in a handshake.
+// Both nodes sign the whole transcript, so a signature is bound to this handshake between these two keys
+// and can't be relayed into a handshake with another node.
+type handshakeTranscript struct {
+	initiatorKey   []byte
+	initiatorNonce []byte
+	responderKey   []byte
+	responderNonce []byte
+}
+
+// message returns the message signed by the node with the given role.
+// Each field is prefixed with its length, so no field can be shifted into its neighbour.
+func (t handshakeTranscript) message(role string) []byte {
+	message := []byte(challengePrefix)
+	for _, field := range [][]byte{[]byte(role), t.initiatorKey, t.initiatorNonce, t.responderKey, t.responderNonce} {
+		message = binary.BigEndian.AppendUint32(message, uint32(len(field)))
+		message = append(message, field...)
+	}
+	return message
+}
+
+// signTranscript signs the transcript in the given role to prove the identity of this node.
+func (h *handshakeService) signTranscript(transcript handshakeTranscript, role string) ([]byte, error) {
+	return h.identity.Sign(transcript.message(role))
+}
+
+// verifyTranscript checks that the node with the given role signed the transcript with the key the transcript contains for it.
+func verifyTranscript(transcript handshakeTranscript, role string, signature []byte) bool {
+	publicKey := transcript.initiatorKey
+	if role == roleResponder {
+		publicKey = transcript.responderKey
+	}
+	if len(publicKey) == 0 || len(transcript.initiatorNonce) == 0 || len(transcript.responderNonce) == 0 {
+		return false
+	}
+	return nodecert.VerifySignature(publicKey, transcript.message(role), signature)
+}
+
+// newVersionInfo creates the VersionInfo of this node carrying the node identity and a new nonce.
+// The nonce is returned, it must be stored in the peer to verify the signature of the answer.
+func (h *handshakeService) newVersionInfo() (VersionInfo, []byte) {
+	info := NewLocalVersionInfo()
+	info.PublicKey = h.identity.PublicKey()
+	info.Nonce = newNonce()
+	return info, info.Nonce
+}
+
+// isSelf reports whether a handshake message was sent by this node, i.e. the node connected to itself,
+// e.g. because another node or the registry passed on one of its own addresses.
+func (h *handshakeService) isSelf(info VersionInfo) bool {
+	return bytes.Equal(info.PublicKey, h.identity.PublicKey())
+}
+
+// rejectSelfConnection drops a connection of the node to itself.
+// The peer is disconnected without a reject message, it would only be sent to this node.
+func (h *handshakeService) rejectSelfConnection(peerID common.PeerId) {
+	logger.Infof("[handshake_handler] peer %s is this node, dropping the connection to ourselves", peerID)
+	_ = h.peerDisconnector.Disconnect(peerID)
+}
+
+// completeHandshake marks a peer whose identity has been verified as connected, if the peer is still in awaitedState.
+// If the node is already connected to the same identity, e.g. over another address of the remote node,
+// the new connection is rejected and the existing connection is kept.
+// It must be called without holding the lock of the peer. It returns whether the peer is connected.
+func (h *handshakeService) completeHandshake(p *common.Peer, awaitedState common.PeerConnectionState, messageType string) bool {
+	// Serializes the completion of handshakes, so two connections of the same identity can't both pass the duplicate check
+	h.identityMu.Lock()
+	defer h.identityMu.Unlock()
+
+	p.Lock()
+	publicKey := p.PublicKey
+	p.Unlock()
+
+	if duplicate, ok := h.connectedPeerWithKey(p.ID(), publicKey); ok {
+		logger.Infof("[handshake_handler] peer %s is the same node as connected peer %s, rejecting duplicate connection", p.ID(), duplicate)
+		h.errorMsgSender.SendReject(p.ID(), common.ErrorTypeRejectInvalid, messageType, []byte("already connected to this node"))
+		_ = h.peerDisconnector.Disconnect(p.ID())
+		return false
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.State != awaitedState {
+		// Disconnected in the meantime
+		return false
+	}
+	p.State = common.StateConnected
+	return true
+}
+
+// connectedPeerWithKey returns a connected peer other than peerID identified by publicKey.
+func (h *handshakeService) connectedPeerWithKey(peerID common.PeerId, publicKey []byte) (common.PeerId, bool) {
+	for _, id := range h.peerRetriever.GetAllConnectedPeers() {
+		if id == peerID {
+			continue
+		}
+		other, ok := h.peerRetriever.GetPeer(id)
+		if !ok {
+			continue
+		}
+		other.Lock()
+		same := other.State == common.StateConnected && bytes.Equal(other.PublicKey, publicKey)
+		other.Unlock()
+		if same {
+			return id, true
+		}
+	}
+	return "", false
+}
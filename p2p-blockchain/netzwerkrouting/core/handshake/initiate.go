@@ -12,8 +12,8 @@ type HandshakeMsgSender interface {
 	SendVersion(peerID common.PeerId, info VersionInfo)
 	// SendVerack sends a Verack message to the specified peer.
 	SendVerack(peerID common.PeerId, info VersionInfo)
-	// SendAck sends an Ack message to the specified peer.
-	SendAck(peerID common.PeerId)
+	// SendAck sends an Ack message with the signature of the peer's nonce to the specified peer.
+	SendAck(peerID common.PeerId, signature []byte)
 }
 
 // HandshakeInitiator defines the interface for initiating handshakes with peers.
@@ -40,8 +40,9 @@ func (h *handshakeService) InitiateHandshake(peerID common.PeerId) error {
 		return fmt.Errorf("cannot initiate handshake with peer %s in state %v. peer state must be StateNew", peerID, p.State)
 	}
 
-	versionInfo := NewLocalVersionInfo()
+	versionInfo, nonce := h.newVersionInfo()
 
+	p.HandshakeNonce = nonce
 	p.State = common.StateAwaitingVerack
 
 	go h.handshakeMsgSender.SendVersion(peerID, versionInfo)
@@ -1,29 +1,46 @@
 package handshake
 
 import (
+	"sync"
+
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 )
 
 // handshakeService implements HandshakeMsgHandler (for infrastructure), HandshakeInitiator (for api),
 // and ObservableHandshakeService (for connection observers) with the actual domain logic.
 type handshakeService struct {
-	handshakeMsgSender HandshakeMsgSender
-	peerRetriever      peerRetriever
-	errorMsgSender     errorMsgSender
+	handshakeMsgSender  HandshakeMsgSender
+	peerRetriever       peerRetriever
+	errorMsgSender      errorMsgSender
+	misbehaviorReporter misbehaviorReporter
+	identity            nodeIdentity
+	peerDisconnector    peerDisconnector
+	// identityMu serializes the duplicate check when handshakes complete, see completeHandshake.
+	identityMu sync.Mutex
 	*observerManager
 }
 
-func NewHandshakeService(handshakeMsgSender HandshakeMsgSender, peerRetriever peerRetriever, errorMsgSender errorMsgSender) *handshakeService {
+func NewHandshakeService(handshakeMsgSender HandshakeMsgSender, peerRetriever peerRetriever, errorMsgSender errorMsgSender, misbehaviorReporter misbehaviorReporter, identity nodeIdentity, peerDisconnector peerDisconnector) *handshakeService {
 	return &handshakeService{
-		handshakeMsgSender: handshakeMsgSender,
-		peerRetriever:      peerRetriever,
-		errorMsgSender:     errorMsgSender,
-		observerManager:    newObserverManager(),
+		handshakeMsgSender:  handshakeMsgSender,
+		peerRetriever:       peerRetriever,
+		errorMsgSender:      errorMsgSender,
+		misbehaviorReporter: misbehaviorReporter,
+		identity:            identity,
+		peerDisconnector:    peerDisconnector,
+		observerManager:     newObserverManager(),
 	}
 }
 
+// misbehaviorReporter is an interface for reporting offences of peers.
+// It is implemented by misbehavior.MisbehaviorService.
+type misbehaviorReporter interface {
+	ReportMisbehavior(peerID common.PeerId, score int, reason string)
+}
+
 // peerRetriever is an interface for retrieving peers.
 // It is implemented by peer.PeerStore.
 type peerRetriever interface {
 	GetPeer(id common.PeerId) (*common.Peer, bool)
+	GetAllConnectedPeers() []common.PeerId
 }
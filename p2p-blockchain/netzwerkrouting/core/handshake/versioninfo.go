@@ -16,6 +16,12 @@ var (
 
 type VersionInfo struct {
 	Version string
+	// PublicKey is the PKIX encoded public key identifying the sending node.
+	PublicKey []byte
+	// Nonce is the challenge the receiving node must sign to prove its identity.
+	Nonce []byte
+	// Signature is the signature of the receiver's nonce by the sending node, only set in Verack messages.
+	Signature []byte
 	// supportedServices holds the list of services supported by the peer.
 	// It is guaranteed to follow all domain rules.
 	supportedServices []common.ServiceType
@@ -0,0 +1,157 @@
+package misbehavior
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// banListFileName is the name of the file in the data directory the ban list is persisted to.
+const banListFileName = "banlist.json"
+
+// Ban is a banned IP address. Connections from and to a banned address are refused until the ban expires.
+type Ban struct {
+	Addr   netip.Addr `json:"addr"`
+	Reason string     `json:"reason"`
+	// CreatedAt and ExpiresAt are Unix timestamps.
+	CreatedAt int64 `json:"createdAt"`
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// BanList holds the banned addresses.
+// A persistent ban list is written to the data directory on every change, so bans survive restarts.
+type BanList struct {
+	mu   sync.RWMutex
+	bans map[netip.Addr]Ban
+	// path is the file the list is persisted to, empty if the list is in-memory only.
+	path string
+	now  func() time.Time
+}
+
+// NewBanList creates an empty in-memory ban list.
+func NewBanList() *BanList {
+	return &BanList{
+		bans: make(map[netip.Addr]Ban),
+		now:  time.Now,
+	}
+}
+
+// NewPersistentBanList creates a ban list that is persisted to the given data directory.
+// Bans of a previous run are loaded, expired bans are dropped. A missing file is not an error.
+func NewPersistentBanList(dataDir string) (*BanList, error) {
+	list := NewBanList()
+	list.path = filepath.Join(dataDir, banListFileName)
+
+	content, err := os.ReadFile(list.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ban list %s: %w", list.path, err)
+	}
+
+	var bans []Ban
+	if err := json.Unmarshal(content, &bans); err != nil {
+		return nil, fmt.Errorf("failed to parse ban list %s: %w", list.path, err)
+	}
+	for _, ban := range bans {
+		if list.isActive(ban) {
+			list.bans[ban.Addr.Unmap()] = ban
+		}
+	}
+
+	logger.Infof("[ban_list] Loaded %d bans from %s", len(list.bans), list.path)
+	return list, nil
+}
+
+// Add bans an address, replacing an existing ban of the address.
+func (l *BanList) Add(ban Ban) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ban.Addr = ban.Addr.Unmap()
+	l.bans[ban.Addr] = ban
+	return l.save()
+}
+
+// Remove lifts the ban of an address. Returns false if the address is not banned.
+func (l *BanList) Remove(addr netip.Addr) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ban, exists := l.bans[addr.Unmap()]
+	if !exists || !l.isActive(ban) {
+		return false, nil
+	}
+	delete(l.bans, ban.Addr)
+	return true, l.save()
+}
+
+// IsBanned returns whether the address is banned.
+func (l *BanList) IsBanned(addr netip.Addr) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ban, exists := l.bans[addr.Unmap()]
+	return exists && l.isActive(ban)
+}
+
+// GetBans returns all bans that have not expired, sorted by address.
+func (l *BanList) GetBans() []Ban {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	bans := make([]Ban, 0, len(l.bans))
+	for _, ban := range l.bans {
+		if l.isActive(ban) {
+			bans = append(bans, ban)
+		}
+	}
+	slices.SortFunc(bans, func(a, b Ban) int {
+		return cmp.Compare(a.Addr.String(), b.Addr.String())
+	})
+	return bans
+}
+
+func (l *BanList) isActive(ban Ban) bool {
+	return ban.ExpiresAt > l.now().Unix()
+}
+
+// save writes the bans that have not expired to the ban list file and drops the expired ones.
+// Does nothing for an in-memory list. mu must be held.
+func (l *BanList) save() error {
+	bans := make([]Ban, 0, len(l.bans))
+	for addr, ban := range l.bans {
+		if !l.isActive(ban) {
+			delete(l.bans, addr)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+
+	if l.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(bans, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize ban list: %w", err)
+	}
+	tempPath := l.path + ".tmp"
+	if err := os.WriteFile(tempPath, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write ban list %s: %w", tempPath, err)
+	}
+	if err := os.Rename(tempPath, l.path); err != nil {
+		return fmt.Errorf("failed to replace ban list %s: %w", l.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package misbehavior scores the offences of peers and bans the addresses of misbehaving peers.
+package misbehavior
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/disconnect"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// MisbehaviorConfig contains the settings of the misbehavior scoring.
+type MisbehaviorConfig struct {
+	// BanThreshold is the misbehavior score at which a peer is disconnected and its addresses are banned.
+	BanThreshold int
+	// BanDuration is the time the addresses of a misbehaving peer stay banned.
+	BanDuration time.Duration
+}
+
+// DefaultMisbehaviorConfig returns the config with the default threshold and duration.
+func DefaultMisbehaviorConfig() MisbehaviorConfig {
+	return MisbehaviorConfig{
+		BanThreshold: common.DefaultBanThreshold,
+		BanDuration:  common.DefaultBanDuration,
+	}
+}
+
+// MisbehaviorService scores the offences of peers and manages the ban list.
+type MisbehaviorService interface {
+	// ReportMisbehavior increases the misbehavior score of a peer by the score of its offence (see common.MisbehaviorInvalidBlock etc.).
+	// Once the score reaches the ban threshold, the addresses of the peer are banned and the peer is disconnected.
+	// Offences of unknown peers and of the node itself (empty peerID) are ignored.
+	ReportMisbehavior(peerID common.PeerId, score int, reason string)
+
+	// BanAddress bans an address for the given duration and disconnects all peers known by the address.
+	BanAddress(addr netip.Addr, duration time.Duration, reason string) error
+
+	// UnbanAddress lifts the ban of an address. Returns an error if the address is not banned.
+	UnbanAddress(addr netip.Addr) error
+
+	// GetBans returns all bans that have not expired.
+	GetBans() []Ban
+}
+
+// peerAddressResolver resolves between peers and their IP addresses.
+// It is implemented by the infrastructure layer's NetworkInfoRegistry.
+type peerAddressResolver interface {
+	// GetPeerAddrs returns the IP addresses the peer is known by.
+	GetPeerAddrs(id common.PeerId) []netip.Addr
+	// GetPeersByAddr returns the peers known by the IP address.
+	GetPeersByAddr(addr netip.Addr) []common.PeerId
+}
+
+// peerRetriever is an interface for retrieving peers.
+// It is implemented by peer.PeerStore.
+type peerRetriever interface {
+	GetPeer(id common.PeerId) (*common.Peer, bool)
+}
+
+// misbehaviorService implements MisbehaviorService with the actual domain logic.
+type misbehaviorService struct {
+	peerRetriever     peerRetriever
+	addressResolver   peerAddressResolver
+	disconnectService disconnect.DisconnectService
+	banList           *BanList
+	config            MisbehaviorConfig
+}
+
+func NewMisbehaviorService(
+	peerRetriever peerRetriever,
+	addressResolver peerAddressResolver,
+	disconnectService disconnect.DisconnectService,
+	banList *BanList,
+	config MisbehaviorConfig,
+) MisbehaviorService {
+	return &misbehaviorService{
+		peerRetriever:     peerRetriever,
+		addressResolver:   addressResolver,
+		disconnectService: disconnectService,
+		banList:           banList,
+		config:            config,
+	}
+}
+
+func (s *misbehaviorService) ReportMisbehavior(peerID common.PeerId, score int, reason string) {
+	if peerID == "" {
+		return
+	}
+
+	peer, ok := s.peerRetriever.GetPeer(peerID)
+	if !ok {
+		logger.Debugf("[misbehavior] Ignoring misbehavior of unknown peer %s: %s", peerID, reason)
+		return
+	}
+
+	peer.Lock()
+	previousScore := peer.MisbehaviorScore
+	peer.MisbehaviorScore += score
+	currentScore := peer.MisbehaviorScore
+	peer.Unlock()
+
+	logger.Infof("[misbehavior] Peer %s misbehaved (%s), score %d -> %d", peerID, reason, previousScore, currentScore)
+
+	// Only the offence crossing the threshold bans the peer, later offences arrive while it is disconnected
+	if previousScore >= s.config.BanThreshold || currentScore < s.config.BanThreshold {
+		return
+	}
+
+	for _, addr := range s.addressResolver.GetPeerAddrs(peerID) {
+		_ = s.ban(addr, s.config.BanDuration, fmt.Sprintf("misbehavior score %d: %s", currentScore, reason))
+	}
+
+	if err := s.disconnectService.Disconnect(peerID); err != nil {
+		logger.Warnf("[misbehavior] Failed to disconnect banned peer %s: %v", peerID, err)
+	}
+}
+
+func (s *misbehaviorService) BanAddress(addr netip.Addr, duration time.Duration, reason string) error {
+	if !addr.IsValid() {
+		return fmt.Errorf("invalid address")
+	}
+	if duration <= 0 {
+		return fmt.Errorf("ban duration must be positive, got %v", duration)
+	}
+
+	err := s.ban(addr, duration, reason)
+
+	for _, peerID := range s.addressResolver.GetPeersByAddr(addr) {
+		if err := s.disconnectService.Disconnect(peerID); err != nil {
+			logger.Warnf("[misbehavior] Failed to disconnect banned peer %s: %v", peerID, err)
+		}
+	}
+	return err
+}
+
+func (s *misbehaviorService) UnbanAddress(addr netip.Addr) error {
+	removed, err := s.banList.Remove(addr)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("address %s is not banned", addr)
+	}
+
+	logger.Infof("[misbehavior] Lifted ban of %s", addr)
+	return nil
+}
+
+func (s *misbehaviorService) GetBans() []Ban {
+	return s.banList.GetBans()
+}
+
+// ban adds the address to the ban list. A failure to persist the list is logged, the ban applies anyway.
+func (s *misbehaviorService) ban(addr netip.Addr, duration time.Duration, reason string) error {
+	now := time.Now()
+	err := s.banList.Add(Ban{
+		Addr:      addr,
+		Reason:    reason,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(duration).Unix(),
+	})
+	if err != nil {
+		logger.Warnf("[misbehavior] Failed to persist ban of %s: %v", addr, err)
+		return err
+	}
+
+	logger.Infof("[misbehavior] Banned %s for %v: %s", addr, duration, reason)
+	return nil
+}
@@ -0,0 +1,153 @@
+package misbehavior
+
+import (
+	"net/netip"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//
+// Mocks
+//
+
+type mockPeerRetriever struct {
+	peers map[common.PeerId]*common.Peer
+}
+
+func (m *mockPeerRetriever) GetPeer(id common.PeerId) (*common.Peer, bool) {
+	p, exists := m.peers[id]
+	return p, exists
+}
+
+type mockAddressResolver struct {
+	addrs map[common.PeerId][]netip.Addr
+}
+
+func (m *mockAddressResolver) GetPeerAddrs(id common.PeerId) []netip.Addr {
+	return m.addrs[id]
+}
+
+func (m *mockAddressResolver) GetPeersByAddr(addr netip.Addr) []common.PeerId {
+	peerIDs := make([]common.PeerId, 0)
+	for id, addrs := range m.addrs {
+		for _, a := range addrs {
+			if a == addr {
+				peerIDs = append(peerIDs, id)
+			}
+		}
+	}
+	return peerIDs
+}
+
+type mockDisconnectService struct {
+	disconnectedPeers []common.PeerId
+}
+
+func (m *mockDisconnectService) Disconnect(id common.PeerId) error {
+	m.disconnectedPeers = append(m.disconnectedPeers, id)
+	return nil
+}
+
+var peerAddr = netip.MustParseAddr("10.0.0.1")
+
+func newTestService(banList *BanList) (*misbehaviorService, *mockDisconnectService) {
+	peerID := common.PeerId("peer-1")
+	disconnectService := &mockDisconnectService{}
+	service := NewMisbehaviorService(
+		&mockPeerRetriever{peers: map[common.PeerId]*common.Peer{peerID: common.NewPeer(peerID)}},
+		&mockAddressResolver{addrs: map[common.PeerId][]netip.Addr{peerID: {peerAddr}}},
+		disconnectService,
+		banList,
+		DefaultMisbehaviorConfig(),
+	)
+	return service.(*misbehaviorService), disconnectService
+}
+
+//
+// Tests
+//
+
+func TestReportMisbehavior_BansPeerAtThreshold(t *testing.T) {
+	service, disconnectService := newTestService(NewBanList())
+
+	for range 9 {
+		service.ReportMisbehavior("peer-1", common.MisbehaviorInvalidTransaction, "invalid transaction")
+	}
+	assert.False(t, service.banList.IsBanned(peerAddr), "peer below the threshold should not be banned")
+	assert.Empty(t, disconnectService.disconnectedPeers)
+
+	service.ReportMisbehavior("peer-1", common.MisbehaviorInvalidTransaction, "invalid transaction")
+
+	assert.True(t, service.banList.IsBanned(peerAddr), "peer reaching the threshold should be banned")
+	assert.Equal(t, []common.PeerId{"peer-1"}, disconnectService.disconnectedPeers)
+
+	// Later offences do not disconnect the peer again
+	service.ReportMisbehavior("peer-1", common.MisbehaviorInvalidBlock, "invalid block")
+	assert.Len(t, disconnectService.disconnectedPeers, 1)
+}
+
+func TestReportMisbehavior_IgnoresUnknownPeersAndSelf(t *testing.T) {
+	service, disconnectService := newTestService(NewBanList())
+
+	service.ReportMisbehavior("", common.MisbehaviorInvalidBlock, "invalid block")
+	service.ReportMisbehavior("unknown", common.MisbehaviorInvalidBlock, "invalid block")
+
+	assert.Empty(t, service.GetBans())
+	assert.Empty(t, disconnectService.disconnectedPeers)
+}
+
+func TestBanAddress_DisconnectsPeersAndUnban(t *testing.T) {
+	service, disconnectService := newTestService(NewBanList())
+
+	require.NoError(t, service.BanAddress(peerAddr, time.Hour, "manual"))
+	assert.Equal(t, []common.PeerId{"peer-1"}, disconnectService.disconnectedPeers)
+
+	bans := service.GetBans()
+	require.Len(t, bans, 1)
+	assert.Equal(t, peerAddr, bans[0].Addr)
+	assert.Equal(t, "manual", bans[0].Reason)
+
+	require.NoError(t, service.UnbanAddress(peerAddr))
+	assert.False(t, service.banList.IsBanned(peerAddr))
+	assert.Error(t, service.UnbanAddress(peerAddr), "unbanning an address that is not banned should fail")
+
+	assert.Error(t, service.BanAddress(peerAddr, 0, "manual"), "a ban needs a positive duration")
+}
+
+func TestBanList_Expiry(t *testing.T) {
+	list := NewBanList()
+	now := time.Now()
+	list.now = func() time.Time { return now }
+
+	require.NoError(t, list.Add(Ban{Addr: peerAddr, ExpiresAt: now.Add(time.Minute).Unix()}))
+	assert.True(t, list.IsBanned(peerAddr))
+	assert.True(t, list.IsBanned(netip.MustParseAddr("::ffff:10.0.0.1")), "IPv4-mapped addresses should match")
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, list.IsBanned(peerAddr), "expired ban should not apply")
+	assert.Empty(t, list.GetBans())
+}
+
+func TestBanList_Persistence(t *testing.T) {
+	dataDir := t.TempDir()
+
+	list, err := NewPersistentBanList(dataDir)
+	require.NoError(t, err)
+	require.NoError(t, list.Add(Ban{Addr: peerAddr, Reason: "invalid block", ExpiresAt: time.Now().Add(time.Hour).Unix()}))
+	require.NoError(t, list.Add(Ban{Addr: netip.MustParseAddr("10.0.0.2"), ExpiresAt: time.Now().Add(time.Hour).Unix()}))
+	removed, err := list.Remove(netip.MustParseAddr("10.0.0.2"))
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	reopened, err := NewPersistentBanList(dataDir)
+	require.NoError(t, err)
+
+	bans := reopened.GetBans()
+	require.Len(t, bans, 1)
+	assert.Equal(t, peerAddr, bans[0].Addr)
+	assert.Equal(t, "invalid block", bans[0].Reason)
+}
@@ -53,6 +53,12 @@ func (s *DiscoveryService) HandleAddr(peerID common.PeerId, addrs []PeerAddress)
 			addr.PeerId, time.Unix(addr.LastActiveTimestamp, 0))
 	}
 
+	announced := make([]common.PeerId, 0, len(addrs))
+	for _, addr := range addrs {
+		announced = append(announced, addr.PeerId)
+	}
+	s.addressCollector.AddFromPeer(peerID, announced)
+
 	// Forward addresses to random neighbors
 	// Uses addrs instead of filteredAddrs by design
 	s.forwardAddrs(addrs, peerID)
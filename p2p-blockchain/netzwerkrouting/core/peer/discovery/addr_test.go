@@ -20,7 +20,7 @@ func TestHandleAddr_UpdatesPeerLastSeenTimestamp(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create and add a peer with an old timestamp
 	oldTimestamp := time.Now().Add(-24 * time.Hour).Unix()
@@ -67,7 +67,7 @@ func TestHandleAddr_DoesNotUpdateOlderTimestamp(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create and add a peer with a recent timestamp
 	recentTimestamp := time.Now().Unix()
@@ -109,7 +109,7 @@ func TestHandleAddr_HandlesMultipleAddresses(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create and add multiple peers
 	now := time.Now().Unix()
@@ -180,7 +180,7 @@ func TestHandleAddr_HandlesEmptyAddressList(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Add a peer
 	now := time.Now().Unix()
@@ -217,7 +217,7 @@ func TestHandleAddr_ThreadSafe(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create a peer
 	now := time.Now().Unix()
@@ -276,7 +276,7 @@ func TestHandleAddr_PanicsIfPeerNotRegistered(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Add sender peer so the connection check passes
 	senderPeerID := common.PeerId("sender-peer")
@@ -312,7 +312,7 @@ func TestHandleAddr_UpdatesCorrectPeer(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create and add multiple peers with different timestamps
 	now := time.Now().Unix()
@@ -381,7 +381,7 @@ func TestHandleAddr_SameTimestamp(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create and add a peer
 	timestamp := time.Now().Unix()
@@ -426,7 +426,7 @@ func TestForwardAddrs_DoesNotForwardToSender(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create and add connected peers including the sender
 	connectedPeers := []common.PeerId{"sender-peer", "peer-1", "peer-2", "peer-3"}
@@ -508,7 +508,7 @@ func TestForwardAddrs_DoesNotForwardToPeersThatAlreadyReceived(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create and add connected peers
 	connectedPeers := []common.PeerId{"sender-peer", "peer-1", "peer-2", "peer-3"}
@@ -592,7 +592,7 @@ func TestForwardAddrs_ForwardsToRandomPeers(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create and add connected peers
 	connectedPeers := []common.PeerId{"sender-peer", "peer-1", "peer-2", "peer-3", "peer-4"}
@@ -685,7 +685,7 @@ func TestForwardAddrs_WithLimitedPeers(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 
 	errorMsgSender := &mockErrorMsgSender{}
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 	connectedPeers := []common.PeerId{"sender-peer", "peer-1"}
 	now := time.Now().Unix()
 
@@ -758,7 +758,7 @@ func TestForwardAddrs_WithNoEligiblePeers(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create only the sender peer
 	now := time.Now().Unix()
@@ -822,7 +822,7 @@ func TestForwardAddrs_IndependentPeerSelection(t *testing.T) {
 	getAddrSender := newMockGetAddrMsgSender()
 	errorMsgSender := &mockErrorMsgSender{}
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, newMockAddressCollector())
 
 	// Create multiple connected peers
 	connectedPeers := []common.PeerId{"sender-peer", "peer-1", "peer-2", "peer-3", "peer-4"}
@@ -896,3 +896,25 @@ func TestForwardAddrs_IndependentPeerSelection(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleAddr_CollectsAnnouncedAddresses(t *testing.T) {
+	peerStore := newMockDiscoveryPeerRetriever()
+	addrSender := newMockAddrMsgSender()
+	getAddrSender := newMockGetAddrMsgSender()
+	errorMsgSender := &mockErrorMsgSender{}
+	addressCollector := newMockAddressCollector()
+
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, errorMsgSender, addressCollector)
+
+	senderPeerID := common.PeerId("sender-peer")
+	peerStore.AddPeerById(senderPeerID, &common.Peer{State: common.StateConnected})
+	peerStore.AddPeerById("peer-1", &common.Peer{State: common.StateNew, AddrsSentTo: mapset.NewSet[common.PeerId]()})
+	peerStore.AddPeerById("peer-2", &common.Peer{State: common.StateNew, AddrsSentTo: mapset.NewSet[common.PeerId]()})
+
+	service.HandleAddr(senderPeerID, []PeerAddress{{PeerId: "peer-1"}, {PeerId: "peer-2"}})
+
+	collected := addressCollector.getAddressesFrom(senderPeerID)
+	if len(collected) != 2 || collected[0] != "peer-1" || collected[1] != "peer-2" {
+		t.Errorf("expected the announced peers to be collected with the sender as source, got %v", collected)
+	}
+}
@@ -19,7 +19,7 @@ func TestHandleGetAddr_SendsPeersToRequester(t *testing.T) {
 	addrSender := newMockAddrMsgSender()
 	getAddrSender := newMockGetAddrMsgSender()
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil, nil)
 
 	// Add some test peers
 	peer1 := &common.Peer{
@@ -82,7 +82,7 @@ func TestHandleGetAddr_ExcludesRequesterPeer(t *testing.T) {
 	addrSender := newMockAddrMsgSender()
 	getAddrSender := newMockGetAddrMsgSender()
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil, nil)
 
 	// Add the requesting peer itself to the store
 	requesterPeerID := common.PeerId("requester-peer")
@@ -130,7 +130,7 @@ func TestHandleGetAddr_DoesNotSendWhenNoPeers(t *testing.T) {
 	addrSender := newMockAddrMsgSender()
 	getAddrSender := newMockGetAddrMsgSender()
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil, nil)
 
 	requesterPeerID := common.PeerId("requester-peer")
 	requesterPeer := &common.Peer{
@@ -156,7 +156,7 @@ func TestHandleGetAddr_IncludesLastActiveTimestamp(t *testing.T) {
 	addrSender := newMockAddrMsgSender()
 	getAddrSender := newMockGetAddrMsgSender()
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil, nil)
 
 	// Add a peer with LastSeen set
 	testPeer := &common.Peer{
@@ -200,7 +200,7 @@ func TestHandleGetAddr_SendsAsynchronously(t *testing.T) {
 	addrSender := newMockAddrMsgSender()
 	getAddrSender := newMockGetAddrMsgSender()
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil, nil)
 
 	// Add a peer
 	testPeer := &common.Peer{
@@ -238,7 +238,7 @@ func TestSendGetAddr_ForwardsToSender(t *testing.T) {
 	addrSender := newMockAddrMsgSender()
 	getAddrSender := newMockGetAddrMsgSender()
 
-	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil)
+	service := NewDiscoveryService(nil, addrSender, peerStore, getAddrSender, nil, nil)
 
 	targetPeerID := common.PeerId("target-peer")
 
@@ -182,3 +182,28 @@ func (m *mockErrorMsgSender) SendReject(peerId common.PeerId, errorType int32, r
 	m.lastMessageType = rejectedMessageType
 	m.lastData = data
 }
+
+type mockAddressCollector struct {
+	mu        sync.Mutex
+	fromPeers map[common.PeerId][]common.PeerId
+}
+
+func newMockAddressCollector() *mockAddressCollector {
+	return &mockAddressCollector{
+		fromPeers: make(map[common.PeerId][]common.PeerId),
+	}
+}
+
+func (m *mockAddressCollector) AddFromPeer(source common.PeerId, peers []common.PeerId) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fromPeers[source] = append(m.fromPeers[source], peers...)
+}
+
+func (m *mockAddressCollector) AddFromRegistry([]common.PeerId) {}
+
+func (m *mockAddressCollector) getAddressesFrom(source common.PeerId) []common.PeerId {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fromPeers[source]
+}
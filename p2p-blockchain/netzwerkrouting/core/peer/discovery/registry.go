@@ -37,4 +37,6 @@ func (s *DiscoveryService) GetPeers() {
 		peer.Unlock()
 		logger.Tracef("[peer-discovery] Discovered peer from registry: %v", peerID)
 	}
+
+	s.addressCollector.AddFromRegistry(peers)
 }
@@ -9,6 +9,13 @@ type errorMsgSender interface {
 	SendReject(peerId common.PeerId, errorType int32, rejectedMessageType string, data []byte)
 }
 
+// addressCollector collects the addresses of discovered peers for the selection of outbound peers.
+// It is implemented by addrman.AddrManager.
+type addressCollector interface {
+	AddFromPeer(source common.PeerId, peers []common.PeerId)
+	AddFromRegistry(peers []common.PeerId)
+}
+
 // DiscoveryService provides peer discovery functionality.
 // This includes (1) querying a registry for peers and (2) asking neighbors for their known peers.
 type DiscoveryService struct {
@@ -17,6 +24,7 @@ type DiscoveryService struct {
 	peerRetriever    peerRetriever
 	getAddrMsgSender GetAddrMsgSender
 	errorMsgSender   errorMsgSender
+	addressCollector addressCollector
 }
 
 // NewDiscoveryService creates a new DiscoveryService.
@@ -26,6 +34,7 @@ func NewDiscoveryService(
 	peerRetriever peerRetriever,
 	getAddrMsgSender GetAddrMsgSender,
 	errorMsgSender errorMsgSender,
+	addressCollector addressCollector,
 ) *DiscoveryService {
 	return &DiscoveryService{
 		querier:          querier,
@@ -33,5 +42,6 @@ func NewDiscoveryService(
 		peerRetriever:    peerRetriever,
 		getAddrMsgSender: getAddrMsgSender,
 		errorMsgSender:   errorMsgSender,
+		addressCollector: addressCollector,
 	}
 }
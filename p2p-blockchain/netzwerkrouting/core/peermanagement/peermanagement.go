@@ -6,7 +6,7 @@
 // General Operation Flow:
 //  1. Periodic Check: Every `checkInterval`, the service checks the current peer count
 //  2. Threshold Evaluation: If count < `minPeers`, new connections are needed
-//  3. Connection Initiation: Attempts to establish connections up to `maxPeersPerAttempt` using `GetUnconnectedPeers()`
+//  3. Connection Initiation: Attempts to establish connections up to `maxPeersPerAttempt` to peers chosen by the address manager (addrman)
 //  4. Handshake: For each peer, initiates the handshake process via HandshakeService
 //
 // Note: Peer discovery is handled separately:
@@ -17,7 +17,6 @@
 package peermanagement
 
 import (
-	"math/rand"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"time"
 
@@ -56,9 +55,11 @@ type handshakeInitiator interface {
 	InitiateHandshake(peerID common.PeerId) error
 }
 
-type knownPeerRetriever interface {
-	// GetUnconnectedPeers retrieves peer IDs that are known but not currently connected.
-	GetUnconnectedPeers() []common.PeerId
+// peerSelector selects the peers to connect to.
+// It is implemented by addrman.AddrManager.
+type peerSelector interface {
+	// SelectPeers selects up to count unconnected peers, preferring network groups none of the connected peers belongs to.
+	SelectPeers(count int, connected []common.PeerId) []common.PeerId
 }
 
 // PeerManagementService manages automatic peer connections.
@@ -67,7 +68,7 @@ type PeerManagementService struct {
 	peerDiscoverer     peerDiscoverer
 	peerCreator        peerCreator
 	handshakeInitiator handshakeInitiator
-	knownPeerRetriever knownPeerRetriever
+	peerSelector       peerSelector
 
 	minPeers           int
 	maxPeersPerAttempt int
@@ -83,14 +84,14 @@ func NewPeerManagementService(
 	peerDiscoverer peerDiscoverer,
 	peerCreator peerCreator,
 	handshakeInitiator handshakeInitiator,
-	knownPeerRetriever knownPeerRetriever,
+	peerSelector peerSelector,
 ) *PeerManagementService {
 	return &PeerManagementService{
 		peerCounter:        peerCounter,
 		peerDiscoverer:     peerDiscoverer,
 		peerCreator:        peerCreator,
 		handshakeInitiator: handshakeInitiator,
-		knownPeerRetriever: knownPeerRetriever,
+		peerSelector:       peerSelector,
 		minPeers:           DefaultMinPeers,
 		maxPeersPerAttempt: DefaultMaxPeersPerAttempt,
 		checkInterval:      DefaultPeerCheckInterval,
@@ -152,21 +153,13 @@ func (s *PeerManagementService) checkAndMaintainPeers() {
 
 // establishNewPeers attempts to establish connections to the specified number of peers.
 func (s *PeerManagementService) establishNewPeers(count int) {
-	potentialPeers := s.knownPeerRetriever.GetUnconnectedPeers()
+	potentialPeers := s.peerSelector.SelectPeers(count, s.peerCounter.GetAllConnectedPeers())
 
 	if len(potentialPeers) == 0 {
 		logger.Warnf("[peer-count-checker] No unconnected peers available")
 		return
 	}
 
-	// Limit to the number of peers we need
-	if len(potentialPeers) > count {
-		rand.Shuffle(len(potentialPeers), func(i, j int) {
-			potentialPeers[i], potentialPeers[j] = potentialPeers[j], potentialPeers[i]
-		})
-		potentialPeers = potentialPeers[:count]
-	}
-
 	// Attempt to establish connections
 	handshakesSent := 0
 	for _, peerID := range potentialPeers {
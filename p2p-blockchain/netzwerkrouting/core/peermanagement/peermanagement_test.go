@@ -60,13 +60,14 @@ func (m *mockHandshakeInitiator) InitiateHandshake(peerID common.PeerId) error {
 	return nil
 }
 
-// mockKnownPeerRetriever is a mock implementation of knownPeerRetriever for testing.
-type mockKnownPeerRetriever struct {
+// mockPeerSelector is a mock implementation of peerSelector for testing.
+// It selects the first unconnected peers in order.
+type mockPeerSelector struct {
 	peerIDs []common.PeerId
 }
 
-func (m *mockKnownPeerRetriever) GetUnconnectedPeers() []common.PeerId {
-	return m.peerIDs
+func (m *mockPeerSelector) SelectPeers(count int, _ []common.PeerId) []common.PeerId {
+	return m.peerIDs[:min(count, len(m.peerIDs))]
 }
 
 //
@@ -78,9 +79,9 @@ func TestNewPeerManagementService(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{}
+	peerSelector := &mockPeerSelector{}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 
 	require.NotNil(t, service, "Service should be created")
 	assert.NotNil(t, service.stopChan, "Stop channel should be initialized")
@@ -91,7 +92,7 @@ func TestNewPeerManagementService(t *testing.T) {
 	assert.Equal(t, peerDiscoverer, service.peerDiscoverer, "Peer discoverer should be set")
 	assert.Equal(t, peerCreator, service.peerCreator, "Peer creator should be set")
 	assert.Equal(t, handshakeInitiator, service.handshakeInitiator, "Handshake initiator should be set")
-	assert.Equal(t, knownPeerRetriever, service.knownPeerRetriever, "Known peer retriever should be set")
+	assert.Equal(t, peerSelector, service.peerSelector, "Peer selector should be set")
 	assert.Nil(t, service.ticker, "Ticker should not be initialized until Start is called")
 }
 
@@ -100,9 +101,9 @@ func TestSetMinPeers(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{}
+	peerSelector := &mockPeerSelector{}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 
 	service.minPeers = 12
 	assert.Equal(t, 12, service.minPeers, "minPeers should be updated")
@@ -113,9 +114,9 @@ func TestSetMaxPeersPerAttempt(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{}
+	peerSelector := &mockPeerSelector{}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 
 	service.maxPeersPerAttempt = 5
 	assert.Equal(t, 5, service.maxPeersPerAttempt, "maxPeersPerAttempt should be updated")
@@ -126,9 +127,9 @@ func TestSetCheckInterval(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{}
+	peerSelector := &mockPeerSelector{}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 
 	service.checkInterval = 5 * time.Minute
 	assert.Equal(t, 5*time.Minute, service.checkInterval, "checkInterval should be updated")
@@ -141,9 +142,9 @@ func TestCheckAndMaintainPeers_SufficientPeers(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{}
+	peerSelector := &mockPeerSelector{}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 	service.minPeers = 8
 
 	service.checkAndMaintainPeers()
@@ -159,11 +160,11 @@ func TestCheckAndMaintainPeers_NeedsMorePeers(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{
+	peerSelector := &mockPeerSelector{
 		peerIDs: []common.PeerId{"registry-peer-1", "registry-peer-2", "registry-peer-3"},
 	}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 	service.minPeers = 8
 
 	service.checkAndMaintainPeers()
@@ -179,11 +180,11 @@ func TestCheckAndMaintainPeers_LimitedByMaxPerAttempt(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{
+	peerSelector := &mockPeerSelector{
 		peerIDs: []common.PeerId{"registry-peer-1", "registry-peer-2", "registry-peer-3", "registry-peer-4", "registry-peer-5"},
 	}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 	service.minPeers = 8
 	service.maxPeersPerAttempt = 2 // Limit to 2 connections per attempt
 
@@ -200,11 +201,11 @@ func TestCheckAndMaintainPeers_NoPeersAvailable(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{
+	peerSelector := &mockPeerSelector{
 		peerIDs: []common.PeerId{},
 	}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 	service.minPeers = 8
 
 	service.checkAndMaintainPeers()
@@ -218,11 +219,11 @@ func TestEstablishNewPeers_SuccessfulConnections(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{
+	peerSelector := &mockPeerSelector{
 		peerIDs: []common.PeerId{"registry-peer-1", "registry-peer-2", "registry-peer-3"},
 	}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 
 	service.establishNewPeers(3)
 
@@ -241,11 +242,11 @@ func TestEstablishNewPeers_WithConnectionErrors(t *testing.T) {
 			"registry-peer-2": true, // Second peer fails
 		},
 	}
-	knownPeerRetriever := &mockKnownPeerRetriever{
+	peerSelector := &mockPeerSelector{
 		peerIDs: []common.PeerId{"registry-peer-1", "registry-peer-2", "registry-peer-3"},
 	}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 
 	service.establishNewPeers(3)
 
@@ -260,9 +261,9 @@ func TestStartAndStop(t *testing.T) {
 	peerDiscoverer := &mockPeerDiscoverer{}
 	peerCreator := &mockPeerCreator{}
 	handshakeInitiator := &mockHandshakeInitiator{}
-	knownPeerRetriever := &mockKnownPeerRetriever{}
+	peerSelector := &mockPeerSelector{}
 
-	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, knownPeerRetriever)
+	service := NewPeerManagementService(peerCounter, peerDiscoverer, peerCreator, handshakeInitiator, peerSelector)
 
 	// Start the service
 	service.Start()
@@ -2,6 +2,7 @@ package adapter
 
 import (
 	"fmt"
+	"math"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/inv"
@@ -207,8 +208,9 @@ func toTx(tx *pb.Transaction) (transaction.Transaction, error) {
 	}
 
 	return transaction.Transaction{
-		Inputs:  inputs,
-		Outputs: outputs,
+		Inputs:   inputs,
+		Outputs:  outputs,
+		LockTime: tx.LockTime,
 	}, nil
 }
 
@@ -240,11 +242,19 @@ func toInput(in *pb.TxInput) (transaction.Input, error) {
 	var pubKey transaction.PubKey
 	copy(pubKey[:], in.PublicKey)
 
+	redeemScript, err := toMultisigScript(in.RedeemScript)
+	if err != nil {
+		return transaction.Input{}, err
+	}
+
 	return transaction.Input{
-		PrevTxID:    prevTxId,
-		OutputIndex: in.OutputIndex,
-		Signature:   in.Signature,
-		PubKey:      pubKey,
+		PrevTxID:     prevTxId,
+		OutputIndex:  in.OutputIndex,
+		Signature:    in.Signature,
+		PubKey:       pubKey,
+		Sequence:     in.Sequence,
+		Signatures:   in.Signatures,
+		RedeemScript: redeemScript,
 	}, nil
 }
 
@@ -272,8 +282,51 @@ func toOutput(outPb *pb.TxOutput) (transaction.Output, error) {
 	var pubKeyHash transaction.PubKeyHash
 	copy(pubKeyHash[:], outPb.PublicKeyHash)
 
+	if outPb.Type < 0 || outPb.Type > math.MaxUint8 {
+		return transaction.Output{}, fmt.Errorf("invalid output type: %d", outPb.Type)
+	}
+
+	multisig, err := toMultisigScript(outPb.Multisig)
+	if err != nil {
+		return transaction.Output{}, err
+	}
+
+	if len(outPb.Data) > math.MaxUint8 {
+		return transaction.Output{}, fmt.Errorf("output data too large")
+	}
+	var data []byte
+	if len(outPb.Data) > 0 {
+		data = outPb.Data
+	}
+
 	return transaction.Output{
 		Value:      outPb.Value,
+		Type:       transaction.OutputType(outPb.Type),
 		PubKeyHash: pubKeyHash,
+		Multisig:   multisig,
+		Data:       data,
+	}, nil
+}
+
+// toMultisigScript converts a multisig script. A nil script is converted to the empty script.
+// Only the encoding is checked here, whether the script is valid is part of the transaction validation.
+func toMultisigScript(scriptPb *pb.MultisigScript) (transaction.MultisigScript, error) {
+	if scriptPb == nil {
+		return transaction.MultisigScript{}, nil
+	}
+	if scriptPb.Required > math.MaxUint8 || len(scriptPb.PublicKeys) > math.MaxUint8 {
+		return transaction.MultisigScript{}, fmt.Errorf("multisig script too large")
+	}
+
+	var pubKeys []transaction.PubKey
+	for _, pubKeyBytes := range scriptPb.PublicKeys {
+		var pubKey transaction.PubKey
+		copy(pubKey[:], pubKeyBytes)
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	return transaction.MultisigScript{
+		Required: uint8(scriptPb.Required),
+		PubKeys:  pubKeys,
 	}, nil
 }
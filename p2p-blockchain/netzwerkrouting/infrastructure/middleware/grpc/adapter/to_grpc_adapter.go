@@ -135,6 +135,11 @@ func toGrpcTransaction(tx *transaction.Transaction) (*pb.Transaction, error) {
 			OutputIndex: input.OutputIndex,
 			Signature:   input.Signature,
 			PublicKey:   input.PubKey[:],
+			Sequence:    input.Sequence,
+			Signatures:  input.Signatures,
+		}
+		if !input.RedeemScript.IsEmpty() {
+			pbInputs[i].RedeemScript = toGrpcMultisigScript(input.RedeemScript)
 		}
 	}
 
@@ -143,11 +148,31 @@ func toGrpcTransaction(tx *transaction.Transaction) (*pb.Transaction, error) {
 		pbOutputs[i] = &pb.TxOutput{
 			Value:         output.Value,
 			PublicKeyHash: output.PubKeyHash[:],
+			Type:          pb.OutputType(output.Type),
+		}
+		switch output.Type {
+		case transaction.OutputTypeMultisig:
+			pbOutputs[i].Multisig = toGrpcMultisigScript(output.Multisig)
+		case transaction.OutputTypeData:
+			pbOutputs[i].Data = output.Data
 		}
 	}
 
 	return &pb.Transaction{
-		Inputs:  pbInputs,
-		Outputs: pbOutputs,
+		Inputs:   pbInputs,
+		Outputs:  pbOutputs,
+		LockTime: tx.LockTime,
 	}, nil
 }
+
+func toGrpcMultisigScript(script transaction.MultisigScript) *pb.MultisigScript {
+	pubKeys := make([][]byte, len(script.PubKeys))
+	for i, pubKey := range script.PubKeys {
+		pubKeys[i] = pubKey[:]
+	}
+
+	return &pb.MultisigScript{
+		Required:   uint32(script.Required),
+		PublicKeys: pubKeys,
+	}
+}
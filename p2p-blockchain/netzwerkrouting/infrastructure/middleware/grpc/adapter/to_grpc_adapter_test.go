@@ -126,6 +126,7 @@ func TestToGrpcTxMsg(t *testing.T) {
 					OutputIndex: 1,
 					Signature:   []byte{0xaa, 0xbb, 0xcc},
 					PubKey:      pubKey,
+					Sequence:    10,
 				},
 			},
 			Outputs: []transaction.Output{
@@ -134,6 +135,7 @@ func TestToGrpcTxMsg(t *testing.T) {
 					PubKeyHash: pubKeyHash,
 				},
 			},
+			LockTime: 1234,
 		}
 
 		msg, err := ToGrpcTxMsg(tx)
@@ -150,12 +152,65 @@ func TestToGrpcTxMsg(t *testing.T) {
 		assert.Equal(t, uint32(1), pbInput.OutputIndex)
 		assert.Equal(t, []byte{0xaa, 0xbb, 0xcc}, pbInput.Signature)
 		assert.Equal(t, pubKey[:], pbInput.PublicKey)
+		assert.Equal(t, uint32(10), pbInput.Sequence)
+		assert.Equal(t, uint32(1234), pbTx.LockTime)
 
 		pbOutput := pbTx.Outputs[0]
 		assert.Equal(t, uint64(500), pbOutput.Value)
 		assert.Equal(t, pubKeyHash[:], pbOutput.PublicKeyHash)
 	})
 
+	t.Run("Multisig transaction round trip", func(t *testing.T) {
+		script := transaction.MultisigScript{Required: 1, PubKeys: []transaction.PubKey{{2}, {3}}}
+
+		tx := &transaction.Transaction{
+			Inputs: []transaction.Input{
+				{
+					PrevTxID:     transaction.TransactionID{1},
+					Signatures:   [][]byte{{0xaa}, {}},
+					RedeemScript: script,
+				},
+			},
+			Outputs: []transaction.Output{
+				transaction.NewMultisigOutput(500, script),
+				transaction.NewMultisigHashOutput(600, script.Hash()),
+			},
+		}
+
+		msg, err := ToGrpcTxMsg(tx)
+		assert.NoError(t, err)
+		assert.Equal(t, pb.OutputType_MULTISIG, msg.Transaction.Outputs[0].Type)
+		assert.Equal(t, pb.OutputType_MULTISIG_HASH, msg.Transaction.Outputs[1].Type)
+		assert.Equal(t, uint32(1), msg.Transaction.Inputs[0].RedeemScript.Required)
+
+		decoded, err := ToTxFromTxMsg(msg)
+		assert.NoError(t, err)
+		assert.Equal(t, tx.TransactionId(), decoded.TransactionId())
+		assert.Equal(t, script, decoded.Inputs[0].RedeemScript)
+		assert.Equal(t, script, decoded.Outputs[0].Multisig)
+	})
+
+	t.Run("Data transaction round trip", func(t *testing.T) {
+		tx := &transaction.Transaction{
+			Inputs: []transaction.Input{{PrevTxID: transaction.TransactionID{1}, Signature: []byte{0xaa}}},
+			Outputs: []transaction.Output{
+				transaction.NewDataOutput([]byte("document hash")),
+				{Value: 100, PubKeyHash: transaction.PubKeyHash{2}},
+			},
+		}
+
+		msg, err := ToGrpcTxMsg(tx)
+		assert.NoError(t, err)
+		assert.Equal(t, pb.OutputType_DATA, msg.Transaction.Outputs[0].Type)
+		assert.Equal(t, []byte("document hash"), msg.Transaction.Outputs[0].Data)
+
+		decoded, err := ToTxFromTxMsg(msg)
+		assert.NoError(t, err)
+		assert.Equal(t, tx.TransactionId(), decoded.TransactionId())
+		assert.Equal(t, []byte("document hash"), decoded.Outputs[0].Data)
+		assert.Nil(t, decoded.Outputs[1].Data)
+	})
+
 	t.Run("Nil transaction error", func(t *testing.T) {
 		msg, err := ToGrpcTxMsg(nil)
 		assert.Error(t, err)
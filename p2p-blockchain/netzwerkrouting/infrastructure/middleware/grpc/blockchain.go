@@ -8,6 +8,7 @@ import (
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/pb"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/infrastructure/middleware/grpc/adapter"
+	"slices"
 
 	"bjoernblessin.de/go-utils/util/logger"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -140,32 +141,38 @@ func (s *Server) NotifyMempool(peerID common.PeerId) {
 	}
 }
 
-// SendGetData sends a getdata message to the given peer
+// SendGetData sends a getdata message to the given peer.
+// Inventories larger than common.MaxInvVectors are split into several messages.
 func (c *Client) SendGetData(inv []*inv.InvVector, peerId common.PeerId) {
-	pbMsg, err := adapter.ToGrpcGetDataMsg(inv)
-	if err != nil {
-		logger.Warnf("[blockchain_grpc] failed to create GetDataMessage from DTO: %v", err)
-		return
-	}
+	for chunk := range slices.Chunk(inv, common.MaxInvVectors) {
+		pbMsg, err := adapter.ToGrpcGetDataMsg(chunk)
+		if err != nil {
+			logger.Warnf("[blockchain_grpc] failed to create GetDataMessage from DTO: %v", err)
+			return
+		}
 
-	go SendHelper(c, peerId, "GetData", pb.NewBlockchainServiceClient, func(client pb.BlockchainServiceClient) error {
-		_, err := client.GetData(context.Background(), pbMsg)
-		return err
-	})
+		go SendHelper(c, peerId, "GetData", pb.NewBlockchainServiceClient, func(client pb.BlockchainServiceClient) error {
+			_, err := client.GetData(context.Background(), pbMsg)
+			return err
+		})
+	}
 }
 
-// SendInv sends an inv message to the given peer
+// SendInv sends an inv message to the given peer.
+// Inventories larger than common.MaxInvVectors are split into several messages.
 func (c *Client) SendInv(inv []*inv.InvVector, peerId common.PeerId) {
-	pbInvMsg, err := adapter.ToGrpcGetInvMsg(inv)
-	if err != nil {
-		logger.Warnf("[blockchain_grpc] failed to create InvMessage from DTO: %v", err)
-		return
+	for chunk := range slices.Chunk(inv, common.MaxInvVectors) {
+		pbInvMsg, err := adapter.ToGrpcGetInvMsg(chunk)
+		if err != nil {
+			logger.Warnf("[blockchain_grpc] failed to create InvMessage from DTO: %v", err)
+			return
+		}
+
+		go SendHelper(c, peerId, "Inv", pb.NewBlockchainServiceClient, func(client pb.BlockchainServiceClient) error {
+			_, err := client.Inv(context.Background(), pbInvMsg)
+			return err
+		})
 	}
-
-	go SendHelper(c, peerId, "Inv", pb.NewBlockchainServiceClient, func(client pb.BlockchainServiceClient) error {
-		_, err := client.Inv(context.Background(), pbInvMsg)
-		return err
-	})
 }
 
 // SendGetHeaders sends a GetHeaders message to the given peer
@@ -1,12 +1,16 @@
 package grpc
 
 import (
+	"crypto/tls"
+
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/infrastructure/middleware/grpc/networkinfo"
 
 	"bjoernblessin.de/go-utils/util/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 )
 
@@ -22,13 +26,24 @@ type peerDisconnector interface {
 type Client struct {
 	networkInfoRegistry *networkinfo.NetworkInfoRegistry
 	peerDisconnector    peerDisconnector
+	// tlsConfig secures the connections to other nodes, nil for plaintext connections, see nodecert.P2PClientConfig.
+	tlsConfig *tls.Config
 }
 
-func NewClient(networkInfoRegistry *networkinfo.NetworkInfoRegistry, peerDisconnector peerDisconnector) *Client {
+func NewClient(networkInfoRegistry *networkinfo.NetworkInfoRegistry, peerDisconnector peerDisconnector, tlsConfig *tls.Config) *Client {
 	return &Client{
 		networkInfoRegistry: networkInfoRegistry,
 		peerDisconnector:    peerDisconnector,
+		tlsConfig:           tlsConfig,
+	}
+}
+
+// transportCredentials returns the credentials of connections to other nodes.
+func (c *Client) transportCredentials() credentials.TransportCredentials {
+	if c.tlsConfig == nil {
+		return insecure.NewCredentials()
 	}
+	return credentials.NewTLS(c.tlsConfig)
 }
 
 // SendHelper is a generic helper to send gRPC messages to a peer.
@@ -38,7 +53,7 @@ func NewClient(networkInfoRegistry *networkinfo.NetworkInfoRegistry, peerDisconn
 // Usage example:
 //
 //	SendHelper(c, peerID, "Ack", pb.NewConnectionEstablishmentClient, func(client pb.ConnectionEstablishmentClient) error {
-//		_, err := client.Ack(context.Background(), &emptypb.Empty{})
+//		_, err := client.Ack(context.Background(), &pb.AckInfo{Signature: signature})
 //		return err
 //	})
 func SendHelper[T any](c *Client, peerID common.PeerId, method string, newClient func(grpc.ClientConnInterface) T, send func(T) error) {
@@ -1,18 +1,22 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/netip"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/nodecert"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/pb"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/handshake"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/infrastructure/middleware/grpc/mapping"
 
 	"bjoernblessin.de/go-utils/util/logger"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	grpcPeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -30,8 +34,11 @@ func getPeerAddr(ctx context.Context) netip.AddrPort {
 	return addrPort
 }
 
-func createGRPCClient(remoteAddrPort netip.AddrPort) (*grpc.ClientConn, error) {
-	conn, err := grpc.NewClient(remoteAddrPort.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+func (c *Client) createGRPCClient(remoteAddrPort netip.AddrPort) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(remoteAddrPort.String(),
+		grpc.WithTransportCredentials(c.transportCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(common.MaxMessageSize), grpc.MaxCallRecvMsgSize(common.MaxMessageSize)),
+	)
 	if err != nil {
 		logger.Warnf("[handshake_grpc] failed to connect to %s: %v", remoteAddrPort.String(), err)
 		return nil, err
@@ -39,6 +46,31 @@ func createGRPCClient(remoteAddrPort netip.AddrPort) (*grpc.ClientConn, error) {
 	return conn, nil
 }
 
+// verifyNodeIdentity checks that the node public key of a VersionInfo is the key of the certificate the peer presented on its connection.
+// It returns the verified key, or nil if the nodes don't use TLS and there is no certificate to verify the key against.
+func (s *Server) verifyNodeIdentity(ctx context.Context, claimedKey []byte) ([]byte, error) {
+	if s.tlsConfig == nil {
+		return nil, nil
+	}
+
+	p, ok := grpcPeer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unknown connection")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "connection is not secured by TLS")
+	}
+	certKey, ok := nodecert.PeerPublicKey(tlsInfo.State)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "no client certificate")
+	}
+	if !bytes.Equal(certKey, claimedKey) {
+		return nil, status.Error(codes.Unauthenticated, "node public key does not match the client certificate")
+	}
+	return certKey, nil
+}
+
 // isPeerInHolddown checks if a peer is in holddown state.
 // Returns true if the peer is in holddown and should reject the connection.
 func (s *Server) isPeerInHolddown(peerID common.PeerId) bool {
@@ -58,6 +90,12 @@ func (s *Server) Version(ctx context.Context, req *pb.VersionInfo) (*emptypb.Emp
 		return &emptypb.Empty{}, nil
 	}
 
+	nodePublicKey, err := s.verifyNodeIdentity(ctx, req.NodePublicKey)
+	if err != nil {
+		logger.Infof("[handshake_grpc] Rejecting Version from %s: %v", inboundAddr, err)
+		return &emptypb.Empty{}, err
+	}
+
 	peerID := s.networkInfoRegistry.GetOrRegisterPeer(inboundAddr, addrPort)
 
 	// Check if peer is in holddown state - reject the connection attempt
@@ -68,6 +106,9 @@ func (s *Server) Version(ctx context.Context, req *pb.VersionInfo) (*emptypb.Emp
 
 	s.networkInfoRegistry.AddInboundAddress(peerID, inboundAddr)
 	s.networkInfoRegistry.SetListeningEndpoint(peerID, addrPort)
+	if nodePublicKey != nil {
+		s.networkInfoRegistry.SetNodePublicKey(peerID, nodePublicKey)
+	}
 
 	s.handshakeMsgHandler.HandleVersion(peerID, info)
 	return &emptypb.Empty{}, nil
@@ -80,6 +121,12 @@ func (s *Server) Verack(ctx context.Context, req *pb.VersionInfo) (*emptypb.Empt
 		return &emptypb.Empty{}, nil
 	}
 
+	nodePublicKey, err := s.verifyNodeIdentity(ctx, req.NodePublicKey)
+	if err != nil {
+		logger.Infof("[handshake_grpc] Rejecting Verack from %s: %v", inboundAddr, err)
+		return &emptypb.Empty{}, err
+	}
+
 	peerID := s.networkInfoRegistry.GetOrRegisterPeer(inboundAddr, addrPort)
 
 	// Check if peer is in holddown state - reject the connection attempt
@@ -90,15 +137,18 @@ func (s *Server) Verack(ctx context.Context, req *pb.VersionInfo) (*emptypb.Empt
 
 	s.networkInfoRegistry.AddInboundAddress(peerID, inboundAddr)
 	s.networkInfoRegistry.SetListeningEndpoint(peerID, addrPort)
+	if nodePublicKey != nil {
+		s.networkInfoRegistry.SetNodePublicKey(peerID, nodePublicKey)
+	}
 
 	s.handshakeMsgHandler.HandleVerack(peerID, info)
 	return &emptypb.Empty{}, nil
 }
 
-func (s *Server) Ack(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+func (s *Server) Ack(ctx context.Context, req *pb.AckInfo) (*emptypb.Empty, error) {
 	peerId := s.GetPeerId(ctx)
 
-	s.handshakeMsgHandler.HandleAck(peerId)
+	s.handshakeMsgHandler.HandleAck(peerId, req.GetSignature())
 	return &emptypb.Empty{}, nil
 }
 
@@ -109,7 +159,13 @@ func (c *Client) SendVersion(peerID common.PeerId, localInfo handshake.VersionIn
 		return
 	}
 
-	conn, err := createGRPCClient(remoteAddrPort)
+	if c.networkInfoRegistry.IsBanned(remoteAddrPort) {
+		logger.Infof("[handshake_grpc] not sending Version to %s: address is banned", remoteAddrPort.String())
+		_ = c.peerDisconnector.Disconnect(peerID)
+		return
+	}
+
+	conn, err := c.createGRPCClient(remoteAddrPort)
 	if err != nil {
 		logger.Warnf("[handshake_grpc] failed to create gRPC client for %s: %v", remoteAddrPort.String(), err)
 		return
@@ -136,7 +192,13 @@ func (c *Client) SendVerack(peerID common.PeerId, localInfo handshake.VersionInf
 		return
 	}
 
-	conn, err := createGRPCClient(remoteAddrPort)
+	if c.networkInfoRegistry.IsBanned(remoteAddrPort) {
+		logger.Infof("[handshake_grpc] not sending Verack to %s: address is banned", remoteAddrPort.String())
+		_ = c.peerDisconnector.Disconnect(peerID)
+		return
+	}
+
+	conn, err := c.createGRPCClient(remoteAddrPort)
 	if err != nil {
 		logger.Warnf("[handshake_grpc] failed to create gRPC client for %s: %v", remoteAddrPort.String(), err)
 		return
@@ -156,9 +218,9 @@ func (c *Client) SendVerack(peerID common.PeerId, localInfo handshake.VersionInf
 	}
 }
 
-func (c *Client) SendAck(peerID common.PeerId) {
+func (c *Client) SendAck(peerID common.PeerId, signature []byte) {
 	SendHelper(c, peerID, "Ack", pb.NewConnectionEstablishmentClient, func(client pb.ConnectionEstablishmentClient) error {
-		_, err := client.Ack(context.Background(), &emptypb.Empty{})
+		_, err := client.Ack(context.Background(), &pb.AckInfo{Signature: signature})
 		return err
 	})
 }
@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/nodecert"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	grpcPeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// tlsPeerContext returns the context of a request on a TLS connection whose client presented the given certificates.
+func tlsPeerContext(certs ...*x509.Certificate) context.Context {
+	return grpcPeer.NewContext(context.Background(), &grpcPeer.Peer{
+		Addr:     &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 40000},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: certs}},
+	})
+}
+
+func TestVerifyNodeIdentity(t *testing.T) {
+	localCert, _ := nodecert.LoadOrCreate("")
+	peerCert, _ := nodecert.LoadOrCreate("")
+	otherCert, _ := nodecert.LoadOrCreate("")
+	peerKey := nodecert.LocalPublicKey(peerCert)
+
+	server := &Server{tlsConfig: nodecert.P2PServerConfig(localCert)}
+
+	key, err := server.verifyNodeIdentity(tlsPeerContext(peerCert.Leaf), peerKey)
+	if err != nil || !bytes.Equal(key, peerKey) {
+		t.Errorf("expected the key of the client certificate to be verified, got %v", err)
+	}
+
+	_, err = server.verifyNodeIdentity(tlsPeerContext(otherCert.Leaf), peerKey)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated for a key not matching the certificate, got %v", err)
+	}
+
+	_, err = server.verifyNodeIdentity(tlsPeerContext(), peerKey)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without client certificate, got %v", err)
+	}
+
+	plaintextServer := &Server{}
+	key, err = plaintextServer.verifyNodeIdentity(context.Background(), peerKey)
+	if err != nil || key != nil {
+		t.Errorf("expected no verification without TLS, got key %x and error %v", key, err)
+	}
+}
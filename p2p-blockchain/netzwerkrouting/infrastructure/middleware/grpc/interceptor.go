@@ -0,0 +1,95 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"path"
+	"strings"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/pb"
+
+	"bjoernblessin.de/go-utils/util/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// refuseBannedPeers is a unary interceptor refusing all requests from banned addresses before they reach a handler.
+// Requests from banned addresses therefore never register a peer in the NetworkInfoRegistry.
+func (s *Server) refuseBannedPeers(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	inboundAddr := getPeerAddr(ctx)
+	if s.networkInfoRegistry.IsBanned(inboundAddr) {
+		logger.Debugf("[blockchain_grpc_server] Refusing %s from banned address %s", info.FullMethod, inboundAddr)
+		return nil, status.Error(codes.PermissionDenied, "address is banned")
+	}
+	return handler(ctx, req)
+}
+
+// limitRate is a unary interceptor dropping messages of peers exceeding the rate of the message type, see messageRates.
+// The first dropped message of a burst is answered with a Reject, if the peer is known.
+func (s *Server) limitRate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	inboundAddr := getPeerAddr(ctx)
+	method := path.Base(info.FullMethod)
+
+	allowed, firstDropped := s.rateLimiter.allow(inboundAddr.Addr(), method)
+	if !allowed {
+		logger.Debugf("[blockchain_grpc_server] Throttling %s from %s", method, inboundAddr)
+		if firstDropped {
+			s.rejectKnownPeer(inboundAddr, method, common.ErrorTypeRejectThrottled, "rate limit exceeded")
+		}
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit for %s exceeded", method)
+	}
+	return handler(ctx, req)
+}
+
+// limitListSizes is a unary interceptor dropping messages whose lists exceed the protocol limits, see common.MaxInvVectors.
+func (s *Server) limitListSizes(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := checkListSizes(req); err != nil {
+		inboundAddr := getPeerAddr(ctx)
+		method := path.Base(info.FullMethod)
+
+		logger.Debugf("[blockchain_grpc_server] Dropping %s from %s: %v", method, inboundAddr, err)
+		s.rejectKnownPeer(inboundAddr, method, common.ErrorTypeRejectMalformed, err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return handler(ctx, req)
+}
+
+// checkListSizes returns an error if a list in the message exceeds its limit.
+func checkListSizes(req any) error {
+	var count, limit int
+	switch msg := req.(type) {
+	case *pb.InvMsg:
+		count, limit = len(msg.Inventory), common.MaxInvVectors
+	case *pb.GetDataMsg:
+		count, limit = len(msg.Inventory), common.MaxInvVectors
+	case *pb.AddrList:
+		count, limit = len(msg.Peers), common.MaxAddrListSize
+	case *pb.BlockHeaders:
+		count, limit = len(msg.Headers), common.MaxHeadersCount
+	case *pb.BlockLocator:
+		count, limit = len(msg.BlockLocatorHashes), common.MaxBlockLocatorHashes
+	default:
+		return nil
+	}
+
+	if count > limit {
+		return fmt.Errorf("%d entries exceed the limit of %d", count, limit)
+	}
+	return nil
+}
+
+// rejectKnownPeer sends a Reject for a dropped message to the peer of the inbound address.
+// Unknown peers are not rejected, they would need to be registered first, and Rejects are never rejected to avoid loops.
+func (s *Server) rejectKnownPeer(inboundAddr netip.AddrPort, method string, errorType int32, reason string) {
+	if method == "Reject" {
+		return
+	}
+	peerID, ok := s.networkInfoRegistry.GetOutboundPeer(inboundAddr)
+	if !ok {
+		return
+	}
+	go s.errorMsgSender.SendReject(peerID, errorType, strings.ToLower(method), []byte(reason))
+}
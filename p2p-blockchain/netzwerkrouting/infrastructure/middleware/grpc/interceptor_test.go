@@ -0,0 +1,155 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/pb"
+	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/infrastructure/middleware/grpc/networkinfo"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcPeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type fixedPeerCreator struct{}
+
+func (fixedPeerCreator) NewPeer() common.PeerId {
+	return "peer-1"
+}
+
+type noBans struct{}
+
+func (noBans) IsBanned(netip.Addr) bool {
+	return false
+}
+
+type rejectRecord struct {
+	peerID      common.PeerId
+	errorType   int32
+	messageType string
+}
+
+type recordingErrorMsgSender struct {
+	rejects chan rejectRecord
+}
+
+func (m *recordingErrorMsgSender) SendReject(peerID common.PeerId, errorType int32, rejectedMessageType string, _ []byte) {
+	m.rejects <- rejectRecord{peerID: peerID, errorType: errorType, messageType: rejectedMessageType}
+}
+
+func newInterceptorTestServer(t *testing.T, inboundAddr netip.AddrPort) (*Server, *recordingErrorMsgSender, context.Context) {
+	t.Helper()
+
+	registry := networkinfo.NewNetworkInfoRegistry(fixedPeerCreator{}, noBans{})
+	peerID := registry.GetOrRegisterPeer(inboundAddr, netip.AddrPort{})
+	registry.AddInboundAddress(peerID, inboundAddr)
+	sender := &recordingErrorMsgSender{rejects: make(chan rejectRecord, 10)}
+	server := &Server{
+		networkInfoRegistry: registry,
+		errorMsgSender:      sender,
+		rateLimiter:         newRateLimiter(map[string]rate{"Inv": {perSecond: 0.001, burst: 1}}, defaultMessageRate),
+	}
+
+	ctx := grpcPeer.NewContext(context.Background(), &grpcPeer.Peer{Addr: net.TCPAddrFromAddrPort(inboundAddr)})
+	return server, sender, ctx
+}
+
+func okHandler(context.Context, any) (any, error) {
+	return "ok", nil
+}
+
+func TestLimitRate_RejectsOncePerEpisode(t *testing.T) {
+	server, sender, ctx := newInterceptorTestServer(t, netip.MustParseAddrPort("10.0.0.1:40000"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.BlockchainService/Inv"}
+
+	if _, err := server.limitRate(ctx, &pb.InvMsg{}, info, okHandler); err != nil {
+		t.Fatalf("expected first message to be allowed, got %v", err)
+	}
+	for range 2 {
+		_, err := server.limitRate(ctx, &pb.InvMsg{}, info, okHandler)
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("expected ResourceExhausted, got %v", err)
+		}
+	}
+
+	select {
+	case reject := <-sender.rejects:
+		if reject.peerID != "peer-1" || reject.errorType != common.ErrorTypeRejectThrottled || reject.messageType != "inv" {
+			t.Errorf("unexpected reject %+v", reject)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a reject for the throttled message")
+	}
+	select {
+	case reject := <-sender.rejects:
+		t.Errorf("expected a single reject per throttling episode, got another %+v", reject)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLimitRate_SharesBucketAcrossConnectionsOfAnAddress(t *testing.T) {
+	server, _, ctx := newInterceptorTestServer(t, netip.MustParseAddrPort("10.0.0.1:40000"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.BlockchainService/Inv"}
+
+	if _, err := server.limitRate(ctx, &pb.InvMsg{}, info, okHandler); err != nil {
+		t.Fatalf("expected first message to be allowed, got %v", err)
+	}
+
+	otherConnCtx := grpcPeer.NewContext(context.Background(), &grpcPeer.Peer{Addr: net.TCPAddrFromAddrPort(netip.MustParseAddrPort("10.0.0.1:40001"))})
+	if _, err := server.limitRate(otherConnCtx, &pb.InvMsg{}, info, okHandler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected a new connection from the same address to be throttled, got %v", err)
+	}
+}
+
+func TestLimitListSizes(t *testing.T) {
+	server, sender, ctx := newInterceptorTestServer(t, netip.MustParseAddrPort("10.0.0.1:40000"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.PeerDiscovery/Addr"}
+
+	_, err := server.limitListSizes(ctx, &pb.AddrList{Peers: make([]*pb.PeerAddress, common.MaxAddrListSize)}, info, okHandler)
+	if err != nil {
+		t.Errorf("expected address list at the limit to be allowed, got %v", err)
+	}
+
+	_, err = server.limitListSizes(ctx, &pb.AddrList{Peers: make([]*pb.PeerAddress, common.MaxAddrListSize+1)}, info, okHandler)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+
+	select {
+	case reject := <-sender.rejects:
+		if reject.errorType != common.ErrorTypeRejectMalformed || reject.messageType != "addr" {
+			t.Errorf("unexpected reject %+v", reject)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a reject for the oversized message")
+	}
+}
+
+func TestCheckListSizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     any
+		wantErr bool
+	}{
+		{"inv at limit", &pb.InvMsg{Inventory: make([]*pb.InvVector, common.MaxInvVectors)}, false},
+		{"inv over limit", &pb.InvMsg{Inventory: make([]*pb.InvVector, common.MaxInvVectors+1)}, true},
+		{"getdata over limit", &pb.GetDataMsg{Inventory: make([]*pb.InvVector, common.MaxInvVectors+1)}, true},
+		{"headers over limit", &pb.BlockHeaders{Headers: make([]*pb.BlockHeader, common.MaxHeadersCount+1)}, true},
+		{"locator over limit", &pb.BlockLocator{BlockLocatorHashes: make([][]byte, common.MaxBlockLocatorHashes+1)}, true},
+		{"message without list", &pb.TxMsg{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkListSizes(tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("checkListSizes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
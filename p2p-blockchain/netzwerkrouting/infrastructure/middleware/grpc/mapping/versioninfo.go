@@ -69,7 +69,10 @@ func VersionInfoFromProto(info *pb.VersionInfo) (handshake.VersionInfo, netip.Ad
 	}
 
 	versionInfo := handshake.VersionInfo{
-		Version: info.GetVersion(),
+		Version:   info.GetVersion(),
+		PublicKey: info.GetNodePublicKey(),
+		Nonce:     info.GetNonce(),
+		Signature: info.GetSignature(),
 	}
 
 	if err := versionInfo.TryAddService(services...); err != nil {
@@ -99,6 +102,9 @@ func VersionInfoToProto(info handshake.VersionInfo, addrPort netip.AddrPort) *pb
 			IpAddress:     addrPort.Addr().AsSlice(),
 			ListeningPort: uint32(addrPort.Port()),
 		},
+		NodePublicKey: info.PublicKey,
+		Nonce:         info.Nonce,
+		Signature:     info.Signature,
 	}
 	for _, service := range info.SupportedServices() {
 		pbInfo.SupportedServices = append(pbInfo.SupportedServices, serviceTypeToProto(service))
@@ -3,6 +3,7 @@
 package networkinfo
 
 import (
+	"encoding/hex"
 	"net/netip"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/api"
@@ -21,6 +22,7 @@ type NetworkInfoEntry struct {
 	ListeningEndpoint netip.AddrPort   // The port we can reach them on (from VersionInfo)
 	InboundAddresses  []netip.AddrPort // Inbound ports we've seen from this peer (from gRPC context)
 	OutboundConn      *grpc.ClientConn // Our gRPC connection to the peer
+	NodePublicKey     []byte           // Public key of the peer's node certificate (from VersionInfo, verified by TLS)
 }
 
 // peerCreator is an interface for creating new peers.
@@ -29,6 +31,12 @@ type peerCreator interface {
 	NewPeer() common.PeerId
 }
 
+// banChecker is an interface for checking whether an IP address is banned.
+// It is implemented by the core layer's misbehavior.BanList.
+type banChecker interface {
+	IsBanned(addr netip.Addr) bool
+}
+
 // NetworkInfoRegistry maintains a registry of peers and their network addresses.
 // It allows representing peers by a generic ID and links:
 // - Listening endpoint (reachable address from VersionInfo)
@@ -41,17 +49,25 @@ type NetworkInfoRegistry struct {
 	inboundAddrToPeer       map[netip.AddrPort]common.PeerId
 	networkInfoEntries      map[common.PeerId]*NetworkInfoEntry
 	peerCreator             peerCreator
+	banChecker              banChecker
 }
 
-func NewNetworkInfoRegistry(peerCreator peerCreator) *NetworkInfoRegistry {
+func NewNetworkInfoRegistry(peerCreator peerCreator, banChecker banChecker) *NetworkInfoRegistry {
 	return &NetworkInfoRegistry{
 		listeningEndpointToPeer: make(map[netip.AddrPort]common.PeerId),
 		inboundAddrToPeer:       make(map[netip.AddrPort]common.PeerId),
 		networkInfoEntries:      make(map[common.PeerId]*NetworkInfoEntry),
 		peerCreator:             peerCreator,
+		banChecker:              banChecker,
 	}
 }
 
+// IsBanned returns whether the IP address of addrPort is banned.
+// Inbound connections from and outbound connections to banned addresses are refused.
+func (r *NetworkInfoRegistry) IsBanned(addrPort netip.AddrPort) bool {
+	return r.banChecker.IsBanned(addrPort.Addr())
+}
+
 // getPeerIDByAddr looks up a peer by address and port.
 // Searches both listening endpoints and inbound addresses.
 func (r *NetworkInfoRegistry) getPeerIDByAddr(addr netip.AddrPort) (common.PeerId, bool) {
@@ -166,6 +182,16 @@ func (r *NetworkInfoRegistry) SetListeningEndpoint(peerID common.PeerId, listeni
 	}
 }
 
+// SetNodePublicKey records the verified public key of the peer's node certificate, which identifies the remote node.
+func (r *NetworkInfoRegistry) SetNodePublicKey(peerID common.PeerId, publicKey []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.networkInfoEntries[peerID]
+	assert.Assert(exists, mustExists, peerID)
+	entry.NodePublicKey = slices.Clone(publicKey)
+}
+
 // SetConnection sets the outbound gRPC connection for an existing peer.
 // Starts monitoring the connection state to detect disconnections.
 func (r *NetworkInfoRegistry) SetConnection(peerID common.PeerId, conn *grpc.ClientConn) {
@@ -210,6 +236,46 @@ func (r *NetworkInfoRegistry) GetOutboundPeer(addrPort netip.AddrPort) (common.P
 	return r.getPeerIDByAddr(addrPort)
 }
 
+// GetPeerAddrs returns the distinct IP addresses of the listening endpoint and the inbound addresses of a peer.
+func (r *NetworkInfoRegistry) GetPeerAddrs(peerID common.PeerId) []netip.Addr {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.networkInfoEntries[peerID]
+	if !exists {
+		return nil
+	}
+
+	addrs := make([]netip.Addr, 0, len(entry.InboundAddresses)+1)
+	if entry.ListeningEndpoint != (netip.AddrPort{}) {
+		addrs = append(addrs, entry.ListeningEndpoint.Addr().Unmap())
+	}
+	for _, inboundAddr := range entry.InboundAddresses {
+		if addr := inboundAddr.Addr().Unmap(); !slices.Contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// GetPeersByAddr returns the peers whose listening endpoint or one of whose inbound addresses has the given IP address.
+func (r *NetworkInfoRegistry) GetPeersByAddr(addr netip.Addr) []common.PeerId {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	addr = addr.Unmap()
+	peerIDs := make([]common.PeerId, 0)
+	for peerID, entry := range r.networkInfoEntries {
+		matches := entry.ListeningEndpoint.Addr().Unmap() == addr || slices.ContainsFunc(entry.InboundAddresses, func(inboundAddr netip.AddrPort) bool {
+			return inboundAddr.Addr().Unmap() == addr
+		})
+		if matches {
+			peerIDs = append(peerIDs, peerID)
+		}
+	}
+	return peerIDs
+}
+
 // GetAllInfrastructureInfo implements the InfrastructureInfoProvider interface from api.
 func (r *NetworkInfoRegistry) GetAllInfrastructureInfo() api.FullInfrastructureInfo {
 	r.mu.RLock()
@@ -222,6 +288,7 @@ func (r *NetworkInfoRegistry) GetAllInfrastructureInfo() api.FullInfrastructureI
 			"listeningEndpoint": entry.ListeningEndpoint.String(),
 			"inboundAddresses":  formatAddrPortsAsAny(entry.InboundAddresses),
 			"hasOutboundConn":   entry.OutboundConn != nil,
+			"nodePublicKey":     hex.EncodeToString(entry.NodePublicKey),
 		}
 	}
 	return result
@@ -56,7 +56,7 @@ func (c *Client) SendGetAddr(peerID common.PeerId) {
 }
 
 // SendAddr sends an Addr message containing known peer addresses to the specified peer.
-// This method is used to share known peer addresses with a specific peer.
+// This method is used to share known peer addresses with a specific peer, at most common.MaxAddrListSize of them.
 func (c *Client) SendAddr(peerID common.PeerId, peerAddresses []discovery.PeerAddress) {
 	// Convert domain model (PeerId + timestamp) to protobuf addresses (IP + port + timestamp)
 	// The infrastructure layer looks up listening endpoints for PeerIds
@@ -70,6 +70,10 @@ func (c *Client) SendAddr(peerID common.PeerId, peerAddresses []discovery.PeerAd
 		pbPeers = append(pbPeers, pbPeer)
 	}
 
+	if len(pbPeers) > common.MaxAddrListSize {
+		pbPeers = pbPeers[:common.MaxAddrListSize]
+	}
+
 	addrList := &pb.AddrList{
 		Peers: pbPeers,
 	}
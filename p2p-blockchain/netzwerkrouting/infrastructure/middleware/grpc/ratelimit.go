@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// pruneInterval is the interval in which buckets of idle connections are removed.
+const pruneInterval = time.Minute
+
+// rate is the refill rate and capacity of a token bucket. One message takes one token.
+type rate struct {
+	// perSecond is the number of tokens refilled per second, i.e. the sustained message rate.
+	perSecond float64
+	// burst is the capacity of the bucket, i.e. the number of messages that may be sent at once.
+	burst float64
+}
+
+// messageRates are the rates of the P2P messages by RPC method name.
+// They leave enough room for the initial block download and the mempool sync after a handshake,
+// where the remote node answers a single GetData message with one Block or Tx message per requested item.
+var messageRates = map[string]rate{
+	"Version":       {perSecond: 1, burst: 10},
+	"Verack":        {perSecond: 1, burst: 10},
+	"Ack":           {perSecond: 1, burst: 10},
+	"GetAddr":       {perSecond: 0.1, burst: 5},
+	"Addr":          {perSecond: 1, burst: 10},
+	"HeartbeatBing": {perSecond: 1, burst: 10},
+	"HeartbeatBong": {perSecond: 1, burst: 10},
+	"Reject":        {perSecond: 50, burst: 500},
+	"Inv":           {perSecond: 50, burst: 500},
+	"GetData":       {perSecond: 50, burst: 500},
+	"Block":         {perSecond: 50, burst: 500},
+	"Tx":            {perSecond: 100, burst: 2000},
+	"GetHeaders":    {perSecond: 10, burst: 50},
+	"Headers":       {perSecond: 10, burst: 50},
+	"Mempool":       {perSecond: 0.1, burst: 5},
+}
+
+// defaultMessageRate is the rate of messages missing in messageRates.
+var defaultMessageRate = rate{perSecond: 10, burst: 100}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// throttled is set once a message was dropped and reset when the next message is allowed.
+	throttled bool
+}
+
+// refill adds the tokens refilled since the last message, up to the burst.
+func (b *bucket) refill(r rate, now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = min(b.tokens+elapsed*r.perSecond, r.burst)
+	b.last = now
+}
+
+type bucketKey struct {
+	addr   netip.Addr
+	method string
+}
+
+// rateLimiter keeps a token bucket per remote IP address and message type.
+// Peers are told by their IP address, because a peer is only known to the NetworkInfoRegistry after the handshake.
+// The port is left out, so a peer can't get fresh buckets by opening new connections.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rates     map[string]rate
+	fallback  rate
+	buckets   map[bucketKey]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter(rates map[string]rate, fallback rate) *rateLimiter {
+	return &rateLimiter{
+		rates:     rates,
+		fallback:  fallback,
+		buckets:   make(map[bucketKey]*bucket),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *rateLimiter) rateOf(method string) rate {
+	if r, ok := l.rates[method]; ok {
+		return r
+	}
+	return l.fallback
+}
+
+// allow takes a token from the bucket of the IP address and message type.
+// It returns whether the message is allowed and, if not, whether it is the first dropped message since the last allowed one.
+// This way a flooding peer is told once about the throttling instead of once per dropped message.
+func (l *rateLimiter) allow(addr netip.Addr, method string) (allowed bool, firstDropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.pruneIdleBuckets(now)
+
+	r := l.rateOf(method)
+	key := bucketKey{addr: addr, method: method}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: r.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.refill(r, now)
+
+	if b.tokens < 1 {
+		firstDropped = !b.throttled
+		b.throttled = true
+		return false, firstDropped
+	}
+	b.tokens--
+	b.throttled = false
+	return true, false
+}
+
+// pruneIdleBuckets removes the buckets that refilled completely, they are equivalent to a new bucket.
+// Called with mu held.
+func (l *rateLimiter) pruneIdleBuckets(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		r := l.rateOf(key.method)
+		b.refill(r, now)
+		if b.tokens >= r.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func newTestRateLimiter(now *time.Time) *rateLimiter {
+	limiter := newRateLimiter(map[string]rate{"Inv": {perSecond: 1, burst: 2}}, rate{perSecond: 10, burst: 10})
+	limiter.now = func() time.Time { return *now }
+	limiter.lastPrune = *now
+	return limiter
+}
+
+func TestRateLimiter_ThrottlesAfterBurst(t *testing.T) {
+	now := time.Now()
+	limiter := newTestRateLimiter(&now)
+	addr := netip.MustParseAddr("10.0.0.1")
+
+	for i := range 2 {
+		if allowed, _ := limiter.allow(addr, "Inv"); !allowed {
+			t.Fatalf("expected message %d of the burst to be allowed", i)
+		}
+	}
+
+	allowed, firstDropped := limiter.allow(addr, "Inv")
+	if allowed || !firstDropped {
+		t.Errorf("expected first dropped message, got allowed=%v firstDropped=%v", allowed, firstDropped)
+	}
+	allowed, firstDropped = limiter.allow(addr, "Inv")
+	if allowed || firstDropped {
+		t.Errorf("expected further dropped message, got allowed=%v firstDropped=%v", allowed, firstDropped)
+	}
+
+	now = now.Add(time.Second)
+	if allowed, _ := limiter.allow(addr, "Inv"); !allowed {
+		t.Error("expected message to be allowed after the bucket refilled")
+	}
+	allowed, firstDropped = limiter.allow(addr, "Inv")
+	if allowed || !firstDropped {
+		t.Errorf("expected a new throttling episode, got allowed=%v firstDropped=%v", allowed, firstDropped)
+	}
+}
+
+func TestRateLimiter_SeparateBuckets(t *testing.T) {
+	now := time.Now()
+	limiter := newTestRateLimiter(&now)
+	addr := netip.MustParseAddr("10.0.0.1")
+	otherAddr := netip.MustParseAddr("10.0.0.2")
+
+	for range 2 {
+		limiter.allow(addr, "Inv")
+	}
+
+	if allowed, _ := limiter.allow(otherAddr, "Inv"); !allowed {
+		t.Error("expected other address to have its own bucket")
+	}
+	if allowed, _ := limiter.allow(addr, "Tx"); !allowed {
+		t.Error("expected other message type to have its own bucket")
+	}
+}
+
+func TestRateLimiter_PrunesIdleBuckets(t *testing.T) {
+	now := time.Now()
+	limiter := newTestRateLimiter(&now)
+	idleAddr := netip.MustParseAddr("10.0.0.1")
+	activeAddr := netip.MustParseAddr("10.0.0.2")
+
+	limiter.allow(idleAddr, "Inv")
+	now = now.Add(pruneInterval)
+	for range 3 {
+		limiter.allow(activeAddr, "Inv")
+	}
+
+	if _, ok := limiter.buckets[bucketKey{addr: idleAddr, method: "Inv"}]; ok {
+		t.Error("expected refilled bucket to be pruned")
+	}
+	if _, ok := limiter.buckets[bucketKey{addr: activeAddr, method: "Inv"}]; !ok {
+		t.Error("expected bucket in use to be kept")
+	}
+}
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -9,6 +10,7 @@ import (
 
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/pb"
+	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/api"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/api/blockchain/observer"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/handshake"
 	"s3b/vsp-blockchain/p2p-blockchain/netzwerkrouting/core/keepalive"
@@ -18,8 +20,12 @@ import (
 	"bjoernblessin.de/go-utils/util/logger"
 	mapset "github.com/deckarep/golang-set/v2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// maxConcurrentStreams is the maximum number of concurrent requests on a single inbound connection.
+const maxConcurrentStreams = 100
+
 // holddownChecker provides the ability to check if a peer is in holddown state.
 // Implemented by PeerStore.
 type holddownChecker interface {
@@ -36,6 +42,10 @@ type Server struct {
 	handshakeMsgHandler handshake.HandshakeMsgHandler
 	networkInfoRegistry *networkinfo.NetworkInfoRegistry
 	holddownChecker     holddownChecker
+	errorMsgSender      api.ErrorMsgSenderAPI
+	rateLimiter         *rateLimiter
+	// tlsConfig secures the connections of other nodes, nil for plaintext connections, see nodecert.P2PServerConfig.
+	tlsConfig *tls.Config
 
 	pb.UnimplementedBlockchainServiceServer
 	observers mapset.Set[observer.BlockchainObserverAPI]
@@ -53,6 +63,8 @@ func NewServer(
 	discoveryService *discovery.DiscoveryService,
 	keepaliveService *keepalive.KeepaliveService,
 	holddownChecker holddownChecker,
+	errorMsgSender api.ErrorMsgSenderAPI,
+	tlsConfig *tls.Config,
 ) *Server {
 	return &Server{
 		handshakeMsgHandler: handshakeMsgHandler,
@@ -61,6 +73,9 @@ func NewServer(
 		discoveryService:    discoveryService,
 		keepaliveService:    keepaliveService,
 		holddownChecker:     holddownChecker,
+		errorMsgSender:      errorMsgSender,
+		rateLimiter:         newRateLimiter(messageRates, defaultMessageRate),
+		tlsConfig:           tlsConfig,
 	}
 }
 
@@ -73,7 +88,16 @@ func (s *Server) Start(port uint16) error {
 	}
 	s.listener = listener
 
-	s.grpcServer = grpc.NewServer()
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.refuseBannedPeers, s.limitRate, s.limitListSizes),
+		grpc.MaxRecvMsgSize(common.MaxMessageSize),
+		grpc.MaxSendMsgSize(common.MaxMessageSize),
+		grpc.MaxConcurrentStreams(maxConcurrentStreams),
+	}
+	if s.tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	s.grpcServer = grpc.NewServer(options...)
 	pb.RegisterConnectionEstablishmentServer(s.grpcServer, s)
 	pb.RegisterBlockchainServiceServer(s.grpcServer, s)
 	pb.RegisterPeerDiscoveryServer(s.grpcServer, s)
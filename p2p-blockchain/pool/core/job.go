@@ -0,0 +1,71 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+)
+
+const (
+	// Extranonce1Size is the size of the extranonce assigned to every connection by the pool.
+	Extranonce1Size = 4
+	// Extranonce2Size is the size of the extranonce chosen by the worker.
+	Extranonce2Size = 4
+	// ExtranonceSize is the size of the whole extranonce in the coinbase data.
+	ExtranonceSize = Extranonce1Size + Extranonce2Size
+)
+
+// Job is the work handed out to the workers of the pool: a block template whose coinbase is split around the extranonce.
+// Every connection gets its own extranonce1 and chooses extranonce2 itself, so no two workers search the same headers.
+type Job struct {
+	ID                string
+	PreviousBlockHash common.Hash
+	Height            uint64
+	DifficultyTarget  uint8
+	// MinTimestamp is the earliest valid timestamp of the block.
+	MinTimestamp int64
+	// Timestamp is the suggested timestamp of the block.
+	Timestamp int64
+	// CoinbasePrefix and CoinbaseSuffix are the serialized coinbase before and after the extranonce, see transaction.SplitCoinbase.
+	CoinbasePrefix []byte
+	CoinbaseSuffix []byte
+	MerkleBranch   []common.Hash
+	// CleanJobs is set if the job builds on a new chain tip, so shares of previous jobs are rejected.
+	CleanJobs bool
+}
+
+// BuildBlock returns the header and the coinbase of the block of the job for the given extranonce, timestamp and nonce.
+// The extranonce is extranonce1 followed by extranonce2 and must have ExtranonceSize bytes.
+func (j *Job) BuildBlock(extranonce []byte, timestamp int64, nonce uint32) (block.BlockHeader, transaction.Transaction, error) {
+	if len(extranonce) != ExtranonceSize {
+		return block.BlockHeader{}, transaction.Transaction{}, fmt.Errorf("extranonce has %d bytes, want %d", len(extranonce), ExtranonceSize)
+	}
+
+	serialized := make([]byte, 0, len(j.CoinbasePrefix)+ExtranonceSize+len(j.CoinbaseSuffix))
+	serialized = append(serialized, j.CoinbasePrefix...)
+	serialized = append(serialized, extranonce...)
+	serialized = append(serialized, j.CoinbaseSuffix...)
+
+	r := bytes.NewReader(serialized)
+	coinbase, err := transaction.DeserializeTransaction(r)
+	if err != nil || r.Len() != 0 || !coinbase.IsCoinbase() {
+		return block.BlockHeader{}, transaction.Transaction{}, fmt.Errorf("job %s has a malformed coinbase", j.ID)
+	}
+
+	header := block.BlockHeader{
+		PreviousBlockHash: j.PreviousBlockHash,
+		MerkleRoot:        block.MerkleRootFromCoinbase(coinbase.Hash(), j.MerkleBranch),
+		Timestamp:         timestamp,
+		DifficultyTarget:  j.DifficultyTarget,
+		Nonce:             nonce,
+	}
+	return header, coinbase, nil
+}
+
+// HeaderDifficulty returns the number of leading zero bits of the hash of the header.
+func HeaderDifficulty(header block.BlockHeader) uint8 {
+	b := block.Block{Header: header}
+	return b.BlockDifficulty()
+}
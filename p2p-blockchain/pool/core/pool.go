@@ -0,0 +1,434 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	blockchainApi "s3b/vsp-blockchain/p2p-blockchain/blockchain/api"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	minerApi "s3b/vsp-blockchain/p2p-blockchain/miner/api"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+const (
+	// maxJobs is the number of jobs of the current chain tip for which shares are accepted.
+	maxJobs = 8
+	// maxShareTimeDrift is how far the timestamp of a share may be ahead of the local clock, see block validation.
+	maxShareTimeDrift = 5 * time.Minute
+	// templateRetryDelay is the delay before a new block template is requested after a failed request.
+	templateRetryDelay = 5 * time.Second
+)
+
+var (
+	ErrInvalidWorker      = errors.New("invalid worker name, expected <V$Address>[.<rig>]")
+	ErrUnknownJob         = errors.New("unknown or stale job")
+	ErrInvalidShare       = errors.New("invalid share")
+	ErrDuplicateShare     = errors.New("duplicate share")
+	ErrLowDifficultyShare = errors.New("share does not meet the share difficulty")
+)
+
+// AddressDecoder decodes V$Addresses.
+// It is implemented by keys.KeyEncodingsImpl.
+type AddressDecoder interface {
+	VSAddressToPubKeyHash(address string) ([common.PublicKeyHashSize]byte, error)
+}
+
+// JobListener is notified about new jobs of the pool.
+// It is implemented by the connections of the Stratum server.
+type JobListener interface {
+	NotifyJob(job Job)
+}
+
+// PoolConfig contains the settings of the pool.
+type PoolConfig struct {
+	// ShareDifficulty is the number of leading zero bits of an accepted share.
+	// It is capped at the difficulty target of the block.
+	ShareDifficulty uint8
+	// PPLNSWindow is the number of last shares a block reward is split among, see PPLNSAccounting.
+	PPLNSWindow int
+	// Payouts receive the rewards of the blocks found by the pool, usually the address of the pool operator.
+	Payouts []transaction.CoinbasePayout
+	// CoinbaseTag is embedded in the coinbase of the blocks found by the pool.
+	CoinbaseTag []byte
+}
+
+// Share is a solution for a job submitted by a worker.
+type Share struct {
+	WorkerName  string
+	JobID       string
+	Extranonce1 []byte
+	Extranonce2 []byte
+	Timestamp   int64
+	Nonce       uint32
+}
+
+// WorkerStats are the share counts of a worker.
+type WorkerStats struct {
+	Name           string
+	Address        string
+	AcceptedShares uint64
+	RejectedShares uint64
+	BlocksFound    uint64
+	LastShare      time.Time
+}
+
+// poolJob is a job with the block template it was created from and the headers of the shares submitted for it.
+type poolJob struct {
+	Job
+	templateID    uint64
+	coinbaseValue uint64
+	seen          map[common.Hash]struct{}
+}
+
+// PoolService runs a mining pool on top of the block templates of the node.
+// It hands out jobs to the connected workers, validates their shares at the lower share difficulty, records the shares
+// for the PPLNS accounting and submits shares that solve the block to the blockchain, see BlockTemplateAPI.SubmitBlock.
+// New jobs are created whenever the chain tip changes or the long poll of the block template times out.
+type PoolService struct {
+	templates      minerApi.BlockTemplateAPI
+	blockStore     blockchainApi.BlockStoreAPI
+	addressDecoder AddressDecoder
+	config         PoolConfig
+	accounting     *PPLNSAccounting
+
+	mu             sync.Mutex
+	jobs           map[string]*poolJob
+	jobOrder       []string
+	currentJob     *Job
+	nextJobID      uint64
+	nextExtranonce uint32
+	listeners      map[JobListener]struct{}
+	// workers are the stats of the authorized workers, workerSessions the number of sessions that authorized them.
+	workers        map[string]*WorkerStats
+	workerSessions map[string]int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPoolService creates a pool with the given settings. Start must be called to create jobs.
+func NewPoolService(
+	templates minerApi.BlockTemplateAPI,
+	blockStore blockchainApi.BlockStoreAPI,
+	addressDecoder AddressDecoder,
+	config PoolConfig,
+) *PoolService {
+	return &PoolService{
+		templates:      templates,
+		blockStore:     blockStore,
+		addressDecoder: addressDecoder,
+		config:         config,
+		accounting:     NewPPLNSAccounting(config.PPLNSWindow),
+		jobs:           make(map[string]*poolJob),
+		listeners:      make(map[JobListener]struct{}),
+		workers:        make(map[string]*WorkerStats),
+		workerSessions: make(map[string]int),
+	}
+}
+
+// Start begins requesting block templates in a goroutine and hands out a new job for each of them.
+func (s *PoolService) Start() {
+	logger.Infof("[pool] Starting mining pool with share difficulty %d and PPLNS window of %d shares", s.config.ShareDifficulty, s.config.PPLNSWindow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		longPollID := ""
+		for {
+			template, err := s.templates.GetBlockTemplate(ctx, longPollID)
+			if ctx.Err() != nil {
+				logger.Infof("[pool] Mining pool stopped")
+				return
+			}
+			if err != nil {
+				logger.Warnf("[pool] Failed to get block template: %v", err)
+				select {
+				case <-time.After(templateRetryDelay):
+				case <-ctx.Done():
+					return
+				}
+				longPollID = ""
+				continue
+			}
+
+			if err := s.addJob(jobTemplate{
+				id:                template.ID,
+				previousBlockHash: template.PreviousBlockHash,
+				height:            template.Height,
+				difficultyTarget:  template.DifficultyTarget,
+				minTimestamp:      template.MinTimestamp,
+				timestamp:         template.CurrentTimestamp,
+				coinbaseValue:     template.CoinbaseValue,
+				merkleBranch:      template.MerkleBranch,
+			}); err != nil {
+				logger.Warnf("[pool] Failed to create job: %v", err)
+			}
+			longPollID = template.LongPollID
+		}
+	}()
+}
+
+// Stop stops requesting block templates and waits until the pending request returned.
+func (s *PoolService) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// jobTemplate contains the fields of a block template a job is created from, see BlockTemplateAPI.GetBlockTemplate.
+type jobTemplate struct {
+	id                uint64
+	previousBlockHash common.Hash
+	height            uint64
+	difficultyTarget  uint8
+	minTimestamp      int64
+	timestamp         int64
+	coinbaseValue     uint64
+	merkleBranch      []common.Hash
+}
+
+// addJob creates a job from a block template and notifies all listeners.
+// All jobs of a previous chain tip are dropped, the job tells the workers to abandon them.
+func (s *PoolService) addJob(template jobTemplate) error {
+	coinbase := transaction.NewCoinbaseTransactionWithPayouts(s.config.Payouts, template.coinbaseValue, template.height, s.config.CoinbaseTag)
+	prefix, suffix, err := coinbase.SplitCoinbase(ExtranonceSize)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+
+	cleanJobs := s.currentJob == nil || s.currentJob.PreviousBlockHash != template.previousBlockHash
+	if cleanJobs {
+		clear(s.jobs)
+		s.jobOrder = s.jobOrder[:0]
+	}
+
+	s.nextJobID++
+	job := &poolJob{
+		Job: Job{
+			ID:                strconv.FormatUint(s.nextJobID, 16),
+			PreviousBlockHash: template.previousBlockHash,
+			Height:            template.height,
+			DifficultyTarget:  template.difficultyTarget,
+			MinTimestamp:      template.minTimestamp,
+			Timestamp:         template.timestamp,
+			CoinbasePrefix:    prefix,
+			CoinbaseSuffix:    suffix,
+			MerkleBranch:      template.merkleBranch,
+			CleanJobs:         cleanJobs,
+		},
+		templateID:    template.id,
+		coinbaseValue: template.coinbaseValue,
+		seen:          make(map[common.Hash]struct{}),
+	}
+	s.jobs[job.ID] = job
+	s.jobOrder = append(s.jobOrder, job.ID)
+	if len(s.jobOrder) > maxJobs {
+		delete(s.jobs, s.jobOrder[0])
+		s.jobOrder = s.jobOrder[1:]
+	}
+	s.currentJob = &job.Job
+
+	listeners := make([]JobListener, 0, len(s.listeners))
+	for listener := range s.listeners {
+		listeners = append(listeners, listener)
+	}
+	s.mu.Unlock()
+
+	logger.Debugf("[pool] New job %s at height %d for %d workers (clean: %t)", job.ID, job.Height, len(listeners), cleanJobs)
+	for _, listener := range listeners {
+		listener.NotifyJob(job.Job)
+	}
+	return nil
+}
+
+// Subscribe registers a listener for new jobs and assigns it a unique extranonce1.
+// Returns the current job, if any, to get the listener started.
+func (s *PoolService) Subscribe(listener JobListener) (extranonce1 []byte, currentJob *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextExtranonce++
+	extranonce1 = binary.BigEndian.AppendUint32(nil, s.nextExtranonce)
+	s.listeners[listener] = struct{}{}
+
+	if s.currentJob != nil {
+		job := *s.currentJob
+		job.CleanJobs = true
+		currentJob = &job
+	}
+	return extranonce1, currentJob
+}
+
+// Unsubscribe removes a listener registered with Subscribe.
+func (s *PoolService) Unsubscribe(listener JobListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.listeners, listener)
+}
+
+// ShareDifficulty returns the number of leading zero bits of an accepted share.
+func (s *PoolService) ShareDifficulty() uint8 {
+	return s.config.ShareDifficulty
+}
+
+// Authorize checks the name of a worker, which is the V$Address receiving its credits optionally followed by
+// a dot and the name of the rig, e.g. "1A...xyz.rig1". Returns ErrInvalidWorker if the address is invalid.
+// Every session authorizing the worker must call Deauthorize once it ends.
+func (s *PoolService) Authorize(workerName string) error {
+	address, _, _ := strings.Cut(workerName, ".")
+	if _, err := s.addressDecoder.VSAddressToPubKeyHash(address); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWorker, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workers[workerName]; !ok {
+		logger.Infof("[pool] Worker %s authorized", workerName)
+		s.workers[workerName] = &WorkerStats{Name: workerName, Address: address}
+	}
+	s.workerSessions[workerName]++
+	return nil
+}
+
+// Deauthorize ends a session of a worker authorized with Authorize. The stats of the worker are dropped once
+// no session has it authorized anymore, so workers that come and go don't accumulate. The credits of its address are kept.
+func (s *PoolService) Deauthorize(workerName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workerSessions[workerName]; !ok {
+		return
+	}
+	s.workerSessions[workerName]--
+	if s.workerSessions[workerName] == 0 {
+		logger.Infof("[pool] Worker %s disconnected", workerName)
+		delete(s.workerSessions, workerName)
+		delete(s.workers, workerName)
+	}
+}
+
+// SubmitShare validates a share of an authorized worker and records it for the PPLNS accounting.
+// A share that also meets the difficulty target of the block is submitted to the blockchain, blockFound reports
+// whether the block was accepted. Shares of unknown or stale jobs, invalid and duplicate shares are rejected.
+func (s *PoolService) SubmitShare(share Share) (blockFound bool, err error) {
+	s.mu.Lock()
+	worker, ok := s.workers[share.WorkerName]
+	if !ok {
+		s.mu.Unlock()
+		return false, ErrInvalidWorker
+	}
+
+	job, blockSolved, err := s.checkShare(share)
+	if err != nil {
+		worker.RejectedShares++
+		s.mu.Unlock()
+		return false, err
+	}
+	worker.AcceptedShares++
+	worker.LastShare = time.Now()
+	address := worker.Address
+	s.mu.Unlock()
+
+	s.accounting.AddShare(address)
+	if blockSolved == nil {
+		return false, nil
+	}
+
+	blockHash, err := s.templates.SubmitBlock(job.templateID, blockSolved.header, blockSolved.coinbase)
+	if err != nil {
+		logger.Warnf("[pool] Block of worker %s was not accepted: %v", share.WorkerName, err)
+		return false, nil
+	}
+
+	credits := s.accounting.CreditBlock(blockHash, job.Height, job.coinbaseValue)
+	logger.Infof("[pool] Worker %s found block %v at height %d, reward split among %d addresses", share.WorkerName, blockHash, job.Height, len(credits))
+
+	s.mu.Lock()
+	worker.BlocksFound++
+	s.mu.Unlock()
+	return true, nil
+}
+
+// solvedBlock is the header and coinbase of a share that meets the difficulty target of the block.
+type solvedBlock struct {
+	header   block.BlockHeader
+	coinbase transaction.Transaction
+}
+
+// checkShare validates the share against its job. Returns the block if the share solves it. mu must be held.
+func (s *PoolService) checkShare(share Share) (*poolJob, *solvedBlock, error) {
+	job, ok := s.jobs[share.JobID]
+	if !ok {
+		return nil, nil, ErrUnknownJob
+	}
+
+	if len(share.Extranonce1) != Extranonce1Size || len(share.Extranonce2) != Extranonce2Size {
+		return nil, nil, fmt.Errorf("%w: extranonce has wrong size", ErrInvalidShare)
+	}
+	if share.Timestamp < job.MinTimestamp || time.Unix(share.Timestamp, 0).After(time.Now().Add(maxShareTimeDrift)) {
+		return nil, nil, fmt.Errorf("%w: timestamp %d out of range", ErrInvalidShare, share.Timestamp)
+	}
+
+	header, coinbase, err := job.BuildBlock(slices.Concat(share.Extranonce1, share.Extranonce2), share.Timestamp, share.Nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidShare, err)
+	}
+
+	hash := header.Hash()
+	if _, ok := job.seen[hash]; ok {
+		return nil, nil, ErrDuplicateShare
+	}
+
+	difficulty := HeaderDifficulty(header)
+	if difficulty < min(s.config.ShareDifficulty, job.DifficultyTarget) {
+		return nil, nil, ErrLowDifficultyShare
+	}
+	job.seen[hash] = struct{}{}
+
+	if difficulty < job.DifficultyTarget {
+		return job, nil, nil
+	}
+	return job, &solvedBlock{header: header, coinbase: coinbase}, nil
+}
+
+// Workers returns the share counts of the workers currently authorized by at least one session.
+func (s *PoolService) Workers() []WorkerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workers := make([]WorkerStats, 0, len(s.workers))
+	for _, worker := range s.workers {
+		workers = append(workers, *worker)
+	}
+	slices.SortFunc(workers, func(a, b WorkerStats) int { return strings.Compare(a.Name, b.Name) })
+	return workers
+}
+
+// Blocks returns the blocks found by the pool with the credits per address.
+func (s *PoolService) Blocks() []CreditedBlock {
+	return s.accounting.Blocks()
+}
+
+// Balances returns the credits per V$Address for the found blocks that are still part of the main chain.
+func (s *PoolService) Balances() map[string]uint64 {
+	return s.accounting.Balances(func(hash common.Hash) bool {
+		b, err := s.blockStore.GetBlockByHash(hash)
+		return err == nil && s.blockStore.IsPartOfMainChain(b)
+	})
+}
@@ -0,0 +1,351 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	blockchainApi "s3b/vsp-blockchain/p2p-blockchain/blockchain/api"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	minerCore "s3b/vsp-blockchain/p2p-blockchain/miner/core"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockBlockTemplateAPI records the submitted blocks and accepts all of them
+type mockBlockTemplateAPI struct {
+	submitted []block.BlockHeader
+}
+
+func (m *mockBlockTemplateAPI) GetBlockTemplate(ctx context.Context, _ string) (minerCore.BlockTemplate, error) {
+	<-ctx.Done()
+	return minerCore.BlockTemplate{}, ctx.Err()
+}
+
+func (m *mockBlockTemplateAPI) SubmitBlock(_ uint64, header block.BlockHeader, _ transaction.Transaction) (common.Hash, error) {
+	m.submitted = append(m.submitted, header)
+	return header.Hash(), nil
+}
+
+// mockAddressDecoder accepts every address except the ones starting with "invalid"
+type mockAddressDecoder struct{}
+
+func (mockAddressDecoder) VSAddressToPubKeyHash(address string) ([common.PublicKeyHashSize]byte, error) {
+	if address == "" || strings.HasPrefix(address, "invalid") {
+		return [common.PublicKeyHashSize]byte{}, errors.New("invalid address")
+	}
+	return [common.PublicKeyHashSize]byte{}, nil
+}
+
+// mainChainBlockStore knows the blocks in mainChain, all other methods are not used
+type mainChainBlockStore struct {
+	blockchainApi.BlockStoreAPI
+	mainChain map[common.Hash]bool
+}
+
+func (m *mainChainBlockStore) GetBlockByHash(hash common.Hash) (block.Block, error) {
+	if _, ok := m.mainChain[hash]; !ok {
+		return block.Block{}, errors.New("unknown block")
+	}
+	return block.Block{}, nil
+}
+
+func (m *mainChainBlockStore) IsPartOfMainChain(_ block.Block) bool {
+	return true
+}
+
+// recordingListener records the jobs it is notified about
+type recordingListener struct {
+	jobs []Job
+}
+
+func (l *recordingListener) NotifyJob(job Job) {
+	l.jobs = append(l.jobs, job)
+}
+
+// createTestPool creates a pool with a single job at height 1 with the given difficulty target.
+func createTestPool(t *testing.T, shareDifficulty uint8, difficultyTarget uint8) (*PoolService, *mockBlockTemplateAPI, *mainChainBlockStore) {
+	t.Helper()
+
+	templates := &mockBlockTemplateAPI{}
+	blockStore := &mainChainBlockStore{mainChain: make(map[common.Hash]bool)}
+	pool := NewPoolService(templates, blockStore, mockAddressDecoder{}, PoolConfig{
+		ShareDifficulty: shareDifficulty,
+		PPLNSWindow:     10,
+		Payouts:         []transaction.CoinbasePayout{{Percent: 100}},
+		CoinbaseTag:     []byte("test pool"),
+	})
+
+	err := pool.addJob(jobTemplate{
+		id:               1,
+		height:           1,
+		difficultyTarget: difficultyTarget,
+		minTimestamp:     time.Now().Unix() - 60,
+		timestamp:        time.Now().Unix(),
+		coinbaseValue:    100,
+	})
+	if err != nil {
+		t.Fatalf("addJob() returned error: %v", err)
+	}
+	return pool, templates, blockStore
+}
+
+// findShare searches a nonce for which the difficulty of the header of the job is in [minDifficulty; maxDifficulty].
+func findShare(t *testing.T, job *Job, extranonce []byte, minDifficulty uint8, maxDifficulty uint8) (int64, uint32) {
+	t.Helper()
+
+	timestamp := job.Timestamp
+	for nonce := uint32(0); nonce < 1<<22; nonce++ {
+		header, _, err := job.BuildBlock(extranonce, timestamp, nonce)
+		if err != nil {
+			t.Fatalf("BuildBlock() returned error: %v", err)
+		}
+		difficulty := HeaderDifficulty(header)
+		if difficulty >= minDifficulty && difficulty <= maxDifficulty {
+			return timestamp, nonce
+		}
+	}
+	t.Fatal("no matching nonce found")
+	return 0, 0
+}
+
+func TestPPLNSAccounting_CreditBlock(t *testing.T) {
+	accounting := NewPPLNSAccounting(3)
+	// The first share drops out of the window
+	for _, address := range []string{"a", "b", "a", "c"} {
+		accounting.AddShare(address)
+	}
+
+	credits := accounting.CreditBlock(common.Hash{1}, 1, 100)
+
+	// 100 / 3 = 33 per share, the remainder goes to the last share
+	expected := map[string]uint64{"a": 33, "b": 33, "c": 34}
+	if len(credits) != len(expected) {
+		t.Fatalf("expected credits %v, got %v", expected, credits)
+	}
+	for address, credit := range expected {
+		if credits[address] != credit {
+			t.Errorf("expected credit %d for %s, got %d", credit, address, credits[address])
+		}
+	}
+
+	blocks := accounting.Blocks()
+	if len(blocks) != 1 || blocks[0].Reward != 100 {
+		t.Fatalf("expected one credited block with reward 100, got %v", blocks)
+	}
+}
+
+func TestPPLNSAccounting_Balances_SkipsUncountedBlocks(t *testing.T) {
+	accounting := NewPPLNSAccounting(2)
+	accounting.AddShare("a")
+	accounting.CreditBlock(common.Hash{1}, 1, 50)
+	accounting.AddShare("b")
+	accounting.CreditBlock(common.Hash{2}, 2, 50)
+
+	balances := accounting.Balances(func(hash common.Hash) bool { return hash == common.Hash{2} })
+
+	if balances["a"] != 25 || balances["b"] != 25 {
+		t.Errorf("expected balances of 25 each, got %v", balances)
+	}
+}
+
+func TestJob_BuildBlock(t *testing.T) {
+	pool, _, _ := createTestPool(t, 1, 1)
+	_, job := pool.Subscribe(&recordingListener{})
+
+	extranonce := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	header, coinbase, err := job.BuildBlock(extranonce, job.Timestamp, 42)
+	if err != nil {
+		t.Fatalf("BuildBlock() returned error: %v", err)
+	}
+
+	if !coinbase.IsCoinbase() {
+		t.Fatal("expected a coinbase transaction")
+	}
+	if !bytes.Contains(coinbase.Inputs[0].Signature, extranonce) {
+		t.Error("expected the extranonce in the coinbase data")
+	}
+	if coinbase.Outputs[0].Value != 100 {
+		t.Errorf("expected coinbase value 100, got %d", coinbase.Outputs[0].Value)
+	}
+
+	b := block.Block{Header: header, Transactions: []transaction.Transaction{coinbase}}
+	if header.MerkleRoot != b.MerkleRoot() {
+		t.Error("expected the merkle root of the block")
+	}
+	if header.Nonce != 42 || header.Timestamp != job.Timestamp {
+		t.Error("expected nonce and timestamp of the header")
+	}
+
+	if _, _, err := job.BuildBlock(extranonce[:4], job.Timestamp, 0); err == nil {
+		t.Error("expected error for extranonce of wrong size")
+	}
+}
+
+func TestPoolService_Subscribe_AssignsUniqueExtranonces(t *testing.T) {
+	pool, _, _ := createTestPool(t, 1, 1)
+
+	first, job := pool.Subscribe(&recordingListener{})
+	second, _ := pool.Subscribe(&recordingListener{})
+
+	if len(first) != Extranonce1Size || len(second) != Extranonce1Size {
+		t.Fatalf("expected extranonces of %d bytes", Extranonce1Size)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("expected unique extranonces")
+	}
+	if job == nil || !job.CleanJobs {
+		t.Error("expected the current job with clean jobs set")
+	}
+}
+
+func TestPoolService_AddJob_NotifiesListeners(t *testing.T) {
+	pool, _, _ := createTestPool(t, 1, 1)
+	listener := &recordingListener{}
+	pool.Subscribe(listener)
+
+	// Same tip, so the jobs of the first template stay valid
+	if err := pool.addJob(jobTemplate{id: 2, height: 1, difficultyTarget: 1, coinbaseValue: 100}); err != nil {
+		t.Fatalf("addJob() returned error: %v", err)
+	}
+	// New tip, all previous jobs are dropped
+	if err := pool.addJob(jobTemplate{id: 3, previousBlockHash: common.Hash{1}, height: 2, difficultyTarget: 1, coinbaseValue: 100}); err != nil {
+		t.Fatalf("addJob() returned error: %v", err)
+	}
+
+	if len(listener.jobs) != 2 {
+		t.Fatalf("expected 2 notified jobs, got %d", len(listener.jobs))
+	}
+	if listener.jobs[0].CleanJobs || !listener.jobs[1].CleanJobs {
+		t.Error("expected clean jobs only for the new tip")
+	}
+	if len(pool.jobs) != 1 {
+		t.Errorf("expected only the job of the new tip, got %d jobs", len(pool.jobs))
+	}
+}
+
+func TestPoolService_Authorize(t *testing.T) {
+	pool, _, _ := createTestPool(t, 1, 1)
+
+	if err := pool.Authorize("address.rig1"); err != nil {
+		t.Errorf("Authorize() returned error: %v", err)
+	}
+	if err := pool.Authorize("invalid.rig1"); !errors.Is(err, ErrInvalidWorker) {
+		t.Errorf("expected ErrInvalidWorker, got %v", err)
+	}
+
+	workers := pool.Workers()
+	if len(workers) != 1 || workers[0].Address != "address" {
+		t.Errorf("expected worker with address, got %v", workers)
+	}
+}
+
+func TestPoolService_Deauthorize(t *testing.T) {
+	pool, _, _ := createTestPool(t, 1, 1)
+	// Two sessions authorize the same worker
+	for range 2 {
+		if err := pool.Authorize("address.rig1"); err != nil {
+			t.Fatalf("Authorize() returned error: %v", err)
+		}
+	}
+
+	pool.Deauthorize("address.rig1")
+	if workers := pool.Workers(); len(workers) != 1 {
+		t.Errorf("expected the worker of the remaining session, got %v", workers)
+	}
+
+	pool.Deauthorize("address.rig1")
+	if workers := pool.Workers(); len(workers) != 0 {
+		t.Errorf("expected the stats to be dropped after the last session, got %v", workers)
+	}
+}
+
+func TestPoolService_SubmitShare(t *testing.T) {
+	pool, templates, _ := createTestPool(t, 4, 30)
+	extranonce1, job := pool.Subscribe(&recordingListener{})
+	if err := pool.Authorize("address.rig1"); err != nil {
+		t.Fatalf("Authorize() returned error: %v", err)
+	}
+	extranonce2 := []byte{0, 0, 0, 1}
+	extranonce := slices.Concat(extranonce1, extranonce2)
+
+	timestamp, nonce := findShare(t, job, extranonce, 4, 29)
+	share := Share{WorkerName: "address.rig1", JobID: job.ID, Extranonce1: extranonce1, Extranonce2: extranonce2, Timestamp: timestamp, Nonce: nonce}
+
+	blockFound, err := pool.SubmitShare(share)
+	if err != nil || blockFound {
+		t.Fatalf("expected accepted share without block, got %t, %v", blockFound, err)
+	}
+	if _, err := pool.SubmitShare(share); !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("expected ErrDuplicateShare, got %v", err)
+	}
+
+	timestamp, nonce = findShare(t, job, extranonce, 0, 3)
+	lowShare := Share{WorkerName: "address.rig1", JobID: job.ID, Extranonce1: extranonce1, Extranonce2: extranonce2, Timestamp: timestamp, Nonce: nonce}
+	if _, err := pool.SubmitShare(lowShare); !errors.Is(err, ErrLowDifficultyShare) {
+		t.Errorf("expected ErrLowDifficultyShare, got %v", err)
+	}
+
+	unknownJob := share
+	unknownJob.JobID = "unknown"
+	if _, err := pool.SubmitShare(unknownJob); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("expected ErrUnknownJob, got %v", err)
+	}
+
+	unauthorized := share
+	unauthorized.WorkerName = "other.rig1"
+	if _, err := pool.SubmitShare(unauthorized); !errors.Is(err, ErrInvalidWorker) {
+		t.Errorf("expected ErrInvalidWorker, got %v", err)
+	}
+
+	workers := pool.Workers()
+	if workers[0].AcceptedShares != 1 || workers[0].RejectedShares != 3 {
+		t.Errorf("expected 1 accepted and 3 rejected shares, got %+v", workers[0])
+	}
+	if len(templates.submitted) != 0 {
+		t.Error("expected no submitted block")
+	}
+}
+
+func TestPoolService_SubmitShare_SubmitsSolvedBlock(t *testing.T) {
+	pool, templates, blockStore := createTestPool(t, 2, 8)
+	extranonce1, job := pool.Subscribe(&recordingListener{})
+	for _, worker := range []string{"alice.rig1", "bob.rig1"} {
+		if err := pool.Authorize(worker); err != nil {
+			t.Fatalf("Authorize() returned error: %v", err)
+		}
+	}
+
+	// bob submits a share, alice solves the block
+	bobExtranonce2 := []byte{0, 0, 0, 1}
+	timestamp, nonce := findShare(t, job, slices.Concat(extranonce1, bobExtranonce2), 2, 7)
+	if _, err := pool.SubmitShare(Share{WorkerName: "bob.rig1", JobID: job.ID, Extranonce1: extranonce1, Extranonce2: bobExtranonce2, Timestamp: timestamp, Nonce: nonce}); err != nil {
+		t.Fatalf("SubmitShare() returned error: %v", err)
+	}
+
+	aliceExtranonce2 := []byte{0, 0, 0, 2}
+	timestamp, nonce = findShare(t, job, slices.Concat(extranonce1, aliceExtranonce2), 8, 255)
+	blockFound, err := pool.SubmitShare(Share{WorkerName: "alice.rig1", JobID: job.ID, Extranonce1: extranonce1, Extranonce2: aliceExtranonce2, Timestamp: timestamp, Nonce: nonce})
+	if err != nil || !blockFound {
+		t.Fatalf("expected found block, got %t, %v", blockFound, err)
+	}
+
+	if len(templates.submitted) != 1 {
+		t.Fatalf("expected one submitted block, got %d", len(templates.submitted))
+	}
+	blocks := pool.Blocks()
+	if len(blocks) != 1 || blocks[0].Credits["alice"] != 50 || blocks[0].Credits["bob"] != 50 {
+		t.Fatalf("expected reward split between alice and bob, got %v", blocks)
+	}
+
+	if len(pool.Balances()) != 0 {
+		t.Error("expected no balances for a block that is not part of the main chain")
+	}
+	blockStore.mainChain[blocks[0].Hash] = true
+	if balances := pool.Balances(); balances["alice"] != 50 || balances["bob"] != 50 {
+		t.Errorf("expected balances of 50 each, got %v", balances)
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"sync"
+)
+
+// CreditedBlock is a block found by the pool with the credits of its reward per payout address.
+type CreditedBlock struct {
+	Hash    common.Hash
+	Height  uint64
+	Reward  uint64
+	Credits map[string]uint64
+}
+
+// PPLNSAccounting splits the rewards of blocks found by the pool among the last N accepted shares (pay per last N shares).
+// Every share in the window earns the same part of the reward for the V$Address of its worker, no matter when the
+// share was submitted relative to the last block. So hopping between pools does not pay off.
+type PPLNSAccounting struct {
+	mu     sync.Mutex
+	window int
+	// shares is a ring buffer of the payout addresses of the last window shares, next is the index of the next share.
+	shares []string
+	next   int
+	blocks []CreditedBlock
+}
+
+// NewPPLNSAccounting creates an accounting splitting block rewards among the given number of last shares.
+func NewPPLNSAccounting(window int) *PPLNSAccounting {
+	return &PPLNSAccounting{
+		window: max(window, 1),
+		shares: make([]string, 0, max(window, 1)),
+	}
+}
+
+// AddShare records an accepted share of a worker paying to the given address.
+func (a *PPLNSAccounting) AddShare(address string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.shares) < a.window {
+		a.shares = append(a.shares, address)
+	} else {
+		a.shares[a.next] = address
+	}
+	a.next = (a.next + 1) % a.window
+}
+
+// CreditBlock splits the reward of a found block among the shares in the window and returns the credits per address.
+// The remainder of the integer division is credited to the address of the last share, which found the block.
+func (a *PPLNSAccounting) CreditBlock(hash common.Hash, height uint64, reward uint64) map[string]uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	credits := make(map[string]uint64)
+	if len(a.shares) == 0 {
+		return credits
+	}
+
+	perShare := reward / uint64(len(a.shares))
+	for _, address := range a.shares {
+		credits[address] += perShare
+	}
+	last := a.shares[(a.next+a.window-1)%a.window]
+	credits[last] += reward - perShare*uint64(len(a.shares))
+
+	a.blocks = append(a.blocks, CreditedBlock{Hash: hash, Height: height, Reward: reward, Credits: credits})
+
+	result := make(map[string]uint64, len(credits))
+	for address, credit := range credits {
+		result[address] = credit
+	}
+	return result
+}
+
+// Blocks returns the blocks found by the pool, oldest first.
+func (a *PPLNSAccounting) Blocks() []CreditedBlock {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	blocks := make([]CreditedBlock, len(a.blocks))
+	copy(blocks, a.blocks)
+	return blocks
+}
+
+// Balances returns the credits per address summed over all found blocks for which countBlock returns true,
+// e.g. the blocks that are still part of the main chain.
+func (a *PPLNSAccounting) Balances(countBlock func(hash common.Hash) bool) map[string]uint64 {
+	balances := make(map[string]uint64)
+	for _, b := range a.Blocks() {
+		if !countBlock(b.Hash) {
+			continue
+		}
+		for address, credit := range b.Credits {
+			balances[address] += credit
+		}
+	}
+	return balances
+}
@@ -0,0 +1,215 @@
+package stratum
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"s3b/vsp-blockchain/p2p-blockchain/pool/core"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// jobBufferSize is the number of jobs buffered for a client that does not keep up, older jobs are dropped.
+const jobBufferSize = 8
+
+var ErrClientClosed = errors.New("connection to pool closed")
+
+// Client is a worker connection to a pool, used by the test client and the tests.
+type Client struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	nextID  uint64
+
+	mu      sync.Mutex
+	pending map[uint64]chan message
+	closed  bool
+
+	jobs            chan core.Job
+	shareDifficulty atomic.Uint32
+	done            chan struct{}
+}
+
+// Dial connects to the pool at the given address, e.g. "localhost:3333".
+func Dial(address string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", address, writeTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to pool: %w", err)
+	}
+
+	c := &Client{
+		conn:    conn,
+		pending: make(map[uint64]chan message),
+		jobs:    make(chan core.Job, jobBufferSize),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// Subscribe subscribes for jobs and returns the extranonce1 assigned by the pool.
+func (c *Client) Subscribe() ([]byte, error) {
+	var result subscribeResult
+	if err := c.call(methodSubscribe, &result); err != nil {
+		return nil, err
+	}
+	if result.Extranonce2Size != core.Extranonce2Size {
+		return nil, fmt.Errorf("unsupported extranonce2 size %d", result.Extranonce2Size)
+	}
+	extranonce1, err := hex.DecodeString(result.Extranonce1)
+	if err != nil || len(extranonce1) != core.Extranonce1Size {
+		return nil, fmt.Errorf("invalid extranonce1 %q", result.Extranonce1)
+	}
+	return extranonce1, nil
+}
+
+// Authorize authorizes a worker, see PoolService.Authorize.
+func (c *Client) Authorize(workerName string) error {
+	var accepted bool
+	if err := c.call(methodAuthorize, &accepted, workerName, ""); err != nil {
+		return err
+	}
+	if !accepted {
+		return fmt.Errorf("worker %s not authorized", workerName)
+	}
+	return nil
+}
+
+// Submit submits a share for a job. Returns an error if the pool rejected the share.
+func (c *Client) Submit(workerName string, jobID string, extranonce2 []byte, timestamp int64, nonce uint32) error {
+	var accepted bool
+	if err := c.call(methodSubmit, &accepted, workerName, jobID, hex.EncodeToString(extranonce2), timestamp, nonce); err != nil {
+		return err
+	}
+	if !accepted {
+		return errors.New("share rejected")
+	}
+	return nil
+}
+
+// Jobs returns the channel of jobs sent by the pool. It is closed when the connection is closed.
+func (c *Client) Jobs() <-chan core.Job {
+	return c.jobs
+}
+
+// ShareDifficulty returns the last share difficulty sent by the pool.
+func (c *Client) ShareDifficulty() uint8 {
+	return uint8(c.shareDifficulty.Load())
+}
+
+// Close closes the connection and waits for the reader to end.
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+// call sends a request and waits for its response, whose result is decoded into result.
+func (c *Client) call(method string, result any, params ...any) error {
+	encodedParams, err := marshalParams(params...)
+	if err != nil {
+		return err
+	}
+
+	response := make(chan message, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = response
+	c.mu.Unlock()
+
+	encoded, err := json.Marshal(message{ID: &id, Method: method, Params: encodedParams})
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = c.conn.Write(append(encoded, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	msg, ok := <-response
+	if !ok {
+		return ErrClientClosed
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	return json.Unmarshal(msg.Result, result)
+}
+
+// readLoop dispatches responses to the pending calls and handles notifications until the connection is closed.
+func (c *Client) readLoop() {
+	defer func() {
+		c.mu.Lock()
+		c.closed = true
+		for id, response := range c.pending {
+			close(response)
+			delete(c.pending, id)
+		}
+		c.mu.Unlock()
+		close(c.jobs)
+		close(c.done)
+	}()
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxMessageSize)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return
+		}
+
+		if msg.ID != nil {
+			c.mu.Lock()
+			response, ok := c.pending[*msg.ID]
+			delete(c.pending, *msg.ID)
+			c.mu.Unlock()
+			if ok {
+				response <- msg
+			}
+			continue
+		}
+		c.handleNotification(msg)
+	}
+}
+
+func (c *Client) handleNotification(msg message) {
+	switch msg.Method {
+	case methodSetDifficulty:
+		var difficulty uint8
+		if unmarshalParams(msg.Params, &difficulty) == nil {
+			c.shareDifficulty.Store(uint32(difficulty))
+		}
+	case methodNotify:
+		var jobMsg jobMessage
+		if unmarshalParams(msg.Params, &jobMsg) != nil {
+			return
+		}
+		job, err := jobMsg.toJob()
+		if err != nil {
+			return
+		}
+		// Drop the oldest job if the worker does not keep up, only the latest jobs are worth working on
+		for {
+			select {
+			case c.jobs <- job:
+				return
+			default:
+				select {
+				case <-c.jobs:
+				default:
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package stratum implements a Stratum-like protocol between the mining pool and its workers.
+// Messages are JSON objects separated by newlines over a plain TCP connection. Workers send requests with an ID
+// (mining.subscribe, mining.authorize, mining.submit), the pool answers with a response carrying the same ID and
+// pushes notifications without an ID (mining.set_difficulty, mining.notify).
+package stratum
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/pool/core"
+)
+
+// Methods of the protocol.
+const (
+	methodSubscribe     = "mining.subscribe"
+	methodAuthorize     = "mining.authorize"
+	methodSubmit        = "mining.submit"
+	methodNotify        = "mining.notify"
+	methodSetDifficulty = "mining.set_difficulty"
+)
+
+// maxMessageSize is the maximum size of a single message, a job with a long merkle branch stays far below.
+const maxMessageSize = 64 * 1024
+
+// Error codes of responses.
+const (
+	errorCodeOther         = 20
+	errorCodeUnknownJob    = 21
+	errorCodeDuplicate     = 22
+	errorCodeLowDifficulty = 23
+	errorCodeUnauthorized  = 24
+	errorCodeNotSubscribed = 25
+)
+
+// message is a request, response or notification.
+type message struct {
+	ID     *uint64           `json:"id"`
+	Method string            `json:"method,omitempty"`
+	Params []json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage   `json:"result,omitempty"`
+	Error  *rpcError         `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("stratum error %d: %s", e.Code, e.Message)
+}
+
+// subscribeResult is the result of mining.subscribe.
+type subscribeResult struct {
+	Extranonce1     string `json:"extranonce1"`
+	Extranonce2Size int    `json:"extranonce2_size"`
+}
+
+// jobMessage is the single parameter of mining.notify.
+type jobMessage struct {
+	JobID             string   `json:"job_id"`
+	PreviousBlockHash string   `json:"prev_hash"`
+	CoinbasePrefix    string   `json:"coinb1"`
+	CoinbaseSuffix    string   `json:"coinb2"`
+	MerkleBranch      []string `json:"merkle_branch"`
+	Height            uint64   `json:"height"`
+	DifficultyTarget  uint8    `json:"difficulty_target"`
+	MinTimestamp      int64    `json:"min_time"`
+	Timestamp         int64    `json:"time"`
+	CleanJobs         bool     `json:"clean_jobs"`
+}
+
+func newJobMessage(job core.Job) jobMessage {
+	branch := make([]string, 0, len(job.MerkleBranch))
+	for _, hash := range job.MerkleBranch {
+		branch = append(branch, hash.String())
+	}
+
+	return jobMessage{
+		JobID:             job.ID,
+		PreviousBlockHash: job.PreviousBlockHash.String(),
+		CoinbasePrefix:    hex.EncodeToString(job.CoinbasePrefix),
+		CoinbaseSuffix:    hex.EncodeToString(job.CoinbaseSuffix),
+		MerkleBranch:      branch,
+		Height:            job.Height,
+		DifficultyTarget:  job.DifficultyTarget,
+		MinTimestamp:      job.MinTimestamp,
+		Timestamp:         job.Timestamp,
+		CleanJobs:         job.CleanJobs,
+	}
+}
+
+func (m jobMessage) toJob() (core.Job, error) {
+	previousBlockHash, err := decodeHash(m.PreviousBlockHash)
+	if err != nil {
+		return core.Job{}, err
+	}
+	prefix, err := hex.DecodeString(m.CoinbasePrefix)
+	if err != nil {
+		return core.Job{}, fmt.Errorf("invalid coinb1: %w", err)
+	}
+	suffix, err := hex.DecodeString(m.CoinbaseSuffix)
+	if err != nil {
+		return core.Job{}, fmt.Errorf("invalid coinb2: %w", err)
+	}
+
+	branch := make([]common.Hash, 0, len(m.MerkleBranch))
+	for _, raw := range m.MerkleBranch {
+		hash, err := decodeHash(raw)
+		if err != nil {
+			return core.Job{}, err
+		}
+		branch = append(branch, hash)
+	}
+
+	return core.Job{
+		ID:                m.JobID,
+		PreviousBlockHash: previousBlockHash,
+		Height:            m.Height,
+		DifficultyTarget:  m.DifficultyTarget,
+		MinTimestamp:      m.MinTimestamp,
+		Timestamp:         m.Timestamp,
+		CoinbasePrefix:    prefix,
+		CoinbaseSuffix:    suffix,
+		MerkleBranch:      branch,
+		CleanJobs:         m.CleanJobs,
+	}, nil
+}
+
+func decodeHash(raw string) (common.Hash, error) {
+	var hash common.Hash
+	decoded, err := hex.DecodeString(raw)
+	if err != nil || len(decoded) != common.HashSize {
+		return hash, fmt.Errorf("invalid hash %q", raw)
+	}
+	copy(hash[:], decoded)
+	return hash, nil
+}
+
+// marshalParams encodes the values as the parameters of a request or notification.
+func marshalParams(values ...any) ([]json.RawMessage, error) {
+	params := make([]json.RawMessage, 0, len(values))
+	for _, value := range values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, raw)
+	}
+	return params, nil
+}
+
+// unmarshalParams decodes the parameters of a request or notification into the given pointers.
+func unmarshalParams(params []json.RawMessage, targets ...any) error {
+	if len(params) < len(targets) {
+		return fmt.Errorf("expected %d parameters, got %d", len(targets), len(params))
+	}
+	for i, target := range targets {
+		if err := json.Unmarshal(params[i], target); err != nil {
+			return fmt.Errorf("invalid parameter %d: %w", i, err)
+		}
+	}
+	return nil
+}
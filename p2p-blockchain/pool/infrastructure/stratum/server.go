@@ -0,0 +1,277 @@
+package stratum
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"s3b/vsp-blockchain/p2p-blockchain/pool/core"
+	"sync"
+	"time"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+const (
+	// writeTimeout is the time a worker has to receive a message before its connection is closed.
+	writeTimeout = 10 * time.Second
+	// maxWorkersPerSession is the number of workers a single connection may authorize.
+	maxWorkersPerSession = 16
+)
+
+// Server accepts connections of workers and serves them jobs of the pool.
+type Server struct {
+	pool     *core.PoolService
+	listener net.Listener
+
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a new Stratum server for the pool.
+func NewServer(pool *core.PoolService) *Server {
+	return &Server{
+		pool:     pool,
+		sessions: make(map[*session]struct{}),
+	}
+}
+
+// Start starts listening on the given port of all interfaces and accepts workers in a goroutine.
+// Workers usually run on other machines, so the server is not bound to localhost.
+func (s *Server) Start(port uint16) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	s.listener = listener
+
+	s.wg.Go(s.acceptLoop)
+	return nil
+}
+
+// ListeningEndpoint returns the address the server is listening on.
+func (s *Server) ListeningEndpoint() (netip.AddrPort, error) {
+	if s.listener == nil {
+		return netip.AddrPort{}, errors.New("server not started")
+	}
+	return netip.ParseAddrPort(s.listener.Addr().String())
+}
+
+// Stop closes the listener and all connections of workers and waits for their goroutines to end.
+func (s *Server) Stop() {
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+
+	s.mu.Lock()
+	for sess := range s.sessions {
+		_ = sess.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Warnf("[pool] Failed to accept worker connection: %v", err)
+			}
+			return
+		}
+
+		sess := &session{conn: conn, pool: s.pool, authorized: make(map[string]struct{})}
+		s.mu.Lock()
+		s.sessions[sess] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Go(func() {
+			sess.serve()
+			s.mu.Lock()
+			delete(s.sessions, sess)
+			s.mu.Unlock()
+		})
+	}
+}
+
+// session is the connection of a single worker process. A process may authorize several workers (e.g. one per rig).
+type session struct {
+	conn    net.Conn
+	pool    *core.PoolService
+	writeMu sync.Mutex
+
+	// extranonce1 is assigned by mining.subscribe, nil before. Only accessed by the serving goroutine.
+	extranonce1 []byte
+	// pendingJob is the current job at the time of mining.subscribe, sent after the response.
+	pendingJob *core.Job
+	authorized map[string]struct{}
+}
+
+// serve handles the requests of the worker until the connection is closed.
+func (s *session) serve() {
+	remote := s.conn.RemoteAddr()
+	logger.Debugf("[pool] Worker connected from %v", remote)
+	defer func() {
+		s.pool.Unsubscribe(s)
+		for workerName := range s.authorized {
+			s.pool.Deauthorize(workerName)
+		}
+		_ = s.conn.Close()
+		logger.Debugf("[pool] Worker from %v disconnected", remote)
+	}()
+
+	scanner := bufio.NewScanner(s.conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxMessageSize)
+	for scanner.Scan() {
+		var request message
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil || request.ID == nil {
+			logger.Debugf("[pool] Closing connection of %v after malformed request", remote)
+			return
+		}
+
+		result, rpcErr := s.handle(request)
+		if err := s.respond(request.ID, result, rpcErr); err != nil {
+			return
+		}
+		if request.Method == methodSubscribe && rpcErr == nil {
+			s.afterSubscribe()
+		}
+	}
+}
+
+// handle executes a request and returns its result or error.
+func (s *session) handle(request message) (any, *rpcError) {
+	switch request.Method {
+	case methodSubscribe:
+		if s.extranonce1 != nil {
+			return nil, &rpcError{Code: errorCodeOther, Message: "already subscribed"}
+		}
+		s.extranonce1, s.pendingJob = s.pool.Subscribe(s)
+		return subscribeResult{Extranonce1: hex.EncodeToString(s.extranonce1), Extranonce2Size: core.Extranonce2Size}, nil
+
+	case methodAuthorize:
+		var workerName, password string
+		if err := unmarshalParams(request.Params, &workerName); err != nil {
+			return nil, &rpcError{Code: errorCodeOther, Message: err.Error()}
+		}
+		_ = unmarshalParams(request.Params[1:], &password) // the password is not checked
+		if _, ok := s.authorized[workerName]; ok {
+			return true, nil
+		}
+		if len(s.authorized) >= maxWorkersPerSession {
+			return false, &rpcError{Code: errorCodeUnauthorized, Message: fmt.Sprintf("at most %d workers per connection", maxWorkersPerSession)}
+		}
+		if err := s.pool.Authorize(workerName); err != nil {
+			return false, &rpcError{Code: errorCodeUnauthorized, Message: err.Error()}
+		}
+		s.authorized[workerName] = struct{}{}
+		return true, nil
+
+	case methodSubmit:
+		return s.handleSubmit(request.Params)
+
+	default:
+		return nil, &rpcError{Code: errorCodeOther, Message: fmt.Sprintf("unknown method %q", request.Method)}
+	}
+}
+
+// handleSubmit validates a share of an authorized worker of the session, see PoolService.SubmitShare.
+func (s *session) handleSubmit(params []json.RawMessage) (any, *rpcError) {
+	var workerName, jobID, extranonce2 string
+	var timestamp int64
+	var nonce uint32
+	if err := unmarshalParams(params, &workerName, &jobID, &extranonce2, &timestamp, &nonce); err != nil {
+		return nil, &rpcError{Code: errorCodeOther, Message: err.Error()}
+	}
+	if s.extranonce1 == nil {
+		return nil, &rpcError{Code: errorCodeNotSubscribed, Message: "not subscribed"}
+	}
+	if _, ok := s.authorized[workerName]; !ok {
+		return nil, &rpcError{Code: errorCodeUnauthorized, Message: "worker not authorized"}
+	}
+	decodedExtranonce2, err := hex.DecodeString(extranonce2)
+	if err != nil {
+		return nil, &rpcError{Code: errorCodeOther, Message: "invalid extranonce2"}
+	}
+
+	_, err = s.pool.SubmitShare(core.Share{
+		WorkerName:  workerName,
+		JobID:       jobID,
+		Extranonce1: s.extranonce1,
+		Extranonce2: decodedExtranonce2,
+		Timestamp:   timestamp,
+		Nonce:       nonce,
+	})
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, core.ErrUnknownJob):
+		return false, &rpcError{Code: errorCodeUnknownJob, Message: err.Error()}
+	case errors.Is(err, core.ErrDuplicateShare):
+		return false, &rpcError{Code: errorCodeDuplicate, Message: err.Error()}
+	case errors.Is(err, core.ErrLowDifficultyShare):
+		return false, &rpcError{Code: errorCodeLowDifficulty, Message: err.Error()}
+	default:
+		return false, &rpcError{Code: errorCodeOther, Message: err.Error()}
+	}
+}
+
+// afterSubscribe sends the share difficulty and the current job after the response of mining.subscribe.
+// Later jobs arrive through NotifyJob.
+func (s *session) afterSubscribe() {
+	if err := s.notify(methodSetDifficulty, s.pool.ShareDifficulty()); err != nil {
+		return
+	}
+	if job := s.pendingJob; job != nil {
+		s.pendingJob = nil
+		s.NotifyJob(*job)
+	}
+}
+
+// NotifyJob sends a new job to the worker. A worker that cannot receive the job is disconnected.
+func (s *session) NotifyJob(job core.Job) {
+	if err := s.notify(methodNotify, newJobMessage(job)); err != nil {
+		logger.Debugf("[pool] Failed to send job to worker %v: %v", s.conn.RemoteAddr(), err)
+		_ = s.conn.Close()
+	}
+}
+
+func (s *session) respond(id *uint64, result any, rpcErr *rpcError) error {
+	response := message{ID: id, Error: rpcErr}
+	if result != nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		response.Result = raw
+	}
+	return s.write(response)
+}
+
+func (s *session) notify(method string, params ...any) error {
+	encoded, err := marshalParams(params...)
+	if err != nil {
+		return err
+	}
+	return s.write(message{Method: method, Params: encoded})
+}
+
+func (s *session) write(msg message) error {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = s.conn.Write(append(encoded, '\n'))
+	return err
+}
@@ -0,0 +1,182 @@
+package stratum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/block"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+	minerCore "s3b/vsp-blockchain/p2p-blockchain/miner/core"
+	"s3b/vsp-blockchain/p2p-blockchain/pool/core"
+	"slices"
+	"testing"
+	"time"
+)
+
+// singleTemplateAPI returns one template and then waits for the tip to change, which never happens
+type singleTemplateAPI struct{}
+
+func (singleTemplateAPI) GetBlockTemplate(ctx context.Context, longPollID string) (minerCore.BlockTemplate, error) {
+	if longPollID != "" {
+		<-ctx.Done()
+		return minerCore.BlockTemplate{}, ctx.Err()
+	}
+	return minerCore.BlockTemplate{
+		ID:               1,
+		Height:           1,
+		DifficultyTarget: 255,
+		MinTimestamp:     time.Now().Unix() - 60,
+		CurrentTimestamp: time.Now().Unix(),
+		CoinbaseValue:    100,
+		LongPollID:       "tip",
+	}, nil
+}
+
+func (singleTemplateAPI) SubmitBlock(uint64, block.BlockHeader, transaction.Transaction) (common.Hash, error) {
+	return common.Hash{}, errors.New("not expected")
+}
+
+// acceptingDecoder accepts every address
+type acceptingDecoder struct{}
+
+func (acceptingDecoder) VSAddressToPubKeyHash(string) ([common.PublicKeyHashSize]byte, error) {
+	return [common.PublicKeyHashSize]byte{}, nil
+}
+
+func startTestServer(t *testing.T) (*core.PoolService, string) {
+	t.Helper()
+
+	pool := core.NewPoolService(singleTemplateAPI{}, nil, acceptingDecoder{}, core.PoolConfig{
+		ShareDifficulty: 4,
+		PPLNSWindow:     10,
+		Payouts:         []transaction.CoinbasePayout{{Percent: 100}},
+	})
+	pool.Start()
+	t.Cleanup(pool.Stop)
+
+	server := NewServer(pool)
+	if err := server.Start(0); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	t.Cleanup(server.Stop)
+
+	endpoint, err := server.ListeningEndpoint()
+	if err != nil {
+		t.Fatalf("ListeningEndpoint() returned error: %v", err)
+	}
+	return pool, fmt.Sprintf("localhost:%d", endpoint.Port())
+}
+
+func TestServer_SubmitShare(t *testing.T) {
+	pool, address := startTestServer(t)
+
+	client, err := Dial(address)
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	extranonce1, err := client.Subscribe()
+	if err != nil {
+		t.Fatalf("Subscribe() returned error: %v", err)
+	}
+	if err := client.Authorize("address.rig1"); err != nil {
+		t.Fatalf("Authorize() returned error: %v", err)
+	}
+
+	var job core.Job
+	select {
+	case job = <-client.Jobs():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a job after subscribing")
+	}
+	if client.ShareDifficulty() != 4 {
+		t.Errorf("expected share difficulty 4, got %d", client.ShareDifficulty())
+	}
+
+	extranonce2 := []byte{0, 0, 0, 1}
+	extranonce := slices.Concat(extranonce1, extranonce2)
+	var nonce uint32
+	for ; ; nonce++ {
+		header, _, err := job.BuildBlock(extranonce, job.Timestamp, nonce)
+		if err != nil {
+			t.Fatalf("BuildBlock() returned error: %v", err)
+		}
+		if core.HeaderDifficulty(header) >= client.ShareDifficulty() {
+			break
+		}
+	}
+
+	if err := client.Submit("address.rig1", job.ID, extranonce2, job.Timestamp, nonce); err != nil {
+		t.Fatalf("Submit() returned error: %v", err)
+	}
+
+	err = client.Submit("address.rig1", job.ID, extranonce2, job.Timestamp, nonce)
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != errorCodeDuplicate {
+		t.Errorf("expected duplicate share error, got %v", err)
+	}
+
+	err = client.Submit("other.rig1", job.ID, extranonce2, job.Timestamp, nonce)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != errorCodeUnauthorized {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+
+	workers := pool.Workers()
+	if len(workers) != 1 || workers[0].AcceptedShares != 1 || workers[0].RejectedShares != 1 {
+		t.Errorf("expected 1 accepted and 1 rejected share, got %+v", workers)
+	}
+}
+
+func TestServer_SubmitWithoutSubscribe(t *testing.T) {
+	_, address := startTestServer(t)
+
+	client, err := Dial(address)
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	err = client.Submit("address.rig1", "1", []byte{0, 0, 0, 1}, time.Now().Unix(), 0)
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != errorCodeNotSubscribed {
+		t.Errorf("expected not subscribed error, got %v", err)
+	}
+}
+
+func TestServer_LimitsAndDropsWorkersOfSession(t *testing.T) {
+	pool, address := startTestServer(t)
+
+	client, err := Dial(address)
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+
+	for i := range maxWorkersPerSession {
+		if err := client.Authorize(fmt.Sprintf("address.rig%d", i)); err != nil {
+			t.Fatalf("Authorize() returned error: %v", err)
+		}
+	}
+	if err := client.Authorize("address.rig0"); err != nil {
+		t.Errorf("Authorize() of an authorized worker returned error: %v", err)
+	}
+	err = client.Authorize("address.oneTooMany")
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != errorCodeUnauthorized {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+	if workers := pool.Workers(); len(workers) != maxWorkersPerSession {
+		t.Errorf("expected %d workers, got %d", maxWorkersPerSession, len(workers))
+	}
+
+	_ = client.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pool.Workers()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the workers to be dropped after the disconnect, got %d", len(pool.Workers()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"encoding/hex"
 
 	blockapi "s3b/vsp-blockchain/p2p-blockchain/blockchain/api"
@@ -15,6 +16,9 @@ import (
 type HistoryAPI interface {
 	// GetHistory returns the transaction history for a given V$Address.
 	GetHistory(vsAddress string) konto.HistoryResult
+	// GetHistoryByData returns all main chain transactions with a data output containing the given data.
+	// Sent and Received of the entries are always zero.
+	GetHistoryByData(data []byte) konto.HistoryResult
 }
 
 // HistoryAPIImpl implements HistoryAPI using the BlockStore.
@@ -50,6 +54,48 @@ func (api *HistoryAPIImpl) GetHistory(vsAddress string) konto.HistoryResult {
 	}
 }
 
+// GetHistoryByData implements HistoryAPI.GetHistoryByData.
+func (api *HistoryAPIImpl) GetHistoryByData(data []byte) konto.HistoryResult {
+	if len(data) == 0 || len(data) > transaction.MaxDataSize {
+		return konto.HistoryResult{
+			Success:      false,
+			ErrorMessage: "invalid data length",
+		}
+	}
+
+	var result []konto.TransactionEntry
+	for _, blockWithMeta := range api.blockStore.GetAllBlocksWithMetadata() {
+		if !blockWithMeta.IsMainChain {
+			continue
+		}
+
+		for _, tx := range blockWithMeta.Block.Transactions {
+			if containsData(tx, data) {
+				txID := tx.TransactionId()
+				result = append(result, konto.TransactionEntry{
+					TransactionID: hex.EncodeToString(txID[:]),
+					BlockHeight:   blockWithMeta.Height,
+				})
+			}
+		}
+	}
+
+	return konto.HistoryResult{
+		Success:      true,
+		Transactions: result,
+	}
+}
+
+// containsData checks if one of the data outputs of the transaction contains the given data.
+func containsData(tx transaction.Transaction, data []byte) bool {
+	for _, output := range tx.Outputs {
+		if output.Type == transaction.OutputTypeData && bytes.Contains(output.Data, data) {
+			return true
+		}
+	}
+	return false
+}
+
 func (api *HistoryAPIImpl) validateAddress(vsAddress string) ([]byte, konto.HistoryResult, bool) {
 	// Decode the V$Address to get the public key hash
 	pubKeyHashBytes, version, err := api.keyDecoder.Base58CheckToBytes(vsAddress)
@@ -60,8 +106,8 @@ func (api *HistoryAPIImpl) validateAddress(vsAddress string) ([]byte, konto.Hist
 		}, true
 	}
 
-	// V$Address uses version 0x00
-	if version != 0x00 {
+	// V$Address uses version 0x00, multisig addresses use their own version byte
+	if version != keys.VSAddressVersion && version != keys.MultisigAddressVersion {
 		return pubKeyHashBytes, konto.HistoryResult{
 			Success:      false,
 			ErrorMessage: "invalid V$Address version byte",
@@ -129,15 +175,14 @@ func (api *HistoryAPIImpl) buildTransactionIndex(allBlocks []block.BlockWithMeta
 func (api *HistoryAPIImpl) isAddressInvolved(tx transaction.Transaction, pubKeyHash transaction.PubKeyHash) bool {
 	// Check outputs (receiving)
 	for _, output := range tx.Outputs {
-		if output.PubKeyHash == pubKeyHash {
+		if output.AddressHash() == pubKeyHash {
 			return true
 		}
 	}
 
-	// Check inputs (sending) - the sender's pubkey hash can be derived from the public key in the input
+	// Check inputs (sending) - the sender's pubkey hash can be derived from the public key or the redeem script in the input
 	for _, input := range tx.Inputs {
-		inputPubKeyHash := transaction.Hash160(input.PubKey)
-		if inputPubKeyHash == pubKeyHash {
+		if input.AddressHash() == pubKeyHash {
 			return true
 		}
 	}
@@ -152,15 +197,14 @@ func (api *HistoryAPIImpl) createTransactionEntry(tx transaction.Transaction, bl
 
 	// Calculate received amount (from outputs to this address)
 	for _, output := range tx.Outputs {
-		if output.PubKeyHash == pubKeyHash {
+		if output.AddressHash() == pubKeyHash {
 			received += output.Value
 		}
 	}
 
 	// Calculate sent amount by looking up the referenced UTXOs
 	for _, input := range tx.Inputs {
-		inputPubKeyHash := transaction.Hash160(input.PubKey)
-		if inputPubKeyHash == pubKeyHash {
+		if input.AddressHash() == pubKeyHash {
 			// Look up the previous transaction to get the actual value spent
 			spentValue := api.lookupPreviousOutputValue(input.PrevTxID, input.OutputIndex, txIndex)
 			sent += spentValue
@@ -16,6 +16,9 @@ type KeyGeneratorApi interface {
 
 	// GetKeysetFromWIF Gets the complete keyset from the WIF encoded private key
 	GetKeysetFromWIF(privateKeyWIF string) (common.Keyset, error)
+
+	// GetMultisigAddress Gets the multisig address that requires signatures of `required` of the given public keys
+	GetMultisigAddress(required int, publicKeys [][common.PublicKeySize]byte) (common.MultisigAddress, error)
 }
 
 type KeyGeneratorApiImpl struct {
@@ -39,3 +42,7 @@ func (k *KeyGeneratorApiImpl) GetKeyset(privateKey [common.PrivateKeySize]byte)
 func (k *KeyGeneratorApiImpl) GetKeysetFromWIF(privateKeyWIF string) (common.Keyset, error) {
 	return k.keyGenerator.GetKeysetFromWIF(privateKeyWIF)
 }
+
+func (k *KeyGeneratorApiImpl) GetMultisigAddress(required int, publicKeys [][common.PublicKeySize]byte) (common.MultisigAddress, error) {
+	return k.keyGenerator.GetMultisigAddress(required, publicKeys)
+}
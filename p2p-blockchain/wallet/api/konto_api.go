@@ -52,14 +52,17 @@ func (api *KontoAPIImpl) GetAssets(vsAddress string) konto.AssetsResult {
 		}
 	}
 
-	return api.handleSuccess(utxos)
+	// Outputs are spent in the next block at the earliest
+	spendHeight := api.blockStore.GetMainChainHeight() + 1
+	return api.handleSuccess(utxos, spendHeight)
 }
 
-func (api *KontoAPIImpl) handleSuccess(utxos []transaction.UTXO) konto.AssetsResult {
+func (api *KontoAPIImpl) handleSuccess(utxos []transaction.UTXO, spendHeight uint64) konto.AssetsResult {
 	assets := make([]konto.Asset, 0, len(utxos))
 	for _, utxo := range utxos {
 		assets = append(assets, konto.Asset{
-			Value: utxo.Output.Value,
+			Value:    utxo.Output.Value,
+			Immature: !utxo.IsSpendableAt(spendHeight),
 		})
 	}
 
@@ -77,8 +80,8 @@ func (api *KontoAPIImpl) validatePubKeyHash(err error, version byte, pubKeyHashB
 		}, false
 	}
 
-	// V$Address uses version 0x00
-	if version != 0x00 {
+	// V$Address uses version 0x00, multisig addresses use their own version byte
+	if version != keys.VSAddressVersion && version != keys.MultisigAddressVersion {
 		return konto.AssetsResult{
 			Success:      false,
 			ErrorMessage: "invalid V$Address version byte",
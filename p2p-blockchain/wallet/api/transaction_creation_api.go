@@ -16,10 +16,58 @@ type TransactionCreationAPI interface {
 	//   - recipientVSAddress: The recipient's V$Address (Base58Check encoded public key hash)
 	//   - amount: The amount of V$Goin to transfer (must be >= 1)
 	//   - senderPrivateKeyWIF: The sender's private key in WIF format (Base58Check encoded)
+	//   - replaceable: Whether the transaction signals replaceability, so its fee can be bumped later with BumpFee
 	//
 	// Returns:
 	//   - TransactionResult containing success status, transaction ID, and any error details
-	CreateTransaction(recipientVSAddress string, amount uint64, senderPrivateKeyWIF string) common.TransactionResult
+	CreateTransaction(recipientVSAddress string, amount uint64, senderPrivateKeyWIF string, replaceable bool) common.TransactionResult
+
+	// BumpFee replaces a pending replaceable transaction of the sender with one paying a higher fee (replace-by-fee).
+	// The replacement pays the same recipients, the higher fee is taken from the change. It is broadcast to the network.
+	//
+	// Parameters:
+	//   - transactionIdHex: The hex encoded ID of the pending transaction
+	//   - fee: The new absolute fee in V$Goin, must be higher than the fee of the pending transaction
+	//   - senderPrivateKeyWIF: The sender's private key in WIF format (Base58Check encoded)
+	//
+	// Returns:
+	//   - TransactionResult containing success status, the ID of the replacement, and any error details
+	BumpFee(transactionIdHex string, fee uint64, senderPrivateKeyWIF string) common.TransactionResult
+
+	// CreateMultisigTransaction creates a transaction that spends from a multisig address and signs it with the first co-signer's key.
+	// The transaction is broadcast as soon as it has enough signatures, otherwise it has to be co-signed with CoSignTransaction.
+	//
+	// Parameters:
+	//   - redeemScriptHex: The hex encoded multisig script of the address to spend from (see common.MultisigAddress)
+	//   - recipientAddress: The recipient's V$Address or multisig address
+	//   - amount: The amount of V$Goin to transfer (must be >= 1)
+	//   - signerPrivateKeyWIF: The private key of one of the co-signers in WIF format
+	//
+	// Returns:
+	//   - MultisigTransactionResult containing the (partially) signed transaction and whether it is complete
+	CreateMultisigTransaction(redeemScriptHex string, recipientAddress string, amount uint64, signerPrivateKeyWIF string) common.MultisigTransactionResult
+
+	// CoSignTransaction adds the signature of another co-signer to a partially signed multisig transaction.
+	// The transaction is broadcast as soon as it has enough signatures.
+	//
+	// Parameters:
+	//   - serializedTransactionHex: The hex encoded transaction returned by CreateMultisigTransaction or a previous CoSignTransaction
+	//   - signerPrivateKeyWIF: The private key of the co-signer in WIF format
+	//
+	// Returns:
+	//   - MultisigTransactionResult containing the (partially) signed transaction and whether it is complete
+	CoSignTransaction(serializedTransactionHex string, signerPrivateKeyWIF string) common.MultisigTransactionResult
+
+	// CreateDataTransaction creates and broadcasts a transaction that anchors arbitrary data (e.g. a document hash) in the blockchain.
+	// The data is stored in an unspendable data output, the sender only pays the transaction fee.
+	//
+	// Parameters:
+	//   - data: The data to anchor, 1 to transaction.MaxDataSize bytes
+	//   - senderPrivateKeyWIF: The sender's private key in WIF format (Base58Check encoded)
+	//
+	// Returns:
+	//   - TransactionResult containing success status, transaction ID, and any error details
+	CreateDataTransaction(data []byte, senderPrivateKeyWIF string) common.TransactionResult
 }
 
 // TransactionCreationAPIImpl implements TransactionCreationAPI using the core TransactionCreationService.
@@ -35,6 +83,26 @@ func NewTransactionCreationAPIImpl(transactionService *core.TransactionCreationS
 }
 
 // CreateTransaction implements TransactionCreationAPI.CreateTransaction.
-func (api *TransactionCreationAPIImpl) CreateTransaction(recipientVSAddress string, amount uint64, senderPrivateKeyWIF string) common.TransactionResult {
-	return api.transactionService.CreateTransaction(recipientVSAddress, amount, senderPrivateKeyWIF)
+func (api *TransactionCreationAPIImpl) CreateTransaction(recipientVSAddress string, amount uint64, senderPrivateKeyWIF string, replaceable bool) common.TransactionResult {
+	return api.transactionService.CreateTransaction(recipientVSAddress, amount, senderPrivateKeyWIF, replaceable)
+}
+
+// BumpFee implements TransactionCreationAPI.BumpFee.
+func (api *TransactionCreationAPIImpl) BumpFee(transactionIdHex string, fee uint64, senderPrivateKeyWIF string) common.TransactionResult {
+	return api.transactionService.BumpFee(transactionIdHex, fee, senderPrivateKeyWIF)
+}
+
+// CreateMultisigTransaction implements TransactionCreationAPI.CreateMultisigTransaction.
+func (api *TransactionCreationAPIImpl) CreateMultisigTransaction(redeemScriptHex string, recipientAddress string, amount uint64, signerPrivateKeyWIF string) common.MultisigTransactionResult {
+	return api.transactionService.CreateMultisigTransaction(redeemScriptHex, recipientAddress, amount, signerPrivateKeyWIF)
+}
+
+// CoSignTransaction implements TransactionCreationAPI.CoSignTransaction.
+func (api *TransactionCreationAPIImpl) CoSignTransaction(serializedTransactionHex string, signerPrivateKeyWIF string) common.MultisigTransactionResult {
+	return api.transactionService.CoSignTransaction(serializedTransactionHex, signerPrivateKeyWIF)
+}
+
+// CreateDataTransaction implements TransactionCreationAPI.CreateDataTransaction.
+func (api *TransactionCreationAPIImpl) CreateDataTransaction(data []byte, senderPrivateKeyWIF string) common.TransactionResult {
+	return api.transactionService.CreateDataTransaction(data, senderPrivateKeyWIF)
 }
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// BumpFee replaces a pending transaction of the sender with a transaction paying the higher fee (replace-by-fee).
+// The replacement spends the same UTXOs and pays the same recipients, the higher fee is taken from the change.
+// The pending transaction must signal replaceability, see CreateTransaction.
+// The replacement evicts the pending transaction and its descendants from the mempool and is broadcast like a new transaction.
+func (s *TransactionCreationService) BumpFee(transactionIdHex string, fee uint64, senderPrivateKeyWIF string) transaction.TransactionResult {
+	txId, err := decodeTransactionId(transactionIdHex)
+	if err != nil {
+		return s.handleInvalidFeeBump(err)
+	}
+
+	original, ok := s.mempoolAPI.GetTransaction(txId)
+	if !ok {
+		return s.handleInvalidFeeBump(fmt.Errorf("transaction %s is not pending in the mempool", transactionIdHex))
+	}
+	if !original.SignalsReplaceability() {
+		return s.handleInvalidFeeBump(fmt.Errorf("transaction %s does not signal replaceability", transactionIdHex))
+	}
+
+	keyset, err := s.keyGenerator.GetKeysetFromWIF(senderPrivateKeyWIF)
+	if err != nil {
+		return s.handleInvalidPrivateKey(err)
+	}
+
+	senderPubKeyHash, err := s.decodeVSAddress(keyset.VSAddress)
+	if err != nil {
+		return s.handleInvalidPrivateKey(err)
+	}
+
+	mainChainTip := s.blockStore.GetMainChainTip()
+	utxos, err := s.utxoAPI.GetUtxosByPubKeyHashFromBlock(senderPubKeyHash, mainChainTip.Hash())
+	if err != nil {
+		return s.handleInsufficientFunds(err)
+	}
+
+	// The UTXOs spent by the pending transaction are available again for the replacement
+	utxos = s.mempoolAPI.ApplyToUtxosReplacing(utxos, senderPubKeyHash, txId)
+	utxos = spendableUtxos(utxos, s.blockStore.GetMainChainHeight()+1)
+
+	privKey := transaction.PrivateKey(keyset.PrivateKey)
+	tx, err := transaction.NewReplacementTransaction(&original, utxos, fee, privKey)
+	if err != nil {
+		if errors.Is(err, transaction.ErrReplacementFeeTooLow) || errors.Is(err, transaction.ErrOriginalUTXONotFound) {
+			return s.handleInvalidFeeBump(err)
+		}
+		return s.handleTransactionCreationError(err)
+	}
+
+	logger.Infof("[wallet] Replacing transaction %s with a fee of %d", transactionIdHex, fee)
+	return s.handleSuccess(tx)
+}
+
+// decodeTransactionId decodes a hex encoded transaction ID.
+func decodeTransactionId(transactionIdHex string) (transaction.TransactionID, error) {
+	data, err := hex.DecodeString(transactionIdHex)
+	if err != nil {
+		return transaction.TransactionID{}, fmt.Errorf("transaction ID is not hex encoded: %w", err)
+	}
+	if len(data) != len(transaction.TransactionID{}) {
+		return transaction.TransactionID{}, fmt.Errorf("transaction ID must be %d bytes long, got %d", len(transaction.TransactionID{}), len(data))
+	}
+	return transaction.TransactionID(data), nil
+}
+
+func (s *TransactionCreationService) handleInvalidFeeBump(err error) transaction.TransactionResult {
+	logger.Warnf("[wallet] Failed to bump the fee of a transaction: %v", err)
+	return transaction.TransactionResult{
+		Success:      false,
+		ErrorCode:    transaction.ErrorCodeValidationFailed,
+		ErrorMessage: fmt.Sprintf("Failed to bump the fee: %v", err),
+	}
+}
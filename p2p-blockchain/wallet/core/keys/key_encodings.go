@@ -10,16 +10,32 @@ import (
 	"github.com/akamensky/base58"
 )
 
+// Base58Check version bytes of the encoded keys and addresses
+const (
+	// VSAddressVersion is the version byte of a V$Address, the hash of a single public key
+	VSAddressVersion byte = 0x00
+	// MultisigAddressVersion is the version byte of a multisig address, the hash of an M-of-N multisig script
+	MultisigAddressVersion byte = 0x05
+	// WifVersion is the version byte of a WIF encoded private key
+	WifVersion byte = 0x80
+)
+
 // KeyEncoder Encodes keys to the most common formats
 type KeyEncoder interface {
 	PrivateKeyToWif(privateKey [common.PrivateKeySize]byte) string
 	BytesToBase58Check(bytes []byte, version byte) string
+	// ScriptHashToMultisigAddress Encodes the hash of a multisig script to a multisig address
+	ScriptHashToMultisigAddress(scriptHash [common.PublicKeyHashSize]byte) string
 }
 
 // KeyDecoder Decodes keys from the most common formats
 type KeyDecoder interface {
 	WifToPrivateKey(wif string) ([common.PrivateKeySize]byte, error)
 	Base58CheckToBytes(input string) ([]byte, byte, error)
+	// MultisigAddressToScriptHash Decodes a multisig address to the hash of the multisig script
+	MultisigAddressToScriptHash(address string) ([common.PublicKeyHashSize]byte, error)
+	// VSAddressToPubKeyHash Decodes a V$Address to the hash of the public key
+	VSAddressToPubKeyHash(address string) ([common.PublicKeyHashSize]byte, error)
 }
 
 // KeyEncodingsImpl Implements KeyEncoder and KeyDecoder
@@ -31,7 +47,11 @@ func NewKeyEncodingsImpl() *KeyEncodingsImpl {
 }
 
 func (keyEncodings *KeyEncodingsImpl) PrivateKeyToWif(privateKey [common.PrivateKeySize]byte) string {
-	return keyEncodings.BytesToBase58Check(privateKey[:], 0x80)
+	return keyEncodings.BytesToBase58Check(privateKey[:], WifVersion)
+}
+
+func (keyEncodings *KeyEncodingsImpl) ScriptHashToMultisigAddress(scriptHash [common.PublicKeyHashSize]byte) string {
+	return keyEncodings.BytesToBase58Check(scriptHash[:], MultisigAddressVersion)
 }
 
 func (keyEncodings *KeyEncodingsImpl) BytesToBase58Check(bytes []byte, version byte) string {
@@ -68,13 +88,41 @@ func (keyEncodings *KeyEncodingsImpl) WifToPrivateKey(wif string) ([common.Priva
 		return [common.PrivateKeySize]byte{}, fmt.Errorf("the wif could not be decoded: %w", err)
 	}
 
-	if version != 0x80 || len(bytes) != common.PrivateKeySize {
+	if version != WifVersion || len(bytes) != common.PrivateKeySize {
 		return [common.PrivateKeySize]byte{}, fmt.Errorf("the Base58Check version byte %x dosent match the required version 0x80 for a WIF", version)
 	}
 
 	return [common.PrivateKeySize]byte(bytes), nil
 }
 
+func (keyEncodings *KeyEncodingsImpl) MultisigAddressToScriptHash(address string) ([common.PublicKeyHashSize]byte, error) {
+	bytes, version, err := keyEncodings.Base58CheckToBytes(address)
+
+	if err != nil {
+		return [common.PublicKeyHashSize]byte{}, fmt.Errorf("the multisig address could not be decoded: %w", err)
+	}
+
+	if version != MultisigAddressVersion || len(bytes) != common.PublicKeyHashSize {
+		return [common.PublicKeyHashSize]byte{}, fmt.Errorf("the Base58Check version byte %x dosent match the required version %x for a multisig address", version, MultisigAddressVersion)
+	}
+
+	return [common.PublicKeyHashSize]byte(bytes), nil
+}
+
+func (keyEncodings *KeyEncodingsImpl) VSAddressToPubKeyHash(address string) ([common.PublicKeyHashSize]byte, error) {
+	bytes, version, err := keyEncodings.Base58CheckToBytes(address)
+
+	if err != nil {
+		return [common.PublicKeyHashSize]byte{}, fmt.Errorf("the V$Address could not be decoded: %w", err)
+	}
+
+	if version != VSAddressVersion || len(bytes) != common.PublicKeyHashSize {
+		return [common.PublicKeyHashSize]byte{}, fmt.Errorf("the Base58Check version byte %x dosent match the required version %x for a V$Address", version, VSAddressVersion)
+	}
+
+	return [common.PublicKeyHashSize]byte(bytes), nil
+}
+
 func (keyEncodings *KeyEncodingsImpl) Base58CheckToBytes(input string) ([]byte, byte, error) {
 	bytes, err := base58.Decode(input)
 	if err != nil {
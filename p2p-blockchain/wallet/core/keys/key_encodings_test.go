@@ -59,3 +59,45 @@ func TestKeyEncodingsImpl_WifToPrivateKey(t *testing.T) {
 		t.Errorf("private key not correct")
 	}
 }
+
+func TestKeyEncodingsImpl_MultisigAddress(t *testing.T) {
+	keyEncodings := NewKeyEncodingsImpl()
+	scriptHash := [common.PublicKeyHashSize]byte{1, 2, 3}
+
+	address := keyEncodings.ScriptHashToMultisigAddress(scriptHash)
+	if address[0] != '3' {
+		t.Errorf("multisig address should start with 3, got %s", address)
+	}
+
+	resultHash, err := keyEncodings.MultisigAddressToScriptHash(address)
+	if err != nil {
+		t.Errorf("unexpected error thrown")
+	}
+	if resultHash != scriptHash {
+		t.Errorf("script hash not correct")
+	}
+
+	if _, err := keyEncodings.MultisigAddressToScriptHash(sampleKeyset.VSAddress); err == nil {
+		t.Errorf("a V$Address should not be accepted as multisig address")
+	}
+}
+
+func TestKeyEncodingsImpl_VSAddressToPubKeyHash(t *testing.T) {
+	keyEncodings := NewKeyEncodingsImpl()
+	pubKeyHash := [common.PublicKeyHashSize]byte{4, 5, 6}
+
+	address := keyEncodings.BytesToBase58Check(pubKeyHash[:], VSAddressVersion)
+
+	resultHash, err := keyEncodings.VSAddressToPubKeyHash(address)
+	if err != nil {
+		t.Errorf("unexpected error thrown")
+	}
+	if resultHash != pubKeyHash {
+		t.Errorf("public key hash not correct")
+	}
+
+	multisigAddress := keyEncodings.ScriptHashToMultisigAddress(pubKeyHash)
+	if _, err := keyEncodings.VSAddressToPubKeyHash(multisigAddress); err == nil {
+		t.Errorf("a multisig address should not be accepted as V$Address")
+	}
+}
@@ -3,9 +3,11 @@ package keys
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
@@ -20,6 +22,10 @@ type KeyGenerator interface {
 
 	// GetKeysetFromWIF Gets the complete keyset from the WIF encoded private key
 	GetKeysetFromWIF(privateKeyWIF string) (common.Keyset, error)
+
+	// GetMultisigAddress Gets the multisig address that requires signatures of `required` of the given public keys.
+	// The order of the public keys is part of the address.
+	GetMultisigAddress(required int, publicKeys [][common.PublicKeySize]byte) (common.MultisigAddress, error)
 }
 
 type KeyGeneratorImpl struct {
@@ -72,6 +78,23 @@ func (generator *KeyGeneratorImpl) GetKeysetFromWIF(privateKeyWIF string) (commo
 	}, nil
 }
 
+func (generator *KeyGeneratorImpl) GetMultisigAddress(required int, publicKeys [][common.PublicKeySize]byte) (common.MultisigAddress, error) {
+	pubKeys := make([]transaction.PubKey, len(publicKeys))
+	for i, publicKey := range publicKeys {
+		pubKeys[i] = publicKey
+	}
+
+	script, err := transaction.NewMultisigScript(required, pubKeys)
+	if err != nil {
+		return common.MultisigAddress{}, fmt.Errorf("error creating multisig script: %w", err)
+	}
+
+	return common.MultisigAddress{
+		Address:      generator.encoder.ScriptHashToMultisigAddress(script.Hash()),
+		RedeemScript: hex.EncodeToString(script.Serialize()),
+	}, nil
+}
+
 //private functions
 
 // n = 1,158.. *10^77
@@ -118,5 +141,5 @@ func (generator *KeyGeneratorImpl) getVsAddress(publicKey [common.PublicKeySize]
 	firstHash := sha256.Sum256(publicKey[:])
 	secondHash := sha256.Sum256(firstHash[:])
 
-	return generator.encoder.BytesToBase58Check(secondHash[:20], 0x00)
+	return generator.encoder.BytesToBase58Check(secondHash[:20], VSAddressVersion)
 }
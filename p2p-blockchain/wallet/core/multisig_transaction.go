@@ -0,0 +1,161 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/transaction"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+// CreateMultisigTransaction creates a transaction that spends from the multisig address of the redeem script and signs it with the signer's key.
+// If the signature of the signer is sufficient (1-of-N), the transaction is broadcast right away.
+// Otherwise the partially signed transaction is returned and has to be passed to the other co-signers, see CoSignTransaction.
+func (s *TransactionCreationService) CreateMultisigTransaction(redeemScriptHex string, recipientAddress string, amount uint64, signerPrivateKeyWIF string) transaction.MultisigTransactionResult {
+	script, err := decodeRedeemScript(redeemScriptHex)
+	if err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInvalidMultisigTransaction(err)}
+	}
+
+	recipient, err := s.decodeRecipient(recipientAddress, amount)
+	if err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInvalidAddress(err)}
+	}
+
+	keyset, err := s.keyGenerator.GetKeysetFromWIF(signerPrivateKeyWIF)
+	if err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInvalidPrivateKey(err)}
+	}
+
+	mainChainTip := s.blockStore.GetMainChainTip()
+	utxos, err := s.utxoAPI.GetUtxosByPubKeyHashFromBlock(script.Hash(), mainChainTip.Hash())
+	if err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInsufficientFunds(err)}
+	}
+
+	utxos = s.mempoolAPI.ApplyToUtxos(utxos, script.Hash())
+	utxos = spendableUtxos(utxos, s.blockStore.GetMainChainHeight()+1)
+	if len(utxos) == 0 {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInsufficientFunds(nil)}
+	}
+
+	tx, err := transaction.NewMultisigTransaction(utxos, script, recipient, common.TransactionFee)
+	if err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleTransactionCreationError(err)}
+	}
+
+	return s.coSign(tx, utxos, keyset)
+}
+
+// CoSignTransaction adds the signature of the signer to a partially signed multisig transaction.
+// The transaction is the hex encoded SerializedTransaction returned by CreateMultisigTransaction or a previous CoSignTransaction.
+// As soon as the transaction has enough signatures it is broadcast.
+func (s *TransactionCreationService) CoSignTransaction(serializedTransactionHex string, signerPrivateKeyWIF string) transaction.MultisigTransactionResult {
+	tx, err := decodeTransaction(serializedTransactionHex)
+	if err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInvalidMultisigTransaction(err)}
+	}
+
+	keyset, err := s.keyGenerator.GetKeysetFromWIF(signerPrivateKeyWIF)
+	if err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInvalidPrivateKey(err)}
+	}
+
+	mainChainTip := s.blockStore.GetMainChainTip()
+	mainChainTipHash := mainChainTip.Hash()
+	utxos := make([]transaction.UTXO, 0, len(tx.Inputs))
+	for _, input := range tx.Inputs {
+		utxo, err := s.utxoAPI.GetUtxoEntryFromBlock(input.PrevTxID, input.OutputIndex, mainChainTipHash)
+		if err != nil {
+			return transaction.MultisigTransactionResult{TransactionResult: s.handleInvalidMultisigTransaction(err)}
+		}
+		utxos = append(utxos, utxo)
+	}
+
+	return s.coSign(tx, utxos, keyset)
+}
+
+// coSign signs the multisig inputs of the transaction with the key of the keyset and broadcasts the transaction if it is complete afterward.
+// utxos must contain the outputs referenced by the inputs in the order of the inputs.
+func (s *TransactionCreationService) coSign(tx *transaction.Transaction, utxos []transaction.UTXO, keyset common.Keyset) transaction.MultisigTransactionResult {
+	if err := tx.CoSign(transaction.PrivateKey(keyset.PrivateKey), utxos); err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInvalidPrivateKey(err)}
+	}
+
+	referencedOutputs := make([]transaction.Output, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		for _, utxo := range utxos {
+			if utxo.TxID == input.PrevTxID && utxo.OutputIndex == input.OutputIndex {
+				referencedOutputs[i] = utxo.Output
+			}
+		}
+	}
+
+	complete, err := tx.VerifyAllSignatures(referencedOutputs)
+	if err != nil {
+		return transaction.MultisigTransactionResult{TransactionResult: s.handleInvalidMultisigTransaction(err)}
+	}
+
+	serialized := hex.EncodeToString(tx.Serialize())
+	if !complete {
+		logger.Infof("[wallet] Multisig transaction signed by %s, more signatures needed", keyset.VSAddress)
+		return transaction.MultisigTransactionResult{
+			TransactionResult:     transaction.TransactionResult{Success: true, ErrorCode: transaction.ErrorCodeNone},
+			Complete:              false,
+			SerializedTransaction: serialized,
+		}
+	}
+
+	return transaction.MultisigTransactionResult{
+		TransactionResult:     s.handleSuccess(tx),
+		Complete:              true,
+		SerializedTransaction: serialized,
+	}
+}
+
+func (s *TransactionCreationService) handleInvalidMultisigTransaction(err error) transaction.TransactionResult {
+	logger.Warnf("[wallet] Invalid multisig transaction: %v", err)
+	return transaction.TransactionResult{
+		Success:      false,
+		ErrorCode:    transaction.ErrorCodeValidationFailed,
+		ErrorMessage: fmt.Sprintf("Invalid multisig transaction: %v", err),
+	}
+}
+
+// decodeRedeemScript decodes a hex encoded multisig script as returned with a multisig address.
+func decodeRedeemScript(redeemScriptHex string) (transaction.MultisigScript, error) {
+	data, err := hex.DecodeString(redeemScriptHex)
+	if err != nil {
+		return transaction.MultisigScript{}, fmt.Errorf("failed to decode redeem script: %w", err)
+	}
+
+	script, err := transaction.DeserializeMultisigScript(data)
+	if err != nil {
+		return transaction.MultisigScript{}, err
+	}
+	if err := script.Validate(); err != nil {
+		return transaction.MultisigScript{}, err
+	}
+	return script, nil
+}
+
+// decodeTransaction decodes a hex encoded transaction as returned in MultisigTransactionResult.SerializedTransaction.
+func decodeTransaction(serializedTransactionHex string) (*transaction.Transaction, error) {
+	data, err := hex.DecodeString(serializedTransactionHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	r := bytes.NewReader(data)
+	tx, err := transaction.DeserializeTransaction(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, transaction.ErrMalformedTransaction
+	}
+	return &tx, nil
+}
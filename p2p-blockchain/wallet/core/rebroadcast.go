@@ -0,0 +1,86 @@
+package core
+
+import (
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common"
+	"s3b/vsp-blockchain/p2p-blockchain/internal/common/data/inv"
+	"time"
+
+	"bjoernblessin.de/go-utils/util/logger"
+)
+
+const DefaultRebroadcastInterval = 10 * time.Minute
+
+// localTransactionSource returns the pending transactions created by the wallet.
+// It is implemented by blockapi.MempoolAPI.
+type localTransactionSource interface {
+	GetLocalTransactionHashes() []common.Hash
+}
+
+// invBroadcaster broadcasts inventory messages to the connected peers.
+// It is implemented by api.BlockchainAPI.
+type invBroadcaster interface {
+	BroadcastInvExclusionary(inventory []*inv.InvVector, peerId common.PeerId)
+}
+
+// TransactionRebroadcastService periodically announces the pending transactions created by the wallet to all peers.
+// A transaction missed by the peers, e.g. because no peer was connected when it was created, is thus relayed eventually.
+// Transactions are announced until they are mined, replaced or expire, as they leave the mempool then.
+type TransactionRebroadcastService struct {
+	broadcaster invBroadcaster
+	source      localTransactionSource
+	stopChan    chan struct{}
+	ticker      *time.Ticker
+	interval    time.Duration
+}
+
+// NewTransactionRebroadcastService creates a new TransactionRebroadcastService with the DefaultRebroadcastInterval.
+func NewTransactionRebroadcastService(broadcaster invBroadcaster, source localTransactionSource) *TransactionRebroadcastService {
+	return &TransactionRebroadcastService{
+		broadcaster: broadcaster,
+		source:      source,
+		stopChan:    make(chan struct{}),
+		interval:    DefaultRebroadcastInterval,
+	}
+}
+
+// Start begins the rebroadcast service.
+// It runs in a goroutine and announces the pending local transactions at regular intervals.
+func (s *TransactionRebroadcastService) Start() {
+	logger.Infof("[wallet] Starting transaction rebroadcast service with %s interval", s.interval)
+
+	s.ticker = time.NewTicker(s.interval)
+
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				s.rebroadcast()
+			case <-s.stopChan:
+				s.ticker.Stop()
+				logger.Infof("[wallet] Transaction rebroadcast service stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the rebroadcast service.
+func (s *TransactionRebroadcastService) Stop() {
+	close(s.stopChan)
+}
+
+// rebroadcast announces all pending local transactions in a single inventory message.
+func (s *TransactionRebroadcastService) rebroadcast() {
+	hashes := s.source.GetLocalTransactionHashes()
+	if len(hashes) == 0 {
+		return
+	}
+
+	invVectors := make([]*inv.InvVector, 0, len(hashes))
+	for _, hash := range hashes {
+		invVectors = append(invVectors, &inv.InvVector{Hash: hash, InvType: inv.InvTypeMsgTx})
+	}
+
+	logger.Debugf("[wallet] Rebroadcasting %d pending transactions", len(invVectors))
+	s.broadcaster.BroadcastInvExclusionary(invVectors, "")
+}
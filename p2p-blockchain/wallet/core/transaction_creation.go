@@ -45,12 +45,33 @@ func NewTransactionCreationService(
 }
 
 // CreateTransaction creates and broadcasts a new transaction.
-func (s *TransactionCreationService) CreateTransaction(recipientVSAddress string, amount uint64, senderPrivateKeyWIF string) transaction.TransactionResult {
-	recipientPubKeyHash, err := s.decodeVSAddress(recipientVSAddress)
+// A replaceable transaction signals replaceability, so its fee can be bumped while it is pending, see BumpFee.
+func (s *TransactionCreationService) CreateTransaction(recipientVSAddress string, amount uint64, senderPrivateKeyWIF string, replaceable bool) transaction.TransactionResult {
+	recipient, err := s.decodeRecipient(recipientVSAddress, amount)
 	if err != nil {
 		return s.handleInvalidAddress(err)
 	}
 
+	return s.createAndBroadcast(recipient, senderPrivateKeyWIF, replaceable)
+}
+
+// CreateDataTransaction creates and broadcasts a transaction that anchors the data in the blockchain.
+// The transaction contains an unspendable data output, the sender only pays the transaction fee.
+func (s *TransactionCreationService) CreateDataTransaction(data []byte, senderPrivateKeyWIF string) transaction.TransactionResult {
+	if len(data) == 0 || len(data) > transaction.MaxDataSize {
+		logger.Warnf("[wallet] Invalid data size for data transaction: %d bytes", len(data))
+		return transaction.TransactionResult{
+			Success:      false,
+			ErrorCode:    transaction.ErrorCodeValidationFailed,
+			ErrorMessage: fmt.Sprintf("Data must contain 1 to %d bytes, got %d", transaction.MaxDataSize, len(data)),
+		}
+	}
+
+	return s.createAndBroadcast(transaction.NewDataOutput(data), senderPrivateKeyWIF, false)
+}
+
+// createAndBroadcast creates a transaction paying the recipient output from the UTXOs of the sender and broadcasts it.
+func (s *TransactionCreationService) createAndBroadcast(recipient transaction.Output, senderPrivateKeyWIF string, replaceable bool) transaction.TransactionResult {
 	// Get sender's keyset from private key first
 	keyset, err := s.keyGenerator.GetKeysetFromWIF(senderPrivateKeyWIF)
 	if err != nil {
@@ -67,12 +88,23 @@ func (s *TransactionCreationService) CreateTransaction(recipientVSAddress string
 	mainChainTipHash := mainChainTip.Hash()
 	// Get UTXOs belonging to the sender (not the recipient!)
 	utxos, err := s.utxoAPI.GetUtxosByPubKeyHashFromBlock(senderPubKeyHash, mainChainTipHash)
-	if err != nil || len(utxos) == 0 {
+	if err != nil {
 		return s.handleInsufficientFunds(err)
 	}
 
+	// Outputs of unconfirmed transactions (e.g. change) can be spent as well
+	utxos = s.mempoolAPI.ApplyToUtxos(utxos, senderPubKeyHash)
+	utxos = spendableUtxos(utxos, s.blockStore.GetMainChainHeight()+1)
+	if len(utxos) == 0 {
+		return s.handleInsufficientFunds(nil)
+	}
+
 	privKey := transaction.PrivateKey(keyset.PrivateKey)
-	tx, err := transaction.NewTransaction(utxos, recipientPubKeyHash, amount, common.TransactionFee, privKey)
+	newTransaction := transaction.NewTransactionToOutput
+	if replaceable {
+		newTransaction = transaction.NewReplaceableTransactionToOutput
+	}
+	tx, err := newTransaction(utxos, recipient, common.TransactionFee, privKey)
 	if err != nil {
 		return s.handleTransactionCreationError(err)
 	}
@@ -80,6 +112,17 @@ func (s *TransactionCreationService) CreateTransaction(recipientVSAddress string
 	return s.handleSuccess(tx)
 }
 
+// spendableUtxos filters out coinbase outputs that have not reached the coinbase maturity at the given spend height.
+func spendableUtxos(utxos []transaction.UTXO, spendHeight uint64) []transaction.UTXO {
+	spendable := make([]transaction.UTXO, 0, len(utxos))
+	for _, utxo := range utxos {
+		if utxo.IsSpendableAt(spendHeight) {
+			spendable = append(spendable, utxo)
+		}
+	}
+	return spendable
+}
+
 func (s *TransactionCreationService) handleSuccess(tx *transaction.Transaction) transaction.TransactionResult {
 	txID := tx.TransactionId()
 	txIDHex := hex.EncodeToString(txID[:])
@@ -89,7 +132,14 @@ func (s *TransactionCreationService) handleSuccess(tx *transaction.Transaction)
 			InvType: inv.InvTypeMsgTx,
 		},
 	}
-	s.mempoolAPI.AddTransaction(*tx)
+	if !s.mempoolAPI.AddLocalTransaction(*tx) {
+		logger.Warnf("[wallet] Transaction %s was not accepted by the mempool", txIDHex)
+		return transaction.TransactionResult{
+			Success:      false,
+			ErrorCode:    transaction.ErrorCodeValidationFailed,
+			ErrorMessage: "Transaction was not accepted by the mempool, the fee rate may be too low, it has too many unconfirmed ancestors or it does not pay enough to replace a pending transaction",
+		}
+	}
 	s.blockchainAPI.BroadcastInvExclusionary(invVectors, "") // TODO: Replace with broadcast to all when implemented
 	logger.Tracef("[wallet] following transaction amounts: %s", s.mempoolAPI.GetTransactionValues())
 
@@ -142,6 +192,28 @@ func (s *TransactionCreationService) handleInvalidAddress(err error) transaction
 	}
 }
 
+// decodeRecipient decodes a V$Address or a multisig address to the output that pays the amount to it.
+func (s *TransactionCreationService) decodeRecipient(address string, amount uint64) (transaction.Output, error) {
+	_, version, err := s.keyDecoder.Base58CheckToBytes(address)
+	if err != nil {
+		return transaction.Output{}, fmt.Errorf("failed to decode V$Address: %w", err)
+	}
+
+	if version == keys.MultisigAddressVersion {
+		scriptHash, err := s.keyDecoder.MultisigAddressToScriptHash(address)
+		if err != nil {
+			return transaction.Output{}, err
+		}
+		return transaction.NewMultisigHashOutput(amount, scriptHash), nil
+	}
+
+	pubKeyHash, err := s.decodeVSAddress(address)
+	if err != nil {
+		return transaction.Output{}, err
+	}
+	return transaction.Output{Value: amount, PubKeyHash: pubKeyHash}, nil
+}
+
 // decodeVSAddress decodes a V$Address (Base58Check encoded public key hash) to a PubKeyHash.
 func (s *TransactionCreationService) decodeVSAddress(vsAddress string) (transaction.PubKeyHash, error) {
 	payload, version, err := s.keyDecoder.Base58CheckToBytes(vsAddress)
@@ -150,7 +222,7 @@ func (s *TransactionCreationService) decodeVSAddress(vsAddress string) (transact
 	}
 
 	// Version byte for V$Address should be 0x00
-	if version != 0x00 {
+	if version != keys.VSAddressVersion {
 		return transaction.PubKeyHash{}, fmt.Errorf("invalid V$Address version: expected 0x00, got 0x%02x", version)
 	}
 
@@ -9,6 +9,8 @@ import (
 type Config struct {
 	// AppAddr is the gRPC address of the app service to query for peer information.
 	AppAddr string
+	// AppTLS contains the TLS settings of the connection to the app service.
+	AppTLS AppTLSConfig
 	// AcceptedP2PPort is the default P2P port used by nodes. Only peers using this port are accepted.
 	AcceptedP2PPort uint16
 	// SeedHostsFile is the path to write the DNS hosts file.
@@ -33,6 +35,17 @@ type Config struct {
 	PeerRegistrySubsetSize int
 }
 
+// AppTLSConfig holds the TLS settings of the connection to the app service.
+type AppTLSConfig struct {
+	// Enabled is set if the app service uses TLS.
+	Enabled bool
+	// ClientCertFile and ClientKeyFile are the PEM files of the client certificate, empty if the node doesn't require one.
+	ClientCertFile string
+	ClientKeyFile  string
+	// ServerFingerprint is the SHA-256 fingerprint of the self-signed node certificate, empty to skip the verification.
+	ServerFingerprint string
+}
+
 // BootstrapConfig holds bootstrap peer configuration.
 type BootstrapConfig struct {
 	// Endpoints are the initial peer addresses to connect to.
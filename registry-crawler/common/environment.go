@@ -17,6 +17,14 @@ import (
 const (
 	// Required. string, gRPC address of the app service (host:port).
 	appGrpcAddrPortEnvVar = "APP_GRPC_ADDR_PORT"
+	// Optional. boolean, enables TLS for the connection to the app service (APP_TLS of the node). Values: "true" or "false". Default: false.
+	appGrpcTLSEnvVar = "APP_GRPC_TLS"
+	// Optional. string, PEM file of the client certificate, required if the node restricts clients (APP_CLIENT_CERTS).
+	appGrpcClientCertEnvVar = "APP_GRPC_CLIENT_CERT"
+	// Optional. string, PEM file of the key of the client certificate.
+	appGrpcClientKeyEnvVar = "APP_GRPC_CLIENT_KEY"
+	// Optional. string, SHA-256 fingerprint of the node certificate, logged by the node on start. Empty skips the verification.
+	appGrpcServerFingerprintEnvVar = "APP_GRPC_SERVER_FINGERPRINT"
 	// Required. uint16, P2P port used by miner nodes. Range: 1-65535.
 	acceptedP2pPortEnvVar = "ACCEPTED_P2P_PORT"
 
@@ -60,6 +68,7 @@ const (
 // Atomic configuration storage.
 var (
 	appGrpcAddr     atomic.Value // string
+	appGrpcTLS      atomic.Value // AppTLSConfig
 	acceptedP2pPort atomic.Uint32
 	seedHostsFile   atomic.Value // string
 	seedNamespace   atomic.Value // string
@@ -79,6 +88,7 @@ func init() {
 	cfg := readAndValidateEnvironment()
 
 	appGrpcAddr.Store(cfg.appAddr)
+	appGrpcTLS.Store(cfg.appTLS)
 	acceptedP2pPort.Store(uint32(cfg.acceptedP2pPort))
 	seedHostsFile.Store(cfg.seedHostsFile)
 	seedNamespace.Store(cfg.seedNamespace)
@@ -94,6 +104,7 @@ func init() {
 
 type envSnapshot struct {
 	appAddr                string
+	appTLS                 AppTLSConfig
 	acceptedP2pPort        uint16
 	seedHostsFile          string
 	seedNamespace          string
@@ -110,6 +121,12 @@ type envSnapshot struct {
 // readAndValidateEnvironment reads and validates all environment variables.
 func readAndValidateEnvironment() envSnapshot {
 	appAddr := env.ReadNonEmptyRequiredEnv(appGrpcAddrPortEnvVar)
+	appTLS := AppTLSConfig{
+		Enabled:           readOptionalBool(appGrpcTLSEnvVar),
+		ClientCertFile:    readOptionalStringWithDefault(appGrpcClientCertEnvVar, ""),
+		ClientKeyFile:     readOptionalStringWithDefault(appGrpcClientKeyEnvVar, ""),
+		ServerFingerprint: readOptionalStringWithDefault(appGrpcServerFingerprintEnvVar, ""),
+	}
 	acceptedP2p := mustReadRequiredPort(acceptedP2pPortEnvVar)
 	interval := mustReadRequiredDuration(seedUpdateIntervalEnvVar)
 
@@ -135,6 +152,7 @@ func readAndValidateEnvironment() envSnapshot {
 
 	return envSnapshot{
 		appAddr:                appAddr,
+		appTLS:                 appTLS,
 		acceptedP2pPort:        acceptedP2p,
 		seedHostsFile:          seedHostsFileVal,
 		seedNamespace:          seedNS,
@@ -259,6 +277,7 @@ func parseCommaSeparatedList(key string) []string {
 func CurrentConfig() Config {
 	return Config{
 		AppAddr:                appGrpcAddr.Load().(string),
+		AppTLS:                 appGrpcTLS.Load().(AppTLSConfig),
 		AcceptedP2PPort:        uint16(acceptedP2pPort.Load()),
 		SeedHostsFile:          seedHostsFile.Load().(string),
 		SeedNamespace:          seedNamespace.Load().(string),
@@ -2,10 +2,18 @@ package discovery
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
+	"s3b/vsp-blockchain/registry-crawler/common"
+	"strings"
 	"sync"
 
+	"bjoernblessin.de/go-utils/util/logger"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -15,10 +23,11 @@ var (
 	appGRPCConnAddr string
 )
 
-// DialAppGRPC establishes a gRPC connection to the app service.
+// DialAppGRPC establishes a gRPC connection to the app service at cfg.AppAddr.
 // Repeated calls with the same address will reuse the existing cached connection.
-func DialAppGRPC(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+func DialAppGRPC(ctx context.Context, cfg common.Config) (*grpc.ClientConn, error) {
 	_ = ctx
+	addr := cfg.AppAddr
 
 	appGRPCConnMu.Lock()
 	defer appGRPCConnMu.Unlock()
@@ -30,7 +39,12 @@ func DialAppGRPC(ctx context.Context, addr string) (*grpc.ClientConn, error) {
 		return appGRPCConn, nil
 	}
 
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	transportCredentials, err := appTransportCredentials(cfg.AppTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(transportCredentials))
 	if err != nil {
 		return nil, err
 	}
@@ -38,3 +52,38 @@ func DialAppGRPC(ctx context.Context, addr string) (*grpc.ClientConn, error) {
 	appGRPCConnAddr = addr
 	return appGRPCConn, nil
 }
+
+// appTransportCredentials returns the credentials of the connection to the app service.
+// The node certificate is self-signed, so it is verified against the configured fingerprint instead of a CA.
+func appTransportCredentials(cfg common.AppTLSConfig) (credentials.TransportCredentials, error) {
+	if !cfg.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true, // replaced by the fingerprint check below
+	}
+
+	if cfg.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	fingerprint := strings.ToLower(strings.ReplaceAll(cfg.ServerFingerprint, ":", ""))
+	if fingerprint == "" {
+		logger.Warnf("no fingerprint of the node certificate configured, the certificate of the node is not verified")
+		return credentials.NewTLS(config), nil
+	}
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		hash := sha256.Sum256(rawCerts[0])
+		if hex.EncodeToString(hash[:]) != fingerprint {
+			return fmt.Errorf("node certificate does not match the configured fingerprint")
+		}
+		return nil
+	}
+	return credentials.NewTLS(config), nil
+}
@@ -17,7 +17,7 @@ import (
 // - peers that do not support "blockchain_full" service
 // - peers that do not use the accepted P2P port (standard port)
 func FetchNetworkPeers(ctx context.Context, cfg common.Config) (map[string]struct{}, int32, error) {
-	entries, err := fetchPeerEntries(ctx, cfg.AppAddr)
+	entries, err := fetchPeerEntries(ctx, cfg)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -34,8 +34,8 @@ func FetchNetworkPeers(ctx context.Context, cfg common.Config) (map[string]struc
 }
 
 // fetchPeerEntries establishes a gRPC connection and retrieves peer info entries.
-func fetchPeerEntries(ctx context.Context, appAddr string) ([]*pb.InternalPeerInfoEntry, error) {
-	conn, err := DialAppGRPC(ctx, appAddr)
+func fetchPeerEntries(ctx context.Context, cfg common.Config) ([]*pb.InternalPeerInfoEntry, error) {
+	conn, err := DialAppGRPC(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
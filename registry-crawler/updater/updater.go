@@ -53,7 +53,7 @@ func discoverOnePeer(ctx context.Context, cfg common.Config) {
 	// Disconnect peers marked in the previous cycle
 	peersToDisconnect := peerManager.GetPeersToDisconnect()
 	if len(peersToDisconnect) > 0 {
-		conn, err := discovery.DialAppGRPC(ctx, cfg.AppAddr)
+		conn, err := discovery.DialAppGRPC(ctx, cfg)
 		if err != nil {
 			logger.Warnf("failed to dial app service for disconnection: %v", err)
 		} else {
@@ -119,7 +119,7 @@ func discoverOnePeer(ctx context.Context, cfg common.Config) {
 // verifyPeer attempts to connect to a peer to verify it is reachable.
 // Returns true if the peer responds correctly or is already connected.
 func verifyPeer(ctx context.Context, cfg common.Config, ip string, port int32) bool {
-	conn, err := discovery.DialAppGRPC(ctx, cfg.AppAddr)
+	conn, err := discovery.DialAppGRPC(ctx, cfg)
 	if err != nil {
 		logger.Warnf("failed to dial app service: %v", err)
 		return false
@@ -0,0 +1,69 @@
+/*
+ * V$-GOIN API
+ *
+ * This is the official API for the interaction with the VS-Blockchain. This API focuses on payment-related use cases in the most easy and feasible way. All relevant keys and parameters are documented directly within the schema definitions.
+ *
+ * API version: 1.2.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+import (
+	"errors"
+	"net/http"
+	"s3b/vsp-blockchain/rest-api/internal/common"
+	"s3b/vsp-blockchain/rest-api/mining"
+
+	"bjoernblessin.de/go-utils/util/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type MiningAPI struct {
+	miningStatusService *mining.MiningStatusService
+}
+
+// NewMiningAPI creates a new MiningAPI with the given service.
+func NewMiningAPI(miningStatusService *mining.MiningStatusService) *MiningAPI {
+	return &MiningAPI{
+		miningStatusService: miningStatusService,
+	}
+}
+
+// Get /mining/status
+// Returns the state of the miner of the node and the blocks it mined
+func (api *MiningAPI) MiningStatusGet(c *gin.Context) {
+	status, err := api.miningStatusService.GetMiningStatus()
+	if errors.Is(err, common.ErrMiningUnavailable) {
+		logger.Warnf("[api_mining] Mining status not available: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Warnf("[api_mining] Failed to get mining status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internalServerError})
+		return
+	}
+
+	minedBlocks := make([]MinedBlock, 0, len(status.MinedBlocks))
+	for _, mined := range status.MinedBlocks {
+		minedBlocks = append(minedBlocks, MinedBlock{
+			Hash:   mined.Hash,
+			Height: mined.Height,
+			Reward: mined.Reward,
+			Status: mined.Status,
+		})
+	}
+
+	c.JSON(http.StatusOK, MiningStatusGet200Response{
+		Enabled:           status.Enabled,
+		Mining:            status.Mining,
+		CandidateHeight:   status.CandidateHeight,
+		PreviousBlockHash: status.PreviousBlockHash,
+		TransactionCount:  status.TransactionCount,
+		HashesTried:       status.HashesTried,
+		HashRate:          status.HashRate,
+		MinedBlocks:       minedBlocks,
+		TotalRewards:      status.TotalRewards,
+	})
+}
@@ -110,6 +110,35 @@ func (api *PaymentAPI) HistoryGet(c *gin.Context) {
 	c.JSON(http.StatusOK, HistoryGet200Response{Transactions: transactions})
 }
 
+// Get /history/data
+// Returns the transactions anchoring the given data
+func (api *PaymentAPI) HistoryDataGet(c *gin.Context) {
+	// Extract data from query parameter
+	data := c.Query("data")
+
+	// Call the domain service
+	transactions, err := api.transaktionsverlaufService.GetHistoryByData(data)
+	if errors.Is(err, common.ErrInvalidData) {
+		logger.Warnf("[api_payment] Data history request validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+	var assetErr *common.AssetError
+	if errors.As(err, &assetErr) {
+		logger.Warnf("[api_payment] Data history request asset error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": assetErr.Message})
+		return
+	}
+	if err != nil {
+		logger.Warnf("[api_payment] Failed to get data history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internalServerError})
+		return
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, HistoryGet200Response{Transactions: transactions})
+}
+
 // Post /transaction
 // Executes a transaction
 func (api *PaymentAPI) TransactionPost(c *gin.Context) {
@@ -126,6 +155,7 @@ func (api *PaymentAPI) TransactionPost(c *gin.Context) {
 		RecipientVSAddress:  req.RecipientVSAddress,
 		Amount:              uint64(req.Amount),
 		SenderPrivateKeyWIF: req.SenderPrivateKeyWIF,
+		Replaceable:         req.Replaceable,
 	}
 
 	// Call the domain service
@@ -144,6 +174,64 @@ func (api *PaymentAPI) TransactionPost(c *gin.Context) {
 	api.writeResponse(c, result)
 }
 
+// Post /transaction/data
+// Anchors data in the blockchain
+func (api *PaymentAPI) TransactionDataPost(c *gin.Context) {
+	// Parse request body
+	var req TransactionDataPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("[api_payment] Failed to decode data transaction request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON request body"})
+		return
+	}
+
+	// Call the domain service
+	result, validationErr := api.transactionService.CreateDataTransaction(req.Data, req.SenderPrivateKeyWIF)
+	if validationErr != nil {
+		logger.Warnf("[api_payment] Data transaction request validation failed: %v", validationErr)
+		if validationErr.IsAuthError {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": validationErr.Message})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		}
+		return
+	}
+
+	// Handle result based on error code
+	api.writeResponse(c, result)
+}
+
+// Post /transaction/bumpfee
+// Replaces a pending transaction with a higher fee
+func (api *PaymentAPI) TransactionBumpfeePost(c *gin.Context) {
+	// Parse request body
+	var req TransactionBumpfeePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("[api_payment] Failed to decode bump fee request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON request body"})
+		return
+	}
+
+	// Call the domain service
+	result, validationErr := api.transactionService.BumpFee(common.BumpFeeRequest{
+		TransactionID:       req.TransactionID,
+		Fee:                 int64(req.Fee),
+		SenderPrivateKeyWIF: req.SenderPrivateKeyWIF,
+	})
+	if validationErr != nil {
+		logger.Warnf("[api_payment] Bump fee request validation failed: %v", validationErr)
+		if validationErr.IsAuthError {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": validationErr.Message})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+		}
+		return
+	}
+
+	// Handle result based on error code
+	api.writeResponse(c, result)
+}
+
 // writeResponse writes the appropriate HTTP response based on the transaction result.
 func (api *PaymentAPI) writeResponse(c *gin.Context, result *common.TransactionResult) {
 	if result.Success {
@@ -0,0 +1,29 @@
+/*
+ * V$-GOIN API
+ *
+ * This is the official API for the interaction with the VS-Blockchain. This API focuses on payment-related use cases in the most easy and feasible way. All relevant keys and parameters are documented directly within the schema definitions.
+ *
+ * API version: 1.2.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+type MinedBlock struct {
+	Hash   string `json:"hash"`
+	Height uint64 `json:"height"`
+	Reward uint64 `json:"reward"`
+	Status string `json:"status"`
+}
+
+type MiningStatusGet200Response struct {
+	Enabled           bool         `json:"enabled"`
+	Mining            bool         `json:"mining"`
+	CandidateHeight   uint64       `json:"candidateHeight,omitempty"`
+	PreviousBlockHash string       `json:"previousBlockHash,omitempty"`
+	TransactionCount  uint32       `json:"transactionCount"`
+	HashesTried       uint64       `json:"hashesTried"`
+	HashRate          uint64       `json:"hashRate"`
+	MinedBlocks       []MinedBlock `json:"minedBlocks"`
+	TotalRewards      uint64       `json:"totalRewards"`
+}
@@ -0,0 +1,22 @@
+/*
+ * V$-GOIN API
+ *
+ * This is the official API for the interaction with the VS-Blockchain. This API focuses on payment-related use cases in the most easy and feasible way. All relevant keys and parameters are documented directly within the schema definitions.
+ *
+ * API version: 1.1.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package openapi
+
+type TransactionBumpfeePostRequest struct {
+
+	// Hex-encoded ID of the pending transaction whose fee is bumped.
+	TransactionID string `json:"transactionID" validate:"regexp=^[0-9a-fA-F]{64}$"`
+
+	// The new fee in V$-Goin, must be higher than the fee of the pending transaction
+	Fee int32 `json:"fee"`
+
+	// Base58Check-encoded private key with 0x80 as prefix (Wallet Import Format, WIF)  The private Key (WIF) gives access to the VSGoins send to the corresponding VSAddress. Be careful not to shared it with others.  Way to generate a PrivateKey: 1. Generate random 256 bit unsigned number 2. Check if number is greater than or equal to 1 3. Check if number is smaller than the order n of the generator G on Secp256k1 4. If the number is invalid, go back to 1 5. Create a SHA256 of the random number
+	SenderPrivateKeyWIF string `json:"senderPrivateKeyWIF" validate:"regexp=^5[1-9A-HJ-NP-Za-km-z]{50}$"`
+}
@@ -19,4 +19,7 @@ type TransactionPostRequest struct {
 
 	// Base58Check-encoded private key with 0x80 as prefix (Wallet Import Format, WIF)  The private Key (WIF) gives access to the VSGoins send to the corresponding VSAddress. Be careful not to shared it with others.  Way to generate a PrivateKey: 1. Generate random 256 bit unsigned number 2. Check if number is greater than or equal to 1 3. Check if number is smaller than the order n of the generator G on Secp256k1 4. If the number is invalid, go back to 1 5. Create a SHA256 of the random number
 	SenderPrivateKeyWIF string `json:"senderPrivateKeyWIF" validate:"regexp=^5[1-9A-HJ-NP-Za-km-z]{50}$"`
+
+	// If true, the transaction can be replaced with a higher fee while it is pending, see /transaction/bumpfee
+	Replaceable bool `json:"replaceable,omitempty"`
 }
@@ -66,6 +66,8 @@ type ApiHandleFunctions struct {
 	DevToolsAPI DevToolsAPI
 	// Routes for the KeyToolsAPI part of the API
 	KeyToolsAPI KeyToolsAPI
+	// Routes for the MiningAPI part of the API
+	MiningAPI MiningAPI
 	// Routes for the PaymentAPI part of the API
 	PaymentAPI PaymentAPI
 }
@@ -90,6 +92,12 @@ func getRoutes(handleFunctions ApiHandleFunctions) []Route {
 			"/address",
 			handleFunctions.KeyToolsAPI.AddressPost,
 		},
+		{
+			"MiningStatusGet",
+			http.MethodGet,
+			"/mining/status",
+			handleFunctions.MiningAPI.MiningStatusGet,
+		},
 		{
 			"BalanceGet",
 			http.MethodGet,
@@ -102,6 +110,12 @@ func getRoutes(handleFunctions ApiHandleFunctions) []Route {
 			"/history",
 			handleFunctions.PaymentAPI.HistoryGet,
 		},
+		{
+			"HistoryDataGet",
+			http.MethodGet,
+			"/history/data",
+			handleFunctions.PaymentAPI.HistoryDataGet,
+		},
 		{
 			"TransactionConfirmationGet",
 			http.MethodGet,
@@ -114,5 +128,17 @@ func getRoutes(handleFunctions ApiHandleFunctions) []Route {
 			"/transaction",
 			handleFunctions.PaymentAPI.TransactionPost,
 		},
+		{
+			"TransactionDataPost",
+			http.MethodPost,
+			"/transaction/data",
+			handleFunctions.PaymentAPI.TransactionDataPost,
+		},
+		{
+			"TransactionBumpfeePost",
+			http.MethodPost,
+			"/transaction/bumpfee",
+			handleFunctions.PaymentAPI.TransactionBumpfeePost,
+		},
 	}
 }
@@ -5,6 +5,8 @@ import "errors"
 var ErrWIFInput = errors.New("the format of the private key WIF is invalid")
 var ErrServer = errors.New("internal server error")
 var ErrInvalidAddress = errors.New("invalid VSAddress format")
+var ErrInvalidData = errors.New("invalid data format")
+var ErrMiningUnavailable = errors.New("mining is not available on the node")
 
 type AssetError struct {
 	Message string
@@ -0,0 +1,23 @@
+package common
+
+// MinedBlock is a block mined by the node.
+type MinedBlock struct {
+	Hash   string
+	Height uint64
+	Reward uint64
+	// Status is one of "main_chain", "side_chain", "orphan" or "unknown".
+	Status string
+}
+
+// MiningStatus describes what the miner of the node is doing and what it mined.
+type MiningStatus struct {
+	Enabled           bool
+	Mining            bool
+	CandidateHeight   uint64
+	PreviousBlockHash string
+	TransactionCount  uint32
+	HashesTried       uint64
+	HashRate          uint64
+	MinedBlocks       []MinedBlock
+	TotalRewards      uint64
+}
@@ -13,4 +13,19 @@ type TransactionRequest struct {
 	RecipientVSAddress  string
 	Amount              uint64
 	SenderPrivateKeyWIF string
+	// Replaceable transactions can be replaced with a higher fee while they are pending, see BumpFeeRequest
+	Replaceable bool
+}
+
+// DataTransactionRequest contains the data needed to create a new transaction anchoring data in the blockchain.
+type DataTransactionRequest struct {
+	Data                []byte
+	SenderPrivateKeyWIF string
+}
+
+// BumpFeeRequest contains the data needed to replace a pending transaction with a higher fee.
+type BumpFeeRequest struct {
+	TransactionID       string
+	Fee                 int64
+	SenderPrivateKeyWIF string
 }
@@ -8,3 +8,9 @@ var PrivateKeyWIFPattern = regexp.MustCompile(`^5[1-9A-HJ-NP-Za-km-z]{50}$`)
 
 // VsAddressPattern VSAddress validation: Base58Check encoded (starts with 1 for mainnet addresses).
 var VsAddressPattern = regexp.MustCompile(`^1[1-9A-HJ-NP-Za-km-z]{25,34}$`)
+
+// DataPattern Data validation: hex-encoded with a length of 1 to 80 bytes, e.g. the SHA 256 hash of a document.
+var DataPattern = regexp.MustCompile(`^([0-9a-fA-F]{2}){1,80}$`)
+
+// TransactionIDPattern Transaction ID validation: hex-encoded with a length of 32 bytes.
+var TransactionIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
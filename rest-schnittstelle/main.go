@@ -9,6 +9,7 @@ import (
 	sw "s3b/vsp-blockchain/rest-api/api_adapter"
 	"s3b/vsp-blockchain/rest-api/internal/pb"
 	"s3b/vsp-blockchain/rest-api/konto"
+	"s3b/vsp-blockchain/rest-api/mining"
 	transactionapi "s3b/vsp-blockchain/rest-api/transaktion"
 	"s3b/vsp-blockchain/rest-api/transaktionsverlauf"
 	"s3b/vsp-blockchain/rest-api/vsgoin_node_adapter"
@@ -16,7 +17,6 @@ import (
 
 	"bjoernblessin.de/go-utils/util/logger"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/gin-gonic/gin"
 )
@@ -34,7 +34,12 @@ func main() {
 		grpcAddrPort = "localhost:20001"
 	}
 
-	conn, err := grpc.NewClient(grpcAddrPort, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	transportCredentials, err := vsgoin_node_adapter.TransportCredentials()
+	if err != nil {
+		logger.Errorf("[rest_schnittstelle] failed to set up the connection security: %v", err)
+	}
+
+	conn, err := grpc.NewClient(grpcAddrPort, grpc.WithTransportCredentials(transportCredentials))
 	if err != nil {
 		logger.Errorf("[rest_schnittstelle] failed to create connection to gRPC server: %v", err)
 	}
@@ -51,16 +56,19 @@ func main() {
 	transactionAdapter := vsgoin_node_adapter.NewTransactionAdapterImpl(appServiceClient)
 	kontoAdapter := vsgoin_node_adapter.NewKontoAdapter(conn)
 	historyAdapter := vsgoin_node_adapter.NewHistoryAdapter(conn)
+	miningAdapter := vsgoin_node_adapter.NewMiningAdapter(conn)
 	kontostand := konto.NewKeyGeneratorImpl(transactionAdapter)
 	transactionApi := transactionapi.NewTransaktionAPI(transactionAdapter)
 	kontostandService := konto.NewKontostandService(kontoAdapter)
 	transaktionsverlaufService := transaktionsverlauf.NewTransaktionsverlaufService(historyAdapter)
+	miningStatusService := mining.NewMiningStatusService(miningAdapter)
 
 	// REST API Server
 	routes := sw.ApiHandleFunctions{
 		KeyToolsAPI: *sw.NewKeyToolsAPI(kontostand),
 		PaymentAPI:  *sw.NewPaymentAPI(transactionApi, kontostandService, transaktionsverlaufService),
 		DevToolsAPI: *sw.NewDevToolsAPI(transactionApi),
+		MiningAPI:   *sw.NewMiningAPI(miningStatusService),
 	}
 
 	logger.Infof("[rest_schnittstelle] Server started")
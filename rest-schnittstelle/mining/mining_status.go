@@ -0,0 +1,25 @@
+// Package mining contains domain logic for querying the miner of the node.
+package mining
+
+import (
+	"s3b/vsp-blockchain/rest-api/internal/common"
+	"s3b/vsp-blockchain/rest-api/vsgoin_node_adapter"
+)
+
+// MiningStatusService handles the domain logic of the mining status.
+type MiningStatusService struct {
+	miningAdapter vsgoin_node_adapter.MiningAdapterAPI
+}
+
+// NewMiningStatusService creates a new MiningStatusService with the given adapter.
+func NewMiningStatusService(miningAdapter vsgoin_node_adapter.MiningAdapterAPI) *MiningStatusService {
+	return &MiningStatusService{
+		miningAdapter: miningAdapter,
+	}
+}
+
+// GetMiningStatus retrieves the state of the miner and the blocks it mined.
+// Returns common.ErrMiningUnavailable if the node does not run the miner.
+func (s *MiningStatusService) GetMiningStatus() (*common.MiningStatus, error) {
+	return s.miningAdapter.GetMiningStatus()
+}
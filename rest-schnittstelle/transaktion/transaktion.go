@@ -2,6 +2,7 @@
 package transaktion
 
 import (
+	"encoding/hex"
 	"s3b/vsp-blockchain/rest-api/internal/common"
 	adapter "s3b/vsp-blockchain/rest-api/vsgoin_node_adapter"
 )
@@ -41,6 +42,7 @@ func (s *TransaktionAPI) CreateTransaction(req common.TransactionRequest) (*comm
 		RecipientVSAddress:  req.RecipientVSAddress,
 		Amount:              uint64(req.Amount),
 		SenderPrivateKeyWIF: req.SenderPrivateKeyWIF,
+		Replaceable:         req.Replaceable,
 	}
 
 	// Call the node adapter to create the transaction
@@ -99,6 +101,93 @@ func (s *TransaktionAPI) validateRequest(req common.TransactionRequest) *Validat
 	return nil
 }
 
+// CreateDataTransaction validates the hex-encoded data and creates a transaction anchoring it in the blockchain.
+// Returns a ValidationError if validation fails, or the result from the node adapter.
+func (s *TransaktionAPI) CreateDataTransaction(dataHex string, senderPrivateKeyWIF string) (*common.TransactionResult, *ValidationError) {
+	if senderPrivateKeyWIF == "" {
+		return nil, &ValidationError{
+			Message:     "senderPrivateKeyWIF is required",
+			IsAuthError: false,
+		}
+	}
+
+	if !common.PrivateKeyWIFPattern.MatchString(senderPrivateKeyWIF) {
+		return nil, &ValidationError{
+			Message:     "Invalid private key format",
+			IsAuthError: true,
+		}
+	}
+
+	if !common.DataPattern.MatchString(dataHex) {
+		return nil, &ValidationError{
+			Message:     "Invalid data format",
+			IsAuthError: false,
+		}
+	}
+	data, err := hex.DecodeString(dataHex)
+	if err != nil {
+		return nil, &ValidationError{
+			Message:     "Invalid data format",
+			IsAuthError: false,
+		}
+	}
+
+	result, err := s.nodeAdapter.CreateDataTransaction(common.DataTransactionRequest{
+		Data:                data,
+		SenderPrivateKeyWIF: senderPrivateKeyWIF,
+	})
+	if err != nil {
+		return nil, &ValidationError{
+			Message:     "Internal server error",
+			IsAuthError: false,
+		}
+	}
+
+	return result, nil
+}
+
+// BumpFee validates the request and replaces the pending transaction with one paying the higher fee.
+// Returns a ValidationError if validation fails, or the result from the node adapter.
+func (s *TransaktionAPI) BumpFee(req common.BumpFeeRequest) (*common.TransactionResult, *ValidationError) {
+	if req.SenderPrivateKeyWIF == "" {
+		return nil, &ValidationError{
+			Message:     "senderPrivateKeyWIF is required",
+			IsAuthError: false,
+		}
+	}
+
+	if !common.PrivateKeyWIFPattern.MatchString(req.SenderPrivateKeyWIF) {
+		return nil, &ValidationError{
+			Message:     "Invalid private key format",
+			IsAuthError: true,
+		}
+	}
+
+	if !common.TransactionIDPattern.MatchString(req.TransactionID) {
+		return nil, &ValidationError{
+			Message:     "Invalid transaction ID format",
+			IsAuthError: false,
+		}
+	}
+
+	if req.Fee < 1 {
+		return nil, &ValidationError{
+			Message:     "fee must be at least 1",
+			IsAuthError: false,
+		}
+	}
+
+	result, err := s.nodeAdapter.BumpFee(req)
+	if err != nil {
+		return nil, &ValidationError{
+			Message:     "Internal server error",
+			IsAuthError: false,
+		}
+	}
+
+	return result, nil
+}
+
 func (s *TransaktionAPI) GetBlockchainVisualization(includeDetails bool) (string, error) {
 	return s.nodeAdapter.GetBlockchainVisualization(includeDetails)
 }
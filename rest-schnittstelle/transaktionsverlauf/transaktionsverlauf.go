@@ -1,6 +1,8 @@
 package transaktionsverlauf
 
 import (
+	"encoding/hex"
+
 	"s3b/vsp-blockchain/rest-api/internal/common"
 	"s3b/vsp-blockchain/rest-api/vsgoin_node_adapter"
 )
@@ -36,3 +38,27 @@ func (s *TransaktionsverlaufService) GetHistory(vsAddress string) ([]string, err
 
 	return result.Transactions, nil
 }
+
+// GetHistoryByData returns the transactions anchoring the given hex-encoded data.
+func (s *TransaktionsverlaufService) GetHistoryByData(dataHex string) ([]string, error) {
+	// Validate the data format
+	if !common.DataPattern.MatchString(dataHex) {
+		return nil, common.ErrInvalidData
+	}
+	data, err := hex.DecodeString(dataHex)
+	if err != nil {
+		return nil, common.ErrInvalidData
+	}
+
+	// Query the local node for the matching transactions
+	result, err := s.historyAdapter.GetHistoryByData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Success {
+		return nil, &common.AssetError{Message: result.ErrorMessage}
+	}
+
+	return result.Transactions, nil
+}
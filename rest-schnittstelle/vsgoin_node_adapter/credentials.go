@@ -0,0 +1,58 @@
+package vsgoin_node_adapter
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"bjoernblessin.de/go-utils/util/logger"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	appTLSEnvVar               = "APP_GRPC_TLS"                // "true" if the app server of the node uses TLS (APP_TLS of the node), default: false
+	appClientCertEnvVar        = "APP_GRPC_CLIENT_CERT"        // PEM file of the client certificate, required if the node restricts clients (APP_CLIENT_CERTS)
+	appClientKeyEnvVar         = "APP_GRPC_CLIENT_KEY"         // PEM file of the key of the client certificate
+	appServerFingerprintEnvVar = "APP_GRPC_SERVER_FINGERPRINT" // SHA-256 fingerprint of the node certificate, logged by the node on start
+)
+
+// TransportCredentials returns the credentials of the connection to the app server of the node.
+// The node certificate is self-signed, so it is verified against the configured fingerprint instead of a CA.
+func TransportCredentials() (credentials.TransportCredentials, error) {
+	if strings.TrimSpace(os.Getenv(appTLSEnvVar)) != "true" {
+		return insecure.NewCredentials(), nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true, // replaced by the fingerprint check below
+	}
+
+	certFile := strings.TrimSpace(os.Getenv(appClientCertEnvVar))
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, strings.TrimSpace(os.Getenv(appClientKeyEnvVar)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	fingerprint := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(os.Getenv(appServerFingerprintEnvVar)), ":", ""))
+	if fingerprint == "" {
+		logger.Warnf("[node_adapter] %s is not set, the certificate of the node is not verified", appServerFingerprintEnvVar)
+		return credentials.NewTLS(config), nil
+	}
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		hash := sha256.Sum256(rawCerts[0])
+		if hex.EncodeToString(hash[:]) != fingerprint {
+			return fmt.Errorf("node certificate does not match %s", appServerFingerprintEnvVar)
+		}
+		return nil
+	}
+	return credentials.NewTLS(config), nil
+}
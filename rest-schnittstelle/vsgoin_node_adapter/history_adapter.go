@@ -15,6 +15,8 @@ import (
 type HistoryAdapterAPI interface {
 	// GetHistory queries the transaction history for a given V$Address via the local node.
 	GetHistory(vsAddress string) (*common.HistoryResult, error)
+	// GetHistoryByData queries the transactions with a data output containing the given data via the local node.
+	GetHistoryByData(data []byte) (*common.HistoryResult, error)
 }
 
 // HistoryAdapter implements HistoryAdapterAPI using gRPC communication with the local node.
@@ -46,3 +48,21 @@ func (a *HistoryAdapter) GetHistory(vsAddress string) (*common.HistoryResult, er
 		Transactions: resp.Transactions,
 	}, nil
 }
+
+// GetHistoryByData queries the transactions with a data output containing the given data via the local node.
+func (a *HistoryAdapter) GetHistoryByData(data []byte) (*common.HistoryResult, error) {
+	grpcReq := &pb.GetHistoryByDataRequest{
+		Data: data,
+	}
+
+	resp, err := a.client.GetHistoryByData(context.Background(), grpcReq)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+
+	return &common.HistoryResult{
+		Success:      resp.Success,
+		ErrorMessage: resp.ErrorMessage,
+		Transactions: resp.Transactions,
+	}, nil
+}
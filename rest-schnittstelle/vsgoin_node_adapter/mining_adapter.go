@@ -0,0 +1,76 @@
+package vsgoin_node_adapter
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"s3b/vsp-blockchain/rest-api/internal/common"
+	"s3b/vsp-blockchain/rest-api/internal/pb"
+
+	"google.golang.org/grpc"
+)
+
+// MiningAdapterAPI provides the interface for querying the miner of the local V$Goin Node.
+type MiningAdapterAPI interface {
+	// GetMiningStatus queries the state of the miner and the blocks it mined.
+	GetMiningStatus() (*common.MiningStatus, error)
+}
+
+// MiningAdapter implements MiningAdapterAPI using gRPC communication with the local node.
+type MiningAdapter struct {
+	client pb.AppServiceClient
+}
+
+// NewMiningAdapter creates a new MiningAdapter with the given gRPC connection.
+func NewMiningAdapter(conn grpc.ClientConnInterface) *MiningAdapter {
+	return &MiningAdapter{
+		client: pb.NewAppServiceClient(conn),
+	}
+}
+
+// GetMiningStatus queries the state of the miner and the blocks it mined.
+// Returns common.ErrMiningUnavailable if the node does not run the miner.
+func (a *MiningAdapter) GetMiningStatus() (*common.MiningStatus, error) {
+	resp, err := a.client.GetMiningStatus(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("%w: %s", common.ErrMiningUnavailable, resp.ErrorMessage)
+	}
+
+	minedBlocks := make([]common.MinedBlock, 0, len(resp.MinedBlocks))
+	for _, mined := range resp.MinedBlocks {
+		minedBlocks = append(minedBlocks, common.MinedBlock{
+			Hash:   hex.EncodeToString(mined.Hash),
+			Height: mined.Height,
+			Reward: mined.Reward,
+			Status: minedBlockStatus(mined.Status),
+		})
+	}
+
+	return &common.MiningStatus{
+		Enabled:           resp.Enabled,
+		Mining:            resp.Mining,
+		CandidateHeight:   resp.CandidateHeight,
+		PreviousBlockHash: hex.EncodeToString(resp.PreviousBlockHash),
+		TransactionCount:  resp.TransactionCount,
+		HashesTried:       resp.HashesTried,
+		HashRate:          resp.HashRate,
+		MinedBlocks:       minedBlocks,
+		TotalRewards:      resp.TotalRewards,
+	}, nil
+}
+
+func minedBlockStatus(status pb.MinedBlockStatus) string {
+	switch status {
+	case pb.MinedBlockStatus_MINED_BLOCK_STATUS_MAIN_CHAIN:
+		return "main_chain"
+	case pb.MinedBlockStatus_MINED_BLOCK_STATUS_SIDE_CHAIN:
+		return "side_chain"
+	case pb.MinedBlockStatus_MINED_BLOCK_STATUS_ORPHAN:
+		return "orphan"
+	default:
+		return "unknown"
+	}
+}
@@ -12,6 +12,10 @@ type TransactionAdapter interface {
 	GetKeysetFromWIF(privateKeyWIF string) (common.Keyset, error)
 	// CreateTransaction creates and broadcasts a new transaction via the local node.
 	CreateTransaction(req common.TransactionRequest) (*common.TransactionResult, error)
+	// CreateDataTransaction creates and broadcasts a new transaction anchoring the data of the request via the local node.
+	CreateDataTransaction(req common.DataTransactionRequest) (*common.TransactionResult, error)
+	// BumpFee replaces a pending transaction with one paying the higher fee of the request via the local node.
+	BumpFee(req common.BumpFeeRequest) (*common.TransactionResult, error)
 	GetBlockchainVisualization(includeDetails bool) (string, error)
 	GetConfirmationStatus(transactionID string) (bool, error)
 }
@@ -69,6 +73,7 @@ func (t *TransactionAdapterImpl) CreateTransaction(req common.TransactionRequest
 		RecipientVsAddress:  req.RecipientVSAddress,
 		Amount:              req.Amount,
 		SenderPrivateKeyWif: req.SenderPrivateKeyWIF,
+		Replaceable:         req.Replaceable,
 	}
 
 	// Call the gRPC service
@@ -86,6 +91,47 @@ func (t *TransactionAdapterImpl) CreateTransaction(req common.TransactionRequest
 	}, nil
 }
 
+// CreateDataTransaction send data transaction request to local node
+func (t *TransactionAdapterImpl) CreateDataTransaction(req common.DataTransactionRequest) (*common.TransactionResult, error) {
+	grpcReq := &pb.CreateDataTransactionRequest{
+		Data:                req.Data,
+		SenderPrivateKeyWif: req.SenderPrivateKeyWIF,
+	}
+
+	resp, err := t.appServiceClient.CreateDataTransaction(context.Background(), grpcReq)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+
+	return &common.TransactionResult{
+		Success:       resp.Success,
+		ErrorCode:     mapErrorCode(resp.ErrorCode),
+		ErrorMessage:  resp.ErrorMessage,
+		TransactionID: resp.TransactionId,
+	}, nil
+}
+
+// BumpFee send bump fee request to local node
+func (t *TransactionAdapterImpl) BumpFee(req common.BumpFeeRequest) (*common.TransactionResult, error) {
+	grpcReq := &pb.BumpFeeRequest{
+		TransactionId:       req.TransactionID,
+		Fee:                 uint64(req.Fee),
+		SenderPrivateKeyWif: req.SenderPrivateKeyWIF,
+	}
+
+	resp, err := t.appServiceClient.BumpFee(context.Background(), grpcReq)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+
+	return &common.TransactionResult{
+		Success:       resp.Success,
+		ErrorCode:     mapErrorCode(resp.ErrorCode),
+		ErrorMessage:  resp.ErrorMessage,
+		TransactionID: resp.TransactionId,
+	}, nil
+}
+
 // mapErrorCode converts gRPC error codes to adapter error codes.
 func mapErrorCode(code pb.TransactionErrorCode) common.TransactionErrorCode {
 	switch code {